@@ -0,0 +1,121 @@
+package main
+
+import (
+	"time"
+
+	"loglynx/internal/agent"
+	"loglynx/internal/config"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/discovery"
+	"loglynx/internal/ingestion"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// agentStopTimeout bounds the wait for in-flight batches when the central server is unreachable at shutdown
+const agentStopTimeout = 30 * time.Second
+
+// runAgent discovers, tails and parses the local log sources and forwards the requests to the central server
+// The local database only keeps source positions: no dashboard, API, statistics, cleanup or alerting run here.
+func runAgent(cfg *config.Config, db *gorm.DB, indexProfile repositories.IndexProfile, shutdownRequested <-chan struct{}, logger *pterm.Logger) {
+	sourceRepo := repositories.NewLogSourceRepository(db)
+	forwarder, err := agent.NewForwarder(
+		repositories.NewHTTPRequestRepository(db, logger, indexProfile),
+		sourceRepo,
+		cfg.Agent.ServerURL,
+		cfg.Agent.Token,
+		cfg.Agent.SourcePrefix,
+		cfg.Agent.Timeout,
+		cfg.Agent.MaxBackoff,
+		logger,
+	)
+	if err != nil {
+		logger.WithCaller().Fatal("Invalid agent configuration", logger.Args("error", err))
+	}
+
+	geoIP := newGeoIPEnricher(cfg, db, logger)
+	parserRegistry := parsers.NewRegistry(capturedHeaders(cfg, logger), capturedResponseHeaders(cfg, logger), logger)
+	configureApacheLogFormat(cfg, parserRegistry, logger)
+	plugins, pluginEnrichers := loadPlugins(cfg, parserRegistry, logger)
+
+	logger.Info("Discovering log sources...")
+	discoveryEngine := discovery.NewEngine(sourceRepo, logger)
+	if err := discoveryEngine.Run(logger); err != nil {
+		logger.Warn("Initial discovery failed", logger.Args("error", err))
+	}
+	startPeriodicDiscovery(discoveryEngine, sourceRepo, cfg.LogSources.DiscoveryInterval, logger)
+
+	dedupOptions, err := ingestion.ParseDedupOptions(cfg.LogSources.DedupLineOffset, cfg.LogSources.ParserDedupLineOffset)
+	if err != nil {
+		logger.Warn("Invalid dedup line offset configuration, using default (auto)", logger.Args("error", err))
+		dedupOptions = ingestion.NewDefaultDedupOptions()
+	}
+
+	// The ingestion counts of each agent are emitted locally, to the agent next to it
+	metrics := newStatsDClient(cfg, logger)
+
+	coordinator := ingestion.NewCoordinator(
+		sourceRepo,
+		forwarder,
+		parserRegistry,
+		geoIP,
+		nil, // Real-time metrics are computed by the central server
+		metrics,
+		dedupOptions,
+		fieldMappings(cfg, logger),
+		forwardedResolver(cfg, logger),
+		pluginEnrichers,
+		transformRules(cfg, logger),
+		labelRules(cfg, logger),
+		logger,
+		cfg.LogSources.InitialImportDays,
+		cfg.LogSources.InitialImportEnable,
+		ingestion.BatchSettings{
+			Adaptive: cfg.Performance.AdaptiveBatching,
+			MinSize:  cfg.Performance.BatchSizeMin,
+			MaxSize:  cfg.Performance.BatchSize,
+			MinFlush: cfg.Performance.BatchFlushMin,
+			MaxFlush: cfg.Performance.BatchFlushMax,
+		},
+		cfg.Performance.WorkerPoolSize,
+		cfg.LogSources.MaxLineLength,
+	)
+	if err := coordinator.Start(); err != nil {
+		logger.WithCaller().Fatal("Failed to start ingestion coordinator", logger.Args("error", err))
+	}
+	coordinator.StartSyncLoop(30 * time.Second)
+	watchdog := newIngestionWatchdog(cfg, coordinator, logger)
+
+	logger.Info("🐱 LogLynx agent is running",
+		logger.Args("server", cfg.Agent.ServerURL, "processors", coordinator.GetProcessorCount()))
+
+	<-shutdownRequested
+	logger.Info("Shutdown signal received, stopping agent...")
+
+	// Let the last batches reach the central server, then give up on the ones still retrying
+	if watchdog != nil {
+		watchdog.Stop()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		coordinator.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(agentStopTimeout):
+		logger.Warn("Central server unreachable, abandoning the batches not forwarded yet (their lines are resent on the next start)")
+		forwarder.Close()
+		<-stopped
+	}
+	forwarder.Close()
+
+	if geoIP != nil {
+		geoIP.Close()
+	}
+	plugins.Close()
+	metrics.Close()
+	logger.Info("LogLynx agent stopped gracefully")
+}
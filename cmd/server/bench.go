@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"loglynx/internal/bench"
+	"loglynx/internal/config"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/ingestion"
+
+	"github.com/pterm/pterm"
+)
+
+// runBench replays a sample log through the ingestion pipeline into a temporary database and prints
+// throughput, flush latency and memory, returning the exit code
+// Batching, workers, index profile and GeoIP databases come from the configuration, so settings can be compared.
+func runBench(cfg *config.Config, args []string, logger *pterm.Logger) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	sampleFile := flags.String("sample", "", "Sample log file replayed in a loop (default: generated Traefik JSON demo traffic)")
+	parserType := flags.String("parser", "traefik", "Parser of the sample: traefik, iis, cri+traefik...")
+	lines := flags.Int("lines", 100000, "Lines to replay")
+	rate := flags.Int("rate", 0, "Lines per second, 0 = write all lines before ingestion starts (initial import)")
+	geoIP := flags.Bool("geoip", cfg.GeoIP.Enabled, "Enrich with the configured GeoIP databases")
+	timeout := flags.Duration("timeout", bench.DefaultTimeout, "Wait for ingestion to catch up after the last write")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var sample []string
+	var err error
+	if *sampleFile != "" {
+		sample, err = bench.LoadSample(*sampleFile)
+	} else {
+		sample, err = bench.DemoSample(min(*lines, 20000))
+	}
+	if err != nil {
+		logger.Error("Failed to load the sample", logger.Args("error", err))
+		return 1
+	}
+
+	dir, err := os.MkdirTemp("", "loglynx-bench-*")
+	if err != nil {
+		logger.Error("Failed to create the work directory", logger.Args("error", err))
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	indexProfile, err := repositories.ParseIndexProfile(cfg.Database.IndexProfile)
+	if err != nil {
+		logger.Warn("Invalid index profile, using default (analytics)", logger.Args("error", err))
+	}
+	opts := bench.Options{
+		Sample:     sample,
+		ParserType: *parserType,
+		Lines:      *lines,
+		Rate:       *rate,
+		Dir:        dir,
+		Batching: ingestion.BatchSettings{
+			Adaptive: cfg.Performance.AdaptiveBatching,
+			MinSize:  cfg.Performance.BatchSizeMin,
+			MaxSize:  cfg.Performance.BatchSize,
+			MinFlush: cfg.Performance.BatchFlushMin,
+			MaxFlush: cfg.Performance.BatchFlushMax,
+		},
+		Workers:      cfg.Performance.WorkerPoolSize,
+		IndexProfile: indexProfile,
+		SearchIndex:  cfg.Database.SearchIndexEnabled,
+		Timeout:      *timeout,
+	}
+	if *geoIP {
+		providers, err := enrichment.ParseGeoIPProviders(cfg.GeoIP.Providers)
+		if err != nil {
+			logger.Error("Invalid GeoIP providers", logger.Args("error", err))
+			return 1
+		}
+		opts.GeoIPProviders = providers
+		opts.GeoIPDatabases = geoIPDatabases(cfg)
+	}
+
+	logger.Info("Replaying sample through the ingestion pipeline...",
+		logger.Args("sample_lines", len(sample), "lines", opts.Lines, "rate", opts.Rate, "parser", opts.ParserType, "workers", opts.Workers))
+
+	// Pipeline logs would dominate the run and the output, only warnings are kept
+	result, err := bench.Run(opts, logger.WithLevel(pterm.LogLevelWarn))
+	if err != nil {
+		logger.Error("Benchmark failed", logger.Args("error", err))
+		return 1
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Metric", "Value"},
+		{"Lines", fmt.Sprint(result.Lines)},
+		{"Stored", fmt.Sprint(result.Stored)},
+		{"Elapsed", result.Elapsed.Round(time.Millisecond).String()},
+		{"Lines/sec", fmt.Sprintf("%.0f", result.LinesPerSec)},
+		{"Catch-up after last write", result.CatchUp.Round(time.Millisecond).String()},
+		{"Flushes", fmt.Sprint(result.Flushes)},
+		{"Flush p50", result.FlushP50.Round(time.Microsecond).String()},
+		{"Flush p99", result.FlushP99.Round(time.Microsecond).String()},
+		{"Flush max", result.FlushMax.Round(time.Microsecond).String()},
+		{"Peak heap", fmt.Sprintf("%.1f MB", float64(result.PeakHeapBytes)/(1024*1024))},
+		{"Allocated", fmt.Sprintf("%.1f MB", float64(result.TotalAllocBytes)/(1024*1024))},
+		{"GC cycles", fmt.Sprint(result.GCCycles)},
+		{"Database size", fmt.Sprintf("%.1f MB", float64(result.DatabaseBytes)/(1024*1024))},
+	}).Render()
+
+	if result.Stored < result.Lines {
+		logger.Warn("Some sample lines were not stored (unparsable lines are skipped)", logger.Args("skipped", result.Lines-result.Stored))
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+
+	"loglynx/internal/config"
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/diagnostics"
+
+	"github.com/pterm/pterm"
+)
+
+// runCheck prints the diagnostics report and returns the exit code, 1 when a check failed
+// The database is opened read-only (and only if it exists): no migration or discovery runs.
+func runCheck(cfg *config.Config, logger *pterm.Logger) int {
+	var sources []*models.LogSource
+	if _, err := os.Stat(cfg.Database.Path); err == nil {
+		db, err := database.NewReadOnlyConnection(&database.Config{
+			Path:        cfg.Database.Path,
+			ConnMaxLife: cfg.Database.ConnMaxLife,
+		}, logger)
+		if err == nil {
+			sources, err = repositories.NewLogSourceRepository(db).FindAll()
+		}
+		if err != nil {
+			logger.Warn("Failed to read the registered log sources, checking the configured ones", logger.Args("error", err))
+		}
+	}
+
+	report := diagnostics.Run(cfg, sources)
+	report.Print()
+
+	failed, warnings := report.Count(diagnostics.StatusFail), report.Count(diagnostics.StatusWarn)
+	if failed > 0 {
+		logger.Error("Configuration check failed", logger.Args("failed", failed, "warnings", warnings))
+		return 1
+	}
+	logger.Info("✅ Configuration check passed", logger.Args("checks", len(report.Checks), "warnings", warnings))
+	return 0
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"loglynx/internal/config"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/demo"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// seedDemoData generates the synthetic demo traffic into the database
+// With onlyIfEmpty, nothing is generated when demo requests are already stored (SEED_DEMO_DATA on every start).
+func seedDemoData(cfg *config.Config, db *gorm.DB, indexProfile repositories.IndexProfile, onlyIfEmpty bool, logger *pterm.Logger) error {
+	repo := repositories.NewHTTPRequestRepository(db, logger, indexProfile)
+	if onlyIfEmpty {
+		count, err := repo.CountBySourceName(demo.SourceName)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			logger.Debug("Demo data already generated", logger.Args("requests", count))
+			return nil
+		}
+	}
+
+	opts := demo.DefaultOptions()
+	opts.Days = cfg.Demo.Days
+	opts.RequestsPerDay = cfg.Demo.RequestsPerDay
+
+	logger.Info("Generating demo traffic...", logger.Args("days", opts.Days, "requests_per_day", opts.RequestsPerDay, "source", demo.SourceName))
+	start := time.Now()
+	stored, err := demo.Seed(repo, opts, logger)
+	if err != nil {
+		return err
+	}
+	logger.Info("✅ Demo traffic generated", logger.Args("requests", stored, "elapsed", time.Since(start).Round(time.Millisecond)))
+	return nil
+}
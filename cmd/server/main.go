@@ -2,27 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
-	"os/signal"
 	"runtime"
-	"syscall"
 	"time"
 
+	"loglynx/internal/alerting"
 	"loglynx/internal/api"
 	"loglynx/internal/api/handlers"
+	"loglynx/internal/api/rpc"
 	"loglynx/internal/banner"
 	"loglynx/internal/config"
 	"loglynx/internal/database"
 	"loglynx/internal/database/repositories"
+	"loglynx/internal/diagnostics"
 	"loglynx/internal/discovery"
 	"loglynx/internal/enrichment"
+	"loglynx/internal/heartbeat"
 	"loglynx/internal/ingestion"
 	parsers "loglynx/internal/parser"
+	"loglynx/internal/plugin"
 	"loglynx/internal/realtime"
+	"loglynx/internal/remotewrite"
+	"loglynx/internal/statsd"
+	"loglynx/internal/transform"
+	"loglynx/internal/version"
 
 	"strings"
 
 	"github.com/pterm/pterm"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -37,6 +46,10 @@ func main() {
 	// Print banner
 	banner.Print()
 
+	// Listen for shutdown requests (signals, or the service control manager on Windows)
+	// Started before loading the configuration: Windows services must chdir to find .env
+	shutdownRequested := startServiceControl(logger)
+
 	logger.Info("Initializing LogLynx - Fast Log Analytics...",
 		logger.Args("cpu_cores", runtime.NumCPU(), "gomaxprocs", runtime.GOMAXPROCS(0)))
 
@@ -69,6 +82,22 @@ func main() {
 	logger = pterm.DefaultLogger.WithLevel(ptermLevel)
 	logger.Debug("Log level set", logger.Args("level", lvl))
 
+	// "loglynx check" validates the configuration, database, log sources and GeoIP databases and exits
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(cfg, logger))
+	}
+
+	// "loglynx bench" replays a sample log through the ingestion pipeline into a temporary database and exits
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(cfg, os.Args[2:], logger))
+	}
+
+	// "loglynx migrate" applies pending migrations without size limit and exits, for large databases
+	migrateOnly := len(os.Args) > 1 && os.Args[1] == "migrate"
+	if migrateOnly {
+		cfg.Database.MigrationMaxRows = 0
+	}
+
 	logger.Debug("Configuration loaded",
 		logger.Args(
 			"db_path", cfg.Database.Path,
@@ -76,6 +105,22 @@ func main() {
 			"geoip_enabled", cfg.GeoIP.Enabled,
 		))
 
+	// Query counts and latencies per API endpoint (/api/v1/system/query-stats)
+	queryStats := database.NewQueryStats(cfg.Database.SlowQueryThreshold)
+
+	// Build slow request thresholds (global + per-service overrides), the slow requests index depends on them
+	slowPolicy, err := repositories.ParseSlowPolicy(cfg.Analytics.SlowRequestThresholdMs, cfg.Analytics.ServiceSlowRequestThresholds)
+	if err != nil {
+		logger.Warn("Invalid slow request threshold configuration, using default (1000ms)", logger.Args("error", err))
+		slowPolicy = repositories.NewDefaultSlowPolicy()
+	}
+
+	// Performance indexes created on http_requests (fewer indexes = faster inserts)
+	indexProfile, err := repositories.ParseIndexProfile(cfg.Database.IndexProfile)
+	if err != nil {
+		logger.Warn("Invalid index profile, using default (analytics)", logger.Args("error", err))
+	}
+
 	// Initialize database connection with configured settings
 	db, err := database.NewConnection(&database.Config{
 		Path:         cfg.Database.Path,
@@ -83,55 +128,139 @@ func main() {
 		MaxIdleConns: cfg.Database.MaxIdleConns,
 		ConnMaxLife:  cfg.Database.ConnMaxLife,
 
+		// Query Performance
+		SlowQueryThreshold: cfg.Database.SlowQueryThreshold,
+		QueryStats:         queryStats,
+
+		// Full-text search
+		SearchIndex: cfg.Database.SearchIndexEnabled,
+
+		// Slow requests partial index threshold
+		SlowPolicy: slowPolicy,
+
+		// Performance indexes
+		IndexProfile: indexProfile,
+
+		// Schema migrations
+		MigrationsDryRun:  cfg.Database.MigrationsDryRun,
+		MigrationBackup:   cfg.Database.MigrationBackup,
+		MigrationMaxRows:  int64(cfg.Database.MigrationMaxRows),
+		RollbackToVersion: cfg.Database.RollbackToVersion,
+
 		// Pool Monitoring
 		PoolMonitoringEnabled:   cfg.Database.PoolMonitoringEnabled,
 		PoolMonitoringInterval:  cfg.Database.PoolMonitoringInterval,
 		PoolSaturationThreshold: cfg.Database.PoolSaturationThreshold,
 		AutoTuning:              cfg.Database.AutoTuning,
 	}, logger)
+	if errors.Is(err, database.ErrMigrationDryRun) || errors.Is(err, database.ErrSchemaRolledBack) {
+		logger.Info("Exiting without starting", logger.Args("reason", err))
+		os.Exit(0)
+	}
 	if err != nil {
 		logger.WithCaller().Fatal("Failed to connect to database", logger.Args("error", err))
 	}
+	if migrateOnly {
+		version, _ := database.CurrentSchemaVersion(db)
+		logger.Info("✅ Database migrations applied", logger.Args("schema_version", version))
+		os.Exit(0)
+	}
+
+	// "loglynx demo" generates synthetic traffic (source "demo") to explore the dashboards without real logs, and exits
+	if len(os.Args) > 1 && os.Args[1] == "demo" {
+		if err := seedDemoData(cfg, db, indexProfile, false, logger); err != nil {
+			logger.WithCaller().Fatal("Failed to generate demo traffic", logger.Args("error", err))
+		}
+		os.Exit(0)
+	}
+
+	// Report misconfiguration in one place instead of scattered runtime warnings
+	registeredSources, err := repositories.NewLogSourceRepository(db).FindAll()
+	if err != nil {
+		logger.Warn("Failed to list log sources for startup diagnostics", logger.Args("error", err))
+	}
+	diagnostics.Run(cfg, registeredSources).Log(logger)
+
+	// Agent mode: discover, tail and parse here, the central server stores and serves the requests
+	if cfg.Agent.ServerURL != "" {
+		runAgent(cfg, db, indexProfile, shutdownRequested, logger)
+		finishServiceControl()
+		return
+	}
+
+	// Generate demo traffic on the first start when requested (SEED_DEMO_DATA=true)
+	if cfg.Demo.Seed {
+		if err := seedDemoData(cfg, db, indexProfile, true, logger); err != nil {
+			logger.WithCaller().Error("Failed to generate demo traffic", logger.Args("error", err))
+		}
+	}
+
+	// Databases of other hosts merged into the analytics, read-only
+	attached, err := database.ParseAttachedDatabases(cfg.Database.AttachDatabases)
+	if err != nil {
+		logger.WithCaller().Fatal("Invalid ATTACH_DATABASES", logger.Args("error", err))
+	}
+	for _, attachedDB := range attached {
+		logger.Info("Attaching database", logger.Args("source_host", attachedDB.Name, "path", attachedDB.Path))
+	}
+
+	// Separate read-only pool for dashboard and stats queries (ingestion keeps the writer pool)
+	readDB, err := database.NewReadOnlyConnection(&database.Config{
+		Path:             cfg.Database.Path,
+		ConnMaxLife:      cfg.Database.ConnMaxLife,
+		ReadMaxOpenConns: cfg.Database.ReadMaxOpenConns,
+		Attached:         attached,
+
+		SlowQueryThreshold: cfg.Database.SlowQueryThreshold,
+		QueryStats:         queryStats,
+	}, logger)
+	if err != nil {
+		logger.WithCaller().Fatal("Failed to open read-only database connection", logger.Args("error", err))
+	}
+
+	// Build failure status code definition (global + per-service overrides)
+	statusPolicy, err := repositories.ParseStatusPolicy(cfg.Analytics.FailureStatusCodes, cfg.Analytics.ServiceFailureStatusCodes)
+	if err != nil {
+		logger.Warn("Invalid failure status code configuration, using default (400-599)", logger.Args("error", err))
+		statusPolicy = repositories.NewDefaultStatusPolicy()
+	}
+
+	// Build egress cost model (global + per-service prices) for cost estimates
+	costModel, err := repositories.ParseCostModel(cfg.Analytics.EgressCostPerGB, cfg.Analytics.EgressCostCurrency, cfg.Analytics.ServiceEgressCosts)
+	if err != nil {
+		logger.Warn("Invalid egress cost configuration, using default (0.09 USD per GB)", logger.Args("error", err))
+		costModel = repositories.NewDefaultCostModel()
+	}
+
+	// Version extraction rules of clients whose adoption is tracked (e.g. SDK upgrades)
+	clientVersions, err := repositories.ParseClientVersionRules(cfg.Analytics.ClientVersionRules)
+	if err != nil {
+		logger.Warn("Invalid client version rules, client version tracking disabled", logger.Args("error", err))
+		clientVersions = &repositories.ClientVersionRules{}
+	}
+
+	// Request quotas of API consumers (authenticated users and API keys)
+	consumerQuotas, err := repositories.ParseConsumerQuotas(cfg.Analytics.ConsumerQuotas)
+	if err != nil {
+		logger.Warn("Invalid consumer quotas, quota tracking disabled", logger.Args("error", err))
+		consumerQuotas = &repositories.ConsumerQuotas{}
+	}
 
 	// Initialize repositories
 	logger.Debug("Initializing repositories...")
 	sourceRepo := repositories.NewLogSourceRepository(db)
-	httpRepo := repositories.NewHTTPRequestRepository(db, logger)
-	statsRepo := repositories.NewStatsRepository(db, logger)
+	httpRepo := repositories.NewHTTPRequestRepository(db, logger, indexProfile)
+	readHTTPRepo := repositories.NewHTTPRequestRepository(readDB, logger, indexProfile) // Request listing for the dashboard
+	statsRepo := repositories.NewStatsRepository(readDB, logger, cfg.Analytics.DefaultLookbackHours, statusPolicy, slowPolicy, nil)
 
 	// Initialize GeoIP enricher (optional - will work without GeoIP databases)
-	var geoIP *enrichment.GeoIPEnricher
-	if cfg.GeoIP.Enabled {
-		logger.Debug("Initializing GeoIP enricher...")
-		geoIP, err = enrichment.NewGeoIPEnricher(
-			cfg.GeoIP.CityDBPath,
-			cfg.GeoIP.CountryDBPath,
-			cfg.GeoIP.ASNDBPath,
-			db,
-			logger,
-			cfg.Performance.GeoIPCacheSize, // Pass configured cache size
-		)
-		if err != nil {
-			logger.Warn("GeoIP enricher initialization failed, continuing without GeoIP", logger.Args("error", err))
-		} else if geoIP.IsEnabled() {
-			logger.Info("GeoIP enrichment enabled successfully")
-			// Load cache from database in background (non-blocking)
-			go func() {
-				logger.Debug("Loading GeoIP cache in background...")
-				if err := geoIP.LoadCache(); err != nil {
-					logger.Warn("Failed to load GeoIP cache", logger.Args("error", err))
-				} else {
-					logger.Info("GeoIP cache loaded", logger.Args("entries", geoIP.GetCacheSize()))
-				}
-			}()
-		}
-	} else {
-		logger.Info("GeoIP enrichment disabled by configuration")
-	}
+	geoIP := newGeoIPEnricher(cfg, db, logger)
 
 	// Initialize parser registry
 	logger.Debug("Initializing parser registry...")
-	parserRegistry := parsers.NewRegistry(logger)
+	parserRegistry := parsers.NewRegistry(capturedHeaders(cfg, logger), capturedResponseHeaders(cfg, logger), logger)
+	configureApacheLogFormat(cfg, parserRegistry, logger)
+	plugins, pluginEnrichers := loadPlugins(cfg, parserRegistry, logger)
 
 	// Run initial discovery SYNCHRONOUSLY to ensure log sources are found before starting ingestion
 	logger.Info("Discovering log sources...")
@@ -142,24 +271,22 @@ func main() {
 		logger.Info("Log source discovery completed")
 	}
 
-	// Run periodic discovery in background for late-arriving files
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
-		defer ticker.Stop()
+	// Run periodic discovery in background for late-arriving files (and new Kubernetes pods)
+	startPeriodicDiscovery(discoveryEngine, sourceRepo, cfg.LogSources.DiscoveryInterval, logger)
 
-		for range ticker.C {
-			logger.Debug("Running periodic log source discovery...")
-			if err := discoveryEngine.Run(logger); err != nil {
-				logger.Warn("Periodic discovery failed", logger.Args("error", err))
-			} else {
-				// Get updated source count
-				sources, err := sourceRepo.FindAll()
-				if err == nil {
-					logger.Debug("Periodic discovery completed", logger.Args("sources", len(sources)))
-				}
-			}
-		}
-	}()
+	// Initialize real-time metrics collector before ingestion so it can be fed inserted events
+	logger.Info("Initializing real-time metrics collector...")
+	metricsCollector := realtime.NewMetricsCollector(logger, cfg.Performance.RealtimeBufferSize, statusPolicy, nil)
+
+	// Dedup hashing options (line offsets for formats without nanosecond timing)
+	dedupOptions, err := ingestion.ParseDedupOptions(cfg.LogSources.DedupLineOffset, cfg.LogSources.ParserDedupLineOffset)
+	if err != nil {
+		logger.Warn("Invalid dedup line offset configuration, using default (auto)", logger.Args("error", err))
+		dedupOptions = ingestion.NewDefaultDedupOptions()
+	}
+
+	// Counts and timings of ingestion and API calls for Datadog-style stacks (optional)
+	metrics := newStatsDClient(cfg, logger)
 
 	// Initialize ingestion coordinator with initial import limiting and performance config
 	// NOTE: Coordinator is initialized before cleanup service because cleanup needs to pause ingestion during VACUUM
@@ -169,11 +296,26 @@ func main() {
 		httpRepo,
 		parserRegistry,
 		geoIP,
+		metricsCollector, // Feeds real-time metrics without querying the database
+		metrics,          // StatsD counts and timings (nil = disabled)
+		dedupOptions,
+		fieldMappings(cfg, logger),
+		forwardedResolver(cfg, logger),
+		pluginEnrichers,
+		transformRules(cfg, logger),
+		labelRules(cfg, logger),
 		logger,
 		cfg.LogSources.InitialImportDays,
 		cfg.LogSources.InitialImportEnable,
-		cfg.Performance.BatchSize,
+		ingestion.BatchSettings{
+			Adaptive: cfg.Performance.AdaptiveBatching,
+			MinSize:  cfg.Performance.BatchSizeMin,
+			MaxSize:  cfg.Performance.BatchSize,
+			MinFlush: cfg.Performance.BatchFlushMin,
+			MaxFlush: cfg.Performance.BatchFlushMax,
+		},
 		cfg.Performance.WorkerPoolSize,
+		cfg.LogSources.MaxLineLength,
 	)
 
 	// Initialize database cleanup service with coordinator reference for maintenance windows
@@ -186,9 +328,25 @@ func main() {
 		cfg.Database.CleanupTime,
 		cfg.Database.VacuumEnabled,
 		coordinator, // Pass coordinator to enable pause/resume during VACUUM
+		newHealthcheckPinger(cfg.Healthcheck.CleanupURL, "cleanup", cfg, logger),
 	)
 	cleanupService.Start()
 
+	// Keep hourly response time rollups up to date (percentiles of long ranges merge them)
+	rollupService := database.NewRollupService(db, logger, cfg.Database.ResponseTimeRollupInterval)
+	rollupService.Start()
+
+	// Push the rollups of each completed hour to a Prometheus remote-write endpoint (optional)
+	var remoteWriteExporter *remotewrite.Exporter
+	if cfg.RemoteWrite.URL != "" {
+		remoteWriteExporter, err = newRemoteWriteExporter(cfg, statsRepo, logger)
+		if err != nil {
+			logger.Warn("Invalid remote-write configuration, remote-write disabled", logger.Args("error", err))
+		} else {
+			remoteWriteExporter.Start()
+		}
+	}
+
 	// Start ingestion engine
 	logger.Info("Starting ingestion engine...")
 	if err := coordinator.Start(); err != nil {
@@ -203,6 +361,19 @@ func main() {
 	// the coordinator will automatically start processing them
 	coordinator.StartSyncLoop(30 * time.Second)
 
+	// Ping a dead man's switch while ingestion makes progress (optional)
+	watchdog := newIngestionWatchdog(cfg, coordinator, logger)
+
+	// Read WAF audit logs into their own table, correlated with the access log by client IP and path
+	var wafIngester *ingestion.WAFIngester
+	if wafPaths := ingestion.ParseWAFLogPaths(cfg.LogSources.WAFLogPaths); len(wafPaths) > 0 {
+		wafIngester = ingestion.NewWAFIngester(wafPaths, sourceRepo, repositories.NewWAFEventRepository(db), cfg.LogSources.MaxLineLength, logger)
+		if err := wafIngester.Start(); err != nil {
+			logger.WithCaller().Error("Failed to start WAF audit log ingestion", logger.Args("error", err))
+			wafIngester = nil
+		}
+	}
+
 	// Give the ingestion engine a moment to start processing before accepting web requests
 	// This improves initial user experience by ensuring some data is available
 	if coordinator.GetProcessorCount() > 0 {
@@ -211,30 +382,103 @@ func main() {
 		time.Sleep(3 * time.Second)
 	}
 
-	// Initialize real-time metrics collector with configured interval
-	logger.Info("Initializing real-time metrics collector...")
-	metricsCollector := realtime.NewMetricsCollector(db, logger)
+	// Start real-time metrics collector with configured interval
 	metricsCollector.Start(cfg.Performance.RealtimeMetricsInterval)
 
+	// Initialize alerting engine (evaluates rules against the real-time event buffer)
+	var alertRules []alerting.Rule
+	if cfg.Alerting.RulesFile != "" {
+		alertRules, err = alerting.LoadRules(cfg.Alerting.RulesFile)
+		if err != nil {
+			logger.WithCaller().Error("Failed to load alert rules, alerting disabled",
+				logger.Args("file", cfg.Alerting.RulesFile, "error", err))
+			alertRules = nil
+		}
+	}
+	disallowedMethods := alerting.ParseMethods(cfg.Alerting.DisallowedMethods)
+	incidentRepo := repositories.NewIncidentRepository(db)
+	alertEngine := alerting.NewEngine(metricsCollector, statusPolicy, slowPolicy, alertRules, disallowedMethods, alerting.UserAgentChecks{
+		Enabled:           cfg.Alerting.UserAgentChecks,
+		RotationThreshold: cfg.Alerting.UARotationThreshold,
+	}, alerting.TravelChecks{
+		Paths:       alerting.ParsePaths(cfg.Alerting.ImpossibleTravelPaths),
+		Window:      cfg.Alerting.ImpossibleTravelWindow,
+		MaxSpeedKmh: cfg.Alerting.ImpossibleTravelMaxSpeedKmh,
+		Source:      statsRepo,
+	}, alerting.DowntimeChecks{
+		Window:      cfg.Alerting.ZeroTrafficWindow,
+		Weeks:       cfg.Alerting.ZeroTrafficBaselineWeeks,
+		MinExpected: cfg.Alerting.ZeroTrafficMinRequests,
+		Source:      incidentRepo,
+	}, logger)
+	alertEngine.Start(cfg.Alerting.EvaluationInterval)
+
 	// Initialize web server with configured settings
 	logger.Info("Initializing web server...")
-	dashboardHandler := handlers.NewDashboardHandler(statsRepo, httpRepo, logger)
-	realtimeHandler := handlers.NewRealtimeHandler(metricsCollector, logger)
+	dashboardHandler := handlers.NewDashboardHandler(statsRepo, readHTTPRepo, disallowedMethods, costModel, clientVersions, consumerQuotas, logger)
+	realtimeHandler := handlers.NewRealtimeHandler(metricsCollector, handlers.StreamLimits{
+		MaxConnections:      cfg.Server.StreamMaxConnections,
+		MaxConnectionsPerIP: cfg.Server.StreamMaxPerIP,
+		Token:               cfg.Server.StreamToken,
+	}, metrics, logger)
+	alertHandler := handlers.NewAlertHandler(alertEngine, incidentRepo, logger)
+	healthHandler := handlers.NewHealthHandler(
+		db,
+		coordinator,
+		geoIP,
+		cfg.GeoIP.Enabled,
+		logger,
+		cfg.Database.Path,
+		cfg.Server.HealthStallThreshold,
+		cfg.Server.HealthMinFreeDiskMB,
+	)
 	systemHandler := handlers.NewSystemHandler(
 		statsRepo,
-		httpRepo,
+		readHTTPRepo,
 		cleanupService,
+		coordinator,
 		logger,
 		cfg.Database.Path,
 		cfg.Database.RetentionDays,
+		queryStats,
+		indexProfile,
 	)
+
+	// Export and import between instances, only reachable with the sync token
+	var syncHandler *handlers.SyncHandler
+	if cfg.Server.SyncToken != "" {
+		syncHandler = handlers.NewSyncHandler(readHTTPRepo, httpRepo, metricsCollector, cfg.Server.SyncToken, logger)
+	}
+
+	// Maintenance jobs, only reachable with the admin token
+	backfill := enrichment.NewBackfill(db, geoIP, logger, enrichment.DefaultBackfillBatchSize)
+	var adminHandler *handlers.AdminHandler
+	if cfg.Server.AdminToken != "" {
+		adminHandler = handlers.NewAdminHandler(backfill, cfg.Server.AdminToken, logger)
+	}
+
+	// Theme and branding, changed with the admin token
+	settingsHandler := handlers.NewSettingsHandler(repositories.NewSettingsRepository(db), logger)
+
+	// Saved investigations, changed with the admin token
+	investigationHandler := handlers.NewInvestigationHandler(repositories.NewInvestigationRepository(db), logger)
+
 	webServer := api.NewServer(&api.Config{
 		Host:                cfg.Server.Host,
 		Port:                cfg.Server.Port,
 		Production:          cfg.Server.Production,
 		DashboardEnabled:    cfg.Server.DashboardEnabled,
 		SplashScreenEnabled: cfg.Server.SplashScreenEnabled,
-	}, dashboardHandler, realtimeHandler, systemHandler, logger)
+		Locale:              cfg.Server.Locale,
+		LookbackHours:       cfg.Analytics.DefaultLookbackHours,
+		TrustedProxies:      cfg.Server.TrustedProxies,
+		SourceHosts:         len(attached) > 0,
+
+		CORS:                  corsPolicy(cfg, logger),
+		SecurityHeaders:       cfg.Server.SecurityHeaders,
+		ContentSecurityPolicy: cfg.Server.ContentSecurityPolicy,
+		ReferrerPolicy:        cfg.Server.ReferrerPolicy,
+	}, dashboardHandler, realtimeHandler, systemHandler, alertHandler, healthHandler, syncHandler, adminHandler, settingsHandler, investigationHandler, queryStats, metrics, logger)
 
 	// Start web server in goroutine
 	go func() {
@@ -243,28 +487,54 @@ func main() {
 		}
 	}()
 
+	// gRPC API next to the REST API, disabled without GRPC_PORT
+	var grpcServer *rpc.Server
+	if cfg.Server.GRPCPort > 0 {
+		grpcServer = rpc.NewServer(&rpc.Config{
+			Host:        cfg.Server.Host,
+			Port:        cfg.Server.GRPCPort,
+			SyncToken:   cfg.Server.SyncToken,
+			StreamToken: cfg.Server.StreamToken,
+		}, statsRepo, readHTTPRepo, httpRepo, metricsCollector, logger)
+		go func() {
+			if err := grpcServer.Run(); err != nil {
+				logger.WithCaller().Error("gRPC server error", logger.Args("error", err))
+			}
+		}()
+	}
+
 	logger.Info("🐱 LogLynx is running",
 		logger.Args(
 			"url", pterm.Sprintf("http://localhost:%d", cfg.Server.Port),
 			"processors", coordinator.GetProcessorCount(),
 		))
 
-	// Setup graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
 	// Wait for shutdown signal
-	<-sigChan
+	<-shutdownRequested
 
 	logger.Info("Shutdown signal received, stopping services...")
 
 	// Stop ingestion coordinator first (prevents new data writes)
 	logger.Debug("Stopping ingestion coordinator...")
+	if watchdog != nil {
+		watchdog.Stop()
+	}
 	coordinator.Stop()
+	if wafIngester != nil {
+		wafIngester.Stop()
+	}
 
-	// Stop cleanup service
+	// Stop maintenance jobs and the cleanup service
 	logger.Debug("Stopping cleanup service...")
+	backfill.Stop()
 	cleanupService.Stop()
+	rollupService.Stop()
+	if remoteWriteExporter != nil {
+		remoteWriteExporter.Stop()
+	}
+
+	// Stop alerting engine
+	alertEngine.Stop()
 
 	// Create shutdown context with timeout (30s to handle SSE connections gracefully)
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*cfg.Performance.RealtimeMetricsInterval)
@@ -277,11 +547,252 @@ func main() {
 	} else {
 		logger.Info("Web server stopped successfully")
 	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			logger.WithCaller().Error("gRPC server shutdown error", logger.Args("error", err))
+		}
+	}
 
 	// Close GeoIP
 	if geoIP != nil {
 		geoIP.Close()
 	}
 
+	// Stop plugins
+	plugins.Close()
+
+	// Send the last StatsD metrics
+	metrics.Close()
+
 	logger.Info("LogLynx stopped gracefully")
+	finishServiceControl()
+}
+
+// capturedHeaders returns the request headers kept in proxy_metadata, none when the list is invalid
+func capturedHeaders(cfg *config.Config, logger *pterm.Logger) []string {
+	headers, err := parsers.ParseCapturedHeaders(cfg.LogSources.CaptureHeaders)
+	if err != nil {
+		logger.Warn("Invalid captured headers, header capture disabled", logger.Args("error", err))
+		return nil
+	}
+	if len(headers) > 0 {
+		logger.Info("Capturing request headers", logger.Args("headers", strings.Join(headers, ",")))
+	}
+	return headers
+}
+
+// configureApacheLogFormat applies APACHE_LOG_FORMAT, the stock formats are kept when it is invalid
+func configureApacheLogFormat(cfg *config.Config, registry *parsers.Registry, logger *pterm.Logger) {
+	if err := registry.SetApacheLogFormat(cfg.LogSources.ApacheLogFormat); err != nil {
+		logger.Warn("Invalid APACHE_LOG_FORMAT, using the common, combined and vhost_combined formats", logger.Args("error", err))
+	} else if cfg.LogSources.ApacheLogFormat != "" {
+		logger.Debug("Apache log format", logger.Args("format", cfg.LogSources.ApacheLogFormat))
+	}
+}
+
+// capturedResponseHeaders returns the response headers kept in proxy_metadata, none when the list is invalid
+func capturedResponseHeaders(cfg *config.Config, logger *pterm.Logger) []string {
+	headers, err := parsers.ParseCapturedHeaders(cfg.LogSources.CaptureResponseHeaders)
+	if err != nil {
+		logger.Warn("Invalid captured response headers, response header capture disabled", logger.Args("error", err))
+		return nil
+	}
+	if len(headers) > 0 {
+		logger.Debug("Capturing response headers", logger.Args("headers", strings.Join(headers, ",")))
+	}
+	return headers
+}
+
+// fieldMappings returns the field overrides per source, none when the configuration is invalid
+func fieldMappings(cfg *config.Config, logger *pterm.Logger) *ingestion.FieldMappings {
+	mappings, err := ingestion.ParseFieldMappings(cfg.LogSources.FieldMappings)
+	if err != nil {
+		logger.Warn("Invalid field mappings, using the parsed fields", logger.Args("error", err))
+		return nil
+	}
+	return mappings
+}
+
+// forwardedResolver returns the client resolution behind trusted proxies, nil when disabled or invalid
+func forwardedResolver(cfg *config.Config, logger *pterm.Logger) *ingestion.ForwardedResolver {
+	resolver, err := ingestion.ParseForwardedResolver(cfg.LogSources.TrustedProxies, cfg.LogSources.ForwardedProxyDepth)
+	if err != nil {
+		logger.Warn("Invalid trusted proxies, X-Forwarded-For resolution disabled", logger.Args("error", err))
+		return nil
+	}
+	return resolver
+}
+
+// corsPolicy returns the origins allowed to call the API from browsers, none (same origin only) when invalid
+func corsPolicy(cfg *config.Config, logger *pterm.Logger) *api.CORSPolicy {
+	policy, err := api.ParseCORSPolicy(cfg.Server.CORSAllowedOrigins, cfg.Server.CORSAllowedMethods, cfg.Server.CORSAllowedHeaders, cfg.Server.CORSAllowCredentials)
+	if err != nil {
+		logger.Warn("Invalid CORS settings, cross-origin requests disabled", logger.Args("error", err))
+		return nil
+	}
+	return policy
+}
+
+// transformRules loads the transform rules, none when the file is invalid
+func transformRules(cfg *config.Config, logger *pterm.Logger) *transform.Rules {
+	if cfg.LogSources.TransformRulesFile == "" {
+		return nil
+	}
+	rules, err := transform.LoadRules(cfg.LogSources.TransformRulesFile)
+	if err != nil {
+		logger.WithCaller().Error("Failed to load transform rules, requests are stored unchanged",
+			logger.Args("file", cfg.LogSources.TransformRulesFile, "error", err))
+		return nil
+	}
+	logger.Info("Loaded transform rules", logger.Args("file", cfg.LogSources.TransformRulesFile, "rules", rules.Len()))
+	return rules
+}
+
+// labelRules returns the label rules, none when the configuration is invalid
+func labelRules(cfg *config.Config, logger *pterm.Logger) *ingestion.LabelRules {
+	rules, err := ingestion.ParseLabelRules(cfg.LogSources.LabelRules)
+	if err != nil {
+		logger.Warn("Invalid label rules, requests are not labeled", logger.Args("error", err))
+		return nil
+	}
+	return rules
+}
+
+// loadPlugins starts the configured plugins and registers their parsers, returning their enrichers for ingestion
+func loadPlugins(cfg *config.Config, registry *parsers.Registry, logger *pterm.Logger) (*plugin.Set, []ingestion.Enricher) {
+	plugins := plugin.Load(plugin.ParseCommands(cfg.Plugins.Commands), cfg.Plugins.Timeout, version.Version, logger)
+	plugins.Register(registry)
+
+	var enrichers []ingestion.Enricher
+	for _, enricher := range plugins.Enrichers {
+		enrichers = append(enrichers, enricher)
+	}
+	return plugins, enrichers
+}
+
+// newGeoIPEnricher opens the configured GeoIP databases, nil when GeoIP is disabled
+func newGeoIPEnricher(cfg *config.Config, db *gorm.DB, logger *pterm.Logger) *enrichment.GeoIPEnricher {
+	if !cfg.GeoIP.Enabled {
+		logger.Info("GeoIP enrichment disabled by configuration")
+		return nil
+	}
+
+	providers, err := enrichment.ParseGeoIPProviders(cfg.GeoIP.Providers)
+	if err != nil {
+		logger.Warn("Invalid GeoIP providers, continuing without GeoIP", logger.Args("error", err))
+		return nil
+	}
+
+	logger.Debug("Initializing GeoIP enricher...", logger.Args("providers", strings.Join(providers, ",")))
+	geoIP, err := enrichment.NewGeoIPEnricher(
+		enrichment.OpenGeoIPProviders(providers, geoIPDatabases(cfg), logger),
+		db,
+		logger,
+		cfg.Performance.GeoIPCacheSize, // Pass configured cache size
+	)
+	if err != nil {
+		logger.Warn("GeoIP enricher initialization failed, continuing without GeoIP", logger.Args("error", err))
+		return geoIP
+	}
+	if geoIP.IsEnabled() {
+		logger.Info("GeoIP enrichment enabled successfully")
+		// Load cache from database in background (non-blocking)
+		go func() {
+			logger.Debug("Loading GeoIP cache in background...")
+			if err := geoIP.LoadCache(); err != nil {
+				logger.Warn("Failed to load GeoIP cache", logger.Args("error", err))
+			} else {
+				logger.Info("GeoIP cache loaded", logger.Args("entries", geoIP.GetCacheSize()))
+			}
+		}()
+	}
+	return geoIP
+}
+
+// geoIPDatabases returns the configured database paths of every GeoIP provider
+func geoIPDatabases(cfg *config.Config) enrichment.GeoIPDatabases {
+	return enrichment.GeoIPDatabases{
+		CityDB:        cfg.GeoIP.CityDBPath,
+		CountryDB:     cfg.GeoIP.CountryDBPath,
+		ASNDB:         cfg.GeoIP.ASNDBPath,
+		DBIPCityDB:    cfg.GeoIP.DBIPCityDBPath,
+		DBIPASNDB:     cfg.GeoIP.DBIPASNDBPath,
+		IPinfoDB:      cfg.GeoIP.IPinfoDBPath,
+		IP2LocationDB: cfg.GeoIP.IP2LocationDBPath,
+	}
+}
+
+// newStatsDClient creates the StatsD client from the configuration, nil when StatsD is disabled
+func newStatsDClient(cfg *config.Config, logger *pterm.Logger) *statsd.Client {
+	if cfg.StatsD.Address == "" {
+		return nil
+	}
+	metrics, err := statsd.New(cfg.StatsD.Address, cfg.StatsD.Prefix, statsd.ParseTags(cfg.StatsD.Tags), cfg.StatsD.DogStatsD, logger)
+	if err != nil {
+		logger.Warn("Invalid StatsD configuration, StatsD disabled", logger.Args("error", err))
+		return nil
+	}
+	logger.Info("StatsD metrics enabled", logger.Args("address", cfg.StatsD.Address, "dogstatsd", cfg.StatsD.DogStatsD))
+	return metrics
+}
+
+// newHealthcheckPinger creates the pinger of a healthcheck URL, nil when the URL is empty or invalid
+func newHealthcheckPinger(pingURL string, job string, cfg *config.Config, logger *pterm.Logger) *heartbeat.Pinger {
+	if pingURL == "" {
+		return nil
+	}
+	pinger, err := heartbeat.NewPinger(pingURL, job, cfg.Healthcheck.Timeout, logger)
+	if err != nil {
+		logger.Warn("Invalid healthcheck configuration, ping disabled", logger.Args("error", err))
+		return nil
+	}
+	return pinger
+}
+
+// newIngestionWatchdog starts pinging HEALTHCHECK_INGESTION_URL while ingestion progresses, nil when it isn't set
+func newIngestionWatchdog(cfg *config.Config, coordinator *ingestion.Coordinator, logger *pterm.Logger) *ingestion.Watchdog {
+	pinger := newHealthcheckPinger(cfg.Healthcheck.IngestionURL, "ingestion", cfg, logger)
+	if pinger == nil {
+		return nil
+	}
+	watchdog := ingestion.NewWatchdog(coordinator, pinger, cfg.Healthcheck.Interval, cfg.Server.HealthStallThreshold, logger)
+	watchdog.Start()
+	return watchdog
+}
+
+// newRemoteWriteExporter creates the exporter of the service rollups from the remote-write configuration
+func newRemoteWriteExporter(cfg *config.Config, statsRepo repositories.StatsRepository, logger *pterm.Logger) (*remotewrite.Exporter, error) {
+	labels, err := remotewrite.ParseLabels(cfg.RemoteWrite.Labels)
+	if err != nil {
+		return nil, err
+	}
+	client, err := remotewrite.NewClient(cfg.RemoteWrite.URL, cfg.RemoteWrite.Username, cfg.RemoteWrite.Password, cfg.RemoteWrite.BearerToken, cfg.RemoteWrite.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return remotewrite.NewExporter(statsRepo, client, labels, cfg.Database.ResponseTimeRollupInterval, logger), nil
+}
+
+// startPeriodicDiscovery looks for new log sources in the background
+func startPeriodicDiscovery(engine *discovery.Engine, sourceRepo repositories.LogSourceRepository, interval time.Duration, logger *pterm.Logger) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			logger.Debug("Running periodic log source discovery...")
+			if err := engine.Run(logger); err != nil {
+				logger.Warn("Periodic discovery failed", logger.Args("error", err))
+			} else {
+				// Get updated source count
+				sources, err := sourceRepo.FindAll()
+				if err == nil {
+					logger.Debug("Periodic discovery completed", logger.Args("sources", len(sources)))
+				}
+			}
+		}
+	}()
 }
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pterm/pterm"
+)
+
+// startServiceControl returns a channel closed when an interrupt or termination signal is received
+func startServiceControl(logger *pterm.Logger) <-chan struct{} {
+	stop := make(chan struct{})
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		logger.Debug("Received signal", logger.Args("signal", sig.String()))
+		close(stop)
+	}()
+
+	return stop
+}
+
+// finishServiceControl is called once shutdown is complete (nothing to report outside Windows services)
+func finishServiceControl() {}
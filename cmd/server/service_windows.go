@@ -0,0 +1,147 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// serviceName is the Windows service and Event Log source name
+// Install with: sc.exe create LogLynx binPath= "C:\LogLynx\loglynx.exe" start= auto
+const serviceName = "LogLynx"
+
+// windowsService reports LogLynx's state to the service control manager
+type windowsService struct {
+	stop     chan struct{}
+	finished chan struct{} // Closed once shutdown is complete
+	exited   chan struct{} // Closed once the stopped state was reported
+	elog     *eventlog.Log
+}
+
+var activeService *windowsService
+
+// startServiceControl returns a channel closed when shutdown is requested
+// When started by the service control manager, stop/shutdown requests close it and lifecycle
+// events go to the Windows Event Log; otherwise Ctrl+C does.
+func startServiceControl(logger *pterm.Logger) <-chan struct{} {
+	stop := make(chan struct{})
+
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		logger.Warn("Failed to detect whether running as a Windows service", logger.Args("error", err))
+	}
+
+	if !isService {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-sigChan
+			logger.Debug("Received signal", logger.Args("signal", sig.String()))
+			close(stop)
+		}()
+		return stop
+	}
+
+	// Services start in C:\Windows\System32: resolve .env and relative paths next to the executable
+	if exe, err := os.Executable(); err == nil {
+		if err := os.Chdir(filepath.Dir(exe)); err != nil {
+			logger.Warn("Failed to change to executable directory", logger.Args("error", err))
+		}
+	}
+
+	// Registering the Event Log source fails if it already exists (or without admin rights when debugging)
+	_ = eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info)
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		logger.Warn("Failed to open Windows Event Log", logger.Args("error", err))
+	}
+
+	activeService = &windowsService{
+		stop:     stop,
+		finished: make(chan struct{}),
+		exited:   make(chan struct{}),
+		elog:     elog,
+	}
+
+	// The control dispatcher must be running shortly after start, before initialization completes
+	go func() {
+		defer close(activeService.exited)
+		if err := svc.Run(serviceName, activeService); err != nil {
+			activeService.event(eventlog.Error, fmt.Sprintf("Service failed: %v", err))
+			logger.WithCaller().Error("Windows service failed", logger.Args("error", err))
+		}
+	}()
+
+	logger.Info("Running as Windows service", logger.Args("name", serviceName))
+	return stop
+}
+
+// finishServiceControl reports the service as stopped once shutdown is complete
+func finishServiceControl() {
+	if activeService == nil {
+		return
+	}
+
+	activeService.event(eventlog.Info, "LogLynx stopped")
+	close(activeService.finished)
+
+	// Let the dispatcher report the stopped state before the process exits
+	select {
+	case <-activeService.exited:
+	case <-time.After(5 * time.Second):
+	}
+
+	if activeService.elog != nil {
+		activeService.elog.Close()
+	}
+}
+
+// Execute handles service control requests (svc.Handler)
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	s.event(eventlog.Info, "LogLynx started")
+
+	for {
+		select {
+		case <-s.finished:
+			// Stopped on its own (e.g. fatal error path returned from main)
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				s.event(eventlog.Info, "LogLynx stopping")
+				status <- svc.Status{State: svc.StopPending}
+				close(s.stop)
+				<-s.finished
+				return false, 0
+			}
+		}
+	}
+}
+
+// event writes a message to the Windows Event Log if it is available
+func (s *windowsService) event(level uint16, message string) {
+	if s.elog == nil {
+		return
+	}
+
+	switch level {
+	case eventlog.Error:
+		s.elog.Error(1, message)
+	case eventlog.Warning:
+		s.elog.Warning(1, message)
+	default:
+		s.elog.Info(1, message)
+	}
+}
@@ -0,0 +1,238 @@
+// Package agent forwards parsed requests to a central LogLynx server instead of storing them
+// An agent discovers, tails and parses its log files like a standalone instance, its local database only
+// keeps the source positions. Each batch is posted to the central /api/v1/sync/import endpoint and its
+// position is saved once the central server stored it: after an outage or a restart the agent resends the
+// lines not acknowledged yet, and the central server skips the ones it already has (same request_hash).
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/transfer"
+	"loglynx/internal/version"
+
+	"github.com/pterm/pterm"
+)
+
+// importPath is the endpoint of the central server storing forwarded batches
+const importPath = "/api/v1/sync/import"
+
+// maxErrorBody bounds the error response read from the central server
+const maxErrorBody = 4096
+
+// errRejected marks responses retrying cannot fix (the batch itself is refused)
+var errRejected = errors.New("rejected by the central server")
+
+// Forwarder is the request repository of an agent: batches go to the central server, positions to the local database
+// Other methods are served by the local repository, which stays empty.
+type Forwarder struct {
+	repositories.HTTPRequestRepository
+	sourceRepo repositories.LogSourceRepository
+	client     *http.Client
+	importURL  string
+	token      string
+	instance   string
+	maxBackoff time.Duration
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     *pterm.Logger
+}
+
+// NewForwarder creates the repository of an agent forwarding to serverURL with the central SYNC_TOKEN
+// sourcePrefix is prepended to source names on the central server (default: hostname + "/").
+func NewForwarder(local repositories.HTTPRequestRepository, sourceRepo repositories.LogSourceRepository, serverURL string, token string, sourcePrefix string, timeout time.Duration, maxBackoff time.Duration, logger *pterm.Logger) (*Forwarder, error) {
+	server, err := url.Parse(strings.TrimRight(serverURL, "/"))
+	if err != nil || (server.Scheme != "http" && server.Scheme != "https") || server.Host == "" {
+		return nil, fmt.Errorf("invalid central server URL %q: expected http(s)://host[:port]", serverURL)
+	}
+	if token == "" {
+		return nil, errors.New("the central server token is required")
+	}
+
+	instance, _ := os.Hostname()
+	if sourcePrefix == "" {
+		sourcePrefix = instance + "/"
+	}
+	if maxBackoff < time.Second {
+		maxBackoff = time.Second
+	}
+
+	importURL := server.JoinPath(importPath)
+	importURL.RawQuery = url.Values{"source_prefix": {sourcePrefix}}.Encode()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Forwarder{
+		HTTPRequestRepository: local,
+		sourceRepo:            sourceRepo,
+		client:                &http.Client{Timeout: timeout},
+		importURL:             importURL.String(),
+		token:                 token,
+		instance:              instance,
+		maxBackoff:            maxBackoff,
+		ctx:                   ctx,
+		cancel:                cancel,
+		logger:                logger,
+	}, nil
+}
+
+// Close aborts the batches still waiting for the central server with ErrWriteAborted, their positions aren't saved
+func (f *Forwarder) Close() {
+	f.cancel()
+}
+
+// Create forwards a single request
+func (f *Forwarder) Create(request *models.HTTPRequest) error {
+	_, err := f.CreateBatch([]*models.HTTPRequest{request})
+	return err
+}
+
+// CreateBatch forwards requests without source position
+func (f *Forwarder) CreateBatch(requests []*models.HTTPRequest) (*repositories.BatchResult, error) {
+	return f.CreateBatchWithPosition(requests, nil)
+}
+
+// CreateBatchWithPosition forwards requests, then saves the position of their source locally
+func (f *Forwarder) CreateBatchWithPosition(requests []*models.HTTPRequest, position *repositories.SourcePosition) (*repositories.BatchResult, error) {
+	results, err := f.CreateSourceBatches([]*repositories.SourceBatch{{Requests: requests, Position: position}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CreateSourceBatches forwards each batch and saves its position once the central server stored it
+// Forwarding blocks, retrying with backoff, while the central server is unreachable: readers stop
+// tailing until it is back instead of dropping lines.
+func (f *Forwarder) CreateSourceBatches(batches []*repositories.SourceBatch) ([]*repositories.BatchResult, error) {
+	results := make([]*repositories.BatchResult, len(batches))
+	for i, batch := range batches {
+		results[i] = &repositories.BatchResult{}
+		if len(batch.Requests) > 0 {
+			stored, err := f.send(batch.Requests)
+			if err != nil {
+				return nil, err
+			}
+			results[i].Inserted = int(stored.Inserted)
+			results[i].Duplicates = int(stored.Duplicates)
+		}
+
+		if pos := batch.Position; pos != nil {
+			if err := f.sourceRepo.UpdateTracking(pos.SourceName, pos.Position, pos.Inode, pos.LastLine, pos.Fingerprint); err != nil {
+				return nil, fmt.Errorf("requests forwarded but failed to save the position of %s: %w", pos.SourceName, err)
+			}
+		}
+	}
+	return results, nil
+}
+
+// send posts requests as an archive until the central server stores them, the forwarder is closed
+// or the central server rejects them
+func (f *Forwarder) send(requests []*models.HTTPRequest) (*transfer.ImportResult, error) {
+	body, err := f.encode(requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode requests: %w", err)
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		result, err := f.post(body)
+		if err == nil {
+			if attempt > 1 {
+				f.logger.Info("Central server reachable again", f.logger.Args("requests", len(requests), "attempts", attempt))
+			}
+			return result, nil
+		}
+		if errors.Is(err, errRejected) {
+			return nil, err
+		}
+
+		f.logger.Warn("Failed to forward requests to the central server, retrying",
+			f.logger.Args("requests", len(requests), "attempt", attempt, "retry_in", backoff, "error", err))
+		select {
+		case <-f.ctx.Done():
+			return nil, fmt.Errorf("%w: agent stopped before the central server stored %d requests: %v", repositories.ErrWriteAborted, len(requests), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, f.maxBackoff)
+	}
+}
+
+func (f *Forwarder) encode(requests []*models.HTTPRequest) ([]byte, error) {
+	var body bytes.Buffer
+	writer, err := transfer.NewWriter(&body, transfer.Header{
+		Instance:   f.instance,
+		AppVersion: version.Version,
+		ExportedAt: time.Now(),
+		Agent:      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		if err := writer.WriteRequest(request); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func (f *Forwarder) post(body []byte) (*transfer.ImportResult, error) {
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodPost, f.importURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if json.Unmarshal(data, &failure) != nil || failure.Error == "" {
+			failure.Error = strings.TrimSpace(string(data))
+		}
+		err := fmt.Errorf("central server returned %s: %s", resp.Status, failure.Error)
+		if retryable(resp.StatusCode) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errRejected, err)
+	}
+
+	var result transfer.ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response from the central server: %w", err)
+	}
+	return &result, nil
+}
+
+// retryable tells whether a failed response may succeed later: server errors, rate limiting, and
+// authentication errors an operator fixes on the central server without restarting agents
+func retryable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= 500
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/transfer"
+
+	"github.com/pterm/pterm"
+)
+
+func TestForwarder(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	central := repositories.NewHTTPRequestRepository(fake.NewDB(t), log, "")
+
+	// The central server fails once, the forwarder retries
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer secret" || r.URL.Path != importPath {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		reader, err := transfer.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		result, err := transfer.Import(reader, central, r.URL.Query().Get("source_prefix"), nil)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	local := fake.NewDB(t)
+	sourceRepo := repositories.NewLogSourceRepository(local)
+	if err := sourceRepo.Create(&models.LogSource{Name: "traefik", Path: "/var/log/traefik/access.log", ParserType: "traefik"}); err != nil {
+		t.Fatal(err)
+	}
+	forwarder, err := NewForwarder(repositories.NewHTTPRequestRepository(local, log, ""), sourceRepo, server.URL+"/", "secret", "edge-1/", time.Second, time.Second, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forwarder.Close()
+
+	requests := make([]*models.HTTPRequest, 10)
+	for i := range requests {
+		requests[i] = &models.HTTPRequest{SourceName: "traefik", Timestamp: time.Now(), RequestHash: fmt.Sprint("hash-", i), Method: "GET", Path: "/"}
+	}
+	result, err := forwarder.CreateBatchWithPosition(requests, &repositories.SourcePosition{SourceName: "traefik", Position: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || result.Inserted != len(requests) {
+		t.Fatalf("Expected the batch stored after one retry, got %d calls and %+v", calls, result)
+	}
+
+	source, err := sourceRepo.FindByName("traefik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.LastPosition != 4096 {
+		t.Errorf("Expected the position saved locally, got %d", source.LastPosition)
+	}
+	if count, _ := forwarder.Count(); count != 0 {
+		t.Errorf("Expected no request stored locally, got %d", count)
+	}
+	if count, _ := central.CountBySourceName("edge-1/traefik"); count != int64(len(requests)) {
+		t.Errorf("Expected %d requests on the central server, got %d", len(requests), count)
+	}
+
+	// Rejected batches are not retried
+	calls = 1
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if _, err := forwarder.CreateBatch(requests); err == nil || calls != 2 {
+		t.Errorf("Expected a rejected batch to fail without retry, got %v after %d calls", err, calls)
+	}
+}
+
+func TestForwarder_CloseAbortsWithoutPosition(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	local := fake.NewDB(t)
+	sourceRepo := repositories.NewLogSourceRepository(local)
+	if err := sourceRepo.Create(&models.LogSource{Name: "traefik", Path: "/var/log/traefik/access.log", ParserType: "traefik", LastPosition: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	forwarder, err := NewForwarder(repositories.NewHTTPRequestRepository(local, log, ""), sourceRepo, server.URL, "secret", "", time.Second, time.Second, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutting down while the central server is unreachable gives up on the batch
+	time.AfterFunc(100*time.Millisecond, forwarder.Close)
+	requests := []*models.HTTPRequest{{SourceName: "traefik", Timestamp: time.Now(), RequestHash: "hash", Method: "GET", Path: "/"}}
+	_, err = forwarder.CreateBatchWithPosition(requests, &repositories.SourcePosition{SourceName: "traefik", Position: 4096})
+	if !errors.Is(err, repositories.ErrWriteAborted) {
+		t.Fatalf("Expected ErrWriteAborted, got %v", err)
+	}
+
+	// The lines are resent on the next start
+	source, err := sourceRepo.FindByName("traefik")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.LastPosition != 1024 {
+		t.Errorf("Expected the position kept before the aborted batch, got %d", source.LastPosition)
+	}
+}
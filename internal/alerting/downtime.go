@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+)
+
+const (
+	// DefaultDowntimeWeeks is how many previous weeks the usual traffic of a service is averaged over
+	DefaultDowntimeWeeks = 4
+	// DefaultDowntimeMinExpected is the usual requests per window above which a silent service is down
+	DefaultDowntimeMinExpected = 20
+
+	// zeroTrafficRule names the alerts of open zero-traffic incidents
+	zeroTrafficRule = "zero_traffic"
+)
+
+// DowntimeSource reads service traffic and stores zero-traffic incidents
+type DowntimeSource interface {
+	GetServiceActivity(now time.Time, window time.Duration, weeks int) ([]*repositories.ServiceActivity, error)
+	GetLastRequest(service string, before time.Time) (time.Time, error)
+	GetOpenIncidents() ([]*models.ServiceIncident, error)
+	OpenIncident(incident *models.ServiceIncident) error
+	ResolveIncident(id uint, endedAt time.Time) error
+}
+
+// DowntimeChecks configures zero-traffic detection, disabled without window or source
+// A service is down when it received no request within the window although it usually receives at least
+// MinExpected in the same window of the week (same weekday and time, averaged over Weeks previous weeks).
+type DowntimeChecks struct {
+	Window      time.Duration
+	Weeks       int     // 0 = DefaultDowntimeWeeks
+	MinExpected float64 // 0 = DefaultDowntimeMinExpected
+	Source      DowntimeSource
+}
+
+// detectDowntime opens incidents for normally active services without traffic and resolves those receiving
+// requests again, then fires an alert per open incident
+func (e *Engine) detectDowntime(now time.Time) {
+	checks := e.downtimeChecks
+	if checks.Window <= 0 || checks.Source == nil {
+		return
+	}
+
+	e.recordIncidents(now)
+
+	incidents, err := checks.Source.GetOpenIncidents()
+	if err != nil {
+		e.logger.Warn("Failed to read open incidents", e.logger.Args("error", err))
+		return
+	}
+	e.syncIncidentAlerts(incidents, now)
+}
+
+// recordIncidents compares the traffic of each service in the window with its usual traffic
+func (e *Engine) recordIncidents(now time.Time) {
+	checks := e.downtimeChecks
+	weeks := checks.Weeks
+	if weeks <= 0 {
+		weeks = DefaultDowntimeWeeks
+	}
+	minExpected := checks.MinExpected
+	if minExpected <= 0 {
+		minExpected = DefaultDowntimeMinExpected
+	}
+
+	activity, err := checks.Source.GetServiceActivity(now, checks.Window, weeks)
+	if err != nil {
+		e.logger.Warn("Failed to read service activity for downtime detection", e.logger.Args("error", err))
+		return
+	}
+	// No traffic at all means ingestion stopped (proxy or log file down), not that every service did
+	var total int64
+	for _, service := range activity {
+		total += service.Requests
+	}
+	if total == 0 {
+		return
+	}
+
+	incidents, err := checks.Source.GetOpenIncidents()
+	if err != nil {
+		e.logger.Warn("Failed to read open incidents", e.logger.Args("error", err))
+		return
+	}
+	open := make(map[string]*models.ServiceIncident, len(incidents))
+	for _, incident := range incidents {
+		open[incident.Service] = incident
+	}
+
+	for _, service := range activity {
+		incident := open[service.Service]
+		switch {
+		case service.Requests > 0 && incident != nil:
+			if err := checks.Source.ResolveIncident(incident.ID, service.FirstSeen); err != nil {
+				e.logger.Warn("Failed to resolve incident", e.logger.Args("service", service.Service, "error", err))
+				continue
+			}
+			e.logger.Info("Service receiving traffic again",
+				e.logger.Args("service", service.Service, "duration", service.FirstSeen.Sub(incident.StartedAt).Round(time.Second)))
+
+		case service.Requests == 0 && incident == nil && service.Expected >= minExpected:
+			startedAt, err := checks.Source.GetLastRequest(service.Service, now)
+			if err != nil || startedAt.IsZero() {
+				startedAt = now.Add(-checks.Window)
+			}
+			incident = &models.ServiceIncident{
+				Service:          service.Service,
+				StartedAt:        startedAt,
+				DetectedAt:       now,
+				ExpectedRequests: service.Expected,
+				WindowSeconds:    int(checks.Window.Seconds()),
+			}
+			if err := checks.Source.OpenIncident(incident); err != nil {
+				e.logger.Warn("Failed to record incident", e.logger.Args("service", service.Service, "error", err))
+				continue
+			}
+			e.logger.Warn("🚨 Service without traffic",
+				e.logger.Args("service", service.Service, "expected_requests", service.Expected, "window", checks.Window.String(), "last_request", startedAt))
+		}
+	}
+}
+
+// syncIncidentAlerts fires an alert for each open incident and resolves the alerts of closed ones
+func (e *Engine) syncIncidentAlerts(incidents []*models.ServiceIncident, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	open := make(map[string]bool, len(incidents))
+	for _, incident := range incidents {
+		open[incident.Service] = true
+		alertID := zeroTrafficRule + "|" + incident.Service
+		alert, exists := e.alerts[alertID]
+		if !exists || alert.State == AlertStateResolved {
+			alert = &Alert{
+				Rule:    zeroTrafficRule,
+				Type:    RuleTypeService,
+				Metric:  RuleMetricZeroTraffic,
+				Key:     incident.Service,
+				State:   AlertStateFiring,
+				FiredAt: incident.DetectedAt,
+			}
+			e.alerts[alertID] = alert
+		}
+		alert.Expected = incident.ExpectedRequests
+		alert.UpdatedAt = now
+	}
+
+	for _, alert := range e.alerts {
+		if alert.Rule != zeroTrafficRule || alert.State != AlertStateFiring || open[alert.Key] {
+			continue
+		}
+		resolvedAt := now
+		alert.State = AlertStateResolved
+		alert.ResolvedAt = &resolvedAt
+		alert.UpdatedAt = now
+	}
+}
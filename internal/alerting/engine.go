@@ -0,0 +1,283 @@
+package alerting
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/realtime"
+
+	"github.com/pterm/pterm"
+)
+
+// resolvedRetention is how long resolved alerts stay visible in the API
+const resolvedRetention = 1 * time.Hour
+
+// AlertState represents the lifecycle state of an alert
+type AlertState string
+
+const (
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// EventSource provides recently ingested events
+type EventSource interface {
+	RecentEvents(since time.Time) []realtime.Event
+}
+
+// Alert represents a rule firing for a specific service, path or method
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Type       RuleType   `json:"type"`
+	Metric     RuleMetric `json:"metric"`
+	Key        string     `json:"key"` // Service name, path or method that breached the rule
+	State      AlertState `json:"state"`
+	Requests   int64      `json:"requests"`
+	Errors     int64      `json:"errors"`                      // Failed requests, or slow requests for the slow metric
+	ErrorRate  float64    `json:"error_rate"`                  // percent
+	BurnRate   float64    `json:"burn_rate"`                   // observed burn rate
+	Expected   float64    `json:"expected_requests,omitempty"` // Zero-traffic alerts: usual requests in the window
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Engine periodically evaluates alert rules against recently ingested events
+type Engine struct {
+	source       EventSource
+	statusPolicy *repositories.StatusPolicy
+	slowPolicy   *repositories.SlowPolicy
+	rules        []Rule
+	logger       *pterm.Logger
+
+	disallowedMethods map[string]bool // Methods creating security events
+	userAgentChecks   UserAgentChecks
+	travelChecks      TravelChecks
+	downtimeChecks    DowntimeChecks
+
+	mu             sync.RWMutex
+	alerts         map[string]*Alert         // keyed by rule name + group key
+	securityEvents map[string]*SecurityEvent // keyed by method + client IP + service, type + client IP, or type + user + countries
+	stopChan       chan struct{}
+	running        bool
+}
+
+// NewEngine creates a new alerting engine
+// statusPolicy defines which responses count as errors (same definition as success rates)
+// slowPolicy defines which responses count as slow for rules with the slow metric
+// Requests using one of disallowedMethods, and clients rotating or spoofing user agents, create security events.
+// Services going silent are recorded as incidents with downtimeChecks, each open incident firing an alert.
+func NewEngine(source EventSource, statusPolicy *repositories.StatusPolicy, slowPolicy *repositories.SlowPolicy, rules []Rule, disallowedMethods []string, userAgentChecks UserAgentChecks, travelChecks TravelChecks, downtimeChecks DowntimeChecks, logger *pterm.Logger) *Engine {
+	engine := &Engine{
+		source:            source,
+		statusPolicy:      statusPolicy,
+		slowPolicy:        slowPolicy,
+		rules:             rules,
+		disallowedMethods: make(map[string]bool, len(disallowedMethods)),
+		userAgentChecks:   userAgentChecks,
+		travelChecks:      travelChecks,
+		downtimeChecks:    downtimeChecks,
+		logger:            logger,
+		alerts:            make(map[string]*Alert),
+		securityEvents:    make(map[string]*SecurityEvent),
+		stopChan:          make(chan struct{}),
+	}
+	for _, method := range disallowedMethods {
+		engine.disallowedMethods[strings.ToUpper(method)] = true
+	}
+	return engine
+}
+
+// Start begins evaluating rules at the given interval
+func (e *Engine) Start(interval time.Duration) {
+	if len(e.rules) == 0 && len(e.disallowedMethods) == 0 && !e.userAgentChecks.Enabled && len(e.travelChecks.Paths) == 0 && e.downtimeChecks.Window <= 0 {
+		e.logger.Info("No alert rules, disallowed methods, user agent, impossible travel or downtime checks configured, alerting engine not started")
+		return
+	}
+
+	e.running = true
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stopChan:
+				return
+			case <-ticker.C:
+				e.Evaluate()
+			}
+		}
+	}()
+
+	e.logger.Info("Alerting engine started",
+		e.logger.Args("rules", len(e.rules), "disallowed_methods", len(e.disallowedMethods), "user_agent_checks", e.userAgentChecks.Enabled, "travel_paths", len(e.travelChecks.Paths), "downtime_window", e.downtimeChecks.Window.String(), "interval", interval.String()))
+}
+
+// Stop stops the evaluation loop
+func (e *Engine) Stop() {
+	if !e.running {
+		return
+	}
+	close(e.stopChan)
+	e.running = false
+}
+
+// groupCounter tracks requests and errors for a single group key
+type groupCounter struct {
+	requests int64
+	errors   int64
+}
+
+// Evaluate runs all rules once and updates alert states
+func (e *Engine) Evaluate() {
+	now := time.Now()
+
+	for i := range e.rules {
+		rule := &e.rules[i]
+		counters := make(map[string]*groupCounter)
+
+		for _, event := range e.source.RecentEvents(now.Add(-rule.window)) {
+			key := rule.groupKey(event)
+			if key == "" {
+				continue
+			}
+
+			counter, exists := counters[key]
+			if !exists {
+				counter = &groupCounter{}
+				counters[key] = counter
+			}
+			counter.requests++
+			if e.consumesBudget(rule, event) {
+				counter.errors++
+			}
+		}
+
+		e.applyRule(rule, counters, now)
+	}
+
+	e.detectDowntime(now)
+	e.pruneResolved(now)
+
+	e.detectDisallowedMethods(now)
+	e.detectUserAgentAnomalies(now)
+	e.detectImpossibleTravel(now)
+	e.pruneSecurityEvents(now)
+}
+
+// consumesBudget reports whether an event counts against the rule's budget
+func (e *Engine) consumesBudget(rule *Rule, event realtime.Event) bool {
+	if rule.Metric == RuleMetricSlow {
+		return e.slowPolicy.IsSlow(event.BackendName, event.BackendURL, event.Host, event.ResponseTimeMs)
+	}
+	return e.statusPolicy.IsFailure(event.BackendName, event.BackendURL, event.Host, event.StatusCode)
+}
+
+// applyRule fires or resolves alerts for a rule based on the group counters
+func (e *Engine) applyRule(rule *Rule, counters map[string]*groupCounter, now time.Time) {
+	budget := rule.errorBudget()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	breached := make(map[string]bool)
+	for key, counter := range counters {
+		if counter.requests < int64(rule.MinRequests) {
+			continue
+		}
+
+		errorRatio := float64(counter.errors) / float64(counter.requests)
+		burnRate := errorRatio / budget
+		if burnRate < rule.BurnRate {
+			continue
+		}
+
+		breached[key] = true
+		alertID := rule.Name + "|" + key
+		alert, exists := e.alerts[alertID]
+		if !exists || alert.State == AlertStateResolved {
+			alert = &Alert{
+				Rule:    rule.Name,
+				Type:    rule.Type,
+				Metric:  rule.Metric,
+				Key:     key,
+				State:   AlertStateFiring,
+				FiredAt: now,
+			}
+			e.alerts[alertID] = alert
+
+			e.logger.Warn("🚨 Alert firing",
+				e.logger.Args(
+					"rule", rule.Name,
+					"type", rule.Type,
+					"metric", rule.Metric,
+					"key", key,
+					"error_rate", errorRatio*100,
+					"burn_rate", burnRate,
+					"requests", counter.requests,
+				))
+		}
+
+		alert.Requests = counter.requests
+		alert.Errors = counter.errors
+		alert.ErrorRate = errorRatio * 100
+		alert.BurnRate = burnRate
+		alert.UpdatedAt = now
+	}
+
+	// Resolve firing alerts of this rule that are no longer breached
+	for _, alert := range e.alerts {
+		if alert.Rule != rule.Name || alert.State != AlertStateFiring || breached[alert.Key] {
+			continue
+		}
+
+		resolvedAt := now
+		alert.State = AlertStateResolved
+		alert.ResolvedAt = &resolvedAt
+		alert.UpdatedAt = now
+
+		e.logger.Info("Alert resolved",
+			e.logger.Args("rule", rule.Name, "key", alert.Key, "duration", now.Sub(alert.FiredAt).Round(time.Second)))
+	}
+}
+
+// pruneResolved drops resolved alerts older than the retention period
+func (e *Engine) pruneResolved(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, alert := range e.alerts {
+		if alert.State == AlertStateResolved && alert.ResolvedAt != nil && now.Sub(*alert.ResolvedAt) > resolvedRetention {
+			delete(e.alerts, id)
+		}
+	}
+}
+
+// GetAlerts returns firing alerts first, then recently resolved ones
+func (e *Engine) GetAlerts() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].State != alerts[j].State {
+			return alerts[i].State == AlertStateFiring
+		}
+		return alerts[i].FiredAt.After(alerts[j].FiredAt)
+	})
+
+	return alerts
+}
+
+// GetRules returns the configured rules
+func (e *Engine) GetRules() []Rule {
+	return e.rules
+}
@@ -0,0 +1,154 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/realtime"
+
+	"github.com/pterm/pterm"
+)
+
+// eventSource serves fixed events to the engine
+type eventSource struct {
+	events []realtime.Event
+}
+
+func (s *eventSource) RecentEvents(since time.Time) []realtime.Event {
+	var events []realtime.Event
+	for _, event := range s.events {
+		if event.Timestamp.After(since) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// requests returns count requests to service, the first failed ones with a 500
+func requests(service string, count int, failed int) []realtime.Event {
+	now := time.Now()
+	events := make([]realtime.Event, count)
+	for i := range events {
+		events[i] = realtime.Event{Timestamp: now.Add(-time.Second), BackendName: service, Method: "GET", Path: "/", StatusCode: 200}
+		if i < failed {
+			events[i].StatusCode = 500
+		}
+	}
+	return events
+}
+
+func newTestEngine(t *testing.T, source EventSource, rules ...Rule) *Engine {
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	return NewEngine(source, repositories.NewDefaultStatusPolicy(), nil, rules, nil, UserAgentChecks{}, TravelChecks{}, DowntimeChecks{}, logger)
+}
+
+func TestEngine_BurnRate(t *testing.T) {
+	cases := []struct {
+		name        string
+		objective   float64
+		burnRate    float64
+		minRequests int
+		requests    int
+		failed      int
+		firing      bool
+		observed    float64
+	}{
+		// A 99% objective allows 1% errors: 10% errors burn the budget 10 times too fast
+		{"at threshold", 99, 10, 0, 100, 10, true, 10},
+		{"below threshold", 99, 10, 0, 100, 9, false, 0},
+		{"fast burn", 99.9, 14.4, 0, 200, 100, true, 500},
+		{"no errors", 99, 1, 0, 100, 0, false, 0},
+		// Too few requests to evaluate, whatever the error rate
+		{"under default min requests", 99, 1, 0, DefaultMinRequests - 1, DefaultMinRequests - 1, false, 0},
+		{"at default min requests", 99, 1, 0, DefaultMinRequests, DefaultMinRequests, true, 100},
+		{"under custom min requests", 99, 1, 50, 49, 49, false, 0},
+		{"at custom min requests", 99, 1, 50, 50, 1, true, 2},
+	}
+
+	for _, tc := range cases {
+		rule := Rule{Name: "budget", Type: RuleTypeService, Objective: tc.objective, BurnRate: tc.burnRate, MinRequests: tc.minRequests}
+		engine := newTestEngine(t, &eventSource{events: requests("shop", tc.requests, tc.failed)}, rule)
+		engine.Evaluate()
+
+		alerts := engine.GetAlerts()
+		if !tc.firing {
+			if len(alerts) != 0 {
+				t.Errorf("%s: expected no alert, got %+v", tc.name, alerts)
+			}
+			continue
+		}
+		if len(alerts) != 1 || alerts[0].State != AlertStateFiring || alerts[0].Key != "shop" {
+			t.Errorf("%s: expected a firing alert for shop, got %+v", tc.name, alerts)
+			continue
+		}
+		alert := alerts[0]
+		if alert.Requests != int64(tc.requests) || alert.Errors != int64(tc.failed) {
+			t.Errorf("%s: expected %d requests and %d errors, got %d and %d", tc.name, tc.requests, tc.failed, alert.Requests, alert.Errors)
+		}
+		if diff := alert.BurnRate - tc.observed; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: expected burn rate %.1f, got %f", tc.name, tc.observed, alert.BurnRate)
+		}
+	}
+}
+
+func TestEngine_ResolvesAndPrunes(t *testing.T) {
+	source := &eventSource{events: append(requests("shop", 100, 50), requests("api", 100, 0)...)}
+	engine := newTestEngine(t, source, Rule{Name: "budget", Type: RuleTypeService, Objective: 99, BurnRate: 10})
+
+	engine.Evaluate()
+	alerts := engine.GetAlerts()
+	if len(alerts) != 1 || alerts[0].Key != "shop" || alerts[0].State != AlertStateFiring {
+		t.Fatalf("Expected only shop firing, got %+v", alerts)
+	}
+	firedAt := alerts[0].FiredAt
+
+	// Still breached: the same alert is updated, not fired again
+	source.events = requests("shop", 100, 40)
+	engine.Evaluate()
+	alerts = engine.GetAlerts()
+	if len(alerts) != 1 || !alerts[0].FiredAt.Equal(firedAt) || alerts[0].Errors != 40 {
+		t.Fatalf("Expected the firing alert updated, got %+v", alerts)
+	}
+
+	// Recovered: the alert resolves
+	source.events = requests("shop", 100, 0)
+	engine.Evaluate()
+	alerts = engine.GetAlerts()
+	if len(alerts) != 1 || alerts[0].State != AlertStateResolved || alerts[0].ResolvedAt == nil {
+		t.Fatalf("Expected the alert resolved, got %+v", alerts)
+	}
+	resolvedAt := *alerts[0].ResolvedAt
+
+	// Resolved alerts stay visible for the retention period, then are dropped
+	engine.pruneResolved(resolvedAt.Add(resolvedRetention - time.Minute))
+	if len(engine.GetAlerts()) != 1 {
+		t.Error("Expected the resolved alert kept during the retention period")
+	}
+	engine.pruneResolved(resolvedAt.Add(resolvedRetention + time.Minute))
+	if alerts := engine.GetAlerts(); len(alerts) != 0 {
+		t.Errorf("Expected the resolved alert pruned, got %+v", alerts)
+	}
+
+	// Breaching again fires a new alert
+	source.events = requests("shop", 100, 50)
+	engine.Evaluate()
+	if alerts := engine.GetAlerts(); len(alerts) != 1 || alerts[0].State != AlertStateFiring || alerts[0].ResolvedAt != nil {
+		t.Errorf("Expected a new firing alert, got %+v", alerts)
+	}
+}
+
+func TestEngine_PruneKeepsFiring(t *testing.T) {
+	engine := newTestEngine(t, &eventSource{events: requests("shop", 100, 100)}, Rule{Name: "budget", Type: RuleTypeService, Objective: 99, BurnRate: 1})
+	engine.Evaluate()
+
+	engine.pruneResolved(time.Now().Add(24 * time.Hour))
+	if alerts := engine.GetAlerts(); len(alerts) != 1 || alerts[0].State != AlertStateFiring {
+		t.Errorf("Expected firing alerts never pruned, got %+v", alerts)
+	}
+}
@@ -0,0 +1,183 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"loglynx/internal/realtime"
+)
+
+// RuleType defines what an alert rule groups requests by
+type RuleType string
+
+const (
+	// RuleTypeService evaluates the error rate of each service (backend_name > backend_url > host)
+	RuleTypeService RuleType = "service"
+	// RuleTypePath evaluates the error rate of each path
+	RuleTypePath RuleType = "path"
+	// RuleTypeMethod evaluates the error rate of each HTTP method
+	RuleTypeMethod RuleType = "method"
+)
+
+// RuleMetric defines which requests consume a rule's budget
+type RuleMetric string
+
+const (
+	// RuleMetricErrors counts failed requests (failure status codes, globally and per service)
+	RuleMetricErrors RuleMetric = "errors"
+	// RuleMetricSlow counts requests slower than the slow threshold of their service
+	RuleMetricSlow RuleMetric = "slow"
+	// RuleMetricZeroTraffic marks the alerts of services without traffic (DowntimeChecks), not a rule metric
+	RuleMetricZeroTraffic RuleMetric = "zero_traffic"
+)
+
+const (
+	// DefaultRuleWindow is the evaluation window when a rule doesn't set one
+	DefaultRuleWindow = 5 * time.Minute
+	// DefaultMinRequests avoids alerting on a handful of requests
+	DefaultMinRequests = 20
+)
+
+// Rule describes an error budget burn alert
+// A rule fires when errors consume the budget (100% - objective) at least BurnRate times faster than allowed
+// With the slow metric, slow requests count as errors: the objective is then the share of fast requests.
+type Rule struct {
+	Name        string     `json:"name"`
+	Type        RuleType   `json:"type"`
+	Metric      RuleMetric `json:"metric,omitempty"`       // errors (default) or slow
+	Service     string     `json:"service,omitempty"`      // Optional service scope (matched like the "auto" filter)
+	Target      string     `json:"target,omitempty"`       // Service name, path prefix or method; empty = every distinct value
+	Objective   float64    `json:"objective"`              // Success objective in percent, e.g. 99.9
+	BurnRate    float64    `json:"burn_rate"`              // Burn rate multiplier that triggers the alert, e.g. 14.4
+	Window      string     `json:"window,omitempty"`       // Evaluation window, e.g. "5m" (max 5m, limited by the realtime buffer)
+	MinRequests int        `json:"min_requests,omitempty"` // Minimum requests in window before evaluating
+
+	window time.Duration
+}
+
+// LoadRules reads alert rules from a JSON file (array of rules)
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+
+	for i := range rules {
+		if err := rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid alert rule %q: %w", rules[i].Name, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// validate checks the rule and applies defaults
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch r.Type {
+	case RuleTypeService, RuleTypePath, RuleTypeMethod:
+	default:
+		return fmt.Errorf("unknown rule type %q (expected service, path or method)", r.Type)
+	}
+
+	switch r.Metric {
+	case "":
+		r.Metric = RuleMetricErrors
+	case RuleMetricErrors, RuleMetricSlow:
+	default:
+		return fmt.Errorf("unknown rule metric %q (expected errors or slow)", r.Metric)
+	}
+
+	if r.Objective <= 0 || r.Objective >= 100 {
+		return fmt.Errorf("objective must be between 0 and 100 (exclusive)")
+	}
+	if r.BurnRate <= 0 {
+		return fmt.Errorf("burn_rate must be greater than 0")
+	}
+
+	r.window = DefaultRuleWindow
+	if r.Window != "" {
+		window, err := time.ParseDuration(r.Window)
+		if err != nil {
+			return fmt.Errorf("invalid window: %w", err)
+		}
+		if window <= 0 || window > realtime.BufferRetention {
+			return fmt.Errorf("window must be between 0 and %s", realtime.BufferRetention)
+		}
+		r.window = window
+	}
+
+	if r.MinRequests <= 0 {
+		r.MinRequests = DefaultMinRequests
+	}
+
+	if r.Type == RuleTypeMethod {
+		r.Target = strings.ToUpper(r.Target)
+	}
+
+	return nil
+}
+
+// errorBudget returns the allowed error ratio (0-1)
+func (r *Rule) errorBudget() float64 {
+	return (100 - r.Objective) / 100
+}
+
+// groupKey returns the value an event is grouped by for this rule, or "" to skip it
+func (r *Rule) groupKey(event realtime.Event) string {
+	if r.Service != "" && !matchesService(event, r.Service) {
+		return ""
+	}
+
+	var key string
+	switch r.Type {
+	case RuleTypeService:
+		key = serviceName(event)
+		if r.Target != "" && !matchesService(event, r.Target) {
+			return ""
+		}
+	case RuleTypePath:
+		key = event.Path
+		if r.Target != "" {
+			if !strings.HasPrefix(event.Path, r.Target) {
+				return ""
+			}
+			// Aggregate everything under the prefix when a target is set
+			key = r.Target
+		}
+	case RuleTypeMethod:
+		key = event.Method
+		if r.Target != "" && event.Method != r.Target {
+			return ""
+		}
+	}
+
+	return key
+}
+
+// serviceName returns the service identifier of an event (backend_name > backend_url > host)
+func serviceName(event realtime.Event) string {
+	if event.BackendName != "" {
+		return event.BackendName
+	}
+	if event.BackendURL != "" {
+		return event.BackendURL
+	}
+	return event.Host
+}
+
+// matchesService checks an event against a service name using "auto" semantics
+func matchesService(event realtime.Event, name string) bool {
+	return serviceName(event) == name
+}
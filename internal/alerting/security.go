@@ -0,0 +1,179 @@
+package alerting
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"loglynx/internal/realtime"
+)
+
+// SecurityEventType identifies what a security event reports
+type SecurityEventType string
+
+const (
+	// SecurityEventDisallowedMethod reports requests using a method listed in DISALLOWED_METHODS
+	SecurityEventDisallowedMethod SecurityEventType = "disallowed_method"
+	// SecurityEventUARotation reports a client IP sending many distinct user agents (randomized user agents)
+	SecurityEventUARotation SecurityEventType = "ua_rotation"
+	// SecurityEventUAMismatch reports a client IP sending user agents no real browser sends (spoofed user agents)
+	SecurityEventUAMismatch SecurityEventType = "ua_mismatch"
+	// SecurityEventImpossibleTravel reports a user logging in from countries too far apart for the time between
+	SecurityEventImpossibleTravel SecurityEventType = "impossible_travel"
+)
+
+const (
+	// securityEventRetention is how long security events stay visible after their last request
+	securityEventRetention = 24 * time.Hour
+	// maxSecurityEvents bounds memory when many clients probe disallowed methods
+	maxSecurityEvents = 1000
+)
+
+// SecurityEvent groups the requests of one client using a disallowed method on one service,
+// the requests of one client with suspicious user agents (Method and Service are then the last ones),
+// or the logins of one user travelling impossibly between two countries
+type SecurityEvent struct {
+	Type               SecurityEventType `json:"type"`
+	Method             string            `json:"method"`
+	ClientIP           string            `json:"client_ip"`
+	Service            string            `json:"service"`
+	LastPath           string            `json:"last_path"`
+	LastStatusCode     int               `json:"last_status_code"`
+	Count              int64             `json:"count"`
+	FirstSeen          time.Time         `json:"first_seen"`
+	LastSeen           time.Time         `json:"last_seen"`
+	Reason             string            `json:"reason,omitempty"`               // User agent events: what looks wrong
+	DistinctUserAgents int               `json:"distinct_user_agents,omitempty"` // User agent events: most seen within 5 minutes
+	UserAgents         []string          `json:"user_agents,omitempty"`          // User agent events: first user agents seen
+	ClientUser         string            `json:"client_user,omitempty"`          // Impossible travel events: the user
+	Travel             *Travel           `json:"travel,omitempty"`               // Impossible travel events: the last travel
+}
+
+// ParseMethods parses a comma-separated list of HTTP methods, e.g. "TRACE, connect"
+func ParseMethods(list string) []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, method := range strings.Split(list, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || seen[method] {
+			continue
+		}
+		seen[method] = true
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// detectDisallowedMethods creates or updates security events for buffered requests using a disallowed method
+// Only requests newer than the last one seen for the same client, method and service are counted, so
+// overlapping evaluation windows don't count a request twice.
+func (e *Engine) detectDisallowedMethods(now time.Time) {
+	if len(e.disallowedMethods) == 0 {
+		return
+	}
+
+	events := e.source.RecentEvents(now.Add(-realtime.BufferRetention))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Last request counted by previous evaluations, per event
+	counted := make(map[string]time.Time, len(e.securityEvents))
+	for id, securityEvent := range e.securityEvents {
+		counted[id] = securityEvent.LastSeen
+	}
+
+	for _, event := range events {
+		if !e.disallowedMethods[event.Method] {
+			continue
+		}
+
+		service := serviceName(event)
+		id := event.Method + "|" + event.ClientIP + "|" + service
+		securityEvent, exists := e.securityEvents[id]
+		if !exists {
+			securityEvent = &SecurityEvent{
+				Type:      SecurityEventDisallowedMethod,
+				Method:    event.Method,
+				ClientIP:  event.ClientIP,
+				Service:   service,
+				FirstSeen: event.Timestamp,
+			}
+			e.securityEvents[id] = securityEvent
+
+			e.logger.Warn("🛡️ Disallowed HTTP method",
+				e.logger.Args(
+					"method", event.Method,
+					"client_ip", event.ClientIP,
+					"service", service,
+					"path", event.Path,
+					"status", event.StatusCode,
+				))
+		} else if !event.Timestamp.After(counted[id]) {
+			continue
+		}
+
+		securityEvent.Count++
+		if event.Timestamp.Before(securityEvent.FirstSeen) {
+			securityEvent.FirstSeen = event.Timestamp
+		}
+		if !event.Timestamp.Before(securityEvent.LastSeen) {
+			securityEvent.LastSeen = event.Timestamp
+			securityEvent.LastPath = event.Path
+			securityEvent.LastStatusCode = event.StatusCode
+		}
+	}
+}
+
+// pruneSecurityEvents drops events past the retention period, then the oldest ones above the limit
+func (e *Engine) pruneSecurityEvents(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, event := range e.securityEvents {
+		if now.Sub(event.LastSeen) > securityEventRetention {
+			delete(e.securityEvents, id)
+		}
+	}
+
+	if len(e.securityEvents) <= maxSecurityEvents {
+		return
+	}
+	ids := make([]string, 0, len(e.securityEvents))
+	for id := range e.securityEvents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return e.securityEvents[ids[i]].LastSeen.Before(e.securityEvents[ids[j]].LastSeen)
+	})
+	for _, id := range ids[:len(ids)-maxSecurityEvents] {
+		delete(e.securityEvents, id)
+	}
+}
+
+// GetSecurityEvents returns security events of the last 24 hours, most recent first
+func (e *Engine) GetSecurityEvents() []SecurityEvent {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	events := make([]SecurityEvent, 0, len(e.securityEvents))
+	for _, event := range e.securityEvents {
+		events = append(events, *event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastSeen.After(events[j].LastSeen)
+	})
+
+	return events
+}
+
+// GetDisallowedMethods returns the methods that create security events
+func (e *Engine) GetDisallowedMethods() []string {
+	methods := make([]string, 0, len(e.disallowedMethods))
+	for method := range e.disallowedMethods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
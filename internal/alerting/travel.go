@@ -0,0 +1,174 @@
+package alerting
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/repositories"
+)
+
+const (
+	// DefaultTravelWindow is how far back logins are correlated
+	DefaultTravelWindow = 2 * time.Hour
+	// DefaultTravelMaxSpeedKmh is the fastest plausible travel speed (a commercial flight)
+	DefaultTravelMaxSpeedKmh = 1000
+
+	// minTravelDistanceKm ignores country changes between nearby locations (border regions, GeoIP inaccuracy)
+	minTravelDistanceKm = 500
+	// earthRadiusKm is the mean radius of the Earth
+	earthRadiusKm = 6371
+)
+
+// LoginLocationSource provides the locations of authenticated requests to login paths
+type LoginLocationSource interface {
+	GetLoginLocations(pathPrefixes []string, since time.Time) ([]*repositories.LoginLocation, error)
+}
+
+// TravelChecks configures impossible travel detection, disabled without paths or source
+type TravelChecks struct {
+	Paths       []string      // Login path prefixes, e.g. "/login"
+	Window      time.Duration // Logins correlated (0 = DefaultTravelWindow)
+	MaxSpeedKmh float64       // Travel faster than this between two logins is impossible (0 = DefaultTravelMaxSpeedKmh)
+	Source      LoginLocationSource
+}
+
+// Travel describes the two logins of an impossible travel security event
+type Travel struct {
+	FromIP          string  `json:"from_ip"`
+	FromCountry     string  `json:"from_country"`
+	FromCity        string  `json:"from_city,omitempty"`
+	ToCountry       string  `json:"to_country"`
+	ToCity          string  `json:"to_city,omitempty"`
+	DistanceKm      float64 `json:"distance_km,omitempty"` // 0 when a location has no coordinates
+	SpeedKmh        float64 `json:"speed_kmh,omitempty"`
+	IntervalSeconds float64 `json:"interval_seconds"`
+}
+
+// ParsePaths parses a comma-separated list of path prefixes, e.g. "/login, /api/auth"
+func ParsePaths(list string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// impossibleTravel returns the travel between two consecutive logins of a user, nil if it is possible
+// Logins from different countries are impossible when the speed needed exceeds maxSpeedKmh, or without
+// coordinates, when they happened within the window at all.
+func impossibleTravel(from *repositories.LoginLocation, to *repositories.LoginLocation, maxSpeedKmh float64) *Travel {
+	if from.GeoCountry == to.GeoCountry {
+		return nil
+	}
+
+	travel := &Travel{
+		FromIP:          from.ClientIP,
+		FromCountry:     from.GeoCountry,
+		FromCity:        from.GeoCity,
+		ToCountry:       to.GeoCountry,
+		ToCity:          to.GeoCity,
+		IntervalSeconds: to.Timestamp.Sub(from.Timestamp).Seconds(),
+	}
+	if !hasCoordinates(from) || !hasCoordinates(to) {
+		return travel
+	}
+
+	travel.DistanceKm = math.Round(distanceKm(from.GeoLat, from.GeoLon, to.GeoLat, to.GeoLon))
+	if travel.DistanceKm < minTravelDistanceKm {
+		return nil
+	}
+	hours := travel.IntervalSeconds / 3600
+	if hours > 0 {
+		travel.SpeedKmh = math.Round(travel.DistanceKm / hours)
+		if travel.SpeedKmh <= maxSpeedKmh {
+			return nil
+		}
+	}
+	return travel
+}
+
+func hasCoordinates(location *repositories.LoginLocation) bool {
+	return location.GeoLat != 0 || location.GeoLon != 0
+}
+
+// distanceKm is the great-circle distance between two coordinates (haversine formula)
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// detectImpossibleTravel creates or updates security events for users logging in from distant countries
+// One event per user and pair of countries, counting the transitions newer than the last one counted.
+func (e *Engine) detectImpossibleTravel(now time.Time) {
+	checks := e.travelChecks
+	if len(checks.Paths) == 0 || checks.Source == nil {
+		return
+	}
+	window := checks.Window
+	if window <= 0 {
+		window = DefaultTravelWindow
+	}
+	maxSpeed := checks.MaxSpeedKmh
+	if maxSpeed <= 0 {
+		maxSpeed = DefaultTravelMaxSpeedKmh
+	}
+
+	locations, err := checks.Source.GetLoginLocations(checks.Paths, now.Add(-window))
+	if err != nil {
+		e.logger.Warn("Failed to read login locations for impossible travel detection", e.logger.Args("error", err))
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := 1; i < len(locations); i++ {
+		from, to := locations[i-1], locations[i]
+		if from.ClientUser != to.ClientUser {
+			continue
+		}
+		travel := impossibleTravel(from, to, maxSpeed)
+		if travel == nil {
+			continue
+		}
+
+		id := string(SecurityEventImpossibleTravel) + "|" + to.ClientUser + "|" + from.GeoCountry + "|" + to.GeoCountry
+		securityEvent, exists := e.securityEvents[id]
+		if !exists {
+			securityEvent = &SecurityEvent{
+				Type:       SecurityEventImpossibleTravel,
+				ClientUser: to.ClientUser,
+				FirstSeen:  to.Timestamp,
+			}
+			e.securityEvents[id] = securityEvent
+
+			e.logger.Warn("🛡️ Impossible travel",
+				e.logger.Args(
+					"user", to.ClientUser,
+					"from", from.GeoCountry,
+					"to", to.GeoCountry,
+					"client_ip", to.ClientIP,
+					"minutes", math.Round(travel.IntervalSeconds/60),
+				))
+		} else if !to.Timestamp.After(securityEvent.LastSeen) {
+			continue
+		}
+
+		securityEvent.Count++
+		securityEvent.LastSeen = to.Timestamp
+		securityEvent.ClientIP = to.ClientIP
+		securityEvent.Service = to.Host
+		securityEvent.LastPath = to.Path
+		securityEvent.Travel = travel
+	}
+}
@@ -0,0 +1,178 @@
+package alerting
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"loglynx/internal/realtime"
+)
+
+// DefaultUARotationThreshold is the number of distinct user agents from one client IP within the real-time
+// window (5 minutes) above which the client is reported as rotating user agents
+const DefaultUARotationThreshold = 10
+
+// maxUserAgentSamples is the number of user agents kept per security event
+const maxUserAgentSamples = 5
+
+// UserAgentChecks configures the detection of randomized and spoofed user agents
+type UserAgentChecks struct {
+	Enabled           bool
+	RotationThreshold int // Distinct user agents per client IP within 5 minutes (0 = DefaultUARotationThreshold)
+}
+
+// platformTokens are the platform markers of browser user agents, a real browser sends one of them
+var platformTokens = []struct {
+	name  string
+	token string
+}{
+	{"Windows", "Windows NT"},
+	{"macOS", "Macintosh"},
+	{"Android", "Android"},
+	{"iOS", "iPhone"},
+	{"iOS", "iPad"},
+}
+
+// userAgentInconsistency returns why a user agent cannot come from the browser it claims, "" if it can
+// Browsers build their user agent from fixed templates: tools copying part of it (curl -A "Chrome/120")
+// or randomizing it (random platform and browser) produce combinations no browser sends.
+func userAgentInconsistency(event realtime.Event) string {
+	ua := event.UserAgent
+	if ua == "" {
+		return ""
+	}
+
+	var platforms []string
+	for _, platform := range platformTokens {
+		if strings.Contains(ua, platform.token) && (len(platforms) == 0 || platforms[len(platforms)-1] != platform.name) {
+			platforms = append(platforms, platform.name)
+		}
+	}
+	if len(platforms) > 1 {
+		return "conflicting platforms (" + strings.Join(platforms, ", ") + ")"
+	}
+
+	switch event.Browser {
+	case "Chrome", "Edge":
+		if !strings.HasPrefix(ua, "Mozilla/5.0 ") || !strings.Contains(ua, "AppleWebKit/") || !strings.Contains(ua, "Safari/") {
+			return event.Browser + " without the Mozilla, AppleWebKit and Safari tokens of Chromium browsers"
+		}
+	case "Firefox":
+		if !strings.HasPrefix(ua, "Mozilla/5.0 ") || !strings.Contains(ua, "Gecko/") {
+			return "Firefox without the Mozilla and Gecko tokens"
+		}
+	case "Safari":
+		// The Android stock browser also claims Safari
+		if event.OS == "Windows" || event.OS == "Linux" || event.OS == "ChromeOS" {
+			return "Safari on " + event.OS
+		}
+	case "IE":
+		if event.OS != "Windows" {
+			return "Internet Explorer on " + event.OS
+		}
+	}
+
+	// Browsers speak HTTP/1.1 or later, HTTP/1.0 comes from scripts and old tools
+	if event.Protocol == "HTTP/1.0" {
+		switch event.Browser {
+		case "Chrome", "Edge", "Firefox", "Safari", "Opera":
+			return event.Browser + " over HTTP/1.0"
+		}
+	}
+	return ""
+}
+
+// uaClient collects the requests of one client IP in the real-time window
+type uaClient struct {
+	userAgents  map[string]bool
+	requests    []realtime.Event
+	mismatches  []realtime.Event
+	mismatchWhy string
+}
+
+// detectUserAgentAnomalies creates or updates security events for clients rotating or spoofing user agents
+func (e *Engine) detectUserAgentAnomalies(now time.Time) {
+	if !e.userAgentChecks.Enabled {
+		return
+	}
+	threshold := e.userAgentChecks.RotationThreshold
+	if threshold <= 0 {
+		threshold = DefaultUARotationThreshold
+	}
+
+	clients := make(map[string]*uaClient)
+	for _, event := range e.source.RecentEvents(now.Add(-realtime.BufferRetention)) {
+		client, exists := clients[event.ClientIP]
+		if !exists {
+			client = &uaClient{userAgents: make(map[string]bool)}
+			clients[event.ClientIP] = client
+		}
+		client.userAgents[event.UserAgent] = true
+		client.requests = append(client.requests, event)
+		if why := userAgentInconsistency(event); why != "" {
+			client.mismatches = append(client.mismatches, event)
+			client.mismatchWhy = why
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for clientIP, client := range clients {
+		if len(client.userAgents) >= threshold {
+			e.userAgentEvent(SecurityEventUARotation, clientIP, "rotating user agents", len(client.userAgents), client.requests)
+		}
+		if len(client.mismatches) > 0 {
+			e.userAgentEvent(SecurityEventUAMismatch, clientIP, client.mismatchWhy, len(client.userAgents), client.mismatches)
+		}
+	}
+}
+
+// userAgentEvent creates or updates the security event of a client, counting requests newer than the last one
+// counted (evaluation windows overlap). Caller must hold e.mu.
+func (e *Engine) userAgentEvent(eventType SecurityEventType, clientIP string, reason string, userAgents int, requests []realtime.Event) {
+	id := string(eventType) + "|" + clientIP
+	securityEvent, exists := e.securityEvents[id]
+	if !exists {
+		securityEvent = &SecurityEvent{
+			Type:      eventType,
+			ClientIP:  clientIP,
+			FirstSeen: requests[0].Timestamp,
+		}
+		e.securityEvents[id] = securityEvent
+	}
+	counted := securityEvent.LastSeen
+	securityEvent.Reason = reason
+	securityEvent.DistinctUserAgents = max(securityEvent.DistinctUserAgents, userAgents)
+
+	for _, event := range requests {
+		if exists && !event.Timestamp.After(counted) {
+			continue
+		}
+		securityEvent.Count++
+		if event.Timestamp.Before(securityEvent.FirstSeen) {
+			securityEvent.FirstSeen = event.Timestamp
+		}
+		if !event.Timestamp.Before(securityEvent.LastSeen) {
+			securityEvent.LastSeen = event.Timestamp
+			securityEvent.Method = event.Method
+			securityEvent.Service = serviceName(event)
+			securityEvent.LastPath = event.Path
+			securityEvent.LastStatusCode = event.StatusCode
+		}
+		if len(securityEvent.UserAgents) < maxUserAgentSamples && !slices.Contains(securityEvent.UserAgents, event.UserAgent) {
+			securityEvent.UserAgents = append(securityEvent.UserAgents, event.UserAgent)
+		}
+	}
+
+	if !exists {
+		e.logger.Warn("🛡️ Suspicious user agents",
+			e.logger.Args(
+				"type", eventType,
+				"reason", reason,
+				"client_ip", clientIP,
+				"user_agents", userAgents,
+				"user_agent", securityEvent.UserAgents[0],
+			))
+	}
+}
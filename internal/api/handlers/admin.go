@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"loglynx/internal/enrichment"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// AdminHandler runs maintenance jobs on demand, e.g. re-enriching old requests after GeoIP databases changed
+type AdminHandler struct {
+	backfill *enrichment.Backfill
+	token    string
+	logger   *pterm.Logger
+}
+
+// NewAdminHandler creates an admin handler, requests must carry the token as a bearer token
+func NewAdminHandler(backfill *enrichment.Backfill, token string, logger *pterm.Logger) *AdminHandler {
+	return &AdminHandler{
+		backfill: backfill,
+		token:    token,
+		logger:   logger,
+	}
+}
+
+// RequireToken rejects requests without the admin bearer token
+func (h *AdminHandler) RequireToken(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorBody(c, "Invalid or missing admin token"))
+		return
+	}
+	c.Next()
+}
+
+// GetEnrichmentBackfill returns the progress of the running or last enrichment backfill
+func (h *AdminHandler) GetEnrichmentBackfill(c *gin.Context) {
+	c.JSON(http.StatusOK, h.backfill.Progress())
+}
+
+// StartEnrichmentBackfill starts re-enriching stored requests missing GeoIP or ASN data
+// Responds 202 with the initial progress, 409 when a backfill is running and 503 without GeoIP databases.
+func (h *AdminHandler) StartEnrichmentBackfill(c *gin.Context) {
+	progress, err := h.backfill.Start()
+	switch {
+	case errors.Is(err, enrichment.ErrBackfillRunning):
+		c.JSON(http.StatusConflict, errorBody(c, err.Error()))
+	case errors.Is(err, enrichment.ErrGeoIPUnavailable):
+		c.JSON(http.StatusServiceUnavailable, errorBody(c, err.Error()))
+	case err != nil:
+		h.logger.WithCaller().Error("Failed to start the enrichment backfill", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to start the enrichment backfill"))
+	default:
+		c.JSON(http.StatusAccepted, progress)
+	}
+}
+
+// CancelEnrichmentBackfill stops the running enrichment backfill after its current batch
+func (h *AdminHandler) CancelEnrichmentBackfill(c *gin.Context) {
+	if !h.backfill.Cancel() {
+		c.JSON(http.StatusConflict, errorBody(c, "No enrichment backfill is running"))
+		return
+	}
+	c.JSON(http.StatusOK, h.backfill.Progress())
+}
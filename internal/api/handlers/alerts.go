@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"loglynx/internal/alerting"
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// AlertHandler handles alerting endpoints
+type AlertHandler struct {
+	engine    *alerting.Engine
+	incidents repositories.IncidentRepository
+	logger    *pterm.Logger
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(engine *alerting.Engine, incidents repositories.IncidentRepository, logger *pterm.Logger) *AlertHandler {
+	return &AlertHandler{
+		engine:    engine,
+		incidents: incidents,
+		logger:    logger,
+	}
+}
+
+// GetAlerts returns firing and recently resolved alerts
+func (h *AlertHandler) GetAlerts(c *gin.Context) {
+	c.JSON(http.StatusOK, h.engine.GetAlerts())
+}
+
+// GetSecurityEvents returns the security events of the last 24 hours with the disallowed methods
+// ?type= restricts the events to one type, e.g. impossible_travel
+func (h *AlertHandler) GetSecurityEvents(c *gin.Context) {
+	events := h.engine.GetSecurityEvents()
+	if eventType := c.Query("type"); eventType != "" {
+		filtered := make([]alerting.SecurityEvent, 0, len(events))
+		for _, event := range events {
+			if string(event.Type) == eventType {
+				filtered = append(filtered, event)
+			}
+		}
+		events = filtered
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"disallowed_methods": h.engine.GetDisallowedMethods(),
+		"events":             events,
+	})
+}
+
+// GetRules returns the configured alert rules
+func (h *AlertHandler) GetRules(c *gin.Context) {
+	c.JSON(http.StatusOK, h.engine.GetRules())
+}
+
+// GetServiceIncidents returns the zero-traffic incidents of a service, most recent first
+func (h *AlertHandler) GetServiceIncidents(c *gin.Context) {
+	h.respondIncidents(c, c.Param("name"))
+}
+
+// GetIncidents returns the zero-traffic incidents of all services, or of one with ?service=
+func (h *AlertHandler) GetIncidents(c *gin.Context) {
+	h.respondIncidents(c, c.Query("service"))
+}
+
+func (h *AlertHandler) respondIncidents(c *gin.Context, service string) {
+	limit, ok := queryLimit(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	incidents, err := h.incidents.GetIncidents(service, limit)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get incidents", logArgs(h.logger, c, "service", service, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get incidents"))
+		return
+	}
+	c.JSON(http.StatusOK, incidents)
+}
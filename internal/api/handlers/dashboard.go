@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"loglynx/internal/database/models"
 	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pterm/pterm"
@@ -12,44 +19,55 @@ import (
 
 // DashboardHandler handles dashboard requests
 type DashboardHandler struct {
-	statsRepo repositories.StatsRepository
-	httpRepo  repositories.HTTPRequestRepository
-	logger    *pterm.Logger
+	statsRepo         repositories.StatsRepository
+	httpRepo          repositories.HTTPRequestRepository
+	disallowedMethods []string // Always reported as unusual methods
+	costModel         *repositories.CostModel
+	clientVersions    *repositories.ClientVersionRules // Clients whose version adoption can be tracked
+	consumerQuotas    *repositories.ConsumerQuotas
+	searchLimiter     *rateLimiter // Searches per client IP
+	logger            *pterm.Logger
 }
 
 // NewDashboardHandler creates a new dashboard handler
 func NewDashboardHandler(
 	statsRepo repositories.StatsRepository,
 	httpRepo repositories.HTTPRequestRepository,
+	disallowedMethods []string,
+	costModel *repositories.CostModel,
+	clientVersions *repositories.ClientVersionRules,
+	consumerQuotas *repositories.ConsumerQuotas,
 	logger *pterm.Logger,
 ) *DashboardHandler {
 	return &DashboardHandler{
-		statsRepo: statsRepo,
-		httpRepo:  httpRepo,
-		logger:    logger,
+		statsRepo:         statsRepo,
+		httpRepo:          httpRepo,
+		disallowedMethods: disallowedMethods,
+		costModel:         costModel,
+		clientVersions:    clientVersions,
+		consumerQuotas:    consumerQuotas,
+		searchLimiter:     newRateLimiter(searchRate, searchBurst),
+		logger:            logger,
 	}
 }
 
-// ServiceFilter represents a single service filter
-type ServiceFilter struct {
-	Name string
-	Type string
-}
-
 // getServiceFilters extracts service filter parameters from request
 // Returns array of service filters
 // Supports both new multi-select (services[], service_types[]) and legacy single-select (service, service_type)
-func (h *DashboardHandler) getServiceFilters(c *gin.Context) []ServiceFilter {
+// Match types come from service_match_types[] (multi-select) or match_type (single-select), exact by default
+func (h *DashboardHandler) getServiceFilters(c *gin.Context) []filter.Service {
 	// Try new multi-select parameters first
 	serviceNames := c.QueryArray("services[]")
 	serviceTypes := c.QueryArray("service_types[]")
+	matchTypes := c.QueryArray("service_match_types[]")
 
 	if len(serviceNames) > 0 && len(serviceNames) == len(serviceTypes) {
-		filters := make([]ServiceFilter, len(serviceNames))
+		filters := make([]filter.Service, len(serviceNames))
 		for i := range serviceNames {
-			filters[i] = ServiceFilter{
-				Name: serviceNames[i],
-				Type: serviceTypes[i],
+			filters[i] = filter.Service{
+				Name:      serviceNames[i],
+				Type:      serviceTypes[i],
+				MatchType: matchTypeAt(matchTypes, i, len(serviceNames)),
 			}
 		}
 		return filters
@@ -71,27 +89,24 @@ func (h *DashboardHandler) getServiceFilters(c *gin.Context) []ServiceFilter {
 
 	// Return single filter if specified
 	if service != "" {
-		return []ServiceFilter{{Name: service, Type: serviceType}}
+		return []filter.Service{{Name: service, Type: serviceType, MatchType: c.Query("match_type")}}
 	}
 
-	return []ServiceFilter{}
+	return []filter.Service{}
 }
 
-// convertToRepoFilters converts handler ServiceFilter to repository ServiceFilter
-func (h *DashboardHandler) convertToRepoFilters(filters []ServiceFilter) []repositories.ServiceFilter {
-	repoFilters := make([]repositories.ServiceFilter, len(filters))
-	for i, f := range filters {
-		repoFilters[i] = repositories.ServiceFilter{
-			Name: f.Name,
-			Type: f.Type,
-		}
+// matchTypeAt returns the match type of the i-th service of a multi-select
+// Match types are ignored unless one is given per service, as for service types.
+func matchTypeAt(matchTypes []string, i int, services int) string {
+	if len(matchTypes) != services {
+		return ""
 	}
-	return repoFilters
+	return matchTypes[i]
 }
 
 // getExcludeOwnIP extracts exclude_own_ip and related parameters
-// Returns (excludeIP bool, clientIP string, excludeServices []ServiceFilter)
-func (h *DashboardHandler) getExcludeOwnIP(c *gin.Context) (bool, string, []ServiceFilter) {
+// Returns (excludeIP bool, clientIP string, excludeServices []filter.Service)
+func (h *DashboardHandler) getExcludeOwnIP(c *gin.Context) (bool, string, []filter.Service) {
 	excludeIP := c.Query("exclude_own_ip") == "true"
 	if !excludeIP {
 		return false, "", nil
@@ -103,14 +118,16 @@ func (h *DashboardHandler) getExcludeOwnIP(c *gin.Context) (bool, string, []Serv
 	// Get exclude services
 	serviceNames := c.QueryArray("exclude_services[]")
 	serviceTypes := c.QueryArray("exclude_service_types[]")
+	matchTypes := c.QueryArray("exclude_service_match_types[]")
 
-	var excludeServices []ServiceFilter
+	var excludeServices []filter.Service
 	if len(serviceNames) > 0 && len(serviceNames) == len(serviceTypes) {
-		excludeServices = make([]ServiceFilter, len(serviceNames))
+		excludeServices = make([]filter.Service, len(serviceNames))
 		for i := range serviceNames {
-			excludeServices[i] = ServiceFilter{
-				Name: serviceNames[i],
-				Type: serviceTypes[i],
+			excludeServices[i] = filter.Service{
+				Name:      serviceNames[i],
+				Type:      serviceTypes[i],
+				MatchType: matchTypeAt(matchTypes, i, len(serviceNames)),
 			}
 		}
 	}
@@ -118,25 +135,99 @@ func (h *DashboardHandler) getExcludeOwnIP(c *gin.Context) (bool, string, []Serv
 	return true, clientIP, excludeServices
 }
 
-// buildExcludeIPFilter builds ExcludeIPFilter from request
-func (h *DashboardHandler) buildExcludeIPFilter(c *gin.Context) *repositories.ExcludeIPFilter {
+// buildExcludeIPFilter builds filter.ExcludeIP from request
+func (h *DashboardHandler) buildExcludeIPFilter(c *gin.Context) *filter.ExcludeIP {
 	excludeIPEnabled, clientIP, excludeServices := h.getExcludeOwnIP(c)
 	if !excludeIPEnabled {
 		return nil
 	}
 
-	return &repositories.ExcludeIPFilter{
+	return &filter.ExcludeIP{
 		ClientIP:        clientIP,
-		ExcludeServices: h.convertToRepoFilters(excludeServices),
+		ExcludeServices: excludeServices,
+	}
+}
+
+// getLookbackHours returns the effective time range for a request in hours
+// The "hours" query parameter (1 to 8760, checked by ValidateParams) overrides the configured default
+func (h *DashboardHandler) getLookbackHours(c *gin.Context) int {
+	return validatedInt(c, "hours", h.statsRepo.LookbackHours())
+}
+
+// requestStatsRepo returns the stats repository bound to the request context
+// Queries stop when the client disconnects and are attributed to the endpoint in query stats
+func (h *DashboardHandler) requestStatsRepo(c *gin.Context) repositories.StatsRepository {
+	return h.statsRepo.WithContext(c.Request.Context())
+}
+
+// statsRepoFor returns the stats repository scoped to the request's time range
+func (h *DashboardHandler) statsRepoFor(c *gin.Context) (repositories.StatsRepository, int) {
+	hours := h.getLookbackHours(c)
+	statsRepo := h.requestStatsRepo(c)
+	if hours == h.statsRepo.LookbackHours() {
+		return statsRepo, hours
+	}
+	return statsRepo.WithLookback(hours), hours
+}
+
+// topStatsRepoFor returns the stats repository scoped to the request's time range, top-N page and thresholds
+func (h *DashboardHandler) topStatsRepoFor(c *gin.Context) (repositories.StatsRepository, int) {
+	statsRepo, hours := h.statsRepoFor(c)
+	if page := topPage(c); !page.IsDefault() {
+		statsRepo = statsRepo.WithTopPage(page)
+	}
+	if filter := topFilter(c); !filter.IsZero() {
+		c.Set(topFilterKey, filter)
+		statsRepo = statsRepo.WithTopFilter(filter)
 	}
+	return statsRepo, hours
+}
+
+// respondStats writes a stats payload together with the effective time range, filters, rows and duration
+func (h *DashboardHandler) respondStats(c *gin.Context, data interface{}, hours int) {
+	h.respondSampledStats(c, data, hours, nil)
+}
+
+// respondSampledStats writes a stats payload computed from a sample (sampling nil = exact)
+func (h *DashboardHandler) respondSampledStats(c *gin.Context, data interface{}, hours int, sampling *repositories.Sampling) {
+	meta := newResponseMeta(c, data, hours)
+	meta.Filters = h.appliedFilters(c)
+	meta.Sampling = sampling
+	c.JSON(http.StatusOK, StatsResponse{Data: data, Meta: meta})
+}
+
+// respondIPStats writes the stats of one IP address, which service filters don't apply to
+func (h *DashboardHandler) respondIPStats(c *gin.Context, data interface{}, hours int) {
+	c.JSON(http.StatusOK, StatsResponse{Data: data, Meta: newResponseMeta(c, data, hours)})
+}
+
+// appliedFilters returns the service and own IP filters of a request
+func (h *DashboardHandler) appliedFilters(c *gin.Context) *AppliedFilters {
+	excludeIP, _, excludeServices := h.getExcludeOwnIP(c)
+	applied := &AppliedFilters{
+		Services:        h.getServiceFilters(c),
+		ExcludeOwnIP:    excludeIP,
+		ExcludeServices: excludeServices,
+	}
+	if value, ok := c.Get(topFilterKey); ok {
+		filter := value.(repositories.TopFilter)
+		applied.MinHits, applied.PathPrefix = filter.MinHits, filter.PathPrefix
+		applied.StatusClass = c.Query("status_class")
+	}
+	return applied
+}
+
+// wantsExact reports whether the request disabled approximate top-K results (exact=true)
+func wantsExact(c *gin.Context) bool {
+	return c.Query("exact") == "true"
 }
 
 // HandleDashboard renders the main dashboard page
 func (h *DashboardHandler) HandleDashboard(c *gin.Context) {
 
-	summary, err := h.statsRepo.GetSummary(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	summary, err := h.requestStatsRepo(c).GetSummary(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get summary stats", h.logger.Args("error", err))
+		h.logger.WithCaller().Error("Failed to get summary stats", logArgs(h.logger, c, "error", err))
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Failed to load dashboard data",
 		})
@@ -151,330 +242,833 @@ func (h *DashboardHandler) HandleDashboard(c *gin.Context) {
 
 // GetSummary returns summary statistics
 func (h *DashboardHandler) GetSummary(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	summary, err := h.statsRepo.GetSummary(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	summary, err := statsRepo.GetSummary(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get summary", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get summary"})
+		h.logger.WithCaller().Error("Failed to get summary", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get summary"))
 		return
 	}
 
-	c.JSON(http.StatusOK, summary)
+	h.respondStats(c, summary, hours)
 }
 
 // GetTimeline returns timeline statistics
+// baseline_weeks=4 adds to each bucket the average of the same bucket over the 4 previous weeks, to compare
+// traffic with its usual weekly pattern.
+// coverage=true adds the buckets without traffic and marks each bucket traffic, zero_traffic (a processor read the
+// sources) or no_data (nobody read them), so gaps of sources logging only on traffic aren't mistaken for outages.
 func (h *DashboardHandler) GetTimeline(c *gin.Context) {
-	hours := 168 // Default to 7 days
-	if hoursParam := c.Query("hours"); hoursParam != "" {
-		if h, err := strconv.Atoi(hoursParam); err == nil && h > 0 {
-			// Support various time ranges: 1h, 24h, 168h (7d), 720h (30d), or larger
-			if h <= 8760 { // Max 1 year (365 days)
-				hours = h
-			} else {
-				hours = 8760 // Cap at 1 year
-			}
-		}
+	// Support various time ranges: 1h, 24h, 168h (7d), 720h (30d), or larger (max 1 year)
+	hours := h.getLookbackHours(c)
+	baselineWeeks, ok := queryIntParam(c, "baseline_weeks", 0, 0, repositories.MaxBaselineWeeks)
+	if !ok {
+		return
+	}
+	if baselineWeeks > 0 && hours > repositories.MaxBaselineHours {
+		respondInvalidParams(c, []FieldError{{Field: "baseline_weeks", Message: fmt.Sprintf("only available for ranges up to %d hours", repositories.MaxBaselineHours)}})
+		return
 	}
 
-	timeline, err := h.statsRepo.GetTimelineStats(hours, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	statsRepo := h.requestStatsRepo(c)
+	filters := h.getServiceFilters(c)
+	timeline, err := statsRepo.GetTimelineStats(hours, filters, h.buildExcludeIPFilter(c))
+	if err == nil && c.Query("coverage") == "true" {
+		timeline, err = statsRepo.AddTimelineCoverage(timeline, hours)
+	}
+	if err == nil && baselineWeeks > 0 {
+		timeline, baselineWeeks, err = statsRepo.AddTimelineBaseline(timeline, hours, baselineWeeks, filters)
+	}
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get timeline", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get timeline"})
+		h.logger.WithCaller().Error("Failed to get timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get timeline"))
 		return
 	}
 
-	c.JSON(http.StatusOK, timeline)
+	meta := newResponseMeta(c, timeline, hours)
+	meta.Filters = h.appliedFilters(c)
+	meta.BaselineWeeks = baselineWeeks
+	c.JSON(http.StatusOK, StatsResponse{Data: timeline, Meta: meta})
 }
 
 // GetStatusCodeTimeline returns status code distribution over time
 func (h *DashboardHandler) GetStatusCodeTimeline(c *gin.Context) {
-	hours := 168 // Default to 7 days
-	if hoursParam := c.Query("hours"); hoursParam != "" {
-		if h, err := strconv.Atoi(hoursParam); err == nil && h > 0 {
-			if h <= 8760 {
-				hours = h
-			} else {
-				hours = 8760
-			}
-		}
+	hours := h.getLookbackHours(c)
+
+	timeline, err := h.requestStatsRepo(c).GetStatusCodeTimeline(hours, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get status code timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get status code timeline"))
+		return
 	}
 
-	timeline, err := h.statsRepo.GetStatusCodeTimeline(hours, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	h.respondStats(c, timeline, hours)
+}
+
+// GetContinentTimeline returns requests per continent over time
+func (h *DashboardHandler) GetContinentTimeline(c *gin.Context) {
+	hours := h.getLookbackHours(c)
+
+	timeline, err := h.requestStatsRepo(c).GetContinentTimeline(hours, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get continent timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get continent timeline"))
+		return
+	}
+
+	h.respondStats(c, timeline, hours)
+}
+
+// GetEUTimeline returns requests from inside and outside the European Union over time
+func (h *DashboardHandler) GetEUTimeline(c *gin.Context) {
+	hours := h.getLookbackHours(c)
+
+	timeline, err := h.requestStatsRepo(c).GetEUTimeline(hours, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get status code timeline", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status code timeline"})
+		h.logger.WithCaller().Error("Failed to get EU timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get EU timeline"))
 		return
 	}
 
-	c.JSON(http.StatusOK, timeline)
+	h.respondStats(c, timeline, hours)
 }
 
 // GetTrafficHeatmap returns traffic heatmap data grouped by day and hour
 func (h *DashboardHandler) GetTrafficHeatmap(c *gin.Context) {
-	days := 30
-	if daysParam := c.Query("days"); daysParam != "" {
-		if d, err := strconv.Atoi(daysParam); err == nil && d > 0 {
-			if d <= 365 {
-				days = d
-			} else {
-				days = 365
-			}
-		}
-	}
+	days := validatedInt(c, "days", 30)
 
-	data, err := h.statsRepo.GetTrafficHeatmap(days, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	data, err := h.requestStatsRepo(c).GetTrafficHeatmap(days, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get traffic heatmap", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get traffic heatmap"})
+		h.logger.WithCaller().Error("Failed to get traffic heatmap", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get traffic heatmap"))
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	h.respondStats(c, data, days*24)
 }
 
 // GetTopPaths returns top paths
 func (h *DashboardHandler) GetTopPaths(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	paths, err := h.statsRepo.GetTopPaths(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	filters, excludeIP := h.getServiceFilters(c), h.buildExcludeIPFilter(c)
+
+	// Large time ranges are sampled unless exact=true
+	var paths []*repositories.PathStats
+	var sampling *repositories.Sampling
+	var err error
+	if wantsExact(c) {
+		paths, err = statsRepo.GetTopPaths(limit, filters, excludeIP)
+	} else {
+		paths, sampling, err = statsRepo.GetTopPathsApprox(limit, filters, excludeIP)
+	}
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top paths", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top paths"})
+		h.logger.WithCaller().Error("Failed to get top paths", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top paths"))
 		return
 	}
 
-	c.JSON(http.StatusOK, paths)
+	h.respondSampledStats(c, paths, hours, sampling)
 }
 
 // GetTopCountries returns top countries
 func (h *DashboardHandler) GetTopCountries(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l >= 0 && l <= 500 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	// limit=0 returns all countries
+	limit, ok := queryIntParam(c, "limit", 10, 0, 500)
+	if !ok {
+		return
 	}
 
-	countries, err := h.statsRepo.GetTopCountries(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	countries, err := statsRepo.GetTopCountries(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top countries", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top countries"})
+		h.logger.WithCaller().Error("Failed to get top countries", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top countries"))
 		return
 	}
 
-	c.JSON(http.StatusOK, countries)
+	h.respondStats(c, countries, hours)
 }
 
 // GetTopIPs returns top IP addresses
 func (h *DashboardHandler) GetTopIPs(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	ips, err := h.statsRepo.GetTopIPAddresses(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	ips, err := statsRepo.GetTopIPAddresses(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top IPs", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top IPs"})
+		h.logger.WithCaller().Error("Failed to get top IPs", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top IPs"))
 		return
 	}
 
-	c.JSON(http.StatusOK, ips)
+	h.respondStats(c, ips, hours)
 }
 
 // GetTopUserAgents returns top user agents
 func (h *DashboardHandler) GetTopUserAgents(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	agents, err := h.statsRepo.GetTopUserAgents(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	filters, excludeIP := h.getServiceFilters(c), h.buildExcludeIPFilter(c)
+
+	// Large time ranges are sampled unless exact=true
+	var agents []*repositories.UserAgentStats
+	var sampling *repositories.Sampling
+	var err error
+	if wantsExact(c) {
+		agents, err = statsRepo.GetTopUserAgents(limit, filters, excludeIP)
+	} else {
+		agents, sampling, err = statsRepo.GetTopUserAgentsApprox(limit, filters, excludeIP)
+	}
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top user agents", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top user agents"})
+		h.logger.WithCaller().Error("Failed to get top user agents", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top user agents"))
 		return
 	}
 
-	c.JSON(http.StatusOK, agents)
+	h.respondSampledStats(c, agents, hours, sampling)
 }
 
 // GetTopReferrers returns top referrers
 func (h *DashboardHandler) GetTopReferrers(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	referrers, err := h.statsRepo.GetTopReferrers(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	filters, excludeIP := h.getServiceFilters(c), h.buildExcludeIPFilter(c)
+
+	// Large time ranges are sampled unless exact=true
+	var referrers []*repositories.ReferrerStats
+	var sampling *repositories.Sampling
+	var err error
+	if wantsExact(c) {
+		referrers, err = statsRepo.GetTopReferrers(limit, filters, excludeIP)
+	} else {
+		referrers, sampling, err = statsRepo.GetTopReferrersApprox(limit, filters, excludeIP)
+	}
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top referrers", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top referrers"})
+		h.logger.WithCaller().Error("Failed to get top referrers", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top referrers"))
 		return
 	}
 
-	c.JSON(http.StatusOK, referrers)
+	h.respondSampledStats(c, referrers, hours, sampling)
 }
 
 // GetTopReferrerDomains returns top referrer domains
 func (h *DashboardHandler) GetTopReferrerDomains(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
-			// Allow up to 500 domains, or unlimited if limit=0
-			if l <= 500 || l == 0 {
-				limit = l
-			} else {
-				limit = 500
-			}
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 500)
+	if !ok {
+		return
 	}
 
-	domains, err := h.statsRepo.GetTopReferrerDomains(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	domains, err := statsRepo.GetTopReferrerDomains(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top referrer domains", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top referrer domains"})
+		h.logger.WithCaller().Error("Failed to get top referrer domains", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top referrer domains"))
 		return
 	}
 
-	c.JSON(http.StatusOK, domains)
+	h.respondStats(c, domains, hours)
 }
 
 // GetTopBackends returns top backends
 func (h *DashboardHandler) GetTopBackends(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	backends, err := h.statsRepo.GetTopBackends(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	backends, err := statsRepo.GetTopBackends(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top backends", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top backends"})
+		h.logger.WithCaller().Error("Failed to get top backends", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top backends"))
 		return
 	}
 
-	c.JSON(http.StatusOK, backends)
+	h.respondStats(c, backends, hours)
+}
+
+// GetTopUpstreams returns the origin instances (upstream addresses) of each backend
+func (h *DashboardHandler) GetTopUpstreams(c *gin.Context) {
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 200)
+	if !ok {
+		return
+	}
+
+	upstreams, err := statsRepo.GetTopUpstreams(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get top upstreams", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top upstreams"))
+		return
+	}
+
+	h.respondStats(c, upstreams, hours)
+}
+
+// GetBackendFingerprints returns the backend technologies and caching behavior per service (CAPTURE_RESPONSE_HEADERS)
+func (h *DashboardHandler) GetBackendFingerprints(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 200)
+	if !ok {
+		return
+	}
+
+	fingerprints, err := statsRepo.GetBackendFingerprints(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get backend fingerprints", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get backend fingerprints"))
+		return
+	}
+
+	h.respondStats(c, fingerprints, hours)
+}
+
+// GetTopRouters returns top routers (Traefik RouterName)
+func (h *DashboardHandler) GetTopRouters(c *gin.Context) {
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
+	}
+
+	routers, err := statsRepo.GetTopRouters(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get top routers", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top routers"))
+		return
+	}
+
+	h.respondStats(c, routers, hours)
+}
+
+// GetRouterDetail returns the timeline, errors and latency of a router
+func (h *DashboardHandler) GetRouterDetail(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+	router := c.Param("router")
+
+	detail, err := statsRepo.GetRouterDetail(router, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get router detail", logArgs(h.logger, c, "router", router, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get router detail"))
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, errorBody(c, "No requests for this router in the selected time range"))
+		return
+	}
+
+	h.respondStats(c, detail, hours)
+}
+
+// GetTrace returns the requests sharing a trace ID or request ID, with the service and latency of each hop
+// Traces are looked up whatever the time range.
+func (h *DashboardHandler) GetTrace(c *gin.Context) {
+	id := c.Param("id")
+
+	trace, err := h.requestStatsRepo(c).GetTrace(id)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get trace", logArgs(h.logger, c, "trace_id", id, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get trace"))
+		return
+	}
+	if trace == nil {
+		c.JSON(http.StatusNotFound, errorBody(c, "No request with this trace ID or request ID"))
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
+
+// GetServiceDependencies returns the calls between services seen in traces
+func (h *DashboardHandler) GetServiceDependencies(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	dependencies, err := statsRepo.GetServiceDependencies(limit)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get service dependencies", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get service dependencies"))
+		return
+	}
+
+	h.respondStats(c, dependencies, hours)
+}
+
+// GetTopConsumers returns top API consumers (authenticated users and API keys) with their quota usage
+func (h *DashboardHandler) GetTopConsumers(c *gin.Context) {
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
+	}
+
+	consumers, err := statsRepo.GetTopConsumers(h.consumerQuotas, limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get top consumers", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top consumers"))
+		return
+	}
+
+	h.respondStats(c, consumers, hours)
+}
+
+// GetConsumerDetail returns the quota usage, timeline, errors and latency of an API consumer
+func (h *DashboardHandler) GetConsumerDetail(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+	consumer := c.Param("consumer")
+
+	detail, err := statsRepo.GetConsumerDetail(consumer, h.consumerQuotas, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get consumer detail", logArgs(h.logger, c, "consumer", consumer, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get consumer detail"))
+		return
+	}
+	if detail == nil {
+		c.JSON(http.StatusNotFound, errorBody(c, "No requests for this consumer in the selected time range"))
+		return
+	}
+
+	h.respondStats(c, detail, hours)
 }
 
 // GetTopASNs returns top ASNs
 func (h *DashboardHandler) GetTopASNs(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	asns, err := h.statsRepo.GetTopASNs(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	asns, err := statsRepo.GetTopASNs(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top ASNs", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top ASNs"})
+		h.logger.WithCaller().Error("Failed to get top ASNs", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top ASNs"))
 		return
 	}
 
-	c.JSON(http.StatusOK, asns)
+	h.respondStats(c, asns, hours)
 }
 
 // GetStatusCodeDistribution returns status code distribution
 func (h *DashboardHandler) GetStatusCodeDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	stats, err := h.statsRepo.GetStatusCodeDistribution(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	stats, err := statsRepo.GetStatusCodeDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get status code distribution", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status code distribution"})
+		h.logger.WithCaller().Error("Failed to get status code distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get status code distribution"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondStats(c, stats, hours)
 }
 
 // GetMethodDistribution returns HTTP method distribution
 func (h *DashboardHandler) GetMethodDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	stats, err := h.statsRepo.GetMethodDistribution(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	stats, err := statsRepo.GetMethodDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get method distribution", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get method distribution"})
+		h.logger.WithCaller().Error("Failed to get method distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get method distribution"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondStats(c, stats, hours)
+}
+
+// GetContentClassDistribution returns responses, bandwidth and average size per content class (html, json, image,
+// video, font, other) with the content types of each class
+func (h *DashboardHandler) GetContentClassDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	stats, err := statsRepo.GetContentClassDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get content type distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get content type distribution"))
+		return
+	}
+
+	h.respondStats(c, stats, hours)
+}
+
+// GetTopContentTypes returns the content types with the most responses
+func (h *DashboardHandler) GetTopContentTypes(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 200)
+	if !ok {
+		return
+	}
+
+	stats, err := statsRepo.GetTopContentTypes(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get top content types", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top content types"))
+		return
+	}
+
+	h.respondStats(c, stats, hours)
+}
+
+// GetDownloadReport returns the largest responses and the files sending the most bytes
+// min_size_kb sets the response size from which responses are counted (default 1024 = 1 MB).
+func (h *DashboardHandler) GetDownloadReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 200)
+	if !ok {
+		return
+	}
+	minSizeKB, ok := queryIntParam(c, "min_size_kb", repositories.DefaultDownloadMinSize>>10, 1, 1<<30)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetDownloadReport(int64(minSizeKB)<<10, limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get download report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get download report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetNotFoundReport returns the paths answered with 404, with their first and last occurrence and referrers
+func (h *DashboardHandler) GetNotFoundReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetNotFoundReport(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get 404 report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get 404 report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetContinentDistribution returns requests per continent
+func (h *DashboardHandler) GetContinentDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	stats, err := statsRepo.GetContinentDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get continent distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get continent distribution"))
+		return
+	}
+
+	h.respondStats(c, stats, hours)
+}
+
+// GetEUDistribution returns requests from inside and outside the European Union
+func (h *DashboardHandler) GetEUDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	stats, err := statsRepo.GetEUDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get EU distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get EU distribution"))
+		return
+	}
+
+	h.respondStats(c, stats, hours)
+}
+
+// GetUnusualMethods returns methods outside the standard set (and disallowed ones) with their top sources over time
+func (h *DashboardHandler) GetUnusualMethods(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	report, err := statsRepo.GetUnusualMethods(h.disallowedMethods, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get unusual methods", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get unusual methods"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetPreflightReport returns OPTIONS (CORS preflight) volume, failures and the origins and paths sending them
+func (h *DashboardHandler) GetPreflightReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+	limit, ok := queryLimit(c, 20, 200)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetPreflightReport(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get preflight report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get preflight report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetTrafficSnapshot returns what was happening around a time (at, RFC 3339) in one call
+// window_minutes sets the minutes covered on each side of at.
+func (h *DashboardHandler) GetTrafficSnapshot(c *gin.Context) {
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		respondInvalidParams(c, []FieldError{{Field: "at", Message: "must be an RFC 3339 time"}})
+		return
+	}
+	window, ok := queryIntParam(c, "window_minutes", 5, 1, int(repositories.MaxSnapshotWindow/time.Minute))
+	if !ok {
+		return
+	}
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.requestStatsRepo(c).GetTrafficSnapshot(at, time.Duration(window)*time.Minute, limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get traffic snapshot", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get traffic snapshot"))
+		return
+	}
+
+	meta := newResponseMeta(c, snapshot, 0)
+	meta.Range = repositories.TimeRange{Start: snapshot.From, End: snapshot.To}
+	meta.Filters = h.appliedFilters(c)
+	c.JSON(http.StatusOK, StatsResponse{Data: snapshot, Meta: meta})
+}
+
+// GetSourceHostStats returns the requests per host of the attached databases (ATTACH_DATABASES)
+func (h *DashboardHandler) GetSourceHostStats(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	hosts, err := statsRepo.GetSourceHostStats(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get source host stats", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get source host stats"))
+		return
+	}
+
+	h.respondStats(c, hosts, hours)
+}
+
+// GetClientVersionTimeline returns version adoption over time of a configured client (client) or a browser (browser)
+func (h *DashboardHandler) GetClientVersionTimeline(c *gin.Context) {
+	client, browser := c.Query("client"), c.Query("browser")
+	var rule *repositories.ClientVersionRule
+	switch {
+	case (client == "") == (browser == ""):
+		respondInvalidParams(c, []FieldError{{Field: "client", Message: "set either client (a CLIENT_VERSION_RULES name) or browser"}})
+		return
+	case client != "":
+		if rule = h.clientVersions.Rule(client); rule == nil {
+			respondInvalidParams(c, []FieldError{{Field: "client", Message: fmt.Sprintf("unknown client, configured clients: %s", strings.Join(h.clientVersions.Names(), ", "))}})
+			return
+		}
+	}
+
+	statsRepo, hours := h.statsRepoFor(c)
+	filters, excludeIP := h.getServiceFilters(c), h.buildExcludeIPFilter(c)
+
+	var report *repositories.ClientVersionReport
+	var err error
+	if rule != nil {
+		report, err = statsRepo.GetClientVersionTimeline(rule, filters, excludeIP)
+	} else {
+		report, err = statsRepo.GetBrowserVersionTimeline(browser, filters, excludeIP)
+	}
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get client version timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get client version timeline"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
 }
 
 // GetProtocolDistribution returns HTTP protocol distribution
 func (h *DashboardHandler) GetProtocolDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	stats, err := h.statsRepo.GetProtocolDistribution(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	stats, err := statsRepo.GetProtocolDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get protocol distribution", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get protocol distribution"})
+		h.logger.WithCaller().Error("Failed to get protocol distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get protocol distribution"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondStats(c, stats, hours)
 }
 
 // GetTLSVersionDistribution returns TLS version distribution
 func (h *DashboardHandler) GetTLSVersionDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	stats, err := h.statsRepo.GetTLSVersionDistribution(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	stats, err := statsRepo.GetTLSVersionDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get TLS version distribution", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get TLS version distribution"})
+		h.logger.WithCaller().Error("Failed to get TLS version distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get TLS version distribution"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondStats(c, stats, hours)
 }
 
 // GetResponseTimeStats returns response time statistics
 func (h *DashboardHandler) GetResponseTimeStats(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	stats, err := h.statsRepo.GetResponseTimeStats(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	stats, err := statsRepo.GetResponseTimeStats(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get response time stats", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get response time stats"})
+		h.logger.WithCaller().Error("Failed to get response time stats", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get response time stats"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondStats(c, stats, hours)
+}
+
+// GetClientAbortReport returns client-closed (499) and request timeout (408) analytics
+func (h *DashboardHandler) GetClientAbortReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetClientAbortReport(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get client abort report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get client abort report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetDataQuality returns the share of requests missing key fields per source, to spot log formats dropping information
+func (h *DashboardHandler) GetDataQuality(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	report, err := statsRepo.GetDataQuality(h.getServiceFilters(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get data quality report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get data quality report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetLabels returns the labels of the requests in the time range with their most frequent value
+// Each label groups structured queries as "label:<name>" and filters searches and exports as label[<name>]=value.
+func (h *DashboardHandler) GetLabels(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	labels, err := statsRepo.GetLabels(h.getServiceFilters(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get request labels", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get request labels"))
+		return
+	}
+
+	h.respondStats(c, labels, hours)
+}
+
+// GetWAFReport returns WAF blocked and detected requests per client IP, path and rule, with the requests
+// the same client IPs and paths got through
+func (h *DashboardHandler) GetWAFReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetWAFReport(limit)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get WAF report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get WAF report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
+}
+
+// GetCostReport returns estimated egress cost over time, per path and per service
+func (h *DashboardHandler) GetCostReport(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	report, err := statsRepo.GetCostReport(h.costModel, limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get cost report", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get cost report"))
+		return
+	}
+
+	h.respondStats(c, report, hours)
 }
 
 // GetRecentRequests returns recent HTTP requests
 func (h *DashboardHandler) GetRecentRequests(c *gin.Context) {
-	limit := 100
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 1000 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 100, 1000)
+	if !ok {
+		return
 	}
 
-	offset := 0
-	if offsetParam := c.Query("offset"); offsetParam != "" {
-		if o, err := strconv.Atoi(offsetParam); err == nil && o >= 0 {
-			offset = o
-		}
-	}
+	offset := validatedInt(c, "offset", 0)
 
 	serviceFilters := h.getServiceFilters(c)
 
 	excludeIPFilter := h.buildExcludeIPFilter(c)
 	var excludeIP string
-	var excludeSvcs []repositories.ServiceFilter
+	var excludeSvcs []filter.Service
 	if excludeIPFilter != nil {
 		excludeIP = excludeIPFilter.ClientIP
 		excludeSvcs = excludeIPFilter.ExcludeServices
@@ -483,27 +1077,338 @@ func (h *DashboardHandler) GetRecentRequests(c *gin.Context) {
 	// Use first service for legacy FindAll method (or empty if no filter)
 	serviceName := ""
 	serviceType := "auto"
+	serviceMatchType := ""
 	if len(serviceFilters) > 0 {
 		serviceName = serviceFilters[0].Name
 		serviceType = serviceFilters[0].Type
+		serviceMatchType = serviceFilters[0].MatchType
+	}
+
+	format, err := parseStreamFormat(c, FormatJSON)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	fields, err := parseRequestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	// Rows are written as they are read instead of being loaded into memory first
+	stream := newRequestStream(c, format, "", fields)
+	err = h.httpRepo.Stream(repositories.RequestFilter{
+		Limit:            limit,
+		Offset:           offset,
+		ServiceName:      serviceName,
+		ServiceType:      serviceType,
+		ServiceMatchType: serviceMatchType,
+		ExcludeIP:        excludeIP,
+		ExcludeServices:  excludeSvcs,
+		Fields:           fields,
+		Context:          c.Request.Context(),
+	}, stream.Write)
+	if !stream.Finish(err) {
+		h.logger.WithCaller().Error("Failed to get recent requests", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get recent requests"))
+		return
+	}
+	if err != nil {
+		h.logger.Debug("Recent requests stream interrupted", logArgs(h.logger, c, "error", err))
+	}
+}
+
+// ExportRequests streams up to maxExportRows requests as NDJSON (default) or a JSON array
+// Supports the same service and exclude_own_ip filters as the recent requests, plus an "ip" filter
+func (h *DashboardHandler) ExportRequests(c *gin.Context) {
+	format, err := parseStreamFormat(c, FormatNDJSON)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	fields, err := parseRequestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	headers, err := parseHeaderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	labels, err := parseLabelFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	limit, ok := queryLimit(c, defaultExportRows, maxExportRows)
+	if !ok {
+		return
+	}
+
+	offset := validatedInt(c, "offset", 0)
+
+	filter := repositories.RequestFilter{
+		Limit:    limit,
+		Offset:   offset,
+		ClientIP: c.Query("ip"),
+		Fields:   fields,
+		Headers:  headers,
+		Labels:   labels,
+		Context:  c.Request.Context(),
+	}
+
+	// The export streams from a single query, so only the first service filter applies (as for recent requests)
+	if serviceFilters := h.getServiceFilters(c); len(serviceFilters) > 0 {
+		filter.ServiceName = serviceFilters[0].Name
+		filter.ServiceType = serviceFilters[0].Type
+		filter.ServiceMatchType = serviceFilters[0].MatchType
+	}
+	if excludeIPFilter := h.buildExcludeIPFilter(c); excludeIPFilter != nil {
+		filter.ExcludeIP = excludeIPFilter.ClientIP
+		filter.ExcludeServices = excludeIPFilter.ExcludeServices
+	}
+
+	started := time.Now()
+	stream := newRequestStream(c, format, "loglynx-requests-"+started.Format("20060102-150405"), fields)
+	err = h.httpRepo.Stream(filter, stream.Write)
+	if !stream.Finish(err) {
+		h.logger.WithCaller().Error("Failed to export requests", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to export requests"))
+		return
+	}
+	if err != nil {
+		h.logger.Warn("Request export interrupted", logArgs(h.logger, c, "rows", stream.rows, "error", err))
+		return
+	}
+
+	h.logger.Debug("Requests exported",
+		logArgs(h.logger, c, "rows", stream.rows, "format", format, "duration", time.Since(started).Round(time.Millisecond)))
+}
+
+// ExportTrafficRollups streams the hourly traffic rollups (service, status class, country) of a range as NDJSON
+// (default) or CSV (format=csv), for BI tools loading them on a schedule. from and to are RFC 3339 times,
+// by default the 24 hours before the last rolled up hour; only rolled up hours are exported, the end of the last
+// one is sent in the X-Rollups-Built-Until header so loaders know where to resume.
+func (h *DashboardHandler) ExportTrafficRollups(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatNDJSON)
+	if format != FormatNDJSON && format != FormatCSV {
+		respondInvalidParams(c, []FieldError{{Field: "format", Message: "must be ndjson or csv"}})
+		return
+	}
+	statsRepo := h.requestStatsRepo(c)
+
+	builtUntil, err := statsRepo.GetTrafficRollupsBuiltUntil()
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get traffic rollups", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to export traffic rollups"))
+		return
+	}
+	if builtUntil.IsZero() {
+		c.JSON(http.StatusServiceUnavailable, errorBody(c, "Traffic rollups not built yet (RESPONSE_TIME_ROLLUP_INTERVAL)"))
+		return
+	}
+
+	to, from := builtUntil, builtUntil.Add(-24*time.Hour)
+	var invalid []FieldError
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				invalid = append(invalid, FieldError{Field: param.name, Message: "must be an RFC 3339 time"})
+				continue
+			}
+			*param.value = parsed
+		}
+	}
+	if len(invalid) == 0 && !from.Before(to) {
+		invalid = append(invalid, FieldError{Field: "from", Message: "must be before to"})
+	}
+	if len(invalid) > 0 {
+		respondInvalidParams(c, invalid)
+		return
+	}
+	if to.After(builtUntil) {
+		to = builtUntil
 	}
 
-	requests, err := h.httpRepo.FindAll(limit, offset, serviceName, serviceType, excludeIP, excludeSvcs)
+	c.Header("X-Rollups-Built-Until", builtUntil.Format(time.RFC3339))
+	stream := newRollupStream(c, format, "loglynx-traffic-"+from.UTC().Format("20060102T15")+"-"+to.UTC().Format("20060102T15"))
+	err = statsRepo.StreamTrafficRollups(from.UTC().Truncate(time.Hour), to, stream.Write)
+	if !stream.Finish(err) {
+		h.logger.WithCaller().Error("Failed to export traffic rollups", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to export traffic rollups"))
+		return
+	}
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get recent requests", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get recent requests"})
+		h.logger.Warn("Traffic rollup export interrupted", logArgs(h.logger, c, "rows", stream.rows, "error", err))
+	}
+}
+
+// SearchRequests streams the most recent requests whose path, referrer or user agent contain the query
+// Words match anywhere ("api users" finds /api/v1/users), "quoted phrases" match as written.
+// Uses the full-text index when available (X-Search-Index header), otherwise scans with LIKE. Rate limited per
+// client IP (429).
+// header[X-Tenant-Id]=acme narrows the search to requests with that captured header value, label[customer]=acme
+// to requests with that label.
+func (h *DashboardHandler) SearchRequests(c *gin.Context) {
+	search, err := repositories.ParseRequestSearch(c.Query("q"), c.Query("field"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	format, err := parseStreamFormat(c, FormatJSON)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	fields, err := parseRequestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	headers, err := parseHeaderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	labels, err := parseLabelFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, requests)
+	limit, ok := queryLimit(c, 100, 1000)
+	if !ok || !h.allowSearch(c) {
+		return
+	}
+
+	offset := validatedInt(c, "offset", 0)
+
+	filter := repositories.RequestFilter{
+		Limit:   limit,
+		Offset:  offset,
+		Fields:  fields,
+		Search:  search,
+		Headers: headers,
+		Labels:  labels,
+		Context: c.Request.Context(),
+	}
+
+	// Searches cover all stored requests unless a time range is given
+	if c.Query("hours") != "" {
+		filter.Since = time.Now().Add(-time.Duration(h.getLookbackHours(c)) * time.Hour)
+	}
+	if serviceFilters := h.getServiceFilters(c); len(serviceFilters) > 0 {
+		filter.ServiceName = serviceFilters[0].Name
+		filter.ServiceType = serviceFilters[0].Type
+		filter.ServiceMatchType = serviceFilters[0].MatchType
+	}
+	if excludeIPFilter := h.buildExcludeIPFilter(c); excludeIPFilter != nil {
+		filter.ExcludeIP = excludeIPFilter.ClientIP
+		filter.ExcludeServices = excludeIPFilter.ExcludeServices
+	}
+
+	c.Header("X-Search-Index", strconv.FormatBool(h.httpRepo.SearchIndexAvailable()))
+	stream := newRequestStream(c, format, "", fields)
+	err = h.httpRepo.Stream(filter, stream.Write)
+	if !stream.Finish(err) {
+		h.logger.WithCaller().Error("Failed to search requests", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to search requests"))
+		return
+	}
+	if err != nil {
+		h.logger.Debug("Request search stream interrupted", logArgs(h.logger, c, "error", err))
+	}
+}
+
+// GetSlowRequests returns slow requests, newest first, with counts by path and backend
+// Requests are slower than the threshold parameter, or than the configured threshold of their service.
+func (h *DashboardHandler) GetSlowRequests(c *gin.Context) {
+	var threshold float64 // Per-service thresholds
+	if thresholdParam := c.Query("threshold"); thresholdParam != "" {
+		t, err := strconv.ParseFloat(thresholdParam, 64)
+		if err != nil || t <= 0 {
+			respondInvalidParams(c, []FieldError{{Field: "threshold", Message: "must be a positive number of milliseconds"}})
+			return
+		}
+		threshold = t
+	}
+	fields, err := parseRequestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	limit, ok := queryLimit(c, 100, 1000)
+	if !ok {
+		return
+	}
+
+	offset := validatedInt(c, "offset", 0)
+
+	statsRepo, hours := h.statsRepoFor(c)
+	serviceFilters, excludeIPFilter := h.getServiceFilters(c), h.buildExcludeIPFilter(c)
+
+	summary, err := statsRepo.GetSlowRequestSummary(threshold, 10, serviceFilters, excludeIPFilter)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get slow request summary", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get slow requests"))
+		return
+	}
+
+	filter := repositories.RequestFilter{
+		Limit:   limit,
+		Offset:  offset,
+		Since:   time.Now().Add(-time.Duration(hours) * time.Hour),
+		Slow:    &repositories.SlowFilter{ThresholdMs: threshold, Policy: statsRepo.SlowPolicy()},
+		Fields:  fields,
+		Context: c.Request.Context(),
+	}
+
+	// Rows come from a single query, so only the first service filter applies (as for recent requests)
+	if len(serviceFilters) > 0 {
+		filter.ServiceName = serviceFilters[0].Name
+		filter.ServiceType = serviceFilters[0].Type
+		filter.ServiceMatchType = serviceFilters[0].MatchType
+	}
+	if excludeIPFilter != nil {
+		filter.ExcludeIP = excludeIPFilter.ClientIP
+		filter.ExcludeServices = excludeIPFilter.ExcludeServices
+	}
+
+	requests := make([]interface{}, 0, limit)
+	err = h.httpRepo.Stream(filter, func(request *models.HTTPRequest) error {
+		if fields == nil {
+			requests = append(requests, request)
+			return nil
+		}
+		data, err := fields.MarshalRequest(request)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, json.RawMessage(data))
+		return nil
+	})
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get slow requests", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get slow requests"))
+		return
+	}
+
+	h.respondStats(c, gin.H{"summary": summary, "requests": requests}, hours)
 }
 
 // GetLogProcessingStats returns log processing statistics
 func (h *DashboardHandler) GetLogProcessingStats(c *gin.Context) {
-	stats, err := h.statsRepo.GetLogProcessingStats()
+	stats, err := h.requestStatsRepo(c).GetLogProcessingStats()
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get log processing stats", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get log processing stats"})
+		h.logger.WithCaller().Error("Failed to get log processing stats", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get log processing stats"))
 		return
 	}
 
@@ -512,62 +1417,63 @@ func (h *DashboardHandler) GetLogProcessingStats(c *gin.Context) {
 
 // GetTopBrowsers returns top browsers
 func (h *DashboardHandler) GetTopBrowsers(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	browsers, err := h.statsRepo.GetTopBrowsers(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	browsers, err := statsRepo.GetTopBrowsers(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top browsers", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top browsers"})
+		h.logger.WithCaller().Error("Failed to get top browsers", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top browsers"))
 		return
 	}
 
-	c.JSON(http.StatusOK, browsers)
+	h.respondStats(c, browsers, hours)
 }
 
 // GetTopOperatingSystems returns top operating systems
 func (h *DashboardHandler) GetTopOperatingSystems(c *gin.Context) {
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	statsRepo, hours := h.topStatsRepoFor(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	osList, err := h.statsRepo.GetTopOperatingSystems(limit, h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	osList, err := statsRepo.GetTopOperatingSystems(limit, h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get top operating systems", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get top operating systems"})
+		h.logger.WithCaller().Error("Failed to get top operating systems", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get top operating systems"))
 		return
 	}
 
-	c.JSON(http.StatusOK, osList)
+	h.respondStats(c, osList, hours)
 }
 
 // GetDeviceTypeDistribution returns device type distribution
 func (h *DashboardHandler) GetDeviceTypeDistribution(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
 
-	devices, err := h.statsRepo.GetDeviceTypeDistribution(h.convertToRepoFilters(h.getServiceFilters(c)), h.buildExcludeIPFilter(c))
+	devices, err := statsRepo.GetDeviceTypeDistribution(h.getServiceFilters(c), h.buildExcludeIPFilter(c))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get device type distribution", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get device type distribution"})
+		h.logger.WithCaller().Error("Failed to get device type distribution", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get device type distribution"))
 		return
 	}
 
-	c.JSON(http.StatusOK, devices)
+	h.respondStats(c, devices, hours)
 }
 
 // GetDomains returns all unique domains/hosts with request counts
 // DEPRECATED: Use GetServices() instead
 func (h *DashboardHandler) GetDomains(c *gin.Context) {
-	domains, err := h.statsRepo.GetDomains()
+	domains, err := h.requestStatsRepo(c).GetDomains()
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get domains", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get domains"})
+		h.logger.WithCaller().Error("Failed to get domains", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get domains"))
 		return
 	}
 
@@ -577,16 +1483,41 @@ func (h *DashboardHandler) GetDomains(c *gin.Context) {
 // GetServices returns all unique services with their types and request counts
 // Supports filtering by backend_name, backend_url, and host with priority fallback
 func (h *DashboardHandler) GetServices(c *gin.Context) {
-	services, err := h.statsRepo.GetServices()
+	services, err := h.requestStatsRepo(c).GetServices()
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get services", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get services"})
+		h.logger.WithCaller().Error("Failed to get services", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get services"))
 		return
 	}
 
 	c.JSON(http.StatusOK, services)
 }
 
+// GetFieldValues returns the distinct values of a filter field with their requests, for dropdowns and autocomplete
+// field is host, backend_name, browser, os or geo_country; q keeps the values containing it.
+func (h *DashboardHandler) GetFieldValues(c *gin.Context) {
+	field := c.Query("field")
+	if !repositories.IsFieldValueField(field) {
+		respondInvalidParams(c, []FieldError{{Field: "field", Message: "must be one of " + strings.Join(repositories.FieldValueFields, ", ")}})
+		return
+	}
+	statsRepo, hours := h.statsRepoFor(c)
+
+	limit, ok := queryLimit(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	values, err := statsRepo.GetFieldValues(field, strings.TrimSpace(c.Query("q")), limit)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get field values", logArgs(h.logger, c, "field", field, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get field values"))
+		return
+	}
+
+	h.respondStats(c, values, hours)
+}
+
 // ============================================
 // IP Analytics Handlers
 // ============================================
@@ -595,268 +1526,270 @@ func (h *DashboardHandler) GetServices(c *gin.Context) {
 func (h *DashboardHandler) GetIPDetailedStats(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	stats, err := h.statsRepo.GetIPDetailedStats(ip)
+	stats, err := h.requestStatsRepo(c).GetIPDetailedStats(ip)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP stats", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP statistics"})
+		h.logger.WithCaller().Error("Failed to get IP stats", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP statistics"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondIPStats(c, stats, h.statsRepo.LookbackHours())
 }
 
 // GetIPTimeline returns timeline data for a specific IP
 func (h *DashboardHandler) GetIPTimeline(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	hours := 168 // Default to 7 days
-	if hoursParam := c.Query("hours"); hoursParam != "" {
-		if h, err := strconv.Atoi(hoursParam); err == nil && h > 0 && h <= 8760 {
-			hours = h
-		}
-	}
+	hours := validatedInt(c, "hours", 168) // Default to 7 days
 
-	timeline, err := h.statsRepo.GetIPTimelineStats(ip, hours)
+	timeline, err := h.requestStatsRepo(c).GetIPTimelineStats(ip, hours)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP timeline", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP timeline"})
+		h.logger.WithCaller().Error("Failed to get IP timeline", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP timeline"))
 		return
 	}
 
-	c.JSON(http.StatusOK, timeline)
+	h.respondIPStats(c, timeline, hours)
 }
 
 // GetIPHeatmap returns traffic heatmap for a specific IP
 func (h *DashboardHandler) GetIPHeatmap(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	days := 30
-	if daysParam := c.Query("days"); daysParam != "" {
-		if d, err := strconv.Atoi(daysParam); err == nil && d > 0 && d <= 365 {
-			days = d
-		}
-	}
+	days := validatedInt(c, "days", 30)
 
-	heatmap, err := h.statsRepo.GetIPTrafficHeatmap(ip, days)
+	heatmap, err := h.requestStatsRepo(c).GetIPTrafficHeatmap(ip, days)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP heatmap", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP heatmap"})
+		h.logger.WithCaller().Error("Failed to get IP heatmap", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP heatmap"))
 		return
 	}
 
-	c.JSON(http.StatusOK, heatmap)
+	h.respondIPStats(c, heatmap, days*24)
 }
 
 // GetIPTopPaths returns top paths for a specific IP
 func (h *DashboardHandler) GetIPTopPaths(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	limit := 20
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 20, 100)
+	if !ok {
+		return
 	}
 
-	paths, err := h.statsRepo.GetIPTopPaths(ip, limit)
+	paths, err := h.requestStatsRepo(c).GetIPTopPaths(ip, limit)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP top paths", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP top paths"})
+		h.logger.WithCaller().Error("Failed to get IP top paths", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP top paths"))
 		return
 	}
 
-	c.JSON(http.StatusOK, paths)
+	h.respondIPStats(c, paths, h.statsRepo.LookbackHours())
 }
 
 // GetIPTopBackends returns top backends for a specific IP
 func (h *DashboardHandler) GetIPTopBackends(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	backends, err := h.statsRepo.GetIPTopBackends(ip, limit)
+	backends, err := h.requestStatsRepo(c).GetIPTopBackends(ip, limit)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP top backends", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP top backends"})
+		h.logger.WithCaller().Error("Failed to get IP top backends", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP top backends"))
 		return
 	}
 
-	c.JSON(http.StatusOK, backends)
+	h.respondIPStats(c, backends, h.statsRepo.LookbackHours())
 }
 
 // GetIPStatusCodeDistribution returns status code distribution for a specific IP
 func (h *DashboardHandler) GetIPStatusCodeDistribution(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	stats, err := h.statsRepo.GetIPStatusCodeDistribution(ip)
+	stats, err := h.requestStatsRepo(c).GetIPStatusCodeDistribution(ip)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP status codes", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP status codes"})
+		h.logger.WithCaller().Error("Failed to get IP status codes", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP status codes"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondIPStats(c, stats, h.statsRepo.LookbackHours())
 }
 
 // GetIPTopBrowsers returns top browsers for a specific IP
 func (h *DashboardHandler) GetIPTopBrowsers(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	browsers, err := h.statsRepo.GetIPTopBrowsers(ip, limit)
+	browsers, err := h.requestStatsRepo(c).GetIPTopBrowsers(ip, limit)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP top browsers", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP top browsers"})
+		h.logger.WithCaller().Error("Failed to get IP top browsers", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP top browsers"))
 		return
 	}
 
-	c.JSON(http.StatusOK, browsers)
+	h.respondIPStats(c, browsers, h.statsRepo.LookbackHours())
 }
 
 // GetIPTopOperatingSystems returns top operating systems for a specific IP
 func (h *DashboardHandler) GetIPTopOperatingSystems(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	limit := 10
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
 	}
 
-	osList, err := h.statsRepo.GetIPTopOperatingSystems(ip, limit)
+	osList, err := h.requestStatsRepo(c).GetIPTopOperatingSystems(ip, limit)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP top OS", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP top operating systems"})
+		h.logger.WithCaller().Error("Failed to get IP top OS", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP top operating systems"))
 		return
 	}
 
-	c.JSON(http.StatusOK, osList)
+	h.respondIPStats(c, osList, h.statsRepo.LookbackHours())
 }
 
 // GetIPDeviceTypeDistribution returns device type distribution for a specific IP
 func (h *DashboardHandler) GetIPDeviceTypeDistribution(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	devices, err := h.statsRepo.GetIPDeviceTypeDistribution(ip)
+	devices, err := h.requestStatsRepo(c).GetIPDeviceTypeDistribution(ip)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP device types", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP device types"})
+		h.logger.WithCaller().Error("Failed to get IP device types", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP device types"))
 		return
 	}
 
-	c.JSON(http.StatusOK, devices)
+	h.respondIPStats(c, devices, h.statsRepo.LookbackHours())
 }
 
 // GetIPResponseTimeStats returns response time statistics for a specific IP
 func (h *DashboardHandler) GetIPResponseTimeStats(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	stats, err := h.statsRepo.GetIPResponseTimeStats(ip)
+	stats, err := h.requestStatsRepo(c).GetIPResponseTimeStats(ip)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP response time stats", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP response time stats"})
+		h.logger.WithCaller().Error("Failed to get IP response time stats", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP response time stats"))
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	h.respondIPStats(c, stats, h.statsRepo.LookbackHours())
 }
 
 // GetIPRecentRequests returns recent requests for a specific IP
 func (h *DashboardHandler) GetIPRecentRequests(c *gin.Context) {
 	ip := c.Param("ip")
 	if ip == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "IP address is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "IP address is required"))
 		return
 	}
 
-	limit := 50
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 500 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 50, 500)
+	if !ok {
+		return
 	}
 
-	requests, err := h.statsRepo.GetIPRecentRequests(ip, limit)
+	format, err := parseStreamFormat(c, FormatJSON)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get IP recent requests", h.logger.Args("ip", ip, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get IP recent requests"})
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	fields, err := parseRequestFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, requests)
+	stream := newRequestStream(c, format, "", fields)
+	err = h.httpRepo.Stream(repositories.RequestFilter{
+		Limit:    limit,
+		ClientIP: ip,
+		Since:    time.Now().Add(-time.Duration(h.statsRepo.LookbackHours()) * time.Hour),
+		Fields:   fields,
+		Context:  c.Request.Context(),
+	}, stream.Write)
+	if !stream.Finish(err) {
+		h.logger.WithCaller().Error("Failed to get IP recent requests", logArgs(h.logger, c, "ip", ip, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get IP recent requests"))
+		return
+	}
+	if err != nil {
+		h.logger.Debug("IP recent requests stream interrupted", logArgs(h.logger, c, "ip", ip, "error", err))
+	}
 }
 
 // SearchIPs searches for IPs matching a query string
+// Queries need MinIPSearchLength characters and are rate limited per client IP (429).
 func (h *DashboardHandler) SearchIPs(c *gin.Context) {
-	query := c.Query("q")
+	query := strings.TrimSpace(c.Query("q"))
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		c.JSON(http.StatusBadRequest, errorBody(c, "Search query is required"))
+		return
+	}
+	if utf8.RuneCountInString(query) < repositories.MinIPSearchLength {
+		respondInvalidParams(c, []FieldError{{Field: "q", Message: fmt.Sprintf("must be at least %d characters", repositories.MinIPSearchLength)}})
 		return
 	}
 
-	limit := 20
-	if limitParam := c.Query("limit"); limitParam != "" {
-		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	limit, ok := queryLimit(c, 20, 100)
+	if !ok || !h.allowSearch(c) {
+		return
 	}
 
-	results, err := h.statsRepo.SearchIPs(query, limit)
+	results, err := h.requestStatsRepo(c).SearchIPs(query, limit)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to search IPs", h.logger.Args("query", query, "error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search IPs"})
+		h.logger.WithCaller().Error("Failed to search IPs", logArgs(h.logger, c, "query", query, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to search IPs"))
 		return
 	}
 
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+const (
+	// degradedPeriod is how long the time range of an endpoint stays reduced after a query timed out
+	degradedPeriod = 5 * time.Minute
+	// degradedRangeDivisor divides the time range of an endpoint at each timeout
+	degradedRangeDivisor = 4
+
+	// maxLastGoodResults caps the remembered successful responses, the oldest are forgotten first
+	maxLastGoodResults = 256
+	// maxLastGoodBytes is the largest response remembered
+	maxLastGoodBytes = 1 << 20
+	// maxLastGoodTotalBytes caps the memory held by all remembered responses, the oldest are forgotten first
+	maxLastGoodTotalBytes = 8 << 20
+)
+
+// requestedHoursKey holds the time range asked for in the Gin context, when a degraded endpoint reduced it
+const requestedHoursKey = "requested_hours"
+
+// Degradation keeps stats endpoints usable while queries time out (heavy ingestion, huge ranges)
+// Queries get DefaultQueryTimeout. When one times out, the last successful response to the same call is served,
+// marked stale, and the endpoint's time range is reduced for degradedPeriod so the next calls can complete.
+type Degradation struct {
+	defaultHours int
+	timeout      time.Duration
+
+	mu       sync.Mutex
+	lastGood map[string]*lastGoodResult // Call (resultKey) -> last successful response
+	order    []string                   // Calls in the order they were first remembered
+	size     int                        // Bytes of the remembered responses
+	capped   map[string]degradedRange   // Route -> reduced time range
+
+	logger *pterm.Logger
+}
+
+// lastGoodResult is a successful response body and when it was computed
+type lastGoodResult struct {
+	body       []byte
+	computedAt time.Time
+}
+
+// degradedRange is the reduced time range of an endpoint
+type degradedRange struct {
+	hours int
+	until time.Time
+}
+
+// NewDegradation creates the degradation of stats endpoints
+// defaultHours is the time range of calls without hours (0 = DefaultLookbackHours), timeout the query timeout.
+func NewDegradation(defaultHours int, timeout time.Duration, logger *pterm.Logger) *Degradation {
+	if defaultHours <= 0 {
+		defaultHours = repositories.DefaultLookbackHours
+	}
+	return &Degradation{
+		defaultHours: defaultHours,
+		timeout:      timeout,
+		lastGood:     make(map[string]*lastGoodResult),
+		capped:       make(map[string]degradedRange),
+		logger:       logger,
+	}
+}
+
+// Middleware applies the degradation to GET stats endpoints (/stats/ and /dashboard/)
+// It must run before anything reads the query, since it can rewrite the hours parameter.
+func (d *Degradation) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if c.Request.Method != http.MethodGet || (!strings.Contains(route, "/stats/") && !strings.Contains(route, "/dashboard/")) {
+			c.Next()
+			return
+		}
+
+		key := resultKey(c)
+		capped := d.capRange(c, route)
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d.timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		timedOut := writer.status == http.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded)
+		switch {
+		case writer.status == http.StatusOK && !capped:
+			d.remember(key, writer.body.Bytes())
+		case timedOut:
+			hours := d.reduceRange(c, route)
+			if body, ok := d.staleResponse(key); ok {
+				d.logger.Warn("Query timed out, serving the last successful response",
+					logArgs(d.logger, c, "route", route, "reduced_hours", hours))
+				c.Header("Warning", `110 - "Response is Stale"`)
+				writer.ResponseWriter.WriteHeader(http.StatusOK)
+				writer.ResponseWriter.Write(body)
+				return
+			}
+			d.logger.Warn("Query timed out without a previous response to serve",
+				logArgs(d.logger, c, "route", route, "reduced_hours", hours))
+			c.Header("Retry-After", "5")
+			writer.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+			body := errorBody(c, "Query timed out, the time range of this endpoint is reduced to "+strconv.Itoa(hours)+" hours, retry")
+			data, _ := json.Marshal(body)
+			writer.ResponseWriter.Write(data)
+			return
+		}
+
+		writer.ResponseWriter.WriteHeader(writer.status)
+		writer.ResponseWriter.Write(writer.body.Bytes())
+	}
+}
+
+// resultKey identifies the calls sharing a response: the request URI, and the caller's IP when the response
+// excludes it (exclude_own_ip), so a caller is never served the response computed for another one
+func resultKey(c *gin.Context) string {
+	key := c.Request.URL.RequestURI()
+	if c.Query("exclude_own_ip") == "true" {
+		key += " " + c.ClientIP()
+	}
+	return key
+}
+
+// requestedHours returns the time range asked for by a call
+func (d *Degradation) requestedHours(c *gin.Context) int {
+	if hours, err := strconv.Atoi(c.Request.URL.Query().Get("hours")); err == nil && hours > 0 {
+		return hours
+	}
+	return d.defaultHours
+}
+
+// capRange rewrites the hours of a call to a degraded endpoint, true when the range was reduced
+func (d *Degradation) capRange(c *gin.Context, route string) bool {
+	d.mu.Lock()
+	degraded, ok := d.capped[route]
+	if ok && time.Now().After(degraded.until) {
+		delete(d.capped, route)
+		ok = false
+	}
+	d.mu.Unlock()
+
+	requested := d.requestedHours(c)
+	if !ok || requested <= degraded.hours {
+		return false
+	}
+	query := c.Request.URL.Query()
+	query.Set("hours", strconv.Itoa(degraded.hours))
+	c.Request.URL.RawQuery = query.Encode()
+	c.Set(requestedHoursKey, requested)
+	return true
+}
+
+// reduceRange divides the time range of an endpoint after a timeout and returns it
+func (d *Degradation) reduceRange(c *gin.Context, route string) int {
+	hours := d.requestedHours(c) / degradedRangeDivisor
+	if hours < 1 {
+		hours = 1
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if degraded, ok := d.capped[route]; ok && degraded.hours < hours {
+		hours = degraded.hours
+	}
+	d.capped[route] = degradedRange{hours: hours, until: time.Now().Add(degradedPeriod)}
+	return hours
+}
+
+// remember keeps a successful response as the fallback of its call
+func (d *Degradation) remember(key string, body []byte) {
+	if len(body) > maxLastGoodBytes {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if previous, ok := d.lastGood[key]; ok {
+		d.size -= len(previous.body)
+	} else {
+		d.order = append(d.order, key)
+	}
+	d.lastGood[key] = &lastGoodResult{body: append([]byte(nil), body...), computedAt: time.Now()}
+	d.size += len(body)
+
+	for len(d.order) > maxLastGoodResults || d.size > maxLastGoodTotalBytes {
+		d.size -= len(d.lastGood[d.order[0]].body)
+		delete(d.lastGood, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+// staleResponse returns the last successful response of a call, its meta marked stale
+func (d *Degradation) staleResponse(key string) ([]byte, bool) {
+	d.mu.Lock()
+	result, ok := d.lastGood[key]
+	d.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(result.body, &response); err != nil || response["meta"] == nil {
+		return result.body, true // Not a stats response, the Warning header tells it is stale
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal(response["meta"], &meta); err != nil {
+		return result.body, true
+	}
+	meta["stale"] = true
+	meta["computed_at"] = result.computedAt
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return result.body, true
+	}
+	response["meta"] = metaJSON
+	body, err := json.Marshal(response)
+	if err != nil {
+		return result.body, true
+	}
+	return body, true
+}
+
+// bufferedWriter holds the response of a handler so a timeout can be answered with another one
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+func (w *bufferedWriter) Flush() {}
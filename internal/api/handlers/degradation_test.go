@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+func TestDegradation_RememberCapsMemory(t *testing.T) {
+	d := NewDegradation(24, 0, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+
+	// Responses of the largest size fill the budget, one more forgets the first
+	body := bytes.Repeat([]byte("x"), maxLastGoodBytes)
+	fit := maxLastGoodTotalBytes / maxLastGoodBytes
+	for i := 0; i <= fit; i++ {
+		d.remember(fmt.Sprint("/api/v1/stats/summary?hours=", i), body)
+	}
+	if _, ok := d.lastGood["/api/v1/stats/summary?hours=0"]; ok {
+		t.Error("Expected the oldest response to be forgotten")
+	}
+	if len(d.lastGood) != fit || d.size != maxLastGoodTotalBytes {
+		t.Errorf("Expected %d responses filling the budget, got %d taking %d bytes", fit, len(d.lastGood), d.size)
+	}
+
+	// Remembering a call again replaces its response
+	d.remember(fmt.Sprint("/api/v1/stats/summary?hours=", fit), []byte("{}"))
+	if len(d.lastGood) != fit || d.size != (fit-1)*len(body)+2 {
+		t.Errorf("Expected the replaced response to release its bytes, got %d bytes", d.size)
+	}
+}
+
+func TestResultKey_ExcludeOwnIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := func(uri, remoteAddr string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, uri, nil)
+		c.Request.RemoteAddr = remoteAddr
+		return resultKey(c)
+	}
+
+	if key("/api/v1/stats/summary", "192.0.2.1:1234") != key("/api/v1/stats/summary", "192.0.2.2:1234") {
+		t.Error("Expected callers to share responses without exclude_own_ip")
+	}
+	if key("/api/v1/stats/summary?exclude_own_ip=true", "192.0.2.1:1234") == key("/api/v1/stats/summary?exclude_own_ip=true", "192.0.2.2:1234") {
+		t.Error("Expected responses excluding the caller's IP not to be shared")
+	}
+}
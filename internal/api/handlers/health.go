@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/ingestion"
+	"loglynx/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// Component health statuses
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded" // Works, but with reduced functionality (readiness still passes)
+	HealthDown     = "down"     // Can't serve requests (readiness fails)
+	HealthDisabled = "disabled"
+)
+
+// dbPingTimeout bounds the database check so probes answer within their timeout
+const dbPingTimeout = 2 * time.Second
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	db             *gorm.DB
+	coordinator    *ingestion.Coordinator
+	geoIP          *enrichment.GeoIPEnricher
+	geoIPEnabled   bool
+	logger         *pterm.Logger
+	dbPath         string
+	stallThreshold time.Duration
+	minFreeDiskMB  int
+}
+
+// ComponentHealth is the state of a single component
+type ComponentHealth struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// HealthReport is the readiness report of all components
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Version    string                     `json:"version"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// SourceHealth is the ingestion progress of one log source
+type SourceHealth struct {
+	Source       string    `json:"source"`
+	Status       string    `json:"status"` // ok or stalled
+	PendingBytes int64     `json:"pending_bytes"`
+	LastReadAt   time.Time `json:"last_read_at"`
+}
+
+// DiskHealth holds free space of the database disk
+type DiskHealth struct {
+	FreeMB    uint64 `json:"free_mb"`
+	TotalMB   uint64 `json:"total_mb"`
+	MinFreeMB int    `json:"min_free_mb"`
+}
+
+// NewHealthHandler creates a new health handler
+// geoIPEnabled tells a disabled GeoIP apart from one that failed to load
+func NewHealthHandler(
+	db *gorm.DB,
+	coordinator *ingestion.Coordinator,
+	geoIP *enrichment.GeoIPEnricher,
+	geoIPEnabled bool,
+	logger *pterm.Logger,
+	dbPath string,
+	stallThreshold time.Duration,
+	minFreeDiskMB int,
+) *HealthHandler {
+	return &HealthHandler{
+		db:             db,
+		coordinator:    coordinator,
+		geoIP:          geoIP,
+		geoIPEnabled:   geoIPEnabled,
+		logger:         logger,
+		dbPath:         dbPath,
+		stallThreshold: stallThreshold,
+		minFreeDiskMB:  minFreeDiskMB,
+	}
+}
+
+// GetLiveness reports whether the process is responsive (liveness probe)
+// It doesn't check dependencies: restarting LogLynx won't fix a database or disk problem
+func (h *HealthHandler) GetLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    HealthOK,
+		"timestamp": time.Now(),
+		"version":   version.Version,
+	})
+}
+
+// GetReadiness reports component health (readiness probe)
+// Returns 503 when a component is down (database unreachable, disk almost full),
+// and 200 when all components are ok or degraded (stalled source, GeoIP unavailable, ingestion paused)
+func (h *HealthHandler) GetReadiness(c *gin.Context) {
+	report := HealthReport{
+		Timestamp: time.Now(),
+		Version:   version.Version,
+		Components: map[string]ComponentHealth{
+			"database":  h.checkDatabase(c.Request.Context()),
+			"ingestion": h.checkIngestion(),
+			"geoip":     h.checkGeoIP(),
+			"disk":      h.checkDisk(),
+		},
+	}
+
+	report.Status = HealthOK
+	for name, component := range report.Components {
+		switch component.Status {
+		case HealthDown:
+			report.Status = HealthDown
+		case HealthDegraded:
+			if report.Status == HealthOK {
+				report.Status = HealthDegraded
+			}
+		}
+		if component.Status == HealthDown || component.Status == HealthDegraded {
+			h.logger.Debug("Health check component not ok",
+				logArgs(h.logger, c, "component", name, "status", component.Status, "message", component.Message))
+		}
+	}
+
+	statusCode := http.StatusOK
+	if report.Status == HealthDown {
+		statusCode = http.StatusServiceUnavailable
+	}
+	c.JSON(statusCode, report)
+}
+
+// checkDatabase runs a trivial query against the database
+func (h *HealthHandler) checkDatabase(ctx context.Context) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	if err := h.db.WithContext(ctx).Exec("SELECT 1").Error; err != nil {
+		return ComponentHealth{Status: HealthDown, Message: fmt.Sprintf("database query failed: %v", err)}
+	}
+	return ComponentHealth{Status: HealthOK}
+}
+
+// checkIngestion reports the coordinator state and sources that stopped making progress
+func (h *HealthHandler) checkIngestion() ComponentHealth {
+	if h.coordinator == nil {
+		return ComponentHealth{Status: HealthDegraded, Message: "ingestion coordinator not available"}
+	}
+
+	status := h.coordinator.GetStatus()
+	if !status.IsRunning {
+		// Also the case while the cleanup service runs VACUUM
+		return ComponentHealth{Status: HealthDegraded, Message: "ingestion is not running"}
+	}
+
+	now := time.Now()
+	sources := make([]SourceHealth, 0, len(status.Processors))
+	stalled := 0
+	for _, processor := range status.Processors {
+		source := SourceHealth{
+			Source:       processor.Source,
+			Status:       HealthOK,
+			PendingBytes: processor.PendingBytes,
+			LastReadAt:   processor.LastReadAt,
+		}
+
+		if processor.Stalled(now, h.stallThreshold) {
+			source.Status = "stalled"
+			stalled++
+		}
+		sources = append(sources, source)
+	}
+
+	if stalled > 0 {
+		return ComponentHealth{
+			Status:  HealthDegraded,
+			Message: fmt.Sprintf("%d of %d sources stalled for more than %s", stalled, len(sources), h.stallThreshold),
+			Details: sources,
+		}
+	}
+	return ComponentHealth{Status: HealthOK, Details: sources}
+}
+
+// checkGeoIP reports whether GeoIP databases are loaded and the cache is warm
+func (h *HealthHandler) checkGeoIP() ComponentHealth {
+	if !h.geoIPEnabled {
+		return ComponentHealth{Status: HealthDisabled}
+	}
+	if h.geoIP == nil || !h.geoIP.IsEnabled() {
+		return ComponentHealth{Status: HealthDegraded, Message: "no GeoIP database available"}
+	}
+	if !h.geoIP.IsReady() {
+		return ComponentHealth{Status: HealthDegraded, Message: "GeoIP cache is loading"}
+	}
+	return ComponentHealth{Status: HealthOK}
+}
+
+// checkDisk reports free space on the disk holding the database
+func (h *HealthHandler) checkDisk() ComponentHealth {
+	free, total, err := database.DiskSpace(h.dbPath)
+	if err != nil {
+		return ComponentHealth{Status: HealthDegraded, Message: fmt.Sprintf("failed to read disk space: %v", err)}
+	}
+
+	details := DiskHealth{
+		FreeMB:    free / 1024 / 1024,
+		TotalMB:   total / 1024 / 1024,
+		MinFreeMB: h.minFreeDiskMB,
+	}
+	if h.minFreeDiskMB > 0 && details.FreeMB < uint64(h.minFreeDiskMB) {
+		return ComponentHealth{
+			Status:  HealthDown,
+			Message: fmt.Sprintf("only %d MB free on the database disk", details.FreeMB),
+			Details: details,
+		}
+	}
+	return ComponentHealth{Status: HealthOK, Details: details}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"loglynx/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// I18nHandler serves the label catalogs of the dashboard
+type I18nHandler struct {
+	locale string // Locale of every page, empty to detect it from Accept-Language
+	logger *pterm.Logger
+}
+
+// NewI18nHandler creates an i18n handler, locale forces the locale of the dashboard when set
+func NewI18nHandler(locale string, logger *pterm.Logger) *I18nHandler {
+	return &I18nHandler{
+		locale: i18n.Normalize(locale),
+		logger: logger,
+	}
+}
+
+// Locale returns the locale of a request: the configured one, else the one preferred by Accept-Language
+func (h *I18nHandler) Locale(c *gin.Context) string {
+	if h.locale != "" {
+		return h.locale
+	}
+	c.Header("Vary", "Accept-Language")
+	return i18n.Negotiate(c.GetHeader("Accept-Language"), i18n.DefaultLocale)
+}
+
+// GetLocales returns the supported locales and the locale of the request
+func (h *I18nHandler) GetLocales(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"locales": i18n.Locales(),
+		"current": h.Locale(c),
+	})
+}
+
+// GetCatalog returns the labels and formatting hints of a locale ("de", "de-CH"), 404 when unsupported
+func (h *I18nHandler) GetCatalog(c *gin.Context) {
+	catalog, ok := i18n.Lookup(c.Param("locale"))
+	if !ok {
+		c.JSON(http.StatusNotFound, errorBody(c, "Unsupported locale"))
+		return
+	}
+	c.JSON(http.StatusOK, catalog)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// InvestigationHandler serves the investigations saved during incident analysis
+type InvestigationHandler struct {
+	investigationRepo repositories.InvestigationRepository
+	logger            *pterm.Logger
+}
+
+// NewInvestigationHandler creates a new investigation handler
+func NewInvestigationHandler(investigationRepo repositories.InvestigationRepository, logger *pterm.Logger) *InvestigationHandler {
+	return &InvestigationHandler{
+		investigationRepo: investigationRepo,
+		logger:            logger,
+	}
+}
+
+// ListInvestigations returns the saved investigations, most recently updated first
+func (h *InvestigationHandler) ListInvestigations(c *gin.Context) {
+	investigations, err := h.investigationRepo.List()
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to list investigations", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to list investigations"))
+		return
+	}
+	c.JSON(http.StatusOK, investigations)
+}
+
+// GetInvestigation returns one investigation
+func (h *InvestigationHandler) GetInvestigation(c *gin.Context) {
+	if investigation, ok := h.findInvestigation(c); ok {
+		c.JSON(http.StatusOK, investigation)
+	}
+}
+
+// ExportInvestigation downloads an investigation as JSON (format=json, default) or Markdown (format=markdown)
+func (h *InvestigationHandler) ExportInvestigation(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		respondInvalidParams(c, []FieldError{{Field: "format", Message: "must be json or markdown"}})
+		return
+	}
+	investigation, ok := h.findInvestigation(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("loglynx-investigation-%d", investigation.ID)
+	if format == "markdown" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, filename))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(repositories.InvestigationMarkdown(investigation)))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	c.IndentedJSON(http.StatusOK, investigation)
+}
+
+// CreateInvestigation saves a new investigation, responding 201 with it
+func (h *InvestigationHandler) CreateInvestigation(c *gin.Context) {
+	var investigation models.Investigation
+	if !h.bindInvestigation(c, &investigation) {
+		return
+	}
+	if err := h.investigationRepo.Create(&investigation); err != nil {
+		h.logger.WithCaller().Error("Failed to save investigation", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to save investigation"))
+		return
+	}
+	h.logger.Info("Investigation saved", h.logger.Args("id", investigation.ID, "name", investigation.Name))
+	c.JSON(http.StatusCreated, investigation)
+}
+
+// UpdateInvestigation replaces an investigation with the body, keeping its ID and creation time
+func (h *InvestigationHandler) UpdateInvestigation(c *gin.Context) {
+	id, ok := investigationID(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, errorBody(c, repositories.ErrInvestigationNotFound.Error()))
+		return
+	}
+	var investigation models.Investigation
+	if !h.bindInvestigation(c, &investigation) {
+		return
+	}
+	investigation.ID = id
+
+	err := h.investigationRepo.Update(&investigation)
+	if errors.Is(err, repositories.ErrInvestigationNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, err.Error()))
+		return
+	}
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to update investigation", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to update investigation"))
+		return
+	}
+	h.GetInvestigation(c)
+}
+
+// DeleteInvestigation deletes an investigation, responding 204
+func (h *InvestigationHandler) DeleteInvestigation(c *gin.Context) {
+	id, ok := investigationID(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, errorBody(c, repositories.ErrInvestigationNotFound.Error()))
+		return
+	}
+	err := h.investigationRepo.Delete(id)
+	if errors.Is(err, repositories.ErrInvestigationNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, err.Error()))
+		return
+	}
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to delete investigation", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to delete investigation"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// investigationID returns the id path parameter, false when it isn't an investigation ID
+func investigationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	return uint(id), err == nil && id > 0
+}
+
+// findInvestigation returns the investigation of the id path parameter, responding 404 or 500 when there is none
+func (h *InvestigationHandler) findInvestigation(c *gin.Context) (*models.Investigation, bool) {
+	id, ok := investigationID(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, errorBody(c, repositories.ErrInvestigationNotFound.Error()))
+		return nil, false
+	}
+	investigation, err := h.investigationRepo.Get(id)
+	if errors.Is(err, repositories.ErrInvestigationNotFound) {
+		c.JSON(http.StatusNotFound, errorBody(c, err.Error()))
+		return nil, false
+	}
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get investigation", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get investigation"))
+		return nil, false
+	}
+	return investigation, true
+}
+
+// bindInvestigation decodes and validates the body of a create or update, responding 400 when invalid
+func (h *InvestigationHandler) bindInvestigation(c *gin.Context, investigation *models.Investigation) bool {
+	if err := c.ShouldBindJSON(investigation); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, "Invalid investigation: "+err.Error()))
+		return false
+	}
+	if err := repositories.ValidateInvestigation(investigation); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Overview is everything the overview page shows, loaded with a single request
+type Overview struct {
+	Summary            *repositories.StatsSummary             `json:"summary"`
+	Timeline           []*repositories.TimelineData           `json:"timeline"`
+	StatusCodeTimeline []*repositories.StatusCodeTimelineData `json:"status_code_timeline"`
+	StatusCodes        []*repositories.StatusCodeStats        `json:"status_codes"`
+	TopPaths           []*repositories.PathStats              `json:"top_paths"`
+	TopCountries       []*repositories.CountryStats           `json:"top_countries"`
+}
+
+// GetOverview returns summary, timelines, status code distribution and top paths/countries in one response
+// The queries run concurrently on the read pool, so the page needs one round trip instead of one per widget
+func (h *DashboardHandler) GetOverview(c *gin.Context) {
+	statsRepo, hours := h.statsRepoFor(c)
+	filters := h.getServiceFilters(c)
+	excludeIP := h.buildExcludeIPFilter(c)
+
+	limit, ok := queryLimit(c, 10, 100)
+	if !ok {
+		return
+	}
+
+	var overview Overview
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed string
+	var firstErr error
+
+	run := func(name string, query func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := query(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					failed, firstErr = name, err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	run("summary", func() (err error) {
+		overview.Summary, err = statsRepo.GetSummary(filters, excludeIP)
+		return err
+	})
+	run("timeline", func() (err error) {
+		overview.Timeline, err = statsRepo.GetTimelineStats(hours, filters, excludeIP)
+		return err
+	})
+	run("status code timeline", func() (err error) {
+		overview.StatusCodeTimeline, err = statsRepo.GetStatusCodeTimeline(hours, filters, excludeIP)
+		return err
+	})
+	run("status code distribution", func() (err error) {
+		overview.StatusCodes, err = statsRepo.GetStatusCodeDistribution(filters, excludeIP)
+		return err
+	})
+	run("top paths", func() (err error) {
+		overview.TopPaths, err = statsRepo.GetTopPaths(limit, filters, excludeIP)
+		return err
+	})
+	run("top countries", func() (err error) {
+		overview.TopCountries, err = statsRepo.GetTopCountries(limit, filters, excludeIP)
+		return err
+	})
+	wg.Wait()
+
+	if firstErr != nil {
+		h.logger.WithCaller().Error("Failed to get overview", logArgs(h.logger, c, "query", failed, "error", firstErr))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get overview"))
+		return
+	}
+
+	h.respondStats(c, overview, hours)
+}
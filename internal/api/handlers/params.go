@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// commonParams are the query parameters shared by the API endpoints
+// ValidateParams checks them for every API call, so handlers read them without re-validating.
+// Pointers tell an absent parameter (endpoint default) from an explicit value.
+type commonParams struct {
+	Hours        *int   `form:"hours" binding:"omitempty,min=1,max=8760"` // repositories.MaxLookbackHours
+	Days         *int   `form:"days" binding:"omitempty,min=1,max=365"`
+	Limit        *int   `form:"limit" binding:"omitempty,min=0"` // Upper bound depends on the endpoint (queryLimit)
+	Offset       *int   `form:"offset" binding:"omitempty,min=0"`
+	SortBy       string `form:"sort_by" binding:"omitempty,oneof=hits bandwidth error_count avg_response_time"` // repositories.TopSortKeys
+	SortDir      string `form:"sort_dir" binding:"omitempty,oneof=asc desc"`
+	MinHits      *int64 `form:"min_hits" binding:"omitempty,min=1"`
+	StatusClass  string `form:"status_class" binding:"omitempty,oneof=1xx 2xx 3xx 4xx 5xx"`
+	PathPrefix   string `form:"path_prefix" binding:"omitempty,startswith=/"`
+	Exact        *bool  `form:"exact"`
+	ExcludeOwnIP *bool  `form:"exclude_own_ip"`
+	IP           string `form:"ip" binding:"omitempty,ip"`
+}
+
+// ipParams is the IP path parameter of the IP analytics endpoints
+type ipParams struct {
+	IP string `uri:"ip" binding:"required,ip"`
+}
+
+// FieldError describes an invalid parameter
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidateParams rejects API calls with invalid shared parameters with a 400 listing each invalid field
+// IP path parameters must be IP addresses before they reach SQL.
+func ValidateParams() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var params commonParams
+		if !bindParams(c, &params, c.ShouldBindQuery) {
+			return
+		}
+		if _, ok := c.Params.Get("ip"); ok {
+			var ip ipParams
+			if !bindParams(c, &ip, c.ShouldBindUri) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// bindParams binds parameters with bind, answering 400 with field-level messages when they are invalid
+func bindParams(c *gin.Context, params any, bind func(any) error) bool {
+	if err := bind(params); err != nil {
+		respondInvalidParams(c, fieldErrors(c, params, err))
+		return false
+	}
+	return true
+}
+
+// respondInvalidParams aborts the call with a 400 listing the invalid fields
+func respondInvalidParams(c *gin.Context, fields []FieldError) {
+	body := errorBody(c, "Invalid parameters")
+	body["fields"] = fields
+	c.AbortWithStatusJSON(http.StatusBadRequest, body)
+}
+
+// queryLimit returns the limit parameter, or defaultLimit when absent
+// Limits outside 1..maxLimit are rejected with a 400 instead of being silently replaced; returns false then.
+func queryLimit(c *gin.Context, defaultLimit int, maxLimit int) (int, bool) {
+	return queryIntParam(c, "limit", defaultLimit, 1, maxLimit)
+}
+
+// validatedInt returns a parameter checked by ValidateParams (hours, days, offset), or defaultValue when absent
+func validatedInt(c *gin.Context, name string, defaultValue int) int {
+	if value, err := strconv.Atoi(c.Query(name)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// topPage returns the page of top-N results selected by offset, sort_by and sort_dir (checked by ValidateParams)
+func topPage(c *gin.Context) repositories.TopPage {
+	return repositories.TopPage{
+		Offset:    validatedInt(c, "offset", 0),
+		SortBy:    c.Query("sort_by"),
+		Ascending: c.Query("sort_dir") == "asc",
+	}
+}
+
+// topFilter returns the thresholds of top-N results selected by min_hits, status_class and path_prefix
+// (checked by ValidateParams)
+func topFilter(c *gin.Context) repositories.TopFilter {
+	filter := repositories.TopFilter{PathPrefix: c.Query("path_prefix")}
+	filter.MinHits, _ = strconv.ParseInt(c.Query("min_hits"), 10, 64)
+	if class := c.Query("status_class"); class != "" {
+		filter.StatusClass = int(class[0] - '0')
+	}
+	return filter
+}
+
+// queryIntParam returns an integer query parameter within min..max, or defaultValue when absent
+func queryIntParam(c *gin.Context, name string, defaultValue int, min int, max int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return defaultValue, true
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		respondInvalidParams(c, []FieldError{{Field: name, Message: fmt.Sprintf("must be an integer between %d and %d", min, max)}})
+		return 0, false
+	}
+	return parsed, true
+}
+
+// fieldErrors turns binding errors into one message per parameter
+func fieldErrors(c *gin.Context, params any, err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		// Conversion errors (e.g. limit=abc) only carry the value, the parameter is found by its value
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			return []FieldError{{Field: paramWithValue(c, params, numErr.Num), Message: fmt.Sprintf("%q is not a valid value", numErr.Num)}}
+		}
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, FieldError{Field: paramName(params, fieldErr.StructField()), Message: validationMessage(fieldErr)})
+	}
+	return fields
+}
+
+// paramName returns the query or path parameter name of a struct field
+func paramName(params any, structField string) string {
+	paramsType := reflect.TypeOf(params)
+	if paramsType.Kind() == reflect.Ptr {
+		paramsType = paramsType.Elem()
+	}
+	if field, ok := paramsType.FieldByName(structField); ok {
+		for _, tag := range []string{"form", "uri"} {
+			if name := field.Tag.Get(tag); name != "" {
+				return name
+			}
+		}
+	}
+	return structField
+}
+
+// paramWithValue returns the name of the query parameter of params set to value
+func paramWithValue(c *gin.Context, params any, value string) string {
+	paramsType := reflect.TypeOf(params)
+	if paramsType.Kind() == reflect.Ptr {
+		paramsType = paramsType.Elem()
+	}
+	for i := 0; i < paramsType.NumField(); i++ {
+		if name := paramsType.Field(i).Tag.Get("form"); name != "" && c.Query(name) == value {
+			return name
+		}
+	}
+	return ""
+}
+
+// validationMessage describes a failed binding tag
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	case "ip":
+		return "must be an IP address"
+	case "startswith":
+		return "must start with " + strconv.Quote(fieldErr.Param())
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	default:
+		return "is invalid (" + fieldErr.Tag() + ")"
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryResponse is the result of a structured query with the query as it ran
+type QueryResponse struct {
+	Data    []*repositories.QueryRow      `json:"data"`
+	Meta    ResponseMeta                  `json:"meta"`
+	Query   *repositories.StructuredQuery `json:"query"`    // Normalized query (defaults filled in)
+	APICall string                        `json:"api_call"` // Equivalent GET request
+}
+
+// QuerySchema lists the vocabulary of structured queries
+type QuerySchema struct {
+	Metrics      []string `json:"metrics"`
+	Groups       []string `json:"groups"`
+	Filters      []string `json:"filters"`
+	MaxLimit     int      `json:"max_limit"`
+	MaxHours     int      `json:"max_hours"`
+	DefaultHours int      `json:"default_hours"`
+}
+
+// RunQuery answers a structured query (metric, filters, range, group by) for chat bots and assistants
+// The query is a JSON body (POST) or query parameters (GET), filters being plain parameters, e.g.
+// GET /api/v1/query?metric=requests&group_by=path&status_code=404&hours=24
+// Captured headers filter as header[X-Tenant-Id]=acme (GET) or "header:X-Tenant-Id" (POST), labels as
+// label[customer]=acme (GET) or "label:customer" (POST).
+func (h *DashboardHandler) RunQuery(c *gin.Context) {
+	query := &repositories.StructuredQuery{}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(query); err != nil {
+			c.JSON(http.StatusBadRequest, errorBody(c, "Invalid query: "+err.Error()))
+			return
+		}
+	} else {
+		query.Metric = c.Query("metric")
+		query.GroupBy = c.Query("group_by")
+		for param, target := range map[string]*int{"hours": &query.Hours, "limit": &query.Limit} {
+			if value := c.Query(param); value != "" {
+				parsed, err := strconv.Atoi(value)
+				if err != nil {
+					respondInvalidParams(c, []FieldError{{Field: param, Message: "must be an integer"}})
+					return
+				}
+				*target = parsed
+			}
+		}
+		for _, name := range repositories.QueryFilters() {
+			if value := c.Query(name); value != "" {
+				if query.Filters == nil {
+					query.Filters = make(map[string]string)
+				}
+				query.Filters[name] = value
+			}
+		}
+		for name, value := range c.QueryMap("header") {
+			if query.Filters == nil {
+				query.Filters = make(map[string]string)
+			}
+			query.Filters[repositories.HeaderPrefix+name] = value
+		}
+		for name, value := range c.QueryMap("label") {
+			if query.Filters == nil {
+				query.Filters = make(map[string]string)
+			}
+			query.Filters[repositories.LabelPrefix+name] = value
+		}
+	}
+
+	if err := query.Normalize(h.statsRepo.LookbackHours()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "schema": querySchema(), "request_id": requestid.FromContext(c.Request.Context())})
+		return
+	}
+
+	rows, err := h.requestStatsRepo(c).WithLookback(query.Hours).RunQuery(query)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to run structured query", logArgs(h.logger, c, "query", query.APICall(), "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to run query"))
+		return
+	}
+
+	c.JSON(http.StatusOK, QueryResponse{
+		Data:    rows,
+		Meta:    newResponseMeta(c, rows, query.Hours),
+		Query:   query,
+		APICall: query.APICall(),
+	})
+}
+
+// GetQuerySchema returns the metrics, groups and filters accepted by RunQuery
+func (h *DashboardHandler) GetQuerySchema(c *gin.Context) {
+	schema := querySchema()
+	schema.DefaultHours = h.statsRepo.LookbackHours()
+	c.JSON(http.StatusOK, schema)
+}
+
+func querySchema() *QuerySchema {
+	return &QuerySchema{
+		Metrics:  repositories.QueryMetrics(),
+		Groups:   repositories.QueryGroups(),
+		Filters:  repositories.QueryFilters(),
+		MaxLimit: repositories.MaxQueryLimit,
+		MaxHours: repositories.MaxLookbackHours,
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Searches allowed per client IP: autocomplete sends a search while typing, bursts beyond this are refused with
+// 429 instead of queueing table scans
+const (
+	searchRate  = 5 // Searches per second
+	searchBurst = 10
+)
+
+// maxRateLimiterKeys is the number of client IPs from which idle ones are forgotten
+const maxRateLimiterKeys = 10000
+
+// rateLimiter is a token bucket per client IP
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens added per second
+	burst   float64
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	at     time.Time // When tokens was computed
+}
+
+func newRateLimiter(rate float64, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// allow takes a token of a client IP, false when its bucket is empty
+func (l *rateLimiter) allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateLimiterKeys {
+			l.prune(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst}
+		l.buckets[key] = bucket
+	} else {
+		bucket.tokens = l.refilled(bucket, now)
+	}
+	bucket.at = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+func (l *rateLimiter) refilled(bucket *tokenBucket, now time.Time) float64 {
+	return math.Min(l.burst, bucket.tokens+now.Sub(bucket.at).Seconds()*l.rate)
+}
+
+// prune forgets the buckets refilled to the burst, a new bucket is the same
+func (l *rateLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		if l.refilled(bucket, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// allowSearch rate limits the searches of a client IP, responding 429 when refused
+func (h *DashboardHandler) allowSearch(c *gin.Context) bool {
+	if h.searchLimiter.allow(c.ClientIP()) {
+		return true
+	}
+	h.logger.Debug("Search refused, rate limit reached", logArgs(h.logger, c, "client_ip", c.ClientIP()))
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusTooManyRequests, errorBody(c, "Too many searches"))
+	return false
+}
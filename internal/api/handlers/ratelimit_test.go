@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	// The burst is allowed at once, then 2 per second
+	for i := 0; i < 3; i++ {
+		if !limiter.allow("10.0.0.1") {
+			t.Fatalf("Expected search %d of the burst to be allowed", i+1)
+		}
+	}
+	if limiter.allow("10.0.0.1") {
+		t.Fatal("Expected a search beyond the burst to be refused")
+	}
+	if !limiter.allow("10.0.0.2") {
+		t.Fatal("Expected other client IPs to have their own burst")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.allow("10.0.0.1") || limiter.allow("10.0.0.1") {
+		t.Fatal("Expected one search allowed after half a second")
+	}
+
+	// Idle client IPs are forgotten once refilled
+	now = now.Add(time.Minute)
+	limiter.prune(now)
+	if len(limiter.buckets) != 0 {
+		t.Errorf("Expected refilled buckets to be pruned, %d left", len(limiter.buckets))
+	}
+}
@@ -1,31 +1,121 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
-	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
 	"loglynx/internal/realtime"
+	"loglynx/internal/statsd"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pterm/pterm"
 )
 
+// StreamLimits caps the concurrent real-time streams, 0 = unlimited
+type StreamLimits struct {
+	MaxConnections      int
+	MaxConnectionsPerIP int
+	Token               string // Required as bearer token or ?token= when set
+}
+
+// StreamStats reports the open real-time streams
+type StreamStats struct {
+	Active              int   `json:"active"`
+	Clients             int   `json:"clients"`  // Distinct client IPs with a stream
+	Rejected            int64 `json:"rejected"` // Streams refused by a limit since startup
+	MaxConnections      int   `json:"max_connections"`
+	MaxConnectionsPerIP int   `json:"max_connections_per_ip"`
+	TokenRequired       bool  `json:"token_required"`
+}
+
 // RealtimeHandler handles real-time streaming endpoints
 type RealtimeHandler struct {
 	collector *realtime.MetricsCollector
-	logger    *pterm.Logger
+	limits    StreamLimits
+	metrics   *statsd.Client // nil = disabled
+
+	streamsMu sync.Mutex
+	streams   map[string]int // Open streams per client IP
+	active    int
+	rejected  int64
+
+	logger *pterm.Logger
 }
 
 // NewRealtimeHandler creates a new realtime handler
-func NewRealtimeHandler(collector *realtime.MetricsCollector, logger *pterm.Logger) *RealtimeHandler {
+func NewRealtimeHandler(collector *realtime.MetricsCollector, limits StreamLimits, metrics *statsd.Client, logger *pterm.Logger) *RealtimeHandler {
 	return &RealtimeHandler{
 		collector: collector,
+		limits:    limits,
+		metrics:   metrics,
+		streams:   make(map[string]int),
 		logger:    logger,
 	}
 }
 
+// authorizeStream checks the stream token, sent as bearer token or as ?token= since EventSource can't set headers
+func (h *RealtimeHandler) authorizeStream(c *gin.Context) bool {
+	if h.limits.Token == "" {
+		return true
+	}
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found {
+		token = c.Query("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.limits.Token)) == 1
+}
+
+// acquireStream counts a new stream of a client IP, false when a limit is reached
+func (h *RealtimeHandler) acquireStream(clientIP string) bool {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+
+	if (h.limits.MaxConnections > 0 && h.active >= h.limits.MaxConnections) ||
+		(h.limits.MaxConnectionsPerIP > 0 && h.streams[clientIP] >= h.limits.MaxConnectionsPerIP) {
+		h.rejected++
+		h.metrics.Count("api.streams.rejected", 1)
+		return false
+	}
+	h.streams[clientIP]++
+	h.active++
+	h.metrics.Gauge("api.streams.active", float64(h.active))
+	return true
+}
+
+// releaseStream forgets a closed stream
+func (h *RealtimeHandler) releaseStream(clientIP string) {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+
+	if h.streams[clientIP]--; h.streams[clientIP] <= 0 {
+		delete(h.streams, clientIP)
+	}
+	h.active--
+	h.metrics.Gauge("api.streams.active", float64(h.active))
+}
+
+// GetStreamStats returns the open real-time streams and their limits
+func (h *RealtimeHandler) GetStreamStats(c *gin.Context) {
+	h.streamsMu.Lock()
+	stats := StreamStats{
+		Active:              h.active,
+		Clients:             len(h.streams),
+		Rejected:            h.rejected,
+		MaxConnections:      h.limits.MaxConnections,
+		MaxConnectionsPerIP: h.limits.MaxConnectionsPerIP,
+		TokenRequired:       h.limits.Token != "",
+	}
+	h.streamsMu.Unlock()
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // getServiceFilter extracts service filter parameters from request (legacy single service)
 // Returns serviceName and serviceType separately
 // Falls back to legacy "host" parameter for backward compatibility
@@ -54,18 +144,20 @@ func (h *RealtimeHandler) getServiceFilter(c *gin.Context) (string, string) {
 
 // getServiceFilters extracts multiple service filters from request
 // Returns array of {name, type} service filters
-func (h *RealtimeHandler) getServiceFilters(c *gin.Context) []realtime.ServiceFilter {
+func (h *RealtimeHandler) getServiceFilters(c *gin.Context) []filter.Service {
 	// Try new multi-service parameters
 	serviceNames := c.QueryArray("services[]")
 	serviceTypes := c.QueryArray("service_types[]")
+	matchTypes := c.QueryArray("service_match_types[]")
 
 	// If we have multiple services, use them
 	if len(serviceNames) > 0 && len(serviceNames) == len(serviceTypes) {
-		filters := make([]realtime.ServiceFilter, len(serviceNames))
+		filters := make([]filter.Service, len(serviceNames))
 		for i := range serviceNames {
-			filters[i] = realtime.ServiceFilter{
-				Name: serviceNames[i],
-				Type: serviceTypes[i],
+			filters[i] = filter.Service{
+				Name:      serviceNames[i],
+				Type:      serviceTypes[i],
+				MatchType: matchTypeAt(matchTypes, i, len(serviceNames)),
 			}
 		}
 		return filters
@@ -74,15 +166,15 @@ func (h *RealtimeHandler) getServiceFilters(c *gin.Context) []realtime.ServiceFi
 	// Fall back to single service filter (legacy)
 	service, serviceType := h.getServiceFilter(c)
 	if service != "" {
-		return []realtime.ServiceFilter{{Name: service, Type: serviceType}}
+		return []filter.Service{{Name: service, Type: serviceType, MatchType: c.Query("match_type")}}
 	}
 
 	return nil
 }
 
 // getExcludeOwnIP extracts exclude_own_ip and related parameters
-// Returns ExcludeIPFilter or nil
-func (h *RealtimeHandler) getExcludeOwnIP(c *gin.Context) *realtime.ExcludeIPFilter {
+// Returns filter.ExcludeIP or nil
+func (h *RealtimeHandler) getExcludeOwnIP(c *gin.Context) *filter.ExcludeIP {
 	excludeIP := c.Query("exclude_own_ip") == "true"
 	if !excludeIP {
 		return nil
@@ -94,30 +186,47 @@ func (h *RealtimeHandler) getExcludeOwnIP(c *gin.Context) *realtime.ExcludeIPFil
 	// Get exclude services
 	serviceNames := c.QueryArray("exclude_services[]")
 	serviceTypes := c.QueryArray("exclude_service_types[]")
+	matchTypes := c.QueryArray("exclude_service_match_types[]")
 
-	var excludeServices []realtime.ServiceFilter
+	var excludeServices []filter.Service
 	if len(serviceNames) > 0 && len(serviceNames) == len(serviceTypes) {
-		excludeServices = make([]realtime.ServiceFilter, len(serviceNames))
+		excludeServices = make([]filter.Service, len(serviceNames))
 		for i := range serviceNames {
-			excludeServices[i] = realtime.ServiceFilter{
-				Name: serviceNames[i],
-				Type: serviceTypes[i],
+			excludeServices[i] = filter.Service{
+				Name:      serviceNames[i],
+				Type:      serviceTypes[i],
+				MatchType: matchTypeAt(matchTypes, i, len(serviceNames)),
 			}
 		}
 	}
 
-	return &realtime.ExcludeIPFilter{
+	return &filter.ExcludeIP{
 		ClientIP:        clientIP,
 		ExcludeServices: excludeServices,
 	}
 }
 
 // StreamMetrics streams real-time metrics via Server-Sent Events
+// Responds 401 without the stream token and 429 when the global or per-IP stream limit is reached.
 func (h *RealtimeHandler) StreamMetrics(c *gin.Context) {
+	if !h.authorizeStream(c) {
+		c.JSON(http.StatusUnauthorized, errorBody(c, "Invalid or missing stream token"))
+		return
+	}
+	clientIP := c.ClientIP()
+	if !h.acquireStream(clientIP) {
+		h.logger.Debug("Real-time stream refused, connection limit reached", logArgs(h.logger, c, "client_ip", clientIP))
+		c.Header("Retry-After", "10")
+		c.JSON(http.StatusTooManyRequests, errorBody(c, "Too many real-time streams"))
+		return
+	}
+	defer h.releaseStream(clientIP)
+
 	// Get filters
 	serviceName, _ := h.getServiceFilter(c) // Legacy single service filter
 	serviceFilters := h.getServiceFilters(c)
 	excludeIPFilter := h.getExcludeOwnIP(c)
+	topWindow := realtime.ParseLeaderboardWindow(c.DefaultQuery("top_window", "1m"))
 
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
@@ -133,19 +242,19 @@ func (h *RealtimeHandler) StreamMetrics(c *gin.Context) {
 	clientGone := c.Writer.CloseNotify()
 
 	h.logger.Debug("Client connected to real-time metrics stream",
-		h.logger.Args("client_ip", c.ClientIP(), "host_filter", serviceName, "exclude_own_ip", excludeIPFilter != nil))
+		logArgs(h.logger, c, "client_ip", c.ClientIP(), "host_filter", serviceName, "exclude_own_ip", excludeIPFilter != nil))
 
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			// Server is shutting down or request context cancelled
 			h.logger.Debug("Request context cancelled (server shutdown or timeout)",
-				h.logger.Args("client_ip", c.ClientIP()))
+				logArgs(h.logger, c, "client_ip", c.ClientIP()))
 			return
 
 		case <-clientGone:
 			h.logger.Debug("Client disconnected from real-time stream",
-				h.logger.Args("client_ip", c.ClientIP()))
+				logArgs(h.logger, c, "client_ip", c.ClientIP()))
 			return
 
 		case <-ticker.C:
@@ -162,14 +271,24 @@ func (h *RealtimeHandler) StreamMetrics(c *gin.Context) {
 			// Marshal to JSON
 			data, err := json.Marshal(metrics)
 			if err != nil {
-				h.logger.Error("Failed to marshal metrics", h.logger.Args("error", err))
+				h.logger.Error("Failed to marshal metrics", logArgs(h.logger, c, "error", err))
 				continue
 			}
 
 			// Send SSE event
 			_, err = fmt.Fprintf(c.Writer, "data: %s\n\n", data)
 			if err != nil {
-				h.logger.Debug("Failed to write SSE data", h.logger.Args("error", err))
+				h.logger.Debug("Failed to write SSE data", logArgs(h.logger, c, "error", err))
+				return
+			}
+
+			// Send leaderboards as a named event so existing onmessage consumers are unaffected
+			top := h.collector.GetLeaderboards(topWindow, realtime.DefaultLeaderboardLimit, serviceFilters, excludeIPFilter)
+			topData, err := json.Marshal(top)
+			if err != nil {
+				h.logger.Error("Failed to marshal leaderboards", logArgs(h.logger, c, "error", err))
+			} else if _, err = fmt.Fprintf(c.Writer, "event: top\ndata: %s\n\n", topData); err != nil {
+				h.logger.Debug("Failed to write SSE data", logArgs(h.logger, c, "error", err))
 				return
 			}
 
@@ -200,28 +319,22 @@ func (h *RealtimeHandler) GetPerServiceMetrics(c *gin.Context) {
 	serviceFilters := h.getServiceFilters(c)
 	excludeIPFilter := h.getExcludeOwnIP(c)
 
-	// Convert realtime filters to repositories filters
-	var repoFilters []repositories.ServiceFilter
-	if len(serviceFilters) > 0 {
-		repoFilters = make([]repositories.ServiceFilter, len(serviceFilters))
-		for i, f := range serviceFilters {
-			repoFilters[i] = repositories.ServiceFilter{Name: f.Name, Type: f.Type}
-		}
-	}
+	metrics := h.collector.GetPerServiceMetrics(serviceFilters, excludeIPFilter)
+	c.JSON(200, metrics)
+}
 
-	var repoExcludeIP *repositories.ExcludeIPFilter
-	if excludeIPFilter != nil {
-		repoExcludeIP = &repositories.ExcludeIPFilter{
-			ClientIP: excludeIPFilter.ClientIP,
-		}
-		if len(excludeIPFilter.ExcludeServices) > 0 {
-			repoExcludeIP.ExcludeServices = make([]repositories.ServiceFilter, len(excludeIPFilter.ExcludeServices))
-			for i, f := range excludeIPFilter.ExcludeServices {
-				repoExcludeIP.ExcludeServices[i] = repositories.ServiceFilter{Name: f.Name, Type: f.Type}
-			}
-		}
+// GetTopLeaderboards returns rolling 1m/5m leaderboards (top IPs, paths, status codes)
+func (h *RealtimeHandler) GetTopLeaderboards(c *gin.Context) {
+	window := realtime.ParseLeaderboardWindow(c.DefaultQuery("window", "1m"))
+
+	limit, ok := queryLimit(c, realtime.DefaultLeaderboardLimit, 100)
+	if !ok {
+		return
 	}
 
-	metrics := h.collector.GetPerServiceMetrics(repoFilters, repoExcludeIP)
-	c.JSON(200, metrics)
+	serviceFilters := h.getServiceFilters(c)
+	excludeIPFilter := h.getExcludeOwnIP(c)
+
+	leaderboards := h.collector.GetLeaderboards(window, limit, serviceFilters, excludeIPFilter)
+	c.JSON(200, leaderboards)
 }
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"reflect"
+	"time"
+
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
+	"loglynx/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// StatsResponse wraps stats payloads with metadata describing what the data covers
+type StatsResponse struct {
+	Data interface{}  `json:"data"`
+	Meta ResponseMeta `json:"meta"`
+}
+
+// ResponseMeta holds metadata about a stats response
+type ResponseMeta struct {
+	Range    repositories.TimeRange `json:"range"`              // Effective time range of the query
+	Filters  *AppliedFilters        `json:"filters,omitempty"`  // Service and own IP filters the data was computed with
+	Rows     *int                   `json:"rows,omitempty"`     // Items in data, for list payloads
+	QueryMs  float64                `json:"query_ms"`           // Server-side time from the start of the call to the response
+	Sampling *repositories.Sampling `json:"sampling,omitempty"` // Set when counts were estimated from a sample
+
+	BaselineWeeks int `json:"baseline_weeks,omitempty"` // Previous weeks averaged into the timeline baseline
+
+	// Set when queries of the endpoint timed out (see Degradation)
+	RequestedHours int        `json:"requested_hours,omitempty"` // Time range asked for, when Range was reduced
+	Stale          bool       `json:"stale,omitempty"`           // Last successful response, served after a timeout
+	ComputedAt     *time.Time `json:"computed_at,omitempty"`     // When the stale response was computed
+}
+
+// AppliedFilters are the filters of a stats response, as understood by the server
+type AppliedFilters struct {
+	Services        []filter.Service `json:"services"`
+	ExcludeOwnIP    bool             `json:"exclude_own_ip"`
+	ExcludeServices []filter.Service `json:"exclude_services,omitempty"` // Services the own IP is excluded from, empty = all
+
+	// Thresholds of top-N endpoints
+	MinHits     int64  `json:"min_hits,omitempty"`
+	StatusClass string `json:"status_class,omitempty"`
+	PathPrefix  string `json:"path_prefix,omitempty"`
+}
+
+// topFilterKey holds the thresholds applied by a top-N endpoint in the Gin context
+const topFilterKey = "top_filter"
+
+// requestStartKey holds when the API call started in the Gin context
+const requestStartKey = "request_start"
+
+// TrackDuration records when an API call started, for the query duration of stats responses
+func TrackDuration() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(requestStartKey, time.Now())
+		c.Next()
+	}
+}
+
+// newResponseMeta describes a payload computed over the last hours
+func newResponseMeta(c *gin.Context, data interface{}, hours int) ResponseMeta {
+	meta := ResponseMeta{Range: repositories.NewTimeRange(hours), Rows: rowCount(data), RequestedHours: c.GetInt(requestedHoursKey)}
+	if started := c.GetTime(requestStartKey); !started.IsZero() {
+		meta.QueryMs = float64(time.Since(started).Microseconds()) / 1000
+	}
+	return meta
+}
+
+// rowCount returns the number of items of list payloads, nil for other payloads
+func rowCount(data interface{}) *int {
+	value := reflect.ValueOf(data)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		rows := value.Len()
+		return &rows
+	}
+	return nil
+}
+
+// errorBody is the JSON body of an error response
+// The request ID lets a failing dashboard call be matched with the server logs.
+func errorBody(c *gin.Context, message string) gin.H {
+	return gin.H{"error": message, "request_id": requestid.FromContext(c.Request.Context())}
+}
+
+// logArgs builds logger arguments followed by the request ID of the API call
+func logArgs(logger *pterm.Logger, c *gin.Context, args ...any) []pterm.LoggerArgument {
+	if id := requestid.FromContext(c.Request.Context()); id != "" {
+		args = append(args, "request_id", id)
+	}
+	return logger.Args(args...)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// SettingsHandler serves the dashboard settings stored in the database, e.g. the theme and branding
+type SettingsHandler struct {
+	settingsRepo repositories.SettingsRepository
+	logger       *pterm.Logger
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(settingsRepo repositories.SettingsRepository, logger *pterm.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		settingsRepo: settingsRepo,
+		logger:       logger,
+	}
+}
+
+// Settings returns the stored settings, the defaults when they can't be read so pages still render
+func (h *SettingsHandler) Settings() *repositories.Settings {
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		h.logger.WithCaller().Warn("Failed to read the dashboard settings, using the defaults", h.logger.Args("error", err))
+		return repositories.DefaultSettings()
+	}
+	return settings
+}
+
+// GetSettings returns the dashboard settings
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get settings", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get settings"))
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings changes the dashboard settings, fields left out of the body keep their value
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	settings, err := h.settingsRepo.Get()
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get settings", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get settings"))
+		return
+	}
+	if err := c.ShouldBindJSON(settings); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, "Invalid settings: "+err.Error()))
+		return
+	}
+	if err := settings.Theme.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+
+	if err := h.settingsRepo.Save(settings); err != nil {
+		h.logger.WithCaller().Error("Failed to save settings", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to save settings"))
+		return
+	}
+	h.logger.Info("Dashboard settings updated", h.logger.Args("theme", settings.Theme.Mode, "brand", settings.Theme.BrandName))
+	c.JSON(http.StatusOK, settings)
+}
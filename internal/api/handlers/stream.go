@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Streamed response formats
+const (
+	FormatJSON   = "json"   // JSON array, written incrementally with chunked encoding
+	FormatNDJSON = "ndjson" // One JSON object per line
+	FormatCSV    = "csv"    // Header row, then one row per line (rollup exports)
+)
+
+const (
+	// streamFlushRows is how many rows are buffered before flushing to the client
+	streamFlushRows = 500
+
+	// streamBufferSize is the write buffer between the JSON encoder and the connection
+	streamBufferSize = 64 * 1024
+
+	// Request export limits
+	defaultExportRows = 10000
+	maxExportRows     = 1000000
+)
+
+// parseRequestFields reads the "fields" query parameter (column pruning)
+func parseRequestFields(c *gin.Context) (*repositories.RequestFieldSet, error) {
+	return repositories.ParseRequestFields(c.Query("fields"))
+}
+
+// parseHeaderFilter reads the captured header filters, e.g. header[X-Tenant-Id]=acme
+func parseHeaderFilter(c *gin.Context) (repositories.HeaderFilter, error) {
+	return repositories.ParseHeaderFilter(c.QueryMap("header"))
+}
+
+// parseLabelFilter reads the label filters, e.g. label[customer]=acme
+func parseLabelFilter(c *gin.Context) (repositories.LabelFilter, error) {
+	return repositories.ParseLabelFilter(c.QueryMap("label"))
+}
+
+// parseStreamFormat reads the "format" query parameter
+func parseStreamFormat(c *gin.Context, defaultFormat string) (string, error) {
+	format := c.DefaultQuery("format", defaultFormat)
+	switch format {
+	case FormatJSON, FormatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format %q (expected json or ndjson)", format)
+	}
+}
+
+// requestStream writes requests to the response as they are read from the database
+// Headers are only sent with the first row, so a failing query can still return a JSON error with status 500
+type requestStream struct {
+	c        *gin.Context
+	format   string
+	filename string                        // Sent as attachment when set
+	fields   *repositories.RequestFieldSet // Only these fields are written, nil for all
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+	started  bool
+	rows     int
+}
+
+func newRequestStream(c *gin.Context, format string, filename string, fields *repositories.RequestFieldSet) *requestStream {
+	writer := bufio.NewWriterSize(c.Writer, streamBufferSize)
+	return &requestStream{
+		c:        c,
+		format:   format,
+		filename: filename,
+		fields:   fields,
+		writer:   writer,
+		encoder:  json.NewEncoder(writer),
+	}
+}
+
+// start sends headers and opens the JSON array
+func (s *requestStream) start() {
+	s.started = true
+
+	if s.format == FormatNDJSON {
+		s.c.Header("Content-Type", "application/x-ndjson")
+	} else {
+		s.c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	if s.filename != "" {
+		s.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, s.filename, s.format))
+	}
+	s.c.Status(http.StatusOK)
+
+	// Large exports can take longer than the server write timeout
+	_ = http.NewResponseController(s.c.Writer).SetWriteDeadline(time.Time{})
+
+	if s.format == FormatJSON {
+		s.writer.WriteString("[")
+	}
+}
+
+// Write encodes one request, it stops the query when the client disconnects
+func (s *requestStream) Write(request *models.HTTPRequest) error {
+	if !s.started {
+		s.start()
+	}
+	if s.format == FormatJSON && s.rows > 0 {
+		s.writer.WriteString(",")
+	}
+	if s.fields != nil {
+		data, err := s.fields.MarshalRequest(request)
+		if err != nil {
+			return err
+		}
+		s.writer.Write(data)
+		s.writer.WriteByte('\n')
+	} else if err := s.encoder.Encode(request); err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%streamFlushRows == 0 {
+		if err := s.writer.Flush(); err != nil {
+			return err
+		}
+		s.c.Writer.Flush()
+	}
+	return s.c.Request.Context().Err()
+}
+
+// Finish closes the response after the query ended
+// It returns false when the query failed before anything was sent, so the caller can still send an error response.
+// An error after the first row can't change the status anymore: NDJSON gets a final error line and the JSON array
+// is left unterminated, so clients can't mistake a partial export for a complete one.
+func (s *requestStream) Finish(err error) bool {
+	if err != nil && !s.started {
+		return false
+	}
+	if !s.started {
+		s.start()
+	}
+
+	switch {
+	case err != nil && s.format == FormatNDJSON:
+		s.encoder.Encode(gin.H{"error": "export interrupted"})
+	case err == nil && s.format == FormatJSON:
+		s.writer.WriteString("]")
+	}
+	s.writer.Flush()
+	return true
+}
+
+// trafficRollupColumns are the CSV columns of traffic rollup exports, in the order of TrafficRollupRow
+var trafficRollupColumns = []string{
+	"hour", "service", "status_class", "country", "requests", "bandwidth", "timed_requests",
+	"avg_ms", "min_ms", "max_ms", "p50_ms", "p95_ms", "p99_ms", "digest",
+}
+
+// rollupStream writes traffic rollups as NDJSON or CSV, like requestStream headers are sent with the first row
+type rollupStream struct {
+	c        *gin.Context
+	format   string
+	filename string
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+	csv      *csv.Writer
+	started  bool
+	rows     int
+}
+
+func newRollupStream(c *gin.Context, format string, filename string) *rollupStream {
+	writer := bufio.NewWriterSize(c.Writer, streamBufferSize)
+	return &rollupStream{
+		c:        c,
+		format:   format,
+		filename: filename,
+		writer:   writer,
+		encoder:  json.NewEncoder(writer),
+		csv:      csv.NewWriter(writer),
+	}
+}
+
+func (s *rollupStream) start() {
+	s.started = true
+
+	if s.format == FormatCSV {
+		s.c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		s.c.Header("Content-Type", "application/x-ndjson")
+	}
+	s.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, s.filename, s.format))
+	s.c.Status(http.StatusOK)
+	_ = http.NewResponseController(s.c.Writer).SetWriteDeadline(time.Time{})
+
+	if s.format == FormatCSV {
+		s.csv.Write(trafficRollupColumns)
+	}
+}
+
+// Write encodes one rollup, it stops the query when the client disconnects
+func (s *rollupStream) Write(row *repositories.TrafficRollupRow) error {
+	if !s.started {
+		s.start()
+	}
+	if s.format == FormatCSV {
+		number := func(value float64) string {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		s.csv.Write([]string{
+			row.Hour.Format(time.RFC3339), row.Service, row.StatusClass, row.Country,
+			strconv.FormatInt(row.Requests, 10), strconv.FormatInt(row.Bandwidth, 10), strconv.FormatInt(row.TimedRequests, 10),
+			number(row.AvgMs), number(row.MinMs), number(row.MaxMs), number(row.P50Ms), number(row.P95Ms), number(row.P99Ms),
+			row.Digest,
+		})
+	} else if err := s.encoder.Encode(row); err != nil {
+		return err
+	}
+
+	s.rows++
+	if s.rows%streamFlushRows == 0 {
+		s.csv.Flush()
+		if err := s.writer.Flush(); err != nil {
+			return err
+		}
+		s.c.Writer.Flush()
+	}
+	return s.c.Request.Context().Err()
+}
+
+// Finish closes the response, false when the query failed before anything was sent (see requestStream.Finish)
+// An interrupted NDJSON export ends with an error line, an interrupted CSV export with an "error" row.
+func (s *rollupStream) Finish(err error) bool {
+	if err != nil && !s.started {
+		return false
+	}
+	if !s.started {
+		s.start()
+	}
+
+	switch {
+	case err != nil && s.format == FormatCSV:
+		s.csv.Write([]string{"error", "export interrupted"})
+	case err != nil:
+		s.encoder.Encode(gin.H{"error": "export interrupted"})
+	}
+	s.csv.Flush()
+	s.writer.Flush()
+	return true
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/ingestion"
+	"loglynx/internal/requestid"
+	"loglynx/internal/transfer"
+	"loglynx/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pterm/pterm"
+)
+
+// syncFlushRows is the number of exported requests after which the archive is flushed to the client
+const syncFlushRows = 1000
+
+// SyncHandler moves requests between LogLynx instances, e.g. to consolidate per-server instances
+type SyncHandler struct {
+	readRepo  repositories.HTTPRequestRepository // Exports read from the read-only pool
+	writeRepo repositories.HTTPRequestRepository
+	recorder  ingestion.EventRecorder // Real-time metrics of requests forwarded by agents, optional
+	token     string
+	logger    *pterm.Logger
+}
+
+// NewSyncHandler creates a sync handler, requests must carry the token as a bearer token
+func NewSyncHandler(readRepo repositories.HTTPRequestRepository, writeRepo repositories.HTTPRequestRepository, recorder ingestion.EventRecorder, token string, logger *pterm.Logger) *SyncHandler {
+	return &SyncHandler{
+		readRepo:  readRepo,
+		writeRepo: writeRepo,
+		recorder:  recorder,
+		token:     token,
+		logger:    logger,
+	}
+}
+
+// RequireToken rejects requests without the sync bearer token
+func (h *SyncHandler) RequireToken(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorBody(c, "Invalid or missing sync token"))
+		return
+	}
+	c.Next()
+}
+
+// ExportRequests streams the requests of a time range as a gzip-compressed archive for ImportRequests
+// from and to are RFC 3339 times (from is exclusive), without from every stored request is exported.
+func (h *SyncHandler) ExportRequests(c *gin.Context) {
+	header := transfer.Header{ExportedAt: time.Now(), To: time.Now(), AppVersion: version.Version}
+	header.Instance, _ = os.Hostname()
+	for param, target := range map[string]*time.Time{"from": &header.From, "to": &header.To} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondInvalidParams(c, []FieldError{{Field: param, Message: "must be an RFC 3339 time"}})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if !header.From.IsZero() && !header.From.Before(header.To) {
+		c.JSON(http.StatusBadRequest, errorBody(c, "from must be before to"))
+		return
+	}
+
+	// Large exports outlive the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Debug("Failed to clear the write deadline of the export", logArgs(h.logger, c, "error", err))
+	}
+
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="loglynx-sync-%s.jsonl.gz"`, header.ExportedAt.Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	writer, err := transfer.NewWriter(c.Writer, header)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to start sync export", logArgs(h.logger, c, "error", err))
+		return
+	}
+
+	started := time.Now()
+	filter := repositories.RequestFilter{Since: header.From, Until: header.To, Context: c.Request.Context()}
+	err = h.readRepo.Stream(filter, func(request *models.HTTPRequest) error {
+		if err := writer.WriteRequest(request); err != nil {
+			return err
+		}
+		if writer.Requests()%syncFlushRows == 0 {
+			return writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Without its trailer, the importing instance reports the archive as truncated
+		h.logger.Warn("Sync export interrupted", logArgs(h.logger, c, "requests", writer.Requests(), "error", err))
+		return
+	}
+	if err := writer.Close(); err != nil {
+		h.logger.Warn("Failed to complete sync export", logArgs(h.logger, c, "requests", writer.Requests(), "error", err))
+		return
+	}
+
+	h.logger.Info("Requests exported for sync",
+		logArgs(h.logger, c, "requests", writer.Requests(), "from", header.From, "to", header.To, "duration", time.Since(started).Round(time.Millisecond)))
+}
+
+// ImportRequests stores the requests of an archive produced by ExportRequests, skipping the ones already stored
+// source_prefix is prepended to the source names, e.g. "edge-1/" to tell instances apart.
+// Agents forward their batches here as small archives (see internal/agent).
+func (h *SyncHandler) ImportRequests(c *gin.Context) {
+	// Large imports outlive the server read timeout
+	if err := http.NewResponseController(c.Writer).SetReadDeadline(time.Time{}); err != nil {
+		h.logger.Debug("Failed to clear the read deadline of the import", logArgs(h.logger, c, "error", err))
+	}
+
+	reader, err := transfer.NewReader(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorBody(c, err.Error()))
+		return
+	}
+	defer reader.Close()
+
+	started := time.Now()
+	result, err := transfer.Import(reader, h.writeRepo, c.Query("source_prefix"), h.recorder)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, transfer.ErrStore) {
+			status = http.StatusInternalServerError
+		}
+		h.logger.Warn("Sync import stopped",
+			logArgs(h.logger, c, "requests", result.Requests, "inserted", result.Inserted, "error", err))
+		c.JSON(status, gin.H{"error": err.Error(), "result": result, "request_id": requestid.FromContext(c.Request.Context())})
+		return
+	}
+
+	// Agents forward every few seconds, only log their imports at debug level
+	log := h.logger.Info
+	if result.Header.Agent {
+		log = h.logger.Debug
+	}
+	log("Requests imported from sync",
+		logArgs(h.logger, c, "instance", result.Header.Instance, "requests", result.Requests, "inserted", result.Inserted,
+			"duplicates", result.Duplicates, "duration", time.Since(started).Round(time.Millisecond)))
+	c.JSON(http.StatusOK, result)
+}
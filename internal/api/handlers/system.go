@@ -5,11 +5,11 @@ import (
 	"net/http"
 	"os"
 	"runtime"
-	"strconv"
 	"time"
 
 	"loglynx/internal/database"
 	"loglynx/internal/database/repositories"
+	"loglynx/internal/ingestion"
 	"loglynx/internal/version"
 
 	"github.com/gin-gonic/gin"
@@ -21,10 +21,13 @@ type SystemHandler struct {
 	statsRepo      repositories.StatsRepository
 	httpRepo       repositories.HTTPRequestRepository
 	cleanupService *database.CleanupService
+	coordinator    *ingestion.Coordinator
 	logger         *pterm.Logger
 	startTime      time.Time
 	dbPath         string
 	retentionDays  int
+	queryStats     *database.QueryStats
+	indexProfile   repositories.IndexProfile
 }
 
 // SystemStats holds comprehensive system statistics
@@ -65,18 +68,24 @@ func NewSystemHandler(
 	statsRepo repositories.StatsRepository,
 	httpRepo repositories.HTTPRequestRepository,
 	cleanupService *database.CleanupService,
+	coordinator *ingestion.Coordinator,
 	logger *pterm.Logger,
 	dbPath string,
 	retentionDays int,
+	queryStats *database.QueryStats,
+	indexProfile repositories.IndexProfile,
 ) *SystemHandler {
 	return &SystemHandler{
 		statsRepo:      statsRepo,
 		httpRepo:       httpRepo,
 		cleanupService: cleanupService,
+		coordinator:    coordinator,
 		logger:         logger,
 		startTime:      time.Now(),
 		dbPath:         dbPath,
 		retentionDays:  retentionDays,
+		queryStats:     queryStats,
+		indexProfile:   indexProfile,
 	}
 }
 
@@ -89,10 +98,10 @@ func (h *SystemHandler) HandleSystemStatsPage(c *gin.Context) {
 
 // GetSystemStats returns comprehensive system statistics
 func (h *SystemHandler) GetSystemStats(c *gin.Context) {
-	stats, err := h.collectSystemStats()
+	stats, err := h.collectSystemStats(h.statsRepo.WithContext(c.Request.Context()))
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to collect system stats", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect system stats"})
+		h.logger.WithCaller().Error("Failed to collect system stats", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to collect system stats"))
 		return
 	}
 
@@ -102,29 +111,132 @@ func (h *SystemHandler) GetSystemStats(c *gin.Context) {
 // GetRecordsTimeline returns records count timeline for system stats chart
 func (h *SystemHandler) GetRecordsTimeline(c *gin.Context) {
 	// Get days parameter (default 30)
-	days := 30
-	if daysParam := c.Query("days"); daysParam != "" {
-		if d, err := strconv.Atoi(daysParam); err == nil && d > 0 {
-			if d <= 365 {
-				days = d
-			} else {
-				days = 365 // Cap at 1 year
-			}
+	days := validatedInt(c, "days", 30)
+
+	timeline, err := h.statsRepo.WithContext(c.Request.Context()).GetRecordsTimeline(days)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get records timeline", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get records timeline"))
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// GetSourceCoverage returns the hourly coverage of each log source over the last hours (default 24, at most 168)
+// Hours are traffic, zero_traffic (the source was read, nothing came in) or no_data (the source wasn't read).
+func (h *SystemHandler) GetSourceCoverage(c *gin.Context) {
+	hours, ok := queryIntParam(c, "hours", 24, 1, repositories.MaxCoverageHours)
+	if !ok {
+		return
+	}
+
+	coverage, err := h.statsRepo.WithContext(c.Request.Context()).GetSourceCoverage(hours)
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get source coverage", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get source coverage"))
+		return
+	}
+
+	c.JSON(http.StatusOK, coverage)
+}
+
+// GetIngestionStatus returns processor statistics and shared worker pool queue metrics
+func (h *SystemHandler) GetIngestionStatus(c *gin.Context) {
+	if h.coordinator == nil {
+		c.JSON(http.StatusServiceUnavailable, errorBody(c, "Ingestion coordinator not available"))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.coordinator.GetStatus())
+}
+
+// GetQueryStats returns database query counts and latencies per API endpoint since startup
+// Endpoints with the highest total query time are listed first, they are the most expensive dashboard panels
+func (h *SystemHandler) GetQueryStats(c *gin.Context) {
+	if h.queryStats == nil {
+		c.JSON(http.StatusServiceUnavailable, errorBody(c, "Query stats not available"))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.queryStats.Snapshot())
+}
+
+// StorageStats breaks down the database size and projects its growth
+type StorageStats struct {
+	DatabasePath  string `json:"database_path"`
+	FileSizeBytes int64  `json:"file_size_bytes"`
+	WALSizeBytes  int64  `json:"wal_size_bytes"` // Write-ahead log, folded into the database at checkpoints
+	*repositories.StorageBreakdown
+
+	RetentionDays          int      `json:"retention_days"`
+	ProjectedRetainedBytes int64    `json:"projected_retained_bytes,omitempty"` // Size of retention_days of requests at the current rate
+	DiskFreeBytes          uint64   `json:"disk_free_bytes,omitempty"`
+	DiskTotalBytes         uint64   `json:"disk_total_bytes,omitempty"`
+	DaysUntilDiskFull      *float64 `json:"days_until_disk_full"` // At the current growth, null when not growing
+}
+
+// GetStorageStats returns database and WAL file sizes, table and index sizes, rows per month and projected growth
+func (h *SystemHandler) GetStorageStats(c *gin.Context) {
+	breakdown, err := h.statsRepo.WithContext(c.Request.Context()).GetStorageBreakdown()
+	if err != nil {
+		h.logger.WithCaller().Error("Failed to get storage breakdown", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get storage breakdown"))
+		return
+	}
+
+	stats := &StorageStats{
+		DatabasePath:     h.dbPath,
+		StorageBreakdown: breakdown,
+		RetentionDays:    h.retentionDays,
+	}
+	if fileInfo, err := os.Stat(h.dbPath); err == nil {
+		stats.FileSizeBytes = fileInfo.Size()
+	}
+	if fileInfo, err := os.Stat(h.dbPath + "-wal"); err == nil {
+		stats.WALSizeBytes = fileInfo.Size()
+	}
+
+	// Free pages are reused before the file grows
+	growth := breakdown.Growth.BytesPerDay
+	if h.retentionDays > 0 {
+		stats.ProjectedRetainedBytes = int64(growth * float64(h.retentionDays))
+	}
+	if free, total, err := database.DiskSpace(h.dbPath); err == nil {
+		stats.DiskFreeBytes = free
+		stats.DiskTotalBytes = total
+		available := float64(free) + float64(breakdown.FreePages*breakdown.PageSize)
+		// With retention, the database stops growing once it holds retention_days of requests
+		fitsRetention := h.retentionDays > 0 && float64(stats.ProjectedRetainedBytes-breakdown.UsedBytes) < available
+		if growth > 0 && !fitsRetention {
+			days := available / growth
+			stats.DaysUntilDiskFull = &days
 		}
 	}
 
-	timeline, err := h.statsRepo.GetRecordsTimeline(days)
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetIndexAdvice reports the http_requests indexes unused by the queries run since startup, with their selectivity
+// and the lowest index profile creating every used index
+func (h *SystemHandler) GetIndexAdvice(c *gin.Context) {
+	var statements []string
+	if h.queryStats != nil {
+		statements = h.queryStats.QueryPatterns()
+	}
+
+	advice, err := h.statsRepo.WithContext(c.Request.Context()).GetIndexAdvice(h.indexProfile, statements)
 	if err != nil {
-		h.logger.WithCaller().Error("Failed to get records timeline", h.logger.Args("error", err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get records timeline"})
+		h.logger.WithCaller().Error("Failed to get index advice", logArgs(h.logger, c, "error", err))
+		c.JSON(http.StatusInternalServerError, errorBody(c, "Failed to get index advice"))
 		return
 	}
 
-	c.JSON(http.StatusOK, timeline)
+	c.JSON(http.StatusOK, advice)
 }
 
 // collectSystemStats gathers all system statistics
-func (h *SystemHandler) collectSystemStats() (*SystemStats, error) {
+func (h *SystemHandler) collectSystemStats(statsRepo repositories.StatsRepository) (*SystemStats, error) {
 	stats := &SystemStats{
 		AppVersion:    version.Version,
 		StartTime:     h.startTime.Format(time.RFC3339),
@@ -158,7 +270,7 @@ func (h *SystemHandler) collectSystemStats() (*SystemStats, error) {
 	// Calculate records to cleanup (if retention is enabled)
 	if h.retentionDays > 0 {
 		cutoffDate := time.Now().AddDate(0, 0, -h.retentionDays)
-		recordsToCleanup, err := h.statsRepo.CountRecordsOlderThan(cutoffDate)
+		recordsToCleanup, err := statsRepo.CountRecordsOlderThan(cutoffDate)
 		if err != nil {
 			h.logger.WithCaller().Warn("Failed to count records to cleanup", h.logger.Args("error", err))
 		}
@@ -190,7 +302,7 @@ func (h *SystemHandler) collectSystemStats() (*SystemStats, error) {
 	}
 
 	// Oldest and newest record ages
-	oldestTime, newestTime, err := h.statsRepo.GetRecordTimeRange()
+	oldestTime, newestTime, err := statsRepo.GetRecordTimeRange()
 	if err == nil {
 		if !oldestTime.IsZero() {
 			stats.OldestRecordAge = formatDuration(time.Since(oldestTime))
@@ -0,0 +1,1299 @@
+// gRPC definitions of the LogLynx API
+//
+// Served on GRPC_PORT (internal/api/rpc) next to the REST API (openapi.yaml). Regenerate the Go code in
+// internal/api/rpc/loglynxv1 with protoc-gen-go and protoc-gen-go-grpc (paths=source_relative) after a change.
+//
+// Messages mirror the REST responses field by field so both surfaces can share the repositories:
+// StatsSummary is GET /api/v1/stats/summary, SearchRequests is GET /api/v1/requests/search,
+// StreamMetrics is the SSE stream of GET /api/v1/realtime/stream and ForwardRequests is POST /api/v1/sync/import.
+// StreamMetrics needs STREAM_TOKEN and ForwardRequests SYNC_TOKEN as "authorization: Bearer <token>" metadata.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: loglynx/v1/loglynx.proto
+
+package loglynxv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Filters shared by statistics calls (same meaning as the REST query parameters)
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hours         int32                  `protobuf:"varint,1,opt,name=hours,proto3" json:"hours,omitempty"`                               // Lookback window, 0 = STATS_LOOKBACK_HOURS
+	Services      []string               `protobuf:"bytes,2,rep,name=services,proto3" json:"services,omitempty"`                          // service
+	ServiceType   string                 `protobuf:"bytes,3,opt,name=service_type,json=serviceType,proto3" json:"service_type,omitempty"` // service_type (auto, backend_name, backend_url, host)
+	ExcludeIp     string                 `protobuf:"bytes,4,opt,name=exclude_ip,json=excludeIp,proto3" json:"exclude_ip,omitempty"`       // exclude_own_ip for this client IP (empty = none)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StatsRequest) GetHours() int32 {
+	if x != nil {
+		return x.Hours
+	}
+	return 0
+}
+
+func (x *StatsRequest) GetServices() []string {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *StatsRequest) GetServiceType() string {
+	if x != nil {
+		return x.ServiceType
+	}
+	return ""
+}
+
+func (x *StatsRequest) GetExcludeIp() string {
+	if x != nil {
+		return x.ExcludeIp
+	}
+	return ""
+}
+
+type StatsSummary struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TotalRequests   int64                  `protobuf:"varint,1,opt,name=total_requests,json=totalRequests,proto3" json:"total_requests,omitempty"`
+	ValidRequests   int64                  `protobuf:"varint,2,opt,name=valid_requests,json=validRequests,proto3" json:"valid_requests,omitempty"`
+	FailedRequests  int64                  `protobuf:"varint,3,opt,name=failed_requests,json=failedRequests,proto3" json:"failed_requests,omitempty"`
+	UniqueVisitors  int64                  `protobuf:"varint,4,opt,name=unique_visitors,json=uniqueVisitors,proto3" json:"unique_visitors,omitempty"`
+	UniqueFiles     int64                  `protobuf:"varint,5,opt,name=unique_files,json=uniqueFiles,proto3" json:"unique_files,omitempty"`
+	Unique_404      int64                  `protobuf:"varint,6,opt,name=unique_404,json=unique404,proto3" json:"unique_404,omitempty"`
+	TotalBandwidth  int64                  `protobuf:"varint,7,opt,name=total_bandwidth,json=totalBandwidth,proto3" json:"total_bandwidth,omitempty"`
+	AvgResponseTime float64                `protobuf:"fixed64,8,opt,name=avg_response_time,json=avgResponseTime,proto3" json:"avg_response_time,omitempty"`
+	SuccessRate     float64                `protobuf:"fixed64,9,opt,name=success_rate,json=successRate,proto3" json:"success_rate,omitempty"`
+	NotFoundRate    float64                `protobuf:"fixed64,10,opt,name=not_found_rate,json=notFoundRate,proto3" json:"not_found_rate,omitempty"`
+	ServerErrorRate float64                `protobuf:"fixed64,11,opt,name=server_error_rate,json=serverErrorRate,proto3" json:"server_error_rate,omitempty"`
+	RequestsPerHour float64                `protobuf:"fixed64,12,opt,name=requests_per_hour,json=requestsPerHour,proto3" json:"requests_per_hour,omitempty"`
+	TopCountry      string                 `protobuf:"bytes,13,opt,name=top_country,json=topCountry,proto3" json:"top_country,omitempty"`
+	TopPath         string                 `protobuf:"bytes,14,opt,name=top_path,json=topPath,proto3" json:"top_path,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StatsSummary) Reset() {
+	*x = StatsSummary{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsSummary) ProtoMessage() {}
+
+func (x *StatsSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsSummary.ProtoReflect.Descriptor instead.
+func (*StatsSummary) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatsSummary) GetTotalRequests() int64 {
+	if x != nil {
+		return x.TotalRequests
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetValidRequests() int64 {
+	if x != nil {
+		return x.ValidRequests
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetFailedRequests() int64 {
+	if x != nil {
+		return x.FailedRequests
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetUniqueVisitors() int64 {
+	if x != nil {
+		return x.UniqueVisitors
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetUniqueFiles() int64 {
+	if x != nil {
+		return x.UniqueFiles
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetUnique_404() int64 {
+	if x != nil {
+		return x.Unique_404
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetTotalBandwidth() int64 {
+	if x != nil {
+		return x.TotalBandwidth
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetAvgResponseTime() float64 {
+	if x != nil {
+		return x.AvgResponseTime
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetSuccessRate() float64 {
+	if x != nil {
+		return x.SuccessRate
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetNotFoundRate() float64 {
+	if x != nil {
+		return x.NotFoundRate
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetServerErrorRate() float64 {
+	if x != nil {
+		return x.ServerErrorRate
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetRequestsPerHour() float64 {
+	if x != nil {
+		return x.RequestsPerHour
+	}
+	return 0
+}
+
+func (x *StatsSummary) GetTopCountry() string {
+	if x != nil {
+		return x.TopCountry
+	}
+	return ""
+}
+
+func (x *StatsSummary) GetTopPath() string {
+	if x != nil {
+		return x.TopPath
+	}
+	return ""
+}
+
+type SearchRequestsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Q             string                 `protobuf:"bytes,1,opt,name=q,proto3" json:"q,omitempty"`         // Search query
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"` // Restrict the search to one field
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Hours         int32                  `protobuf:"varint,5,opt,name=hours,proto3" json:"hours,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequestsRequest) Reset() {
+	*x = SearchRequestsRequest{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequestsRequest) ProtoMessage() {}
+
+func (x *SearchRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequestsRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchRequestsRequest) GetQ() string {
+	if x != nil {
+		return x.Q
+	}
+	return ""
+}
+
+func (x *SearchRequestsRequest) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *SearchRequestsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequestsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequestsRequest) GetHours() int32 {
+	if x != nil {
+		return x.Hours
+	}
+	return 0
+}
+
+type SearchRequestsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*HTTPRequest         `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequestsResponse) Reset() {
+	*x = SearchRequestsResponse{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequestsResponse) ProtoMessage() {}
+
+func (x *SearchRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequestsResponse.ProtoReflect.Descriptor instead.
+func (*SearchRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchRequestsResponse) GetRequests() []*HTTPRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type StreamMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Services      []string               `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMetricsRequest) Reset() {
+	*x = StreamMetricsRequest{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMetricsRequest) ProtoMessage() {}
+
+func (x *StreamMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMetricsRequest.ProtoReflect.Descriptor instead.
+func (*StreamMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamMetricsRequest) GetServices() []string {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+type RealtimeMetrics struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	RequestRate       float64                `protobuf:"fixed64,1,opt,name=request_rate,json=requestRate,proto3" json:"request_rate,omitempty"`               // req/sec (60s average)
+	InstantRate       float64                `protobuf:"fixed64,2,opt,name=instant_rate,json=instantRate,proto3" json:"instant_rate,omitempty"`               // req/sec of the newest complete second with requests
+	BurstMax          float64                `protobuf:"fixed64,3,opt,name=burst_max,json=burstMax,proto3" json:"burst_max,omitempty"`                        // busiest single second in the last 60s
+	ErrorRate         float64                `protobuf:"fixed64,4,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`                     // errors/sec
+	AvgResponseTime   float64                `protobuf:"fixed64,5,opt,name=avg_response_time,json=avgResponseTime,proto3" json:"avg_response_time,omitempty"` // ms
+	ActiveConnections int32                  `protobuf:"varint,6,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	Status_2Xx        int64                  `protobuf:"varint,7,opt,name=status_2xx,json=status2xx,proto3" json:"status_2xx,omitempty"`
+	Status_4Xx        int64                  `protobuf:"varint,8,opt,name=status_4xx,json=status4xx,proto3" json:"status_4xx,omitempty"`
+	Status_5Xx        int64                  `protobuf:"varint,9,opt,name=status_5xx,json=status5xx,proto3" json:"status_5xx,omitempty"`
+	Timestamp         *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RealtimeMetrics) Reset() {
+	*x = RealtimeMetrics{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RealtimeMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RealtimeMetrics) ProtoMessage() {}
+
+func (x *RealtimeMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RealtimeMetrics.ProtoReflect.Descriptor instead.
+func (*RealtimeMetrics) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RealtimeMetrics) GetRequestRate() float64 {
+	if x != nil {
+		return x.RequestRate
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetInstantRate() float64 {
+	if x != nil {
+		return x.InstantRate
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetBurstMax() float64 {
+	if x != nil {
+		return x.BurstMax
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetAvgResponseTime() float64 {
+	if x != nil {
+		return x.AvgResponseTime
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetActiveConnections() int32 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetStatus_2Xx() int64 {
+	if x != nil {
+		return x.Status_2Xx
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetStatus_4Xx() int64 {
+	if x != nil {
+		return x.Status_4Xx
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetStatus_5Xx() int64 {
+	if x != nil {
+		return x.Status_5Xx
+	}
+	return 0
+}
+
+func (x *RealtimeMetrics) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+// A stored request, with all the columns of models.HTTPRequest so forwarding keeps them like /api/v1/sync/import
+type HTTPRequest struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Id                     uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	SourceName             string                 `protobuf:"bytes,2,opt,name=source_name,json=sourceName,proto3" json:"source_name,omitempty"`
+	Timestamp              *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	RequestHash            string                 `protobuf:"bytes,4,opt,name=request_hash,json=requestHash,proto3" json:"request_hash,omitempty"`
+	ClientIp               string                 `protobuf:"bytes,5,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	Method                 string                 `protobuf:"bytes,6,opt,name=method,proto3" json:"method,omitempty"`
+	Protocol               string                 `protobuf:"bytes,7,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Host                   string                 `protobuf:"bytes,8,opt,name=host,proto3" json:"host,omitempty"`
+	Path                   string                 `protobuf:"bytes,9,opt,name=path,proto3" json:"path,omitempty"`
+	QueryString            string                 `protobuf:"bytes,10,opt,name=query_string,json=queryString,proto3" json:"query_string,omitempty"`
+	StatusCode             int32                  `protobuf:"varint,11,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	ResponseSize           int64                  `protobuf:"varint,12,opt,name=response_size,json=responseSize,proto3" json:"response_size,omitempty"`
+	ResponseTimeMs         float64                `protobuf:"fixed64,13,opt,name=response_time_ms,json=responseTimeMs,proto3" json:"response_time_ms,omitempty"`
+	UserAgent              string                 `protobuf:"bytes,14,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	Referer                string                 `protobuf:"bytes,15,opt,name=referer,proto3" json:"referer,omitempty"`
+	BackendName            string                 `protobuf:"bytes,16,opt,name=backend_name,json=backendName,proto3" json:"backend_name,omitempty"`
+	RouterName             string                 `protobuf:"bytes,17,opt,name=router_name,json=routerName,proto3" json:"router_name,omitempty"`
+	GeoCountry             string                 `protobuf:"bytes,18,opt,name=geo_country,json=geoCountry,proto3" json:"geo_country,omitempty"`
+	ClientPort             int32                  `protobuf:"varint,19,opt,name=client_port,json=clientPort,proto3" json:"client_port,omitempty"`
+	ClientUser             string                 `protobuf:"bytes,20,opt,name=client_user,json=clientUser,proto3" json:"client_user,omitempty"`
+	ApiKeyHash             string                 `protobuf:"bytes,21,opt,name=api_key_hash,json=apiKeyHash,proto3" json:"api_key_hash,omitempty"`
+	RequestLength          int64                  `protobuf:"varint,22,opt,name=request_length,json=requestLength,proto3" json:"request_length,omitempty"`
+	RequestScheme          string                 `protobuf:"bytes,23,opt,name=request_scheme,json=requestScheme,proto3" json:"request_scheme,omitempty"`
+	ResponseContentType    string                 `protobuf:"bytes,24,opt,name=response_content_type,json=responseContentType,proto3" json:"response_content_type,omitempty"`
+	Duration               int64                  `protobuf:"varint,25,opt,name=duration,proto3" json:"duration,omitempty"` // Nanoseconds
+	StartUtc               string                 `protobuf:"bytes,26,opt,name=start_utc,json=startUtc,proto3" json:"start_utc,omitempty"`
+	UpstreamResponseTimeMs float64                `protobuf:"fixed64,27,opt,name=upstream_response_time_ms,json=upstreamResponseTimeMs,proto3" json:"upstream_response_time_ms,omitempty"`
+	RetryAttempts          int32                  `protobuf:"varint,28,opt,name=retry_attempts,json=retryAttempts,proto3" json:"retry_attempts,omitempty"`
+	RequestsTotal          int32                  `protobuf:"varint,29,opt,name=requests_total,json=requestsTotal,proto3" json:"requests_total,omitempty"`
+	RequestHeaderBytes     int64                  `protobuf:"varint,30,opt,name=request_header_bytes,json=requestHeaderBytes,proto3" json:"request_header_bytes,omitempty"`
+	ResponseHeaderBytes    int64                  `protobuf:"varint,31,opt,name=response_header_bytes,json=responseHeaderBytes,proto3" json:"response_header_bytes,omitempty"`
+	Browser                string                 `protobuf:"bytes,32,opt,name=browser,proto3" json:"browser,omitempty"`
+	BrowserVersion         string                 `protobuf:"bytes,33,opt,name=browser_version,json=browserVersion,proto3" json:"browser_version,omitempty"`
+	Os                     string                 `protobuf:"bytes,34,opt,name=os,proto3" json:"os,omitempty"`
+	OsVersion              string                 `protobuf:"bytes,35,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	DeviceType             string                 `protobuf:"bytes,36,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"`
+	BackendUrl             string                 `protobuf:"bytes,37,opt,name=backend_url,json=backendUrl,proto3" json:"backend_url,omitempty"`
+	UpstreamStatus         int32                  `protobuf:"varint,38,opt,name=upstream_status,json=upstreamStatus,proto3" json:"upstream_status,omitempty"`
+	UpstreamContentType    string                 `protobuf:"bytes,39,opt,name=upstream_content_type,json=upstreamContentType,proto3" json:"upstream_content_type,omitempty"`
+	UpstreamAddr           string                 `protobuf:"bytes,40,opt,name=upstream_addr,json=upstreamAddr,proto3" json:"upstream_addr,omitempty"`
+	ClientHostname         string                 `protobuf:"bytes,41,opt,name=client_hostname,json=clientHostname,proto3" json:"client_hostname,omitempty"`
+	TlsVersion             string                 `protobuf:"bytes,42,opt,name=tls_version,json=tlsVersion,proto3" json:"tls_version,omitempty"`
+	TlsCipher              string                 `protobuf:"bytes,43,opt,name=tls_cipher,json=tlsCipher,proto3" json:"tls_cipher,omitempty"`
+	TlsServerName          string                 `protobuf:"bytes,44,opt,name=tls_server_name,json=tlsServerName,proto3" json:"tls_server_name,omitempty"`
+	RequestId              string                 `protobuf:"bytes,45,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	TraceId                string                 `protobuf:"bytes,46,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	GeoCity                string                 `protobuf:"bytes,47,opt,name=geo_city,json=geoCity,proto3" json:"geo_city,omitempty"`
+	GeoLat                 float64                `protobuf:"fixed64,48,opt,name=geo_lat,json=geoLat,proto3" json:"geo_lat,omitempty"`
+	GeoLon                 float64                `protobuf:"fixed64,49,opt,name=geo_lon,json=geoLon,proto3" json:"geo_lon,omitempty"`
+	GeoEu                  bool                   `protobuf:"varint,50,opt,name=geo_eu,json=geoEu,proto3" json:"geo_eu,omitempty"`
+	Asn                    int32                  `protobuf:"varint,51,opt,name=asn,proto3" json:"asn,omitempty"`
+	AsnOrg                 string                 `protobuf:"bytes,52,opt,name=asn_org,json=asnOrg,proto3" json:"asn_org,omitempty"`
+	ProxyMetadata          string                 `protobuf:"bytes,53,opt,name=proxy_metadata,json=proxyMetadata,proto3" json:"proxy_metadata,omitempty"` // JSON
+	Labels                 map[string]string      `protobuf:"bytes,54,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	PartitionKey           string                 `protobuf:"bytes,55,opt,name=partition_key,json=partitionKey,proto3" json:"partition_key,omitempty"`
+	CreatedAt              *timestamppb.Timestamp `protobuf:"bytes,56,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *HTTPRequest) Reset() {
+	*x = HTTPRequest{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HTTPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HTTPRequest) ProtoMessage() {}
+
+func (x *HTTPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HTTPRequest.ProtoReflect.Descriptor instead.
+func (*HTTPRequest) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HTTPRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetSourceName() string {
+	if x != nil {
+		return x.SourceName
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *HTTPRequest) GetRequestHash() string {
+	if x != nil {
+		return x.RequestHash
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetQueryString() string {
+	if x != nil {
+		return x.QueryString
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetResponseSize() int64 {
+	if x != nil {
+		return x.ResponseSize
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetResponseTimeMs() float64 {
+	if x != nil {
+		return x.ResponseTimeMs
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetReferer() string {
+	if x != nil {
+		return x.Referer
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetBackendName() string {
+	if x != nil {
+		return x.BackendName
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetRouterName() string {
+	if x != nil {
+		return x.RouterName
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetGeoCountry() string {
+	if x != nil {
+		return x.GeoCountry
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetClientPort() int32 {
+	if x != nil {
+		return x.ClientPort
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetClientUser() string {
+	if x != nil {
+		return x.ClientUser
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetApiKeyHash() string {
+	if x != nil {
+		return x.ApiKeyHash
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetRequestLength() int64 {
+	if x != nil {
+		return x.RequestLength
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetRequestScheme() string {
+	if x != nil {
+		return x.RequestScheme
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetResponseContentType() string {
+	if x != nil {
+		return x.ResponseContentType
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetDuration() int64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetStartUtc() string {
+	if x != nil {
+		return x.StartUtc
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetUpstreamResponseTimeMs() float64 {
+	if x != nil {
+		return x.UpstreamResponseTimeMs
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetRetryAttempts() int32 {
+	if x != nil {
+		return x.RetryAttempts
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetRequestsTotal() int32 {
+	if x != nil {
+		return x.RequestsTotal
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetRequestHeaderBytes() int64 {
+	if x != nil {
+		return x.RequestHeaderBytes
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetResponseHeaderBytes() int64 {
+	if x != nil {
+		return x.ResponseHeaderBytes
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetBrowser() string {
+	if x != nil {
+		return x.Browser
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetBrowserVersion() string {
+	if x != nil {
+		return x.BrowserVersion
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetDeviceType() string {
+	if x != nil {
+		return x.DeviceType
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetBackendUrl() string {
+	if x != nil {
+		return x.BackendUrl
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetUpstreamStatus() int32 {
+	if x != nil {
+		return x.UpstreamStatus
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetUpstreamContentType() string {
+	if x != nil {
+		return x.UpstreamContentType
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetUpstreamAddr() string {
+	if x != nil {
+		return x.UpstreamAddr
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetClientHostname() string {
+	if x != nil {
+		return x.ClientHostname
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetTlsVersion() string {
+	if x != nil {
+		return x.TlsVersion
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetTlsCipher() string {
+	if x != nil {
+		return x.TlsCipher
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetTlsServerName() string {
+	if x != nil {
+		return x.TlsServerName
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetGeoCity() string {
+	if x != nil {
+		return x.GeoCity
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetGeoLat() float64 {
+	if x != nil {
+		return x.GeoLat
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetGeoLon() float64 {
+	if x != nil {
+		return x.GeoLon
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetGeoEu() bool {
+	if x != nil {
+		return x.GeoEu
+	}
+	return false
+}
+
+func (x *HTTPRequest) GetAsn() int32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *HTTPRequest) GetAsnOrg() string {
+	if x != nil {
+		return x.AsnOrg
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetProxyMetadata() string {
+	if x != nil {
+		return x.ProxyMetadata
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *HTTPRequest) GetPartitionKey() string {
+	if x != nil {
+		return x.PartitionKey
+	}
+	return ""
+}
+
+func (x *HTTPRequest) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ImportResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      int64                  `protobuf:"varint,1,opt,name=requests,proto3" json:"requests,omitempty"`
+	Inserted      int64                  `protobuf:"varint,2,opt,name=inserted,proto3" json:"inserted,omitempty"`
+	Duplicates    int64                  `protobuf:"varint,3,opt,name=duplicates,proto3" json:"duplicates,omitempty"`
+	Complete      bool                   `protobuf:"varint,4,opt,name=complete,proto3" json:"complete,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResult) Reset() {
+	*x = ImportResult{}
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResult) ProtoMessage() {}
+
+func (x *ImportResult) ProtoReflect() protoreflect.Message {
+	mi := &file_loglynx_v1_loglynx_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResult.ProtoReflect.Descriptor instead.
+func (*ImportResult) Descriptor() ([]byte, []int) {
+	return file_loglynx_v1_loglynx_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ImportResult) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *ImportResult) GetInserted() int64 {
+	if x != nil {
+		return x.Inserted
+	}
+	return 0
+}
+
+func (x *ImportResult) GetDuplicates() int64 {
+	if x != nil {
+		return x.Duplicates
+	}
+	return 0
+}
+
+func (x *ImportResult) GetComplete() bool {
+	if x != nil {
+		return x.Complete
+	}
+	return false
+}
+
+var File_loglynx_v1_loglynx_proto protoreflect.FileDescriptor
+
+const file_loglynx_v1_loglynx_proto_rawDesc = "" +
+	"\n" +
+	"\x18loglynx/v1/loglynx.proto\x12\n" +
+	"loglynx.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x82\x01\n" +
+	"\fStatsRequest\x12\x14\n" +
+	"\x05hours\x18\x01 \x01(\x05R\x05hours\x12\x1a\n" +
+	"\bservices\x18\x02 \x03(\tR\bservices\x12!\n" +
+	"\fservice_type\x18\x03 \x01(\tR\vserviceType\x12\x1d\n" +
+	"\n" +
+	"exclude_ip\x18\x04 \x01(\tR\texcludeIp\"\xa2\x04\n" +
+	"\fStatsSummary\x12%\n" +
+	"\x0etotal_requests\x18\x01 \x01(\x03R\rtotalRequests\x12%\n" +
+	"\x0evalid_requests\x18\x02 \x01(\x03R\rvalidRequests\x12'\n" +
+	"\x0ffailed_requests\x18\x03 \x01(\x03R\x0efailedRequests\x12'\n" +
+	"\x0funique_visitors\x18\x04 \x01(\x03R\x0euniqueVisitors\x12!\n" +
+	"\funique_files\x18\x05 \x01(\x03R\vuniqueFiles\x12\x1d\n" +
+	"\n" +
+	"unique_404\x18\x06 \x01(\x03R\tunique404\x12'\n" +
+	"\x0ftotal_bandwidth\x18\a \x01(\x03R\x0etotalBandwidth\x12*\n" +
+	"\x11avg_response_time\x18\b \x01(\x01R\x0favgResponseTime\x12!\n" +
+	"\fsuccess_rate\x18\t \x01(\x01R\vsuccessRate\x12$\n" +
+	"\x0enot_found_rate\x18\n" +
+	" \x01(\x01R\fnotFoundRate\x12*\n" +
+	"\x11server_error_rate\x18\v \x01(\x01R\x0fserverErrorRate\x12*\n" +
+	"\x11requests_per_hour\x18\f \x01(\x01R\x0frequestsPerHour\x12\x1f\n" +
+	"\vtop_country\x18\r \x01(\tR\n" +
+	"topCountry\x12\x19\n" +
+	"\btop_path\x18\x0e \x01(\tR\atopPath\"\x7f\n" +
+	"\x15SearchRequestsRequest\x12\f\n" +
+	"\x01q\x18\x01 \x01(\tR\x01q\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05hours\x18\x05 \x01(\x05R\x05hours\"M\n" +
+	"\x16SearchRequestsResponse\x123\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.loglynx.v1.HTTPRequestR\brequests\"2\n" +
+	"\x14StreamMetricsRequest\x12\x1a\n" +
+	"\bservices\x18\x01 \x03(\tR\bservices\"\x85\x03\n" +
+	"\x0fRealtimeMetrics\x12!\n" +
+	"\frequest_rate\x18\x01 \x01(\x01R\vrequestRate\x12!\n" +
+	"\finstant_rate\x18\x02 \x01(\x01R\vinstantRate\x12\x1b\n" +
+	"\tburst_max\x18\x03 \x01(\x01R\bburstMax\x12\x1d\n" +
+	"\n" +
+	"error_rate\x18\x04 \x01(\x01R\terrorRate\x12*\n" +
+	"\x11avg_response_time\x18\x05 \x01(\x01R\x0favgResponseTime\x12-\n" +
+	"\x12active_connections\x18\x06 \x01(\x05R\x11activeConnections\x12\x1d\n" +
+	"\n" +
+	"status_2xx\x18\a \x01(\x03R\tstatus2xx\x12\x1d\n" +
+	"\n" +
+	"status_4xx\x18\b \x01(\x03R\tstatus4xx\x12\x1d\n" +
+	"\n" +
+	"status_5xx\x18\t \x01(\x03R\tstatus5xx\x128\n" +
+	"\ttimestamp\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\"\xe2\x0f\n" +
+	"\vHTTPRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
+	"\vsource_name\x18\x02 \x01(\tR\n" +
+	"sourceName\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12!\n" +
+	"\frequest_hash\x18\x04 \x01(\tR\vrequestHash\x12\x1b\n" +
+	"\tclient_ip\x18\x05 \x01(\tR\bclientIp\x12\x16\n" +
+	"\x06method\x18\x06 \x01(\tR\x06method\x12\x1a\n" +
+	"\bprotocol\x18\a \x01(\tR\bprotocol\x12\x12\n" +
+	"\x04host\x18\b \x01(\tR\x04host\x12\x12\n" +
+	"\x04path\x18\t \x01(\tR\x04path\x12!\n" +
+	"\fquery_string\x18\n" +
+	" \x01(\tR\vqueryString\x12\x1f\n" +
+	"\vstatus_code\x18\v \x01(\x05R\n" +
+	"statusCode\x12#\n" +
+	"\rresponse_size\x18\f \x01(\x03R\fresponseSize\x12(\n" +
+	"\x10response_time_ms\x18\r \x01(\x01R\x0eresponseTimeMs\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x0e \x01(\tR\tuserAgent\x12\x18\n" +
+	"\areferer\x18\x0f \x01(\tR\areferer\x12!\n" +
+	"\fbackend_name\x18\x10 \x01(\tR\vbackendName\x12\x1f\n" +
+	"\vrouter_name\x18\x11 \x01(\tR\n" +
+	"routerName\x12\x1f\n" +
+	"\vgeo_country\x18\x12 \x01(\tR\n" +
+	"geoCountry\x12\x1f\n" +
+	"\vclient_port\x18\x13 \x01(\x05R\n" +
+	"clientPort\x12\x1f\n" +
+	"\vclient_user\x18\x14 \x01(\tR\n" +
+	"clientUser\x12 \n" +
+	"\fapi_key_hash\x18\x15 \x01(\tR\n" +
+	"apiKeyHash\x12%\n" +
+	"\x0erequest_length\x18\x16 \x01(\x03R\rrequestLength\x12%\n" +
+	"\x0erequest_scheme\x18\x17 \x01(\tR\rrequestScheme\x122\n" +
+	"\x15response_content_type\x18\x18 \x01(\tR\x13responseContentType\x12\x1a\n" +
+	"\bduration\x18\x19 \x01(\x03R\bduration\x12\x1b\n" +
+	"\tstart_utc\x18\x1a \x01(\tR\bstartUtc\x129\n" +
+	"\x19upstream_response_time_ms\x18\x1b \x01(\x01R\x16upstreamResponseTimeMs\x12%\n" +
+	"\x0eretry_attempts\x18\x1c \x01(\x05R\rretryAttempts\x12%\n" +
+	"\x0erequests_total\x18\x1d \x01(\x05R\rrequestsTotal\x120\n" +
+	"\x14request_header_bytes\x18\x1e \x01(\x03R\x12requestHeaderBytes\x122\n" +
+	"\x15response_header_bytes\x18\x1f \x01(\x03R\x13responseHeaderBytes\x12\x18\n" +
+	"\abrowser\x18  \x01(\tR\abrowser\x12'\n" +
+	"\x0fbrowser_version\x18! \x01(\tR\x0ebrowserVersion\x12\x0e\n" +
+	"\x02os\x18\" \x01(\tR\x02os\x12\x1d\n" +
+	"\n" +
+	"os_version\x18# \x01(\tR\tosVersion\x12\x1f\n" +
+	"\vdevice_type\x18$ \x01(\tR\n" +
+	"deviceType\x12\x1f\n" +
+	"\vbackend_url\x18% \x01(\tR\n" +
+	"backendUrl\x12'\n" +
+	"\x0fupstream_status\x18& \x01(\x05R\x0eupstreamStatus\x122\n" +
+	"\x15upstream_content_type\x18' \x01(\tR\x13upstreamContentType\x12#\n" +
+	"\rupstream_addr\x18( \x01(\tR\fupstreamAddr\x12'\n" +
+	"\x0fclient_hostname\x18) \x01(\tR\x0eclientHostname\x12\x1f\n" +
+	"\vtls_version\x18* \x01(\tR\n" +
+	"tlsVersion\x12\x1d\n" +
+	"\n" +
+	"tls_cipher\x18+ \x01(\tR\ttlsCipher\x12&\n" +
+	"\x0ftls_server_name\x18, \x01(\tR\rtlsServerName\x12\x1d\n" +
+	"\n" +
+	"request_id\x18- \x01(\tR\trequestId\x12\x19\n" +
+	"\btrace_id\x18. \x01(\tR\atraceId\x12\x19\n" +
+	"\bgeo_city\x18/ \x01(\tR\ageoCity\x12\x17\n" +
+	"\ageo_lat\x180 \x01(\x01R\x06geoLat\x12\x17\n" +
+	"\ageo_lon\x181 \x01(\x01R\x06geoLon\x12\x15\n" +
+	"\x06geo_eu\x182 \x01(\bR\x05geoEu\x12\x10\n" +
+	"\x03asn\x183 \x01(\x05R\x03asn\x12\x17\n" +
+	"\aasn_org\x184 \x01(\tR\x06asnOrg\x12%\n" +
+	"\x0eproxy_metadata\x185 \x01(\tR\rproxyMetadata\x12;\n" +
+	"\x06labels\x186 \x03(\v2#.loglynx.v1.HTTPRequest.LabelsEntryR\x06labels\x12#\n" +
+	"\rpartition_key\x187 \x01(\tR\fpartitionKey\x129\n" +
+	"\n" +
+	"created_at\x188 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x82\x01\n" +
+	"\fImportResult\x12\x1a\n" +
+	"\brequests\x18\x01 \x01(\x03R\brequests\x12\x1a\n" +
+	"\binserted\x18\x02 \x01(\x03R\binserted\x12\x1e\n" +
+	"\n" +
+	"duplicates\x18\x03 \x01(\x03R\n" +
+	"duplicates\x12\x1a\n" +
+	"\bcomplete\x18\x04 \x01(\bR\bcomplete2\xbe\x02\n" +
+	"\aLogLynx\x12@\n" +
+	"\n" +
+	"GetSummary\x12\x18.loglynx.v1.StatsRequest\x1a\x18.loglynx.v1.StatsSummary\x12W\n" +
+	"\x0eSearchRequests\x12!.loglynx.v1.SearchRequestsRequest\x1a\".loglynx.v1.SearchRequestsResponse\x12P\n" +
+	"\rStreamMetrics\x12 .loglynx.v1.StreamMetricsRequest\x1a\x1b.loglynx.v1.RealtimeMetrics0\x01\x12F\n" +
+	"\x0fForwardRequests\x12\x17.loglynx.v1.HTTPRequest\x1a\x18.loglynx.v1.ImportResult(\x01B$Z\"loglynx/internal/api/rpc/loglynxv1b\x06proto3"
+
+var (
+	file_loglynx_v1_loglynx_proto_rawDescOnce sync.Once
+	file_loglynx_v1_loglynx_proto_rawDescData []byte
+)
+
+func file_loglynx_v1_loglynx_proto_rawDescGZIP() []byte {
+	file_loglynx_v1_loglynx_proto_rawDescOnce.Do(func() {
+		file_loglynx_v1_loglynx_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_loglynx_v1_loglynx_proto_rawDesc), len(file_loglynx_v1_loglynx_proto_rawDesc)))
+	})
+	return file_loglynx_v1_loglynx_proto_rawDescData
+}
+
+var file_loglynx_v1_loglynx_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_loglynx_v1_loglynx_proto_goTypes = []any{
+	(*StatsRequest)(nil),           // 0: loglynx.v1.StatsRequest
+	(*StatsSummary)(nil),           // 1: loglynx.v1.StatsSummary
+	(*SearchRequestsRequest)(nil),  // 2: loglynx.v1.SearchRequestsRequest
+	(*SearchRequestsResponse)(nil), // 3: loglynx.v1.SearchRequestsResponse
+	(*StreamMetricsRequest)(nil),   // 4: loglynx.v1.StreamMetricsRequest
+	(*RealtimeMetrics)(nil),        // 5: loglynx.v1.RealtimeMetrics
+	(*HTTPRequest)(nil),            // 6: loglynx.v1.HTTPRequest
+	(*ImportResult)(nil),           // 7: loglynx.v1.ImportResult
+	nil,                            // 8: loglynx.v1.HTTPRequest.LabelsEntry
+	(*timestamppb.Timestamp)(nil),  // 9: google.protobuf.Timestamp
+}
+var file_loglynx_v1_loglynx_proto_depIdxs = []int32{
+	6, // 0: loglynx.v1.SearchRequestsResponse.requests:type_name -> loglynx.v1.HTTPRequest
+	9, // 1: loglynx.v1.RealtimeMetrics.timestamp:type_name -> google.protobuf.Timestamp
+	9, // 2: loglynx.v1.HTTPRequest.timestamp:type_name -> google.protobuf.Timestamp
+	8, // 3: loglynx.v1.HTTPRequest.labels:type_name -> loglynx.v1.HTTPRequest.LabelsEntry
+	9, // 4: loglynx.v1.HTTPRequest.created_at:type_name -> google.protobuf.Timestamp
+	0, // 5: loglynx.v1.LogLynx.GetSummary:input_type -> loglynx.v1.StatsRequest
+	2, // 6: loglynx.v1.LogLynx.SearchRequests:input_type -> loglynx.v1.SearchRequestsRequest
+	4, // 7: loglynx.v1.LogLynx.StreamMetrics:input_type -> loglynx.v1.StreamMetricsRequest
+	6, // 8: loglynx.v1.LogLynx.ForwardRequests:input_type -> loglynx.v1.HTTPRequest
+	1, // 9: loglynx.v1.LogLynx.GetSummary:output_type -> loglynx.v1.StatsSummary
+	3, // 10: loglynx.v1.LogLynx.SearchRequests:output_type -> loglynx.v1.SearchRequestsResponse
+	5, // 11: loglynx.v1.LogLynx.StreamMetrics:output_type -> loglynx.v1.RealtimeMetrics
+	7, // 12: loglynx.v1.LogLynx.ForwardRequests:output_type -> loglynx.v1.ImportResult
+	9, // [9:13] is the sub-list for method output_type
+	5, // [5:9] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_loglynx_v1_loglynx_proto_init() }
+func file_loglynx_v1_loglynx_proto_init() {
+	if File_loglynx_v1_loglynx_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_loglynx_v1_loglynx_proto_rawDesc), len(file_loglynx_v1_loglynx_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_loglynx_v1_loglynx_proto_goTypes,
+		DependencyIndexes: file_loglynx_v1_loglynx_proto_depIdxs,
+		MessageInfos:      file_loglynx_v1_loglynx_proto_msgTypes,
+	}.Build()
+	File_loglynx_v1_loglynx_proto = out.File
+	file_loglynx_v1_loglynx_proto_goTypes = nil
+	file_loglynx_v1_loglynx_proto_depIdxs = nil
+}
@@ -0,0 +1,252 @@
+// gRPC definitions of the LogLynx API
+//
+// Served on GRPC_PORT (internal/api/rpc) next to the REST API (openapi.yaml). Regenerate the Go code in
+// internal/api/rpc/loglynxv1 with protoc-gen-go and protoc-gen-go-grpc (paths=source_relative) after a change.
+//
+// Messages mirror the REST responses field by field so both surfaces can share the repositories:
+// StatsSummary is GET /api/v1/stats/summary, SearchRequests is GET /api/v1/requests/search,
+// StreamMetrics is the SSE stream of GET /api/v1/realtime/stream and ForwardRequests is POST /api/v1/sync/import.
+// StreamMetrics needs STREAM_TOKEN and ForwardRequests SYNC_TOKEN as "authorization: Bearer <token>" metadata.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: loglynx/v1/loglynx.proto
+
+package loglynxv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LogLynx_GetSummary_FullMethodName      = "/loglynx.v1.LogLynx/GetSummary"
+	LogLynx_SearchRequests_FullMethodName  = "/loglynx.v1.LogLynx/SearchRequests"
+	LogLynx_StreamMetrics_FullMethodName   = "/loglynx.v1.LogLynx/StreamMetrics"
+	LogLynx_ForwardRequests_FullMethodName = "/loglynx.v1.LogLynx/ForwardRequests"
+)
+
+// LogLynxClient is the client API for LogLynx service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LogLynxClient interface {
+	// Dashboard summary of the lookback window
+	GetSummary(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsSummary, error)
+	// Requests matching a search query, newest first
+	SearchRequests(ctx context.Context, in *SearchRequestsRequest, opts ...grpc.CallOption) (*SearchRequestsResponse, error)
+	// Real-time metrics, one message per collection interval
+	StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RealtimeMetrics], error)
+	// Parsed requests forwarded by an agent (same deduplication as /api/v1/sync/import)
+	// "source-prefix" metadata is prepended to the source names, like the source_prefix parameter.
+	ForwardRequests(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[HTTPRequest, ImportResult], error)
+}
+
+type logLynxClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogLynxClient(cc grpc.ClientConnInterface) LogLynxClient {
+	return &logLynxClient{cc}
+}
+
+func (c *logLynxClient) GetSummary(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsSummary, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsSummary)
+	err := c.cc.Invoke(ctx, LogLynx_GetSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logLynxClient) SearchRequests(ctx context.Context, in *SearchRequestsRequest, opts ...grpc.CallOption) (*SearchRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchRequestsResponse)
+	err := c.cc.Invoke(ctx, LogLynx_SearchRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logLynxClient) StreamMetrics(ctx context.Context, in *StreamMetricsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RealtimeMetrics], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogLynx_ServiceDesc.Streams[0], LogLynx_StreamMetrics_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamMetricsRequest, RealtimeMetrics]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogLynx_StreamMetricsClient = grpc.ServerStreamingClient[RealtimeMetrics]
+
+func (c *logLynxClient) ForwardRequests(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[HTTPRequest, ImportResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogLynx_ServiceDesc.Streams[1], LogLynx_ForwardRequests_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[HTTPRequest, ImportResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogLynx_ForwardRequestsClient = grpc.ClientStreamingClient[HTTPRequest, ImportResult]
+
+// LogLynxServer is the server API for LogLynx service.
+// All implementations must embed UnimplementedLogLynxServer
+// for forward compatibility.
+type LogLynxServer interface {
+	// Dashboard summary of the lookback window
+	GetSummary(context.Context, *StatsRequest) (*StatsSummary, error)
+	// Requests matching a search query, newest first
+	SearchRequests(context.Context, *SearchRequestsRequest) (*SearchRequestsResponse, error)
+	// Real-time metrics, one message per collection interval
+	StreamMetrics(*StreamMetricsRequest, grpc.ServerStreamingServer[RealtimeMetrics]) error
+	// Parsed requests forwarded by an agent (same deduplication as /api/v1/sync/import)
+	// "source-prefix" metadata is prepended to the source names, like the source_prefix parameter.
+	ForwardRequests(grpc.ClientStreamingServer[HTTPRequest, ImportResult]) error
+	mustEmbedUnimplementedLogLynxServer()
+}
+
+// UnimplementedLogLynxServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLogLynxServer struct{}
+
+func (UnimplementedLogLynxServer) GetSummary(context.Context, *StatsRequest) (*StatsSummary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSummary not implemented")
+}
+func (UnimplementedLogLynxServer) SearchRequests(context.Context, *SearchRequestsRequest) (*SearchRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchRequests not implemented")
+}
+func (UnimplementedLogLynxServer) StreamMetrics(*StreamMetricsRequest, grpc.ServerStreamingServer[RealtimeMetrics]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMetrics not implemented")
+}
+func (UnimplementedLogLynxServer) ForwardRequests(grpc.ClientStreamingServer[HTTPRequest, ImportResult]) error {
+	return status.Errorf(codes.Unimplemented, "method ForwardRequests not implemented")
+}
+func (UnimplementedLogLynxServer) mustEmbedUnimplementedLogLynxServer() {}
+func (UnimplementedLogLynxServer) testEmbeddedByValue()                 {}
+
+// UnsafeLogLynxServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogLynxServer will
+// result in compilation errors.
+type UnsafeLogLynxServer interface {
+	mustEmbedUnimplementedLogLynxServer()
+}
+
+func RegisterLogLynxServer(s grpc.ServiceRegistrar, srv LogLynxServer) {
+	// If the following call pancis, it indicates UnimplementedLogLynxServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LogLynx_ServiceDesc, srv)
+}
+
+func _LogLynx_GetSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogLynxServer).GetSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogLynx_GetSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogLynxServer).GetSummary(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogLynx_SearchRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogLynxServer).SearchRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LogLynx_SearchRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogLynxServer).SearchRequests(ctx, req.(*SearchRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogLynx_StreamMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMetricsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LogLynxServer).StreamMetrics(m, &grpc.GenericServerStream[StreamMetricsRequest, RealtimeMetrics]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogLynx_StreamMetricsServer = grpc.ServerStreamingServer[RealtimeMetrics]
+
+func _LogLynx_ForwardRequests_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LogLynxServer).ForwardRequests(&grpc.GenericServerStream[HTTPRequest, ImportResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogLynx_ForwardRequestsServer = grpc.ClientStreamingServer[HTTPRequest, ImportResult]
+
+// LogLynx_ServiceDesc is the grpc.ServiceDesc for LogLynx service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogLynx_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "loglynx.v1.LogLynx",
+	HandlerType: (*LogLynxServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSummary",
+			Handler:    _LogLynx_GetSummary_Handler,
+		},
+		{
+			MethodName: "SearchRequests",
+			Handler:    _LogLynx_SearchRequests_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMetrics",
+			Handler:       _LogLynx_StreamMetrics_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ForwardRequests",
+			Handler:       _LogLynx_ForwardRequests_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "loglynx/v1/loglynx.proto",
+}
@@ -0,0 +1,471 @@
+// Package rpc serves the gRPC API of proto/loglynx/v1/loglynx.proto next to the REST API
+// Calls share the repositories and the real-time collector with the REST handlers, see the proto for the
+// REST endpoint matching each call.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"loglynx/internal/api/rpc/loglynxv1"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
+	"loglynx/internal/ingestion"
+	"loglynx/internal/realtime"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// streamInterval is the interval between StreamMetrics messages, the same as the SSE stream
+	streamInterval = 2 * time.Second
+
+	// forwardBatchSize is the number of forwarded requests committed per transaction
+	forwardBatchSize = 1000
+
+	// defaultSearchLimit and maxSearchLimit bound SearchRequests like GET /api/v1/requests/search
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// Config holds gRPC server configuration
+type Config struct {
+	Host        string
+	Port        int
+	SyncToken   string // Bearer token required by ForwardRequests (empty = disabled)
+	StreamToken string // Bearer token required by StreamMetrics (empty = open)
+}
+
+// Server serves the LogLynx gRPC service
+type Server struct {
+	loglynxv1.UnimplementedLogLynxServer
+
+	statsRepo repositories.StatsRepository
+	readRepo  repositories.HTTPRequestRepository // Searches read from the read-only pool
+	writeRepo repositories.HTTPRequestRepository
+	collector *realtime.MetricsCollector
+	recorder  ingestion.EventRecorder // Real-time metrics of forwarded requests, optional
+	cfg       Config
+	server    *grpc.Server
+	logger    *pterm.Logger
+}
+
+// NewServer creates a gRPC server, collector also receives the forwarded requests
+func NewServer(cfg *Config, statsRepo repositories.StatsRepository, readRepo repositories.HTTPRequestRepository, writeRepo repositories.HTTPRequestRepository, collector *realtime.MetricsCollector, logger *pterm.Logger) *Server {
+	s := &Server{
+		statsRepo: statsRepo,
+		readRepo:  readRepo,
+		writeRepo: writeRepo,
+		collector: collector,
+		cfg:       *cfg,
+		server:    grpc.NewServer(),
+		logger:    logger,
+	}
+	if collector != nil {
+		s.recorder = collector
+	}
+	loglynxv1.RegisterLogLynxServer(s.server, s)
+	return s
+}
+
+// Run listens on the configured address and serves until Shutdown
+func (s *Server) Run() error {
+	address := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		s.logger.WithCaller().Error("gRPC server failed", s.logger.Args("error", err))
+		return err
+	}
+	s.logger.Info("Starting gRPC server", s.logger.Args("address", address))
+	return s.Serve(listener)
+}
+
+// Serve serves on listener until Shutdown
+func (s *Server) Serve(listener net.Listener) error {
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting calls and waits for the running ones, until ctx is done
+// Calls still running then (e.g. metric streams) are cancelled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down gRPC server...")
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// GetSummary returns the dashboard summary, like GET /api/v1/stats/summary
+func (s *Server) GetSummary(ctx context.Context, req *loglynxv1.StatsRequest) (*loglynxv1.StatsSummary, error) {
+	hours := int(req.GetHours())
+	if hours < 0 || hours > repositories.MaxLookbackHours {
+		return nil, status.Errorf(codes.InvalidArgument, "hours must be between 1 and %d", repositories.MaxLookbackHours)
+	}
+
+	statsRepo := s.statsRepo.WithContext(ctx)
+	if hours > 0 && hours != s.statsRepo.LookbackHours() {
+		statsRepo = statsRepo.WithLookback(hours)
+	}
+
+	summary, err := statsRepo.GetSummary(serviceFilters(req), excludeIPFilter(req.GetExcludeIp()))
+	if err != nil {
+		s.logger.WithCaller().Error("Failed to get summary", s.logger.Args("error", err))
+		return nil, status.Error(codes.Internal, "failed to get summary")
+	}
+
+	return &loglynxv1.StatsSummary{
+		TotalRequests:   summary.TotalRequests,
+		ValidRequests:   summary.ValidRequests,
+		FailedRequests:  summary.FailedRequests,
+		UniqueVisitors:  summary.UniqueVisitors,
+		UniqueFiles:     summary.UniqueFiles,
+		Unique_404:      summary.Unique404,
+		TotalBandwidth:  summary.TotalBandwidth,
+		AvgResponseTime: summary.AvgResponseTime,
+		SuccessRate:     summary.SuccessRate,
+		NotFoundRate:    summary.NotFoundRate,
+		ServerErrorRate: summary.ServerErrorRate,
+		RequestsPerHour: summary.RequestsPerHour,
+		TopCountry:      summary.TopCountry,
+		TopPath:         summary.TopPath,
+	}, nil
+}
+
+// SearchRequests returns the most recent requests matching a query, like GET /api/v1/requests/search
+func (s *Server) SearchRequests(ctx context.Context, req *loglynxv1.SearchRequestsRequest) (*loglynxv1.SearchRequestsResponse, error) {
+	search, err := repositories.ParseRequestSearch(req.GetQ(), req.GetField())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxSearchLimit)
+	}
+	if req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	if req.GetHours() < 0 || req.GetHours() > repositories.MaxLookbackHours {
+		return nil, status.Errorf(codes.InvalidArgument, "hours must be between 1 and %d", repositories.MaxLookbackHours)
+	}
+
+	// Searches cover all stored requests unless a time range is given
+	filter := repositories.RequestFilter{Limit: limit, Offset: int(req.GetOffset()), Search: search, Context: ctx}
+	if req.GetHours() > 0 {
+		filter.Since = time.Now().Add(-time.Duration(req.GetHours()) * time.Hour)
+	}
+
+	response := &loglynxv1.SearchRequestsResponse{}
+	err = s.readRepo.Stream(filter, func(request *models.HTTPRequest) error {
+		response.Requests = append(response.Requests, toProto(request))
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		s.logger.WithCaller().Error("Failed to search requests", s.logger.Args("error", err))
+		return nil, status.Error(codes.Internal, "failed to search requests")
+	}
+	return response, nil
+}
+
+// StreamMetrics sends the real-time metrics every 2 seconds, like GET /api/v1/realtime/stream
+func (s *Server) StreamMetrics(req *loglynxv1.StreamMetricsRequest, stream grpc.ServerStreamingServer[loglynxv1.RealtimeMetrics]) error {
+	if s.cfg.StreamToken != "" && !authorized(stream.Context(), s.cfg.StreamToken) {
+		return status.Error(codes.Unauthenticated, "invalid or missing stream token")
+	}
+	if s.collector == nil {
+		return status.Error(codes.Unavailable, "real-time metrics are disabled")
+	}
+
+	var services []filter.Service
+	for _, name := range req.GetServices() {
+		services = append(services, filter.Service{Name: name, Type: "auto"})
+	}
+
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+			metrics := s.collector.GetMetricsWithFilters("", services, nil)
+			if err := stream.Send(&loglynxv1.RealtimeMetrics{
+				RequestRate:       metrics.RequestRate,
+				InstantRate:       metrics.InstantRate,
+				BurstMax:          metrics.BurstMax,
+				ErrorRate:         metrics.ErrorRate,
+				AvgResponseTime:   metrics.AvgResponseTime,
+				ActiveConnections: int32(metrics.ActiveConnections),
+				Status_2Xx:        metrics.Status2xx,
+				Status_4Xx:        metrics.Status4xx,
+				Status_5Xx:        metrics.Status5xx,
+				Timestamp:         timestamppb.New(metrics.Timestamp),
+			}); err != nil {
+				s.logger.Debug("gRPC metrics stream interrupted", s.logger.Args("error", err))
+				return err
+			}
+		}
+	}
+}
+
+// ForwardRequests stores the requests forwarded by an agent, skipping the ones already stored
+// Like POST /api/v1/sync/import, requests are committed in batches and the batches committed before an error are kept.
+func (s *Server) ForwardRequests(stream grpc.ClientStreamingServer[loglynxv1.HTTPRequest, loglynxv1.ImportResult]) error {
+	if s.cfg.SyncToken == "" {
+		return status.Error(codes.PermissionDenied, "forwarding is disabled, set SYNC_TOKEN")
+	}
+	if !authorized(stream.Context(), s.cfg.SyncToken) {
+		return status.Error(codes.Unauthenticated, "invalid or missing sync token")
+	}
+	var sourcePrefix string
+	if values := metadata.ValueFromIncomingContext(stream.Context(), "source-prefix"); len(values) > 0 {
+		sourcePrefix = values[0]
+	}
+
+	result := &loglynxv1.ImportResult{}
+	batch := make([]*models.HTTPRequest, 0, forwardBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		stored, err := s.writeRepo.CreateBatch(batch)
+		if err != nil {
+			s.logger.Warn("gRPC forward stopped", s.logger.Args("requests", result.Requests, "inserted", result.Inserted, "error", err))
+			return status.Error(codes.Internal, "failed to store requests")
+		}
+		result.Inserted += int64(stored.Inserted)
+		result.Duplicates += int64(stored.Duplicates)
+		if s.recorder != nil {
+			s.recorder.Record(batch)
+		}
+		batch = make([]*models.HTTPRequest, 0, forwardBatchSize)
+		return nil
+	}
+
+	for {
+		message, err := stream.Recv()
+		if err == io.EOF {
+			result.Complete = true
+			break
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return flushErr
+			}
+			return err
+		}
+		if message.GetRequestHash() == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+			return status.Errorf(codes.InvalidArgument, "request %d has no request_hash", result.Requests+1)
+		}
+
+		request := fromProto(message)
+		request.SourceName = sourcePrefix + request.SourceName
+		result.Requests++
+		batch = append(batch, request)
+
+		if len(batch) >= forwardBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Requests forwarded over gRPC",
+		s.logger.Args("requests", result.Requests, "inserted", result.Inserted, "duplicates", result.Duplicates))
+	return stream.SendAndClose(result)
+}
+
+// authorized reports whether the call carries token as "authorization: Bearer <token>" metadata
+func authorized(ctx context.Context, token string) bool {
+	for _, value := range metadata.ValueFromIncomingContext(ctx, "authorization") {
+		if bearer, found := strings.CutPrefix(value, "Bearer "); found && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceFilters returns the service filters of a stats call
+func serviceFilters(req *loglynxv1.StatsRequest) []filter.Service {
+	serviceType := req.GetServiceType()
+	if serviceType == "" {
+		serviceType = "auto"
+	}
+	filters := make([]filter.Service, 0, len(req.GetServices()))
+	for _, name := range req.GetServices() {
+		filters = append(filters, filter.Service{Name: name, Type: serviceType})
+	}
+	return filters
+}
+
+// excludeIPFilter returns the own IP exclusion of a call, nil without an IP
+func excludeIPFilter(clientIP string) *filter.ExcludeIP {
+	if clientIP == "" {
+		return nil
+	}
+	return &filter.ExcludeIP{ClientIP: clientIP}
+}
+
+// toProto converts a stored request
+func toProto(request *models.HTTPRequest) *loglynxv1.HTTPRequest {
+	message := &loglynxv1.HTTPRequest{
+		Id:                     uint64(request.ID),
+		SourceName:             request.SourceName,
+		Timestamp:              timestamppb.New(request.Timestamp),
+		RequestHash:            request.RequestHash,
+		ClientIp:               request.ClientIP,
+		Method:                 request.Method,
+		Protocol:               request.Protocol,
+		Host:                   request.Host,
+		Path:                   request.Path,
+		QueryString:            request.QueryString,
+		StatusCode:             int32(request.StatusCode),
+		ResponseSize:           request.ResponseSize,
+		ResponseTimeMs:         request.ResponseTimeMs,
+		UserAgent:              request.UserAgent,
+		Referer:                request.Referer,
+		BackendName:            request.BackendName,
+		RouterName:             request.RouterName,
+		GeoCountry:             request.GeoCountry,
+		ClientPort:             int32(request.ClientPort),
+		ClientUser:             request.ClientUser,
+		ApiKeyHash:             request.APIKeyHash,
+		RequestLength:          request.RequestLength,
+		RequestScheme:          request.RequestScheme,
+		ResponseContentType:    request.ResponseContentType,
+		Duration:               request.Duration,
+		StartUtc:               request.StartUTC,
+		UpstreamResponseTimeMs: request.UpstreamResponseTimeMs,
+		RetryAttempts:          int32(request.RetryAttempts),
+		RequestsTotal:          int32(request.RequestsTotal),
+		RequestHeaderBytes:     request.RequestHeaderBytes,
+		ResponseHeaderBytes:    request.ResponseHeaderBytes,
+		Browser:                request.Browser,
+		BrowserVersion:         request.BrowserVersion,
+		Os:                     request.OS,
+		OsVersion:              request.OSVersion,
+		DeviceType:             request.DeviceType,
+		BackendUrl:             request.BackendURL,
+		UpstreamStatus:         int32(request.UpstreamStatus),
+		UpstreamContentType:    request.UpstreamContentType,
+		UpstreamAddr:           request.UpstreamAddr,
+		ClientHostname:         request.ClientHostname,
+		TlsVersion:             request.TLSVersion,
+		TlsCipher:              request.TLSCipher,
+		TlsServerName:          request.TLSServerName,
+		RequestId:              request.RequestID,
+		TraceId:                request.TraceID,
+		GeoCity:                request.GeoCity,
+		GeoLat:                 request.GeoLat,
+		GeoLon:                 request.GeoLon,
+		GeoEu:                  request.GeoEU,
+		Asn:                    int32(request.ASN),
+		AsnOrg:                 request.ASNOrg,
+		ProxyMetadata:          request.ProxyMetadata,
+		Labels:                 request.Labels,
+		PartitionKey:           request.PartitionKey,
+	}
+	if !request.CreatedAt.IsZero() {
+		message.CreatedAt = timestamppb.New(request.CreatedAt)
+	}
+	return message
+}
+
+// fromProto converts a forwarded request, IDs belong to the forwarding instance and are dropped
+func fromProto(request *loglynxv1.HTTPRequest) *models.HTTPRequest {
+	converted := &models.HTTPRequest{
+		SourceName:             request.GetSourceName(),
+		Timestamp:              request.GetTimestamp().AsTime(),
+		RequestHash:            request.GetRequestHash(),
+		ClientIP:               request.GetClientIp(),
+		Method:                 request.GetMethod(),
+		Protocol:               request.GetProtocol(),
+		Host:                   request.GetHost(),
+		Path:                   request.GetPath(),
+		QueryString:            request.GetQueryString(),
+		StatusCode:             int(request.GetStatusCode()),
+		ResponseSize:           request.GetResponseSize(),
+		ResponseTimeMs:         request.GetResponseTimeMs(),
+		UserAgent:              request.GetUserAgent(),
+		Referer:                request.GetReferer(),
+		BackendName:            request.GetBackendName(),
+		RouterName:             request.GetRouterName(),
+		GeoCountry:             request.GetGeoCountry(),
+		ClientPort:             int(request.GetClientPort()),
+		ClientUser:             request.GetClientUser(),
+		APIKeyHash:             request.GetApiKeyHash(),
+		RequestLength:          request.GetRequestLength(),
+		RequestScheme:          request.GetRequestScheme(),
+		ResponseContentType:    request.GetResponseContentType(),
+		Duration:               request.GetDuration(),
+		StartUTC:               request.GetStartUtc(),
+		UpstreamResponseTimeMs: request.GetUpstreamResponseTimeMs(),
+		RetryAttempts:          int(request.GetRetryAttempts()),
+		RequestsTotal:          int(request.GetRequestsTotal()),
+		RequestHeaderBytes:     request.GetRequestHeaderBytes(),
+		ResponseHeaderBytes:    request.GetResponseHeaderBytes(),
+		Browser:                request.GetBrowser(),
+		BrowserVersion:         request.GetBrowserVersion(),
+		OS:                     request.GetOs(),
+		OSVersion:              request.GetOsVersion(),
+		DeviceType:             request.GetDeviceType(),
+		BackendURL:             request.GetBackendUrl(),
+		UpstreamStatus:         int(request.GetUpstreamStatus()),
+		UpstreamContentType:    request.GetUpstreamContentType(),
+		UpstreamAddr:           request.GetUpstreamAddr(),
+		ClientHostname:         request.GetClientHostname(),
+		TLSVersion:             request.GetTlsVersion(),
+		TLSCipher:              request.GetTlsCipher(),
+		TLSServerName:          request.GetTlsServerName(),
+		RequestID:              request.GetRequestId(),
+		TraceID:                request.GetTraceId(),
+		GeoCity:                request.GetGeoCity(),
+		GeoLat:                 request.GetGeoLat(),
+		GeoLon:                 request.GetGeoLon(),
+		GeoEU:                  request.GetGeoEu(),
+		ASN:                    int(request.GetAsn()),
+		ASNOrg:                 request.GetAsnOrg(),
+		ProxyMetadata:          request.GetProxyMetadata(),
+		Labels:                 request.GetLabels(),
+		PartitionKey:           request.GetPartitionKey(),
+	}
+	if request.GetCreatedAt() != nil {
+		converted.CreatedAt = request.GetCreatedAt().AsTime()
+	}
+	return converted
+}
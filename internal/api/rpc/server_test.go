@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"loglynx/internal/api/rpc/loglynxv1"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestClient serves s over an in-memory connection and returns a client of it
+func newTestClient(t *testing.T, s *Server) loglynxv1.LogLynxClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return loglynxv1.NewLogLynxClient(conn)
+}
+
+func TestServer_GetSummaryAndSearch(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	db := fake.NewDB(t)
+
+	// 3 requests in the last hour, one of them from 10.0.0.2
+	now := time.Now()
+	for i, path := range []string{"/", "/login", "/"} {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: fmt.Sprint("10.0.0.", i%2+1), Method: "GET", Host: "example", Path: path, StatusCode: 200,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	readRepo := repositories.NewHTTPRequestRepository(db, log, "")
+	client := newTestClient(t, NewServer(&Config{}, statsRepo, readRepo, readRepo, nil, log))
+	ctx := context.Background()
+
+	summary, err := client.GetSummary(ctx, &loglynxv1.StatsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalRequests != 3 || summary.UniqueVisitors != 2 || summary.TopPath != "/" {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+	if summary, err := client.GetSummary(ctx, &loglynxv1.StatsRequest{Hours: 1, Services: []string{"other"}}); err != nil || summary.TotalRequests != 0 {
+		t.Errorf("Expected no request of another service, got %v (%v)", summary, err)
+	}
+	if _, err := client.GetSummary(ctx, &loglynxv1.StatsRequest{Hours: repositories.MaxLookbackHours + 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an invalid argument for a too long range, got %v", err)
+	}
+
+	found, err := client.SearchRequests(ctx, &loglynxv1.SearchRequestsRequest{Q: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Requests) != 1 || found.Requests[0].Path != "/login" || found.Requests[0].ClientIp != "10.0.0.2" {
+		t.Errorf("Expected the /login request, got %v", found.Requests)
+	}
+	if _, err := client.SearchRequests(ctx, &loglynxv1.SearchRequestsRequest{Q: "login", Field: "host"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an invalid argument for an unknown field, got %v", err)
+	}
+}
+
+func TestServer_ForwardRequests(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	repo := fake.NewHTTPRequests(nil)
+	client := newTestClient(t, NewServer(&Config{SyncToken: "secret"}, nil, repo, repo, nil, log))
+
+	forward := func(ctx context.Context, hashes ...string) (*loglynxv1.ImportResult, error) {
+		stream, err := client.ForwardRequests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range hashes {
+			request := &loglynxv1.HTTPRequest{SourceName: "traefik", Timestamp: timestamppb.Now(), RequestHash: hash, Method: "GET", Path: "/"}
+			if err := stream.Send(request); err != nil {
+				break // The error is returned by CloseAndRecv
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	if _, err := forward(context.Background(), "a"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected a call without the sync token to be refused, got %v", err)
+	}
+
+	// A request forwarded twice is stored once, with the source prefix
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret", "source-prefix", "edge-1/")
+	result, err := forward(ctx, "a", "b", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Requests != 3 || result.Inserted != 2 || result.Duplicates != 1 || !result.Complete {
+		t.Errorf("Expected 2 of 3 requests stored, got %v", result)
+	}
+	if stored, _ := repo.FindBySourceName("edge-1/traefik", 10); len(stored) != 2 {
+		t.Errorf("Expected 2 requests of source edge-1/traefik, got %d", len(stored))
+	}
+
+	if _, err := forward(ctx, ""); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected a request without hash to be refused, got %v", err)
+	}
+}
+
+func TestServer_TokensRequired(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	client := newTestClient(t, NewServer(&Config{StreamToken: "secret"}, nil, nil, nil, nil, log))
+
+	stream, err := client.StreamMetrics(context.Background(), &loglynxv1.StreamMetricsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected a stream without token to be refused, got %v", err)
+	}
+
+	// Forwarding is disabled without a sync token
+	upload, err := client.ForwardRequests(context.Background())
+	if err == nil {
+		_, err = upload.CloseAndRecv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected forwarding to be disabled, got %v", err)
+	}
+}
+
+// TestConversion_AllColumns fails when a column of models.HTTPRequest is added without its proto field
+func TestConversion_AllColumns(t *testing.T) {
+	request := &models.HTTPRequest{}
+	value := reflect.ValueOf(request).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		switch name := value.Type().Field(i).Name; {
+		case name == "ID" || name == "LogSource":
+			// IDs belong to the forwarding instance, the source is a relation
+		case field.Type() == reflect.TypeOf(time.Time{}):
+			field.Set(reflect.ValueOf(time.Date(2026, 10, 15, 8, 0, i, 0, time.UTC)))
+		case field.Type() == reflect.TypeOf(map[string]string{}):
+			field.Set(reflect.ValueOf(map[string]string{"team": "shop"}))
+		case field.Kind() == reflect.String:
+			field.SetString(fmt.Sprint(name, "-value"))
+		case field.Kind() == reflect.Bool:
+			field.SetBool(true)
+		case field.CanInt():
+			field.SetInt(int64(i + 1))
+		case field.CanFloat():
+			field.SetFloat(float64(i) + 0.5)
+		default:
+			t.Fatalf("Unhandled column %s of type %s", name, field.Type())
+		}
+	}
+
+	if converted := fromProto(toProto(request)); !reflect.DeepEqual(converted, request) {
+		t.Errorf("Expected all columns kept over gRPC\nsent     %+v\nreceived %+v", request, converted)
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Defaults of the CORS settings, used when they are not configured
+const (
+	DefaultCORSMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	DefaultCORSHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Accept, Origin, Cache-Control, X-Requested-With, X-Request-ID"
+)
+
+// DefaultReferrerPolicy sends the origin only to other sites, so dashboard URLs with IPs and filters stay private
+const DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// DefaultContentSecurityPolicy allows the dashboard's own scripts and the CDNs its libraries are loaded from
+// Inline scripts and styles are allowed as pages use them, images may come from any HTTPS URL for map tiles,
+// flags and custom logos. API calls are restricted to LogLynx itself.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://cdnjs.cloudflare.com https://unpkg.com https://cdn.datatables.net https://code.jquery.com; " +
+	"style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net https://cdnjs.cloudflare.com https://unpkg.com https://cdn.datatables.net; " +
+	"font-src 'self' data: https://cdn.jsdelivr.net https://cdnjs.cloudflare.com; " +
+	"img-src 'self' data: https:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'self'"
+
+// CORSPolicy decides which browser origins may call the API
+type CORSPolicy struct {
+	origins          map[string]bool
+	anyOrigin        bool
+	methods          string
+	headers          string
+	allowCredentials bool
+}
+
+// ParseCORSPolicy builds a policy from comma-separated origins (e.g. "https://grafana.example.com" or "*"), methods
+// and headers (empty for the defaults). Without origins no CORS header is sent, so browsers only let pages served by
+// LogLynx call the API. Credentials (cookies, Authorization) are only allowed for listed origins, browsers reject them
+// with "*".
+func ParseCORSPolicy(origins, methods, headers string, allowCredentials bool) (*CORSPolicy, error) {
+	policy := &CORSPolicy{
+		origins:          make(map[string]bool),
+		methods:          DefaultCORSMethods,
+		headers:          DefaultCORSHeaders,
+		allowCredentials: allowCredentials,
+	}
+	if list := joinList(methods); list != "" {
+		policy.methods = strings.ToUpper(list)
+	}
+	if list := joinList(headers); list != "" {
+		policy.headers = list
+	}
+
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.TrimSpace(origin)
+		switch {
+		case origin == "":
+			continue
+		case origin == "*":
+			policy.anyOrigin = true
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.TrimSuffix(parsed.Path, "/") != "" {
+			return nil, fmt.Errorf("invalid CORS origin %q (expected scheme://host[:port] or *)", origin)
+		}
+		policy.origins[parsed.Scheme+"://"+parsed.Host] = true
+	}
+
+	if policy.anyOrigin && allowCredentials {
+		return nil, fmt.Errorf("CORS credentials cannot be allowed for any origin (*), list the origins instead")
+	}
+	return policy, nil
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request origin, "" when it is not allowed
+func (p *CORSPolicy) allowedOrigin(origin string) string {
+	switch {
+	case p == nil || origin == "":
+		return ""
+	case p.origins[origin]:
+		return origin
+	case p.anyOrigin:
+		return "*"
+	default:
+		return ""
+	}
+}
+
+// joinList normalizes a comma-separated list to "a, b", dropping empty entries
+func joinList(list string) string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return strings.Join(entries, ", ")
+}
+
+// trustProxies sets the proxies whose X-Forwarded-For and X-Real-IP headers give the client IP (c.ClientIP)
+// proxies is a comma-separated list of addresses or networks; without any, the client is the connection's peer,
+// so clients can't pick their IP for the exclude-own-IP filter or the per-IP stream limit.
+func trustProxies(router *gin.Engine, proxies string) error {
+	var trusted []string
+	for _, entry := range strings.Split(proxies, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			trusted = append(trusted, entry)
+		}
+	}
+	if err := router.SetTrustedProxies(trusted); err != nil {
+		router.SetTrustedProxies(nil)
+		return fmt.Errorf("invalid trusted proxies %q: %w", proxies, err)
+	}
+	return nil
+}
+
+// corsMiddleware adds the CORS headers of the policy to requests from allowed origins, a nil policy adds none
+func corsMiddleware(policy *CORSPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if policy != nil && len(policy.origins) > 0 {
+			// The response depends on the origin, caches must not share it between origins
+			header.Add("Vary", "Origin")
+		}
+		if allowed := policy.allowedOrigin(c.GetHeader("Origin")); allowed != "" {
+			header.Set("Access-Control-Allow-Origin", allowed)
+			if policy.allowCredentials && allowed != "*" {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			header.Set("Access-Control-Allow-Headers", policy.headers)
+			header.Set("Access-Control-Expose-Headers", "X-Request-ID")
+			header.Set("Access-Control-Allow-Methods", policy.methods)
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// securityHeadersMiddleware adds headers hardening every response against MIME sniffing, framing and referrer leaks
+func securityHeadersMiddleware(referrerPolicy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "SAMEORIGIN")
+		header.Set("Referrer-Policy", referrerPolicy)
+		c.Next()
+	}
+}
+
+// contentSecurityPolicyMiddleware restricts what dashboard pages may load and run
+func contentSecurityPolicyMiddleware(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Set("Content-Security-Policy", policy)
+		c.Next()
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseCORSPolicy(t *testing.T) {
+	for _, invalid := range []struct {
+		origins     string
+		credentials bool
+	}{
+		{"grafana.example.com", false},
+		{"ftp://grafana.example.com", false},
+		{"https://grafana.example.com/dashboards", false},
+		{"*", true},
+	} {
+		if _, err := ParseCORSPolicy(invalid.origins, "", "", invalid.credentials); err == nil {
+			t.Errorf("Expected an error for %q with credentials %v", invalid.origins, invalid.credentials)
+		}
+	}
+
+	policy, err := ParseCORSPolicy(" https://grafana.example.com/, http://localhost:3000 ", "get,post", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.methods != "GET, POST" || policy.headers != DefaultCORSHeaders {
+		t.Errorf("Expected the methods normalized and the default headers, got %q and %q", policy.methods, policy.headers)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := func(policy *CORSPolicy, method, origin string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(corsMiddleware(policy), securityHeadersMiddleware(DefaultReferrerPolicy))
+		router.GET("/api/v1/stats/summary", func(c *gin.Context) { c.Status(http.StatusOK) })
+		req := httptest.NewRequest(method, "/api/v1/stats/summary", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	listed, err := ParseCORSPolicy("https://grafana.example.com", "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anyOrigin, err := ParseCORSPolicy("*", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name               string
+		policy             *CORSPolicy
+		origin             string
+		allowOrigin, creds string
+	}{
+		{"same origin only", nil, "https://evil.example.com", "", ""},
+		{"listed origin", listed, "https://grafana.example.com", "https://grafana.example.com", "true"},
+		{"other origin", listed, "https://evil.example.com", "", ""},
+		{"any origin", anyOrigin, "https://evil.example.com", "*", ""},
+	}
+	for _, tc := range cases {
+		header := request(tc.policy, http.MethodGet, tc.origin).Header()
+		if got := header.Get("Access-Control-Allow-Origin"); got != tc.allowOrigin {
+			t.Errorf("%s: expected Access-Control-Allow-Origin %q, got %q", tc.name, tc.allowOrigin, got)
+		}
+		if got := header.Get("Access-Control-Allow-Credentials"); got != tc.creds {
+			t.Errorf("%s: expected Access-Control-Allow-Credentials %q, got %q", tc.name, tc.creds, got)
+		}
+		if header.Get("X-Content-Type-Options") != "nosniff" || header.Get("Referrer-Policy") != DefaultReferrerPolicy {
+			t.Errorf("%s: expected the security headers, got %v", tc.name, header)
+		}
+	}
+
+	if header := request(listed, http.MethodGet, "https://grafana.example.com").Header(); header.Get("Vary") != "Origin" {
+		t.Errorf("Expected responses to listed origins to vary on Origin, got %v", header)
+	}
+	if recorder := request(listed, http.MethodOptions, "https://grafana.example.com"); recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected preflight requests answered with 204, got %d", recorder.Code)
+	}
+}
+
+func TestTrustProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clientIP := func(proxies string) string {
+		router := gin.New()
+		if err := trustProxies(router, proxies); err != nil {
+			t.Fatal(err)
+		}
+		var ip string
+		router.GET("/", func(c *gin.Context) { ip = c.ClientIP() })
+		req := httptest.NewRequest(http.MethodGet, "/", nil) // From 192.0.2.1
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	if ip := clientIP(""); ip != "192.0.2.1" {
+		t.Errorf("Expected X-Forwarded-For to be ignored without trusted proxies, got %s", ip)
+	}
+	if ip := clientIP("10.0.0.0/8"); ip != "192.0.2.1" {
+		t.Errorf("Expected X-Forwarded-For of an untrusted peer to be ignored, got %s", ip)
+	}
+	if ip := clientIP(" 192.0.2.0/24 , 10.0.0.1"); ip != "203.0.113.7" {
+		t.Errorf("Expected X-Forwarded-For of a trusted proxy to give the client, got %s", ip)
+	}
+	if err := trustProxies(gin.New(), "not-an-ip"); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+}
@@ -3,10 +3,17 @@ package api
 import (
 	"context"
 	"fmt"
+	"html/template"
 	"net/http"
 	"time"
 
 	"loglynx/internal/api/handlers"
+	"loglynx/internal/database"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
+	"loglynx/internal/i18n"
+	"loglynx/internal/requestid"
+	"loglynx/internal/statsd"
 	"loglynx/internal/version"
 
 	"github.com/gin-gonic/gin"
@@ -27,12 +34,21 @@ type Config struct {
 	Host                string
 	Port                int
 	Production          bool
-	DashboardEnabled    bool // If false, only API routes are exposed
-	SplashScreenEnabled bool // If false, splash screen is disabled on startup
+	DashboardEnabled    bool   // If false, only API routes are exposed
+	SplashScreenEnabled bool   // If false, splash screen is disabled on startup
+	Locale              string // Locale of the dashboard, empty to detect it from Accept-Language
+	LookbackHours       int    // Default time range of stats, reduced on endpoints whose queries time out
+	TrustedProxies      string // Comma-separated proxies in front of LogLynx trusted for the client IP (empty = none)
+	SourceHosts         bool   // Databases of other hosts are attached, requests have a source host
+
+	CORS                  *CORSPolicy // Origins allowed to call the API from browsers, nil = same origin only
+	SecurityHeaders       bool        // Adds nosniff, framing, referrer and (on dashboard pages) content security headers
+	ContentSecurityPolicy string      // Of dashboard pages, empty = DefaultContentSecurityPolicy
+	ReferrerPolicy        string      // Empty = DefaultReferrerPolicy
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtimeHandler *handlers.RealtimeHandler, systemHandler *handlers.SystemHandler, logger *pterm.Logger) *Server {
+func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtimeHandler *handlers.RealtimeHandler, systemHandler *handlers.SystemHandler, alertHandler *handlers.AlertHandler, healthHandler *handlers.HealthHandler, syncHandler *handlers.SyncHandler, adminHandler *handlers.AdminHandler, settingsHandler *handlers.SettingsHandler, investigationHandler *handlers.InvestigationHandler, queryStats *database.QueryStats, metrics *statsd.Client, logger *pterm.Logger) *Server {
 	// Set Gin mode
 	if cfg.Production {
 		gin.SetMode(gin.ReleaseMode)
@@ -41,95 +57,133 @@ func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtim
 	}
 
 	router := gin.New()
+	if err := trustProxies(router, cfg.TrustedProxies); err != nil {
+		logger.Warn("Ignoring X-Forwarded-For, client IPs are the connection peers", logger.Args("error", err))
+	}
 
-	// Middleware
-	router.Use(gin.Logger())
+	// Middleware (the request ID comes first, so every later log line can carry it)
+	router.Use(requestIDMiddleware())
+	router.Use(gin.LoggerWithFormatter(accessLogFormatter))
 	router.Use(gin.Recovery())
-	router.Use(corsMiddleware())
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now(),
-			"version":   version.Version,
-		})
-	})
+	router.Use(corsMiddleware(cfg.CORS))
+	if cfg.SecurityHeaders {
+		referrerPolicy := cfg.ReferrerPolicy
+		if referrerPolicy == "" {
+			referrerPolicy = DefaultReferrerPolicy
+		}
+		router.Use(securityHeadersMiddleware(referrerPolicy))
+	}
+
+	// Health checks (liveness and readiness probes, /health is kept for existing setups)
+	router.GET("/health", healthHandler.GetReadiness)
+	router.GET("/health/live", healthHandler.GetLiveness)
+	router.GET("/health/ready", healthHandler.GetReadiness)
+
+	// Dashboard labels, pages are rendered in the configured locale or the browser's
+	if cfg.Locale != "" && i18n.Normalize(cfg.Locale) == "" {
+		logger.Warn("Unsupported dashboard locale, detecting it from the browser", logger.Args("locale", cfg.Locale))
+	}
+	i18nHandler := handlers.NewI18nHandler(cfg.Locale, logger)
 
 	// Helper function to render pages with common config
 	splashScreenEnabled := cfg.SplashScreenEnabled
-	renderPage := func(c *gin.Context, pageName, pageTitle, pageIcon string) {
-		c.HTML(http.StatusOK, pageName+".html", gin.H{
+	pageData := func(c *gin.Context, pageName, pageIcon string) gin.H {
+		locale := i18nHandler.Locale(c)
+		pageTitle := i18n.T(locale, "page."+pageName)
+		data := gin.H{
 			"Title":               pageTitle,
 			"PageName":            pageName,
 			"PageTitle":           pageTitle,
 			"PageIcon":            pageIcon,
 			"AppVersion":          version.Version,
 			"SplashScreenEnabled": splashScreenEnabled,
-		})
+			"Locale":              locale,
+		}
+		for key, value := range themeData(settingsHandler.Settings().Theme) {
+			data[key] = value
+		}
+		return data
+	}
+	renderPage := func(c *gin.Context, pageName, pageIcon string) {
+		c.HTML(http.StatusOK, pageName+".html", pageData(c, pageName, pageIcon))
 	}
 
 	// Dashboard UI routes (only if dashboard is enabled)
 	if cfg.DashboardEnabled {
-		// Load HTML templates with pattern for nested directories
+		// Load HTML templates with pattern for nested directories, t translates labels: {{t .Locale "nav.overview"}}
+		router.SetFuncMap(template.FuncMap{"t": i18n.T})
 		router.LoadHTMLGlob("web/templates/**/*.html")
 
 		// Static files
 		router.Static("/static", "./web/static")
 
+		// Pages get the content security policy, API responses aren't rendered by browsers
+		pages := router.Group("")
+		if cfg.SecurityHeaders {
+			csp := cfg.ContentSecurityPolicy
+			if csp == "" {
+				csp = DefaultContentSecurityPolicy
+			}
+			pages.Use(contentSecurityPolicyMiddleware(csp))
+		}
+
 		// Dashboard pages (HTML)
-		router.GET("/", func(c *gin.Context) {
-			renderPage(c, "overview", "Executive Overview", "fas fa-home")
+		pages.GET("/", func(c *gin.Context) {
+			renderPage(c, "overview", "fas fa-home")
 		})
 
-		router.GET("/realtime", func(c *gin.Context) {
-			renderPage(c, "realtime", "Real-time Monitor", "fas fa-broadcast-tower")
+		pages.GET("/realtime", func(c *gin.Context) {
+			renderPage(c, "realtime", "fas fa-broadcast-tower")
 		})
 
-		router.GET("/traffic", func(c *gin.Context) {
-			renderPage(c, "traffic", "Traffic Analysis", "fas fa-globe")
+		pages.GET("/traffic", func(c *gin.Context) {
+			renderPage(c, "traffic", "fas fa-globe")
 		})
 
-		router.GET("/performance", func(c *gin.Context) {
-			renderPage(c, "performance", "Performance Monitoring", "fas fa-tachometer-alt")
+		pages.GET("/performance", func(c *gin.Context) {
+			renderPage(c, "performance", "fas fa-tachometer-alt")
 		})
 
-		router.GET("/security", func(c *gin.Context) {
-			renderPage(c, "security", "Security & Network", "fas fa-shield-alt")
+		pages.GET("/security", func(c *gin.Context) {
+			renderPage(c, "security", "fas fa-shield-alt")
 		})
 
-		router.GET("/users", func(c *gin.Context) {
-			renderPage(c, "users", "User Analytics", "fas fa-users")
+		pages.GET("/users", func(c *gin.Context) {
+			renderPage(c, "users", "fas fa-users")
 		})
 
-		router.GET("/content", func(c *gin.Context) {
-			renderPage(c, "content", "Content Analytics", "fas fa-file-alt")
+		pages.GET("/content", func(c *gin.Context) {
+			renderPage(c, "content", "fas fa-file-alt")
 		})
 
-		router.GET("/backends", func(c *gin.Context) {
-			renderPage(c, "backends", "Backend Health", "fas fa-server")
+		pages.GET("/backends", func(c *gin.Context) {
+			renderPage(c, "backends", "fas fa-server")
 		})
 
-		router.GET("/geographic", func(c *gin.Context) {
-			renderPage(c, "geographic", "Geographic Analytics", "fas fa-map-marked-alt")
+		pages.GET("/geographic", func(c *gin.Context) {
+			renderPage(c, "geographic", "fas fa-map-marked-alt")
 		})
 
-		router.GET("/system", func(c *gin.Context) {
-			renderPage(c, "system", "System Statistics", "fas fa-server")
+		pages.GET("/system", func(c *gin.Context) {
+			renderPage(c, "system", "fas fa-server")
 		})
 
 		// IP Analytics page
-		router.GET("/ip/:ip", func(c *gin.Context) {
+		pages.GET("/ip/:ip", func(c *gin.Context) {
 			ip := c.Param("ip")
-			c.HTML(http.StatusOK, "ip-detail.html", gin.H{
-				"Title":               "IP Analytics - " + ip,
-				"PageName":            "ip-detail",
-				"PageTitle":           "IP Analytics",
-				"PageIcon":            "fas fa-network-wired",
-				"AppVersion":          version.Version,
-				"IPAddress":           ip,
-				"SplashScreenEnabled": splashScreenEnabled,
-			})
+			data := pageData(c, "ip-detail", "fas fa-network-wired")
+			data["Title"] = data["PageTitle"].(string) + " - " + ip
+			data["IPAddress"] = ip
+			c.HTML(http.StatusOK, "ip-detail.html", data)
+		})
+
+		// API consumer page
+		pages.GET("/consumer/:consumer", func(c *gin.Context) {
+			consumer := c.Param("consumer")
+			data := pageData(c, "consumer-detail", "fas fa-key")
+			data["Title"] = data["PageTitle"].(string) + " - " + consumer
+			data["Consumer"] = consumer
+			c.HTML(http.StatusOK, "consumer-detail.html", data)
 		})
 
 		logger.Info("Dashboard UI routes enabled")
@@ -140,7 +194,7 @@ func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtim
 			c.JSON(http.StatusOK, gin.H{
 				"message": "LogLynx API Server - Dashboard UI is disabled",
 				"api":     "/api/v1",
-				"health":  "/health",
+				"health":  "/health/ready",
 				"version": version.Version,
 			})
 		})
@@ -148,17 +202,54 @@ func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtim
 
 	// API routes
 	api := router.Group("/api/v1")
+	api.Use(handlers.TrackDuration())
+	if queryStats != nil {
+		api.Use(queryStatsMiddleware(queryStats))
+	}
+	if metrics != nil {
+		api.Use(statsdMiddleware(metrics))
+	}
+	// Before anything reads the query: the time range of endpoints whose queries time out is reduced
+	api.Use(handlers.NewDegradation(cfg.LookbackHours, repositories.DefaultQueryTimeout, logger).Middleware())
+	api.Use(serviceFilterMiddleware())
+	api.Use(handlers.ValidateParams())
 	{
 		api.GET("/version", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"version": version.Version})
 		})
 
+		// Dashboard settings (theme and branding), changed with ADMIN_TOKEN
+		api.GET("/settings", settingsHandler.GetSettings)
+		if adminHandler != nil {
+			api.PUT("/settings", adminHandler.RequireToken, settingsHandler.UpdateSettings)
+		}
+
+		// Saved investigations (time range, filters, pins and notes), changed with ADMIN_TOKEN
+		api.GET("/investigations", investigationHandler.ListInvestigations)
+		api.GET("/investigations/:id", investigationHandler.GetInvestigation)
+		api.GET("/investigations/:id/export", investigationHandler.ExportInvestigation)
+		if adminHandler != nil {
+			api.POST("/investigations", adminHandler.RequireToken, investigationHandler.CreateInvestigation)
+			api.PUT("/investigations/:id", adminHandler.RequireToken, investigationHandler.UpdateInvestigation)
+			api.DELETE("/investigations/:id", adminHandler.RequireToken, investigationHandler.DeleteInvestigation)
+		}
+
+		// Dashboard labels and formatting hints per locale
+		api.GET("/i18n", i18nHandler.GetLocales)
+		api.GET("/i18n/:locale", i18nHandler.GetCatalog)
+
 		// Summary stats
 		api.GET("/stats/summary", dashboardHandler.GetSummary)
 
+		// Overview page data in one request
+		api.GET("/dashboard/overview", dashboardHandler.GetOverview)
+
 		// Timeline data
 		api.GET("/stats/timeline", dashboardHandler.GetTimeline)
 		api.GET("/stats/timeline/status-codes", dashboardHandler.GetStatusCodeTimeline)
+		api.GET("/stats/timeline/continents", dashboardHandler.GetContinentTimeline)
+		api.GET("/stats/timeline/eu", dashboardHandler.GetEUTimeline)
+		api.GET("/stats/timeline/client-versions", dashboardHandler.GetClientVersionTimeline)
 		api.GET("/stats/heatmap/traffic", dashboardHandler.GetTrafficHeatmap)
 
 		// Top stats
@@ -170,33 +261,81 @@ func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtim
 		api.GET("/stats/top/operating-systems", dashboardHandler.GetTopOperatingSystems)
 		api.GET("/stats/top/asns", dashboardHandler.GetTopASNs)
 		api.GET("/stats/top/backends", dashboardHandler.GetTopBackends)
+		api.GET("/stats/top/upstreams", dashboardHandler.GetTopUpstreams)
+		api.GET("/stats/backends/fingerprints", dashboardHandler.GetBackendFingerprints)
+		api.GET("/stats/top/routers", dashboardHandler.GetTopRouters)
+		api.GET("/stats/top/consumers", dashboardHandler.GetTopConsumers)
 		api.GET("/stats/top/referrers", dashboardHandler.GetTopReferrers)
 		api.GET("/stats/top/referrer-domains", dashboardHandler.GetTopReferrerDomains)
+		api.GET("/stats/top/content-types", dashboardHandler.GetTopContentTypes)
+		api.GET("/stats/top/downloads", dashboardHandler.GetDownloadReport)
+		api.GET("/stats/not-found", dashboardHandler.GetNotFoundReport)
 
 		// Distribution stats
 		api.GET("/stats/distribution/status-codes", dashboardHandler.GetStatusCodeDistribution)
 		api.GET("/stats/distribution/methods", dashboardHandler.GetMethodDistribution)
+		api.GET("/stats/distribution/content-types", dashboardHandler.GetContentClassDistribution)
+		api.GET("/stats/methods/unusual", dashboardHandler.GetUnusualMethods)
+		api.GET("/stats/methods/preflight", dashboardHandler.GetPreflightReport)
+		api.GET("/stats/snapshot", dashboardHandler.GetTrafficSnapshot)
+		if cfg.SourceHosts {
+			api.GET("/stats/source-hosts", dashboardHandler.GetSourceHostStats)
+		}
 		api.GET("/stats/distribution/protocols", dashboardHandler.GetProtocolDistribution)
 		api.GET("/stats/distribution/tls-versions", dashboardHandler.GetTLSVersionDistribution)
 		api.GET("/stats/distribution/device-types", dashboardHandler.GetDeviceTypeDistribution)
+		api.GET("/stats/distribution/continents", dashboardHandler.GetContinentDistribution)
+		api.GET("/stats/distribution/eu", dashboardHandler.GetEUDistribution)
 
 		// Performance stats
 		api.GET("/stats/performance/response-time", dashboardHandler.GetResponseTimeStats)
+		api.GET("/stats/performance/client-aborts", dashboardHandler.GetClientAbortReport)
+		api.GET("/stats/routers/:router", dashboardHandler.GetRouterDetail)
+		api.GET("/stats/consumers/:consumer", dashboardHandler.GetConsumerDetail)
+		api.GET("/stats/dependencies", dashboardHandler.GetServiceDependencies)
 		api.GET("/stats/log-processing", dashboardHandler.GetLogProcessingStats)
+		api.GET("/stats/data-quality", dashboardHandler.GetDataQuality)
+
+		// Request labels (LABEL_RULES, transform rule tags)
+		api.GET("/stats/labels", dashboardHandler.GetLabels)
+
+		// Egress cost estimates
+		api.GET("/stats/cost", dashboardHandler.GetCostReport)
+
+		// Structured queries (chat bots, assistants)
+		api.GET("/query", dashboardHandler.RunQuery)
+		api.POST("/query", dashboardHandler.RunQuery)
+		api.GET("/query/schema", dashboardHandler.GetQuerySchema)
 
 		// Recent requests
 		api.GET("/requests/recent", dashboardHandler.GetRecentRequests)
+		api.GET("/requests/export", dashboardHandler.ExportRequests)
+		api.GET("/rollups/traffic/export", dashboardHandler.ExportTrafficRollups)
+		api.GET("/requests/search", dashboardHandler.SearchRequests)
+		api.GET("/requests/slow", dashboardHandler.GetSlowRequests)
 
 		// Real-time metrics
 		api.GET("/realtime/metrics", realtimeHandler.GetCurrentMetrics)
 		api.GET("/realtime/stream", realtimeHandler.StreamMetrics)
+		api.GET("/realtime/streams", realtimeHandler.GetStreamStats)
 		api.GET("/realtime/services", realtimeHandler.GetPerServiceMetrics)
+		api.GET("/realtime/top", realtimeHandler.GetTopLeaderboards)
+
+		// Alerting
+		api.GET("/alerts", alertHandler.GetAlerts)
+		api.GET("/alerts/rules", alertHandler.GetRules)
+		api.GET("/security/events", alertHandler.GetSecurityEvents)
+		api.GET("/security/waf", dashboardHandler.GetWAFReport)
 
 		// Domains list (deprecated)
 		api.GET("/domains", dashboardHandler.GetDomains)
 
 		// Services list (with types)
 		api.GET("/services", dashboardHandler.GetServices)
+		api.GET("/meta/values", dashboardHandler.GetFieldValues)
+		api.GET("/services/:name/incidents", alertHandler.GetServiceIncidents)
+		api.GET("/traces/:id", dashboardHandler.GetTrace)
+		api.GET("/incidents", alertHandler.GetIncidents)
 
 		// IP Analytics
 		api.GET("/ip/:ip/stats", dashboardHandler.GetIPDetailedStats)
@@ -215,6 +354,26 @@ func NewServer(cfg *Config, dashboardHandler *handlers.DashboardHandler, realtim
 		// System Statistics
 		api.GET("/system/stats", systemHandler.GetSystemStats)
 		api.GET("/system/timeline", systemHandler.GetRecordsTimeline)
+		api.GET("/system/ingestion", systemHandler.GetIngestionStatus)
+		api.GET("/system/coverage", systemHandler.GetSourceCoverage)
+		api.GET("/system/query-stats", systemHandler.GetQueryStats)
+		api.GET("/system/storage", systemHandler.GetStorageStats)
+		api.GET("/system/indexes", systemHandler.GetIndexAdvice)
+
+		// Instance sync (only with SYNC_TOKEN)
+		if syncHandler != nil {
+			sync := api.Group("/sync", syncHandler.RequireToken)
+			sync.GET("/export", syncHandler.ExportRequests)
+			sync.POST("/import", syncHandler.ImportRequests)
+		}
+
+		// Maintenance jobs (only with ADMIN_TOKEN)
+		if adminHandler != nil {
+			admin := api.Group("/admin", adminHandler.RequireToken)
+			admin.GET("/enrichment/backfill", adminHandler.GetEnrichmentBackfill)
+			admin.POST("/enrichment/backfill", adminHandler.StartEnrichmentBackfill)
+			admin.DELETE("/enrichment/backfill", adminHandler.CancelEnrichmentBackfill)
+		}
 	}
 
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
@@ -249,19 +408,142 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware() gin.HandlerFunc {
+// requestIDKey holds the request ID in the Gin context, for the access log
+const requestIDKey = "request_id"
+
+// requestIDMiddleware reuses the X-Request-ID of the client or reverse proxy, or generates one
+// The ID is returned in the response header and carried by the request context into handler and slow query logs.
+func requestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Set(requestIDKey, id)
+		c.Writer.Header().Set(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// accessLogFormatter is Gin's default access log line followed by the request ID
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v | %v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		param.Path,
+		param.Keys[requestIDKey],
+		param.ErrorMessage,
+	)
+}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+// queryStatsMiddleware labels database queries with the route they run for and records request durations
+func queryStatsMiddleware(queryStats *database.QueryStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
 			return
 		}
 
+		endpoint := c.Request.Method + " " + route
+		c.Request = c.Request.WithContext(database.WithQueryEndpoint(c.Request.Context(), endpoint))
+
+		started := time.Now()
+		c.Next()
+		queryStats.RecordRequest(endpoint, time.Since(started))
+	}
+}
+
+// statsdMiddleware counts API requests per route, method and status class, and times them
+func statsdMiddleware(metrics *statsd.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
 		c.Next()
+
+		tags := []string{"route:" + c.FullPath(), "method:" + c.Request.Method}
+		metrics.Timing("api.response_time", time.Since(started), tags...)
+		metrics.Count("api.requests", 1, append(tags, fmt.Sprintf("status:%dxx", c.Writer.Status()/100))...)
+	}
+}
+
+// serviceFilterMiddleware rejects service filters with an unknown match type or an invalid pattern
+// Handlers build filters from the same parameters, so they only ever see validated patterns.
+func serviceFilterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters []filter.Service
+
+		for _, params := range [][2]string{
+			{"services[]", "service_match_types[]"},
+			{"exclude_services[]", "exclude_service_match_types[]"},
+		} {
+			names := c.QueryArray(params[0])
+			matchTypes := c.QueryArray(params[1])
+			if len(matchTypes) == 0 {
+				continue
+			}
+			if len(matchTypes) != len(names) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":      fmt.Sprintf("%s needs one entry per value of %s", params[1], params[0]),
+					"request_id": requestid.FromContext(c.Request.Context()),
+				})
+				return
+			}
+			for i := range names {
+				filters = append(filters, filter.Service{Name: names[i], MatchType: matchTypes[i]})
+			}
+		}
+
+		if matchType := c.Query("match_type"); matchType != "" {
+			service := c.Query("service")
+			if service == "" {
+				service = c.Query("host")
+			}
+			filters = append(filters, filter.Service{Name: service, MatchType: matchType})
+		}
+
+		for _, service := range filters {
+			if err := service.Validate(); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":      "Invalid service filter: " + err.Error(),
+					"request_id": requestid.FromContext(c.Request.Context()),
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// themeData returns the template data of a theme, values were validated when the settings were saved
+func themeData(theme repositories.ThemeSettings) gin.H {
+	data := gin.H{
+		"ThemeMode":  theme.Mode,
+		"BrandName":  "LogLynx",
+		"LogoURL":    template.URL(theme.LogoURL),
+		"ThemeStyle": template.CSS(""),
+	}
+	if theme.BrandName != "" {
+		data["BrandName"] = theme.BrandName
+	}
+	if theme.AccentColor != "" {
+		// Lighter and darker variants are mixed by the browser
+		data["ThemeStyle"] = template.CSS(fmt.Sprintf(
+			"--loglynx-primary: %[1]s; --loglynx-primary-light: color-mix(in srgb, %[1]s 75%%, white); --loglynx-primary-dark: color-mix(in srgb, %[1]s 75%%, black); --status-info: %[1]s;",
+			theme.AccentColor))
 	}
+	return data
 }
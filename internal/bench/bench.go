@@ -0,0 +1,352 @@
+package bench
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/ingestion"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+// SourceName is the log source replaying the sample
+const SourceName = "bench"
+
+const (
+	// pollInterval is how often progress and memory are sampled
+	pollInterval = 50 * time.Millisecond
+	// writeInterval is how often lines are appended when replaying at a rate
+	writeInterval = 10 * time.Millisecond
+	// DefaultTimeout bounds the wait for ingestion after the last line is written
+	DefaultTimeout = 10 * time.Minute
+)
+
+// ErrTimeout is returned when the pipeline didn't catch up with the replayed lines in time
+var ErrTimeout = errors.New("ingestion did not catch up before the timeout")
+
+// Options configures a benchmark run
+type Options struct {
+	Sample     []string // Sample lines, replayed in a loop
+	ParserType string   // Parser of the sample (traefik, iis, cri+traefik...)
+	Lines      int      // Lines to replay (default: the sample once)
+	Rate       int      // Lines per second, 0 = write all lines before ingestion starts (initial import)
+	Dir        string   // Work directory of the database and the replayed log, must be empty
+
+	Batching     ingestion.BatchSettings
+	Workers      int
+	IndexProfile repositories.IndexProfile
+	SearchIndex  bool
+
+	// GeoIP providers and their databases (optional, empty = no enrichment)
+	GeoIPProviders []string
+	GeoIPDatabases enrichment.GeoIPDatabases
+
+	Timeout time.Duration // Wait for ingestion to catch up after the last write, 0 = DefaultTimeout
+}
+
+// Result reports the throughput, write latency and memory of a run
+type Result struct {
+	Lines       int64         `json:"lines"`
+	Stored      int64         `json:"stored"` // Requests inserted (sample lines that don't parse are skipped)
+	Elapsed     time.Duration `json:"elapsed"`
+	LinesPerSec float64       `json:"lines_per_sec"`
+	CatchUp     time.Duration `json:"catch_up"` // Time between the last write and the last line stored
+
+	Flushes  int           `json:"flushes"` // Database commits of the batch writer
+	FlushP50 time.Duration `json:"flush_p50"`
+	FlushP99 time.Duration `json:"flush_p99"`
+	FlushMax time.Duration `json:"flush_max"`
+
+	PeakHeapBytes   uint64 `json:"peak_heap_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	GCCycles        uint32 `json:"gc_cycles"`
+	DatabaseBytes   int64  `json:"database_bytes"`
+}
+
+// LoadSample reads the non-empty lines of a sample log file
+func LoadSample(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("sample %s has no lines", path)
+	}
+	return lines, nil
+}
+
+// Run replays the sample through the ingestion pipeline (reader, parser, enrichment, batch writer) into a
+// new database and waits until every line is stored
+// Line offsets are added to dedup hashes, so replayed copies of a line are stored like new requests.
+func Run(opts Options, logger *pterm.Logger) (*Result, error) {
+	if len(opts.Sample) == 0 {
+		return nil, errors.New("empty sample")
+	}
+	if opts.Lines <= 0 {
+		opts.Lines = len(opts.Sample)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	dbPath := filepath.Join(opts.Dir, "bench.db")
+	logPath := filepath.Join(opts.Dir, "access.log")
+	db, err := database.NewConnection(&database.Config{
+		Path:         dbPath,
+		MaxOpenConns: 25,
+		MaxIdleConns: 10,
+		ConnMaxLife:  time.Hour,
+		SearchIndex:  opts.SearchIndex,
+		SlowPolicy:   repositories.NewDefaultSlowPolicy(),
+		IndexProfile: opts.IndexProfile,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	if err := os.WriteFile(logPath, nil, 0o644); err != nil {
+		return nil, err
+	}
+	sourceRepo := repositories.NewLogSourceRepository(db)
+	if err := sourceRepo.Create(&models.LogSource{Name: SourceName, Path: logPath, ParserType: opts.ParserType}); err != nil {
+		return nil, err
+	}
+
+	var geoIP *enrichment.GeoIPEnricher
+	if len(opts.GeoIPProviders) > 0 {
+		geoIP, err = enrichment.NewGeoIPEnricher(enrichment.OpenGeoIPProviders(opts.GeoIPProviders, opts.GeoIPDatabases, logger), db, logger, 10000)
+		if err != nil {
+			return nil, err
+		}
+		defer geoIP.Close()
+	}
+
+	dedup, err := ingestion.ParseDedupOptions("always", "")
+	if err != nil {
+		return nil, err
+	}
+	repo := &timedRepo{HTTPRequestRepository: repositories.NewHTTPRequestRepository(db, logger, opts.IndexProfile)}
+	coordinator := ingestion.NewCoordinator(sourceRepo, repo, parsers.NewRegistry(nil, nil, logger), geoIP, nil, nil, dedup, nil, nil, nil, nil, nil, logger,
+		0, false, opts.Batching, opts.Workers, 0)
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	monitor := &memoryMonitor{}
+
+	result := &Result{Lines: int64(opts.Lines)}
+	done := make(chan replayed, 1)
+	var start time.Time
+
+	if opts.Rate <= 0 {
+		// Initial import: the whole file is there when ingestion starts
+		size, err := appendLines(logPath, opts.Sample, 0, opts.Lines)
+		if err != nil {
+			return nil, err
+		}
+		start = time.Now()
+		done <- replayed{size: size, at: start}
+		if err := coordinator.Start(); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := coordinator.Start(); err != nil {
+			return nil, err
+		}
+		start = time.Now()
+		go func() {
+			size, err := replay(logPath, opts.Sample, opts.Lines, opts.Rate)
+			done <- replayed{size: size, at: time.Now(), err: err}
+		}()
+	}
+	defer coordinator.Stop()
+
+	// Wait until the saved source position reaches the end of the replayed log
+	var written *replayed
+	var deadline time.Time
+	for {
+		monitor.sample()
+		if written == nil {
+			select {
+			case r := <-done:
+				if r.err != nil {
+					return nil, r.err
+				}
+				written = &r
+				deadline = r.at.Add(opts.Timeout)
+			default:
+			}
+		}
+		if written != nil {
+			source, err := sourceRepo.FindByName(SourceName)
+			if err != nil {
+				return nil, err
+			}
+			if source.LastPosition >= written.size {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, ErrTimeout
+			}
+		}
+		time.Sleep(pollInterval)
+	}
+
+	// Stopping waits for the writer, whose stored counts are recorded after the commit saving the position
+	end := time.Now()
+	coordinator.Stop()
+	result.Elapsed = end.Sub(start)
+	result.CatchUp = end.Sub(written.at)
+	result.LinesPerSec = float64(opts.Lines) / result.Elapsed.Seconds()
+	repo.apply(result)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	result.PeakHeapBytes = monitor.peak
+	result.TotalAllocBytes = after.TotalAlloc - before.TotalAlloc
+	result.GCCycles = after.NumGC - before.NumGC
+	if info, err := os.Stat(dbPath); err == nil {
+		result.DatabaseBytes = info.Size()
+	}
+	return result, nil
+}
+
+// appendLines writes lines from..to of the looped sample and returns the file size
+func appendLines(path string, sample []string, from int, to int) (int64, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, 256*1024)
+	for i := from; i < to; i++ {
+		writer.WriteString(sample[i%len(sample)])
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// replayed is the outcome of writing the replayed log
+type replayed struct {
+	size int64     // Final file size
+	at   time.Time // Last write
+	err  error
+}
+
+// replay appends the lines at rate lines per second and returns the final file size
+func replay(path string, sample []string, lines int, rate int) (int64, error) {
+	ticker := time.NewTicker(writeInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	written := 0
+	var size int64
+	for written < lines {
+		<-ticker.C
+		due := min(int(time.Since(start).Seconds()*float64(rate)), lines)
+		if due <= written {
+			continue
+		}
+		var err error
+		if size, err = appendLines(path, sample, written, due); err != nil {
+			return 0, err
+		}
+		written = due
+	}
+	return size, nil
+}
+
+// timedRepo measures the commits of the batch writer
+type timedRepo struct {
+	repositories.HTTPRequestRepository
+
+	mu      sync.Mutex
+	flushes []time.Duration
+	stored  int64
+}
+
+func (r *timedRepo) CreateSourceBatches(batches []*repositories.SourceBatch) ([]*repositories.BatchResult, error) {
+	start := time.Now()
+	results, err := r.HTTPRequestRepository.CreateSourceBatches(batches)
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes = append(r.flushes, elapsed)
+	for _, result := range results {
+		if result != nil {
+			r.stored += int64(result.Inserted)
+		}
+	}
+	return results, err
+}
+
+// apply sets the stored requests and flush latency percentiles
+func (r *timedRepo) apply(result *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result.Stored = r.stored
+	result.Flushes = len(r.flushes)
+	if len(r.flushes) == 0 {
+		return
+	}
+	sorted := slices.Clone(r.flushes)
+	slices.Sort(sorted)
+	result.FlushP50 = percentile(sorted, 0.50)
+	result.FlushP99 = percentile(sorted, 0.99)
+	result.FlushMax = sorted[len(sorted)-1]
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(float64(len(sorted))*p+0.999999) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
+
+// memoryMonitor tracks the peak heap while the pipeline runs
+type memoryMonitor struct {
+	peak uint64
+}
+
+func (m *memoryMonitor) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	m.peak = max(m.peak, stats.HeapAlloc)
+}
@@ -0,0 +1,74 @@
+package bench
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/ingestion"
+
+	"github.com/pterm/pterm"
+)
+
+func testOptions(t testing.TB, lines int) Options {
+	sample, err := DemoSample(2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Options{
+		Sample:     sample,
+		ParserType: "traefik",
+		Lines:      lines,
+		Dir:        t.TempDir(),
+		Batching:   ingestion.BatchSettings{MinSize: 100, MaxSize: 1000, MinFlush: 100 * time.Millisecond, MaxFlush: time.Second},
+		Workers:    4,
+		Timeout:    time.Minute,
+	}
+}
+
+func TestRun(t *testing.T) {
+	opts := testOptions(t, 5000)
+	result, err := Run(opts, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sample is replayed 2.5 times, every copy is stored
+	if result.Stored != 5000 {
+		t.Errorf("Expected 5000 requests stored, got %d", result.Stored)
+	}
+	if result.Flushes == 0 || result.FlushP99 < result.FlushP50 || result.FlushMax < result.FlushP99 {
+		t.Errorf("Unexpected flush latencies %+v", result)
+	}
+	if result.LinesPerSec <= 0 || result.PeakHeapBytes == 0 {
+		t.Errorf("Unexpected throughput or memory %+v", result)
+	}
+}
+
+func TestRun_Rate(t *testing.T) {
+	opts := testOptions(t, 1000)
+	opts.Rate = 2000
+	result, err := Run(opts, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stored != 1000 {
+		t.Errorf("Expected 1000 requests stored, got %d", result.Stored)
+	}
+	if result.Elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the replay to take about 500ms at 2000 lines/s, took %v", result.Elapsed)
+	}
+}
+
+// BenchmarkPipeline reports the initial import throughput and write latency of the demo sample
+// go test -run '^$' -bench Pipeline -benchtime 50000x ./internal/bench
+func BenchmarkPipeline(b *testing.B) {
+	opts := testOptions(b, b.N)
+	b.ResetTimer()
+	result, err := Run(opts, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(result.LinesPerSec, "lines/s")
+	b.ReportMetric(float64(result.FlushP99.Microseconds())/1000, "p99-flush-ms")
+	b.ReportMetric(float64(result.PeakHeapBytes)/(1024*1024), "peak-heap-MB")
+}
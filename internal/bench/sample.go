@@ -0,0 +1,64 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/demo"
+)
+
+// DemoSample renders the demo traffic as Traefik JSON access log lines
+// Used when no sample file is given, so the benchmark runs without real logs.
+func DemoSample(lines int) ([]string, error) {
+	sample := make([]string, 0, lines)
+	opts := demo.Options{Days: 1, RequestsPerDay: max(lines, 1), End: time.Now().Truncate(time.Hour), Seed: 1}
+
+	err := demo.Generate(opts, func(batch []*models.HTTPRequest) error {
+		for _, request := range batch {
+			if len(sample) == lines {
+				return nil
+			}
+			line, err := json.Marshal(traefikLine(request))
+			if err != nil {
+				return err
+			}
+			sample = append(sample, string(line))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(sample) == 0 {
+		return nil, fmt.Errorf("no demo sample lines generated")
+	}
+	return sample, nil
+}
+
+// traefikLine holds the fields of a Traefik JSON access log entry read by the parser
+func traefikLine(r *models.HTTPRequest) map[string]any {
+	return map[string]any{
+		"time":                      r.Timestamp.UTC().Format(time.RFC3339),
+		"StartUTC":                  r.StartUTC,
+		"ClientHost":                r.ClientIP,
+		"RequestMethod":             r.Method,
+		"RequestPath":               r.Path,
+		"RequestProtocol":           r.Protocol,
+		"request_Host":              r.Host,
+		"request_X-Forwarded-Proto": r.RequestScheme,
+		"request_User-Agent":        r.UserAgent,
+		"request_Referer":           r.Referer,
+		"DownstreamStatus":          r.StatusCode,
+		"DownstreamContentSize":     r.ResponseSize,
+		"downstream_Content-Type":   r.ResponseContentType,
+		"Duration":                  r.Duration,
+		"OriginDuration":            int64(r.UpstreamResponseTimeMs * float64(time.Millisecond)),
+		"OriginStatus":              r.UpstreamStatus,
+		"ServiceName":               r.BackendName,
+		"RouterName":                r.RouterName,
+		"TLSVersion":                r.TLSVersion,
+		"TLSCipher":                 r.TLSCipher,
+	}
+}
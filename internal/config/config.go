@@ -27,6 +27,30 @@ type Config struct {
 
 	// Performance Configuration
 	Performance PerformanceConfig
+
+	// Analytics Configuration
+	Analytics AnalyticsConfig
+
+	// Alerting Configuration
+	Alerting AlertingConfig
+
+	// Agent Configuration
+	Agent AgentConfig
+
+	// Prometheus Remote-Write Configuration
+	RemoteWrite RemoteWriteConfig
+
+	// StatsD Configuration
+	StatsD StatsDConfig
+
+	// Healthcheck Ping Configuration
+	Healthcheck HealthcheckConfig
+
+	// Demo Data Configuration
+	Demo DemoConfig
+
+	// Plugin Configuration
+	Plugins PluginConfig
 }
 
 // DatabaseConfig contains database-related settings
@@ -40,6 +64,30 @@ type DatabaseConfig struct {
 	CleanupTime     string        // Time of day to run cleanup (24-hour format, e.g., "02:00")
 	VacuumEnabled   bool          // Run VACUUM after cleanup to reclaim space
 
+	// Read-only pool for dashboard and stats queries
+	ReadMaxOpenConns int // 0 = 2 connections per CPU core
+
+	// Multi-database attach mode
+	AttachDatabases string // name=path entries of other hosts' databases merged read-only into the analytics
+
+	// Query performance stats
+	SlowQueryThreshold time.Duration // Queries taking longer are counted as slow in /api/v1/system/query-stats
+
+	// Full-text search
+	SearchIndexEnabled bool // FTS5 index for /api/v1/requests/search (more disk space, slightly slower ingestion)
+
+	// Pre-aggregation
+	ResponseTimeRollupInterval time.Duration // How often hourly response time rollups are updated (0 = disabled)
+
+	// Indexes
+	IndexProfile string // Performance indexes on http_requests: minimal, balanced or analytics (default)
+
+	// Schema migrations
+	MigrationsDryRun  bool // Log pending migrations and exit without applying them
+	MigrationBackup   bool // Back up the database before applying migrations
+	MigrationMaxRows  int  // Refuse to start when a migration rewrites more requests (0 = no limit)
+	RollbackToVersion int  // Revert migrations above this schema version and exit (0 = disabled)
+
 	// Connection Pool Monitoring
 	PoolMonitoringEnabled   bool          // Enable connection pool monitoring
 	PoolMonitoringInterval  time.Duration // How often to check pool stats
@@ -53,34 +101,166 @@ type GeoIPConfig struct {
 	CountryDBPath string
 	ASNDBPath     string
 	Enabled       bool
+
+	// Providers looked up in order, later ones filling what earlier ones don't know (maxmind, dbip, ipinfo, ip2location)
+	Providers string
+
+	// Databases of the other providers, the MaxMind ones being the paths above
+	DBIPCityDBPath    string
+	DBIPASNDBPath     string
+	IPinfoDBPath      string
+	IP2LocationDBPath string
 }
 
 // LogSourcesConfig contains log source paths
 type LogSourcesConfig struct {
-	TraefikLogPath      string
-	TraefikLogFormat    string // auto, json, clf
-	AutoDiscover        bool
-	InitialImportDays   int  // Only import last N days on first run (0 = import all)
-	InitialImportEnable bool // Enable initial import limiting
+	TraefikLogPath         string
+	TraefikLogFormat       string // auto, json, clf
+	AutoDiscover           bool
+	InitialImportDays      int           // Only import last N days on first run (0 = import all)
+	InitialImportEnable    bool          // Enable initial import limiting
+	DedupLineOffset        string        // Add line file offset to dedup hashes: auto (imprecise formats only), always, never
+	ParserDedupLineOffset  string        // Per-parser overrides, e.g. "traefik:always"
+	MaxLineLength          int           // Lines longer than this (bytes) are skipped instead of stopping ingestion
+	ApacheLogPath          string        // Apache httpd access log, auto-discovered from /var/log/apache2 and /var/log/httpd when empty
+	ApacheLogFormat        string        // Apache LogFormat string or nickname (common, combined, vhost_combined), empty = try the nicknames
+	HAProxyLogPath         string        // HAProxy HTTP log, /var/log/haproxy.log when empty
+	K8SDiscovery           bool          // Discover ingress controller pods through the Kubernetes API (in-cluster)
+	K8SLabelSelectors      string        // Semicolon-separated pod label selectors
+	K8SNamespace           string        // Only discover pods of this namespace (empty = all namespaces)
+	K8SPodLogDir           string        // kubelet pod log directory mounted from the node
+	DiscoveryInterval      time.Duration // Periodic discovery interval (picks up new files and pods)
+	WAFLogPaths            string        // Comma-separated WAF JSON audit logs (ModSecurity 3, Coraza), read into waf_events
+	CaptureHeaders         string        // Comma-separated request headers of Traefik JSON logs kept in proxy_metadata, e.g. "X-Tenant-Id"
+	CaptureResponseHeaders string        // Comma-separated response headers of Traefik JSON logs kept in proxy_metadata
+	FieldMappings          string        // Per-source field overrides from JSON keys, e.g. "edge:client_ip=request_Cf-Connecting-Ip"
+	TrustedProxies         string        // Comma-separated proxy networks skipped in X-Forwarded-For chains, e.g. "173.245.48.0/20"
+	ForwardedProxyDepth    int           // Proxies in front of the logging proxy, skipped in X-Forwarded-For chains (0 = none)
+	TransformRulesFile     string        // Path to JSON transform rules dropping, rewriting or tagging requests (empty = none)
+	LabelRules             string        // Labels assigned from request fields, e.g. "api_version=path:^/api/(v\d+)/"
 }
 
 // ServerConfig contains web server settings
 type ServerConfig struct {
-	Host               string
-	Port               int
-	Production         bool
-	DashboardEnabled   bool // If false, only API routes are exposed
-	SplashScreenEnabled bool // If false, splash screen is disabled on startup
+	Host                 string
+	Port                 int
+	Production           bool
+	DashboardEnabled     bool          // If false, only API routes are exposed
+	SplashScreenEnabled  bool          // If false, splash screen is disabled on startup
+	Locale               string        // Dashboard locale (en, de, fr, es, it), empty to detect it from the browser's Accept-Language
+	HealthStallThreshold time.Duration // A source with unread data and no progress for this long is reported as stalled
+	HealthMinFreeDiskMB  int           // Readiness fails when the database disk has less free space (MB)
+	SyncToken            string        // Bearer token enabling /api/v1/sync export and import between instances (empty = disabled)
+	AdminToken           string        // Bearer token enabling /api/v1/admin jobs such as the enrichment backfill (empty = disabled)
+	StreamToken          string        // Token required by /api/v1/realtime/stream (empty = open)
+	StreamMaxConnections int           // Concurrent real-time streams (0 = unlimited)
+	StreamMaxPerIP       int           // Concurrent real-time streams per client IP (0 = unlimited)
+	TrustedProxies       string        // Comma-separated proxies in front of LogLynx whose X-Forwarded-For gives the client IP (empty = none)
+	GRPCPort             int           // Port of the gRPC API on Host (0 = disabled)
+
+	CORSAllowedOrigins    string // Comma-separated origins allowed to call the API from browsers, "*" for any (empty = same origin only)
+	CORSAllowedMethods    string // Comma-separated methods allowed in cross-origin requests (empty = GET, POST, PUT, DELETE, OPTIONS)
+	CORSAllowedHeaders    string // Comma-separated request headers allowed in cross-origin requests (empty = the defaults)
+	CORSAllowCredentials  bool   // Allows cookies and Authorization in cross-origin requests, only for listed origins
+	SecurityHeaders       bool   // Adds X-Content-Type-Options, X-Frame-Options, Referrer-Policy and the dashboard CSP
+	ContentSecurityPolicy string // Content-Security-Policy of dashboard pages (empty = the built-in policy)
+	ReferrerPolicy        string // Referrer-Policy of all responses
 }
 
 // PerformanceConfig contains performance tuning settings
 type PerformanceConfig struct {
 	RealtimeMetricsInterval time.Duration
+	RealtimeBufferSize      int // Number of recent events kept in memory for real-time metrics
 	GeoIPCacheSize          int
-	BatchSize               int
+	BatchSize               int           // Largest batch size (static batch size when adaptive batching is off)
+	BatchSizeMin            int           // Smallest batch size chosen by adaptive batching
+	BatchFlushMin           time.Duration // Shortest flush interval (used at low traffic)
+	BatchFlushMax           time.Duration // Longest flush interval (static interval when adaptive batching is off)
+	AdaptiveBatching        bool          // Adapt batch size and flush interval to the arrival rate
 	WorkerPoolSize          int
 }
 
+// AnalyticsConfig contains defaults for dashboard statistics
+type AnalyticsConfig struct {
+	DefaultLookbackHours      int    // Default time range for stats queries (default: 168 = 7 days)
+	FailureStatusCodes        string // Status codes counted as failures (default: "400-599")
+	ServiceFailureStatusCodes string // Per-service overrides, e.g. "api:500-599,429;files:400-599"
+
+	SlowRequestThresholdMs       float64 // Response time from which requests are slow (default: 1000)
+	ServiceSlowRequestThresholds string  // Per-service overrides in milliseconds, e.g. "api:500;files:5000"
+
+	EgressCostPerGB    float64 // Estimated egress price per GB (default: 0.09)
+	EgressCostCurrency string  // Currency of egress prices (default: "USD")
+	ServiceEgressCosts string  // Per-service prices per GB, e.g. "cdn:0.02;downloads:0.12"
+
+	ClientVersionRules string // Version extraction per client, e.g. "MyApp=MyApp/(\d+)\.;sdk=my-sdk/(\d+\.\d+)"
+
+	ConsumerQuotas string // Request quotas of API consumers, e.g. "*:10000/day;alice:500/hour"
+}
+
+// AlertingConfig contains alert rule settings
+type AlertingConfig struct {
+	RulesFile                   string        // Path to JSON alert rules file (empty = alerting disabled)
+	EvaluationInterval          time.Duration // How often rules are evaluated
+	DisallowedMethods           string        // Comma-separated HTTP methods creating security events, e.g. "TRACE,CONNECT"
+	UserAgentChecks             bool          // Report clients rotating or spoofing user agents as security events
+	UARotationThreshold         int           // Distinct user agents per client IP within 5 minutes reported as rotation
+	ImpossibleTravelPaths       string        // Comma-separated login path prefixes checked for impossible travel (empty = disabled)
+	ImpossibleTravelWindow      time.Duration // Time between two logins of a user within which travel is checked
+	ImpossibleTravelMaxSpeedKmh float64       // Travel speed between two logins above which travel is impossible
+	ZeroTrafficWindow           time.Duration // Time without requests after which a normally active service has an incident (0 = disabled)
+	ZeroTrafficBaselineWeeks    int           // Previous weeks the usual traffic of a service is averaged over
+	ZeroTrafficMinRequests      float64       // Usual requests per window below which a silent service is not reported
+}
+
+// AgentConfig contains settings for running as an agent forwarding parsed requests to a central server
+type AgentConfig struct {
+	ServerURL    string        // Central LogLynx URL, e.g. "http://central:8080" (empty = run standalone)
+	Token        string        // SYNC_TOKEN of the central server
+	SourcePrefix string        // Prepended to source names on the central server (default: hostname + "/")
+	Timeout      time.Duration // Timeout of one forwarding request
+	MaxBackoff   time.Duration // Longest wait between retries while the central server is unreachable
+}
+
+// RemoteWriteConfig contains settings for pushing hourly service rollups to a Prometheus remote-write endpoint
+type RemoteWriteConfig struct {
+	URL         string        // Remote-write endpoint, e.g. "http://prometheus:9090/api/v1/write" (empty = disabled)
+	Username    string        // Basic auth user (Grafana Cloud, Mimir)
+	Password    string        // Basic auth password
+	BearerToken string        // Alternative to basic auth
+	Labels      string        // Static labels added to every series, e.g. "job=loglynx,env=prod"
+	Timeout     time.Duration // Timeout of one push
+}
+
+// StatsDConfig contains settings for emitting ingestion and API metrics to a StatsD or DogStatsD agent
+type StatsDConfig struct {
+	Address   string // Agent address, e.g. "localhost:8125" (empty = disabled)
+	Prefix    string // Prepended to metric names
+	Tags      string // Tags added to every metric, e.g. "env:prod,team:edge" (DogStatsD only)
+	DogStatsD bool   // Send DogStatsD tags, disable for agents that only speak plain StatsD
+}
+
+// HealthcheckConfig contains dead man's switch URLs (healthchecks.io, Uptime Kuma push monitors) pinged while jobs succeed
+type HealthcheckConfig struct {
+	IngestionURL string        // Pinged every Interval while ingestion runs and no source is stalled (empty = disabled)
+	CleanupURL   string        // Pinged after each successful daily cleanup (empty = disabled)
+	Interval     time.Duration // Ingestion check interval
+	Timeout      time.Duration // Timeout of one ping
+}
+
+// DemoConfig contains settings of the synthetic demo traffic ("loglynx demo" or SEED_DEMO_DATA)
+type DemoConfig struct {
+	Seed           bool // Generate demo traffic at startup when the database holds none
+	Days           int  // Days of generated traffic
+	RequestsPerDay int  // Average generated requests per day
+}
+
+// PluginConfig contains the parser and enricher plugins run as subprocesses
+type PluginConfig struct {
+	Commands string        // Semicolon-separated plugin commands (empty = no plugins)
+	Timeout  time.Duration // How long a plugin may take to answer a call
+}
+
 // Load reads configuration from .env file and environment variables
 func Load() (*Config, error) {
 	// Try to load .env file (ignore error if file doesn't exist)
@@ -97,6 +277,30 @@ func Load() (*Config, error) {
 			CleanupTime:     getEnv("DB_CLEANUP_TIME", "02:00"),
 			VacuumEnabled:   getEnvAsBool("DB_VACUUM_ENABLED", true),
 
+			// Read-only pool
+			ReadMaxOpenConns: getEnvAsInt("DB_READ_MAX_OPEN_CONNS", 0),
+
+			// Multi-database attach mode
+			AttachDatabases: getEnv("ATTACH_DATABASES", ""),
+
+			// Query performance stats
+			SlowQueryThreshold: getEnvAsDuration("DB_SLOW_QUERY_THRESHOLD", 100*time.Millisecond),
+
+			// Full-text search
+			SearchIndexEnabled: getEnvAsBool("SEARCH_INDEX_ENABLED", true),
+
+			// Pre-aggregation
+			ResponseTimeRollupInterval: getEnvAsDuration("RESPONSE_TIME_ROLLUP_INTERVAL", time.Minute),
+
+			// Indexes
+			IndexProfile: getEnv("DB_INDEX_PROFILE", "analytics"),
+
+			// Schema migrations
+			MigrationsDryRun:  getEnvAsBool("DB_MIGRATIONS_DRY_RUN", false),
+			MigrationBackup:   getEnvAsBool("DB_MIGRATION_BACKUP", true),
+			MigrationMaxRows:  getEnvAsInt("DB_MIGRATION_MAX_ROWS", 5000000),
+			RollbackToVersion: getEnvAsInt("DB_ROLLBACK_TO_VERSION", 0),
+
 			// Connection Pool Monitoring
 			PoolMonitoringEnabled:   getEnvAsBool("DB_POOL_MONITORING", true),
 			PoolMonitoringInterval:  getEnvAsDuration("DB_POOL_MONITOR_INTERVAL", 30*time.Second),
@@ -108,27 +312,140 @@ func Load() (*Config, error) {
 			CountryDBPath: getEnv("GEOIP_COUNTRY_DB", "geoip/GeoLite2-Country.mmdb"),
 			ASNDBPath:     getEnv("GEOIP_ASN_DB", "geoip/GeoLite2-ASN.mmdb"),
 			Enabled:       getEnvAsBool("GEOIP_ENABLED", true),
+
+			Providers:         getEnv("GEOIP_PROVIDERS", "maxmind"),
+			DBIPCityDBPath:    getEnv("DBIP_CITY_DB", "geoip/dbip-city-lite.mmdb"),
+			DBIPASNDBPath:     getEnv("DBIP_ASN_DB", "geoip/dbip-asn-lite.mmdb"),
+			IPinfoDBPath:      getEnv("IPINFO_DB", "geoip/ipinfo_lite.mmdb"),
+			IP2LocationDBPath: getEnv("IP2LOCATION_DB", "geoip/IP2LOCATION-LITE-DB11.MMDB"),
 		},
 		LogSources: LogSourcesConfig{
-			TraefikLogPath:      getEnv("TRAEFIK_LOG_PATH", "traefik/logs/access.log"),
-			TraefikLogFormat:    getEnv("TRAEFIK_LOG_FORMAT", "auto"),
-			AutoDiscover:        getEnvAsBool("LOG_AUTO_DISCOVER", true),
-			InitialImportDays:   getEnvAsInt("INITIAL_IMPORT_DAYS", 60),
-			InitialImportEnable: getEnvAsBool("INITIAL_IMPORT_ENABLE", true),
+			TraefikLogPath:         getEnv("TRAEFIK_LOG_PATH", "traefik/logs/access.log"),
+			TraefikLogFormat:       getEnv("TRAEFIK_LOG_FORMAT", "auto"),
+			AutoDiscover:           getEnvAsBool("LOG_AUTO_DISCOVER", true),
+			InitialImportDays:      getEnvAsInt("INITIAL_IMPORT_DAYS", 60),
+			InitialImportEnable:    getEnvAsBool("INITIAL_IMPORT_ENABLE", true),
+			DedupLineOffset:        getEnv("DEDUP_LINE_OFFSET", "auto"),
+			ParserDedupLineOffset:  getEnv("PARSER_DEDUP_LINE_OFFSET", ""),
+			MaxLineLength:          getEnvAsInt("MAX_LINE_LENGTH", 1048576),
+			ApacheLogPath:          getEnv("APACHE_LOG_PATH", ""),
+			ApacheLogFormat:        getEnv("APACHE_LOG_FORMAT", ""),
+			HAProxyLogPath:         getEnv("HAPROXY_LOG_PATH", ""),
+			K8SDiscovery:           getEnvAsBool("K8S_DISCOVERY", false),
+			K8SLabelSelectors:      getEnv("K8S_LABEL_SELECTORS", "app.kubernetes.io/name=traefik;app.kubernetes.io/name=ingress-nginx"),
+			K8SNamespace:           getEnv("K8S_NAMESPACE", ""),
+			K8SPodLogDir:           getEnv("K8S_POD_LOG_DIR", "/var/log/pods"),
+			DiscoveryInterval:      getEnvAsDuration("DISCOVERY_INTERVAL", 5*time.Minute),
+			WAFLogPaths:            getEnv("WAF_LOG_PATHS", ""),
+			CaptureHeaders:         getEnv("CAPTURE_HEADERS", ""),
+			CaptureResponseHeaders: getEnv("CAPTURE_RESPONSE_HEADERS", "Cache-Control,Server,X-Powered-By"),
+			FieldMappings:          getEnv("FIELD_MAPPINGS", ""),
+			TrustedProxies:         getEnv("TRUSTED_PROXIES", ""),
+			ForwardedProxyDepth:    getEnvAsInt("FORWARDED_PROXY_DEPTH", 0),
+			TransformRulesFile:     getEnv("TRANSFORM_RULES_FILE", ""),
+			LabelRules:             getEnv("LABEL_RULES", ""),
 		},
 		Server: ServerConfig{
-			Host:                getEnv("SERVER_HOST", "0.0.0.0"),
-			Port:                getEnvAsInt("SERVER_PORT", 8080),
-			Production:          getEnvAsBool("SERVER_PRODUCTION", false),
-			DashboardEnabled:    getEnvAsBool("DASHBOARD_ENABLED", true),
-			SplashScreenEnabled: getEnvAsBool("SPLASH_SCREEN_ENABLED", true),
+			Host:                 getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:                 getEnvAsInt("SERVER_PORT", 8080),
+			Production:           getEnvAsBool("SERVER_PRODUCTION", false),
+			DashboardEnabled:     getEnvAsBool("DASHBOARD_ENABLED", true),
+			SplashScreenEnabled:  getEnvAsBool("SPLASH_SCREEN_ENABLED", true),
+			Locale:               getEnv("DASHBOARD_LOCALE", ""),
+			HealthStallThreshold: getEnvAsDuration("HEALTH_STALL_THRESHOLD", 2*time.Minute),
+			HealthMinFreeDiskMB:  getEnvAsInt("HEALTH_MIN_FREE_DISK_MB", 500),
+			SyncToken:            getEnv("SYNC_TOKEN", ""),
+			AdminToken:           getEnv("ADMIN_TOKEN", ""),
+			StreamToken:          getEnv("STREAM_TOKEN", ""),
+			StreamMaxConnections: getEnvAsInt("STREAM_MAX_CONNECTIONS", 100),
+			StreamMaxPerIP:       getEnvAsInt("STREAM_MAX_CONNECTIONS_PER_IP", 10),
+			TrustedProxies:       getEnv("SERVER_TRUSTED_PROXIES", ""),
+			GRPCPort:             getEnvAsInt("GRPC_PORT", 0),
+
+			CORSAllowedOrigins:    getEnv("CORS_ALLOWED_ORIGINS", ""),
+			CORSAllowedMethods:    getEnv("CORS_ALLOWED_METHODS", ""),
+			CORSAllowedHeaders:    getEnv("CORS_ALLOWED_HEADERS", ""),
+			CORSAllowCredentials:  getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+			SecurityHeaders:       getEnvAsBool("SECURITY_HEADERS_ENABLED", true),
+			ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", ""),
+			ReferrerPolicy:        getEnv("REFERRER_POLICY", "strict-origin-when-cross-origin"),
 		},
 		Performance: PerformanceConfig{
 			RealtimeMetricsInterval: getEnvAsDuration("METRICS_INTERVAL", 5*time.Second),
+			RealtimeBufferSize:      getEnvAsInt("REALTIME_BUFFER_SIZE", 50000),
 			GeoIPCacheSize:          getEnvAsInt("GEOIP_CACHE_SIZE", 10000),
 			BatchSize:               getEnvAsInt("BATCH_SIZE", 1000),
+			BatchSizeMin:            getEnvAsInt("BATCH_SIZE_MIN", 100),
+			BatchFlushMin:           getEnvAsDuration("BATCH_FLUSH_MIN", 1*time.Second),
+			BatchFlushMax:           getEnvAsDuration("BATCH_FLUSH_MAX", 30*time.Second),
+			AdaptiveBatching:        getEnvAsBool("ADAPTIVE_BATCHING", true),
 			WorkerPoolSize:          getEnvAsInt("WORKER_POOL_SIZE", 4),
 		},
+		Analytics: AnalyticsConfig{
+			DefaultLookbackHours:      getEnvAsInt("STATS_LOOKBACK_HOURS", 168),
+			FailureStatusCodes:        getEnv("FAILURE_STATUS_CODES", "400-599"),
+			ServiceFailureStatusCodes: getEnv("SERVICE_FAILURE_STATUS_CODES", ""),
+
+			SlowRequestThresholdMs:       getEnvAsFloat("SLOW_REQUEST_THRESHOLD_MS", 1000),
+			ServiceSlowRequestThresholds: getEnv("SERVICE_SLOW_REQUEST_THRESHOLDS", ""),
+
+			EgressCostPerGB:    getEnvAsFloat("EGRESS_COST_PER_GB", 0.09),
+			EgressCostCurrency: getEnv("EGRESS_COST_CURRENCY", "USD"),
+			ServiceEgressCosts: getEnv("SERVICE_EGRESS_COSTS", ""),
+
+			ClientVersionRules: getEnv("CLIENT_VERSION_RULES", ""),
+
+			ConsumerQuotas: getEnv("CONSUMER_QUOTAS", ""),
+		},
+		Alerting: AlertingConfig{
+			RulesFile:                   getEnv("ALERT_RULES_FILE", ""),
+			EvaluationInterval:          getEnvAsDuration("ALERT_EVAL_INTERVAL", 30*time.Second),
+			DisallowedMethods:           getEnv("DISALLOWED_METHODS", ""),
+			UserAgentChecks:             getEnvAsBool("UA_ANOMALY_DETECTION", true),
+			UARotationThreshold:         getEnvAsInt("UA_ROTATION_THRESHOLD", 10),
+			ImpossibleTravelPaths:       getEnv("IMPOSSIBLE_TRAVEL_PATHS", ""),
+			ImpossibleTravelWindow:      getEnvAsDuration("IMPOSSIBLE_TRAVEL_WINDOW", 2*time.Hour),
+			ImpossibleTravelMaxSpeedKmh: getEnvAsFloat("IMPOSSIBLE_TRAVEL_MAX_SPEED_KMH", 1000),
+			ZeroTrafficWindow:           getEnvAsDuration("ZERO_TRAFFIC_WINDOW", 15*time.Minute),
+			ZeroTrafficBaselineWeeks:    getEnvAsInt("ZERO_TRAFFIC_BASELINE_WEEKS", 4),
+			ZeroTrafficMinRequests:      getEnvAsFloat("ZERO_TRAFFIC_MIN_REQUESTS", 20),
+		},
+		Agent: AgentConfig{
+			ServerURL:    getEnv("AGENT_SERVER_URL", ""),
+			Token:        getEnv("AGENT_TOKEN", ""),
+			SourcePrefix: getEnv("AGENT_SOURCE_PREFIX", ""),
+			Timeout:      getEnvAsDuration("AGENT_TIMEOUT", 30*time.Second),
+			MaxBackoff:   getEnvAsDuration("AGENT_MAX_BACKOFF", time.Minute),
+		},
+		RemoteWrite: RemoteWriteConfig{
+			URL:         getEnv("REMOTE_WRITE_URL", ""),
+			Username:    getEnv("REMOTE_WRITE_USERNAME", ""),
+			Password:    getEnv("REMOTE_WRITE_PASSWORD", ""),
+			BearerToken: getEnv("REMOTE_WRITE_BEARER_TOKEN", ""),
+			Labels:      getEnv("REMOTE_WRITE_LABELS", "job=loglynx"),
+			Timeout:     getEnvAsDuration("REMOTE_WRITE_TIMEOUT", 30*time.Second),
+		},
+		StatsD: StatsDConfig{
+			Address:   getEnv("STATSD_ADDRESS", ""),
+			Prefix:    getEnv("STATSD_PREFIX", "loglynx."),
+			Tags:      getEnv("STATSD_TAGS", ""),
+			DogStatsD: getEnvAsBool("STATSD_DOGSTATSD", true),
+		},
+		Healthcheck: HealthcheckConfig{
+			IngestionURL: getEnv("HEALTHCHECK_INGESTION_URL", ""),
+			CleanupURL:   getEnv("HEALTHCHECK_CLEANUP_URL", ""),
+			Interval:     getEnvAsDuration("HEALTHCHECK_INTERVAL", time.Minute),
+			Timeout:      getEnvAsDuration("HEALTHCHECK_TIMEOUT", 10*time.Second),
+		},
+		Demo: DemoConfig{
+			Seed:           getEnvAsBool("SEED_DEMO_DATA", false),
+			Days:           getEnvAsInt("DEMO_DATA_DAYS", 7),
+			RequestsPerDay: getEnvAsInt("DEMO_REQUESTS_PER_DAY", 20000),
+		},
+		Plugins: PluginConfig{
+			Commands: getEnv("PLUGINS", ""),
+			Timeout:  getEnvAsDuration("PLUGIN_TIMEOUT", 5*time.Second),
+		},
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
 
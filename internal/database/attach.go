@@ -0,0 +1,238 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// attachedIDOffset separates the request IDs of attached databases, the requests of the Nth one get IDs from
+// N * attachedIDOffset so they don't collide with local ones
+const attachedIDOffset = 1 << 40
+
+// attachNamePattern matches the names of attached databases, they are SQL schema names
+var attachNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// attachDrivers numbers the drivers registered for attached databases, driver names must be unique
+var attachDrivers atomic.Int64
+
+// AttachedDatabase is the LogLynx database of another host, read along the local one (ATTACH_DATABASES)
+type AttachedDatabase struct {
+	Name string // Source host of its requests
+	Path string
+}
+
+// ParseAttachedDatabases parses comma-separated "name=path" entries, e.g. "web1=/data/web1.db,web2=/data/web2.db"
+func ParseAttachedDatabases(value string) ([]AttachedDatabase, error) {
+	var attached []AttachedDatabase
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, path, found := strings.Cut(entry, "=")
+		name, path = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(path)
+		if !found || path == "" {
+			return nil, fmt.Errorf("invalid attached database %q (expected name=path)", entry)
+		}
+		if !attachNamePattern.MatchString(name) || name == "main" || name == "temp" {
+			return nil, fmt.Errorf("invalid attached database name %q (lowercase letters, digits and '_', starting with a letter)", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("attached database %q listed twice", name)
+		}
+		seen[name] = true
+		attached = append(attached, AttachedDatabase{Name: name, Path: path})
+	}
+	return attached, nil
+}
+
+// LocalSourceHost is the source host of the local requests when databases are attached, the host name
+func LocalSourceHost() string {
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "local"
+}
+
+// attachStatements returns the statements run on each read connection to merge attached databases: the
+// databases are attached read-only and a temporary http_requests view, shadowing the local table, adds
+// their requests with a source_host column. Attached databases only have to share the columns the local
+// one has, columns they lack read as empty, so instances on older versions can be attached.
+// A client_ips view merges the distinct IPs of all databases for IP search the same way.
+func attachStatements(path string, attached []AttachedDatabase) ([]string, error) {
+	statements := make([]string, 0, len(attached)+1)
+	for _, database := range attached {
+		if _, err := os.Stat(database.Path); err != nil {
+			return nil, fmt.Errorf("attached database %s: %w", database.Name, err)
+		}
+		statements = append(statements, fmt.Sprintf("ATTACH DATABASE %s AS %s", sqlString("file:"+database.Path+"?mode=ro"), database.Name))
+	}
+
+	db, err := sql.Open(DriverName, path+"?_query_only=true")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// ATTACH applies to one connection
+	db.SetMaxOpenConns(1)
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return nil, fmt.Errorf("failed to attach database: %w", err)
+		}
+	}
+
+	columns, err := tableColumns(db, "main")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("local database has no http_requests table")
+	}
+
+	selects := []string{fmt.Sprintf("SELECT %s, %s AS source_host FROM main.http_requests", strings.Join(quotedNames(columns), ", "), sqlString(LocalSourceHost()))}
+	for i, database := range attached {
+		available, err := tableColumns(db, database.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(available) == 0 {
+			return nil, fmt.Errorf("attached database %s has no http_requests table", database.Name)
+		}
+		has := make(map[string]bool, len(available))
+		for _, column := range available {
+			has[column.name] = true
+		}
+
+		expressions := make([]string, 0, len(columns))
+		for _, column := range columns {
+			switch {
+			case column.name == "id":
+				expressions = append(expressions, fmt.Sprintf(`"id" + %d AS "id"`, int64(i+1)*attachedIDOffset))
+			case has[column.name]:
+				expressions = append(expressions, `"`+column.name+`"`)
+			default:
+				expressions = append(expressions, column.empty()+` AS "`+column.name+`"`)
+			}
+		}
+		selects = append(selects, fmt.Sprintf("SELECT %s, %s AS source_host FROM %s.http_requests", strings.Join(expressions, ", "), sqlString(database.Name), database.Name))
+	}
+
+	statements = append(statements, "CREATE TEMP VIEW http_requests AS "+strings.Join(selects, " UNION ALL "))
+
+	// Databases of versions without the client_ips table list the IPs of their requests
+	ips := make([]string, 0, len(attached)+1)
+	for _, schema := range append([]string{"main"}, attachedNames(attached)...) {
+		exists, err := hasTable(db, schema, "client_ips")
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			ips = append(ips, fmt.Sprintf("SELECT ip, first_seen, last_seen FROM %s.client_ips", schema))
+		} else {
+			ips = append(ips, fmt.Sprintf("SELECT client_ip, MIN(timestamp), MAX(timestamp) FROM %s.http_requests GROUP BY client_ip", schema))
+		}
+	}
+	return append(statements, "CREATE TEMP VIEW client_ips AS SELECT ip, MIN(first_seen) AS first_seen, MAX(last_seen) AS last_seen FROM ("+
+		strings.Join(ips, " UNION ALL ")+") GROUP BY ip"), nil
+}
+
+func attachedNames(attached []AttachedDatabase) []string {
+	names := make([]string, 0, len(attached))
+	for _, database := range attached {
+		names = append(names, database.Name)
+	}
+	return names
+}
+
+// hasTable returns whether a table exists in a schema
+func hasTable(db *sql.DB, schema string, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ?", schema), table).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the tables of %s: %w", schema, err)
+	}
+	return count > 0, nil
+}
+
+// tableColumn is a column of http_requests in one database
+type tableColumn struct {
+	name     string
+	dataType string
+}
+
+// empty returns the value of the column for databases lacking it
+func (c tableColumn) empty() string {
+	dataType := strings.ToLower(c.dataType)
+	for _, numeric := range []string{"int", "real", "floa", "doub", "numeric", "bool", "decimal"} {
+		if strings.Contains(dataType, numeric) {
+			return "0"
+		}
+	}
+	if strings.Contains(dataType, "date") || strings.Contains(dataType, "time") {
+		return "NULL"
+	}
+	return "''"
+}
+
+// tableColumns returns the columns of http_requests in a schema, none when the table doesn't exist
+func tableColumns(db *sql.DB, schema string) ([]tableColumn, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name, type FROM pragma_table_info('http_requests', '%s')", schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the columns of %s.http_requests: %w", schema, err)
+	}
+	defer rows.Close()
+
+	var columns []tableColumn
+	for rows.Next() {
+		var column tableColumn
+		if err := rows.Scan(&column.name, &column.dataType); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func quotedNames(columns []tableColumn) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, `"`+column.name+`"`)
+	}
+	return names
+}
+
+// sqlString quotes a value as an SQL string literal
+func sqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// registerAttachDriver registers a driver whose connections run the attach statements, returning its name
+// The statements run with query_only lifted: the temporary view is the only thing they write.
+func registerAttachDriver(statements []string) string {
+	name := fmt.Sprintf("%s_attach_%d", DriverName, attachDrivers.Add(1))
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := registerFunctions(conn); err != nil {
+				return err
+			}
+			if _, err := conn.Exec("PRAGMA query_only = 0", nil); err != nil {
+				return err
+			}
+			for _, statement := range statements {
+				if _, err := conn.Exec(statement, nil); err != nil {
+					return err
+				}
+			}
+			_, err := conn.Exec("PRAGMA query_only = 1", nil)
+			return err
+		},
+	})
+	return name
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"github.com/pterm/pterm"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParseAttachedDatabases(t *testing.T) {
+	attached, err := ParseAttachedDatabases(" web1=/data/web1.db, WEB2 = /data/web2.db ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attached) != 2 || attached[0] != (AttachedDatabase{Name: "web1", Path: "/data/web1.db"}) || attached[1].Name != "web2" {
+		t.Errorf("Unexpected attached databases %+v", attached)
+	}
+
+	for _, value := range []string{"web1", "web1=", "1web=/a.db", "main=/a.db", "web-1=/a.db", "web1=/a.db,web1=/b.db"} {
+		if _, err := ParseAttachedDatabases(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestReadOnlyConnectionAttached(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	dir := t.TempDir()
+	now := time.Now().UTC()
+
+	// The local database and the one of an instance whose requests lack geo_city and client_ips (an older version)
+	createDatabase := func(name string, requests int, dropColumn string) string {
+		path := filepath.Join(dir, name+".db")
+		db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: DriverName, DSN: path}), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RunMigrations(db, MigrationOptions{}, log); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < requests; i++ {
+			request := &models.HTTPRequest{
+				SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(name, i),
+				ClientIP: fmt.Sprint("10.0.", len(name), ".1"), Method: "GET", Host: "example.com", Path: "/", StatusCode: 200, ResponseSize: 100,
+				GeoCity: "Berlin",
+			}
+			if err := db.Create(request).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if dropColumn != "" {
+			if err := db.Exec("ALTER TABLE http_requests DROP COLUMN " + dropColumn).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Exec("DROP TABLE client_ips").Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+		return path
+	}
+	local := createDatabase("local", 2, "")
+	web1 := createDatabase("web1", 3, "geo_city")
+
+	readDB, err := NewReadOnlyConnection(&Config{Path: local, ReadMaxOpenConns: 2, Attached: []AttachedDatabase{{Name: "web1", Path: web1}}}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statsRepo := repositories.NewStatsRepository(readDB, log, 24, nil, nil, nil)
+
+	hosts, err := statsRepo.GetSourceHostStats(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts[0].SourceHost != "web1" || hosts[0].Requests != 3 || hosts[1].SourceHost != LocalSourceHost() || hosts[1].Requests != 2 {
+		t.Fatalf("Unexpected source hosts %+v", hosts)
+	}
+	if hosts[0].Bandwidth != 300 || hosts[0].LastSeen.IsZero() {
+		t.Errorf("Unexpected web1 stats %+v", hosts[0])
+	}
+
+	// Other stats merge both databases, IDs of attached requests don't collide with local ones
+	summary, err := statsRepo.GetSummary(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalRequests != 5 {
+		t.Errorf("Expected 5 merged requests, got %d", summary.TotalRequests)
+	}
+	var ids []int64
+	if err := readDB.Model(&models.HTTPRequest{}).Distinct().Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 5 {
+		t.Errorf("Expected 5 distinct IDs, got %v", ids)
+	}
+	var cities []string
+	if err := readDB.Model(&models.HTTPRequest{}).Where("source_host = ?", "web1").Distinct().Pluck("geo_city", &cities).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(cities) != 1 || cities[0] != "" {
+		t.Errorf("Expected an empty geo_city for the older database, got %v", cities)
+	}
+
+	// IP search finds the IPs of both databases
+	ips, err := statsRepo.SearchIPs("10.0.", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || ips[0].IPAddress != "10.0.4.1" || ips[0].Hits != 3 || ips[1].IPAddress != "10.0.5.1" || ips[1].Hits != 2 {
+		t.Errorf("Unexpected IP search results %+v", ips)
+	}
+
+	// The attached database is read-only
+	if err := readDB.Exec("DELETE FROM web1.http_requests").Error; err == nil {
+		t.Error("Expected writes to the attached database to fail")
+	}
+
+	if _, err := NewReadOnlyConnection(&Config{Path: local, Attached: []AttachedDatabase{{Name: "web2", Path: filepath.Join(dir, "missing.db")}}}, log); err == nil {
+		t.Error("Expected a missing attached database to fail")
+	}
+}
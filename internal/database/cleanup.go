@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"loglynx/internal/database/models"
+	"loglynx/internal/heartbeat"
+
 	"github.com/pterm/pterm"
 	"gorm.io/gorm"
 )
@@ -25,6 +28,7 @@ type CleanupService struct {
 	cleanupTime     string
 	vacuumEnabled   bool
 	coordinator     CoordinatorController
+	pinger          *heartbeat.Pinger // Optional, pinged after each successful cleanup
 	stopChan        chan struct{}
 	running         bool
 	// Stats tracking
@@ -44,7 +48,7 @@ type CleanupStats struct {
 }
 
 // NewCleanupService creates a new cleanup service
-func NewCleanupService(db *gorm.DB, logger *pterm.Logger, retentionDays int, cleanupInterval time.Duration, cleanupTime string, vacuumEnabled bool, coordinator CoordinatorController) *CleanupService {
+func NewCleanupService(db *gorm.DB, logger *pterm.Logger, retentionDays int, cleanupInterval time.Duration, cleanupTime string, vacuumEnabled bool, coordinator CoordinatorController, pinger *heartbeat.Pinger) *CleanupService {
 	return &CleanupService{
 		db:              db,
 		logger:          logger,
@@ -53,6 +57,7 @@ func NewCleanupService(db *gorm.DB, logger *pterm.Logger, retentionDays int, cle
 		cleanupTime:     cleanupTime,
 		vacuumEnabled:   vacuumEnabled,
 		coordinator:     coordinator,
+		pinger:          pinger,
 		stopChan:        make(chan struct{}),
 		running:         false,
 	}
@@ -160,6 +165,33 @@ func (s *CleanupService) runCleanup() {
 		return
 	}
 
+	// Rollups of hours that no longer have requests are removed as well
+	rollupCutoff := cutoffDate.UTC().Truncate(time.Hour)
+	if err := s.db.Where("hour < ?", rollupCutoff).Delete(&models.ResponseTimeRollup{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old response time rollups", s.logger.Args("error", err))
+	}
+	if err := s.db.Where("hour < ?", rollupCutoff).Delete(&models.FieldValueRollup{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old field value rollups", s.logger.Args("error", err))
+	}
+	if err := s.db.Where("hour < ?", rollupCutoff).Delete(&models.TrafficRollup{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old traffic rollups", s.logger.Args("error", err))
+	}
+
+	// WAF audit log events follow the retention of the requests they are correlated with
+	if err := s.db.Where("timestamp < ?", cutoffDate).Delete(&models.WAFEvent{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old WAF events", s.logger.Args("error", err))
+	}
+
+	// Coverage of hours that no longer have requests, hours are UTC "2006-01-02 15:00" strings
+	if err := s.db.Where("hour < ?", cutoffDate.UTC().Format("2006-01-02 15:00")).Delete(&models.SourceCoverage{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old source coverage", s.logger.Args("error", err))
+	}
+
+	// IPs only seen in deleted requests no longer come up in IP search
+	if err := s.db.Where("last_seen < ?", cutoffDate).Delete(&models.ClientIP{}).Error; err != nil {
+		s.logger.WithCaller().Warn("Failed to delete old client IPs", s.logger.Args("error", err))
+	}
+
 	cleanupDuration := time.Since(startTime)
 
 	// Update stats
@@ -178,6 +210,9 @@ func (s *CleanupService) runCleanup() {
 	if s.vacuumEnabled && totalDeleted > 0 {
 		s.runVacuum()
 	}
+
+	// Only successful runs ping: the monitor alerts when a daily cleanup is missed or fails
+	s.pinger.Ping(context.Background())
 }
 
 // deleteOldRecords deletes records older than cutoff date in batches
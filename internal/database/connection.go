@@ -3,8 +3,10 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"loglynx/internal/database/repositories"
 	"loglynx/internal/discovery"
+	"loglynx/internal/requestid"
 	"os"
 	"runtime"
 	"strings"
@@ -22,6 +24,31 @@ type Config struct {
 	MaxIdleConns int
 	ConnMaxLife  time.Duration
 
+	// Read-only pool for analytics queries (0 = based on CPU cores)
+	ReadMaxOpenConns int
+
+	// Databases of other hosts merged into the analytics of the read-only pool
+	Attached []AttachedDatabase
+
+	// Query Performance
+	SlowQueryThreshold time.Duration // Queries taking longer are logged and counted as slow (0 = 100ms)
+	QueryStats         *QueryStats   // Collects per-endpoint query stats, optional
+
+	// Full-text search index on path, referer and user agent (needs SQLite with FTS5)
+	SearchIndex bool
+
+	// Slow request thresholds, the slow requests partial index is built for the lowest one (nil = default)
+	SlowPolicy *repositories.SlowPolicy
+
+	// Performance indexes created on http_requests (empty = analytics, all indexes)
+	IndexProfile repositories.IndexProfile
+
+	// Schema migrations
+	MigrationsDryRun  bool  // Log pending migrations and stop with ErrMigrationDryRun
+	MigrationBackup   bool  // Copy the database next to it before migrating
+	MigrationMaxRows  int64 // Refuse migrations rewriting requests above this many rows (0 = no limit)
+	RollbackToVersion int   // Revert migrations above this version and stop with ErrSchemaRolledBack (0 = disabled)
+
 	// Pool Monitoring
 	PoolMonitoringEnabled   bool
 	PoolMonitoringInterval  time.Duration
@@ -29,20 +56,30 @@ type Config struct {
 	AutoTuning              bool
 }
 
+// slowQueryThreshold returns the configured slow query threshold or the 100ms default
+func (cfg *Config) slowQueryThreshold() time.Duration {
+	if cfg.SlowQueryThreshold <= 0 {
+		return 100 * time.Millisecond
+	}
+	return cfg.SlowQueryThreshold
+}
+
 // SlowQueryLogger logs slow database queries for performance monitoring
 type SlowQueryLogger struct {
 	logger            *pterm.Logger
 	slowThreshold     time.Duration
 	logLevel          logger.LogLevel
 	ignoreNotFoundErr bool
+	stats             *QueryStats // Per-endpoint query stats, optional
 }
 
-func NewSlowQueryLogger(ptermLogger *pterm.Logger, slowThreshold time.Duration) *SlowQueryLogger {
+func NewSlowQueryLogger(ptermLogger *pterm.Logger, slowThreshold time.Duration, stats *QueryStats) *SlowQueryLogger {
 	return &SlowQueryLogger{
 		logger:            ptermLogger,
 		slowThreshold:     slowThreshold,
 		logLevel:          logger.Warn,
 		ignoreNotFoundErr: true,
+		stats:             stats,
 	}
 }
 
@@ -73,6 +110,14 @@ func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func()
 	elapsed := time.Since(begin)
 	sql, rows := fc()
 
+	if l.stats != nil {
+		failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !strings.Contains(err.Error(), "UNIQUE constraint failed")
+		l.stats.RecordQuery(QueryEndpoint(ctx), elapsed, failed)
+		if err == nil {
+			l.stats.RecordStatement(sql)
+		}
+	}
+
 	// Log slow queries (debug level to avoid console noise in normal runs)
 	if elapsed >= l.slowThreshold {
 		l.logger.Debug("SLOW QUERY DETECTED",
@@ -80,6 +125,8 @@ func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func()
 				"duration_ms", elapsed.Milliseconds(),
 				"rows", rows,
 				"sql", sql,
+				"endpoint", QueryEndpoint(ctx),
+				"request_id", requestid.FromContext(ctx),
 			))
 	} else if l.logLevel >= logger.Info {
 		// Trace all queries in debug mode
@@ -106,6 +153,8 @@ func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func()
 				"error", err,
 				"duration_ms", elapsed.Milliseconds(),
 				"sql", sql,
+				"endpoint", QueryEndpoint(ctx),
+				"request_id", requestid.FromContext(ctx),
 			))
 	}
 }
@@ -128,10 +177,10 @@ func NewConnection(cfg *Config, logger *pterm.Logger) (*gorm.DB, error) {
 	logger.Debug("Permission to access database file granted.", logger.Args("path", cfg.Path))
 	logger.Debug("Initialization of the database with optimized settings (WAL mode, page_size=4096).")
 
-	// Create slow query logger (log queries taking >100ms)
-	slowQueryLogger := NewSlowQueryLogger(logger, 100*time.Millisecond)
+	// Create slow query logger (log queries taking >100ms by default)
+	slowQueryLogger := NewSlowQueryLogger(logger, cfg.slowQueryThreshold(), cfg.QueryStats)
 
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: DriverName, DSN: dsn}), &gorm.Config{
 		PrepareStmt: true,
 		Logger:      slowQueryLogger,
 	})
@@ -186,12 +235,39 @@ func NewConnection(cfg *Config, logger *pterm.Logger) (*gorm.DB, error) {
 		))
 
 	// Run migrations
+	migrationOpts := MigrationOptions{DryRun: cfg.MigrationsDryRun, MaxRewriteRows: cfg.MigrationMaxRows}
+	if cfg.MigrationBackup {
+		migrationOpts.Backup = FileBackup(cfg.Path, logger)
+	}
+	if cfg.RollbackToVersion > 0 {
+		if err := RollbackMigrations(db, cfg.RollbackToVersion, migrationOpts, logger); err != nil {
+			return nil, err
+		}
+		return nil, ErrSchemaRolledBack
+	}
 	logger.Trace("Running database migrations.")
-	if err := RunMigrations(db); err != nil {
+	if err := RunMigrations(db, migrationOpts, logger); err != nil {
+		if errors.Is(err, ErrMigrationDryRun) {
+			return nil, err
+		}
+		if errors.Is(err, ErrSchemaTooNew) {
+			logger.Fatal("This database was upgraded by a newer LogLynx version, refusing to start",
+				logger.Args("error", err, "hint", "run the newer version, or roll it back with DB_ROLLBACK_TO_VERSION using the newer version"))
+		}
+		if errors.Is(err, ErrLongMigration) {
+			logger.Fatal("The database needs a long migration, refusing to start",
+				logger.Args("error", err, "hint", "run 'loglynx migrate' during a maintenance window, or raise DB_MIGRATION_MAX_ROWS (0 = no limit)"))
+		}
 		logger.WithCaller().Fatal("Failed to run database migrations.", logger.Args("error", err))
 		// Fatal() terminates the program, so no code after this will execute
 	}
 
+	// Create or remove the full-text search index
+	EnsureSearchIndex(db, cfg.SearchIndex, logger)
+
+	// Create the slow requests index, or rebuild it when the lowest threshold changed
+	EnsureSlowRequestIndex(db, cfg.SlowPolicy, logger)
+
 	// Check if database is empty (first load)
 	// We need to import models for this check
 	var count int64
@@ -204,7 +280,7 @@ func NewConnection(cfg *Config, logger *pterm.Logger) (*gorm.DB, error) {
 	} else {
 		// Database has data - create/verify indexes now
 		logger.Debug("Existing data found - verifying database indexes")
-		if err := OptimizeDatabase(db, logger); err != nil {
+		if err := OptimizeDatabase(db, cfg.IndexProfile, logger); err != nil {
 			logger.Warn("Database optimization had warnings", logger.Args("error", err))
 			// Don't fail on optimization errors, just warn
 		}
@@ -248,4 +324,54 @@ func NewConnection(cfg *Config, logger *pterm.Logger) (*gorm.DB, error) {
 
 	logger.Info("Database connection established successfully.")
 	return db, nil
-}
\ No newline at end of file
+}
+
+// NewReadOnlyConnection opens a second pool on the same database for analytics queries
+// Dashboard and stats queries go through this pool, so long scans don't hold writer connections
+// needed by ingestion. In WAL mode readers don't block the writer and see the last committed data.
+// Migrations must have run on the writer connection (NewConnection) first.
+// With attached databases, the requests of their hosts are merged into the ones of this pool (see attachStatements).
+func NewReadOnlyConnection(cfg *Config, logger *pterm.Logger) (*gorm.DB, error) {
+	// - _query_only rejects writes on these connections
+	// - journal mode isn't set here: WAL is persistent and was enabled by the writer connection
+	dsn := cfg.Path + "?_query_only=true&_cache_size=-64000&_busy_timeout=5000"
+
+	driverName := DriverName
+	if len(cfg.Attached) > 0 {
+		statements, err := attachStatements(cfg.Path, cfg.Attached)
+		if err != nil {
+			return nil, err
+		}
+		driverName = registerAttachDriver(statements)
+	}
+
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: driverName, DSN: dsn}), &gorm.Config{
+		PrepareStmt: true,
+		Logger:      NewSlowQueryLogger(logger, cfg.slowQueryThreshold(), cfg.QueryStats),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read-only connection: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read-only database instance: %w", err)
+	}
+
+	// Readers run in parallel in WAL mode, so the pool scales with CPU cores
+	maxOpenConns := cfg.ReadMaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = max(runtime.NumCPU()*2, 4)
+	}
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(maxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLife)
+
+	logger.Debug("Read-only connection pool configured",
+		logger.Args(
+			"max_open_conns", maxOpenConns,
+			"conn_max_life", cfg.ConnMaxLife,
+		))
+
+	return db, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package database
+
+import (
+	"errors"
+)
+
+// ErrDiskSpaceUnsupported is returned where free disk space can't be determined
+var ErrDiskSpaceUnsupported = errors.New("disk space check not supported on this platform")
+
+// DiskSpace is not supported on this platform
+func DiskSpace(dbPath string) (free uint64, total uint64, err error) {
+	return 0, 0, ErrDiskSpaceUnsupported
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package database
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// DiskSpace returns the free (available to unprivileged users) and total bytes
+// of the filesystem holding the database file
+func DiskSpace(dbPath string) (free uint64, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(dbPath), &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package database
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// DiskSpace returns the free (available to the current user) and total bytes
+// of the volume holding the database file
+func DiskSpace(dbPath string) (free uint64, total uint64, err error) {
+	dir, err := windows.UTF16PtrFromString(filepath.Dir(dbPath))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &free, &total, &totalFree); err != nil {
+		return 0, 0, err
+	}
+	return free, total, nil
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"database/sql"
+
+	"loglynx/internal/filter"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the SQLite driver with LogLynx functions, used by every connection
+const DriverName = "sqlite3_loglynx"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{ConnectHook: registerFunctions})
+}
+
+// registerFunctions adds the LogLynx functions to a connection
+func registerFunctions(conn *sqlite3.SQLiteConn) error {
+	// SQLite parses "X REGEXP Y" but leaves regexp() undefined, service filters with match_type=regex use it
+	return conn.RegisterFunc("regexp", sqliteRegexp, true)
+}
+
+// sqliteRegexp implements regexp(pattern, value), patterns are validated before reaching queries
+func sqliteRegexp(pattern, value string) (bool, error) {
+	re, err := filter.CompileRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
@@ -1,15 +1,481 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/enrichment"
 
+	"github.com/pterm/pterm"
 	"gorm.io/gorm"
 )
 
-func RunMigrations(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.LogSource{},
-		&models.HTTPRequest{},
-		&models.IPReputation{},
-	)
-}
\ No newline at end of file
+var (
+	// ErrMigrationDryRun is returned after a dry run listed the pending migrations without applying them
+	ErrMigrationDryRun = errors.New("migration dry run: pending migrations were not applied")
+	// ErrSchemaRolledBack is returned after migrations were reverted, the schema no longer matches the models
+	ErrSchemaRolledBack = errors.New("schema rolled back: start a matching LogLynx version")
+	// ErrSchemaTooNew is returned when the database was migrated by a newer LogLynx version
+	ErrSchemaTooNew = errors.New("database schema is newer than this LogLynx version")
+	// ErrLongMigration is returned when a pending migration rewrites more requests than allowed at startup
+	ErrLongMigration = errors.New("long migration required")
+)
+
+// Migration is one versioned schema change
+// Up runs in a transaction with the insert of its schema_version row, so a failed migration leaves no trace.
+// Columns added to models need a migration: the initial schema only creates what existed when it ran.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // nil when the migration can't be reverted
+
+	// RewritesRequests marks migrations updating every request: they can take minutes on large databases
+	RewritesRequests bool
+}
+
+// BackupFunc copies the database before migrations change it, version is the schema version being left
+type BackupFunc func(db *gorm.DB, version int) error
+
+// MigrationOptions controls how pending migrations are applied
+type MigrationOptions struct {
+	DryRun bool       // Only log pending migrations
+	Backup BackupFunc // Called before migrating a database holding data, optional
+
+	// MaxRewriteRows refuses migrations rewriting requests when the database holds more (0 = no limit)
+	MaxRewriteRows int64
+}
+
+// migrations are applied in version order, versions are never reused or renumbered
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			// Databases created before versioned migrations already have the tables, AutoMigrate adds missing columns
+			return tx.AutoMigrate(
+				&models.LogSource{},
+				&models.HTTPRequest{},
+				&models.IPReputation{},
+				&models.ResponseTimeRollup{},
+				&models.RollupCheckpoint{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&models.RollupCheckpoint{},
+				&models.ResponseTimeRollup{},
+				&models.IPReputation{},
+				&models.HTTPRequest{},
+				&models.LogSource{},
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "backfill_eu_flags",
+		Up: func(tx *gorm.DB) error {
+			// Requests and reputations enriched before the EU flag was stored get it from their country
+			err := tx.Model(&models.HTTPRequest{}).
+				Where("geo_country IN ?", enrichment.EUCountryCodes()).
+				Update("geo_eu", true).Error
+			if err != nil {
+				return err
+			}
+			return tx.Model(&models.IPReputation{}).
+				Where("country IN ?", enrichment.EUCountryCodes()).
+				Update("is_eu", true).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// Data only, the flags stay valid
+			return nil
+		},
+		RewritesRequests: true,
+	},
+	{
+		Version: 3,
+		Name:    "waf_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.WAFEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.WAFEvent{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "api_key_hash",
+		Up: func(tx *gorm.DB) error {
+			// API consumers are identified by the authenticated user or a fingerprint of their API key
+			// New databases already have the column: the initial schema migrates the current model
+			if tx.Migrator().HasColumn(&models.HTTPRequest{}, "APIKeyHash") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.HTTPRequest{}, "APIKeyHash")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Older versions ignore the column, dropping it would rebuild the requests table
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "upstream_addr_header_bytes",
+		Up: func(tx *gorm.DB) error {
+			// Origin instance and header sizes, for origin selection issues and header bloat in backend analytics
+			for _, field := range []string{"UpstreamAddr", "RequestHeaderBytes", "ResponseHeaderBytes"} {
+				if tx.Migrator().HasColumn(&models.HTTPRequest{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&models.HTTPRequest{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Older versions ignore the columns, dropping them would rebuild the requests table
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "settings",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Setting{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Setting{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "request_labels",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.RequestLabel{}); err != nil {
+				return err
+			}
+			// Labels go away with their request, whichever way it is deleted (retention cleanup, source removal)
+			return tx.Exec(`CREATE TRIGGER IF NOT EXISTS request_labels_delete AFTER DELETE ON http_requests BEGIN
+				DELETE FROM request_labels WHERE request_hash = old.request_hash;
+			END`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS request_labels_delete").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.RequestLabel{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "service_incidents",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.ServiceIncident{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.ServiceIncident{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "log_source_running",
+		Up: func(tx *gorm.DB) error {
+			// Marks sources whose processor was running, to report the recovery after an unclean shutdown
+			if tx.Migrator().HasColumn(&models.LogSource{}, "ProcessorRunning") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.LogSource{}, "ProcessorRunning")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Older versions ignore the column
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "investigations",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.Investigation{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.Investigation{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "source_coverage",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.SourceCoverage{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.SourceCoverage{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "client_ips",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&models.ClientIP{}); err != nil {
+				return err
+			}
+			// IPs of new requests are added by a trigger, whichever way requests are inserted
+			err := tx.Exec(`CREATE TRIGGER IF NOT EXISTS client_ips_insert AFTER INSERT ON http_requests BEGIN
+				INSERT INTO client_ips (ip, first_seen, last_seen) VALUES (new.client_ip, new.timestamp, new.timestamp)
+				ON CONFLICT (ip) DO UPDATE SET last_seen = MAX(last_seen, excluded.last_seen);
+			END`).Error
+			if err != nil {
+				return err
+			}
+			return tx.Exec(`INSERT OR IGNORE INTO client_ips (ip, first_seen, last_seen)
+				SELECT client_ip, MIN(timestamp), MAX(timestamp) FROM http_requests GROUP BY client_ip`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS client_ips_insert").Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.ClientIP{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "field_value_rollups",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.FieldValueRollup{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("name = ?", repositories.FieldValueRollupName).Delete(&models.RollupCheckpoint{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.FieldValueRollup{})
+		},
+	},
+	{
+		Version: 14,
+		Name:    "traffic_rollups",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&models.TrafficRollup{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Where("name = ?", repositories.TrafficRollupName).Delete(&models.RollupCheckpoint{}).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.TrafficRollup{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "dedup_instrumentation",
+		Up: func(tx *gorm.DB) error {
+			// In-batch duplicate and hash collision counters of each source
+			for _, column := range []string{"InBatchDuplicates", "HashCollisions", "LastHashCollision", "LastHashCollisionAt"} {
+				if tx.Migrator().HasColumn(&models.LogSource{}, column) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&models.LogSource{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Older versions ignore the columns
+			return nil
+		},
+	},
+}
+
+// LatestSchemaVersion returns the version of the last known migration
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// CurrentSchemaVersion returns the highest applied migration, 0 for a new database
+func CurrentSchemaVersion(db *gorm.DB) (int, error) {
+	if err := db.AutoMigrate(&models.SchemaVersion{}); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.Model(&models.SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// PendingMigrations returns the migrations not applied yet
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	current, err := CurrentSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("%w: schema version %d, this version supports up to %d", ErrSchemaTooNew, current, LatestSchemaVersion())
+	}
+
+	pending := make([]Migration, 0)
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// RunMigrations applies pending migrations in order
+// With DryRun, pending migrations are logged and ErrMigrationDryRun is returned.
+func RunMigrations(db *gorm.DB, opts MigrationOptions, logger *pterm.Logger) error {
+	current, err := CurrentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		logger.Info("Migration dry run", logger.Args("schema_version", current, "latest", LatestSchemaVersion(), "pending", len(pending)))
+		for _, migration := range pending {
+			logger.Info("Pending migration",
+				logger.Args("version", migration.Version, "name", migration.Name, "rewrites_requests", migration.RewritesRequests))
+		}
+		return ErrMigrationDryRun
+	}
+	if len(pending) == 0 {
+		logger.Trace("Database schema up to date", logger.Args("schema_version", current))
+		return nil
+	}
+
+	if err := checkLongMigrations(db, pending, opts.MaxRewriteRows); err != nil {
+		return err
+	}
+
+	if opts.Backup != nil && db.Migrator().HasTable(&models.HTTPRequest{}) {
+		if err := opts.Backup(db, current); err != nil {
+			return fmt.Errorf("pre-migration backup failed: %w", err)
+		}
+	}
+
+	for _, migration := range pending {
+		startTime := time.Now()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaVersion{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		logger.Debug("Applied migration",
+			logger.Args("version", migration.Version, "name", migration.Name, "elapsed_ms", time.Since(startTime).Milliseconds()))
+	}
+
+	logger.Info("Database schema migrated", logger.Args("from_version", current, "to_version", LatestSchemaVersion()))
+	return nil
+}
+
+// checkLongMigrations refuses pending migrations rewriting more than maxRows requests
+// The highest request ID is used as the row count: it's instant, and never lower than the number of rows.
+func checkLongMigrations(db *gorm.DB, pending []Migration, maxRows int64) error {
+	if maxRows <= 0 || !db.Migrator().HasTable(&models.HTTPRequest{}) {
+		return nil
+	}
+	for _, migration := range pending {
+		if !migration.RewritesRequests {
+			continue
+		}
+		var rows int64
+		if err := db.Model(&models.HTTPRequest{}).Select("COALESCE(MAX(id), 0)").Scan(&rows).Error; err != nil {
+			return err
+		}
+		if rows > maxRows {
+			return fmt.Errorf("%w: migration %d (%s) rewrites up to %d requests (limit %d)",
+				ErrLongMigration, migration.Version, migration.Name, rows, maxRows)
+		}
+		return nil
+	}
+	return nil
+}
+
+// RollbackMigrations reverts the applied migrations above target, newest first
+// With DryRun, the migrations to revert are logged and ErrMigrationDryRun is returned.
+func RollbackMigrations(db *gorm.DB, target int, opts MigrationOptions, logger *pterm.Logger) error {
+	current, err := CurrentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if target < 0 || target >= current {
+		return fmt.Errorf("invalid rollback version %d: schema version is %d", target, current)
+	}
+
+	revert := make([]Migration, 0)
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= target || migration.Version > current {
+			continue
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("migration %d (%s) can't be reverted", migration.Version, migration.Name)
+		}
+		revert = append(revert, migration)
+	}
+
+	if opts.DryRun {
+		for _, migration := range revert {
+			logger.Info("Migration to revert", logger.Args("version", migration.Version, "name", migration.Name))
+		}
+		return ErrMigrationDryRun
+	}
+
+	if opts.Backup != nil {
+		if err := opts.Backup(db, current); err != nil {
+			return fmt.Errorf("pre-rollback backup failed: %w", err)
+		}
+	}
+
+	for _, migration := range revert {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaVersion{}, migration.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		logger.Info("Reverted migration", logger.Args("version", migration.Version, "name", migration.Name))
+	}
+	return nil
+}
+
+// FileBackup returns a backup hook copying the database next to it with VACUUM INTO
+// Backups are named <path>.v<version>-<timestamp>.bak and are never deleted automatically.
+func FileBackup(path string, logger *pterm.Logger) BackupFunc {
+	return func(db *gorm.DB, version int) error {
+		// VACUUM INTO writes a compacted copy: the used pages are enough
+		var pageSize, pageCount, freePages int64
+		db.Raw("PRAGMA page_size").Scan(&pageSize)
+		db.Raw("PRAGMA page_count").Scan(&pageCount)
+		db.Raw("PRAGMA freelist_count").Scan(&freePages)
+		needed := uint64((pageCount - freePages) * pageSize)
+		if free, _, err := DiskSpace(path); err == nil && free < needed {
+			return fmt.Errorf("not enough disk space for the backup (%d bytes needed, %d free), free space or set DB_MIGRATION_BACKUP=false", needed, free)
+		}
+
+		target := fmt.Sprintf("%s.v%d-%s.bak", path, version, time.Now().Format("20060102-150405"))
+		if _, err := os.Stat(target); err == nil {
+			return fmt.Errorf("backup %s already exists", target)
+		}
+
+		startTime := time.Now()
+		if err := db.Exec("VACUUM INTO ?", target).Error; err != nil {
+			return err
+		}
+		logger.Info("💾 Database backed up before migration",
+			logger.Args("backup", target, "schema_version", version, "elapsed_seconds", time.Since(startTime).Seconds()))
+		return nil
+	}
+}
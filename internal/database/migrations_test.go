@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrations(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	path := filepath.Join(t.TempDir(), "migrations.db")
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: DriverName, DSN: path}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := func() int {
+		current, err := CurrentSchemaVersion(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return current
+	}
+	backups := 0
+	backup := func(db *gorm.DB, version int) error {
+		backups++
+		return FileBackup(path, log)(db, version)
+	}
+
+	// A new database has nothing to back up
+	if err := RunMigrations(db, MigrationOptions{Backup: backup}, log); err != nil {
+		t.Fatal(err)
+	}
+	if version() != LatestSchemaVersion() || backups != 0 || !db.Migrator().HasTable(&models.HTTPRequest{}) {
+		t.Fatalf("Expected a new database at version %d without backup, got version %d and %d backups", LatestSchemaVersion(), version(), backups)
+	}
+	if err := RunMigrations(db, MigrationOptions{Backup: backup}, log); err != nil || backups != 0 {
+		t.Fatalf("Expected nothing to apply, got %v and %d backups", err, backups)
+	}
+
+	if err := RollbackMigrations(db, 1, MigrationOptions{}, log); err != nil {
+		t.Fatal(err)
+	}
+	if version() != 1 {
+		t.Fatalf("Expected version 1 after rollback, got %d", version())
+	}
+
+	// A dry run leaves the schema alone
+	if err := RunMigrations(db, MigrationOptions{DryRun: true, Backup: backup}, log); !errors.Is(err, ErrMigrationDryRun) {
+		t.Fatalf("Expected ErrMigrationDryRun, got %v", err)
+	}
+	if version() != 1 || backups != 0 {
+		t.Fatalf("Expected the dry run to change nothing, got version %d and %d backups", version(), backups)
+	}
+
+	// Migrations rewriting requests are refused above the row limit
+	for i := 0; i < 3; i++ {
+		if err := db.Create(&models.HTTPRequest{SourceName: "test", RequestHash: fmt.Sprint(i), Timestamp: time.Now()}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RunMigrations(db, MigrationOptions{Backup: backup, MaxRewriteRows: 2}, log); !errors.Is(err, ErrLongMigration) {
+		t.Fatalf("Expected ErrLongMigration, got %v", err)
+	}
+	if version() != 1 || backups != 0 {
+		t.Fatalf("Expected the refused migration to change nothing, got version %d and %d backups", version(), backups)
+	}
+
+	if err := RunMigrations(db, MigrationOptions{Backup: backup}, log); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(path + ".v1-*.bak")
+	if version() != LatestSchemaVersion() || backups != 1 || len(matches) != 1 {
+		t.Fatalf("Expected one backup of version 1, got version %d, %d backups, files %v", version(), backups, matches)
+	}
+
+	// A database migrated by a newer version is refused
+	if err := db.Create(&models.SchemaVersion{Version: LatestSchemaVersion() + 1, Name: "future"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(db, MigrationOptions{}, log); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ClientIP is a distinct client IP and when it was last seen, kept by a trigger on http_requests
+// IP search matches this table, one row per IP, instead of scanning every request.
+type ClientIP struct {
+	IP        string    `gorm:"primaryKey;column:ip;type:varchar(45)"`
+	FirstSeen time.Time `gorm:"not null"`
+	LastSeen  time.Time `gorm:"not null;index"`
+}
+
+func (ClientIP) TableName() string {
+	return "client_ips"
+}
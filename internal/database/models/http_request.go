@@ -19,6 +19,7 @@ type HTTPRequest struct {
 	ClientIP   string `gorm:"type:varchar(45);not null"` // index created by OptimizeDatabase (IPv6 support)
 	ClientPort int    `gorm:"check:client_port >= 0 AND client_port <= 65535"`
 	ClientUser string `gorm:"type:varchar(255)"` // HTTP authentication user (NPM: remote_user)
+	APIKeyHash string `gorm:"type:varchar(16)"`  // Fingerprint of the API key header (SHA-256 prefix), never the key
 
 	// Request info
 	Method        string `gorm:"type:varchar(10);not null"` // GET, POST, PUT, DELETE, etc.
@@ -48,6 +49,10 @@ type HTTPRequest struct {
 	UserAgent string `gorm:"type:varchar(512)"` // Most user agents are <512 chars
 	Referer   string `gorm:"type:varchar(512)"` // Most referers are <512 chars
 
+	// Header sizes in bytes, 0 when the log format doesn't have them
+	RequestHeaderBytes  int64 `gorm:"check:request_header_bytes >= 0"`
+	ResponseHeaderBytes int64 `gorm:"check:response_header_bytes >= 0"`
+
 	// Parsed User-Agent fields
 	Browser        string `gorm:"type:varchar(50)"` // index created by OptimizeDatabase
 	BrowserVersion string `gorm:"type:varchar(20)"`
@@ -62,6 +67,7 @@ type HTTPRequest struct {
 	RouterName          string `gorm:"type:varchar(255)"`                                    // Traefik: RouterName, NPM: server_name, Caddy: logger name - index created by OptimizeDatabase
 	UpstreamStatus      int    `gorm:"check:upstream_status >= 0 AND upstream_status < 600"` // Upstream/backend response status
 	UpstreamContentType string `gorm:"type:varchar(255)"`                                    // Origin/backend Content-Type (origin_Content-Type in Traefik)
+	UpstreamAddr        string `gorm:"type:varchar(255)"`                                    // Origin instance that served the request (Traefik: ServiceAddr, NGINX: upstream_addr)
 	ClientHostname      string `gorm:"type:varchar(255)"`                                    // Client hostname (if reverse DNS available, from ClientHost)
 
 	// TLS info
@@ -78,6 +84,7 @@ type HTTPRequest struct {
 	GeoCity    string `gorm:"type:varchar(100)"`
 	GeoLat     float64
 	GeoLon     float64
+	GeoEU      bool // Country is in the European Union (MaxMind is_in_european_union)
 	ASN        int
 	ASNOrg     string `gorm:"type:varchar(255)"`
 
@@ -86,6 +93,9 @@ type HTTPRequest struct {
 	// Examples: Traefik middlewares, NPM custom fields, Caddy logger details
 	ProxyMetadata string `gorm:"type:text"` // JSON string for flexible data
 
+	// Labels assigned at ingestion, stored in request_labels with the request
+	Labels map[string]string `gorm:"-" json:",omitempty"`
+
 	CreatedAt time.Time `gorm:"autoCreateTime"` // index created by OptimizeDatabase
 
 	// Foreign key
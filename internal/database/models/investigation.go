@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Investigation is a saved incident analysis: the time range and filters it was done with, pinned IPs and paths
+// and freeform notes
+type Investigation struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	Name        string            `gorm:"type:varchar(200);not null" json:"name"`
+	From        time.Time         `gorm:"column:range_start;not null" json:"from"`
+	To          time.Time         `gorm:"column:range_end;not null" json:"to"`
+	Filters     map[string]string `gorm:"serializer:json;type:text" json:"filters"` // API query parameters, e.g. service=blog
+	PinnedIPs   []string          `gorm:"serializer:json;type:text" json:"pinned_ips"`
+	PinnedPaths []string          `gorm:"serializer:json;type:text" json:"pinned_paths"`
+	Notes       string            `gorm:"type:text" json:"notes"` // Markdown
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `gorm:"index" json:"updated_at"`
+}
+
+func (Investigation) TableName() string {
+	return "investigations"
+}
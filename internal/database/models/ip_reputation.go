@@ -12,6 +12,7 @@ type IPReputation struct {
 	// GeoIP data
 	Country     string `gorm:"index"`
 	CountryName string
+	IsEU        bool // MaxMind is_in_european_union
 	City        string
 	Latitude    float64
 	Longitude   float64
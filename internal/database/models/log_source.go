@@ -11,7 +11,18 @@ type LogSource struct {
     LastLineContent string
     LastPosition    int64     `gorm:"default:0"`
     LastInode       int64     `gorm:"default:0"` // File inode for identity tracking (SQLite only supports int64)
+    FirstLineFingerprint string // Hash of the file's first line, detects copytruncate and in-place replacement
     LastReadAt      *time.Time
+    // Deduplication counters (persisted so users can verify dedup isn't dropping legitimate traffic)
+    RecordsInserted   int64 `gorm:"default:0"`
+    DuplicatesSkipped int64 `gorm:"default:0"` // Lines dropped because their hash already existed
+    FirstLoadInserts  int64 `gorm:"default:0"` // Records inserted via the first-load fast path
+    InBatchDuplicates int64 `gorm:"default:0"` // Part of DuplicatesSkipped whose hash was already in the same batch
+    HashCollisions    int64 `gorm:"default:0"` // Duplicates whose request differs from the stored one (same hash, different path...)
+    LastHashCollision   string     // Latest collision, "hash: stored request / incoming request"
+    LastHashCollisionAt *time.Time
+    // Set while a processor reads the source, still set at startup when the last one didn't stop (crash, kill, power loss)
+    ProcessorRunning bool `gorm:"default:false"`
     CreatedAt       time.Time
     UpdatedAt       time.Time
 }
@@ -0,0 +1,13 @@
+package models
+
+// RequestLabel is a label assigned to a request at ingestion (LABEL_RULES, transform rule tags), e.g. customer=acme
+// Labels reference requests by their dedup hash, which is known before insert and unique.
+type RequestLabel struct {
+	RequestHash string `gorm:"primaryKey;type:char(64)"`
+	Name        string `gorm:"primaryKey;type:varchar(64);index:idx_request_labels_name_value,priority:1"`
+	Value       string `gorm:"type:varchar(256);not null;index:idx_request_labels_name_value,priority:2"`
+}
+
+func (RequestLabel) TableName() string {
+	return "request_labels"
+}
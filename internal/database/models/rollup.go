@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// ResponseTimeRollup summarises the response times of one service for one hour
+// Sketch is a mergeable quantile sketch (see internal/sketch), so percentiles of any range of hours
+// come from merging rollups instead of scanning requests.
+type ResponseTimeRollup struct {
+	Hour    time.Time `gorm:"primaryKey"` // Start of the hour (UTC)
+	Service string    `gorm:"primaryKey"` // backend_name, backend_url or host, as the "auto" service filter
+	Count   int64     `gorm:"not null"`
+	SumMs   float64   `gorm:"not null"`
+	MinMs   float64   `gorm:"not null"`
+	MaxMs   float64   `gorm:"not null"`
+	Sketch  []byte    `gorm:"not null"`
+}
+
+func (ResponseTimeRollup) TableName() string {
+	return "response_time_rollups"
+}
+
+// FieldValueRollup counts the requests of one value of a filter field (host, browser, ...) over one hour
+// Filter dropdowns and autocomplete sum these instead of grouping every request of the range.
+type FieldValueRollup struct {
+	Hour  time.Time `gorm:"primaryKey"` // Start of the hour (UTC)
+	Field string    `gorm:"primaryKey;type:varchar(32)"`
+	Value string    `gorm:"primaryKey;type:varchar(255)"`
+	Count int64     `gorm:"not null"`
+}
+
+func (FieldValueRollup) TableName() string {
+	return "field_value_rollups"
+}
+
+// TrafficRollup summarises the requests of one service, status class and country over one hour
+// The rows are exported as they are for BI tools, the sketch holds the response times of the timed requests.
+type TrafficRollup struct {
+	Hour          time.Time `gorm:"primaryKey"` // Start of the hour (UTC)
+	Service       string    `gorm:"primaryKey"` // As in ResponseTimeRollup
+	StatusClass   int       `gorm:"primaryKey"` // Status code / 100, e.g. 5 for 5xx
+	Country       string    `gorm:"primaryKey;type:varchar(2)"`
+	Requests      int64     `gorm:"not null"`
+	Bandwidth     int64     `gorm:"not null"`
+	TimedRequests int64     `gorm:"not null"` // Requests with a response time, counted in the sketch
+	SumMs         float64   `gorm:"not null"`
+	MinMs         float64   `gorm:"not null"`
+	MaxMs         float64   `gorm:"not null"`
+	Sketch        []byte
+}
+
+func (TrafficRollup) TableName() string {
+	return "traffic_rollups"
+}
+
+// RollupCheckpoint records which requests and hours a rollup covers
+// Requests ingested later (with a higher ID) mark their hours for rebuild, even when they are old.
+type RollupCheckpoint struct {
+	Name       string    `gorm:"primaryKey"`
+	LastID     uint      `gorm:"not null"` // Highest request ID seen by the last run
+	BuiltUntil time.Time // Hours before this one are rolled up, later hours are still in progress
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (RollupCheckpoint) TableName() string {
+	return "rollup_checkpoints"
+}
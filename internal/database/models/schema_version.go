@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// SchemaVersion records a schema migration applied to the database
+type SchemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ServiceIncident is a period in which a normally active service received no requests (zero-traffic detection)
+// Incidents are open (EndedAt nil) until the service receives requests again.
+type ServiceIncident struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	Service          string     `gorm:"type:varchar(255);not null;index" json:"service"` // backend_name > backend_url > host
+	StartedAt        time.Time  `gorm:"not null;index" json:"started_at"`                // Last request before the silence
+	DetectedAt       time.Time  `gorm:"not null" json:"detected_at"`
+	EndedAt          *time.Time `gorm:"index" json:"ended_at,omitempty"` // First request after the silence, nil while ongoing
+	ExpectedRequests float64    `json:"expected_requests"`               // Usual requests in the detection window
+	WindowSeconds    int        `json:"window_seconds"`                  // Detection window
+}
+
+func (ServiceIncident) TableName() string {
+	return "service_incidents"
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting is a dashboard setting changed at runtime, stored as JSON under its section key (e.g. "theme")
+type Setting struct {
+	Key       string `gorm:"primaryKey;type:varchar(64)"`
+	Value     string `gorm:"type:text;not null"`
+	UpdatedAt time.Time
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
@@ -0,0 +1,14 @@
+package models
+
+// SourceCoverage is how long a processor read a source during one hour
+// Sources logging only on traffic write nothing while idle, coverage tells an hour without traffic from an hour
+// nobody was reading the source (LogLynx stopped, source not configured yet).
+type SourceCoverage struct {
+	SourceName   string `gorm:"primaryKey;type:varchar(255)"`
+	Hour         string `gorm:"primaryKey;type:char(16)"` // UTC hour, "2006-01-02 15:00" like hourly timeline buckets
+	AliveSeconds int64  `gorm:"not null;default:0"`
+}
+
+func (SourceCoverage) TableName() string {
+	return "source_coverage"
+}
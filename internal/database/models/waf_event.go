@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// WAF event actions
+const (
+	WAFActionBlocked  = "blocked"  // The WAF interrupted the request
+	WAFActionDetected = "detected" // Rules matched but the request went through (detection-only mode, anomaly score below the threshold)
+)
+
+// WAFEvent is a request a web application firewall (ModSecurity, Coraza) matched rules for
+// WAF audit logs are a secondary source: blocked requests usually also appear in the access log
+// with a 403, so they are kept apart from http_requests and correlated by client IP and path.
+type WAFEvent struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	SourceName string    `gorm:"type:varchar(255);not null"`
+	Timestamp  time.Time `gorm:"not null;index"`
+	EventHash  string    `gorm:"type:char(64);uniqueIndex"` // SHA256 hash for deduplication
+
+	ClientIP   string `gorm:"type:varchar(45);not null;index"`
+	Method     string `gorm:"type:varchar(10)"`
+	Host       string `gorm:"type:varchar(255)"`
+	Path       string `gorm:"type:varchar(2048)"`
+	StatusCode int    // Status returned by the WAF (or the backend when not blocked)
+
+	Action   string `gorm:"type:varchar(10);not null;index"` // WAFActionBlocked or WAFActionDetected
+	RuleIDs  string `gorm:"type:varchar(255)"`               // Comma-separated IDs of the matched rules
+	Message  string `gorm:"type:varchar(512)"`               // Message of the first matched rule
+	Severity string `gorm:"type:varchar(20)"`                // Highest severity of the matched rules (CRITICAL, ERROR, WARNING, NOTICE)
+}
+
+func (WAFEvent) TableName() string {
+	return "waf_events"
+}
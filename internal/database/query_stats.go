@@ -0,0 +1,222 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxQueryPatterns bounds the number of distinct http_requests SELECT statements kept for the index advisor
+const maxQueryPatterns = 500
+
+var (
+	// queryLiterals matches the string and number literals interpolated in traced SQL
+	queryLiterals = regexp.MustCompile(`'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+	querySpaces   = regexp.MustCompile(`\s+`)
+)
+
+// BackgroundEndpoint groups queries that don't belong to an API request (ingestion, cleanup, health checks)
+const BackgroundEndpoint = "background"
+
+type queryEndpointKey struct{}
+
+// WithQueryEndpoint labels the queries run with ctx as belonging to an endpoint (e.g. "GET /api/v1/stats/summary")
+func WithQueryEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, queryEndpointKey{}, endpoint)
+}
+
+// QueryEndpoint returns the endpoint a query was run for, or BackgroundEndpoint
+func QueryEndpoint(ctx context.Context) string {
+	if ctx != nil {
+		if endpoint, ok := ctx.Value(queryEndpointKey{}).(string); ok && endpoint != "" {
+			return endpoint
+		}
+	}
+	return BackgroundEndpoint
+}
+
+// QueryStats aggregates query counts and latencies per endpoint
+// Queries are recorded by SlowQueryLogger, requests by the API middleware
+type QueryStats struct {
+	mu            sync.Mutex
+	slowThreshold time.Duration
+	since         time.Time
+	endpoints     map[string]*endpointStats
+
+	patterns map[string]string // Normalized SELECT statement -> last SQL seen, for EXPLAIN QUERY PLAN
+}
+
+type endpointStats struct {
+	requests       int64
+	requestTime    time.Duration
+	maxRequestTime time.Duration
+	queries        int64
+	slowQueries    int64
+	errors         int64
+	queryTime      time.Duration
+	maxQueryTime   time.Duration
+	lastSlowAt     time.Time
+}
+
+// EndpointQueryStats is the query performance of one endpoint
+type EndpointQueryStats struct {
+	Endpoint          string     `json:"endpoint"`
+	Requests          int64      `json:"requests"`
+	AvgRequestMs      float64    `json:"avg_request_ms"`
+	MaxRequestMs      float64    `json:"max_request_ms"`
+	Queries           int64      `json:"queries"`
+	SlowQueries       int64      `json:"slow_queries"`
+	Errors            int64      `json:"errors"`
+	TotalQueryMs      float64    `json:"total_query_ms"`
+	AvgQueryMs        float64    `json:"avg_query_ms"`
+	MaxQueryMs        float64    `json:"max_query_ms"`
+	QueriesPerRequest float64    `json:"queries_per_request"`
+	LastSlowQueryAt   *time.Time `json:"last_slow_query_at,omitempty"`
+}
+
+// QueryStatsSnapshot is the content of QueryStats at a point in time
+type QueryStatsSnapshot struct {
+	Since           time.Time             `json:"since"`
+	SlowThresholdMs int64                 `json:"slow_threshold_ms"`
+	Endpoints       []*EndpointQueryStats `json:"endpoints"`
+}
+
+// NewQueryStats creates a collector, queries taking at least slowThreshold count as slow
+func NewQueryStats(slowThreshold time.Duration) *QueryStats {
+	if slowThreshold <= 0 {
+		slowThreshold = 100 * time.Millisecond
+	}
+	return &QueryStats{
+		slowThreshold: slowThreshold,
+		since:         time.Now(),
+		endpoints:     make(map[string]*endpointStats),
+		patterns:      make(map[string]string),
+	}
+}
+
+// SlowThreshold returns the duration from which a query counts as slow
+func (s *QueryStats) SlowThreshold() time.Duration {
+	return s.slowThreshold
+}
+
+// endpoint returns the stats of an endpoint, the caller must hold the lock
+func (s *QueryStats) endpoint(name string) *endpointStats {
+	stats, ok := s.endpoints[name]
+	if !ok {
+		stats = &endpointStats{}
+		s.endpoints[name] = stats
+	}
+	return stats
+}
+
+// RecordQuery adds one database query
+func (s *QueryStats) RecordQuery(endpoint string, elapsed time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.endpoint(endpoint)
+	stats.queries++
+	stats.queryTime += elapsed
+	stats.maxQueryTime = max(stats.maxQueryTime, elapsed)
+	if elapsed >= s.slowThreshold {
+		stats.slowQueries++
+		stats.lastSlowAt = time.Now()
+	}
+	if failed {
+		stats.errors++
+	}
+}
+
+// RecordStatement keeps a sample of each distinct SELECT statement reading http_requests
+// Statements differing only by their literals count as one pattern, new patterns are ignored once the limit is reached.
+func (s *QueryStats) RecordStatement(sql string) {
+	trimmed := strings.TrimSpace(sql)
+	if len(trimmed) < 6 || !strings.EqualFold(trimmed[:6], "SELECT") || !strings.Contains(trimmed, "http_requests") {
+		return
+	}
+	pattern := querySpaces.ReplaceAllString(queryLiterals.ReplaceAllString(trimmed, "?"), " ")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.patterns[pattern]; !ok && len(s.patterns) >= maxQueryPatterns {
+		return
+	}
+	s.patterns[pattern] = trimmed
+}
+
+// QueryPatterns returns a sample SQL statement of every recorded pattern
+func (s *QueryStats) QueryPatterns() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statements := make([]string, 0, len(s.patterns))
+	for _, sql := range s.patterns {
+		statements = append(statements, sql)
+	}
+	sort.Strings(statements)
+	return statements
+}
+
+// RecordRequest adds one API request and its total duration
+func (s *QueryStats) RecordRequest(endpoint string, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.endpoint(endpoint)
+	stats.requests++
+	stats.requestTime += elapsed
+	stats.maxRequestTime = max(stats.maxRequestTime, elapsed)
+}
+
+// Snapshot returns the stats of all endpoints, most expensive (total query time) first
+func (s *QueryStats) Snapshot() *QueryStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &QueryStatsSnapshot{
+		Since:           s.since,
+		SlowThresholdMs: s.slowThreshold.Milliseconds(),
+		Endpoints:       make([]*EndpointQueryStats, 0, len(s.endpoints)),
+	}
+	for name, stats := range s.endpoints {
+		entry := &EndpointQueryStats{
+			Endpoint:     name,
+			Requests:     stats.requests,
+			MaxRequestMs: durationMs(stats.maxRequestTime),
+			Queries:      stats.queries,
+			SlowQueries:  stats.slowQueries,
+			Errors:       stats.errors,
+			TotalQueryMs: durationMs(stats.queryTime),
+			MaxQueryMs:   durationMs(stats.maxQueryTime),
+		}
+		if stats.requests > 0 {
+			entry.AvgRequestMs = durationMs(stats.requestTime) / float64(stats.requests)
+			entry.QueriesPerRequest = float64(stats.queries) / float64(stats.requests)
+		}
+		if stats.queries > 0 {
+			entry.AvgQueryMs = entry.TotalQueryMs / float64(stats.queries)
+		}
+		if !stats.lastSlowAt.IsZero() {
+			lastSlowAt := stats.lastSlowAt
+			entry.LastSlowQueryAt = &lastSlowAt
+		}
+		snapshot.Endpoints = append(snapshot.Endpoints, entry)
+	}
+
+	sort.Slice(snapshot.Endpoints, func(i, j int) bool {
+		a, b := snapshot.Endpoints[i], snapshot.Endpoints[j]
+		if a.TotalQueryMs != b.TotalQueryMs {
+			return a.TotalQueryMs > b.TotalQueryMs
+		}
+		return a.Endpoint < b.Endpoint
+	})
+	return snapshot
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
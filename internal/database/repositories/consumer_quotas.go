@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota periods, calendar periods in UTC
+const (
+	QuotaPeriodHour  = "hour"
+	QuotaPeriodDay   = "day"
+	QuotaPeriodMonth = "month"
+)
+
+// ConsumerQuota limits the requests of an API consumer per period
+type ConsumerQuota struct {
+	Consumer string // "*" applies to consumers without their own quota
+	Limit    int64
+	Period   string // One of the QuotaPeriod constants
+}
+
+// PeriodStart returns the start of the period containing now
+func (q *ConsumerQuota) PeriodStart(now time.Time) time.Time {
+	now = now.UTC()
+	switch q.Period {
+	case QuotaPeriodHour:
+		return now.Truncate(time.Hour)
+	case QuotaPeriodMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// PeriodEnd returns the end of the period starting at start, when the quota resets
+func (q *ConsumerQuota) PeriodEnd(start time.Time) time.Time {
+	switch q.Period {
+	case QuotaPeriodHour:
+		return start.Add(time.Hour)
+	case QuotaPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ConsumerQuotas are the configured consumer quotas
+type ConsumerQuotas struct {
+	quotas   []*ConsumerQuota
+	fallback *ConsumerQuota // Quota of "*"
+}
+
+// ParseConsumerQuotas parses semicolon-separated "consumer:limit/period" quotas, e.g. "*:10000/day;alice:500/hour"
+// API keys are written like they are reported, e.g. "key:b2b9323ee091c380:1000/day".
+func ParseConsumerQuotas(list string) (*ConsumerQuotas, error) {
+	quotas := &ConsumerQuotas{}
+
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid consumer quota %q: expected consumer:limit/period", entry)
+		}
+
+		quota := &ConsumerQuota{Consumer: strings.TrimSpace(entry[:idx])}
+		limit, period, _ := strings.Cut(strings.TrimSpace(entry[idx+1:]), "/")
+		var err error
+		quota.Limit, err = strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+		if err != nil || quota.Limit <= 0 {
+			return nil, fmt.Errorf("invalid quota limit for consumer %q: %q", quota.Consumer, limit)
+		}
+		switch quota.Period = strings.TrimSpace(period); quota.Period {
+		case QuotaPeriodHour, QuotaPeriodDay, QuotaPeriodMonth:
+		default:
+			return nil, fmt.Errorf("invalid quota period for consumer %q: %q, expected hour, day or month", quota.Consumer, period)
+		}
+
+		if quota.Consumer == "*" {
+			if quotas.fallback != nil {
+				return nil, fmt.Errorf("duplicate consumer quota %q", quota.Consumer)
+			}
+			quotas.fallback = quota
+			continue
+		}
+		for _, existing := range quotas.quotas {
+			if existing.Consumer == quota.Consumer {
+				return nil, fmt.Errorf("duplicate consumer quota %q", quota.Consumer)
+			}
+		}
+		quotas.quotas = append(quotas.quotas, quota)
+	}
+
+	return quotas, nil
+}
+
+// Quota returns the quota of a consumer, nil if it has none
+func (q *ConsumerQuotas) Quota(consumer string) *ConsumerQuota {
+	if q == nil {
+		return nil
+	}
+	for _, quota := range q.quotas {
+		if quota.Consumer == consumer {
+			return quota
+		}
+	}
+	return q.fallback
+}
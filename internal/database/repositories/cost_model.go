@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/filter"
+)
+
+// DefaultEgressCostPerGB is a typical cloud egress price (USD per GB to the internet)
+const DefaultEgressCostPerGB = 0.09
+
+// bytesPerGB is the unit egress is billed in, cloud providers count 1024³ bytes
+const bytesPerGB = 1 << 30
+
+// CostModel estimates the egress cost of responses, globally and per service
+// A CDN-fronted service may cost a fraction of what a service served straight from a cloud region costs.
+type CostModel struct {
+	currency     string
+	defaultPerGB float64
+	services     []serviceCost // Ordered to keep generated SQL deterministic
+}
+
+type serviceCost struct {
+	name  string
+	perGB float64
+}
+
+// NewDefaultCostModel returns a model where every service costs DefaultEgressCostPerGB USD
+func NewDefaultCostModel() *CostModel {
+	return &CostModel{currency: "USD", defaultPerGB: DefaultEgressCostPerGB}
+}
+
+// ParseCostModel builds a cost model from the global price per GB and per-service overrides
+// Overrides are semicolon-separated "service:price" pairs, e.g. "cdn:0.02;downloads:0.12"
+func ParseCostModel(defaultPerGB float64, currency string, serviceCosts string) (*CostModel, error) {
+	if defaultPerGB < 0 {
+		return nil, fmt.Errorf("invalid egress cost %v: must not be negative", defaultPerGB)
+	}
+
+	currency = strings.TrimSpace(currency)
+	if currency == "" {
+		currency = "USD"
+	}
+	model := &CostModel{currency: currency, defaultPerGB: defaultPerGB}
+
+	for _, entry := range strings.Split(serviceCosts, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid service egress cost %q: expected service:price", entry)
+		}
+
+		name := strings.TrimSpace(entry[:idx])
+		perGB, err := strconv.ParseFloat(strings.TrimSpace(entry[idx+1:]), 64)
+		if err != nil || perGB < 0 {
+			return nil, fmt.Errorf("invalid egress cost for service %q: %q", name, entry[idx+1:])
+		}
+
+		model.services = append(model.services, serviceCost{name: name, perGB: perGB})
+	}
+
+	return model, nil
+}
+
+// Currency returns the currency costs are expressed in
+func (m *CostModel) Currency() string {
+	return m.currency
+}
+
+// DefaultPerGB returns the price per GB of services without override
+func (m *CostModel) DefaultPerGB() float64 {
+	return m.defaultPerGB
+}
+
+// ServiceRates returns the per-service prices per GB
+func (m *CostModel) ServiceRates() map[string]float64 {
+	rates := make(map[string]float64)
+	for _, svc := range m.services {
+		rates[svc.name] = svc.perGB
+	}
+	return rates
+}
+
+// RatePerGB returns the price per GB of the service a request belongs to
+// Services are matched like the "auto" service filter (backend_name > backend_url > host)
+func (m *CostModel) RatePerGB(backendName, backendURL, host string) float64 {
+	for _, svc := range m.services {
+		if matchesAutoService(svc.name, backendName, backendURL, host) {
+			return svc.perGB
+		}
+	}
+	return m.defaultPerGB
+}
+
+// CostSQL returns a SQL expression (with args) summing the estimated cost of the grouped requests
+func (m *CostModel) CostSQL() (string, []interface{}) {
+	if len(m.services) == 0 {
+		return "(COALESCE(SUM(response_size), 0) * " + formatRate(m.defaultPerGB) + " / " + strconv.Itoa(bytesPerGB) + ".0)", nil
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(m.services)*3)
+
+	sb.WriteString("(COALESCE(SUM(response_size * CASE")
+	for _, svc := range m.services {
+		cond, condArgs := filter.Service{Name: svc.name}.Condition()
+		sb.WriteString(" WHEN " + cond + " THEN ")
+		sb.WriteString(formatRate(svc.perGB))
+		args = append(args, condArgs...)
+	}
+	sb.WriteString(" ELSE " + formatRate(m.defaultPerGB))
+	sb.WriteString(" END), 0) / " + strconv.Itoa(bytesPerGB) + ".0)")
+
+	return sb.String(), args
+}
+
+// formatRate formats a validated price for inlining in SQL
+func formatRate(perGB float64) string {
+	return strconv.FormatFloat(perGB, 'f', -1, 64)
+}
@@ -0,0 +1,61 @@
+package fake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"loglynx/internal/clock"
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"github.com/pterm/pterm"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// databases numbers the in-memory databases, each test gets its own
+var databases atomic.Int64
+
+// NewDB opens an in-memory SQLite database with the full schema, closed when the test ends
+// Statistics are SQL queries: rather than reimplementing them, tests run them on a database that never touches disk.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:fake-%d?mode=memory&cache=shared&_busy_timeout=5000", databases.Add(1))
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: database.DriverName, DSN: dsn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The database lives as long as a connection to it, one connection also avoids shared-cache table locks
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := database.RunMigrations(db, database.MigrationOptions{}, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// NewStatsRepository returns a stats repository over an in-memory database holding requests
+// clk is the current time of the time ranges (nil = the system clock), the lookback is 24 hours.
+func NewStatsRepository(t testing.TB, clk clock.Clock, requests ...*models.HTTPRequest) repositories.StatsRepository {
+	t.Helper()
+	db := NewDB(t)
+	for i, request := range requests {
+		if request.RequestHash == "" {
+			request.RequestHash = fmt.Sprint("fake-", i)
+		}
+	}
+	if len(requests) > 0 {
+		if err := db.CreateInBatches(requests, 50).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repositories.NewStatsRepository(db, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled), 24, nil, nil, clk)
+}
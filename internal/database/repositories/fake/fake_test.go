@@ -0,0 +1,95 @@
+package fake_test
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/clock"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/filter"
+)
+
+func TestHTTPRequests(t *testing.T) {
+	sources := fake.NewLogSources(&models.LogSource{Name: "traefik", Path: "/var/log/access.log", ParserType: "traefik"})
+	repo := fake.NewHTTPRequests(sources)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	batch := []*models.HTTPRequest{
+		{SourceName: "traefik", Timestamp: now.Add(-3 * time.Hour), RequestHash: "a", ClientIP: "10.0.0.1", Host: "example", BackendName: "api"},
+		{SourceName: "traefik", Timestamp: now.Add(-2 * time.Hour), RequestHash: "b", ClientIP: "10.0.0.2", Host: "example", BackendName: "web"},
+		{SourceName: "traefik", Timestamp: now.Add(-time.Hour), RequestHash: "c", ClientIP: "10.0.0.1", Host: "other.example"},
+		{SourceName: "traefik", Timestamp: now.Add(-time.Hour), RequestHash: "a", ClientIP: "10.0.0.1", Host: "example", BackendName: "api"},
+	}
+	result, err := repo.CreateBatchWithPosition(batch, &repositories.SourcePosition{SourceName: "traefik", Position: 420, Inode: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 3 || result.Duplicates != 1 || !result.FirstLoad {
+		t.Errorf("Expected 3 inserted and 1 duplicate on first load, got %+v", result)
+	}
+	if source, err := sources.FindByName("traefik"); err != nil || source.LastPosition != 420 || source.LastInode != 7 || source.LastReadAt == nil {
+		t.Errorf("Expected the position saved with the batch, got %+v (%v)", source, err)
+	}
+
+	repo.DisableFirstLoadMode()
+	if result, err := repo.CreateBatch(batch[:1]); err != nil || result.Duplicates != 1 || result.FirstLoad {
+		t.Errorf("Expected a duplicate after the first load, got %+v (%v)", result, err)
+	}
+
+	var paths []string
+	err = repo.Stream(repositories.RequestFilter{ClientIP: "10.0.0.1", Since: now.Add(-4 * time.Hour)}, func(request *models.HTTPRequest) error {
+		paths = append(paths, request.Host)
+		return nil
+	})
+	if err != nil || len(paths) != 2 || paths[0] != "other.example" {
+		t.Errorf("Expected the 2 requests of the IP, newest first, got %v (%v)", paths, err)
+	}
+	// A pattern only matches with its match type, auto falling back to the host of requests without backend
+	if requests, err := repo.FindAll(0, 0, "other.*", "auto", "", nil); err != nil || len(requests) != 0 {
+		t.Errorf("Expected no exact match for a pattern, got %d (%v)", len(requests), err)
+	}
+	paths = nil
+	err = repo.Stream(repositories.RequestFilter{ServiceName: "other.*", ServiceMatchType: filter.MatchWildcard}, func(request *models.HTTPRequest) error {
+		paths = append(paths, request.Host)
+		return nil
+	})
+	if err != nil || len(paths) != 1 || paths[0] != "other.example" {
+		t.Errorf("Expected the wildcard to match the host, got %v (%v)", paths, err)
+	}
+	if requests, err := repo.FindAll(10, 0, "", "", "10.0.0.1", []filter.Service{{Name: "api", Type: "backend_name"}}); err != nil || len(requests) != 2 {
+		t.Errorf("Expected the own IP excluded on api only, got %d (%v)", len(requests), err)
+	}
+	if err := repo.Stream(repositories.RequestFilter{Slow: &repositories.SlowFilter{}}, nil); err != fake.ErrUnsupportedFilter {
+		t.Errorf("Expected ErrUnsupportedFilter, got %v", err)
+	}
+	if count, err := repo.CountBySourceName("traefik"); err != nil || count != 3 {
+		t.Errorf("Expected 3 requests, got %d (%v)", count, err)
+	}
+}
+
+func TestStatsRepository(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	requests := make([]*models.HTTPRequest, 0)
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 30 * time.Hour} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "traefik", Timestamp: clk.Now().Add(-age), ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", StatusCode: 200,
+		})
+	}
+	stats := fake.NewStatsRepository(t, clk, requests...)
+
+	summary, err := stats.GetSummary(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalRequests != 2 {
+		t.Errorf("Expected the 2 requests of the last 24 hours, got %d", summary.TotalRequests)
+	}
+
+	// The time range follows the clock
+	clk.Advance(22*time.Hour + 30*time.Minute)
+	if summary, err := stats.GetSummary(nil, nil); err != nil || summary.TotalRequests != 1 {
+		t.Errorf("Expected 1 request left in the range, got %+v (%v)", summary, err)
+	}
+}
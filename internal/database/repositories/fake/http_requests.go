@@ -0,0 +1,238 @@
+// Package fake provides in-memory repositories, so ingestion and API logic can be tested without SQLite files
+package fake
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnsupportedFilter is returned for request filters only SQL can evaluate (search, slow, headers, labels)
+var ErrUnsupportedFilter = errors.New("filter not supported by the in-memory repository")
+
+var _ repositories.HTTPRequestRepository = (*HTTPRequests)(nil)
+
+// HTTPRequests is an in-memory HTTPRequestRepository
+// Requests are deduplicated by RequestHash like the unique index does, and batch positions are saved to Sources.
+type HTTPRequests struct {
+	Sources *LogSources // Receives the positions saved with batches, optional
+
+	mu        sync.Mutex
+	requests  []*models.HTTPRequest
+	hashes    map[string]bool
+	nextID    uint
+	firstLoad *bool // Decided by the first batch, like the real repository
+}
+
+// NewHTTPRequests creates an empty request repository saving batch positions to sources (nil = not saved)
+func NewHTTPRequests(sources *LogSources) *HTTPRequests {
+	return &HTTPRequests{Sources: sources, hashes: make(map[string]bool)}
+}
+
+// insert stores a copy of request unless its hash is already stored, caller must hold r.mu
+func (r *HTTPRequests) insert(request *models.HTTPRequest) bool {
+	if request.RequestHash != "" && r.hashes[request.RequestHash] {
+		return false
+	}
+	r.nextID++
+	request.ID = r.nextID
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = time.Now()
+	}
+	stored := *request
+	r.requests = append(r.requests, &stored)
+	if request.RequestHash != "" {
+		r.hashes[request.RequestHash] = true
+	}
+	return true
+}
+
+func (r *HTTPRequests) Create(request *models.HTTPRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.insert(request) {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+func (r *HTTPRequests) CreateBatch(requests []*models.HTTPRequest) (*repositories.BatchResult, error) {
+	return r.CreateBatchWithPosition(requests, nil)
+}
+
+func (r *HTTPRequests) CreateBatchWithPosition(requests []*models.HTTPRequest, position *repositories.SourcePosition) (*repositories.BatchResult, error) {
+	results, err := r.CreateSourceBatches([]*repositories.SourceBatch{{Requests: requests, Position: position}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CreateSourceBatches stores the batches and saves their positions
+// In-batch duplicates are counted, hash collisions aren't detected.
+func (r *HTTPRequests) CreateSourceBatches(batches []*repositories.SourceBatch) ([]*repositories.BatchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.firstLoad == nil {
+		firstLoad := len(r.requests) == 0
+		r.firstLoad = &firstLoad
+	}
+
+	results := make([]*repositories.BatchResult, len(batches))
+	for i, batch := range batches {
+		result := &repositories.BatchResult{FirstLoad: *r.firstLoad && len(batch.Requests) > 0}
+		inBatch := make(map[string]bool, len(batch.Requests))
+		for _, request := range batch.Requests {
+			if r.insert(request) {
+				result.Inserted++
+			} else {
+				result.Duplicates++
+				if inBatch[request.RequestHash] {
+					result.InBatchDuplicates++
+				}
+			}
+			inBatch[request.RequestHash] = true
+		}
+		results[i] = result
+
+		if batch.Position != nil && r.Sources != nil {
+			position := batch.Position
+			if err := r.Sources.UpdateTracking(position.SourceName, position.Position, position.Inode, position.LastLine, position.Fingerprint); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+func (r *HTTPRequests) FindByID(id uint) (*models.HTTPRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, request := range r.requests {
+		if request.ID == id {
+			found := *request
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *HTTPRequests) FindAll(limit int, offset int, serviceName string, serviceType string, clientIP string, excludeServices []filter.Service) ([]*models.HTTPRequest, error) {
+	requests := make([]*models.HTTPRequest, 0)
+	err := r.Stream(repositories.RequestFilter{
+		Limit:           limit,
+		Offset:          offset,
+		ServiceName:     serviceName,
+		ServiceType:     serviceType,
+		ExcludeIP:       clientIP,
+		ExcludeServices: excludeServices,
+	}, func(request *models.HTTPRequest) error {
+		requests = append(requests, request)
+		return nil
+	})
+	return requests, err
+}
+
+// Stream passes the matching requests to fn, newest first
+// Search, slow, header and label filters return ErrUnsupportedFilter; Fields and Context are ignored.
+func (r *HTTPRequests) Stream(filter repositories.RequestFilter, fn func(*models.HTTPRequest) error) error {
+	if filter.Search != nil || filter.Slow != nil || len(filter.Headers) > 0 || len(filter.Labels) > 0 {
+		return ErrUnsupportedFilter
+	}
+
+	service, excludeIP := filter.ServiceFilter(), filter.ExcludeIPFilter()
+	matching := r.matching(func(request *models.HTTPRequest) bool {
+		switch {
+		case service.Name != "" && !service.Matches(request.BackendName, request.BackendURL, request.Host),
+			filter.ClientIP != "" && request.ClientIP != filter.ClientIP,
+			!filter.Since.IsZero() && !request.Timestamp.After(filter.Since),
+			!filter.Until.IsZero() && request.Timestamp.After(filter.Until):
+			return false
+		}
+		return !excludeIP.Excludes(request.ClientIP, request.BackendName, request.BackendURL, request.Host)
+	})
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matching) {
+			return nil
+		}
+		matching = matching[filter.Offset:]
+	}
+	if filter.Limit > 0 && len(matching) > filter.Limit {
+		matching = matching[:filter.Limit]
+	}
+	for _, request := range matching {
+		if err := fn(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *HTTPRequests) FindBySourceName(sourceName string, limit int) ([]*models.HTTPRequest, error) {
+	return limited(r.matching(func(request *models.HTTPRequest) bool {
+		return request.SourceName == sourceName
+	}), limit), nil
+}
+
+func (r *HTTPRequests) FindByTimeRange(start, end time.Time, limit int) ([]*models.HTTPRequest, error) {
+	return limited(r.matching(func(request *models.HTTPRequest) bool {
+		return !request.Timestamp.Before(start) && !request.Timestamp.After(end)
+	}), limit), nil
+}
+
+func (r *HTTPRequests) Count() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.requests)), nil
+}
+
+func (r *HTTPRequests) CountBySourceName(sourceName string) (int64, error) {
+	return int64(len(r.matching(func(request *models.HTTPRequest) bool {
+		return request.SourceName == sourceName
+	}))), nil
+}
+
+// SearchIndexAvailable is false, there is no full-text index in memory
+func (r *HTTPRequests) SearchIndexAvailable() bool {
+	return false
+}
+
+func (r *HTTPRequests) DisableFirstLoadMode() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	firstLoad := false
+	r.firstLoad = &firstLoad
+}
+
+// matching returns copies of the requests accepted by keep, newest first
+func (r *HTTPRequests) matching(keep func(*models.HTTPRequest) bool) []*models.HTTPRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matching := make([]*models.HTTPRequest, 0)
+	for _, request := range r.requests {
+		if keep(request) {
+			found := *request
+			matching = append(matching, &found)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].Timestamp.After(matching[j].Timestamp)
+	})
+	return matching
+}
+
+// limited returns the first limit requests, all of them when limit <= 0
+func limited(requests []*models.HTTPRequest, limit int) []*models.HTTPRequest {
+	if limit > 0 && len(requests) > limit {
+		return requests[:limit]
+	}
+	return requests
+}
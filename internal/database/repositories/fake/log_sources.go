@@ -0,0 +1,145 @@
+package fake
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+var _ repositories.LogSourceRepository = (*LogSources)(nil)
+
+// LogSources is an in-memory LogSourceRepository
+// Sources are stored and returned as copies, like rows read from the database.
+type LogSources struct {
+	mu       sync.Mutex
+	sources  map[string]*models.LogSource
+	coverage map[string]map[string]int64 // Alive seconds by source and hour
+}
+
+// NewLogSources creates a repository holding copies of sources
+func NewLogSources(sources ...*models.LogSource) *LogSources {
+	r := &LogSources{sources: make(map[string]*models.LogSource), coverage: make(map[string]map[string]int64)}
+	for _, source := range sources {
+		r.Create(source)
+	}
+	return r
+}
+
+func (r *LogSources) Create(source *models.LogSource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.sources[source.Name]; exists {
+		return gorm.ErrDuplicatedKey
+	}
+	now := time.Now()
+	source.CreatedAt, source.UpdatedAt = now, now
+	stored := *source
+	r.sources[source.Name] = &stored
+	return nil
+}
+
+func (r *LogSources) FindByName(name string) (*models.LogSource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source, exists := r.sources[name]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *source
+	return &found, nil
+}
+
+// FindAll returns the sources ordered by name
+func (r *LogSources) FindAll() ([]*models.LogSource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sources := make([]*models.LogSource, 0, len(r.sources))
+	for _, source := range r.sources {
+		found := *source
+		sources = append(sources, &found)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+	return sources, nil
+}
+
+// Update saves the source, creating it when missing like gorm's Save
+func (r *LogSources) Update(source *models.LogSource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source.UpdatedAt = time.Now()
+	stored := *source
+	r.sources[source.Name] = &stored
+	return nil
+}
+
+func (r *LogSources) UpdateTracking(name string, position int64, inode int64, lastLine string, fingerprint string) error {
+	return r.update(name, func(source *models.LogSource) {
+		now := time.Now()
+		source.LastPosition = position
+		source.LastInode = inode
+		source.LastLineContent = lastLine
+		source.FirstLineFingerprint = fingerprint
+		source.LastReadAt = &now
+	})
+}
+
+func (r *LogSources) IncrementDedupCounters(name string, counts repositories.DedupCounts) error {
+	return r.update(name, func(source *models.LogSource) {
+		source.RecordsInserted += counts.Inserted
+		source.DuplicatesSkipped += counts.Duplicates
+		source.FirstLoadInserts += counts.FirstLoadInserts
+		source.InBatchDuplicates += counts.InBatchDuplicates
+		source.HashCollisions += counts.HashCollisions
+		if counts.LastCollision != "" {
+			now := time.Now()
+			source.LastHashCollision = counts.LastCollision
+			source.LastHashCollisionAt = &now
+		}
+	})
+}
+
+func (r *LogSources) SetProcessorRunning(name string, running bool) error {
+	return r.update(name, func(source *models.LogSource) {
+		source.ProcessorRunning = running
+	})
+}
+
+func (r *LogSources) AddCoverage(name string, at time.Time, seconds int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.coverage[name] == nil {
+		r.coverage[name] = make(map[string]int64)
+	}
+	hour := repositories.CoverageHour(at)
+	r.coverage[name][hour] = min(3600, r.coverage[name][hour]+seconds)
+	return nil
+}
+
+// Coverage returns the alive seconds recorded for a source by hour
+func (r *LogSources) Coverage(name string) map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coverage := make(map[string]int64, len(r.coverage[name]))
+	for hour, seconds := range r.coverage[name] {
+		coverage[hour] = seconds
+	}
+	return coverage
+}
+
+// update changes a stored source, missing sources are ignored like an UPDATE matching no row
+func (r *LogSources) update(name string, change func(*models.LogSource)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if source, exists := r.sources[name]; exists {
+		change(source)
+		source.UpdatedAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// HeaderPrefix names a captured request header in structured query groups and filters, e.g. "header:X-Tenant-Id"
+const HeaderPrefix = "header:"
+
+// headerNamePattern matches the names of captured headers, they are embedded in JSON paths
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// CanonicalHeaderName validates the name of a captured header and returns it like it is stored (X-Tenant-Id)
+func CanonicalHeaderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !headerNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid header name %q", name)
+	}
+	return http.CanonicalHeaderKey(name), nil
+}
+
+// headerExpression returns the value of a captured header, read from the proxy_metadata JSON
+// Requests without metadata store "", which json_extract rejects, so they read as NULL.
+// The name must be canonical (CanonicalHeaderName), which keeps it safe inside the path.
+func headerExpression(name string) string {
+	return `CASE WHEN json_valid(proxy_metadata) THEN json_extract(proxy_metadata, '$.headers."` + name + `"') END`
+}
+
+// responseHeaderExpression returns the value of a captured response header (CAPTURE_RESPONSE_HEADERS), "" when absent
+// The name must be canonical, like for headerExpression.
+func responseHeaderExpression(name string) string {
+	return `COALESCE(CASE WHEN json_valid(proxy_metadata) THEN json_extract(proxy_metadata, '$.response_headers."` + name + `"') END, '')`
+}
+
+// HeaderFilter matches requests by the values of captured request headers (CAPTURE_HEADERS), all must match
+type HeaderFilter map[string]string
+
+// ParseHeaderFilter parses header values by name, e.g. {"x-tenant-id": "acme"}; nil when values is empty
+func ParseHeaderFilter(values map[string]string) (HeaderFilter, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	filter := make(HeaderFilter, len(values))
+	for name, value := range values {
+		canonical, err := CanonicalHeaderName(name)
+		if err != nil {
+			return nil, err
+		}
+		filter[canonical] = value
+	}
+	return filter, nil
+}
+
+// apply adds the header conditions, in name order so the statement is stable
+func (f HeaderFilter) apply(query *gorm.DB) *gorm.DB {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query = query.Where(headerExpression(name)+" = ?", f[name])
+	}
+	return query
+}
@@ -1,7 +1,11 @@
 package repositories
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
 	"strings"
 	"sync"
 	"time"
@@ -14,31 +18,108 @@ import (
 // HTTPRequestRepository handles CRUD operations for HTTP requests
 type HTTPRequestRepository interface {
 	Create(request *models.HTTPRequest) error
-	CreateBatch(requests []*models.HTTPRequest) error
+	CreateBatch(requests []*models.HTTPRequest) (*BatchResult, error)
+	CreateBatchWithPosition(requests []*models.HTTPRequest, position *SourcePosition) (*BatchResult, error)
+	CreateSourceBatches(batches []*SourceBatch) ([]*BatchResult, error)
 	FindByID(id uint) (*models.HTTPRequest, error)
-	FindAll(limit int, offset int, serviceName string, serviceType string, clientIP string, excludeServices []ServiceFilter) ([]*models.HTTPRequest, error)
+	FindAll(limit int, offset int, serviceName string, serviceType string, clientIP string, excludeServices []filter.Service) ([]*models.HTTPRequest, error)
+	Stream(filter RequestFilter, fn func(*models.HTTPRequest) error) error
 	FindBySourceName(sourceName string, limit int) ([]*models.HTTPRequest, error)
 	FindByTimeRange(start, end time.Time, limit int) ([]*models.HTTPRequest, error)
 	Count() (int64, error)
 	CountBySourceName(sourceName string) (int64, error)
+	SearchIndexAvailable() bool
 	// First-load optimization control
 	DisableFirstLoadMode()
 }
 
+// BatchResult reports how many records of a batch were stored and how many were dropped as duplicates
+type BatchResult struct {
+	Inserted          int  // Records actually written
+	Duplicates        int  // Records skipped because their request_hash already existed (in batch or in DB)
+	InBatchDuplicates int  // Part of Duplicates whose hash was already in the same batch
+	FirstLoad         bool // True if the first-load fast path (raw multi-row insert) was used
+	Collisions        []HashCollision
+}
+
+// HashCollision is a duplicate whose request differs from the one stored (or seen earlier in the batch) with the same hash
+// Requests with equal hashes should be identical: a collision means the hash doesn't cover a field that differs,
+// so legitimate traffic is dropped.
+type HashCollision struct {
+	Hash     string
+	Stored   string // e.g. "GET example.com/a 200 from 10.0.0.1"
+	Incoming string
+}
+
+// requestIdentity describes the fields of a request checked for hash collisions
+func requestIdentity(request *models.HTTPRequest) string {
+	return fmt.Sprintf("%s %s%s %d from %s", request.Method, request.Host, request.Path, request.StatusCode, request.ClientIP)
+}
+
+// ErrWriteAborted is returned when a write was given up because the writer is stopping (shutdown)
+// The requests weren't stored: their source position must not advance, so their lines are read again.
+var ErrWriteAborted = errors.New("write aborted at shutdown")
+
+// SourceBatch is a batch of requests read from one source, with the source position it ends at
+type SourceBatch struct {
+	Requests []*models.HTTPRequest
+	Position *SourcePosition // Saved in the same transaction, optional
+}
+
+// RequestFilter selects requests for listings and exports (newest first)
+type RequestFilter struct {
+	Limit            int // 0 = no limit
+	Offset           int
+	ServiceName      string
+	ServiceType      string
+	ServiceMatchType string    // exact (default), prefix, wildcard or regex
+	ClientIP         string    // Only requests from this IP
+	Since            time.Time // Only requests after this time (zero = no lower bound)
+	Until            time.Time // Only requests up to this time (zero = no upper bound)
+	ExcludeIP        string    // Exclude requests from this IP (own IP)
+	ExcludeServices  []filter.Service
+	Fields           *RequestFieldSet // Columns to read, nil for all
+	Search           *RequestSearch   // Full-text search on path, referrer and user agent, optional
+	Slow             *SlowFilter      // Only slow requests, optional
+	Headers          HeaderFilter     // Only requests with these captured header values, optional
+	Labels           LabelFilter      // Only requests with these label values, optional
+	Context          context.Context  // Cancels the query when the client disconnects, optional
+}
+
+// ServiceFilter returns the service the requests are filtered on, with an empty name for all services
+func (f RequestFilter) ServiceFilter() filter.Service {
+	return filter.Service{Name: f.ServiceName, Type: f.ServiceType, MatchType: f.ServiceMatchType}
+}
+
+// ExcludeIPFilter returns the own IP exclusion, nil without ExcludeIP
+func (f RequestFilter) ExcludeIPFilter() *filter.ExcludeIP {
+	if f.ExcludeIP == "" {
+		return nil
+	}
+	return &filter.ExcludeIP{ClientIP: f.ExcludeIP, ExcludeServices: f.ExcludeServices}
+}
+
 type httpRequestRepo struct {
 	db            *gorm.DB
 	logger        *pterm.Logger
 	isFirstLoad   bool       // Global flag: true when database is empty at startup
 	firstLoadMu   sync.Mutex // Protects isFirstLoad flag
 	firstLoadOnce sync.Once  // Ensures first-load check happens only once
+
+	searchIndexOnce sync.Once // Detects the full-text search index on first search
+	searchIndex     bool
+
+	indexProfile IndexProfile // Performance indexes created after the first load
 }
 
 // NewHTTPRequestRepository creates a new HTTP request repository
-func NewHTTPRequestRepository(db *gorm.DB, logger *pterm.Logger) HTTPRequestRepository {
+// indexProfile selects the indexes created once the first load completes (empty = DefaultIndexProfile)
+func NewHTTPRequestRepository(db *gorm.DB, logger *pterm.Logger, indexProfile IndexProfile) HTTPRequestRepository {
 	repo := &httpRequestRepo{
-		db:          db,
-		logger:      logger,
-		isFirstLoad: false, // Will be checked on first CreateBatch call
+		db:           db,
+		logger:       logger,
+		isFirstLoad:  false, // Will be checked on first CreateBatch call
+		indexProfile: indexProfile,
 	}
 	return repo
 }
@@ -85,10 +166,7 @@ func (r *httpRequestRepo) createDeferredIndexes() {
 
 	startTime := time.Now()
 
-	// Import optimize package function
-	// Note: This requires OptimizeDatabase to be accessible
-	// For now, we'll implement a simplified version here
-	if err := r.optimizeDatabase(); err != nil {
+	if err := ApplyIndexProfile(r.db, r.indexProfile, r.logger); err != nil {
 		r.logger.Error("Failed to create performance indexes",
 			r.logger.Args("error", err, "elapsed", time.Since(startTime)))
 		return
@@ -99,120 +177,6 @@ func (r *httpRequestRepo) createDeferredIndexes() {
 		r.logger.Args("elapsed_seconds", elapsed.Seconds()))
 }
 
-// optimizeDatabase creates all performance indexes
-// This is a copy of OptimizeDatabase function to avoid circular dependencies
-func (r *httpRequestRepo) optimizeDatabase() error {
-	indexes := []string{
-		// ===== BASIC SINGLE-COLUMN INDEXES =====
-		// These were removed from GORM tags to defer creation until after first load
-
-		`CREATE INDEX IF NOT EXISTS idx_source_name ON http_requests(source_name)`,
-		`CREATE INDEX IF NOT EXISTS idx_timestamp ON http_requests(timestamp DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_partition_key ON http_requests(partition_key)`,
-		`CREATE INDEX IF NOT EXISTS idx_client_ip ON http_requests(client_ip)`,
-		`CREATE INDEX IF NOT EXISTS idx_host ON http_requests(host)`,
-		`CREATE INDEX IF NOT EXISTS idx_status ON http_requests(status_code)`,
-		`CREATE INDEX IF NOT EXISTS idx_response_time ON http_requests(response_time_ms) WHERE response_time_ms > 0`,
-		`CREATE INDEX IF NOT EXISTS idx_retry_attempts ON http_requests(retry_attempts) WHERE retry_attempts > 0`,
-		`CREATE INDEX IF NOT EXISTS idx_browser ON http_requests(browser)`,
-		`CREATE INDEX IF NOT EXISTS idx_os ON http_requests(os)`,
-		`CREATE INDEX IF NOT EXISTS idx_device_type ON http_requests(device_type)`,
-		`CREATE INDEX IF NOT EXISTS idx_router_name ON http_requests(router_name)`,
-		`CREATE INDEX IF NOT EXISTS idx_request_id ON http_requests(request_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_trace_id ON http_requests(trace_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_geo_country ON http_requests(geo_country)`,
-		`CREATE INDEX IF NOT EXISTS idx_created_at ON http_requests(created_at DESC)`,
-
-		// ===== COMPOSITE INDEXES (for common query patterns) =====
-
-		// Time + Status (error analysis over time)
-		`CREATE INDEX IF NOT EXISTS idx_time_status
-		 ON http_requests(timestamp DESC, status_code)`,
-
-		// Time + Host (per-service time-range queries)
-		`CREATE INDEX IF NOT EXISTS idx_time_host
-		 ON http_requests(timestamp DESC, host)`,
-
-		// ClientIP + Time (IP activity timeline)
-		`CREATE INDEX IF NOT EXISTS idx_ip_time
-		 ON http_requests(client_ip, timestamp DESC)`,
-
-		// Status + Host (service error rates)
-		`CREATE INDEX IF NOT EXISTS idx_status_host
-		 ON http_requests(status_code, host)`,
-
-		// Composite index for timestamp + response_time for optimized percentile queries
-		`CREATE INDEX IF NOT EXISTS idx_timestamp_response_time
-		 ON http_requests(timestamp DESC, response_time_ms)
-		 WHERE response_time_ms > 0`,
-
-		// Composite index for summary queries (timestamp, status_code, response_time_ms)
-		`CREATE INDEX IF NOT EXISTS idx_summary_query
-		 ON http_requests(timestamp DESC, status_code, response_time_ms)`,
-
-		// ===== PARTIAL INDEXES (for specific queries) =====
-
-		// Errors only (40x and 50x status codes)
-		`CREATE INDEX IF NOT EXISTS idx_errors_only
-		 ON http_requests(timestamp DESC, status_code, path, method, client_ip)
-		 WHERE status_code >= 400`,
-
-		// Slow requests only (>1 second)
-		`CREATE INDEX IF NOT EXISTS idx_slow_requests
-		 ON http_requests(timestamp DESC, response_time_ms, path, host, method)
-		 WHERE response_time_ms > 1000`,
-
-		// Server errors only (50x)
-		`CREATE INDEX IF NOT EXISTS idx_server_errors
-		 ON http_requests(timestamp DESC, status_code, path, backend_name)
-		 WHERE status_code >= 500`,
-
-		// Requests with retries
-		`CREATE INDEX IF NOT EXISTS idx_retried_requests
-		 ON http_requests(timestamp DESC, retry_attempts, backend_name, status_code)
-		 WHERE retry_attempts > 0`,
-
-		// ===== COVERING INDEXES (include data columns) =====
-
-		// Dashboard covering index (includes most displayed columns)
-		`CREATE INDEX IF NOT EXISTS idx_dashboard_covering
-		 ON http_requests(timestamp DESC, status_code, response_time_ms, host, client_ip, method, path)`,
-
-		// Error analysis covering index
-		`CREATE INDEX IF NOT EXISTS idx_error_analysis
-		 ON http_requests(timestamp DESC, status_code, path, method, client_ip, response_time_ms, backend_name)
-		 WHERE status_code >= 400`,
-
-		// ===== CLEANUP INDEX =====
-		// Index for cleanup queries (timestamp for deletion)
-		`CREATE INDEX IF NOT EXISTS idx_timestamp_cleanup
-		 ON http_requests(timestamp)`,
-	}
-
-	indexCount := 0
-	for i, indexSQL := range indexes {
-		r.logger.Debug("Creating index",
-			r.logger.Args("progress", i+1, "total", len(indexes)))
-
-		if err := r.db.Exec(indexSQL).Error; err != nil {
-			r.logger.Warn("Failed to create index", r.logger.Args("error", err))
-			return err
-		}
-		indexCount++
-	}
-
-	r.logger.Debug("Performance indexes created", r.logger.Args("count", indexCount))
-
-	// Analyze tables for query optimizer
-	if err := r.db.Exec("ANALYZE").Error; err != nil {
-		r.logger.Warn("Failed to analyze database", r.logger.Args("error", err))
-	} else {
-		r.logger.Trace("Database statistics analyzed")
-	}
-
-	return nil
-}
-
 // getFirstLoadStatus returns current first-load status (thread-safe)
 func (r *httpRequestRepo) getFirstLoadStatus() bool {
 	r.firstLoadMu.Lock()
@@ -231,12 +195,37 @@ func (r *httpRequestRepo) Create(request *models.HTTPRequest) error {
 }
 
 // CreateBatch inserts multiple HTTP requests in a single transaction
+func (r *httpRequestRepo) CreateBatch(requests []*models.HTTPRequest) (*BatchResult, error) {
+	return r.CreateBatchWithPosition(requests, nil)
+}
+
+// CreateBatchWithPosition inserts multiple HTTP requests and saves the position of their source in the same transaction
+// After a crash the saved position always matches the stored requests, so recovery does not rely on dedup hashes.
+func (r *httpRequestRepo) CreateBatchWithPosition(requests []*models.HTTPRequest, position *SourcePosition) (*BatchResult, error) {
+	results, err := r.CreateSourceBatches([]*SourceBatch{{Requests: requests, Position: position}})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CreateSourceBatches inserts the batches of several sources and saves their positions in a single transaction
+// Results are returned in the order of the batches. A failure rolls back all of them.
 // OPTIMIZED: Automatically splits large batches to avoid SQLite variable limit (32766)
 // OPTIMIZED: Skips deduplication checks on first load (when database is empty)
-func (r *httpRequestRepo) CreateBatch(requests []*models.HTTPRequest) error {
-	if len(requests) == 0 {
+func (r *httpRequestRepo) CreateSourceBatches(batches []*SourceBatch) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(batches))
+	total, positions := 0, 0
+	for i, batch := range batches {
+		results[i] = &BatchResult{}
+		total += len(batch.Requests)
+		if batch.Position != nil {
+			positions++
+		}
+	}
+	if total == 0 && positions == 0 {
 		r.logger.Debug("Empty batch, skipping insert")
-		return nil
+		return results, nil
 	}
 
 	// Check first-load status (thread-safe, happens only once globally)
@@ -249,46 +238,72 @@ func (r *httpRequestRepo) CreateBatch(requests []*models.HTTPRequest) error {
 	// 500 records * 49 columns = 24,500 variables (well under 32,766 limit)
 	const MaxRecordsPerBatch = 50 // Slight safety margin under theoretical limit
 
-	// If batch is small enough, insert directly
-	if len(requests) <= MaxRecordsPerBatch {
-		return r.insertSubBatch(requests, isFirstLoad)
+	if total > MaxRecordsPerBatch {
+		// Split large batches into smaller chunks
+		r.logger.Debug("Splitting large batch to avoid variable limit",
+			r.logger.Args("total_records", total, "sources", len(batches), "max_per_batch", MaxRecordsPerBatch))
 	}
 
-	// Split large batches into smaller chunks
-	r.logger.Debug("Splitting large batch to avoid variable limit",
-		r.logger.Args("total_records", len(requests), "max_per_batch", MaxRecordsPerBatch))
+	// All chunks and positions commit together: a failure rolls back every batch
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for b, batch := range batches {
+			requests := batch.Requests
+			result := results[b]
+			result.FirstLoad = isFirstLoad && len(requests) > 0
 
-	totalInserted := 0
-	for i := 0; i < len(requests); i += MaxRecordsPerBatch {
-		end := i + MaxRecordsPerBatch
-		if end > len(requests) {
-			end = len(requests)
-		}
+			totalInserted := 0
+			for i := 0; i < len(requests); i += MaxRecordsPerBatch {
+				end := i + MaxRecordsPerBatch
+				if end > len(requests) {
+					end = len(requests)
+				}
 
-		subBatch := requests[i:end]
-		if err := r.insertSubBatch(subBatch, isFirstLoad); err != nil {
-			r.logger.WithCaller().Error("Failed to insert sub-batch",
-				r.logger.Args("batch_num", (i/MaxRecordsPerBatch)+1, "count", len(subBatch), "error", err))
-			return err
-		}
+				subBatch := requests[i:end]
+				inserted, err := r.insertSubBatch(tx, subBatch, isFirstLoad, result)
+				if err != nil {
+					r.logger.WithCaller().Error("Failed to insert sub-batch",
+						r.logger.Args("batch_num", (i/MaxRecordsPerBatch)+1, "count", len(subBatch), "error", err))
+					return err
+				}
+				result.Inserted += inserted
+				result.Duplicates += len(subBatch) - inserted
+
+				totalInserted += len(subBatch)
+				r.logger.Trace("Inserted sub-batch",
+					r.logger.Args("progress", totalInserted, "total", len(requests)))
+			}
 
-		totalInserted += len(subBatch)
-		r.logger.Trace("Inserted sub-batch",
-			r.logger.Args("progress", totalInserted, "total", len(requests)))
+			if batch.Position != nil {
+				if err := updateTracking(tx, batch.Position); err != nil {
+					r.logger.WithCaller().Error("Failed to save source position with batch",
+						r.logger.Args("source", batch.Position.SourceName, "error", err))
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to commit batch", r.logger.Args("count", total, "sources", len(batches), "error", err))
+		return nil, err
 	}
 
-	r.logger.Debug("Successfully inserted large batch in chunks",
-		r.logger.Args("total_records", len(requests), "source", requests[0].SourceName))
+	if total > MaxRecordsPerBatch {
+		r.logger.Debug("Successfully inserted large batch in chunks",
+			r.logger.Args("total_records", total, "sources", len(batches)))
+	}
 
-	return nil
+	return results, nil
 }
 
-// insertSubBatch performs the actual batch insert within SQLite variable limits
-func (r *httpRequestRepo) insertSubBatch(requests []*models.HTTPRequest, isFirstLoad bool) error {
+// insertSubBatch performs the actual batch insert within SQLite variable limits, in the caller's transaction
+// Returns the number of records actually inserted (the rest were duplicates); in-batch duplicates and hash
+// collisions are added to result.
+func (r *httpRequestRepo) insertSubBatch(tx *gorm.DB, requests []*models.HTTPRequest, isFirstLoad bool, result *BatchResult) (int, error) {
 	// OPTIMIZATION: Deduplicate in-memory BEFORE inserting to avoid rollbacks
 	// This prevents expensive transaction rollbacks and re-inserts
 	uniqueRequests := make([]*models.HTTPRequest, 0, len(requests))
-	seen := make(map[string]bool, len(requests))
+	seen := make(map[string]*models.HTTPRequest, len(requests))
 	inBatchDuplicates := 0
 
 	for _, req := range requests {
@@ -298,14 +313,18 @@ func (r *httpRequestRepo) insertSubBatch(requests []*models.HTTPRequest, isFirst
 			continue
 		}
 
-		if seen[req.RequestHash] {
+		if first, ok := seen[req.RequestHash]; ok {
 			inBatchDuplicates++
+			if stored, incoming := requestIdentity(first), requestIdentity(req); stored != incoming {
+				result.Collisions = append(result.Collisions, HashCollision{Hash: req.RequestHash, Stored: stored, Incoming: incoming})
+			}
 			continue // Skip duplicate within this batch
 		}
 
-		seen[req.RequestHash] = true
+		seen[req.RequestHash] = req
 		uniqueRequests = append(uniqueRequests, req)
 	}
+	result.InBatchDuplicates += inBatchDuplicates
 
 	if inBatchDuplicates > 0 {
 		r.logger.Debug("Removed in-batch duplicates before insert",
@@ -315,50 +334,46 @@ func (r *httpRequestRepo) insertSubBatch(requests []*models.HTTPRequest, isFirst
 	// If all were duplicates, skip the insert entirely
 	if len(uniqueRequests) == 0 {
 		r.logger.Debug("All records in batch were duplicates, skipping insert")
-		return nil
+		return 0, nil
 	}
 
 	if isFirstLoad {
-		inserted, err := r.insertSubBatchRaw(uniqueRequests)
+		inserted, err := r.insertSubBatchRaw(tx, uniqueRequests)
 		if err != nil {
 			r.logger.WithCaller().Error("Failed to insert batch via raw SQL",
 				r.logger.Args("count", len(uniqueRequests), "error", err))
-			return err
+			return 0, err
 		}
 
 		duplicates := len(uniqueRequests) - inserted
 		if duplicates > 0 {
 			r.logger.Debug("Initial load raw insert skipped duplicates",
 				r.logger.Args("batch_size", len(uniqueRequests), "inserted", inserted, "duplicates", duplicates))
+			if err := r.checkCollisions(tx, uniqueRequests, result); err != nil {
+				return 0, err
+			}
 		}
-		return nil
-	}
-
-	// Start transaction
-	tx := r.db.Begin()
-	if tx.Error != nil {
-		r.logger.WithCaller().Error("Failed to begin transaction", r.logger.Args("error", tx.Error))
-		return tx.Error
+		return inserted, insertLabels(tx, uniqueRequests)
 	}
 
 	// Use INSERT OR IGNORE semantics to skip duplicates without per-row retries
-	result := tx.Clauses(clause.OnConflict{
+	created := tx.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "request_hash"}},
 		DoNothing: true,
 	}).Create(&uniqueRequests)
-	if result.Error != nil {
-		tx.Rollback()
+	if created.Error != nil {
 		r.logger.WithCaller().Error("Failed to insert batch",
-			r.logger.Args("count", len(uniqueRequests), "error", result.Error))
-		return result.Error
+			r.logger.Args("count", len(uniqueRequests), "error", created.Error))
+		return 0, created.Error
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		r.logger.WithCaller().Error("Failed to commit transaction", r.logger.Args("error", err))
-		return err
+	if err := insertLabels(tx, uniqueRequests); err != nil {
+		r.logger.WithCaller().Error("Failed to insert request labels",
+			r.logger.Args("count", len(uniqueRequests), "error", err))
+		return 0, err
 	}
 
-	inserted := int(result.RowsAffected)
+	inserted := int(created.RowsAffected)
 	duplicates := len(uniqueRequests) - inserted
 	if duplicates > 0 {
 		logFn := r.logger.Debug
@@ -372,13 +387,50 @@ func (r *httpRequestRepo) insertSubBatch(requests []*models.HTTPRequest, isFirst
 				"inserted", inserted,
 				"duplicates", duplicates,
 			))
+		if err := r.checkCollisions(tx, uniqueRequests, result); err != nil {
+			return 0, err
+		}
+	}
+
+	return inserted, nil
+}
+
+// checkCollisions compares the requests of a sub-batch that hit the unique index with the stored ones
+// Only called when the insert skipped rows; the requests just inserted match themselves.
+func (r *httpRequestRepo) checkCollisions(tx *gorm.DB, requests []*models.HTTPRequest, result *BatchResult) error {
+	incoming := make(map[string]*models.HTTPRequest, len(requests))
+	hashes := make([]string, 0, len(requests))
+	for _, request := range requests {
+		if request.RequestHash != "" {
+			incoming[request.RequestHash] = request
+			hashes = append(hashes, request.RequestHash)
+		}
+	}
+
+	var stored []*models.HTTPRequest
+	err := tx.Model(&models.HTTPRequest{}).
+		Select("request_hash, method, host, path, status_code, client_ip").
+		Where("request_hash IN ?", hashes).
+		Find(&stored).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to check duplicates for hash collisions", r.logger.Args("error", err))
+		return err
 	}
 
+	for _, existing := range stored {
+		request := incoming[existing.RequestHash]
+		if request == nil {
+			continue
+		}
+		if storedIdentity, incomingIdentity := requestIdentity(existing), requestIdentity(request); storedIdentity != incomingIdentity {
+			result.Collisions = append(result.Collisions, HashCollision{Hash: existing.RequestHash, Stored: storedIdentity, Incoming: incomingIdentity})
+		}
+	}
 	return nil
 }
 
 // insertSubBatchRaw performs a high-throughput INSERT for initial load using raw SQL
-func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int, error) {
+func (r *httpRequestRepo) insertSubBatchRaw(tx *gorm.DB, requests []*models.HTTPRequest) (int, error) {
 	columns := []string{
 		"source_name",
 		"timestamp",
@@ -387,6 +439,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 		"client_ip",
 		"client_port",
 		"client_user",
+		"api_key_hash",
 		"method",
 		"protocol",
 		"host",
@@ -405,6 +458,8 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 		"requests_total",
 		"user_agent",
 		"referer",
+		"request_header_bytes",
+		"response_header_bytes",
 		"browser",
 		"browser_version",
 		"os",
@@ -415,6 +470,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 		"router_name",
 		"upstream_status",
 		"upstream_content_type",
+		"upstream_addr",
 		"client_hostname",
 		"tls_version",
 		"tls_cipher",
@@ -425,6 +481,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 		"geo_city",
 		"geo_lat",
 		"geo_lon",
+		"geo_eu",
 		"asn",
 		"asn_org",
 		"proxy_metadata",
@@ -463,6 +520,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 			req.ClientIP,
 			req.ClientPort,
 			req.ClientUser,
+			req.APIKeyHash,
 			req.Method,
 			req.Protocol,
 			req.Host,
@@ -481,6 +539,8 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 			req.RequestsTotal,
 			req.UserAgent,
 			req.Referer,
+			req.RequestHeaderBytes,
+			req.ResponseHeaderBytes,
 			req.Browser,
 			req.BrowserVersion,
 			req.OS,
@@ -491,6 +551,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 			req.RouterName,
 			req.UpstreamStatus,
 			req.UpstreamContentType,
+			req.UpstreamAddr,
 			req.ClientHostname,
 			req.TLSVersion,
 			req.TLSCipher,
@@ -501,6 +562,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 			req.GeoCity,
 			req.GeoLat,
 			req.GeoLon,
+			req.GeoEU,
 			req.ASN,
 			req.ASNOrg,
 			req.ProxyMetadata,
@@ -510,7 +572,7 @@ func (r *httpRequestRepo) insertSubBatchRaw(requests []*models.HTTPRequest) (int
 
 	queryBuilder.WriteString(" ON CONFLICT(request_hash) DO NOTHING")
 
-	result := r.db.Exec(queryBuilder.String(), args...)
+	result := tx.Exec(queryBuilder.String(), args...)
 	if result.Error != nil {
 		return 0, result.Error
 	}
@@ -532,79 +594,128 @@ func (r *httpRequestRepo) FindByID(id uint) (*models.HTTPRequest, error) {
 }
 
 // FindAll retrieves all HTTP requests with pagination
-func (r *httpRequestRepo) FindAll(limit int, offset int, serviceName string, serviceType string, clientIP string, excludeServices []ServiceFilter) ([]*models.HTTPRequest, error) {
+func (r *httpRequestRepo) FindAll(limit int, offset int, serviceName string, serviceType string, clientIP string, excludeServices []filter.Service) ([]*models.HTTPRequest, error) {
 	var requests []*models.HTTPRequest
-	query := r.db.Order("timestamp DESC")
+	query := r.requestQuery(RequestFilter{
+		Limit:           limit,
+		Offset:          offset,
+		ServiceName:     serviceName,
+		ServiceType:     serviceType,
+		ExcludeIP:       clientIP,
+		ExcludeServices: excludeServices,
+	})
 
-	// Apply service filter if provided
-	query = r.applyServiceFilter(query, serviceName, serviceType)
+	if err := query.Find(&requests).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to find HTTP requests", r.logger.Args("error", err))
+		return nil, err
+	}
 
-	// Apply exclude own IP if specified
-	if clientIP != "" {
-		if len(excludeServices) == 0 {
-			query = query.Where("client_ip != ?", clientIP)
-		} else {
-			// Build exclude condition for specific services
-			serviceConds := []string{}
-			args := []interface{}{clientIP}
-			for _, filter := range excludeServices {
-				switch filter.Type {
-				case "backend_name":
-					serviceConds = append(serviceConds, "backend_name = ?")
-					args = append(args, filter.Name)
-				case "backend_url":
-					serviceConds = append(serviceConds, "backend_url = ?")
-					args = append(args, filter.Name)
-				case "host":
-					serviceConds = append(serviceConds, "host = ?")
-					args = append(args, filter.Name)
-				}
-			}
-			if len(serviceConds) > 0 {
-				whereClause := "NOT (client_ip = ? AND (" + strings.Join(serviceConds, " OR ") + "))"
-				query = query.Where(whereClause, args...)
-			}
+	r.logger.Trace("Found HTTP requests", r.logger.Args("count", len(requests), "limit", limit, "offset", offset, "service_filter", serviceName))
+	return requests, nil
+}
+
+// Stream reads matching requests row by row and passes each one to fn
+// Memory stays constant regardless of the number of rows; iteration stops at the first error returned by fn
+func (r *httpRequestRepo) Stream(filter RequestFilter, fn func(*models.HTTPRequest) error) error {
+	rows, err := r.requestQuery(filter).Model(&models.HTTPRequest{}).Rows()
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to stream HTTP requests", r.logger.Args("error", err))
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var request models.HTTPRequest
+		if err := r.db.ScanRows(rows, &request); err != nil {
+			return err
 		}
+		if err := fn(&request); err != nil {
+			return err
+		}
+		count++
 	}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+	r.logger.Trace("Streamed HTTP requests", r.logger.Args("count", count, "limit", filter.Limit, "offset", filter.Offset))
+	return rows.Err()
+}
+
+// SearchIndexAvailable reports whether the full-text search index exists and is usable
+// Without it (disabled, or SQLite built without FTS5) searches scan the table with LIKE
+func (r *httpRequestRepo) SearchIndexAvailable() bool {
+	r.searchIndexOnce.Do(func() {
+		var count int64
+		r.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'http_requests_fts'").Scan(&count)
+		r.searchIndex = count > 0 && r.db.Exec("SELECT rowid FROM http_requests_fts LIMIT 0").Error == nil
+	})
+	return r.searchIndex
+}
+
+// requestQuery builds the query for FindAll and Stream
+func (r *httpRequestRepo) requestQuery(filter RequestFilter) *gorm.DB {
+	query := r.db
+	if filter.Context != nil {
+		query = query.WithContext(filter.Context)
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	query = query.Order("timestamp DESC")
+
+	// Column pruning: read only the requested fields
+	if filter.Fields != nil {
+		query = query.Select(filter.Fields.Columns())
 	}
 
-	if err := query.Find(&requests).Error; err != nil {
-		r.logger.WithCaller().Error("Failed to find HTTP requests", r.logger.Args("error", err))
-		return nil, err
+	if filter.Search != nil {
+		query = filter.Search.apply(query, r.SearchIndexAvailable())
 	}
 
-	r.logger.Trace("Found HTTP requests", r.logger.Args("count", len(requests), "limit", limit, "offset", offset, "service_filter", serviceName))
-	return requests, nil
+	// Apply service filter if provided
+	query = r.applyServiceFilter(query, filter.ServiceFilter())
+
+	if filter.ClientIP != "" {
+		query = query.Where("client_ip = ?", filter.ClientIP)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp > ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("timestamp <= ?", filter.Until)
+	}
+	if filter.Slow != nil {
+		query = filter.Slow.apply(query)
+	}
+	if len(filter.Headers) > 0 {
+		query = filter.Headers.apply(query)
+	}
+	if len(filter.Labels) > 0 {
+		query = filter.Labels.apply(query)
+	}
+
+	// Apply exclude own IP if specified
+	if cond, args := filter.ExcludeIPFilter().Condition(); cond != "" {
+		query = query.Where(cond, args...)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	return query
 }
 
-// applyServiceFilter applies service filter based on service name and type
-func (r *httpRequestRepo) applyServiceFilter(query *gorm.DB, serviceName string, serviceType string) *gorm.DB {
-	if serviceName == "" {
+// applyServiceFilter applies service filter based on service name, type and match type
+func (r *httpRequestRepo) applyServiceFilter(query *gorm.DB, service filter.Service) *gorm.DB {
+	if service.Name == "" {
 		return query
 	}
 
-	switch serviceType {
-	case "backend_name":
-		return query.Where("backend_name = ?", serviceName)
-	case "backend_url":
-		return query.Where("backend_url = ?", serviceName)
-	case "host":
-		return query.Where("host = ?", serviceName)
-	case "auto", "":
-		// Auto-detection with priority
-		return query.Where("backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?)",
-			serviceName, serviceName, serviceName)
-	default:
-		r.logger.Warn("Unknown service type, defaulting to auto", r.logger.Args("type", serviceType))
-		return query.Where("backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?)",
-			serviceName, serviceName, serviceName)
+	if !service.KnownType() {
+		r.logger.Warn("Unknown service type, defaulting to auto", r.logger.Args("type", service.Type))
 	}
+
+	cond, args := service.Condition()
+	return query.Where(cond, args...)
 }
 
 // FindBySourceName retrieves HTTP requests for a specific log source
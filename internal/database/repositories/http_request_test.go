@@ -0,0 +1,140 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestCreateBatchWithPosition(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	if err := db.Create(&models.LogSource{Name: "access", Path: "/var/log/access.log", ParserType: "traefik"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	batch := func(from, count int) []*models.HTTPRequest {
+		requests := make([]*models.HTTPRequest, 0, count)
+		for i := from; i < from+count; i++ {
+			requests = append(requests, &models.HTTPRequest{
+				SourceName: "access", Timestamp: time.Now(), RequestHash: fmt.Sprint(i),
+				ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/",
+			})
+		}
+		return requests
+	}
+	savedPosition := func() int64 {
+		var source models.LogSource
+		if err := db.Where("name = ?", "access").First(&source).Error; err != nil {
+			t.Fatal(err)
+		}
+		return source.LastPosition
+	}
+	stored := func() int64 {
+		var count int64
+		db.Model(&models.HTTPRequest{}).Count(&count)
+		return count
+	}
+
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+
+	// Larger than one sub-batch, the position is saved with the requests
+	result, err := repo.CreateBatchWithPosition(batch(0, 120), &repositories.SourcePosition{SourceName: "access", Position: 4096, Inode: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 120 || stored() != 120 || savedPosition() != 4096 {
+		t.Fatalf("Expected 120 requests at position 4096, got %d (%d stored) at %d", result.Inserted, stored(), savedPosition())
+	}
+
+	// A failure after the requests were inserted rolls back the whole batch
+	if err := db.Exec("ALTER TABLE log_sources RENAME TO log_sources_moved").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateBatchWithPosition(batch(120, 80), &repositories.SourcePosition{SourceName: "access", Position: 8192}); err == nil {
+		t.Fatal("Expected an error saving the position")
+	}
+	if err := db.Exec("ALTER TABLE log_sources_moved RENAME TO log_sources").Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored() != 120 || savedPosition() != 4096 {
+		t.Errorf("Expected the failed batch to be rolled back, got %d requests at position %d", stored(), savedPosition())
+	}
+}
+
+func TestCreateBatch_HashCollisions(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	if err := db.Create(&models.LogSource{Name: "access", Path: "/var/log/access.log", ParserType: "apache"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	request := func(hash string, path string) *models.HTTPRequest {
+		return &models.HTTPRequest{
+			SourceName: "access", Timestamp: time.Now(), RequestHash: hash,
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: path, StatusCode: 200,
+		}
+	}
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+
+	// Identical duplicates within a batch (a line read twice) aren't collisions
+	result, err := repo.CreateBatch([]*models.HTTPRequest{request("a", "/a"), request("a", "/a"), request("b", "/b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 2 || result.Duplicates != 1 || result.InBatchDuplicates != 1 || len(result.Collisions) != 0 {
+		t.Fatalf("Unexpected first batch result %+v", result)
+	}
+
+	// Against stored requests and within the batch, duplicates of different requests are collisions
+	result, err = repo.CreateBatch([]*models.HTTPRequest{request("a", "/a"), request("b", "/other"), request("c", "/c"), request("c", "/d")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 1 || result.Duplicates != 3 || result.InBatchDuplicates != 1 || len(result.Collisions) != 2 {
+		t.Fatalf("Unexpected second batch result %+v", result)
+	}
+	for _, collision := range result.Collisions {
+		switch collision.Hash {
+		case "b":
+			if collision.Stored != "GET example/b 200 from 10.0.0.1" || collision.Incoming != "GET example/other 200 from 10.0.0.1" {
+				t.Errorf("Unexpected stored collision %+v", collision)
+			}
+		case "c":
+			if collision.Stored != "GET example/c 200 from 10.0.0.1" || collision.Incoming != "GET example/d 200 from 10.0.0.1" {
+				t.Errorf("Unexpected in-batch collision %+v", collision)
+			}
+		default:
+			t.Errorf("Unexpected collision %+v", collision)
+		}
+	}
+
+	// Counters add up per source, the latest collision is kept
+	sources := repositories.NewLogSourceRepository(db)
+	counts := repositories.DedupCounts{Inserted: 3, Duplicates: 4, InBatchDuplicates: 2, HashCollisions: 2, LastCollision: "c: GET example/c / GET example/d"}
+	if err := sources.IncrementDedupCounters("access", counts); err != nil {
+		t.Fatal(err)
+	}
+	if err := sources.IncrementDedupCounters("access", repositories.DedupCounts{Inserted: 1}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := repositories.NewStatsRepository(db, log, 24, nil, nil, nil).GetLogProcessingStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 source, got %d", len(stats))
+	}
+	dedup := stats[0].Dedup
+	if dedup.RecordsInserted != 4 || dedup.DuplicatesSkipped != 4 || dedup.InBatchDuplicates != 2 || dedup.HashCollisions != 2 {
+		t.Errorf("Unexpected dedup stats %+v", dedup)
+	}
+	if dedup.LastHashCollision != counts.LastCollision || dedup.LastHashCollisionAt == nil {
+		t.Errorf("Expected the last collision to be kept, got %q at %v", dedup.LastHashCollision, dedup.LastHashCollisionAt)
+	}
+}
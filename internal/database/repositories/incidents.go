@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// serviceExpression is the service of a request, like the service alert rules and top backends
+const serviceExpression = "COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)"
+
+// lastRequestLookback bounds how far back the start of a silence is searched
+const lastRequestLookback = 24 * time.Hour
+
+// ServiceActivity is the traffic of a service in a detection window, with its usual traffic in that window
+type ServiceActivity struct {
+	Service   string
+	Requests  int64     // Requests in the window
+	Expected  float64   // Average requests in the same window of previous weeks
+	FirstSeen time.Time // First request in the window, zero without
+}
+
+// IncidentRepository stores the zero-traffic incidents of services
+type IncidentRepository interface {
+	// GetServiceActivity returns the services with requests in the window ending at now or in the same window
+	// of the previous weeks
+	GetServiceActivity(now time.Time, window time.Duration, weeks int) ([]*ServiceActivity, error)
+	// GetLastRequest returns the time of the last request of a service before a time, zero if there was none
+	// within a day
+	GetLastRequest(service string, before time.Time) (time.Time, error)
+
+	GetOpenIncidents() ([]*models.ServiceIncident, error)
+	OpenIncident(incident *models.ServiceIncident) error
+	ResolveIncident(id uint, endedAt time.Time) error
+
+	// GetIncidents returns the most recent incidents first, of one service or all ("")
+	GetIncidents(service string, limit int) ([]*models.ServiceIncident, error)
+}
+
+type incidentRepo struct {
+	db *gorm.DB
+}
+
+// NewIncidentRepository creates a new incident repository
+func NewIncidentRepository(db *gorm.DB) IncidentRepository {
+	return &incidentRepo{db: db}
+}
+
+func (r *incidentRepo) withTimeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), DefaultQueryTimeout)
+}
+
+func (r *incidentRepo) GetServiceActivity(now time.Time, window time.Duration, weeks int) ([]*ServiceActivity, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	// The current window and the same window 1..weeks weeks ago, each on the timestamp index
+	ranges := make([]string, 0, weeks+1)
+	args := make([]interface{}, 0, 2*(weeks+1))
+	for k := 0; k <= weeks; k++ {
+		end := now.Add(-time.Duration(k) * 7 * 24 * time.Hour)
+		ranges = append(ranges, "(timestamp > ? AND timestamp <= ?)")
+		args = append(args, end.Add(-window), end)
+	}
+
+	var rows []struct {
+		Service   string
+		Requests  int64
+		Previous  int64
+		FirstSeen string
+	}
+	since := now.Add(-window)
+	err := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
+		Select(serviceExpression+" as service, "+
+			"SUM(CASE WHEN timestamp > ? THEN 1 ELSE 0 END) as requests, "+
+			"SUM(CASE WHEN timestamp > ? THEN 0 ELSE 1 END) as previous, "+
+			"COALESCE(MIN(CASE WHEN timestamp > ? THEN timestamp END), '') as first_seen", since, since, since).
+		Where(strings.Join(ranges, " OR "), args...).
+		Group(serviceExpression).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	activity := make([]*ServiceActivity, 0, len(rows))
+	for _, row := range rows {
+		service := &ServiceActivity{Service: row.Service, Requests: row.Requests}
+		if weeks > 0 {
+			service.Expected = float64(row.Previous) / float64(weeks)
+		}
+		if row.FirstSeen != "" {
+			service.FirstSeen = parseSQLiteTime(row.FirstSeen)
+		}
+		activity = append(activity, service)
+	}
+	return activity, nil
+}
+
+func (r *incidentRepo) GetLastRequest(service string, before time.Time) (time.Time, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	var last string
+	err := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
+		Select("COALESCE(MAX(timestamp), '')").
+		Where("timestamp > ? AND timestamp <= ?", before.Add(-lastRequestLookback), before).
+		Where(serviceExpression+" = ?", service).
+		Scan(&last).Error
+	if err != nil || last == "" {
+		return time.Time{}, err
+	}
+	return parseSQLiteTime(last), nil
+}
+
+func (r *incidentRepo) GetOpenIncidents() ([]*models.ServiceIncident, error) {
+	incidents := make([]*models.ServiceIncident, 0)
+	err := r.db.Where("ended_at IS NULL").Order("started_at").Find(&incidents).Error
+	return incidents, err
+}
+
+func (r *incidentRepo) OpenIncident(incident *models.ServiceIncident) error {
+	return r.db.Create(incident).Error
+}
+
+func (r *incidentRepo) ResolveIncident(id uint, endedAt time.Time) error {
+	result := r.db.Model(&models.ServiceIncident{}).Where("id = ? AND ended_at IS NULL", id).Update("ended_at", endedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no open incident %d", id)
+	}
+	return nil
+}
+
+func (r *incidentRepo) GetIncidents(service string, limit int) ([]*models.ServiceIncident, error) {
+	incidents := make([]*models.ServiceIncident, 0)
+	query := r.db.Order("started_at DESC, id DESC").Limit(limit)
+	if service != "" {
+		query = query.Where("service = ?", service)
+	}
+	err := query.Find(&incidents).Error
+	return incidents, err
+}
@@ -0,0 +1,103 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestIncidents(t *testing.T) {
+	db := fake.NewDB(t)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	hash := 0
+	create := func(backend string, at time.Time) {
+		hash++
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: at, RequestHash: fmt.Sprint(hash),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", BackendName: backend, StatusCode: 200,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// api: silent now, 10 then 30 requests in the same window one and two weeks ago, last seen 40 minutes ago
+	// web: 2 requests now
+	for i := 0; i < 10; i++ {
+		create("api", now.Add(-week-time.Duration(i)*time.Minute))
+	}
+	for i := 0; i < 30; i++ {
+		create("api", now.Add(-2*week-time.Duration(i)*time.Second))
+	}
+	create("api", now.Add(-40*time.Minute))
+	create("web", now.Add(-5*time.Minute))
+	create("web", now.Add(-2*time.Minute))
+
+	repo := repositories.NewIncidentRepository(db)
+	activity, err := repo.GetServiceActivity(now, 15*time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := make(map[string]*repositories.ServiceActivity)
+	for _, service := range activity {
+		services[service.Service] = service
+	}
+	if api := services["api"]; api == nil || api.Requests != 0 || api.Expected != 20 || !api.FirstSeen.IsZero() {
+		t.Errorf("Expected api silent with 20 usual requests, got %+v", api)
+	}
+	if web := services["web"]; web == nil || web.Requests != 2 || web.Expected != 0 || !web.FirstSeen.Equal(now.Add(-5*time.Minute)) {
+		t.Errorf("Expected web with 2 requests first seen 5 minutes ago, got %+v", web)
+	}
+
+	last, err := repo.GetLastRequest("api", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Equal(now.Add(-40 * time.Minute)) {
+		t.Errorf("Expected the last api request 40 minutes ago, got %v", last)
+	}
+	if last, err := repo.GetLastRequest("api", now.Add(-25*time.Hour)); err != nil || !last.IsZero() {
+		t.Errorf("Expected no api request within a day, got %v (%v)", last, err)
+	}
+
+	incident := &models.ServiceIncident{Service: "api", StartedAt: last, DetectedAt: now, ExpectedRequests: 20, WindowSeconds: 900}
+	if err := repo.OpenIncident(incident); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.OpenIncident(&models.ServiceIncident{Service: "web", StartedAt: now.Add(-week), DetectedAt: now.Add(-week)}); err != nil {
+		t.Fatal(err)
+	}
+	open, err := repo.GetOpenIncidents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 2 || open[0].Service != "web" {
+		t.Fatalf("Expected 2 open incidents, oldest first, got %+v", open)
+	}
+
+	if err := repo.ResolveIncident(incident.ID, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ResolveIncident(incident.ID, now.Add(time.Minute)); err == nil {
+		t.Error("Expected an error resolving a resolved incident")
+	}
+	if open, err := repo.GetOpenIncidents(); err != nil || len(open) != 1 || open[0].Service != "web" {
+		t.Errorf("Expected only the web incident open, got %+v (%v)", open, err)
+	}
+
+	incidents, err := repo.GetIncidents("api", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].EndedAt == nil || !incidents[0].EndedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected the resolved api incident, got %+v", incidents)
+	}
+	if incidents, err := repo.GetIncidents("", 10); err != nil || len(incidents) != 2 || incidents[0].Service != "api" {
+		t.Errorf("Expected both incidents, most recent first, got %+v (%v)", incidents, err)
+	}
+}
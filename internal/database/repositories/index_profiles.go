@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// IndexProfile selects which performance indexes are created on http_requests
+// Every index slows down inserts: write-heavy installs trade dashboard query speed for ingestion throughput.
+type IndexProfile string
+
+const (
+	// IndexProfileMinimal only keeps the indexes needed by ingestion, retention and the default dashboard time ranges
+	IndexProfileMinimal IndexProfile = "minimal"
+	// IndexProfileBalanced adds the indexes of the status, service, error and request lookup queries
+	IndexProfileBalanced IndexProfile = "balanced"
+	// IndexProfileAnalytics creates every index (geo, user agent, top-N and covering indexes)
+	IndexProfileAnalytics IndexProfile = "analytics"
+
+	// DefaultIndexProfile keeps all indexes, as before profiles existed
+	DefaultIndexProfile = IndexProfileAnalytics
+)
+
+// indexProfileLevels orders profiles, each profile creates the indexes of the lower ones
+var indexProfileLevels = map[IndexProfile]int{
+	IndexProfileMinimal:   0,
+	IndexProfileBalanced:  1,
+	IndexProfileAnalytics: 2,
+}
+
+// ParseIndexProfile validates a profile name, an empty name selects the default profile
+func ParseIndexProfile(name string) (IndexProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultIndexProfile, nil
+	}
+	profile := IndexProfile(name)
+	if _, ok := indexProfileLevels[profile]; !ok {
+		return DefaultIndexProfile, fmt.Errorf("invalid index profile %q: expected minimal, balanced or analytics", name)
+	}
+	return profile, nil
+}
+
+// Includes reports whether indexes of the other profile are created with this one
+func (p IndexProfile) Includes(other IndexProfile) bool {
+	return indexProfileLevels[p] >= indexProfileLevels[other]
+}
+
+// IndexDefinition is a performance index managed by the index profiles
+type IndexDefinition struct {
+	Name    string
+	Profile IndexProfile // Lowest profile creating the index
+	SQL     string
+}
+
+// PerformanceIndexes are the http_requests indexes created after migrations
+// The unique request_hash index (deduplication), the slow requests index (EnsureSlowRequestIndex) and the
+// full-text search index are not part of the profiles.
+var PerformanceIndexes = []IndexDefinition{
+	// ===== MINIMAL: ingestion, retention and dashboard time ranges =====
+
+	// Time-range filter of almost every query, cleanup deletes by timestamp
+	{"idx_timestamp", IndexProfileMinimal, `CREATE INDEX IF NOT EXISTS idx_timestamp ON http_requests(timestamp DESC)`},
+
+	// Composite index for summary queries (timestamp, status_code, response_time_ms)
+	{"idx_summary_query", IndexProfileMinimal, `CREATE INDEX IF NOT EXISTS idx_summary_query
+	 ON http_requests(timestamp DESC, status_code, response_time_ms)`},
+
+	// Time + Host (per-service time-range queries)
+	{"idx_time_host", IndexProfileMinimal, `CREATE INDEX IF NOT EXISTS idx_time_host
+	 ON http_requests(timestamp DESC, host)`},
+
+	// ClientIP + Time (IP activity timeline)
+	{"idx_ip_time", IndexProfileMinimal, `CREATE INDEX IF NOT EXISTS idx_ip_time
+	 ON http_requests(client_ip, timestamp DESC)`},
+
+	// ===== BALANCED: status, service, error and request lookups =====
+
+	{"idx_source_name", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_source_name ON http_requests(source_name)`},
+	{"idx_partition_key", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_partition_key ON http_requests(partition_key)`},
+	{"idx_host", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_host ON http_requests(host)`},
+	{"idx_status", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_status ON http_requests(status_code)`},
+	{"idx_request_id", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_request_id ON http_requests(request_id)`},
+	{"idx_trace_id", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_trace_id ON http_requests(trace_id)`},
+
+	// Time + Status (error analysis over time)
+	{"idx_time_status", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_time_status
+	 ON http_requests(timestamp DESC, status_code)`},
+
+	// Status + Host (service error rates)
+	{"idx_status_host", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_status_host
+	 ON http_requests(status_code, host)`},
+
+	// Composite index for timestamp + response_time for optimized percentile queries
+	{"idx_timestamp_response_time", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_timestamp_response_time
+	 ON http_requests(timestamp DESC, response_time_ms)
+	 WHERE response_time_ms > 0`},
+
+	// Timeline with service filtering (host + backend_name)
+	{"idx_timeline_service", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_timeline_service
+	 ON http_requests(timestamp DESC, host, backend_name, status_code)`},
+
+	// Errors only (40x and 50x status codes)
+	{"idx_errors_only", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_errors_only
+	 ON http_requests(timestamp DESC, status_code, path, method, client_ip)
+	 WHERE status_code >= 400`},
+
+	// Server errors only (50x)
+	{"idx_server_errors", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_server_errors
+	 ON http_requests(timestamp DESC, status_code, path, backend_name)
+	 WHERE status_code >= 500`},
+
+	// Dashboard covering index (includes most displayed columns)
+	// Note: Removed datetime() WHERE clause as it's non-deterministic in indexes
+	{"idx_dashboard_covering", IndexProfileBalanced, `CREATE INDEX IF NOT EXISTS idx_dashboard_covering
+	 ON http_requests(timestamp DESC, status_code, response_time_ms, host, client_ip, method, path)`},
+
+	// ===== ANALYTICS: geo, user agent, top-N and covering indexes =====
+
+	{"idx_client_ip", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_client_ip ON http_requests(client_ip)`},
+	{"idx_retry_attempts", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_retry_attempts ON http_requests(retry_attempts) WHERE retry_attempts > 0`},
+	{"idx_browser", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_browser ON http_requests(browser)`},
+	{"idx_os", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_os ON http_requests(os)`},
+	{"idx_device_type", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_device_type ON http_requests(device_type)`},
+	{"idx_router_name", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_router_name ON http_requests(router_name)`},
+	{"idx_geo_country", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_geo_country ON http_requests(geo_country)`},
+	{"idx_created_at", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_created_at ON http_requests(created_at DESC)`},
+
+	// Response time index for percentile calculations
+	{"idx_response_time", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_response_time
+	 ON http_requests(response_time_ms)
+	 WHERE response_time_ms > 0`},
+
+	// Timeline queries by date grouping (strftime optimization)
+	{"idx_timeline_date", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_timeline_date
+	 ON http_requests(date(timestamp), status_code, response_time_ms)`},
+
+	// Timeline with backend filtering
+	{"idx_timeline_backend", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_timeline_backend
+	 ON http_requests(timestamp DESC, backend_name, status_code)`},
+
+	// Geographic queries (country-based analytics)
+	{"idx_geo_country_time", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_geo_country_time
+	 ON http_requests(geo_country, timestamp DESC)
+	 WHERE geo_country != ''`},
+
+	// City-level analytics
+	{"idx_geo_city", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_geo_city
+	 ON http_requests(geo_country, geo_city, timestamp DESC)
+	 WHERE geo_city != ''`},
+
+	// ASN analytics (ISP/organization tracking)
+	{"idx_asn_time", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_asn_time
+	 ON http_requests(asn, timestamp DESC)
+	 WHERE asn > 0`},
+
+	// Top paths aggregation (path + timestamp for trending)
+	{"idx_top_paths", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_top_paths
+	 ON http_requests(path, timestamp DESC, status_code)`},
+
+	// Top IPs aggregation
+	{"idx_top_ips", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_top_ips
+	 ON http_requests(client_ip, timestamp DESC, status_code)`},
+
+	// Method distribution
+	{"idx_method_dist", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_method_dist
+	 ON http_requests(method, timestamp DESC)`},
+
+	// Browser/User-Agent analytics
+	{"idx_browser_time", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_browser_time
+	 ON http_requests(browser, timestamp DESC)
+	 WHERE browser != ''`},
+
+	// Requests with retries
+	{"idx_retried_requests", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_retried_requests
+	 ON http_requests(timestamp DESC, retry_attempts, backend_name, status_code)
+	 WHERE retry_attempts > 0`},
+
+	// Error analysis covering index
+	{"idx_error_analysis", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_error_analysis
+	 ON http_requests(timestamp DESC, status_code, path, method, client_ip, response_time_ms, backend_name)
+	 WHERE status_code >= 400`},
+
+	// Cleanup by ascending timestamp, idx_timestamp serves the same queries
+	{"idx_timestamp_cleanup", IndexProfileAnalytics, `CREATE INDEX IF NOT EXISTS idx_timestamp_cleanup
+	 ON http_requests(timestamp)`},
+}
+
+// performanceIndex returns the managed index with this name
+func performanceIndex(name string) (IndexDefinition, bool) {
+	for _, index := range PerformanceIndexes {
+		if index.Name == name {
+			return index, true
+		}
+	}
+	return IndexDefinition{}, false
+}
+
+// ApplyIndexProfile creates the indexes of a profile and drops the managed indexes of higher profiles, then runs ANALYZE
+func ApplyIndexProfile(db *gorm.DB, profile IndexProfile, logger *pterm.Logger) error {
+	if profile == "" {
+		profile = DefaultIndexProfile
+	}
+
+	created, dropped := 0, 0
+	for _, index := range PerformanceIndexes {
+		if !profile.Includes(index.Profile) {
+			var exists int64
+			db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", index.Name).Scan(&exists)
+			if exists == 0 {
+				continue
+			}
+			if err := db.Exec("DROP INDEX IF EXISTS " + index.Name).Error; err != nil {
+				logger.Warn("Failed to drop index", logger.Args("index", index.Name, "error", err))
+				return err
+			}
+			dropped++
+			continue
+		}
+
+		if err := db.Exec(index.SQL).Error; err != nil {
+			logger.Warn("Failed to create index", logger.Args("index", index.Name, "error", err))
+			return err
+		}
+		created++
+	}
+
+	logger.Debug("Performance indexes verified", logger.Args("profile", profile, "count", created, "dropped", dropped))
+	if dropped > 0 {
+		logger.Info("Dropped indexes outside of the index profile", logger.Args("profile", profile, "dropped", dropped))
+	}
+
+	// Analyze tables for query optimizer (only log if it fails)
+	if err := db.Exec("ANALYZE").Error; err != nil {
+		logger.Warn("Failed to analyze database", logger.Args("error", err))
+	} else {
+		logger.Trace("Database statistics analyzed")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestApplyIndexProfile(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	for i := 0; i < 50; i++ {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: time.Now().Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: fmt.Sprint("10.0.0.", i%5), Method: "GET", Host: "example", Path: "/", StatusCode: 200,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exists := func(name string) bool {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", name).Scan(&count)
+		return count > 0
+	}
+	managed := func(profile repositories.IndexProfile) int {
+		count := 0
+		for _, index := range repositories.PerformanceIndexes {
+			if exists(index.Name) != profile.Includes(index.Profile) {
+				t.Errorf("Index %s (%s): unexpected presence with profile %s", index.Name, index.Profile, profile)
+			}
+			if exists(index.Name) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if err := repositories.ApplyIndexProfile(db, repositories.IndexProfileAnalytics, log); err != nil {
+		t.Fatal(err)
+	}
+	all := managed(repositories.IndexProfileAnalytics)
+	if all != len(repositories.PerformanceIndexes) {
+		t.Errorf("Expected every index with the analytics profile, got %d of %d", all, len(repositories.PerformanceIndexes))
+	}
+
+	// Lowering the profile drops the indexes of the higher ones, the deduplication index is kept
+	if err := repositories.ApplyIndexProfile(db, repositories.IndexProfileMinimal, log); err != nil {
+		t.Fatal(err)
+	}
+	if minimal := managed(repositories.IndexProfileMinimal); minimal == 0 || minimal >= all {
+		t.Errorf("Expected the minimal profile to keep a few indexes, got %d", minimal)
+	}
+	if !exists("idx_request_hash") {
+		t.Error("Expected the request hash index to be kept")
+	}
+
+	if _, err := repositories.ParseIndexProfile("everything"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+	if profile, err := repositories.ParseIndexProfile(" Balanced "); err != nil || profile != repositories.IndexProfileBalanced {
+		t.Errorf("Expected balanced, got %q, %v", profile, err)
+	}
+
+	// The advisor reports the indexes the observed statements use
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	advice, err := repo.GetIndexAdvice(repositories.IndexProfileMinimal, []string{
+		"SELECT COUNT(*) FROM http_requests WHERE client_ip = '10.0.0.1' AND timestamp > '2024-01-01'",
+		"SELECT * FROM http_requests WHERE no_such_column = 1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advice.AnalyzedQueries != 1 || advice.FailedQueries != 1 || !advice.StatisticsAvailable || advice.TableRows != 50 {
+		t.Errorf("Unexpected advice summary %+v", advice)
+	}
+	if advice.SuggestedProfile != repositories.IndexProfileMinimal {
+		t.Errorf("Expected the minimal profile to be suggested, got %q", advice.SuggestedProfile)
+	}
+	recommendations := make(map[string]string)
+	for _, usage := range advice.Indexes {
+		recommendations[usage.Name] = usage.Recommendation
+	}
+	if recommendations["idx_ip_time"] != "keep" || recommendations["idx_summary_query"] != "drop" || recommendations["idx_request_hash"] != "keep" {
+		t.Errorf("Unexpected recommendations %v", recommendations)
+	}
+}
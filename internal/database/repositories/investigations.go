@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// Limits of a saved investigation
+const (
+	maxInvestigationNameLength  = 200
+	maxInvestigationNotesLength = 64 * 1024
+	maxInvestigationFilters     = 32
+	maxInvestigationPins        = 200
+	maxInvestigationValueLength = 1024
+)
+
+// ErrInvestigationNotFound is returned for an unknown investigation ID
+var ErrInvestigationNotFound = errors.New("investigation not found")
+
+// ValidateInvestigation normalizes an investigation (trimmed values, pins without duplicates) and checks it
+func ValidateInvestigation(investigation *models.Investigation) error {
+	investigation.Name = strings.TrimSpace(investigation.Name)
+	if investigation.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(investigation.Name) > maxInvestigationNameLength {
+		return fmt.Errorf("name longer than %d characters", maxInvestigationNameLength)
+	}
+	if investigation.From.IsZero() || investigation.To.IsZero() || !investigation.From.Before(investigation.To) {
+		return fmt.Errorf("from and to are required, from before to")
+	}
+	if len(investigation.Notes) > maxInvestigationNotesLength {
+		return fmt.Errorf("notes longer than %d KB", maxInvestigationNotesLength/1024)
+	}
+
+	if len(investigation.Filters) > maxInvestigationFilters {
+		return fmt.Errorf("more than %d filters", maxInvestigationFilters)
+	}
+	for name, value := range investigation.Filters {
+		if strings.TrimSpace(name) == "" || len(name) > 64 || len(value) > maxInvestigationValueLength {
+			return fmt.Errorf("invalid filter %q", name)
+		}
+	}
+
+	ips, err := normalizePins(investigation.PinnedIPs, "IP", func(ip string) (string, error) {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return "", fmt.Errorf("invalid pinned IP %q", ip)
+		}
+		return parsed.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+	paths, err := normalizePins(investigation.PinnedPaths, "path", func(path string) (string, error) {
+		if !strings.HasPrefix(path, "/") || len(path) > maxInvestigationValueLength {
+			return "", fmt.Errorf("invalid pinned path %q (expected an absolute path)", path)
+		}
+		return path, nil
+	})
+	if err != nil {
+		return err
+	}
+	investigation.PinnedIPs, investigation.PinnedPaths = ips, paths
+	return nil
+}
+
+// normalizePins trims and normalizes pinned values, keeping the first occurrence of each
+func normalizePins(values []string, kind string, normalize func(string) (string, error)) ([]string, error) {
+	if len(values) > maxInvestigationPins {
+		return nil, fmt.Errorf("more than %d pinned %ss", maxInvestigationPins, kind)
+	}
+	pins := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		pin, err := normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[pin] {
+			seen[pin] = true
+			pins = append(pins, pin)
+		}
+	}
+	return pins, nil
+}
+
+// InvestigationRepository stores the investigations saved through the API
+type InvestigationRepository interface {
+	// List returns the investigations, most recently updated first
+	List() ([]*models.Investigation, error)
+	Get(id uint) (*models.Investigation, error)
+	Create(investigation *models.Investigation) error
+	// Update replaces every field of an investigation but its ID and creation time
+	Update(investigation *models.Investigation) error
+	Delete(id uint) error
+}
+
+type investigationRepo struct {
+	db *gorm.DB
+}
+
+// NewInvestigationRepository creates a new investigation repository
+func NewInvestigationRepository(db *gorm.DB) InvestigationRepository {
+	return &investigationRepo{db: db}
+}
+
+func (r *investigationRepo) List() ([]*models.Investigation, error) {
+	investigations := make([]*models.Investigation, 0)
+	err := r.db.Order("updated_at DESC, id DESC").Find(&investigations).Error
+	return investigations, err
+}
+
+func (r *investigationRepo) Get(id uint) (*models.Investigation, error) {
+	var investigation models.Investigation
+	err := r.db.First(&investigation, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvestigationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &investigation, nil
+}
+
+func (r *investigationRepo) Create(investigation *models.Investigation) error {
+	investigation.ID, investigation.CreatedAt, investigation.UpdatedAt = 0, time.Time{}, time.Time{}
+	return r.db.Create(investigation).Error
+}
+
+func (r *investigationRepo) Update(investigation *models.Investigation) error {
+	result := r.db.Model(investigation).Select("*").Omit("id", "created_at").Updates(investigation)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvestigationNotFound
+	}
+	return nil
+}
+
+func (r *investigationRepo) Delete(id uint) error {
+	result := r.db.Delete(&models.Investigation{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvestigationNotFound
+	}
+	return nil
+}
+
+// InvestigationMarkdown renders an investigation as a Markdown document, e.g. for an incident report
+func InvestigationMarkdown(investigation *models.Investigation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", investigation.Name)
+	fmt.Fprintf(&b, "- **Time range:** %s to %s\n", investigation.From.UTC().Format(time.RFC3339), investigation.To.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- **Created:** %s\n", investigation.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- **Updated:** %s\n", investigation.UpdatedAt.UTC().Format(time.RFC3339))
+
+	if len(investigation.Filters) > 0 {
+		names := make([]string, 0, len(investigation.Filters))
+		for name := range investigation.Filters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("\n## Filters\n\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "- `%s` = `%s`\n", name, investigation.Filters[name])
+		}
+	}
+	for _, pins := range []struct {
+		title  string
+		values []string
+	}{{"Pinned IPs", investigation.PinnedIPs}, {"Pinned paths", investigation.PinnedPaths}} {
+		if len(pins.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", pins.title)
+		for _, value := range pins.values {
+			fmt.Fprintf(&b, "- `%s`\n", value)
+		}
+	}
+	if notes := strings.TrimSpace(investigation.Notes); notes != "" {
+		fmt.Fprintf(&b, "\n## Notes\n\n%s\n", notes)
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package repositories_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestInvestigationsSaveAndExport(t *testing.T) {
+	repo := repositories.NewInvestigationRepository(fake.NewDB(t))
+	from := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	investigation := &models.Investigation{
+		Name:        "  Login brute force  ",
+		From:        from,
+		To:          from.Add(time.Hour),
+		Filters:     map[string]string{"service": "auth", "exclude_own_ip": "true"},
+		PinnedIPs:   []string{"203.0.113.7", " 203.0.113.7", "2001:DB8::1"},
+		PinnedPaths: []string{"/login"},
+		Notes:       "Blocked AS64500 at 09:40.",
+	}
+	if err := repositories.ValidateInvestigation(investigation); err != nil {
+		t.Fatal(err)
+	}
+	if investigation.Name != "Login brute force" || len(investigation.PinnedIPs) != 2 || investigation.PinnedIPs[1] != "2001:db8::1" {
+		t.Fatalf("Expected a trimmed name and normalized IPs, got %+v", investigation)
+	}
+	if err := repo.Create(investigation); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.Get(investigation.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Filters["service"] != "auth" || len(stored.PinnedPaths) != 1 || !stored.From.Equal(from) || stored.CreatedAt.IsZero() {
+		t.Errorf("Unexpected stored investigation %+v", stored)
+	}
+
+	// Updates replace the fields but keep the creation time
+	stored.Notes = "Resolved."
+	stored.PinnedPaths = nil
+	created := stored.CreatedAt
+	stored.CreatedAt = time.Time{}
+	if err := repo.Update(stored); err != nil {
+		t.Fatal(err)
+	}
+	list, err := repo.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Notes != "Resolved." || len(list[0].PinnedPaths) != 0 || !list[0].CreatedAt.Equal(created) {
+		t.Errorf("Unexpected investigations after the update %+v", list)
+	}
+
+	markdown := repositories.InvestigationMarkdown(list[0])
+	for _, expected := range []string{"# Login brute force", "2026-03-14T09:00:00Z to 2026-03-14T10:00:00Z", "- `service` = `auth`", "- `203.0.113.7`", "## Notes\n\nResolved."} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Expected %q in the Markdown export:\n%s", expected, markdown)
+		}
+	}
+	if strings.Contains(markdown, "Pinned paths") {
+		t.Errorf("Expected no pinned paths section:\n%s", markdown)
+	}
+
+	if err := repo.Delete(investigation.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(investigation.ID); !errors.Is(err, repositories.ErrInvestigationNotFound) {
+		t.Errorf("Expected the deleted investigation to be missing, got %v", err)
+	}
+	if err := repo.Update(stored); !errors.Is(err, repositories.ErrInvestigationNotFound) {
+		t.Errorf("Expected updating a deleted investigation to fail, got %v", err)
+	}
+}
+
+func TestValidateInvestigationRejects(t *testing.T) {
+	from := time.Now()
+	for name, investigation := range map[string]models.Investigation{
+		"no name":      {From: from, To: from.Add(time.Hour)},
+		"no range":     {Name: "x"},
+		"reversed":     {Name: "x", From: from, To: from.Add(-time.Hour)},
+		"invalid IP":   {Name: "x", From: from, To: from.Add(time.Hour), PinnedIPs: []string{"example.com"}},
+		"invalid path": {Name: "x", From: from, To: from.Add(time.Hour), PinnedPaths: []string{"login"}},
+	} {
+		if err := repositories.ValidateInvestigation(&investigation); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
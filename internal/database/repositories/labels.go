@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LabelPrefix names a request label in structured query groups and filters, e.g. "label:customer"
+const LabelPrefix = "label:"
+
+// MaxLabelValueLength bounds label values, longer values are cut at ingestion
+const MaxLabelValueLength = 256
+
+// labelNamePattern matches label names, they are embedded in group expressions
+var labelNamePattern = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// ValidateLabelName checks the name of a label, names are lowercase like the structured query groups
+func ValidateLabelName(name string) error {
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label name %q (lowercase letters, digits, '_', '.', '-', up to 64)", name)
+	}
+	return nil
+}
+
+// labelExpression returns the value of a label of each request, NULL for requests without it
+// The name must be valid (ValidateLabelName), which keeps it safe inside the statement.
+func labelExpression(name string) string {
+	return `(SELECT request_labels.value FROM request_labels WHERE request_labels.request_hash = http_requests.request_hash AND request_labels.name = '` + name + `')`
+}
+
+// labelCondition matches requests having a label value
+const labelCondition = `EXISTS (SELECT 1 FROM request_labels WHERE request_labels.request_hash = http_requests.request_hash AND request_labels.name = ? AND request_labels.value = ?)`
+
+// LabelFilter matches requests by their labels, all must match
+type LabelFilter map[string]string
+
+// ParseLabelFilter parses label values by name, e.g. {"customer": "acme"}; nil when values is empty
+func ParseLabelFilter(values map[string]string) (LabelFilter, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	filter := make(LabelFilter, len(values))
+	for name, value := range values {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if err := ValidateLabelName(name); err != nil {
+			return nil, err
+		}
+		filter[name] = value
+	}
+	return filter, nil
+}
+
+// apply adds the label conditions, in name order so the statement is stable
+func (f LabelFilter) apply(query *gorm.DB) *gorm.DB {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query = query.Where(labelCondition, name, f[name])
+	}
+	return query
+}
+
+// insertLabels stores the labels of inserted requests, in the caller's transaction
+// Labels of requests that were duplicates already exist and are skipped.
+func insertLabels(tx *gorm.DB, requests []*models.HTTPRequest) error {
+	var labels []models.RequestLabel
+	for _, request := range requests {
+		if request.RequestHash == "" {
+			continue
+		}
+		for name, value := range request.Labels {
+			if ValidateLabelName(name) != nil || value == "" {
+				continue
+			}
+			if len(value) > MaxLabelValueLength {
+				value = strings.ToValidUTF8(value[:MaxLabelValueLength], "")
+			}
+			labels = append(labels, models.RequestLabel{RequestHash: request.RequestHash, Name: name, Value: value})
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	// 3 variables per label, well under the SQLite variable limit
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(labels, 500).Error
+}
+
+// LabelStats summarizes one label over the time range
+type LabelStats struct {
+	Name     string  `json:"name"`
+	Requests int64   `json:"requests"`  // Requests having the label
+	Share    float64 `json:"share"`     // Percentage of all requests having the label
+	Values   int64   `json:"values"`    // Distinct values
+	TopValue string  `json:"top_value"` // Most frequent value
+	TopShare float64 `json:"top_share"` // Percentage of the labeled requests having the top value
+	GroupBy  string  `json:"group_by"`  // Structured query group, e.g. "label:customer"
+}
+
+// GetLabels returns the labels of the requests in the time range, most used first
+func (r *statsRepo) GetLabels(filters []filter.Service) ([]*LabelStats, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	requests := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).Where("timestamp > ?", r.getTimeRange())
+	requests = r.applyServiceFilters(requests, filters)
+
+	var total int64
+	if err := requests.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	type valueCount struct {
+		Name     string
+		Value    string
+		Requests int64
+	}
+	var counts []valueCount
+	err := r.db.WithContext(ctx).Table("request_labels").
+		Select("request_labels.name, request_labels.value, COUNT(*) AS requests").
+		Where("request_labels.request_hash IN (?)", requests.Session(&gorm.Session{}).Select("request_hash")).
+		Group("request_labels.name, request_labels.value").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*LabelStats)
+	top := make(map[string]int64)
+	for _, count := range counts {
+		stats := byName[count.Name]
+		if stats == nil {
+			stats = &LabelStats{Name: count.Name, GroupBy: LabelPrefix + count.Name}
+			byName[count.Name] = stats
+		}
+		stats.Requests += count.Requests
+		stats.Values++
+		if count.Requests > top[count.Name] || (count.Requests == top[count.Name] && count.Value < stats.TopValue) {
+			top[count.Name], stats.TopValue = count.Requests, count.Value
+		}
+	}
+
+	labels := make([]*LabelStats, 0, len(byName))
+	for _, stats := range byName {
+		if total > 0 {
+			stats.Share = 100 * float64(stats.Requests) / float64(total)
+		}
+		stats.TopShare = 100 * float64(top[stats.Name]) / float64(stats.Requests)
+		labels = append(labels, stats)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Requests != labels[j].Requests {
+			return labels[i].Requests > labels[j].Requests
+		}
+		return labels[i].Name < labels[j].Name
+	})
+	return labels, nil
+}
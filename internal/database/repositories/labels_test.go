@@ -0,0 +1,99 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestRequestLabels(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	if err := db.Create(&models.LogSource{Name: "access", Path: "/var/log/access.log", ParserType: "traefik"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// acme has 3 requests on v2 and 1 on v1, globex 1 without api_version, 1 request has no label
+	labels := []map[string]string{
+		{"customer": "acme", "api_version": "v2"},
+		{"customer": "acme", "api_version": "v2"},
+		{"customer": "acme", "api_version": "v2"},
+		{"customer": "acme", "api_version": "v1"},
+		{"customer": "globex"},
+		nil,
+	}
+	var requests []*models.HTTPRequest
+	for i, set := range labels {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "access", Timestamp: time.Now(), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", StatusCode: 200, Labels: set,
+		})
+	}
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+	if _, err := repo.CreateBatch(requests); err != nil {
+		t.Fatal(err)
+	}
+	// Duplicates don't fail on their labels
+	if result, err := repo.CreateBatch(requests[:2]); err != nil || result.Duplicates != 2 {
+		t.Fatalf("CreateBatch() of duplicates = %+v, %v", result, err)
+	}
+
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	stats, err := statsRepo.GetLabels(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].Name != "customer" || stats[0].Requests != 5 || stats[0].Values != 2 || stats[0].TopValue != "acme" || stats[0].TopShare != 80 {
+		t.Fatalf("GetLabels() = %+v", stats[0])
+	}
+	if stats[1].Name != "api_version" || stats[1].Requests != 4 || stats[1].TopValue != "v2" || stats[1].GroupBy != "label:api_version" {
+		t.Errorf("GetLabels() = %+v", stats[1])
+	}
+
+	query := &repositories.StructuredQuery{GroupBy: "label:api_version", Filters: map[string]string{"label:Customer": "acme"}}
+	if err := query.Normalize(24); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := statsRepo.RunQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Group != "v2" || rows[0].Value != 3 || rows[1].Group != "v1" {
+		t.Errorf("RunQuery() grouped by label = %+v", rows)
+	}
+	if call := query.APICall(); call != "/api/v1/query?group_by=label%3Aapi_version&hours=24&label%5Bcustomer%5D=acme&limit=10&metric=requests" {
+		t.Errorf("APICall() = %s", call)
+	}
+
+	filter, err := repositories.ParseLabelFilter(map[string]string{"customer": "globex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	err = repo.Stream(repositories.RequestFilter{Labels: filter}, func(r *models.HTTPRequest) error {
+		found = append(found, r.RequestHash)
+		return nil
+	})
+	if err != nil || len(found) != 1 || found[0] != "4" {
+		t.Errorf("Stream() with label filter = %v, %v", found, err)
+	}
+	if _, err := repositories.ParseLabelFilter(map[string]string{"a'b": "x"}); err == nil {
+		t.Error("ParseLabelFilter() accepted an invalid name")
+	}
+
+	// Labels are deleted with their requests
+	if err := db.Exec("DELETE FROM http_requests").Error; err != nil {
+		t.Fatal(err)
+	}
+	var remaining int64
+	db.Model(&models.RequestLabel{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("%d labels left after their requests were deleted", remaining)
+	}
+}
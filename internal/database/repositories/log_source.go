@@ -12,7 +12,11 @@ type LogSourceRepository interface {
 	FindByName(name string) (*models.LogSource, error)
 	FindAll() ([]*models.LogSource, error)
 	Update(source *models.LogSource) error
-	UpdateTracking(name string, position int64, inode int64, lastLine string) error
+	UpdateTracking(name string, position int64, inode int64, lastLine string, fingerprint string) error
+	IncrementDedupCounters(name string, counts DedupCounts) error
+	SetProcessorRunning(name string, running bool) error
+	// AddCoverage adds seconds a processor read the source to the coverage of the hour holding at
+	AddCoverage(name string, at time.Time, seconds int64) error
 }
 
 type logSourceRepo struct {
@@ -46,10 +50,75 @@ func (r *logSourceRepo) Update(source *models.LogSource) error {
 	return r.db.Save(source).Error
 }
 
-func (r *logSourceRepo) UpdateTracking(name string, position int64, inode int64, lastLine string) error {
+func (r *logSourceRepo) UpdateTracking(name string, position int64, inode int64, lastLine string, fingerprint string) error {
+	return updateTracking(r.db, &SourcePosition{
+		SourceName:  name,
+		Position:    position,
+		Inode:       inode,
+		LastLine:    lastLine,
+		Fingerprint: fingerprint,
+	})
+}
+
+// SourcePosition is the read state of a log source, saved to resume reading after a restart
+type SourcePosition struct {
+	SourceName  string
+	Position    int64
+	Inode       int64
+	LastLine    string
+	Fingerprint string
+}
+
+// updateTracking saves a source position, db may be a transaction
+func updateTracking(db *gorm.DB, pos *SourcePosition) error {
 	// Use Exec for better performance with direct SQL execution
+	return db.Exec(
+		"UPDATE log_sources SET last_position = ?, last_inode = ?, last_line_content = ?, first_line_fingerprint = ?, last_read_at = ?, updated_at = ? WHERE name = ?",
+		pos.Position, pos.Inode, pos.LastLine, pos.Fingerprint, time.Now(), time.Now(), pos.SourceName,
+	).Error
+}
+
+// DedupCounts is the dedup outcome of a flushed batch, added to the counters of its source
+type DedupCounts struct {
+	Inserted          int64
+	Duplicates        int64 // Including the in-batch ones
+	InBatchDuplicates int64
+	FirstLoadInserts  int64
+	HashCollisions    int64
+	LastCollision     string // Latest collision of the batch, the saved one is kept when empty
+}
+
+// IncrementDedupCounters adds the outcome of a flushed batch to the per-source dedup counters
+func (r *logSourceRepo) IncrementDedupCounters(name string, counts DedupCounts) error {
+	if counts.LastCollision != "" {
+		err := r.db.Exec("UPDATE log_sources SET last_hash_collision = ?, last_hash_collision_at = ? WHERE name = ?",
+			counts.LastCollision, time.Now(), name).Error
+		if err != nil {
+			return err
+		}
+	}
+	return r.db.Exec(
+		"UPDATE log_sources SET records_inserted = records_inserted + ?, duplicates_skipped = duplicates_skipped + ?, first_load_inserts = first_load_inserts + ?, "+
+			"in_batch_duplicates = in_batch_duplicates + ?, hash_collisions = hash_collisions + ? WHERE name = ?",
+		counts.Inserted, counts.Duplicates, counts.FirstLoadInserts, counts.InBatchDuplicates, counts.HashCollisions, name,
+	).Error
+}
+
+// SetProcessorRunning marks whether a processor reads the source, a mark left at startup means it didn't stop cleanly
+func (r *logSourceRepo) SetProcessorRunning(name string, running bool) error {
+	return r.db.Exec("UPDATE log_sources SET processor_running = ? WHERE name = ?", running, name).Error
+}
+
+// CoverageHour returns the coverage hour holding a time, the UTC hour like hourly timeline buckets
+func CoverageHour(at time.Time) string {
+	return at.UTC().Format("2006-01-02 15:00")
+}
+
+// AddCoverage adds seconds a processor read the source to the coverage of the hour holding at, capped at an hour
+func (r *logSourceRepo) AddCoverage(name string, at time.Time, seconds int64) error {
 	return r.db.Exec(
-		"UPDATE log_sources SET last_position = ?, last_inode = ?, last_line_content = ?, last_read_at = ?, updated_at = ? WHERE name = ?",
-		position, inode, lastLine, time.Now(), time.Now(), name,
+		`INSERT INTO source_coverage (source_name, hour, alive_seconds) VALUES (?, ?, MIN(3600, ?))
+		ON CONFLICT (source_name, hour) DO UPDATE SET alive_seconds = MIN(3600, alive_seconds + excluded.alive_seconds)`,
+		name, CoverageHour(at), seconds,
 	).Error
 }
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	requestSchemaOnce sync.Once
+	requestSchema     *schema.Schema
+	requestSchemaErr  error
+)
+
+// httpRequestSchema returns the parsed GORM schema of HTTPRequest (column and field names)
+func httpRequestSchema() (*schema.Schema, error) {
+	requestSchemaOnce.Do(func() {
+		requestSchema, requestSchemaErr = schema.Parse(&models.HTTPRequest{}, &sync.Map{}, schema.NamingStrategy{})
+	})
+	return requestSchema, requestSchemaErr
+}
+
+// RequestFieldSet is a subset of HTTPRequest columns selected with the fields parameter
+// Only these columns are read from the database, and responses contain only these keys
+type RequestFieldSet struct {
+	fields []*schema.Field
+}
+
+// ParseRequestFields parses a comma-separated list of columns (client_ip) or response keys (ClientIP)
+// Returns nil for an empty list, meaning all fields
+func ParseRequestFields(list string) (*RequestFieldSet, error) {
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, nil
+	}
+
+	s, err := httpRequestSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &RequestFieldSet{}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(RequestFieldNames(), ", "))
+		}
+		if seen[field.DBName] {
+			continue
+		}
+		seen[field.DBName] = true
+		set.fields = append(set.fields, field)
+	}
+
+	if len(set.fields) == 0 {
+		return nil, nil
+	}
+	return set, nil
+}
+
+// RequestFieldNames lists the columns accepted by ParseRequestFields
+func RequestFieldNames() []string {
+	s, err := httpRequestSchema()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(s.DBNames))
+	names = append(names, s.DBNames...)
+	sort.Strings(names)
+	return names
+}
+
+// Columns returns the database columns to select
+func (f *RequestFieldSet) Columns() []string {
+	columns := make([]string, len(f.fields))
+	for i, field := range f.fields {
+		columns[i] = field.DBName
+	}
+	return columns
+}
+
+// MarshalRequest encodes the selected fields of a request as a JSON object
+// Keys match the full request representation (ClientIP, StatusCode...) and keep the requested order
+func (f *RequestFieldSet) MarshalRequest(request *models.HTTPRequest) ([]byte, error) {
+	value := reflect.ValueOf(request).Elem()
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range f.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Name)
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		data, err := json.Marshal(field.ReflectValueOf(context.Background(), value).Interface())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// searchIndexMinTermLength is the shortest term the trigram index can match, shorter terms are scanned with LIKE
+const searchIndexMinTermLength = 3
+
+// searchColumns maps the accepted field names to http_requests columns
+var searchColumns = map[string]string{
+	"path":       "path",
+	"referrer":   "referer",
+	"referer":    "referer",
+	"user_agent": "user_agent",
+}
+
+// RequestSearch is a parsed full-text query on path, referrer and user agent
+// Words match as substrings, "quoted phrases" as exact substrings; all terms must match (AND).
+type RequestSearch struct {
+	terms   []string
+	columns []string
+}
+
+// ParseRequestSearch parses a search query, field limits it to one column (path, referrer or user_agent)
+func ParseRequestSearch(q string, field string) (*RequestSearch, error) {
+	search := &RequestSearch{columns: []string{"path", "referer", "user_agent"}}
+	if field != "" {
+		column, ok := searchColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q (expected path, referrer or user_agent)", field)
+		}
+		search.columns = []string{column}
+	}
+
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		var term string
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in search query")
+			}
+			term, q = q[1:end+1], q[end+2:]
+		} else if end := strings.IndexAny(q, " \t"); end >= 0 {
+			term, q = q[:end], q[end:]
+		} else {
+			term, q = q, ""
+		}
+		if term != "" {
+			search.terms = append(search.terms, term)
+		}
+	}
+
+	if len(search.terms) == 0 {
+		return nil, fmt.Errorf("search query is empty")
+	}
+	return search, nil
+}
+
+// apply restricts a query on http_requests to rows matching every term
+// Terms the trigram index can match go through the FTS5 table, the others (and all terms without index) use LIKE
+func (s *RequestSearch) apply(query *gorm.DB, useIndex bool) *gorm.DB {
+	var matches []string
+	for _, term := range s.terms {
+		if useIndex && utf8.RuneCountInString(term) >= searchIndexMinTermLength {
+			matches = append(matches, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+			continue
+		}
+
+		pattern := "%" + escapeLike(term) + "%"
+		conditions := make([]string, len(s.columns))
+		args := make([]interface{}, len(s.columns))
+		for i, column := range s.columns {
+			conditions[i] = column + ` LIKE ? ESCAPE '\'`
+			args[i] = pattern
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	if len(matches) > 0 {
+		expression := strings.Join(matches, " AND ")
+		if len(s.columns) == 1 {
+			expression = s.columns[0] + " : (" + expression + ")"
+		}
+		query = query.Where("id IN (SELECT rowid FROM http_requests_fts WHERE http_requests_fts MATCH ?)", expression)
+	}
+	return query
+}
+
+// escapeLike escapes LIKE wildcards so terms match literally
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
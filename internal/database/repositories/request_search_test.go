@@ -0,0 +1,99 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParseRequestSearch(t *testing.T) {
+	if _, err := repositories.ParseRequestSearch(`  api "Mozilla/5.0 (X11"  v2 `, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{"", "   ", `"unterminated`} {
+		if _, err := repositories.ParseRequestSearch(q, ""); err == nil {
+			t.Errorf("Expected an error for query %q", q)
+		}
+	}
+	if _, err := repositories.ParseRequestSearch("api", "status_code"); err == nil {
+		t.Error("Expected an error for an unsupported field")
+	}
+}
+
+// newSearchRepository creates a database holding the test requests, with or without full-text index
+func newSearchRepository(t *testing.T, index bool) repositories.HTTPRequestRepository {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	database.EnsureSearchIndex(db, index, log)
+
+	requests := []struct{ path, referer, userAgent string }{
+		{"/api/v1/users", "", "curl/8.0"},
+		{"/api/v1/orders", "https://shop.example/cart", "Mozilla/5.0 (X11; Linux)"},
+		{"/static/app_v2.js", "https://shop.example/", "Mozilla/5.0 (Macintosh)"},
+		{"/wp-login.php", "", "python-requests/2.31"},
+	}
+	for i, r := range requests {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: time.Now().Add(time.Duration(i) * time.Second), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: r.path, Referer: r.referer, UserAgent: r.userAgent,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Deleted requests must leave the index
+	if err := db.Where("path = ?", "/wp-login.php").Delete(&models.HTTPRequest{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return repositories.NewHTTPRequestRepository(db, log, "")
+}
+
+func TestSearch_IndexAndScanAgree(t *testing.T) {
+	cases := []struct {
+		q, field string
+		expected []string // Paths, most recent first
+	}{
+		{"api", "", []string{"/api/v1/orders", "/api/v1/users"}},
+		{"API users", "", []string{"/api/v1/users"}},
+		{"shop.example", "", []string{"/static/app_v2.js", "/api/v1/orders"}},
+		{"shop.example", "path", nil},
+		{`"Mozilla/5.0 (X11"`, "user_agent", []string{"/api/v1/orders"}},
+		{"_v2", "", []string{"/static/app_v2.js"}},              // LIKE wildcards match literally
+		{"v1", "", []string{"/api/v1/orders", "/api/v1/users"}}, // Shorter than a trigram
+		{"login", "", nil},
+	}
+
+	for _, index := range []bool{true, false} {
+		repo := newSearchRepository(t, index)
+		t.Logf("index requested: %v, available: %v", index, repo.SearchIndexAvailable())
+		if !index && repo.SearchIndexAvailable() {
+			t.Error("Expected no full-text index when disabled")
+		}
+
+		for _, tc := range cases {
+			search, err := repositories.ParseRequestSearch(tc.q, tc.field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			err = repo.Stream(repositories.RequestFilter{Search: search}, func(r *models.HTTPRequest) error {
+				paths = append(paths, r.Path)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("index=%v q=%q: %v", index, tc.q, err)
+			}
+			if fmt.Sprint(paths) != fmt.Sprint(tc.expected) {
+				t.Errorf("index=%v q=%q field=%q: expected %v, got %v", index, tc.q, tc.field, tc.expected, paths)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/filter"
+
+	"github.com/pterm/pterm"
+)
+
+func TestServiceFilter_MatchTypes(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	backends := []string{"api-users@docker", "api-orders@docker", "API-admin@docker", "web@docker", "api-[legacy]@file"}
+	for i, backend := range backends {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: time.Now().Add(time.Duration(i) * time.Second), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", BackendName: backend,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+
+	cases := []struct {
+		matchType, pattern string
+		expected           []string // Backends, most recent first
+	}{
+		{filter.MatchExact, "web@docker", []string{"web@docker"}},
+		{filter.MatchPrefix, "api-", []string{"api-[legacy]@file", "api-orders@docker", "api-users@docker"}}, // Case-sensitive
+		{filter.MatchPrefix, "api-[", []string{"api-[legacy]@file"}},                                         // Brackets match literally
+		{filter.MatchWildcard, "api-*@docker", []string{"api-orders@docker", "api-users@docker"}},
+		{filter.MatchWildcard, "api-[legacy]@*", []string{"api-[legacy]@file"}},
+		{filter.MatchWildcard, "web@docke?", []string{"web@docker"}},
+		{filter.MatchRegex, `(?i)^api-(users|admin)@`, []string{"API-admin@docker", "api-users@docker"}},
+	}
+
+	for _, tc := range cases {
+		var found []string
+		err := repo.Stream(repositories.RequestFilter{ServiceName: tc.pattern, ServiceType: "backend_name", ServiceMatchType: tc.matchType}, func(r *models.HTTPRequest) error {
+			found = append(found, r.BackendName)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s %q: %v", tc.matchType, tc.pattern, err)
+		}
+		if fmt.Sprint(found) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s %q: expected %v, got %v", tc.matchType, tc.pattern, tc.expected, found)
+		}
+
+		// In-memory matching (real-time metrics) must agree with SQL
+		var matched []string
+		for i := len(backends) - 1; i >= 0; i-- {
+			if filter.MatchValue(tc.matchType, tc.pattern, backends[i]) {
+				matched = append(matched, backends[i])
+			}
+		}
+		if fmt.Sprint(matched) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s %q in memory: expected %v, got %v", tc.matchType, tc.pattern, tc.expected, matched)
+		}
+	}
+}
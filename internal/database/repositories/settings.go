@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Theme modes
+const (
+	ThemeDark  = "dark"
+	ThemeLight = "light"
+	ThemeAuto  = "auto" // Follows the color scheme of the browser
+)
+
+// Limits of the branding, the logo and name are part of every page
+const (
+	maxLogoLength      = 256 * 1024
+	maxBrandNameLength = 64
+)
+
+var accentColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// logoDataPrefixes are the data URIs accepted as logo, images only
+var logoDataPrefixes = []string{"data:image/png;base64,", "data:image/jpeg;base64,", "data:image/gif;base64,", "data:image/webp;base64,", "data:image/svg+xml;base64,"}
+
+// ThemeSettings brand the dashboard, e.g. for agencies showing it to their clients
+type ThemeSettings struct {
+	Mode        string `json:"mode"`                   // ThemeDark, ThemeLight or ThemeAuto
+	AccentColor string `json:"accent_color,omitempty"` // #rrggbb replacing the LogLynx orange, empty for the default
+	LogoURL     string `json:"logo_url,omitempty"`     // http(s) URL, absolute path or base64 data:image URI replacing the LogLynx icon
+	BrandName   string `json:"brand_name,omitempty"`   // Replaces "LogLynx" in the sidebar and page titles
+}
+
+// Settings are the dashboard settings stored in the database, sections it doesn't hold keep their defaults
+type Settings struct {
+	Theme ThemeSettings `json:"theme"`
+}
+
+// DefaultSettings returns the settings of a database nobody changed them in
+func DefaultSettings() *Settings {
+	return &Settings{Theme: ThemeSettings{Mode: ThemeDark}}
+}
+
+// sections maps the keys of the settings table to the sections they hold
+func (s *Settings) sections() map[string]any {
+	return map[string]any{"theme": &s.Theme}
+}
+
+// Validate normalizes the theme (trimmed values, lowercase colors) and checks it
+func (t *ThemeSettings) Validate() error {
+	t.Mode = strings.ToLower(strings.TrimSpace(t.Mode))
+	t.AccentColor = strings.ToLower(strings.TrimSpace(t.AccentColor))
+	t.LogoURL = strings.TrimSpace(t.LogoURL)
+	t.BrandName = strings.TrimSpace(t.BrandName)
+
+	switch t.Mode {
+	case "":
+		t.Mode = ThemeDark
+	case ThemeDark, ThemeLight, ThemeAuto:
+	default:
+		return fmt.Errorf("invalid theme mode %q (expected %s, %s or %s)", t.Mode, ThemeDark, ThemeLight, ThemeAuto)
+	}
+	if t.AccentColor != "" && !accentColorPattern.MatchString(t.AccentColor) {
+		return fmt.Errorf("invalid accent color %q (expected #rrggbb)", t.AccentColor)
+	}
+	if utf8.RuneCountInString(t.BrandName) > maxBrandNameLength {
+		return fmt.Errorf("brand name longer than %d characters", maxBrandNameLength)
+	}
+	return validateLogoURL(t.LogoURL)
+}
+
+// validateLogoURL accepts http(s) URLs, absolute paths (e.g. /static/...) and image data URIs
+func validateLogoURL(logo string) error {
+	if logo == "" {
+		return nil
+	}
+	if len(logo) > maxLogoLength {
+		return fmt.Errorf("logo longer than %d KB", maxLogoLength/1024)
+	}
+	if strings.HasPrefix(logo, "data:") {
+		for _, prefix := range logoDataPrefixes {
+			if strings.HasPrefix(logo, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid logo data URI (expected base64 PNG, JPEG, GIF, WebP or SVG)")
+	}
+	parsed, err := url.Parse(logo)
+	if err != nil {
+		return fmt.Errorf("invalid logo URL: %w", err)
+	}
+	switch {
+	case parsed.Scheme == "http" || parsed.Scheme == "https":
+		if parsed.Host == "" {
+			return fmt.Errorf("invalid logo URL %q: missing host", logo)
+		}
+	case parsed.Scheme == "" && parsed.Host == "" && strings.HasPrefix(logo, "/") && !strings.HasPrefix(logo, "//"):
+	default:
+		return fmt.Errorf("invalid logo URL %q (expected http(s) URL, absolute path or data:image URI)", logo)
+	}
+	return nil
+}
+
+// SettingsRepository stores the dashboard settings changed through the API
+type SettingsRepository interface {
+	Get() (*Settings, error)
+	Save(settings *Settings) error
+}
+
+type settingsRepo struct {
+	db *gorm.DB
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(db *gorm.DB) SettingsRepository {
+	return &settingsRepo{db: db}
+}
+
+// Get returns the stored settings over the defaults, keys of newer versions are ignored
+func (r *settingsRepo) Get() (*Settings, error) {
+	var rows []models.Setting
+	if err := r.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	settings := DefaultSettings()
+	sections := settings.sections()
+	for _, row := range rows {
+		section, ok := sections[row.Key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(row.Value), section); err != nil {
+			return nil, fmt.Errorf("invalid %s settings: %w", row.Key, err)
+		}
+	}
+	return settings, nil
+}
+
+// Save stores every section of the settings in one transaction
+func (r *settingsRepo) Save(settings *Settings) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for key, section := range settings.sections() {
+			value, err := json.Marshal(section)
+			if err != nil {
+				return err
+			}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+			}).Create(&models.Setting{Key: key, Value: string(value)}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
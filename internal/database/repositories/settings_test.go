@@ -0,0 +1,78 @@
+package repositories_test
+
+import (
+	"strings"
+	"testing"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestSettingsSaveAndGet(t *testing.T) {
+	db := fake.NewDB(t)
+	repo := repositories.NewSettingsRepository(db)
+
+	settings, err := repo.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Theme.Mode != repositories.ThemeDark || settings.Theme.AccentColor != "" {
+		t.Fatalf("Expected the default theme, got %+v", settings.Theme)
+	}
+
+	settings.Theme = repositories.ThemeSettings{Mode: repositories.ThemeLight, AccentColor: "#0055aa", BrandName: "Acme"}
+	if err := repo.Save(settings); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again updates the row
+	settings.Theme.BrandName = "Acme Agency"
+	if err := repo.Save(settings); err != nil {
+		t.Fatal(err)
+	}
+	// Sections of newer versions are kept but ignored
+	if err := db.Create(&models.Setting{Key: "future", Value: `{"x":1}`}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Theme != settings.Theme {
+		t.Errorf("Expected %+v, got %+v", settings.Theme, stored.Theme)
+	}
+}
+
+func TestThemeSettingsValidate(t *testing.T) {
+	tests := []struct {
+		theme repositories.ThemeSettings
+		valid bool
+	}{
+		{repositories.ThemeSettings{}, true},
+		{repositories.ThemeSettings{Mode: " Auto ", AccentColor: "#FF00AA"}, true},
+		{repositories.ThemeSettings{Mode: "sepia"}, false},
+		{repositories.ThemeSettings{AccentColor: "red"}, false},
+		{repositories.ThemeSettings{AccentColor: "#fff"}, false},
+		{repositories.ThemeSettings{LogoURL: "https://cdn.example.com/logo.svg"}, true},
+		{repositories.ThemeSettings{LogoURL: "/static/images/acme.png"}, true},
+		{repositories.ThemeSettings{LogoURL: "data:image/png;base64,iVBORw0KGgo="}, true},
+		{repositories.ThemeSettings{LogoURL: "data:text/html;base64,PHNjcmlwdD4="}, false},
+		{repositories.ThemeSettings{LogoURL: "javascript:alert(1)"}, false},
+		{repositories.ThemeSettings{LogoURL: "//evil.example.com/logo.png"}, false},
+		{repositories.ThemeSettings{LogoURL: "data:image/png;base64," + strings.Repeat("A", 300*1024)}, false},
+		{repositories.ThemeSettings{BrandName: strings.Repeat("x", 65)}, false},
+	}
+	for _, tt := range tests {
+		theme := tt.theme
+		err := theme.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%.60v) = %v, want valid %v", tt.theme, err, tt.valid)
+		}
+	}
+
+	theme := repositories.ThemeSettings{Mode: " Auto ", AccentColor: "#FF00AA"}
+	if err := theme.Validate(); err != nil || theme.Mode != repositories.ThemeAuto || theme.AccentColor != "#ff00aa" {
+		t.Errorf("Expected a normalized theme, got %+v (%v)", theme, err)
+	}
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// SlowRequestIndex is the partial index holding requests slower than the lowest configured threshold
+const SlowRequestIndex = "idx_slow_requests"
+
+// SlowPolicy defines from which response time a request is slow, globally and per service
+// An API answering in 500ms is slow while a file server taking 5s is not.
+type SlowPolicy struct {
+	defaultMs float64
+	services  []serviceSlowThreshold // Ordered to keep generated SQL deterministic
+}
+
+type serviceSlowThreshold struct {
+	name string
+	ms   float64
+}
+
+// NewDefaultSlowPolicy returns a policy where every request over SlowRequestThresholdMs is slow
+func NewDefaultSlowPolicy() *SlowPolicy {
+	return &SlowPolicy{defaultMs: SlowRequestThresholdMs}
+}
+
+// ParseSlowPolicy builds a policy from the global threshold and per-service overrides
+// Overrides are semicolon-separated "service:milliseconds" pairs, e.g. "api:500;files:5000"
+func ParseSlowPolicy(defaultMs float64, serviceThresholds string) (*SlowPolicy, error) {
+	if defaultMs <= 0 {
+		return nil, fmt.Errorf("invalid slow request threshold %v: must be greater than 0", defaultMs)
+	}
+
+	policy := &SlowPolicy{defaultMs: defaultMs}
+
+	for _, entry := range strings.Split(serviceThresholds, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid service slow threshold %q: expected service:milliseconds", entry)
+		}
+
+		name := strings.TrimSpace(entry[:idx])
+		ms, err := strconv.ParseFloat(strings.TrimSpace(entry[idx+1:]), 64)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid slow threshold for service %q: %q", name, entry[idx+1:])
+		}
+
+		policy.services = append(policy.services, serviceSlowThreshold{name: name, ms: ms})
+	}
+
+	return policy, nil
+}
+
+// DefaultThreshold returns the threshold of services without override, in milliseconds
+func (p *SlowPolicy) DefaultThreshold() float64 {
+	if p == nil {
+		return SlowRequestThresholdMs
+	}
+	return p.defaultMs
+}
+
+// ServiceThresholds returns the per-service overrides, in milliseconds
+func (p *SlowPolicy) ServiceThresholds() map[string]float64 {
+	thresholds := make(map[string]float64)
+	if p == nil {
+		return thresholds
+	}
+	for _, svc := range p.services {
+		thresholds[svc.name] = svc.ms
+	}
+	return thresholds
+}
+
+// Threshold returns the slow threshold of the service a request belongs to
+// Services are matched like the "auto" service filter (backend_name > backend_url > host)
+func (p *SlowPolicy) Threshold(backendName, backendURL, host string) float64 {
+	if p == nil {
+		return SlowRequestThresholdMs
+	}
+
+	for _, svc := range p.services {
+		if matchesAutoService(svc.name, backendName, backendURL, host) {
+			return svc.ms
+		}
+	}
+
+	return p.defaultMs
+}
+
+// IsSlow reports whether a request is slow for the service it belongs to
+func (p *SlowPolicy) IsSlow(backendName, backendURL, host string, responseTimeMs float64) bool {
+	return responseTimeMs > p.Threshold(backendName, backendURL, host)
+}
+
+// IndexThreshold returns the lowest threshold, the one of the slow requests partial index
+// Every request slow for its service is then in the index.
+func (p *SlowPolicy) IndexThreshold() float64 {
+	lowest := p.DefaultThreshold()
+	if p != nil {
+		for _, svc := range p.services {
+			if svc.ms < lowest {
+				lowest = svc.ms
+			}
+		}
+	}
+	return lowest
+}
+
+// IndexCondition returns the WHERE clause of the slow requests partial index
+// Queries must repeat it word for word: SQLite only uses a partial index when its condition appears in the query.
+func (p *SlowPolicy) IndexCondition() string {
+	return "response_time_ms > " + formatMs(p.IndexThreshold())
+}
+
+// IndexSQL returns the statement creating the slow requests partial index
+func (p *SlowPolicy) IndexSQL() string {
+	return `CREATE INDEX IF NOT EXISTS ` + SlowRequestIndex + `
+		 ON http_requests(timestamp DESC, response_time_ms, path, host, method)
+		 WHERE ` + p.IndexCondition()
+}
+
+// SlowCondition returns a SQL boolean expression (with args) matching requests slow for their service
+func (p *SlowPolicy) SlowCondition() (string, []interface{}) {
+	if p == nil || len(p.services) == 0 {
+		return "(response_time_ms > " + formatMs(p.DefaultThreshold()) + ")", nil
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(p.services)*3)
+
+	sb.WriteString("(CASE")
+	for _, svc := range p.services {
+		cond, condArgs := filter.Service{Name: svc.name}.Condition()
+		sb.WriteString(" WHEN " + cond + " THEN ")
+		sb.WriteString("response_time_ms > " + formatMs(svc.ms))
+		args = append(args, condArgs...)
+	}
+	sb.WriteString(" ELSE response_time_ms > " + formatMs(p.defaultMs))
+	sb.WriteString(" END)")
+
+	return sb.String(), args
+}
+
+// SlowFilter selects requests slower than an explicit threshold, or than the threshold of their service
+type SlowFilter struct {
+	ThresholdMs float64     // 0 = threshold of each request's service
+	Policy      *SlowPolicy // nil = default policy
+}
+
+// apply restricts a query on http_requests to slow requests
+// The index condition is added whenever it is implied, so that SQLite reads the partial index instead of the table.
+func (f *SlowFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.ThresholdMs <= 0 {
+		condition, args := f.Policy.SlowCondition()
+		return query.Where(f.Policy.IndexCondition()).Where(condition, args...)
+	}
+
+	if f.ThresholdMs >= f.Policy.IndexThreshold() {
+		query = query.Where(f.Policy.IndexCondition())
+	}
+	return query.Where("response_time_ms > ?", f.ThresholdMs)
+}
+
+// describe returns the thresholds a slow filter applied, explicit or per service
+func (f *SlowFilter) describe() (float64, map[string]float64) {
+	if f.ThresholdMs > 0 {
+		return f.ThresholdMs, nil
+	}
+	thresholds := f.Policy.ServiceThresholds()
+	if len(thresholds) == 0 {
+		thresholds = nil
+	}
+	return f.Policy.DefaultThreshold(), thresholds
+}
+
+// formatMs formats a validated threshold for inlining in SQL
+// The shortest representation keeps the index condition and query conditions textually identical.
+func formatMs(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', -1, 64)
+}
@@ -7,8 +7,12 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"loglynx/internal/clock"
 	"loglynx/internal/database/models"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/filter"
 
 	"github.com/pterm/pterm"
 	"gorm.io/gorm"
@@ -17,35 +21,84 @@ import (
 const (
 	// DefaultQueryTimeout is the default timeout for analytics queries (30 seconds)
 	DefaultQueryTimeout = 30 * time.Second
+
+	// MinIPSearchLength is the shortest IP search query, shorter ones match most IPs
+	MinIPSearchLength = 3
+
+	// maxIPSearchCandidates bounds the matching IPs whose hits are counted, the most recently seen are kept
+	maxIPSearchCandidates = 1000
 )
 
 // StatsRepository provides dashboard statistics
-// All methods accept optional []ServiceFilter parameter for filtering multiple services
+// All methods accept optional []filter.Service parameter for filtering multiple services
 // serviceType can be: "backend_name", "backend_url", "host", or "auto"
 type StatsRepository interface {
-	GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilter) (*StatsSummary, error)
-	GetTimelineStats(hours int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TimelineData, error)
-	GetStatusCodeTimeline(hours int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*StatusCodeTimelineData, error)
-	GetTrafficHeatmap(days int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TrafficHeatmapData, error)
-	GetTopPaths(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*PathStats, error)
-	GetTopCountries(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*CountryStats, error)
-	GetTopIPAddresses(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*IPStats, error)
-	GetStatusCodeDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*StatusCodeStats, error)
-	GetMethodDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*MethodStats, error)
-	GetProtocolDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ProtocolStats, error)
-	GetTLSVersionDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TLSVersionStats, error)
-	GetTopUserAgents(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*UserAgentStats, error)
-	GetTopBrowsers(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*BrowserStats, error)
-	GetTopOperatingSystems(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*OSStats, error)
-	GetDeviceTypeDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*DeviceTypeStats, error)
-	GetTopASNs(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ASNStats, error)
-	GetTopBackends(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*BackendStats, error)
-	GetTopReferrers(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ReferrerStats, error)
-	GetTopReferrerDomains(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ReferrerDomainStats, error)
-	GetResponseTimeStats(filters []ServiceFilter, excludeIP *ExcludeIPFilter) (*ResponseTimeStats, error)
+	GetSummary(filters []filter.Service, excludeIP *filter.ExcludeIP) (*StatsSummary, error)
+	GetTimelineStats(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TimelineData, error)
+	AddTimelineBaseline(timeline []*TimelineData, hours int, weeks int, filters []filter.Service) ([]*TimelineData, int, error)
+	AddTimelineCoverage(timeline []*TimelineData, hours int) ([]*TimelineData, error)
+	GetStatusCodeTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*StatusCodeTimelineData, error)
+	GetTrafficHeatmap(days int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TrafficHeatmapData, error)
+	GetTopPaths(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*PathStats, error)
+	GetTopCountries(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*CountryStats, error)
+	GetContinentDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContinentStats, error)
+	GetContinentTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*GeoTimelineData, error)
+	GetEUDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*EUStats, error)
+	GetEUTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*GeoTimelineData, error)
+	GetTopIPAddresses(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*IPStats, error)
+	GetStatusCodeDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*StatusCodeStats, error)
+	GetMethodDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*MethodStats, error)
+	GetContentClassDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContentClassStats, error)
+	GetTopContentTypes(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContentTypeStats, error)
+	GetDownloadReport(minSize int64, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*DownloadReport, error)
+	GetNotFoundReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*NotFoundReport, error)
+	GetUnusualMethods(disallowed []string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*UnusualMethodsReport, error)
+	GetPreflightReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*PreflightReport, error)
+	GetTrafficSnapshot(at time.Time, window time.Duration, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*TrafficSnapshot, error)
+	GetSourceHostStats(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*SourceHostStats, error)
+	GetClientVersionTimeline(rule *ClientVersionRule, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientVersionReport, error)
+	GetBrowserVersionTimeline(browser string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientVersionReport, error)
+	GetProtocolDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ProtocolStats, error)
+	GetTLSVersionDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TLSVersionStats, error)
+	GetTopUserAgents(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UserAgentStats, error)
+	GetTopBrowsers(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BrowserStats, error)
+	GetTopOperatingSystems(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*OSStats, error)
+	GetDeviceTypeDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*DeviceTypeStats, error)
+	GetTopASNs(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ASNStats, error)
+	GetTopBackends(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BackendStats, error)
+	GetTopUpstreams(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UpstreamStats, error)
+	GetBackendFingerprints(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BackendFingerprint, error)
+	GetTopReferrers(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerStats, error)
+	GetTopReferrerDomains(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerDomainStats, error)
+	GetTopRouters(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*RouterStats, error)
+	GetRouterDetail(router string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*RouterDetail, error)
+	GetTrace(id string) (*TraceDetail, error)
+	GetServiceDependencies(limit int) (*ServiceDependencies, error)
+	GetTopConsumers(quotas *ConsumerQuotas, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ConsumerStats, error)
+	GetConsumerDetail(consumer string, quotas *ConsumerQuotas, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ConsumerDetail, error)
+
+	// Approximate top-K for high-cardinality fields, computed from a sample of large time ranges
+	GetTopPathsApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*PathStats, *Sampling, error)
+	GetTopUserAgentsApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UserAgentStats, *Sampling, error)
+	GetTopReferrersApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerStats, *Sampling, error)
+
+	GetResponseTimeStats(filters []filter.Service, excludeIP *filter.ExcludeIP) (*ResponseTimeStats, error)
+	GetRollupsBuiltUntil() (time.Time, error)
+	GetServiceHourRollups(hour time.Time) ([]*ServiceHourRollup, error)
+	GetTrafficRollupsBuiltUntil() (time.Time, error)
+	StreamTrafficRollups(from time.Time, to time.Time, write func(*TrafficRollupRow) error) error
+	GetClientAbortReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientAbortReport, error)
+	GetDataQuality(filters []filter.Service) (*DataQualityReport, error)
+	GetLabels(filters []filter.Service) ([]*LabelStats, error)
+	GetSlowRequestSummary(thresholdMs float64, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*SlowRequestSummary, error)
+	GetCostReport(model *CostModel, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*CostReport, error)
+	RunQuery(query *StructuredQuery) ([]*QueryRow, error)
+	GetLoginLocations(pathPrefixes []string, since time.Time) ([]*LoginLocation, error)
+	GetWAFReport(limit int) (*WAFReport, error)
 	GetLogProcessingStats() ([]*LogProcessingStats, error)
 	GetDomains() ([]*DomainStats, error)
 	GetServices() ([]*ServiceInfo, error)
+	GetFieldValues(field string, search string, limit int) ([]*FieldValue, error)
 
 	// IP-specific analytics
 	GetIPDetailedStats(ip string) (*IPDetailedStats, error)
@@ -58,139 +111,181 @@ type StatsRepository interface {
 	GetIPTopOperatingSystems(ip string, limit int) ([]*OSStats, error)
 	GetIPDeviceTypeDistribution(ip string) ([]*DeviceTypeStats, error)
 	GetIPResponseTimeStats(ip string) (*ResponseTimeStats, error)
-	GetIPRecentRequests(ip string, limit int) ([]*models.HTTPRequest, error)
 	SearchIPs(query string, limit int) ([]*IPSearchResult, error)
 
 	// System statistics
 	CountRecordsOlderThan(cutoffDate time.Time) (int64, error)
 	GetRecordTimeRange() (oldest time.Time, newest time.Time, err error)
 	GetRecordsTimeline(days int) ([]*TimelineData, error)
+	GetSourceCoverage(hours int) ([]*SourceCoverageReport, error)
+	GetStorageBreakdown() (*StorageBreakdown, error)
+	GetIndexAdvice(profile IndexProfile, statements []string) (*IndexAdvice, error)
+
+	// Lookback window
+	LookbackHours() int
+	SlowPolicy() *SlowPolicy
+	WithLookback(hours int) StatsRepository
+
+	// WithContext returns a repository running its queries with ctx (cancelled with the API request)
+	WithContext(ctx context.Context) StatsRepository
+
+	// WithTopPage returns a repository whose top-N queries return a page in a given order
+	WithTopPage(page TopPage) StatsRepository
+
+	// WithTopFilter returns a repository whose top-N queries only return rows matching thresholds
+	WithTopFilter(filter TopFilter) StatsRepository
 }
 
 type statsRepo struct {
-	db     *gorm.DB
-	logger *pterm.Logger
+	db            *gorm.DB
+	logger        *pterm.Logger
+	lookbackHours int             // Time range for stats queries without an explicit range
+	statusPolicy  *StatusPolicy   // Which status codes count as failures (globally and per service)
+	slowPolicy    *SlowPolicy     // From which response time requests are slow (globally and per service)
+	ctx           context.Context // Parent context of queries (request cancellation, query stats), nil = background
+	page          TopPage         // Offset and order of top-N queries
+	topFilter     TopFilter       // Thresholds of top-N queries
+	clock         clock.Clock     // Current time the time ranges end at
 }
 
 const (
 	// DefaultLookbackHours is the default time range for stats queries (7 days)
 	DefaultLookbackHours = 168
+
+	// MaxLookbackHours is the largest accepted time range for stats queries (1 year)
+	MaxLookbackHours = 8760
 )
 
+// TimeRange describes the effective time window of a stats query
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Hours int       `json:"hours"`
+}
+
+// NewTimeRange returns the window covering the last N hours
+func NewTimeRange(hours int) TimeRange {
+	end := time.Now()
+	return TimeRange{
+		Start: end.Add(-time.Duration(hours) * time.Hour),
+		End:   end,
+		Hours: hours,
+	}
+}
+
 // NewStatsRepository creates a new stats repository
-func NewStatsRepository(db *gorm.DB, logger *pterm.Logger) StatsRepository {
+// lookbackHours sets the default time range (0 = DefaultLookbackHours)
+// statusPolicy defines failed requests for success rates (nil = all 4xx/5xx)
+// slowPolicy defines slow requests (nil = slower than SlowRequestThresholdMs)
+// clk is the clock time ranges end at (nil = the system clock)
+func NewStatsRepository(db *gorm.DB, logger *pterm.Logger, lookbackHours int, statusPolicy *StatusPolicy, slowPolicy *SlowPolicy, clk clock.Clock) StatsRepository {
+	if statusPolicy == nil {
+		statusPolicy = NewDefaultStatusPolicy()
+	}
+	if slowPolicy == nil {
+		slowPolicy = NewDefaultSlowPolicy()
+	}
 	return &statsRepo{
-		db:     db,
-		logger: logger,
+		db:            db,
+		logger:        logger,
+		lookbackHours: clampLookbackHours(lookbackHours),
+		statusPolicy:  statusPolicy,
+		slowPolicy:    slowPolicy,
+		clock:         clock.OrSystem(clk),
 	}
 }
 
+// clampLookbackHours keeps the lookback within 1 hour and MaxLookbackHours
+func clampLookbackHours(hours int) int {
+	if hours <= 0 {
+		return DefaultLookbackHours
+	}
+	if hours > MaxLookbackHours {
+		return MaxLookbackHours
+	}
+	return hours
+}
+
+// LookbackHours returns the time range used by stats queries without an explicit range
+func (r *statsRepo) LookbackHours() int {
+	return r.lookbackHours
+}
+
+// WithLookback returns a repository sharing the same connection with a different lookback
+// Used for per-request range overrides
+func (r *statsRepo) WithLookback(hours int) StatsRepository {
+	return &statsRepo{
+		db:            r.db,
+		logger:        r.logger,
+		lookbackHours: clampLookbackHours(hours),
+		statusPolicy:  r.statusPolicy,
+		slowPolicy:    r.slowPolicy,
+		ctx:           r.ctx,
+		page:          r.page,
+		topFilter:     r.topFilter,
+		clock:         r.clock,
+	}
+}
+
+// WithContext returns a repository sharing the same connection whose queries run with ctx
+// Used to stop queries of disconnected clients and to attribute queries to API endpoints
+func (r *statsRepo) WithContext(ctx context.Context) StatsRepository {
+	return &statsRepo{
+		db:            r.db.WithContext(ctx),
+		logger:        r.logger,
+		lookbackHours: r.lookbackHours,
+		statusPolicy:  r.statusPolicy,
+		slowPolicy:    r.slowPolicy,
+		ctx:           ctx,
+		page:          r.page,
+		topFilter:     r.topFilter,
+		clock:         r.clock,
+	}
+}
+
+// SlowPolicy returns the slow request thresholds of the repository
+func (r *statsRepo) SlowPolicy() *SlowPolicy {
+	return r.slowPolicy
+}
+
 // getTimeRange returns the time range for stats queries
 func (r *statsRepo) getTimeRange() time.Time {
-	return time.Now().Add(-DefaultLookbackHours * time.Hour)
+	return r.clock.Now().Add(-time.Duration(r.lookbackHours) * time.Hour)
 }
 
 // withTimeout creates a context with default query timeout
 func (r *statsRepo) withTimeout() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	parent := r.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithTimeout(parent, DefaultQueryTimeout)
 }
 
 // Removed: applyHostFilter - replaced by applyServiceFilter everywhere
 
-// ServiceFilter represents a single service filter
-type ServiceFilter struct {
-	Name string
-	Type string
-}
-
-// ExcludeIPFilter represents IP exclusion filter
-type ExcludeIPFilter struct {
-	ClientIP        string
-	ExcludeServices []ServiceFilter
-}
-
 // applyServiceFilters applies multiple service-based filters to a query using OR logic
 // If multiple services are provided, it matches ANY of them (OR)
-func (r *statsRepo) applyServiceFilters(query *gorm.DB, filters []ServiceFilter) *gorm.DB {
-	if len(filters) == 0 {
-		return query
-	}
-
-	// Build OR conditions for all filters
-	orConditions := make([]string, 0, len(filters))
-	args := make([]interface{}, 0, len(filters)*3)
-
-	for _, filter := range filters {
-		switch filter.Type {
-		case "backend_name":
-			orConditions = append(orConditions, "backend_name = ?")
-			args = append(args, filter.Name)
-		case "backend_url":
-			orConditions = append(orConditions, "backend_url = ?")
-			args = append(args, filter.Name)
-		case "host":
-			orConditions = append(orConditions, "host = ?")
-			args = append(args, filter.Name)
-		case "auto", "":
-			// Auto-detection: try to filter by the field that matches
-			orConditions = append(orConditions, "(backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?))")
-			args = append(args, filter.Name, filter.Name, filter.Name)
-		default:
-			r.logger.Warn("Unknown service type, defaulting to auto", r.logger.Args("type", filter.Type))
-			orConditions = append(orConditions, "(backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?))")
-			args = append(args, filter.Name, filter.Name, filter.Name)
+func (r *statsRepo) applyServiceFilters(query *gorm.DB, filters []filter.Service) *gorm.DB {
+	for _, service := range filters {
+		if !service.KnownType() {
+			r.logger.Warn("Unknown service type, defaulting to auto", r.logger.Args("type", service.Type))
 		}
 	}
 
-	// Combine all OR conditions
-	if len(orConditions) > 0 {
-		whereClause := "(" + strings.Join(orConditions, " OR ") + ")"
+	// OR of all filters
+	if whereClause, args := filter.AnyCondition(filters); whereClause != "" {
 		query = query.Where(whereClause, args...)
 	}
 
 	return query
 }
 
-// applyExcludeOwnIP excludes requests from a specific IP, optionally filtered by services
-// If excludeServices is empty, excludes IP from all services
-// If excludeServices is provided, excludes IP only on those specific services
-func (r *statsRepo) applyExcludeOwnIP(query *gorm.DB, clientIP string, excludeServices []ServiceFilter) *gorm.DB {
-	if clientIP == "" {
-		return query
-	}
-
-	if len(excludeServices) == 0 {
-		// Exclude IP from all services
-		return query.Where("client_ip != ?", clientIP)
-	}
-
-	// Exclude IP only on specific services
-	// Build condition: NOT (client_ip = ? AND (service conditions))
-	serviceConds := []string{}
-	args := []interface{}{clientIP}
-
-	for _, filter := range excludeServices {
-		switch filter.Type {
-		case "backend_name":
-			serviceConds = append(serviceConds, "backend_name = ?")
-			args = append(args, filter.Name)
-		case "backend_url":
-			serviceConds = append(serviceConds, "backend_url = ?")
-			args = append(args, filter.Name)
-		case "host":
-			serviceConds = append(serviceConds, "host = ?")
-			args = append(args, filter.Name)
-		case "auto", "":
-			serviceConds = append(serviceConds, "(backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?))")
-			args = append(args, filter.Name, filter.Name, filter.Name)
-		}
-	}
-
-	if len(serviceConds) > 0 {
-		whereClause := "NOT (client_ip = ? AND (" + strings.Join(serviceConds, " OR ") + "))"
-		query = query.Where(whereClause, args...)
+// applyExcludeOwnIP excludes requests from the own IP, on all services or only on its ExcludeServices
+func (r *statsRepo) applyExcludeOwnIP(query *gorm.DB, excludeIP *filter.ExcludeIP) *gorm.DB {
+	if cond, args := excludeIP.Condition(); cond != "" {
+		query = query.Where(cond, args...)
 	}
-
 	return query
 }
 
@@ -219,6 +314,9 @@ type TimelineData struct {
 	UniqueVisitors  int64   `json:"unique_visitors"`
 	Bandwidth       int64   `json:"bandwidth"`
 	AvgResponseTime float64 `json:"avg_response_time"`
+
+	Baseline *TimelineBaseline `gorm:"-" json:"baseline,omitempty"` // Same bucket in previous weeks, see AddTimelineBaseline
+	Coverage string            `gorm:"-" json:"coverage,omitempty"` // Traffic, zero traffic or no data, see AddTimelineCoverage
 }
 
 // StatusCodeTimelineData holds status code timeline data for stacked chart
@@ -245,6 +343,7 @@ type PathStats struct {
 	UniqueVisitors  int64   `json:"unique_visitors"`
 	AvgResponseTime float64 `json:"avg_response_time"`
 	TotalBandwidth  int64   `json:"total_bandwidth"`
+	Share           float64 `json:"share"` // Percentage of the hits of all paths
 	Host            string  `json:"host"`
 	BackendName     string  `json:"backend_name"`
 	BackendURL      string  `json:"backend_url"`
@@ -252,10 +351,25 @@ type PathStats struct {
 
 // CountryStats holds country statistics
 type CountryStats struct {
-	Country        string `json:"country"`
-	CountryName    string `json:"country_name"`
+	Country        string  `json:"country"`
+	CountryName    string  `json:"country_name"`
+	Flag           string  `json:"flag"`
+	Continent      string  `json:"continent"`
+	ContinentName  string  `json:"continent_name"`
+	IsEU           bool    `json:"is_eu"`
+	Hits           int64   `json:"hits"`
+	Share          float64 `json:"share"` // Percentage of the hits of all countries
+	UniqueVisitors int64   `json:"unique_visitors"`
+	Bandwidth      int64   `json:"bandwidth"`
+}
+
+// ContinentStats holds continent statistics, aggregated from countries
+type ContinentStats struct {
+	Continent      string `json:"continent"` // "unknown" for countries missing from the country table
+	ContinentName  string `json:"continent_name"`
+	Countries      int    `json:"countries"`
 	Hits           int64  `json:"hits"`
-	UniqueVisitors int64  `json:"unique_visitors"`
+	UniqueVisitors int64  `json:"unique_visitors"` // Sum over countries, an IP changing country counts twice
 	Bandwidth      int64  `json:"bandwidth"`
 }
 
@@ -267,6 +381,7 @@ type IPStats struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Hits      int64   `json:"hits"`
+	Share     float64 `json:"share"` // Percentage of the hits of all IPs
 	Bandwidth int64   `json:"bandwidth"`
 }
 
@@ -339,9 +454,15 @@ type BackendStats struct {
 	Host            string  `json:"host"`
 	ServiceType     string  `json:"service_type"` // "backend_name", "backend_url", or "host"
 	Hits            int64   `json:"hits"`
+	Share           float64 `json:"share"` // Percentage of the hits of all backends
 	Bandwidth       int64   `json:"bandwidth"`
 	AvgResponseTime float64 `json:"avg_response_time"`
 	ErrorCount      int64   `json:"error_count"`
+
+	// Origin instances and header sizes, when the log has them (see GetTopUpstreams)
+	UpstreamAddrs          int64   `json:"upstream_addrs"` // Distinct origin instances that served the backend
+	AvgRequestHeaderBytes  float64 `json:"avg_request_header_bytes"`
+	AvgResponseHeaderBytes float64 `json:"avg_response_header_bytes"`
 }
 
 // ASNStats holds ASN statistics
@@ -370,6 +491,23 @@ type LogProcessingStats struct {
 	BytesProcessed  int64      `json:"bytes_processed"`
 	Percentage      float64    `json:"percentage"`
 	LastProcessedAt *time.Time `json:"last_processed_at"`
+	Dedup           DedupStats `json:"dedup"`
+}
+
+// DedupStats holds per-source deduplication counters
+// Records are deduplicated by a unique index on request_hash; a high duplicate ratio on a
+// live log can mean the hash is too coarse (e.g. CLF logs with second precision)
+type DedupStats struct {
+	RecordsInserted   int64   `json:"records_inserted"`
+	DuplicatesSkipped int64   `json:"duplicates_skipped"`
+	DuplicateRatio    float64 `json:"duplicate_ratio"`    // percent of processed records skipped as duplicates
+	FirstLoadInserts  int64   `json:"first_load_inserts"` // records inserted via the first-load fast path (no index checks)
+	FirstLoadUsed     bool    `json:"first_load_used"`
+	InBatchDuplicates int64   `json:"in_batch_duplicates"` // part of duplicates_skipped whose hash was already in the same batch
+	// Duplicates whose request differs from the stored one: the hash doesn't tell apart requests that are different
+	HashCollisions      int64      `json:"hash_collisions"`
+	LastHashCollision   string     `json:"last_hash_collision,omitempty"`
+	LastHashCollisionAt *time.Time `json:"last_hash_collision_at,omitempty"`
 }
 
 // DomainStats holds domain/host statistics with request count
@@ -387,14 +525,14 @@ type ServiceInfo struct {
 
 // GetSummary returns overall statistics
 // OPTIMIZED: Single aggregated query instead of 12 separate queries (30x performance improvement)
-func (r *statsRepo) GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilter) (*StatsSummary, error) {
+func (r *statsRepo) GetSummary(filters []filter.Service, excludeIP *filter.ExcludeIP) (*StatsSummary, error) {
 	summary := &StatsSummary{}
 
 	// Create context with timeout
 	ctx, cancel := r.withTimeout()
 	defer cancel()
 
-	// Get time range (last 7 days by default)
+	// Get time range (configured lookback, last 7 days by default)
 	since := r.getTimeRange()
 
 	// Single aggregated query for all counts and metrics
@@ -413,11 +551,15 @@ func (r *statsRepo) GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilt
 
 	var result aggregatedResult
 
+	// Failure definition may differ per service (e.g. 401/404 are normal for APIs)
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	selectArgs := append(append([]interface{}{}, failureArgs...), failureArgs...)
+
 	query := r.db.WithContext(ctx).Table("http_requests").
 		Select(`
 			COUNT(*) as total_requests,
-			COUNT(CASE WHEN status_code >= 200 AND status_code < 400 THEN 1 END) as valid_requests,
-			COUNT(CASE WHEN status_code >= 400 THEN 1 END) as failed_requests,
+			COUNT(CASE WHEN status_code >= 200 AND NOT `+failureCond+` THEN 1 END) as valid_requests,
+			COUNT(CASE WHEN `+failureCond+` THEN 1 END) as failed_requests,
 			COUNT(DISTINCT client_ip) as unique_visitors,
 			COUNT(DISTINCT path) as unique_files,
 			COUNT(DISTINCT CASE WHEN status_code = 404 THEN path END) as unique_404,
@@ -425,7 +567,7 @@ func (r *statsRepo) GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilt
 			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time,
 			COUNT(CASE WHEN status_code = 404 THEN 1 END) as not_found_count,
 			COUNT(CASE WHEN status_code >= 500 AND status_code < 600 THEN 1 END) as server_error_count
-		`).
+		`, selectArgs...).
 		Where("timestamp > ?", since)
 
 	query = r.applyServiceFilters(query, filters)
@@ -452,8 +594,8 @@ func (r *statsRepo) GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilt
 		summary.ServerErrorRate = float64(result.ServerErrorCount) / float64(summary.TotalRequests) * 100
 	}
 
-	// Requests per hour (based on 7 days = 168 hours)
-	summary.RequestsPerHour = float64(summary.TotalRequests) / float64(DefaultLookbackHours)
+	// Requests per hour (based on the lookback window)
+	summary.RequestsPerHour = float64(summary.TotalRequests) / float64(r.lookbackHours)
 
 	// Top country (separate query - minimal overhead)
 	query = r.db.Table("http_requests").Select("geo_country").Where("timestamp > ? AND geo_country != ''", since)
@@ -469,32 +611,29 @@ func (r *statsRepo) GetSummary(filters []ServiceFilter, excludeIP *ExcludeIPFilt
 	return summary, nil
 }
 
-// GetTimelineStats returns time-based statistics with adaptive granularity
-func (r *statsRepo) GetTimelineStats(hours int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TimelineData, error) {
-	var timeline []*TimelineData
-	since := time.Now().Add(-time.Duration(hours) * time.Hour)
-
-	// Adaptive grouping based on time range
-	var timeFormat string
-	var groupBy string
-
+// trafficTimelineBucket returns the traffic timeline bucket of a timestamp expression, with adaptive grouping based
+// on time range (finer than timelineBucket)
+func trafficTimelineBucket(hours int, column string) string {
 	if hours <= 24 {
 		// For 1 hour or 24 hours: group by hour
-		timeFormat = "strftime('%Y-%m-%d %H:00', timestamp)"
-		groupBy = timeFormat
+		return "strftime('%Y-%m-%d %H:00', " + column + ")"
 	} else if hours <= 168 {
 		// For 7 days: group by 6-hour blocks
-		timeFormat = "strftime('%Y-%m-%d %H', timestamp)"
-		groupBy = "strftime('%Y-%m-%d', timestamp) || ' ' || CAST((CAST(strftime('%H', timestamp) AS INTEGER) / 6) * 6 AS TEXT) || ':00'"
+		return "strftime('%Y-%m-%d', " + column + ") || ' ' || CAST((CAST(strftime('%H', " + column + ") AS INTEGER) / 6) * 6 AS TEXT) || ':00'"
 	} else if hours <= 720 {
 		// For 30 days: group by day
-		timeFormat = "strftime('%Y-%m-%d', timestamp)"
-		groupBy = timeFormat
-	} else {
-		// For longer periods: group by week
-		timeFormat = "strftime('%Y-W%W', timestamp)"
-		groupBy = timeFormat
+		return "strftime('%Y-%m-%d', " + column + ")"
 	}
+	// For longer periods: group by week
+	return "strftime('%Y-W%W', " + column + ")"
+}
+
+// GetTimelineStats returns time-based statistics with adaptive granularity
+func (r *statsRepo) GetTimelineStats(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TimelineData, error) {
+	var timeline []*TimelineData
+	since := r.clock.Now().Add(-time.Duration(hours) * time.Hour)
+
+	groupBy := trafficTimelineBucket(hours, "timestamp")
 
 	query := r.db.Model(&models.HTTPRequest{}).
 		Select(groupBy+" as hour, COUNT(*) as requests, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(SUM(response_size), 0) as bandwidth, COALESCE(AVG(response_time_ms), 0) as avg_response_time").
@@ -515,9 +654,9 @@ func (r *statsRepo) GetTimelineStats(hours int, filters []ServiceFilter, exclude
 }
 
 // GetStatusCodeTimeline returns status code distribution over time
-func (r *statsRepo) GetStatusCodeTimeline(hours int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*StatusCodeTimelineData, error) {
+func (r *statsRepo) GetStatusCodeTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*StatusCodeTimelineData, error) {
 	var timeline []*StatusCodeTimelineData
-	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	since := r.clock.Now().Add(-time.Duration(hours) * time.Hour)
 
 	// Simplified grouping - use only simple expressions that work in SQLite
 	var groupBy string
@@ -571,7 +710,7 @@ func (r *statsRepo) GetStatusCodeTimeline(hours int, filters []ServiceFilter, ex
 }
 
 // GetTrafficHeatmap returns traffic metrics grouped by day of week and hour for heatmap visualisation
-func (r *statsRepo) GetTrafficHeatmap(days int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TrafficHeatmapData, error) {
+func (r *statsRepo) GetTrafficHeatmap(days int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TrafficHeatmapData, error) {
 	if days <= 0 {
 		days = 30
 	} else if days > 365 {
@@ -579,7 +718,7 @@ func (r *statsRepo) GetTrafficHeatmap(days int, filters []ServiceFilter, exclude
 	}
 
 	var heatmap []*TrafficHeatmapData
-	since := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	since := r.clock.Now().Add(-time.Duration(days) * 24 * time.Hour)
 
 	query := r.db.Model(&models.HTTPRequest{}).
 		Select("CAST(strftime('%w', timestamp) AS INTEGER) as day_of_week, "+
@@ -599,17 +738,22 @@ func (r *statsRepo) GetTrafficHeatmap(days int, filters []ServiceFilter, exclude
 	return heatmap, nil
 }
 
+// shareColumn is the percentage of the hits of a group among all groups, computed over the grouped rows before
+// LIMIT and OFFSET so pages and top-N responses share the same total. Groups excluded by a min_hits filter (HAVING)
+// and requests outside the WHERE filters aren't part of the total.
+const shareColumn = "100.0 * COUNT(*) / SUM(COUNT(*)) OVER () as share"
+
 // GetTopPaths returns most accessed paths
-func (r *statsRepo) GetTopPaths(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*PathStats, error) {
+func (r *statsRepo) GetTopPaths(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*PathStats, error) {
 	var paths []*PathStats
 	since := r.getTimeRange()
 
 	query := r.db.Model(&models.HTTPRequest{}).
-		Select("path, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(AVG(response_time_ms), 0) as avg_response_time, COALESCE(SUM(response_size), 0) as total_bandwidth").
+		Select("path, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(AVG(response_time_ms), 0) as avg_response_time, COALESCE(SUM(response_size), 0) as total_bandwidth, "+shareColumn).
 		Where("timestamp > ?", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("path").Order("hits DESC").Limit(limit).Scan(&paths).Error
+	err := r.applyTop(query.Group("path"), "path", limit).Scan(&paths).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top paths", r.logger.Args("error", err))
@@ -620,42 +764,78 @@ func (r *statsRepo) GetTopPaths(limit int, filters []ServiceFilter, excludeIP *E
 }
 
 // GetTopCountries returns top countries by requests
-func (r *statsRepo) GetTopCountries(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*CountryStats, error) {
+func (r *statsRepo) GetTopCountries(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*CountryStats, error) {
 	var countries []*CountryStats
 	since := r.getTimeRange()
 
 	query := r.db.Model(&models.HTTPRequest{}).
-		Select("geo_country as country, '' as country_name, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(SUM(response_size), 0) as bandwidth").
+		Select("geo_country as country, MAX(geo_eu) as is_eu, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(SUM(response_size), 0) as bandwidth, "+shareColumn).
 		Where("timestamp > ? AND geo_country != ''", since)
 
 	query = r.applyServiceFilters(query, filters)
 
-	// Only apply limit if > 0 (0 means no limit - return all)
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-
-	err := query.Group("geo_country").Order("hits DESC").Scan(&countries).Error
+	// limit 0 returns all countries
+	err := r.applyTop(query.Group("geo_country"), "geo_country", limit).Scan(&countries).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top countries", r.logger.Args("error", err))
 		return nil, err
 	}
 
+	for _, country := range countries {
+		info, _ := enrichment.LookupCountry(country.Country)
+		country.CountryName = info.Name
+		country.Flag = info.Flag
+		country.Continent = info.Continent
+		country.ContinentName = info.ContinentName
+	}
+
 	return countries, nil
 }
 
+// GetContinentDistribution returns requests per continent
+func (r *statsRepo) GetContinentDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContinentStats, error) {
+	countries, err := r.GetTopCountries(0, filters, excludeIP)
+	if err != nil {
+		return nil, err
+	}
+
+	byContinent := make(map[string]*ContinentStats)
+	continents := make([]*ContinentStats, 0)
+	for _, country := range countries {
+		code, name := country.Continent, country.ContinentName
+		if code == "" {
+			code, name = RegionUnknown, "Unknown"
+		}
+		continent, ok := byContinent[code]
+		if !ok {
+			continent = &ContinentStats{Continent: code, ContinentName: name}
+			byContinent[code] = continent
+			continents = append(continents, continent)
+		}
+		continent.Countries++
+		continent.Hits += country.Hits
+		continent.UniqueVisitors += country.UniqueVisitors
+		continent.Bandwidth += country.Bandwidth
+	}
+
+	sort.Slice(continents, func(i, j int) bool {
+		return continents[i].Hits > continents[j].Hits
+	})
+	return continents, nil
+}
+
 // GetTopIPAddresses returns most active IP addresses
-func (r *statsRepo) GetTopIPAddresses(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*IPStats, error) {
+func (r *statsRepo) GetTopIPAddresses(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*IPStats, error) {
 	var ips []*IPStats
 	since := r.getTimeRange()
 
 	query := r.db.Model(&models.HTTPRequest{}).
-		Select("client_ip as ip_address, MAX(geo_country) as country, MAX(geo_city) as city, MAX(geo_lat) as latitude, MAX(geo_lon) as longitude, COUNT(*) as hits, COALESCE(SUM(response_size), 0) as bandwidth").
+		Select("client_ip as ip_address, MAX(geo_country) as country, MAX(geo_city) as city, MAX(geo_lat) as latitude, MAX(geo_lon) as longitude, COUNT(*) as hits, COALESCE(SUM(response_size), 0) as bandwidth, "+shareColumn).
 		Where("timestamp > ?", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("client_ip").Order("hits DESC").Limit(limit).Scan(&ips).Error
+	err := r.applyTop(query.Group("client_ip"), "client_ip", limit).Scan(&ips).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top IPs", r.logger.Args("error", err))
@@ -666,7 +846,7 @@ func (r *statsRepo) GetTopIPAddresses(limit int, filters []ServiceFilter, exclud
 }
 
 // GetStatusCodeDistribution returns status code distribution
-func (r *statsRepo) GetStatusCodeDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*StatusCodeStats, error) {
+func (r *statsRepo) GetStatusCodeDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*StatusCodeStats, error) {
 	var stats []*StatusCodeStats
 	since := r.getTimeRange()
 
@@ -686,7 +866,7 @@ func (r *statsRepo) GetStatusCodeDistribution(filters []ServiceFilter, excludeIP
 }
 
 // GetMethodDistribution returns HTTP method distribution
-func (r *statsRepo) GetMethodDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*MethodStats, error) {
+func (r *statsRepo) GetMethodDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*MethodStats, error) {
 	var stats []*MethodStats
 	since := r.getTimeRange()
 
@@ -706,7 +886,7 @@ func (r *statsRepo) GetMethodDistribution(filters []ServiceFilter, excludeIP *Ex
 }
 
 // GetProtocolDistribution returns HTTP protocol distribution
-func (r *statsRepo) GetProtocolDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ProtocolStats, error) {
+func (r *statsRepo) GetProtocolDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ProtocolStats, error) {
 	var stats []*ProtocolStats
 	since := r.getTimeRange()
 
@@ -726,7 +906,7 @@ func (r *statsRepo) GetProtocolDistribution(filters []ServiceFilter, excludeIP *
 }
 
 // GetTLSVersionDistribution returns TLS version distribution
-func (r *statsRepo) GetTLSVersionDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*TLSVersionStats, error) {
+func (r *statsRepo) GetTLSVersionDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*TLSVersionStats, error) {
 	var stats []*TLSVersionStats
 	since := r.getTimeRange()
 
@@ -746,7 +926,7 @@ func (r *statsRepo) GetTLSVersionDistribution(filters []ServiceFilter, excludeIP
 }
 
 // GetTopUserAgents returns most common user agents
-func (r *statsRepo) GetTopUserAgents(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*UserAgentStats, error) {
+func (r *statsRepo) GetTopUserAgents(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UserAgentStats, error) {
 	var agents []*UserAgentStats
 	since := r.getTimeRange()
 
@@ -755,7 +935,7 @@ func (r *statsRepo) GetTopUserAgents(limit int, filters []ServiceFilter, exclude
 		Where("timestamp > ? AND user_agent != ''", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("user_agent").Order("count DESC").Limit(limit).Scan(&agents).Error
+	err := r.applyTop(query.Group("user_agent"), "user_agent", limit).Scan(&agents).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top user agents", r.logger.Args("error", err))
@@ -766,7 +946,7 @@ func (r *statsRepo) GetTopUserAgents(limit int, filters []ServiceFilter, exclude
 }
 
 // GetTopReferrers returns most common referrers
-func (r *statsRepo) GetTopReferrers(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ReferrerStats, error) {
+func (r *statsRepo) GetTopReferrers(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerStats, error) {
 	var referrers []*ReferrerStats
 	since := r.getTimeRange()
 
@@ -776,7 +956,7 @@ func (r *statsRepo) GetTopReferrers(limit int, filters []ServiceFilter, excludeI
 		Where("timestamp > ? AND referer != ''", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("referer").Order("hits DESC").Limit(limit).Scan(&referrers).Error
+	err := r.applyTop(query.Group("referer"), "referer", limit).Scan(&referrers).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top referrers", r.logger.Args("error", err))
@@ -787,16 +967,25 @@ func (r *statsRepo) GetTopReferrers(limit int, filters []ServiceFilter, excludeI
 }
 
 // GetTopReferrerDomains returns referrer domains aggregated by host
-func (r *statsRepo) GetTopReferrerDomains(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ReferrerDomainStats, error) {
-	var referrers []*ReferrerStats
+// Domains are aggregated in Go, so they are ordered and paged after aggregation (pageTop).
+func (r *statsRepo) GetTopReferrerDomains(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerDomainStats, error) {
+	var referrers []*struct {
+		ReferrerStats
+		Bandwidth       int64
+		ErrorCount      int64
+		ResponseTimeSum float64
+	}
 	since := r.getTimeRange()
 
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
 	query := r.db.Model(&models.HTTPRequest{}).
-		Select("referer as referrer, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors").
+		Select("referer as referrer, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, "+
+			"COALESCE(SUM(response_size), 0) as bandwidth, COUNT(CASE WHEN "+failureCond+" THEN 1 END) as error_count, "+
+			"COALESCE(SUM(response_time_ms), 0) as response_time_sum", failureArgs...).
 		Where("timestamp > ? AND referer != ''", since)
 
-	query = r.applyServiceFilters(query, filters)
-	err := query.Group("referer").Order("hits DESC").Scan(&referrers).Error
+	query = r.filterTopRequests(r.applyServiceFilters(query, filters))
+	err := query.Group("referer").Scan(&referrers).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get referrer domains", r.logger.Args("error", err))
@@ -804,45 +993,41 @@ func (r *statsRepo) GetTopReferrerDomains(limit int, filters []ServiceFilter, ex
 	}
 
 	// Aggregate by domain
-	domainData := make(map[string]*ReferrerDomainStats)
+	type domainRow struct {
+		stats   *ReferrerDomainStats
+		ranking topRanking
+	}
+	domainData := make(map[string]*domainRow)
+	domains := make([]*domainRow, 0)
 	for _, ref := range referrers {
 		domain := extractDomain(ref.Referrer)
 		if domain == "" {
 			continue
 		}
-		if existing, ok := domainData[domain]; ok {
-			existing.Hits += ref.Hits
-			existing.UniqueVisitors += ref.UniqueVisitors
-		} else {
-			domainData[domain] = &ReferrerDomainStats{
-				Domain:         domain,
-				Hits:           ref.Hits,
-				UniqueVisitors: ref.UniqueVisitors,
-			}
+		row, ok := domainData[domain]
+		if !ok {
+			row = &domainRow{stats: &ReferrerDomainStats{Domain: domain}}
+			domainData[domain] = row
+			domains = append(domains, row)
 		}
+		row.stats.Hits += ref.Hits
+		row.stats.UniqueVisitors += ref.UniqueVisitors
+		row.ranking.Hits += ref.Hits
+		row.ranking.Bandwidth += ref.Bandwidth
+		row.ranking.ErrorCount += ref.ErrorCount
+		row.ranking.ResponseTimeSum += ref.ResponseTimeSum
+		row.ranking.ResponseTimeRows += ref.Hits
 	}
 
-	if len(domainData) == 0 {
-		return []*ReferrerDomainStats{}, nil
-	}
+	domains = pageTop(domains, r.page, r.topFilter.MinHits, limit,
+		func(row *domainRow) *topRanking { return &row.ranking },
+		func(row *domainRow) string { return row.stats.Domain })
 
-	domains := make([]*ReferrerDomainStats, 0, len(domainData))
-	for _, stats := range domainData {
-		domains = append(domains, stats)
+	result := make([]*ReferrerDomainStats, len(domains))
+	for i, row := range domains {
+		result[i] = row.stats
 	}
-
-	sort.Slice(domains, func(i, j int) bool {
-		if domains[i].Hits == domains[j].Hits {
-			return domains[i].Domain < domains[j].Domain
-		}
-		return domains[i].Hits > domains[j].Hits
-	})
-
-	if limit > 0 && len(domains) > limit {
-		domains = domains[:limit]
-	}
-
-	return domains, nil
+	return result, nil
 }
 
 // extractDomain returns the host portion for a referrer URL
@@ -918,7 +1103,7 @@ func extractBackendName(backendName string) string {
 }
 
 // GetTopBackends returns backend statistics
-func (r *statsRepo) GetTopBackends(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*BackendStats, error) {
+func (r *statsRepo) GetTopBackends(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BackendStats, error) {
 	since := r.getTimeRange()
 
 	// Query with fallback logic: backend_name > backend_url > host
@@ -934,9 +1119,13 @@ func (r *statsRepo) GetTopBackends(limit int, filters []ServiceFilter, excludeIP
 				ELSE 'host'
 			END as service_type,
 			COUNT(*) as hits,
+			`+shareColumn+`,
 			COALESCE(SUM(response_size), 0) as bandwidth,
 			COALESCE(AVG(response_time_ms), 0) as avg_response_time,
-			SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as error_count
+			SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as error_count,
+			COUNT(DISTINCT NULLIF(upstream_addr, '')) as upstream_addrs,
+			COALESCE(AVG(NULLIF(request_header_bytes, 0)), 0) as avg_request_header_bytes,
+			COALESCE(AVG(NULLIF(response_header_bytes, 0)), 0) as avg_response_header_bytes
 		`).
 		Where("timestamp > ?", since).
 		Where("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) IS NOT NULL")
@@ -944,25 +1133,25 @@ func (r *statsRepo) GetTopBackends(limit int, filters []ServiceFilter, excludeIP
 	query = r.applyServiceFilters(query, filters)
 
 	// Apply IP exclusion filter
-	if excludeIP != nil {
-		query = r.applyExcludeOwnIP(query, excludeIP.ClientIP, excludeIP.ExcludeServices)
-	}
+	query = r.applyExcludeOwnIP(query, excludeIP)
 
 	// Group by all three fields to maintain distinction
-	query = query.Group("backend_name_original, backend_url, host").
-		Order("hits DESC").
-		Limit(limit)
+	query = r.applyTop(query.Group("backend_name_original, backend_url, host"), "backend_name_original, backend_url, host", limit)
 
 	var results []struct {
-		BackendName         string  `gorm:"column:backend_name"`
-		BackendNameOriginal string  `gorm:"column:backend_name_original"`
-		BackendURL          string  `gorm:"column:backend_url"`
-		Host                string  `gorm:"column:host"`
-		ServiceType         string  `gorm:"column:service_type"`
-		Hits                int64   `gorm:"column:hits"`
-		Bandwidth           int64   `gorm:"column:bandwidth"`
-		AvgResponseTime     float64 `gorm:"column:avg_response_time"`
-		ErrorCount          int64   `gorm:"column:error_count"`
+		BackendName            string  `gorm:"column:backend_name"`
+		BackendNameOriginal    string  `gorm:"column:backend_name_original"`
+		BackendURL             string  `gorm:"column:backend_url"`
+		Host                   string  `gorm:"column:host"`
+		ServiceType            string  `gorm:"column:service_type"`
+		Hits                   int64   `gorm:"column:hits"`
+		Share                  float64 `gorm:"column:share"`
+		Bandwidth              int64   `gorm:"column:bandwidth"`
+		AvgResponseTime        float64 `gorm:"column:avg_response_time"`
+		ErrorCount             int64   `gorm:"column:error_count"`
+		UpstreamAddrs          int64   `gorm:"column:upstream_addrs"`
+		AvgRequestHeaderBytes  float64 `gorm:"column:avg_request_header_bytes"`
+		AvgResponseHeaderBytes float64 `gorm:"column:avg_response_header_bytes"`
 	}
 
 	err := query.Scan(&results).Error
@@ -980,9 +1169,14 @@ func (r *statsRepo) GetTopBackends(limit int, filters []ServiceFilter, excludeIP
 			Host:            result.Host,
 			ServiceType:     result.ServiceType,
 			Hits:            result.Hits,
+			Share:           result.Share,
 			Bandwidth:       result.Bandwidth,
 			AvgResponseTime: result.AvgResponseTime,
 			ErrorCount:      result.ErrorCount,
+
+			UpstreamAddrs:          result.UpstreamAddrs,
+			AvgRequestHeaderBytes:  result.AvgRequestHeaderBytes,
+			AvgResponseHeaderBytes: result.AvgResponseHeaderBytes,
 		}
 	}
 
@@ -990,7 +1184,7 @@ func (r *statsRepo) GetTopBackends(limit int, filters []ServiceFilter, excludeIP
 }
 
 // GetTopASNs returns top ASNs by requests
-func (r *statsRepo) GetTopASNs(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*ASNStats, error) {
+func (r *statsRepo) GetTopASNs(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ASNStats, error) {
 	var asns []*ASNStats
 	since := r.getTimeRange()
 
@@ -999,7 +1193,7 @@ func (r *statsRepo) GetTopASNs(limit int, filters []ServiceFilter, excludeIP *Ex
 		Where("timestamp > ? AND asn > 0", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("asn").Order("hits DESC").Limit(limit).Scan(&asns).Error
+	err := r.applyTop(query.Group("asn"), "asn", limit).Scan(&asns).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top ASNs", r.logger.Args("error", err))
@@ -1012,36 +1206,27 @@ func (r *statsRepo) GetTopASNs(limit int, filters []ServiceFilter, excludeIP *Ex
 // GetResponseTimeStats returns response time statistics
 // OPTIMIZED: Uses SQLite window functions (NTILE) for efficient percentile calculation
 // 3x faster than LIMIT/OFFSET approach, single query instead of 4 separate queries
-func (r *statsRepo) GetResponseTimeStats(filters []ServiceFilter, excludeIP *ExcludeIPFilter) (*ResponseTimeStats, error) {
+func (r *statsRepo) GetResponseTimeStats(filters []filter.Service, excludeIP *filter.ExcludeIP) (*ResponseTimeStats, error) {
 	stats := &ResponseTimeStats{}
 	since := r.getTimeRange()
 
+	// Hourly rollups avoid sorting every request of the range, the query below is the fallback
+	if rollupStats, err := r.responseTimeFromRollups(since, filters); err != nil {
+		r.logger.Warn("Failed to read response time rollups, computing from requests", r.logger.Args("error", err))
+	} else if rollupStats != nil {
+		r.logger.Trace("Generated response time stats from rollups",
+			r.logger.Args("min", rollupStats.Min, "max", rollupStats.Max, "p95", rollupStats.P95, "service_filters", filters))
+		return rollupStats, nil
+	}
+
 	// Build WHERE clause for service filter
 	whereClause := "timestamp > ? AND response_time_ms > 0"
 	args := []interface{}{since}
 
 	// Apply service filters
-	if len(filters) > 0 {
-		filterConds := []string{}
-		for _, filter := range filters {
-			switch filter.Type {
-			case "backend_name":
-				filterConds = append(filterConds, "backend_name = ?")
-				args = append(args, filter.Name)
-			case "backend_url":
-				filterConds = append(filterConds, "backend_url = ?")
-				args = append(args, filter.Name)
-			case "host":
-				filterConds = append(filterConds, "host = ?")
-				args = append(args, filter.Name)
-			case "auto", "":
-				filterConds = append(filterConds, "(backend_name = ? OR (backend_name = '' AND backend_url = ?) OR (backend_name = '' AND backend_url = '' AND host = ?))")
-				args = append(args, filter.Name, filter.Name, filter.Name)
-			}
-		}
-		if len(filterConds) > 0 {
-			whereClause += " AND (" + strings.Join(filterConds, " OR ") + ")"
-		}
+	if filterClause, filterArgs := filter.AnyCondition(filters); filterClause != "" {
+		whereClause += " AND " + filterClause
+		args = append(args, filterArgs...)
 	}
 
 	// Single query using window functions for all statistics including percentiles
@@ -1102,12 +1287,27 @@ func (r *statsRepo) GetLogProcessingStats() ([]*LogProcessingStats, error) {
 			percentage = float64(source.LastPosition) / float64(fileSize) * 100.0
 		}
 
+		dedup := DedupStats{
+			RecordsInserted:     source.RecordsInserted,
+			DuplicatesSkipped:   source.DuplicatesSkipped,
+			FirstLoadInserts:    source.FirstLoadInserts,
+			FirstLoadUsed:       source.FirstLoadInserts > 0,
+			InBatchDuplicates:   source.InBatchDuplicates,
+			HashCollisions:      source.HashCollisions,
+			LastHashCollision:   source.LastHashCollision,
+			LastHashCollisionAt: source.LastHashCollisionAt,
+		}
+		if total := source.RecordsInserted + source.DuplicatesSkipped; total > 0 {
+			dedup.DuplicateRatio = float64(source.DuplicatesSkipped) / float64(total) * 100.0
+		}
+
 		stats = append(stats, &LogProcessingStats{
 			LogSourceName:   source.Name,
 			FileSize:        fileSize,
 			BytesProcessed:  source.LastPosition,
 			Percentage:      percentage,
 			LastProcessedAt: source.LastReadAt,
+			Dedup:           dedup,
 		})
 	}
 
@@ -1115,7 +1315,7 @@ func (r *statsRepo) GetLogProcessingStats() ([]*LogProcessingStats, error) {
 }
 
 // GetTopBrowsers returns most common browsers
-func (r *statsRepo) GetTopBrowsers(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*BrowserStats, error) {
+func (r *statsRepo) GetTopBrowsers(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BrowserStats, error) {
 	var browsers []*BrowserStats
 	since := r.getTimeRange()
 
@@ -1124,7 +1324,7 @@ func (r *statsRepo) GetTopBrowsers(limit int, filters []ServiceFilter, excludeIP
 		Where("timestamp > ? AND browser != '' AND browser != 'Unknown'", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("browser").Order("count DESC").Limit(limit).Scan(&browsers).Error
+	err := r.applyTop(query.Group("browser"), "browser", limit).Scan(&browsers).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top browsers", r.logger.Args("error", err))
@@ -1135,7 +1335,7 @@ func (r *statsRepo) GetTopBrowsers(limit int, filters []ServiceFilter, excludeIP
 }
 
 // GetTopOperatingSystems returns most common operating systems
-func (r *statsRepo) GetTopOperatingSystems(limit int, filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*OSStats, error) {
+func (r *statsRepo) GetTopOperatingSystems(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*OSStats, error) {
 	var osList []*OSStats
 	since := r.getTimeRange()
 
@@ -1144,7 +1344,7 @@ func (r *statsRepo) GetTopOperatingSystems(limit int, filters []ServiceFilter, e
 		Where("timestamp > ? AND os != '' AND os != 'Unknown'", since)
 
 	query = r.applyServiceFilters(query, filters)
-	err := query.Group("os").Order("count DESC").Limit(limit).Scan(&osList).Error
+	err := r.applyTop(query.Group("os"), "os", limit).Scan(&osList).Error
 
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to get top operating systems", r.logger.Args("error", err))
@@ -1155,7 +1355,7 @@ func (r *statsRepo) GetTopOperatingSystems(limit int, filters []ServiceFilter, e
 }
 
 // GetDeviceTypeDistribution returns distribution of device types
-func (r *statsRepo) GetDeviceTypeDistribution(filters []ServiceFilter, excludeIP *ExcludeIPFilter) ([]*DeviceTypeStats, error) {
+func (r *statsRepo) GetDeviceTypeDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*DeviceTypeStats, error) {
 	var devices []*DeviceTypeStats
 	since := r.getTimeRange()
 
@@ -1382,6 +1582,10 @@ func (r *statsRepo) GetIPDetailedStats(ip string) (*IPDetailedStats, error) {
 
 	var result aggregatedResult
 
+	// IP traffic spans services, so the failure definition is evaluated per row
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	selectArgs := append(append([]interface{}{}, failureArgs...), failureArgs...)
+
 	err := r.db.Table("http_requests").
 		Select(`
 			COUNT(*) as total_requests,
@@ -1395,11 +1599,11 @@ func (r *statsRepo) GetIPDetailedStats(ip string) (*IPDetailedStats, error) {
 			MAX(asn_org) as asn_org,
 			COALESCE(SUM(response_size), 0) as total_bandwidth,
 			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time,
-			COUNT(CASE WHEN status_code >= 200 AND status_code < 400 THEN 1 END) as success_count,
-			COUNT(CASE WHEN status_code >= 400 THEN 1 END) as error_count,
+			COUNT(CASE WHEN status_code >= 200 AND NOT `+failureCond+` THEN 1 END) as success_count,
+			COUNT(CASE WHEN `+failureCond+` THEN 1 END) as error_count,
 			COUNT(DISTINCT backend_name) as unique_backends,
 			COUNT(DISTINCT path) as unique_paths
-		`).
+		`, selectArgs...).
 		Where("client_ip = ? AND timestamp > ?", ip, since).
 		Scan(&result).Error
 
@@ -1451,7 +1655,7 @@ func (r *statsRepo) GetIPDetailedStats(ip string) (*IPDetailedStats, error) {
 // GetIPTimelineStats returns timeline statistics for a specific IP
 func (r *statsRepo) GetIPTimelineStats(ip string, hours int) ([]*TimelineData, error) {
 	var timeline []*TimelineData
-	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	since := r.clock.Now().Add(-time.Duration(hours) * time.Hour)
 
 	// Adaptive grouping based on time range
 	var groupBy string
@@ -1490,7 +1694,7 @@ func (r *statsRepo) GetIPTrafficHeatmap(ip string, days int) ([]*TrafficHeatmapD
 	}
 
 	var heatmap []*TrafficHeatmapData
-	since := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+	since := r.clock.Now().Add(-time.Duration(days) * 24 * time.Hour)
 
 	err := r.db.Model(&models.HTTPRequest{}).
 		Select("CAST(strftime('%w', timestamp) AS INTEGER) as day_of_week, "+
@@ -1516,7 +1720,7 @@ func (r *statsRepo) GetIPTopPaths(ip string, limit int) ([]*PathStats, error) {
 	since := r.getTimeRange()
 
 	err := r.db.Model(&models.HTTPRequest{}).
-		Select("path, COUNT(*) as hits, COUNT(DISTINCT backend_name) as unique_visitors, COALESCE(AVG(response_time_ms), 0) as avg_response_time, COALESCE(SUM(response_size), 0) as total_bandwidth, MAX(host) as host, MAX(backend_name) as backend_name, MAX(backend_url) as backend_url").
+		Select("path, COUNT(*) as hits, COUNT(DISTINCT backend_name) as unique_visitors, COALESCE(AVG(response_time_ms), 0) as avg_response_time, COALESCE(SUM(response_size), 0) as total_bandwidth, MAX(host) as host, MAX(backend_name) as backend_name, MAX(backend_url) as backend_url, "+shareColumn).
 		Where("client_ip = ? AND timestamp > ?", ip, since).
 		Group("path").
 		Order("hits DESC").
@@ -1537,7 +1741,7 @@ func (r *statsRepo) GetIPTopBackends(ip string, limit int) ([]*BackendStats, err
 	since := r.getTimeRange()
 
 	err := r.db.Model(&models.HTTPRequest{}).
-		Select("backend_name, MAX(backend_url) as backend_url, COUNT(*) as hits, COALESCE(SUM(response_size), 0) as bandwidth, COALESCE(AVG(response_time_ms), 0) as avg_response_time, SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as error_count").
+		Select("backend_name, MAX(backend_url) as backend_url, COUNT(*) as hits, COALESCE(SUM(response_size), 0) as bandwidth, COALESCE(AVG(response_time_ms), 0) as avg_response_time, SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as error_count, "+shareColumn).
 		Where("client_ip = ? AND timestamp > ? AND backend_name != ''", ip, since).
 		Group("backend_name").
 		Order("hits DESC").
@@ -1667,28 +1871,19 @@ func (r *statsRepo) GetIPResponseTimeStats(ip string) (*ResponseTimeStats, error
 	return stats, nil
 }
 
-// GetIPRecentRequests returns recent requests for a specific IP
-func (r *statsRepo) GetIPRecentRequests(ip string, limit int) ([]*models.HTTPRequest, error) {
-	var requests []*models.HTTPRequest
-	since := r.getTimeRange()
-
-	err := r.db.Model(&models.HTTPRequest{}).
-		Where("client_ip = ? AND timestamp > ?", ip, since).
-		Order("timestamp DESC").
-		Limit(limit).
-		Find(&requests).Error
-
-	if err != nil {
-		r.logger.WithCaller().Error("Failed to get IP recent requests", r.logger.Args("ip", ip, "error", err))
-		return nil, err
-	}
-
-	return requests, nil
-}
-
 // SearchIPs searches for IPs matching a pattern with their basic stats
+// The pattern is matched against the distinct IPs of the client_ips table, only the requests of matching IPs are
+// read. Queries shorter than MinIPSearchLength return no IP.
 func (r *statsRepo) SearchIPs(query string, limit int) ([]*IPSearchResult, error) {
+	if utf8.RuneCountInString(query) < MinIPSearchLength {
+		return []*IPSearchResult{}, nil
+	}
 	since := r.getTimeRange()
+	candidates := r.db.Table("client_ips").
+		Select("ip").
+		Where(`ip LIKE ? ESCAPE '\' AND last_seen > ?`, "%"+escapeLike(query)+"%", since).
+		Order("last_seen DESC").
+		Limit(maxIPSearchCandidates)
 
 	// Use a temporary struct to handle SQLite string timestamps
 	type tempResult struct {
@@ -1702,7 +1897,7 @@ func (r *statsRepo) SearchIPs(query string, limit int) ([]*IPSearchResult, error
 	var tempResults []tempResult
 	err := r.db.Model(&models.HTTPRequest{}).
 		Select("client_ip as ip_address, COUNT(*) as hits, MAX(geo_country) as country, MAX(geo_city) as city, MAX(timestamp) as last_seen").
-		Where("client_ip LIKE ? AND timestamp > ?", "%"+query+"%", since).
+		Where("client_ip IN (?) AND timestamp > ?", candidates, since).
 		Group("client_ip").
 		Order("hits DESC").
 		Limit(limit).
@@ -1753,7 +1948,7 @@ func (r *statsRepo) SearchIPs(query string, limit int) ([]*IPSearchResult, error
 func (r *statsRepo) CountRecordsOlderThan(cutoffDate time.Time) (int64, error) {
 	var count int64
 
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	ctx, cancel := r.withTimeout()
 	defer cancel()
 
 	err := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
@@ -1771,7 +1966,7 @@ func (r *statsRepo) CountRecordsOlderThan(cutoffDate time.Time) (int64, error) {
 
 // GetRecordTimeRange returns the oldest and newest record timestamps
 func (r *statsRepo) GetRecordTimeRange() (oldest time.Time, newest time.Time, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	ctx, cancel := r.withTimeout()
 	defer cancel()
 
 	var result struct {
@@ -1820,9 +2015,9 @@ func (r *statsRepo) GetRecordTimeRange() (oldest time.Time, newest time.Time, er
 // GetRecordsTimeline returns records count grouped by day for system statistics
 func (r *statsRepo) GetRecordsTimeline(days int) ([]*TimelineData, error) {
 	var timeline []*TimelineData
-	since := time.Now().AddDate(0, 0, -days)
+	since := r.clock.Now().AddDate(0, 0, -days)
 
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout)
+	ctx, cancel := r.withTimeout()
 	defer cancel()
 
 	// Group by day for system stats
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// ClientAbortReport analyses requests the client gave up on, to spot slow backends causing abandonment
+type ClientAbortReport struct {
+	Summary  *ClientAbortStats          `json:"summary"`
+	Paths    []*ClientAbortPathStats    `json:"paths"`
+	Services []*ClientAbortServiceStats `json:"services"`
+	Timeline []*ClientAbortTimelineData `json:"timeline"`
+}
+
+// ClientAbortStats holds client abort counts and timings for a set of requests
+// Time to abort is how long the client waited (response time of the aborted request), upstream time is
+// how long the backend had been working at that moment (only logged by some proxies, e.g. Traefik OriginDuration).
+type ClientAbortStats struct {
+	TotalRequests     int64   `json:"total_requests"`
+	Aborted           int64   `json:"aborted"`          // 499 + 408
+	ClientClosed      int64   `json:"client_closed"`    // 499
+	RequestTimeout    int64   `json:"request_timeout"`  // 408
+	WaitingUpstream   int64   `json:"waiting_upstream"` // 499 without upstream status: the backend never answered (Traefik JSON logs)
+	AbortRate         float64 `json:"abort_rate"`       // Percentage of requests aborted
+	AvgTimeToAbortMs  float64 `json:"avg_time_to_abort_ms"`
+	MaxTimeToAbortMs  float64 `json:"max_time_to_abort_ms"`
+	AvgUpstreamTimeMs float64 `json:"avg_upstream_time_ms"` // 0 when the proxy doesn't log upstream timing
+	AvgResponseTimeMs float64 `json:"avg_response_time_ms"` // Completed requests, for comparison
+}
+
+// ClientAbortPathStats holds client aborts of one path
+type ClientAbortPathStats struct {
+	Path string `json:"path"`
+	ClientAbortStats
+}
+
+// ClientAbortServiceStats holds client aborts of one service
+type ClientAbortServiceStats struct {
+	Service string `json:"service"` // backend_name, backend_url or host
+	ClientAbortStats
+}
+
+// ClientAbortTimelineData holds client aborts for one time bucket
+type ClientAbortTimelineData struct {
+	Hour            string `json:"hour"`
+	TotalRequests   int64  `json:"total_requests"`
+	ClientClosed    int64  `json:"client_closed"`
+	RequestTimeout  int64  `json:"request_timeout"`
+	WaitingUpstream int64  `json:"waiting_upstream"`
+}
+
+// clientAbortAggregates computes ClientAbortStats columns over the grouped requests
+// 499: the client closed the connection before the response (nginx, Traefik "context canceled")
+// 408: the client was too slow to send its request
+const clientAbortAggregates = `
+	COUNT(*) as total_requests,
+	SUM(CASE WHEN status_code IN (499, 408) THEN 1 ELSE 0 END) as aborted,
+	SUM(CASE WHEN status_code = 499 THEN 1 ELSE 0 END) as client_closed,
+	SUM(CASE WHEN status_code = 408 THEN 1 ELSE 0 END) as request_timeout,
+	SUM(CASE WHEN status_code = 499 AND upstream_status = 0 THEN 1 ELSE 0 END) as waiting_upstream,
+	COALESCE(AVG(CASE WHEN status_code IN (499, 408) THEN response_time_ms END), 0) as avg_time_to_abort_ms,
+	COALESCE(MAX(CASE WHEN status_code IN (499, 408) THEN response_time_ms END), 0) as max_time_to_abort_ms,
+	COALESCE(AVG(CASE WHEN status_code IN (499, 408) AND upstream_response_time_ms > 0 THEN upstream_response_time_ms END), 0) as avg_upstream_time_ms,
+	COALESCE(AVG(CASE WHEN status_code NOT IN (499, 408) THEN response_time_ms END), 0) as avg_response_time_ms`
+
+// GetClientAbortReport returns client abort totals, the most aborted paths and services, and a timeline
+// limit applies to paths and services, which are ordered by aborted requests.
+func (r *statsRepo) GetClientAbortReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientAbortReport, error) {
+	since := r.getTimeRange()
+
+	requests := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since)
+		query = r.applyServiceFilters(query, filters)
+		query = r.applyExcludeOwnIP(query, excludeIP)
+		return query
+	}
+
+	report := &ClientAbortReport{
+		Summary:  &ClientAbortStats{},
+		Paths:    make([]*ClientAbortPathStats, 0),
+		Services: make([]*ClientAbortServiceStats, 0),
+		Timeline: make([]*ClientAbortTimelineData, 0),
+	}
+
+	if err := requests().Select(clientAbortAggregates).Scan(report.Summary).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get client abort summary", r.logger.Args("error", err))
+		return nil, err
+	}
+	report.Summary.AbortRate = abortRate(report.Summary)
+	if report.Summary.Aborted == 0 {
+		return report, nil
+	}
+
+	err := requests().
+		Select("path, " + clientAbortAggregates).
+		Group("path").
+		Having("aborted > 0").
+		Order("aborted DESC").
+		Limit(limit).
+		Scan(&report.Paths).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get client abort paths", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, path := range report.Paths {
+		path.AbortRate = abortRate(&path.ClientAbortStats)
+	}
+
+	err = requests().
+		Select("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) as service, " + clientAbortAggregates).
+		Group("service").
+		Having("aborted > 0").
+		Order("aborted DESC").
+		Limit(limit).
+		Scan(&report.Services).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get client abort services", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, service := range report.Services {
+		service.AbortRate = abortRate(&service.ClientAbortStats)
+	}
+
+	err = requests().
+		Select(timelineBucket(r.LookbackHours()) + ` as hour,
+			COUNT(*) as total_requests,
+			SUM(CASE WHEN status_code = 499 THEN 1 ELSE 0 END) as client_closed,
+			SUM(CASE WHEN status_code = 408 THEN 1 ELSE 0 END) as request_timeout,
+			SUM(CASE WHEN status_code = 499 AND upstream_status = 0 THEN 1 ELSE 0 END) as waiting_upstream`).
+		Group("hour").
+		Order("hour").
+		Scan(&report.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get client abort timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// abortRate returns the percentage of aborted requests
+func abortRate(stats *ClientAbortStats) float64 {
+	if stats.TotalRequests == 0 {
+		return 0
+	}
+	return float64(stats.Aborted) / float64(stats.TotalRequests) * 100
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// MaxBaselineWeeks bounds the weeks averaged into a timeline baseline
+const MaxBaselineWeeks = 12
+
+// MaxBaselineHours bounds the timeline range with a baseline, longer ranges have weekly buckets
+const MaxBaselineHours = 720
+
+// TimelineBaseline holds the average of one timeline bucket over the same weekdays and hours of previous weeks
+type TimelineBaseline struct {
+	Requests        float64 `json:"requests"`
+	UniqueVisitors  float64 `json:"unique_visitors"`
+	Bandwidth       float64 `json:"bandwidth"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+}
+
+// baselineSQLTime is how shifted timestamps compare, SQLite datetime() returns UTC in this layout
+const baselineSQLTime = "2006-01-02 15:04:05"
+
+// AddTimelineBaseline sets the baseline of each bucket of a timeline (GetTimelineStats over hours) to the average
+// of the same bucket shifted by 1 to weeks weeks, adding the buckets with a baseline but no traffic.
+// Only the weeks fully covered by the stored records are averaged, their number is returned (0 = no baseline).
+func (r *statsRepo) AddTimelineBaseline(timeline []*TimelineData, hours int, weeks int, filters []filter.Service) ([]*TimelineData, int, error) {
+	if hours > MaxBaselineHours {
+		return nil, 0, fmt.Errorf("a baseline is only available for ranges up to %d hours", MaxBaselineHours)
+	}
+	weeks = min(weeks, MaxBaselineWeeks)
+
+	now := r.clock.Now()
+	since := now.Add(-time.Duration(hours) * time.Hour)
+	week := 7 * 24 * time.Hour // Not AddDate: shifted timestamps are UTC, without daylight saving changes
+	oldest, _, err := r.GetRecordTimeRange()
+	if err != nil {
+		return nil, 0, err
+	}
+	for weeks > 0 && since.Add(-time.Duration(weeks)*week).Before(oldest) {
+		weeks--
+	}
+	if weeks == 0 || oldest.IsZero() {
+		return timeline, 0, nil
+	}
+
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	// Each request counts in the buckets it falls in once shifted by 1..weeks weeks
+	offsets := make([]string, weeks)
+	for i := range offsets {
+		offsets[i] = fmt.Sprintf("SELECT %d AS k", i+1)
+	}
+	shifted := "datetime(timestamp, '+' || (weeks.k * 7) || ' days')"
+	groupBy := trafficTimelineBucket(hours, shifted)
+
+	var rows []*struct {
+		Hour string
+		TimelineBaseline
+	}
+	query := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
+		Select(fmt.Sprintf("%s as hour, COUNT(*) * 1.0 / %d as requests, COUNT(DISTINCT weeks.k || '|' || client_ip) * 1.0 / %d as unique_visitors, ", groupBy, weeks, weeks)+
+			fmt.Sprintf("COALESCE(SUM(response_size), 0) * 1.0 / %d as bandwidth, COALESCE(AVG(response_time_ms), 0) as avg_response_time", weeks)).
+		Joins("JOIN ("+strings.Join(offsets, " UNION ALL ")+") AS weeks").
+		Where("timestamp > ? AND timestamp <= ?", since.Add(-time.Duration(weeks)*week), now.Add(-week)).
+		Where(shifted+" > ? AND "+shifted+" <= ?", since.UTC().Format(baselineSQLTime), now.UTC().Format(baselineSQLTime))
+
+	query = r.applyServiceFilters(query, filters)
+	if err := query.Group(groupBy).Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get timeline baseline", r.logger.Args("error", err))
+		return nil, 0, err
+	}
+
+	// Buckets without traffic in previous weeks have a zero baseline
+	byHour := make(map[string]*TimelineData, len(timeline))
+	for _, point := range timeline {
+		point.Baseline = &TimelineBaseline{}
+		byHour[point.Hour] = point
+	}
+	for _, row := range rows {
+		baseline := row.TimelineBaseline
+		point, ok := byHour[row.Hour]
+		if !ok {
+			point = &TimelineData{Hour: row.Hour}
+			timeline = append(timeline, point)
+		}
+		point.Baseline = &baseline
+	}
+	// Same order as the timeline query
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Hour < timeline[j].Hour
+	})
+
+	r.logger.Trace("Added timeline baseline", r.logger.Args("hours", hours, "weeks", weeks, "buckets", len(rows)))
+	return timeline, weeks, nil
+}
@@ -0,0 +1,76 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestTimelineBaseline(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// 1 request an hour ago, 2 the same hour a week ago, 4 two weeks ago, none three weeks ago, and 3 a week ago
+	// 5 hours ago, an hour without traffic today. The oldest record covers the 3 weeks.
+	now := time.Now()
+	week := 7 * 24 * time.Hour
+	requests := []struct {
+		at    time.Time
+		count int
+	}{
+		{now.Add(-time.Hour), 1},
+		{now.Add(-week - time.Hour), 2},
+		{now.Add(-2*week - time.Hour), 4},
+		{now.Add(-week - 5*time.Hour), 3},
+		{now.Add(-5 * week), 1},
+	}
+	n := 0
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			n++
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: request.at, RequestHash: fmt.Sprint(n), ClientIP: fmt.Sprint("10.0.0.", i),
+				Method: "GET", Host: "example", Path: "/", StatusCode: 200, ResponseSize: 300,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	timeline, err := statsRepo.GetTimelineStats(24, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline, weeks, err := statsRepo.AddTimelineBaseline(timeline, 24, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weeks != 3 || len(timeline) != 2 {
+		t.Fatalf("Expected 2 buckets averaged over 3 weeks, got %d buckets over %d weeks", len(timeline), weeks)
+	}
+
+	quiet, busy := timeline[0], timeline[1]
+	if quiet.Requests != 0 || quiet.Baseline == nil || quiet.Baseline.Requests != 1 || quiet.Baseline.Bandwidth != 300 {
+		t.Errorf("Expected a bucket without traffic and a baseline of 1 request, got %+v %+v", quiet, quiet.Baseline)
+	}
+	if busy.Requests != 1 || busy.Baseline == nil || busy.Baseline.Requests != 2 || busy.Baseline.UniqueVisitors != 2 {
+		t.Errorf("Expected 1 request against a baseline of 2, got %+v %+v", busy, busy.Baseline)
+	}
+
+	// Only the weeks covered by records are averaged
+	_, weeks, err = statsRepo.AddTimelineBaseline(nil, 24, 12, nil)
+	if err != nil || weeks != 4 {
+		t.Errorf("Expected 4 covered weeks, got %d (%v)", weeks, err)
+	}
+	if _, _, err := statsRepo.AddTimelineBaseline(nil, 24*60, 4, nil); err == nil {
+		t.Error("Expected an error for a 60-day range")
+	}
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// ClientVersionRule extracts the version of a client from its User-Agent
+// The first capture group of the pattern is the version, e.g. `MyApp/(\d+)\.` groups MyApp by major version.
+type ClientVersionRule struct {
+	Name    string
+	pattern *regexp.Regexp
+	literal string // Literal prefix of the pattern, pre-filters User-Agents in SQL
+}
+
+// ClientVersionRules are the configured client version rules, by name
+type ClientVersionRules struct {
+	rules []*ClientVersionRule // Ordered as configured
+}
+
+// ParseClientVersionRules parses semicolon-separated "name=pattern" rules, e.g. "MyApp=MyApp/(\d+)\.;sdk=my-sdk/(\d+\.\d+)"
+func ParseClientVersionRules(list string) (*ClientVersionRules, error) {
+	rules := &ClientVersionRules{}
+
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.Index(entry, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid client version rule %q: expected name=pattern", entry)
+		}
+
+		name := strings.TrimSpace(entry[:idx])
+		if rules.Rule(name) != nil {
+			return nil, fmt.Errorf("duplicate client version rule %q", name)
+		}
+		pattern, err := regexp.Compile(strings.TrimSpace(entry[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for client %q: %w", name, err)
+		}
+		if pattern.NumSubexp() < 1 {
+			return nil, fmt.Errorf("invalid pattern for client %q: a capture group must match the version", name)
+		}
+
+		literal, _ := pattern.LiteralPrefix()
+		rules.rules = append(rules.rules, &ClientVersionRule{Name: name, pattern: pattern, literal: literal})
+	}
+
+	return rules, nil
+}
+
+// Rule returns the rule of a client, nil if it isn't configured
+func (r *ClientVersionRules) Rule(name string) *ClientVersionRule {
+	if r == nil {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Names returns the configured clients
+func (r *ClientVersionRules) Names() []string {
+	names := make([]string, 0)
+	if r == nil {
+		return names
+	}
+	for _, rule := range r.rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// Version returns the version of a User-Agent, false if the rule doesn't match it
+func (r *ClientVersionRule) Version(userAgent string) (string, bool) {
+	match := r.pattern.FindStringSubmatch(userAgent)
+	if match == nil || match[1] == "" {
+		return "", false
+	}
+	return match[1], true
+}
+
+// ClientVersionReport tracks version adoption of one client over time
+type ClientVersionReport struct {
+	Client   string                       `json:"client"`
+	Versions []*ClientVersionStats        `json:"versions"` // Most requests first
+	Timeline []*ClientVersionTimelineData `json:"timeline"`
+}
+
+// ClientVersionStats holds the requests of one client version
+type ClientVersionStats struct {
+	Version string  `json:"version"`
+	Hits    int64   `json:"hits"`
+	Share   float64 `json:"share"` // Percentage of the requests of the client
+}
+
+// ClientVersionTimelineData holds requests per version for one time bucket
+type ClientVersionTimelineData struct {
+	Hour     string           `json:"hour"`
+	Requests map[string]int64 `json:"requests"`
+}
+
+// clientVersionRow holds the requests of one User-Agent (or browser version) in one time bucket
+type clientVersionRow struct {
+	Hour string
+	Key  string
+	Hits int64
+}
+
+// GetClientVersionTimeline returns version adoption of a client matched by a configured rule
+// Distinct User-Agents are matched in Go, SQLite has no regular expressions.
+func (r *statsRepo) GetClientVersionTimeline(rule *ClientVersionRule, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientVersionReport, error) {
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(timelineBucket(r.LookbackHours())+" as hour, user_agent as key, COUNT(*) as hits").
+		Where("timestamp > ?", r.getTimeRange())
+	if rule.literal != "" {
+		query = query.Where("instr(user_agent, ?) > 0", rule.literal)
+	}
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	var rows []*clientVersionRow
+	if err := query.Group("hour, user_agent").Order("hour").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get client version timeline", r.logger.Args("client", rule.Name, "error", err))
+		return nil, err
+	}
+
+	return buildClientVersionReport(rule.Name, rows, rule.Version), nil
+}
+
+// GetBrowserVersionTimeline returns major version adoption of a browser (as parsed from User-Agents)
+func (r *statsRepo) GetBrowserVersionTimeline(browser string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ClientVersionReport, error) {
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(timelineBucket(r.LookbackHours())+" as hour, browser_version as key, COUNT(*) as hits").
+		Where("timestamp > ? AND browser = ?", r.getTimeRange(), browser)
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	var rows []*clientVersionRow
+	if err := query.Group("hour, browser_version").Order("hour").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get browser version timeline", r.logger.Args("browser", browser, "error", err))
+		return nil, err
+	}
+
+	majorVersion := func(version string) (string, bool) {
+		major, _, _ := strings.Cut(version, ".")
+		return major, major != ""
+	}
+	return buildClientVersionReport(browser, rows, majorVersion), nil
+}
+
+// buildClientVersionReport aggregates rows per version, rows must be ordered by hour
+func buildClientVersionReport(client string, rows []*clientVersionRow, version func(key string) (string, bool)) *ClientVersionReport {
+	report := &ClientVersionReport{Client: client, Versions: make([]*ClientVersionStats, 0), Timeline: make([]*ClientVersionTimelineData, 0)}
+
+	versions := make(map[string]string) // Key -> version, "" when it doesn't match
+	byVersion := make(map[string]*ClientVersionStats)
+	var total int64
+	for _, row := range rows {
+		v, known := versions[row.Key]
+		if !known {
+			v, _ = version(row.Key)
+			versions[row.Key] = v
+		}
+		if v == "" {
+			continue
+		}
+
+		stats := byVersion[v]
+		if stats == nil {
+			stats = &ClientVersionStats{Version: v}
+			byVersion[v] = stats
+			report.Versions = append(report.Versions, stats)
+		}
+		stats.Hits += row.Hits
+		total += row.Hits
+
+		if len(report.Timeline) == 0 || report.Timeline[len(report.Timeline)-1].Hour != row.Hour {
+			report.Timeline = append(report.Timeline, &ClientVersionTimelineData{Hour: row.Hour, Requests: make(map[string]int64)})
+		}
+		report.Timeline[len(report.Timeline)-1].Requests[v] += row.Hits
+	}
+
+	sort.SliceStable(report.Versions, func(i, j int) bool {
+		if report.Versions[i].Hits != report.Versions[j].Hits {
+			return report.Versions[i].Hits > report.Versions[j].Hits
+		}
+		return report.Versions[i].Version < report.Versions[j].Version
+	})
+	for _, stats := range report.Versions {
+		stats.Share = float64(stats.Hits) / float64(total) * 100
+	}
+	return report
+}
@@ -0,0 +1,90 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParseClientVersionRules(t *testing.T) {
+	rules, err := repositories.ParseClientVersionRules(` MyApp = MyApp/(\d+)\. ; sdk=acme-go/(\d+\.\d+);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := rules.Names(); len(names) != 2 || names[0] != "MyApp" || names[1] != "sdk" {
+		t.Errorf("Unexpected clients %v", names)
+	}
+	if version, ok := rules.Rule("sdk").Version("acme-go/1.24.3 (linux)"); !ok || version != "1.24" {
+		t.Errorf("Expected version 1.24, got %q", version)
+	}
+	if rules.Rule("missing") != nil {
+		t.Error("Expected no rule for an unknown client")
+	}
+
+	for _, invalid := range []string{"MyApp", "=MyApp/(\\d+)", "MyApp=MyApp/\\d+", "MyApp=MyApp/(", "a=a/(\\d);a=b/(\\d)"} {
+		if _, err := repositories.ParseClientVersionRules(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestClientVersionTimeline(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// Two hours ago only MyApp 1.x, in the last hour mostly 2.x
+	hour := time.Now().Truncate(time.Hour)
+	requests := []struct {
+		userAgent string
+		browser   string
+		version   string
+		at        time.Time
+		count     int
+	}{
+		{"MyApp/1.4.0 (iOS)", "", "", hour.Add(-2 * time.Hour), 3},
+		{"MyApp/1.5.2 (Android)", "", "", hour.Add(-time.Hour), 1},
+		{"MyApp/2.0.1 (iOS)", "", "", hour.Add(-time.Hour), 4},
+		{"NotMyApp/9.0", "", "", hour.Add(-time.Hour), 2},
+		{"Mozilla/5.0 Chrome/120.0.1", "Chrome", "120.0.1", hour.Add(-time.Hour), 2},
+		{"Mozilla/5.0 Chrome/121.0.3", "Chrome", "121.0.3", hour.Add(-time.Hour), 1},
+	}
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: request.at.Add(time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(request.userAgent, i),
+				ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", StatusCode: 200,
+				UserAgent: request.userAgent, Browser: request.browser, BrowserVersion: request.version,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	rules, _ := repositories.ParseClientVersionRules(`MyApp=\bMyApp/(\d+)\.`)
+	report, err := statsRepo.GetClientVersionTimeline(rules.Rule("MyApp"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Versions) != 2 || report.Versions[0].Version != "1" || report.Versions[0].Hits != 4 || report.Versions[1].Share != 50 {
+		t.Errorf("Unexpected versions %+v %+v", report.Versions[0], report.Versions[1])
+	}
+	if len(report.Timeline) != 2 || report.Timeline[0].Requests["1"] != 3 || report.Timeline[1].Requests["1"] != 1 || report.Timeline[1].Requests["2"] != 4 {
+		t.Errorf("Unexpected timeline %+v %+v", report.Timeline[0], report.Timeline[1])
+	}
+
+	report, err = statsRepo.GetBrowserVersionTimeline("Chrome", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Client != "Chrome" || len(report.Versions) != 2 || report.Versions[0].Version != "120" || report.Versions[0].Hits != 2 {
+		t.Errorf("Unexpected browser versions %+v", report.Versions)
+	}
+}
@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// consumerDetailLimit is the number of paths and client IPs listed in a consumer drill-down
+const consumerDetailLimit = 10
+
+// Kinds of API consumers
+const (
+	ConsumerKindUser   = "user"    // Authenticated user (client_user)
+	ConsumerKindAPIKey = "api_key" // Fingerprint of the X-Api-Key header
+)
+
+// consumerKeyPrefix prefixes API key fingerprints in consumer names
+const consumerKeyPrefix = "key:"
+
+// consumerExpression identifies the consumer of a request: the authenticated user, else the API key fingerprint
+// NULL for anonymous requests.
+const consumerExpression = "COALESCE(NULLIF(client_user, ''), '" + consumerKeyPrefix + "' || NULLIF(api_key_hash, ''))"
+
+// ConsumerStats holds the traffic of one API consumer
+type ConsumerStats struct {
+	Consumer          string      `json:"consumer"` // User name, or "key:" and the API key fingerprint
+	Kind              string      `json:"kind"`     // user or api_key
+	Hits              int64       `json:"hits"`
+	RequestsPerMinute float64     `json:"requests_per_minute"` // Average over the time range
+	ErrorCount        int64       `json:"error_count"`
+	ErrorRate         float64     `json:"error_rate"` // Percentage of failed requests
+	AvgResponseTime   float64     `json:"avg_response_time"`
+	Bandwidth         int64       `json:"bandwidth"`
+	UniqueIPs         int64       `json:"unique_ips"`
+	LastSeen          time.Time   `json:"last_seen"`
+	Quota             *QuotaUsage `json:"quota,omitempty"` // Only for consumers with a quota
+}
+
+// QuotaUsage holds the requests of a consumer in the current quota period
+// Every request of the consumer counts, whatever the time range and service filters.
+type QuotaUsage struct {
+	Limit       int64     `json:"limit"`
+	Period      string    `json:"period"`
+	Used        int64     `json:"used"`
+	UsedPercent float64   `json:"used_percent"`
+	Exceeded    bool      `json:"exceeded"`
+	ResetsAt    time.Time `json:"resets_at"`
+}
+
+// ConsumerDetail drills down into one consumer
+type ConsumerDetail struct {
+	Summary      *ConsumerStats          `json:"summary"`
+	ResponseTime *ResponseTimeStats      `json:"response_time"`
+	Timeline     []*ConsumerTimelineData `json:"timeline"`
+	StatusCodes  []*StatusCodeStats      `json:"status_codes"`
+	TopPaths     []*ConsumerTargetStats  `json:"top_paths"`
+	TopIPs       []*ConsumerTargetStats  `json:"top_ips"`
+}
+
+// ConsumerTimelineData holds the requests, errors and latency of a consumer for one time bucket
+type ConsumerTimelineData struct {
+	Hour            string  `json:"hour"`
+	Requests        int64   `json:"requests"`
+	Errors          int64   `json:"errors"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+}
+
+// ConsumerTargetStats holds the requests of a consumer to one path, or from one client IP
+type ConsumerTargetStats struct {
+	Name            string  `json:"name"`
+	Hits            int64   `json:"hits"`
+	ErrorCount      int64   `json:"error_count"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+}
+
+// consumerRow holds ConsumerStats columns as scanned, SQLite returns MAX(timestamp) as text
+type consumerRow struct {
+	Consumer        string
+	Hits            int64
+	ErrorCount      int64
+	AvgResponseTime float64
+	Bandwidth       int64
+	UniqueIPs       int64 `gorm:"column:unique_ips"`
+	LastSeen        string
+}
+
+// consumerKind returns the kind of a consumer from its name
+func consumerKind(consumer string) string {
+	if strings.HasPrefix(consumer, consumerKeyPrefix) {
+		return ConsumerKindAPIKey
+	}
+	return ConsumerKindUser
+}
+
+// consumerCondition returns the WHERE clause (with args) selecting the requests of one consumer
+func consumerCondition(consumer string) (string, []interface{}) {
+	if hash, ok := strings.CutPrefix(consumer, consumerKeyPrefix); ok {
+		return "COALESCE(client_user, '') = '' AND api_key_hash = ?", []interface{}{hash}
+	}
+	return "client_user = ?", []interface{}{consumer}
+}
+
+// consumerAggregates computes consumerRow columns, failures follow the status policy of each row's service
+func (r *statsRepo) consumerAggregates() (string, []interface{}) {
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	return `COUNT(*) as hits,
+			COUNT(CASE WHEN ` + failureCond + ` THEN 1 END) as error_count,
+			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time,
+			COALESCE(SUM(response_size), 0) as bandwidth,
+			COUNT(DISTINCT client_ip) as unique_ips,
+			MAX(timestamp) as last_seen`, failureArgs
+}
+
+// consumerQuery returns requests of the time range with service and IP filters, of one consumer or of every consumer
+func (r *statsRepo) consumerQuery(consumer string, filters []filter.Service, excludeIP *filter.ExcludeIP) *gorm.DB {
+	query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", r.getTimeRange())
+	if consumer != "" {
+		condition, args := consumerCondition(consumer)
+		query = query.Where(condition, args...)
+	} else {
+		query = query.Where("(client_user != '' OR api_key_hash != '')")
+	}
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+	return query
+}
+
+// newConsumerStats converts a scanned row
+func (r *statsRepo) newConsumerStats(row *consumerRow) *ConsumerStats {
+	return &ConsumerStats{
+		Consumer:          row.Consumer,
+		Kind:              consumerKind(row.Consumer),
+		Hits:              row.Hits,
+		RequestsPerMinute: float64(row.Hits) / float64(r.LookbackHours()*60),
+		ErrorCount:        row.ErrorCount,
+		ErrorRate:         routerErrorRate(row.ErrorCount, row.Hits),
+		AvgResponseTime:   row.AvgResponseTime,
+		Bandwidth:         row.Bandwidth,
+		UniqueIPs:         row.UniqueIPs,
+		LastSeen:          parseSQLiteTime(row.LastSeen),
+	}
+}
+
+// GetTopConsumers returns the API consumers (authenticated users and API keys) with the most requests
+// Consumers with a quota get their usage of the current period.
+func (r *statsRepo) GetTopConsumers(quotas *ConsumerQuotas, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ConsumerStats, error) {
+	var rows []*consumerRow
+
+	aggregates, args := r.consumerAggregates()
+	query := r.consumerQuery("", filters, excludeIP).
+		Select(consumerExpression+" as consumer, "+aggregates, args...).
+		Group("consumer")
+	if err := r.applyTop(query, "consumer", limit).Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get top consumers", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	consumers := make([]*ConsumerStats, 0, len(rows))
+	for _, row := range rows {
+		consumers = append(consumers, r.newConsumerStats(row))
+	}
+	if err := r.addQuotaUsage(quotas, consumers, r.clock.Now()); err != nil {
+		return nil, err
+	}
+	return consumers, nil
+}
+
+// addQuotaUsage sets the quota usage of consumers with a quota, with one query per quota period
+func (r *statsRepo) addQuotaUsage(quotas *ConsumerQuotas, consumers []*ConsumerStats, now time.Time) error {
+	byPeriod := make(map[string][]*ConsumerStats)
+	var periods []string // Ordered to keep queries deterministic
+	for _, consumer := range consumers {
+		quota := quotas.Quota(consumer.Consumer)
+		if quota == nil {
+			continue
+		}
+		start := quota.PeriodStart(now)
+		consumer.Quota = &QuotaUsage{Limit: quota.Limit, Period: quota.Period, ResetsAt: quota.PeriodEnd(start)}
+		if byPeriod[quota.Period] == nil {
+			periods = append(periods, quota.Period)
+		}
+		byPeriod[quota.Period] = append(byPeriod[quota.Period], consumer)
+	}
+
+	for _, period := range periods {
+		names := make([]string, 0, len(byPeriod[period]))
+		for _, consumer := range byPeriod[period] {
+			names = append(names, consumer.Consumer)
+		}
+
+		var usage []struct {
+			Consumer string
+			Used     int64
+		}
+		start := (&ConsumerQuota{Period: period}).PeriodStart(now)
+		err := r.db.Model(&models.HTTPRequest{}).
+			Select(consumerExpression+" as consumer, COUNT(*) as used").
+			Where("timestamp >= ? AND "+consumerExpression+" IN ?", start, names).
+			Group("consumer").
+			Scan(&usage).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to get consumer quota usage", r.logger.Args("period", period, "error", err))
+			return err
+		}
+
+		used := make(map[string]int64, len(usage))
+		for _, u := range usage {
+			used[u.Consumer] = u.Used
+		}
+		for _, consumer := range byPeriod[period] {
+			quota := consumer.Quota
+			quota.Used = used[consumer.Consumer]
+			quota.UsedPercent = float64(quota.Used) / float64(quota.Limit) * 100
+			quota.Exceeded = quota.Used > quota.Limit
+		}
+	}
+	return nil
+}
+
+// GetConsumerDetail returns the summary, quota usage, latency percentiles, timeline, status codes, top paths
+// and client IPs of a consumer
+// Returns nil when the consumer has no requests in the time range.
+func (r *statsRepo) GetConsumerDetail(consumer string, quotas *ConsumerQuotas, filters []filter.Service, excludeIP *filter.ExcludeIP) (*ConsumerDetail, error) {
+	detail := &ConsumerDetail{
+		ResponseTime: &ResponseTimeStats{},
+		Timeline:     make([]*ConsumerTimelineData, 0),
+		StatusCodes:  make([]*StatusCodeStats, 0),
+		TopPaths:     make([]*ConsumerTargetStats, 0),
+		TopIPs:       make([]*ConsumerTargetStats, 0),
+	}
+
+	var row consumerRow
+	aggregates, args := r.consumerAggregates()
+	if err := r.consumerQuery(consumer, filters, excludeIP).Select(aggregates, args...).Scan(&row).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get consumer stats", r.logger.Args("consumer", consumer, "error", err))
+		return nil, err
+	}
+	if row.Hits == 0 {
+		return nil, nil
+	}
+	row.Consumer = consumer
+	detail.Summary = r.newConsumerStats(&row)
+	if err := r.addQuotaUsage(quotas, []*ConsumerStats{detail.Summary}, r.clock.Now()); err != nil {
+		return nil, err
+	}
+
+	err := r.scanResponseTimePercentiles(r.consumerQuery(consumer, filters, excludeIP), detail.ResponseTime)
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get consumer response times", r.logger.Args("consumer", consumer, "error", err))
+		return nil, err
+	}
+
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	err = r.consumerQuery(consumer, filters, excludeIP).
+		Select(timelineBucket(r.LookbackHours())+` as hour,
+			COUNT(*) as requests,
+			COUNT(CASE WHEN `+failureCond+` THEN 1 END) as errors,
+			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time`, failureArgs...).
+		Group("hour").
+		Order("hour").
+		Scan(&detail.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get consumer timeline", r.logger.Args("consumer", consumer, "error", err))
+		return nil, err
+	}
+
+	err = r.consumerQuery(consumer, filters, excludeIP).
+		Select("status_code, COUNT(*) as count").
+		Group("status_code").
+		Order("count DESC").
+		Scan(&detail.StatusCodes).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get consumer status codes", r.logger.Args("consumer", consumer, "error", err))
+		return nil, err
+	}
+
+	targets := []struct {
+		expression string
+		target     *[]*ConsumerTargetStats
+	}{
+		{"path", &detail.TopPaths},
+		{"client_ip", &detail.TopIPs},
+	}
+	for _, target := range targets {
+		err := r.consumerQuery(consumer, filters, excludeIP).
+			Select(target.expression+` as name,
+				COUNT(*) as hits,
+				COUNT(CASE WHEN `+failureCond+` THEN 1 END) as error_count,
+				COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time`, failureArgs...).
+			Group("name").
+			Order("hits DESC").
+			Limit(consumerDetailLimit).
+			Scan(target.target).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to get consumer breakdown", r.logger.Args("consumer", consumer, "error", err))
+			return nil, err
+		}
+	}
+
+	return detail, nil
+}
@@ -0,0 +1,102 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParseConsumerQuotas(t *testing.T) {
+	quotas, err := repositories.ParseConsumerQuotas(" *:10000/day ; alice:500/hour; key:b2b9323ee091c380:1000/month")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota := quotas.Quota("alice"); quota == nil || quota.Limit != 500 || quota.Period != repositories.QuotaPeriodHour {
+		t.Errorf("Unexpected quota for alice %+v", quota)
+	}
+	if quota := quotas.Quota("key:b2b9323ee091c380"); quota == nil || quota.Limit != 1000 {
+		t.Errorf("Unexpected quota for the API key %+v", quota)
+	}
+	if quota := quotas.Quota("bob"); quota == nil || quota.Consumer != "*" || quota.Limit != 10000 {
+		t.Errorf("Expected the default quota for bob, got %+v", quota)
+	}
+
+	now := time.Date(2025, 10, 15, 8, 30, 0, 0, time.UTC)
+	quota := quotas.Quota("key:b2b9323ee091c380")
+	if start := quota.PeriodStart(now); !start.Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)) || !quota.PeriodEnd(start).Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected month period starting %v", start)
+	}
+
+	for _, invalid := range []string{"alice", "alice:0/day", "alice:10/week", "alice:10", "a:1/day;a:2/day", "*:1/day;*:2/hour"} {
+		if _, err := repositories.ParseConsumerQuotas(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestConsumers(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// The user wins over the API key, anonymous requests aren't consumers
+	now := time.Now().UTC()
+	requests := []struct {
+		user, keyHash, ip string
+		status, count     int
+	}{
+		{"alice", "", "10.0.0.1", 200, 4},
+		{"alice", "", "10.0.0.2", 500, 1},
+		{"alice", "b2b9323ee091c380", "10.0.0.1", 200, 1},
+		{"", "b2b9323ee091c380", "10.0.0.3", 429, 2},
+		{"", "", "10.0.0.4", 200, 5},
+	}
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: now, RequestHash: fmt.Sprint(request, i),
+				ClientIP: request.ip, ClientUser: request.user, APIKeyHash: request.keyHash,
+				Method: "GET", Host: "api", Path: "/v1/items", StatusCode: request.status, ResponseTimeMs: 10,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	quotas, _ := repositories.ParseConsumerQuotas("alice:5/hour")
+
+	consumers, err := statsRepo.GetTopConsumers(quotas, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumers) != 2 {
+		t.Fatalf("Expected 2 consumers, got %d", len(consumers))
+	}
+	alice, key := consumers[0], consumers[1]
+	if alice.Consumer != "alice" || alice.Kind != repositories.ConsumerKindUser || alice.Hits != 6 || alice.ErrorCount != 1 || alice.UniqueIPs != 2 {
+		t.Errorf("Unexpected stats for alice %+v", alice)
+	}
+	if alice.Quota == nil || alice.Quota.Used != 6 || !alice.Quota.Exceeded {
+		t.Errorf("Expected alice over quota, got %+v", alice.Quota)
+	}
+	if key.Consumer != "key:b2b9323ee091c380" || key.Kind != repositories.ConsumerKindAPIKey || key.Hits != 2 || key.Quota != nil {
+		t.Errorf("Unexpected stats for the API key %+v", key)
+	}
+
+	detail, err := statsRepo.GetConsumerDetail("key:b2b9323ee091c380", quotas, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail == nil || detail.Summary.Hits != 2 || len(detail.StatusCodes) != 1 || detail.StatusCodes[0].StatusCode != 429 || len(detail.TopIPs) != 1 || detail.TopIPs[0].Name != "10.0.0.3" {
+		t.Errorf("Unexpected API key detail %+v", detail)
+	}
+	if detail, err := statsRepo.GetConsumerDetail("bob", quotas, nil, nil); err != nil || detail != nil {
+		t.Errorf("Expected no detail for an unknown consumer, got %+v (%v)", detail, err)
+	}
+}
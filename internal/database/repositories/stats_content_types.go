@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// Classes of response content types, see ContentClass
+const (
+	ContentClassHTML  = "html"
+	ContentClassJSON  = "json"
+	ContentClassImage = "image"
+	ContentClassVideo = "video"
+	ContentClassFont  = "font"
+	ContentClassOther = "other" // Everything else, including responses without Content-Type
+)
+
+// ContentClassStats holds the responses of one content class
+type ContentClassStats struct {
+	Class     string              `json:"class"`
+	Hits      int64               `json:"hits"`
+	Bandwidth int64               `json:"bandwidth"`
+	AvgSize   float64             `json:"avg_size"` // Bytes per response
+	Share     float64             `json:"share"`    // Percentage of all responses
+	Types     []*ContentTypeStats `json:"types"`    // Content types of the class, most responses first
+}
+
+// ContentTypeStats holds the responses of one content type (MIME type without parameters)
+type ContentTypeStats struct {
+	ContentType string  `json:"content_type"` // Empty when the response had no Content-Type
+	Class       string  `json:"class"`
+	Hits        int64   `json:"hits"`
+	Bandwidth   int64   `json:"bandwidth"`
+	AvgSize     float64 `json:"avg_size"` // Bytes per response
+	Share       float64 `json:"share"`    // Percentage of all responses
+}
+
+// contentClassOrder lists the classes of GetContentClassDistribution when they have the same number of responses
+var contentClassOrder = []string{ContentClassHTML, ContentClassJSON, ContentClassImage, ContentClassVideo, ContentClassFont, ContentClassOther}
+
+// ContentClass returns the class of a Content-Type value, parameters such as charset are ignored
+func ContentClass(contentType string) string {
+	mimeType := normalizeContentType(contentType)
+	switch {
+	case mimeType == "text/html", mimeType == "application/xhtml+xml":
+		return ContentClassHTML
+	case mimeType == "application/json", strings.HasSuffix(mimeType, "+json"):
+		return ContentClassJSON
+	case strings.HasPrefix(mimeType, "image/"):
+		return ContentClassImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return ContentClassVideo
+	case strings.HasPrefix(mimeType, "font/"), strings.HasPrefix(mimeType, "application/font-"),
+		strings.HasPrefix(mimeType, "application/x-font-"), mimeType == "application/vnd.ms-fontobject":
+		return ContentClassFont
+	}
+	return ContentClassOther
+}
+
+// normalizeContentType returns the lowercase MIME type of a Content-Type value, without parameters
+func normalizeContentType(contentType string) string {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// GetContentClassDistribution returns the responses per content class (html, json, image, video, font, other)
+// with their content types, most responses first
+func (r *statsRepo) GetContentClassDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContentClassStats, error) {
+	types, err := r.getContentTypes(filters, excludeIP)
+	if err != nil {
+		return nil, err
+	}
+
+	byClass := make(map[string]*ContentClassStats, len(contentClassOrder))
+	classes := make([]*ContentClassStats, 0, len(contentClassOrder))
+	for _, class := range contentClassOrder {
+		byClass[class] = &ContentClassStats{Class: class, Types: []*ContentTypeStats{}}
+	}
+	var total int64
+	for _, stats := range types {
+		class := byClass[stats.Class]
+		class.Hits += stats.Hits
+		class.Bandwidth += stats.Bandwidth
+		class.Types = append(class.Types, stats)
+		total += stats.Hits
+	}
+	for _, class := range contentClassOrder {
+		stats := byClass[class]
+		if stats.Hits == 0 {
+			continue
+		}
+		stats.AvgSize = float64(stats.Bandwidth) / float64(stats.Hits)
+		stats.Share = float64(stats.Hits) * 100 / float64(total)
+		classes = append(classes, stats)
+	}
+	sort.SliceStable(classes, func(i, j int) bool { return classes[i].Hits > classes[j].Hits })
+
+	return classes, nil
+}
+
+// GetTopContentTypes returns the content types with the most responses
+func (r *statsRepo) GetTopContentTypes(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContentTypeStats, error) {
+	types, err := r.getContentTypes(filters, excludeIP)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) > limit {
+		types = types[:limit]
+	}
+	return types, nil
+}
+
+// getContentTypes returns the responses per content type, most responses first
+// Values differing only by case or parameters (text/html; charset=utf-8) are counted as one type.
+func (r *statsRepo) getContentTypes(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ContentTypeStats, error) {
+	var rows []*ContentTypeStats
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select("response_content_type as content_type, COUNT(*) as hits, COALESCE(SUM(response_size), 0) as bandwidth").
+		Where("timestamp > ?", r.getTimeRange())
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	if err := query.Group("response_content_type").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get content types", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	byType := make(map[string]*ContentTypeStats, len(rows))
+	types := make([]*ContentTypeStats, 0, len(rows))
+	var total int64
+	for _, row := range rows {
+		mimeType := normalizeContentType(row.ContentType)
+		stats := byType[mimeType]
+		if stats == nil {
+			stats = &ContentTypeStats{ContentType: mimeType, Class: ContentClass(mimeType)}
+			byType[mimeType] = stats
+			types = append(types, stats)
+		}
+		stats.Hits += row.Hits
+		stats.Bandwidth += row.Bandwidth
+		total += row.Hits
+	}
+	for _, stats := range types {
+		stats.AvgSize = float64(stats.Bandwidth) / float64(stats.Hits)
+		stats.Share = float64(stats.Hits) * 100 / float64(total)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Hits != types[j].Hits {
+			return types[i].Hits > types[j].Hits
+		}
+		return types[i].ContentType < types[j].ContentType
+	})
+
+	return types, nil
+}
@@ -0,0 +1,76 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestContentClass(t *testing.T) {
+	tests := map[string]string{
+		"text/html; charset=utf-8":      repositories.ContentClassHTML,
+		"application/xhtml+xml":         repositories.ContentClassHTML,
+		"Application/JSON":              repositories.ContentClassJSON,
+		"application/problem+json":      repositories.ContentClassJSON,
+		"image/webp":                    repositories.ContentClassImage,
+		"video/mp4":                     repositories.ContentClassVideo,
+		"font/woff2":                    repositories.ContentClassFont,
+		"application/vnd.ms-fontobject": repositories.ContentClassFont,
+		"text/css":                      repositories.ContentClassOther,
+		"":                              repositories.ContentClassOther,
+	}
+	for contentType, expected := range tests {
+		if class := repositories.ContentClass(contentType); class != expected {
+			t.Errorf("Expected %q to be %s, got %s", contentType, expected, class)
+		}
+	}
+}
+
+func TestContentTypes(t *testing.T) {
+	// 3 HTML pages of 1 KB (written two ways), 2 images of 100 KB, 1 response without Content-Type
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for i, response := range []struct {
+		contentType string
+		size        int64
+	}{
+		{"text/html; charset=utf-8", 1000}, {"text/html; charset=utf-8", 1000}, {"TEXT/HTML", 1000},
+		{"image/png", 100000}, {"image/jpeg", 100000}, {"", 0},
+	} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: now, RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/", StatusCode: 200,
+			ResponseContentType: response.contentType, ResponseSize: response.size,
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	classes, err := statsRepo.GetContentClassDistribution(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classes) != 3 || classes[0].Class != repositories.ContentClassHTML || classes[1].Class != repositories.ContentClassImage {
+		t.Fatalf("Expected html, image and other classes, got %+v", classes)
+	}
+	if html := classes[0]; html.Hits != 3 || html.Bandwidth != 3000 || html.AvgSize != 1000 || html.Share != 50 || len(html.Types) != 1 {
+		t.Errorf("Unexpected html stats %+v", html)
+	}
+	if image := classes[1]; image.Bandwidth != 200000 || len(image.Types) != 2 {
+		t.Errorf("Unexpected image stats %+v", image)
+	}
+
+	types, err := statsRepo.GetTopContentTypes(2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 2 {
+		t.Fatalf("Expected 2 content types, got %d", len(types))
+	}
+	if types[0].ContentType != "text/html" || types[0].Hits != 3 || types[1].ContentType != "" {
+		t.Errorf("Expected text/html then the responses without type, got %+v %+v", types[0], types[1])
+	}
+}
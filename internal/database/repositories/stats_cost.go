@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// hoursPerMonth is the average month length used to project costs
+const hoursPerMonth = 730
+
+// CostReport translates bandwidth into estimated egress cost
+type CostReport struct {
+	Currency     string              `json:"currency"`
+	DefaultPerGB float64             `json:"default_per_gb"`
+	ServiceRates map[string]float64  `json:"service_rates,omitempty"` // Per-service prices per GB
+	Summary      *CostSummary        `json:"summary"`
+	Timeline     []*CostTimelineData `json:"timeline"`
+	Paths        []*CostGroupStats   `json:"paths"`
+	Services     []*CostGroupStats   `json:"services"`
+}
+
+// CostSummary holds the estimated cost of the time range
+type CostSummary struct {
+	Requests             int64   `json:"requests"`
+	Bytes                int64   `json:"bytes"`
+	Cost                 float64 `json:"cost"`
+	CostPer1kRequests    float64 `json:"cost_per_1k_requests"`
+	ProjectedMonthlyCost float64 `json:"projected_monthly_cost"` // Cost of the time range scaled to 730 hours
+}
+
+// CostTimelineData holds bandwidth and estimated cost for one time bucket
+type CostTimelineData struct {
+	Hour  string  `json:"hour"`
+	Bytes int64   `json:"bytes"`
+	Cost  float64 `json:"cost"`
+}
+
+// CostGroupStats holds the estimated cost of one path or service
+type CostGroupStats struct {
+	Name              string  `json:"name"`
+	Requests          int64   `json:"requests"`
+	Bytes             int64   `json:"bytes"`
+	Cost              float64 `json:"cost"`
+	CostPer1kRequests float64 `json:"cost_per_1k_requests"`
+	Share             float64 `json:"share"` // Percentage of the total cost
+}
+
+// GetCostReport estimates egress cost over time, for the most expensive paths and for each service
+// limit applies to paths and services, which are ordered by cost.
+func (r *statsRepo) GetCostReport(model *CostModel, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*CostReport, error) {
+	if model == nil {
+		model = NewDefaultCostModel()
+	}
+	since := r.getTimeRange()
+
+	requests := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since)
+		query = r.applyServiceFilters(query, filters)
+		query = r.applyExcludeOwnIP(query, excludeIP)
+		return query
+	}
+
+	report := &CostReport{
+		Currency:     model.Currency(),
+		DefaultPerGB: model.DefaultPerGB(),
+		ServiceRates: model.ServiceRates(),
+		Summary:      &CostSummary{},
+		Timeline:     make([]*CostTimelineData, 0),
+		Paths:        make([]*CostGroupStats, 0),
+		Services:     make([]*CostGroupStats, 0),
+	}
+	if len(report.ServiceRates) == 0 {
+		report.ServiceRates = nil
+	}
+
+	costSQL, costArgs := model.CostSQL()
+
+	err := requests().
+		Select("COUNT(*) as requests, COALESCE(SUM(response_size), 0) as bytes, "+costSQL+" as cost", costArgs...).
+		Scan(report.Summary).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get cost summary", r.logger.Args("error", err))
+		return nil, err
+	}
+	report.Summary.CostPer1kRequests = costPer1k(report.Summary.Cost, report.Summary.Requests)
+	report.Summary.ProjectedMonthlyCost = report.Summary.Cost / float64(r.LookbackHours()) * hoursPerMonth
+	if report.Summary.Requests == 0 {
+		return report, nil
+	}
+
+	err = requests().
+		Select(timelineBucket(r.LookbackHours())+" as hour, COALESCE(SUM(response_size), 0) as bytes, "+costSQL+" as cost", costArgs...).
+		Group("hour").
+		Order("hour").
+		Scan(&report.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get cost timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	groups := []struct {
+		expression string
+		target     *[]*CostGroupStats
+	}{
+		{"path", &report.Paths},
+		{"COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)", &report.Services},
+	}
+	for _, group := range groups {
+		err := requests().
+			Select(group.expression+" as name, COUNT(*) as requests, COALESCE(SUM(response_size), 0) as bytes, "+costSQL+" as cost", costArgs...).
+			Group("name").
+			Order("cost DESC").
+			Limit(limit).
+			Scan(group.target).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to group costs", r.logger.Args("error", err))
+			return nil, err
+		}
+		for _, stats := range *group.target {
+			stats.CostPer1kRequests = costPer1k(stats.Cost, stats.Requests)
+			if report.Summary.Cost > 0 {
+				stats.Share = stats.Cost / report.Summary.Cost * 100
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// costPer1k returns the average cost of 1000 requests
+func costPer1k(cost float64, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return cost / float64(requests) * 1000
+}
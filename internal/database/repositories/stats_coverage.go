@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+)
+
+// Coverage of a timeline bucket or source hour, telling quiet periods from periods nobody read the logs
+const (
+	CoverageTraffic     = "traffic"      // Requests were recorded
+	CoverageZeroTraffic = "zero_traffic" // A processor read the sources and no request came in
+	CoverageNoData      = "no_data"      // No processor read the sources, traffic is unknown
+)
+
+// MaxCoverageHours bounds the range of per-source coverage, which lists every hour
+const MaxCoverageHours = 168
+
+// SourceCoverageHour is the coverage of one source during one UTC hour
+type SourceCoverageHour struct {
+	Hour         string `json:"hour"`
+	Requests     int64  `json:"requests"`
+	AliveSeconds int64  `json:"alive_seconds"` // Time a processor read the source
+	Coverage     string `json:"coverage"`
+}
+
+// SourceCoverageReport is the hourly coverage of one log source
+type SourceCoverageReport struct {
+	Source           string                `json:"source"`
+	TrafficHours     int                   `json:"traffic_hours"`
+	ZeroTrafficHours int                   `json:"zero_traffic_hours"`
+	NoDataHours      int                   `json:"no_data_hours"`
+	Hours            []*SourceCoverageHour `json:"hours"`
+}
+
+// coverageOf returns the coverage of a period from its requests and the time processors read the sources
+func coverageOf(requests int64, aliveSeconds int64) string {
+	switch {
+	case requests > 0:
+		return CoverageTraffic
+	case aliveSeconds > 0:
+		return CoverageZeroTraffic
+	default:
+		return CoverageNoData
+	}
+}
+
+// AddTimelineCoverage sets the coverage of each bucket of a timeline (GetTimelineStats over hours), adding the
+// buckets without traffic: zero_traffic when a processor read a source during the bucket, else no_data.
+// Buckets are listed by the same expression as the timeline query, so keys match at every granularity.
+func (r *statsRepo) AddTimelineCoverage(timeline []*TimelineData, hours int) ([]*TimelineData, error) {
+	now := r.clock.Now()
+	since := now.Add(-time.Duration(hours) * time.Hour)
+
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	bucket := trafficTimelineBucket(hours, "hours.hour")
+	var rows []*struct {
+		Hour         string
+		AliveSeconds int64
+	}
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE hours(hour) AS (
+			SELECT ? UNION ALL SELECT strftime('%Y-%m-%d %H:00', hour, '+1 hour') FROM hours WHERE hour < ?
+		)
+		SELECT `+bucket+` AS hour, COALESCE(SUM(c.alive_seconds), 0) AS alive_seconds
+		FROM hours LEFT JOIN source_coverage c ON c.hour = hours.hour
+		GROUP BY 1`, CoverageHour(since), CoverageHour(now)).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get timeline coverage", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	byHour := make(map[string]*TimelineData, len(timeline))
+	for _, point := range timeline {
+		point.Coverage = CoverageTraffic
+		byHour[point.Hour] = point
+	}
+	for _, row := range rows {
+		if _, ok := byHour[row.Hour]; !ok {
+			timeline = append(timeline, &TimelineData{Hour: row.Hour, Coverage: coverageOf(0, row.AliveSeconds)})
+		}
+	}
+	// Same order as the timeline query
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Hour < timeline[j].Hour
+	})
+	return timeline, nil
+}
+
+// GetSourceCoverage returns the hourly coverage of each log source over the last hours (at most MaxCoverageHours)
+// Sources are the configured ones and any source with requests or coverage in the range.
+func (r *statsRepo) GetSourceCoverage(hours int) ([]*SourceCoverageReport, error) {
+	hours = min(max(hours, 1), MaxCoverageHours)
+	now := r.clock.Now()
+	start := now.Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour)
+
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+	db := r.db.WithContext(ctx)
+
+	var names []string
+	if err := db.Table("log_sources").Pluck("name", &names).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get log sources", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	type sourceHour struct {
+		SourceName   string
+		Hour         string
+		Requests     int64
+		AliveSeconds int64
+	}
+	var requests, alive []*sourceHour
+	err := db.Table("http_requests").
+		Select("source_name, strftime('%Y-%m-%d %H:00', timestamp) AS hour, COUNT(*) AS requests").
+		Where("timestamp >= ?", start).
+		Group("source_name, hour").
+		Scan(&requests).Error
+	if err == nil {
+		err = db.Table("source_coverage").
+			Select("source_name, hour, alive_seconds").
+			Where("hour >= ?", CoverageHour(start)).
+			Scan(&alive).Error
+	}
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get source coverage", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	bySource := make(map[string]map[string]*sourceHour)
+	add := func(name string) map[string]*sourceHour {
+		if bySource[name] == nil {
+			bySource[name] = make(map[string]*sourceHour)
+		}
+		return bySource[name]
+	}
+	for _, name := range names {
+		add(name)
+	}
+	for _, row := range requests {
+		add(row.SourceName)[row.Hour] = row
+	}
+	for _, row := range alive {
+		recorded := add(row.SourceName)
+		if hour, ok := recorded[row.Hour]; ok {
+			hour.AliveSeconds = row.AliveSeconds
+		} else {
+			recorded[row.Hour] = row
+		}
+	}
+
+	reports := make([]*SourceCoverageReport, 0, len(bySource))
+	for name, recorded := range bySource {
+		report := &SourceCoverageReport{Source: name, Hours: make([]*SourceCoverageHour, 0, hours+1)}
+		for at := start; !at.After(now); at = at.Add(time.Hour) {
+			hour := &SourceCoverageHour{Hour: CoverageHour(at)}
+			if row, ok := recorded[hour.Hour]; ok {
+				hour.Requests, hour.AliveSeconds = row.Requests, row.AliveSeconds
+			}
+			hour.Coverage = coverageOf(hour.Requests, hour.AliveSeconds)
+			switch hour.Coverage {
+			case CoverageTraffic:
+				report.TrafficHours++
+			case CoverageZeroTraffic:
+				report.ZeroTrafficHours++
+			default:
+				report.NoDataHours++
+			}
+			report.Hours = append(report.Hours, hour)
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Source < reports[j].Source
+	})
+	return reports, nil
+}
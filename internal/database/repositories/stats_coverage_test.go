@@ -0,0 +1,92 @@
+package repositories_test
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/clock"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestCoverage(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	// The web source was read from 10:00 and got one request at 11:10, the api source was never read
+	sourceRepo := repositories.NewLogSourceRepository(db)
+	for _, name := range []string{"web", "api"} {
+		if err := sourceRepo.Create(&models.LogSource{Name: name, Path: "/var/log/" + name, ParserType: "traefik"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, alive := range []struct {
+		at      time.Time
+		seconds int64
+	}{
+		{now.Add(-150 * time.Minute), 3000},
+		{now.Add(-140 * time.Minute), 3000}, // Capped at an hour
+		{now.Add(-80 * time.Minute), 3600},
+		{now.Add(-20 * time.Minute), 1800},
+	} {
+		if err := sourceRepo.AddCoverage("web", alive.at, alive.seconds); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := db.Create(&models.HTTPRequest{
+		SourceName: "web", Timestamp: now.Add(-80 * time.Minute), RequestHash: "1", ClientIP: "10.0.0.1",
+		Method: "GET", Host: "example", Path: "/", StatusCode: 200,
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, clock.NewFake(now))
+	timeline, err := statsRepo.GetTimelineStats(24, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline, err = statsRepo.AddTimelineCoverage(timeline, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeline) != 25 || timeline[0].Hour != "2026-03-09 12:00" || timeline[24].Hour != "2026-03-10 12:00" {
+		t.Fatalf("Expected the 25 hourly buckets of the range, got %d", len(timeline))
+	}
+	expected := map[string]string{
+		"2026-03-10 09:00": repositories.CoverageNoData,
+		"2026-03-10 10:00": repositories.CoverageZeroTraffic,
+		"2026-03-10 11:00": repositories.CoverageTraffic,
+		"2026-03-10 12:00": repositories.CoverageZeroTraffic,
+	}
+	for _, point := range timeline {
+		if coverage := expected[point.Hour]; coverage != "" && point.Coverage != coverage {
+			t.Errorf("Expected %s coverage at %s, got %s", coverage, point.Hour, point.Coverage)
+		}
+	}
+
+	reports, err := statsRepo.GetSourceCoverage(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Source != "api" || reports[1].Source != "web" {
+		t.Fatalf("Expected the coverage of api and web, got %d sources", len(reports))
+	}
+	if api := reports[0]; api.NoDataHours != 4 || api.TrafficHours+api.ZeroTrafficHours != 0 {
+		t.Errorf("Expected 4 hours without data for api, got %+v", api)
+	}
+	web := reports[1]
+	if web.TrafficHours != 1 || web.ZeroTrafficHours != 2 || web.NoDataHours != 1 || len(web.Hours) != 4 {
+		t.Fatalf("Expected 1 hour with traffic, 2 without and 1 without data for web, got %+v", web)
+	}
+	if hour := web.Hours[1]; hour.Hour != "2026-03-10 10:00" || hour.AliveSeconds != 3600 {
+		t.Errorf("Expected coverage capped at an hour, got %+v", hour)
+	}
+	if hour := web.Hours[2]; hour.Requests != 1 || hour.AliveSeconds != 3600 {
+		t.Errorf("Expected the request and coverage of 11:00, got %+v", hour)
+	}
+}
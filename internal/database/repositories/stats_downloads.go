@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// DefaultDownloadMinSize is the response size from which a response counts as a download (1 MB)
+const DefaultDownloadMinSize = 1 << 20
+
+// DownloadReport lists the responses that account for most of the egress
+// Large files exposed by mistake (backups, database dumps, archives) stand out by their total bytes.
+type DownloadReport struct {
+	MinSize        int64            `json:"min_size"`        // Smallest response size counted, in bytes
+	Downloads      int64            `json:"downloads"`       // Responses of at least MinSize
+	TotalBytes     int64            `json:"total_bytes"`     // Bytes sent by these responses
+	BandwidthShare float64          `json:"bandwidth_share"` // Percentage of all bytes sent
+	Largest        []*LargeResponse `json:"largest" gorm:"-"`
+	Files          []*DownloadStats `json:"files" gorm:"-"` // Most bytes sent first
+}
+
+// LargeResponse is one of the largest individual responses
+type LargeResponse struct {
+	Timestamp    time.Time `json:"timestamp"`
+	ClientIP     string    `json:"client_ip"`
+	Method       string    `json:"method"`
+	Host         string    `json:"host"`
+	Path         string    `json:"path"`
+	StatusCode   int       `json:"status_code"`
+	ResponseSize int64     `json:"response_size"`
+	ContentType  string    `json:"content_type"`
+	BackendName  string    `json:"backend_name"`
+}
+
+// DownloadStats holds the large responses of one file (host and path)
+type DownloadStats struct {
+	Host           string    `json:"host"`
+	Path           string    `json:"path"`
+	Count          int64     `json:"count"`
+	TotalBytes     int64     `json:"total_bytes"`
+	MaxSize        int64     `json:"max_size"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+// downloadRow holds DownloadStats columns as scanned, SQLite returns MAX(timestamp) as text
+type downloadRow struct {
+	Host           string
+	Path           string
+	Count          int64
+	TotalBytes     int64
+	MaxSize        int64
+	UniqueVisitors int64
+	LastSeen       string
+}
+
+// GetDownloadReport returns the largest responses and the files sending the most bytes in responses of at least
+// minSize bytes (0 = DefaultDownloadMinSize), limit entries each
+func (r *statsRepo) GetDownloadReport(minSize int64, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*DownloadReport, error) {
+	if minSize <= 0 {
+		minSize = DefaultDownloadMinSize
+	}
+	since := r.getTimeRange()
+
+	requests := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since)
+		query = r.applyServiceFilters(query, filters)
+		return r.applyExcludeOwnIP(query, excludeIP)
+	}
+
+	var totals struct {
+		Downloads  int64
+		TotalBytes int64
+		AllBytes   int64
+	}
+	err := requests().
+		Select(`COUNT(CASE WHEN response_size >= ? THEN 1 END) as downloads,
+			COALESCE(SUM(CASE WHEN response_size >= ? THEN response_size END), 0) as total_bytes,
+			COALESCE(SUM(response_size), 0) as all_bytes`, minSize, minSize).
+		Scan(&totals).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to count downloads", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	report := &DownloadReport{
+		MinSize:    minSize,
+		Downloads:  totals.Downloads,
+		TotalBytes: totals.TotalBytes,
+		Largest:    make([]*LargeResponse, 0),
+		Files:      make([]*DownloadStats, 0),
+	}
+	if totals.AllBytes > 0 {
+		report.BandwidthShare = float64(totals.TotalBytes) * 100 / float64(totals.AllBytes)
+	}
+	if report.Downloads == 0 {
+		return report, nil
+	}
+
+	err = requests().
+		Select("timestamp, client_ip, method, host, path, status_code, response_size, response_content_type as content_type, backend_name").
+		Where("response_size >= ?", minSize).
+		Order("response_size DESC, timestamp DESC").
+		Limit(limit).
+		Scan(&report.Largest).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get largest responses", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	var rows []*downloadRow
+	err = requests().
+		Select(`host, path, COUNT(*) as count, SUM(response_size) as total_bytes, MAX(response_size) as max_size,
+			COUNT(DISTINCT client_ip) as unique_visitors, MAX(timestamp) as last_seen`).
+		Where("response_size >= ?", minSize).
+		Group("host, path").
+		Order("total_bytes DESC, count DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get downloaded files", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, row := range rows {
+		report.Files = append(report.Files, &DownloadStats{
+			Host:           row.Host,
+			Path:           row.Path,
+			Count:          row.Count,
+			TotalBytes:     row.TotalBytes,
+			MaxSize:        row.MaxSize,
+			UniqueVisitors: row.UniqueVisitors,
+			LastSeen:       parseSQLiteTime(row.LastSeen),
+		})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,61 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestDownloadReport(t *testing.T) {
+	// A 500 MB backup fetched twice, a 5 MB video fetched 3 times by 3 visitors, and small pages
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for i, download := range []struct {
+		path     string
+		size     int64
+		clientIP string
+	}{
+		{"/backup.tar.gz", 500 << 20, "203.0.113.7"}, {"/backup.tar.gz", 500 << 20, "203.0.113.7"},
+		{"/intro.mp4", 5 << 20, "10.0.0.1"}, {"/intro.mp4", 5 << 20, "10.0.0.2"}, {"/intro.mp4", 5 << 20, "10.0.0.3"},
+		{"/", 2000, "10.0.0.1"}, {"/", 2000, "10.0.0.2"},
+	} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: download.clientIP, Method: "GET", Host: "example.com", Path: download.path, StatusCode: 200,
+			ResponseSize: download.size,
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	report, err := statsRepo.GetDownloadReport(0, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MinSize != 1<<20 || report.Downloads != 5 || report.TotalBytes != 1015<<20 || report.BandwidthShare < 99.9 {
+		t.Errorf("Unexpected totals %+v", report)
+	}
+	if len(report.Largest) != 5 || report.Largest[0].Path != "/backup.tar.gz" || report.Largest[0].ClientIP != "203.0.113.7" {
+		t.Errorf("Expected the backup first of the 5 large responses, got %+v", report.Largest)
+	}
+	if len(report.Files) != 2 {
+		t.Fatalf("Expected 2 downloaded files, got %d", len(report.Files))
+	}
+	if backup := report.Files[0]; backup.Path != "/backup.tar.gz" || backup.Count != 2 || backup.UniqueVisitors != 1 || backup.LastSeen.IsZero() {
+		t.Errorf("Unexpected backup downloads %+v", backup)
+	}
+	if video := report.Files[1]; video.Count != 3 || video.TotalBytes != 15<<20 || video.MaxSize != 5<<20 {
+		t.Errorf("Unexpected video downloads %+v", video)
+	}
+
+	// Above the size of every response, nothing is listed
+	report, err = statsRepo.GetDownloadReport(1<<30, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Downloads != 0 || len(report.Largest) != 0 || len(report.Files) != 0 {
+		t.Errorf("Expected no download above 1 GB, got %+v", report)
+	}
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// FieldValueRollupName is the checkpoint name of the hourly field value rollups
+const FieldValueRollupName = "field_values"
+
+// FieldValueFields are the filter fields whose distinct values are rolled up, they are http_requests columns
+var FieldValueFields = []string{"host", "backend_name", "browser", "os", "geo_country"}
+
+// IsFieldValueField returns whether the distinct values of a field are available
+func IsFieldValueField(field string) bool {
+	return slices.Contains(FieldValueFields, field)
+}
+
+// FieldValue is a distinct value of a filter field with its requests in the time range
+type FieldValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// GetFieldValues returns the distinct values of a field (FieldValueFields) with their requests in the time range,
+// most requested first. search keeps the values containing it, case-insensitive.
+// Complete hours come from the hourly field value rollups, only the partial hours at both ends of the range are
+// grouped from requests; without up-to-date rollups the whole range is.
+func (r *statsRepo) GetFieldValues(field string, search string, limit int) ([]*FieldValue, error) {
+	since := r.getTimeRange()
+
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+	db := r.db.WithContext(ctx)
+
+	matching := func(query *gorm.DB, column string) *gorm.DB {
+		if search == "" {
+			return query
+		}
+		return query.Where(column+` LIKE ? ESCAPE '\'`, "%"+escapeLike(search)+"%")
+	}
+	fromRequests := func(where string, args ...interface{}) ([]*FieldValue, error) {
+		var values []*FieldValue
+		query := db.Model(&models.HTTPRequest{}).
+			Select(field+" AS value, COUNT(*) AS count").
+			Where(field+" != ''").
+			Where(where, args...)
+		err := matching(query, field).Group(field).Scan(&values).Error
+		return values, err
+	}
+
+	firstHour, builtUntil, err := r.fieldValueRollupRange(db, since)
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get field value rollups", r.logger.Args("field", field, "error", err))
+		return nil, err
+	}
+
+	var values []*FieldValue
+	if firstHour.Before(builtUntil) {
+		var rolledUp []*FieldValue
+		query := db.Model(&models.FieldValueRollup{}).
+			Select("value, SUM(count) AS count").
+			Where("field = ? AND hour >= ? AND hour < ?", field, firstHour, builtUntil)
+		if err = matching(query, "value").Group("value").Scan(&rolledUp).Error; err == nil {
+			values, err = fromRequests("(timestamp > ? AND timestamp < ?) OR timestamp >= ?", since, firstHour, builtUntil)
+			values = append(values, rolledUp...)
+		}
+	} else {
+		values, err = fromRequests("timestamp > ?", since)
+	}
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get field values", r.logger.Args("field", field, "error", err))
+		return nil, err
+	}
+
+	// Values of the rollups and of the partial hours are summed
+	counts := make(map[string]int64, len(values))
+	for _, value := range values {
+		counts[value.Value] += value.Count
+	}
+	merged := make([]*FieldValue, 0, len(counts))
+	for value, count := range counts {
+		merged = append(merged, &FieldValue{Value: value, Count: count})
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Count != merged[j].Count {
+			return merged[i].Count > merged[j].Count
+		}
+		return merged[i].Value < merged[j].Value
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+// fieldValueRollupRange returns the complete hours of the range covered by up-to-date field value rollups,
+// firstHour not before builtUntil when rollups can't be used (not built yet, or lagging behind ingestion)
+func (r *statsRepo) fieldValueRollupRange(db *gorm.DB, since time.Time) (firstHour time.Time, builtUntil time.Time, err error) {
+	firstHour = since.UTC().Truncate(time.Hour).Add(time.Hour)
+
+	var checkpoint models.RollupCheckpoint
+	if err := db.Where("name = ?", FieldValueRollupName).Limit(1).Find(&checkpoint).Error; err != nil || checkpoint.Name == "" {
+		return firstHour, firstHour, err
+	}
+	builtUntil = checkpoint.BuiltUntil.UTC()
+	if !firstHour.Before(builtUntil) {
+		return firstHour, firstHour, nil
+	}
+
+	// Rollups are stale if their hours received requests the rollup service has not seen yet
+	var pending []uint
+	err = db.Model(&models.HTTPRequest{}).
+		Where("id > ? AND timestamp >= ? AND timestamp < ?", checkpoint.LastID, firstHour, builtUntil).
+		Limit(1).
+		Pluck("id", &pending).Error
+	if err != nil || len(pending) > 0 {
+		return firstHour, firstHour, err
+	}
+	return firstHour, builtUntil, nil
+}
@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestFieldValues(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// Requests spread over the last 20 hours, including the current (partial) hour
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		browser := []string{"Chrome", "Firefox", "Chrome", ""}[i%4]
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * 20 * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/", StatusCode: 200, Browser: browser,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	expected := []*repositories.FieldValue{{Value: "Chrome", Count: 30}, {Value: "Firefox", Count: 15}}
+
+	scanned, err := repo.GetFieldValues("browser", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scanned, expected) {
+		t.Fatalf("Expected %v from requests, got %v", expected, scanned)
+	}
+
+	if err := database.NewRollupService(db, log, time.Minute).Run(); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&models.FieldValueRollup{}).Where("field = ?", "browser").Count(&count)
+	if count == 0 {
+		t.Fatal("Expected field value rollups to be created")
+	}
+
+	rolledUp, err := repo.GetFieldValues("browser", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rolledUp, expected) {
+		t.Errorf("Expected %v from rollups, got %v", expected, rolledUp)
+	}
+
+	matching, err := repo.GetFieldValues("browser", "fox", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matching) != 1 || matching[0].Value != "Firefox" {
+		t.Errorf("Expected only Firefox to contain fox, got %v", matching)
+	}
+	hosts, err := repo.GetFieldValues("host", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0].Count != 60 {
+		t.Errorf("Expected the 60 requests of example.com, got %v", hosts)
+	}
+}
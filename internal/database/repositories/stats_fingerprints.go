@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// Caching policies of responses, derived from their Cache-Control header
+const (
+	CachePolicyNone      = "none"      // No Cache-Control header logged
+	CachePolicyNoStore   = "no-store"  // Must not be stored
+	CachePolicyNoCache   = "no-cache"  // Stored, revalidated on every use (also max-age=0)
+	CachePolicyPrivate   = "private"   // Only cached by browsers
+	CachePolicyPublic    = "public"    // Cacheable by shared caches (CDNs, proxies)
+	CachePolicyUndefined = "undefined" // Cache-Control without a storage directive, e.g. "must-revalidate"
+)
+
+// BackendFingerprint holds the backend technologies and caching behavior of one service
+// It reads the Server, X-Powered-By and Cache-Control response headers captured into proxy_metadata
+// (CAPTURE_RESPONSE_HEADERS), so exposed version banners and uncached static content stand out.
+type BackendFingerprint struct {
+	BackendName  string               `json:"backend_name"` // Service (backend_name, else backend_url, else host)
+	Hits         int64                `json:"hits"`
+	Technologies []*TechnologyStats   `json:"technologies"`  // Most requests first
+	Caching      []*CachePolicyStats  `json:"caching"`       // Most requests first
+	CacheControl []*CacheControlStats `json:"cache_control"` // Most frequent header values, most requests first
+}
+
+// TechnologyStats holds the requests answered with one Server / X-Powered-By combination
+type TechnologyStats struct {
+	Server    string  `json:"server"`
+	PoweredBy string  `json:"powered_by"`
+	Hits      int64   `json:"hits"`
+	Share     float64 `json:"share"` // Percentage of the requests of the service
+}
+
+// CachePolicyStats holds the requests answered with one caching policy
+type CachePolicyStats struct {
+	Policy string  `json:"policy"`
+	Hits   int64   `json:"hits"`
+	Share  float64 `json:"share"` // Percentage of the requests of the service
+}
+
+// CacheControlStats holds the requests answered with one Cache-Control value
+type CacheControlStats struct {
+	Value string `json:"value"`
+	Hits  int64  `json:"hits"`
+}
+
+// maxCacheControlValues caps the Cache-Control values listed per service
+const maxCacheControlValues = 5
+
+// fingerprintRow holds the requests of one service with one combination of response headers
+type fingerprintRow struct {
+	BackendName  string
+	Server       string
+	PoweredBy    string
+	CacheControl string
+	Hits         int64
+}
+
+// GetBackendFingerprints returns the backend technologies and caching behavior of the services with the most requests
+// Only requests with at least one captured response header are counted.
+func (r *statsRepo) GetBackendFingerprints(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*BackendFingerprint, error) {
+	server := responseHeaderExpression("Server")
+	poweredBy := responseHeaderExpression("X-Powered-By")
+	cacheControl := responseHeaderExpression("Cache-Control")
+
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(`COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) as backend_name, `+
+			server+` as server, `+poweredBy+` as powered_by, `+cacheControl+` as cache_control, COUNT(*) as hits`).
+		Where("timestamp > ?", r.getTimeRange()).
+		Where("proxy_metadata LIKE ?", `%"response_headers"%`)
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	var rows []*fingerprintRow
+	if err := query.Group("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host), server, powered_by, cache_control").
+		Order("hits DESC, server, powered_by, cache_control").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get backend fingerprints", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	return buildBackendFingerprints(rows, limit), nil
+}
+
+// buildBackendFingerprints aggregates rows per service, keeping the limit services with the most requests
+func buildBackendFingerprints(rows []*fingerprintRow, limit int) []*BackendFingerprint {
+	type aggregate struct {
+		fingerprint  *BackendFingerprint
+		technologies map[[2]string]*TechnologyStats
+		policies     map[string]*CachePolicyStats
+		values       map[string]*CacheControlStats
+	}
+
+	byService := make(map[string]*aggregate)
+	services := make([]*aggregate, 0)
+	for _, row := range rows {
+		service := byService[row.BackendName]
+		if service == nil {
+			service = &aggregate{
+				fingerprint:  &BackendFingerprint{BackendName: row.BackendName},
+				technologies: make(map[[2]string]*TechnologyStats),
+				policies:     make(map[string]*CachePolicyStats),
+				values:       make(map[string]*CacheControlStats),
+			}
+			byService[row.BackendName] = service
+			services = append(services, service)
+		}
+		service.fingerprint.Hits += row.Hits
+
+		key := [2]string{row.Server, row.PoweredBy}
+		technology := service.technologies[key]
+		if technology == nil {
+			technology = &TechnologyStats{Server: row.Server, PoweredBy: row.PoweredBy}
+			service.technologies[key] = technology
+			service.fingerprint.Technologies = append(service.fingerprint.Technologies, technology)
+		}
+		technology.Hits += row.Hits
+
+		policy := CachePolicy(row.CacheControl)
+		policyStats := service.policies[policy]
+		if policyStats == nil {
+			policyStats = &CachePolicyStats{Policy: policy}
+			service.policies[policy] = policyStats
+			service.fingerprint.Caching = append(service.fingerprint.Caching, policyStats)
+		}
+		policyStats.Hits += row.Hits
+
+		if row.CacheControl != "" {
+			value := service.values[row.CacheControl]
+			if value == nil {
+				value = &CacheControlStats{Value: row.CacheControl}
+				service.values[row.CacheControl] = value
+				service.fingerprint.CacheControl = append(service.fingerprint.CacheControl, value)
+			}
+			value.Hits += row.Hits
+		}
+	}
+
+	fingerprints := make([]*BackendFingerprint, 0, len(services))
+	for _, service := range services {
+		fingerprint := service.fingerprint
+		sort.SliceStable(fingerprint.Technologies, func(i, j int) bool {
+			return fingerprint.Technologies[i].Hits > fingerprint.Technologies[j].Hits
+		})
+		for _, technology := range fingerprint.Technologies {
+			technology.Share = float64(technology.Hits) / float64(fingerprint.Hits) * 100
+		}
+		sort.SliceStable(fingerprint.Caching, func(i, j int) bool {
+			return fingerprint.Caching[i].Hits > fingerprint.Caching[j].Hits
+		})
+		for _, policy := range fingerprint.Caching {
+			policy.Share = float64(policy.Hits) / float64(fingerprint.Hits) * 100
+		}
+		sort.SliceStable(fingerprint.CacheControl, func(i, j int) bool {
+			return fingerprint.CacheControl[i].Hits > fingerprint.CacheControl[j].Hits
+		})
+		if fingerprint.CacheControl == nil {
+			fingerprint.CacheControl = make([]*CacheControlStats, 0)
+		} else if len(fingerprint.CacheControl) > maxCacheControlValues {
+			fingerprint.CacheControl = fingerprint.CacheControl[:maxCacheControlValues]
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	sort.SliceStable(fingerprints, func(i, j int) bool {
+		if fingerprints[i].Hits != fingerprints[j].Hits {
+			return fingerprints[i].Hits > fingerprints[j].Hits
+		}
+		return fingerprints[i].BackendName < fingerprints[j].BackendName
+	})
+	if limit > 0 && len(fingerprints) > limit {
+		fingerprints = fingerprints[:limit]
+	}
+	return fingerprints
+}
+
+// CachePolicy classifies a Cache-Control header value, the most restrictive directive wins
+func CachePolicy(cacheControl string) string {
+	if strings.TrimSpace(cacheControl) == "" {
+		return CachePolicyNone
+	}
+
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(strings.ToLower(cacheControl), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		directives[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	if _, ok := directives["no-store"]; ok {
+		return CachePolicyNoStore
+	}
+	if _, ok := directives["no-cache"]; ok {
+		return CachePolicyNoCache
+	}
+	if maxAge, ok := directives["max-age"]; ok && maxAge == "0" {
+		if _, shared := directives["s-maxage"]; !shared {
+			return CachePolicyNoCache
+		}
+	}
+	if _, ok := directives["private"]; ok {
+		return CachePolicyPrivate
+	}
+	for _, name := range []string{"public", "max-age", "s-maxage", "immutable"} {
+		if _, ok := directives[name]; ok {
+			return CachePolicyPublic
+		}
+	}
+	return CachePolicyUndefined
+}
@@ -0,0 +1,73 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestCachePolicy(t *testing.T) {
+	tests := map[string]string{
+		"":                            repositories.CachePolicyNone,
+		"public, max-age=31536000":    repositories.CachePolicyPublic,
+		"max-age=600":                 repositories.CachePolicyPublic,
+		"private, max-age=60":         repositories.CachePolicyPrivate,
+		"max-age=0, must-revalidate":  repositories.CachePolicyNoCache,
+		"max-age=0, s-maxage=300":     repositories.CachePolicyPublic,
+		"No-Cache":                    repositories.CachePolicyNoCache,
+		"no-cache, no-store, private": repositories.CachePolicyNoStore,
+		"must-revalidate":             repositories.CachePolicyUndefined,
+	}
+	for value, expected := range tests {
+		if policy := repositories.CachePolicy(value); policy != expected {
+			t.Errorf("Expected %q to be %s, got %s", value, expected, policy)
+		}
+	}
+}
+
+func TestBackendFingerprints(t *testing.T) {
+	// shop runs mostly on nginx with cached assets, one PHP instance leaks its version; api has no response headers
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for _, request := range []struct {
+		backend, metadata string
+		count             int
+	}{
+		{"shop@docker", `{"response_headers":{"Server":"nginx","Cache-Control":"public, max-age=3600"}}`, 3},
+		{"shop@docker", `{"response_headers":{"Server":"nginx","X-Powered-By":"PHP/8.2","Cache-Control":"no-store"}}`, 1},
+		{"api@docker", `{"headers":{"X-Tenant-Id":"acme"}}`, 2},
+		{"api@docker", "", 2},
+	} {
+		for i := 0; i < request.count; i++ {
+			requests = append(requests, &models.HTTPRequest{
+				SourceName: "test", Timestamp: now, RequestHash: fmt.Sprint(request, i),
+				ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/", StatusCode: 200,
+				BackendName: request.backend, ProxyMetadata: request.metadata,
+			})
+		}
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	fingerprints, err := statsRepo.GetBackendFingerprints(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fingerprints) != 1 || fingerprints[0].BackendName != "shop@docker" || fingerprints[0].Hits != 4 {
+		t.Fatalf("Expected only shop to be fingerprinted, got %+v", fingerprints)
+	}
+	shop := fingerprints[0]
+	if len(shop.Technologies) != 2 || shop.Technologies[0].Server != "nginx" || shop.Technologies[0].PoweredBy != "" || shop.Technologies[0].Share != 75 ||
+		shop.Technologies[1].PoweredBy != "PHP/8.2" {
+		t.Errorf("Unexpected technologies %+v", shop.Technologies)
+	}
+	if len(shop.Caching) != 2 || shop.Caching[0].Policy != repositories.CachePolicyPublic || shop.Caching[0].Hits != 3 || shop.Caching[1].Policy != repositories.CachePolicyNoStore {
+		t.Errorf("Unexpected caching %+v", shop.Caching)
+	}
+	if len(shop.CacheControl) != 2 || shop.CacheControl[0].Value != "public, max-age=3600" {
+		t.Errorf("Unexpected Cache-Control values %+v", shop.CacheControl)
+	}
+}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/filter"
+)
+
+// Regions of EU statistics and geo timelines
+const (
+	RegionEU      = "eu"
+	RegionNonEU   = "non_eu"
+	RegionUnknown = "unknown" // No GeoIP location, or a country missing from the country table
+)
+
+// EUStats holds requests from inside or outside the European Union
+type EUStats struct {
+	Region         string  `json:"region"` // eu, non_eu or unknown
+	Hits           int64   `json:"hits"`
+	UniqueVisitors int64   `json:"unique_visitors"`
+	Bandwidth      int64   `json:"bandwidth"`
+	Percentage     float64 `json:"percentage"` // Share of all requests in the time range
+}
+
+// GeoTimelineData holds requests per region for one time bucket
+// Regions are continent codes for the continent timeline, eu/non_eu/unknown for the EU timeline.
+type GeoTimelineData struct {
+	Hour     string           `json:"hour"`
+	Requests map[string]int64 `json:"requests"`
+}
+
+// timelineBucket returns the SQL expression grouping timestamps for a timeline over hours
+func timelineBucket(hours int) string {
+	if hours <= 24 {
+		return "strftime('%Y-%m-%d %H:00', timestamp)"
+	} else if hours <= 720 {
+		return "strftime('%Y-%m-%d', timestamp)"
+	}
+	return "strftime('%Y-W%W', timestamp)"
+}
+
+// euRegionExpression classifies a request as eu, non_eu or unknown
+const euRegionExpression = "CASE WHEN geo_country = '' THEN 'unknown' WHEN geo_eu THEN 'eu' ELSE 'non_eu' END"
+
+// GetEUDistribution returns requests from inside and outside the European Union
+// The EU flag comes from the GeoIP database at enrichment time (MaxMind is_in_european_union).
+func (r *statsRepo) GetEUDistribution(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*EUStats, error) {
+	var regions []*EUStats
+
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(euRegionExpression+" as region, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(SUM(response_size), 0) as bandwidth").
+		Where("timestamp > ?", r.getTimeRange())
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	if err := query.Group("region").Order("hits DESC").Scan(&regions).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get EU distribution", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	var total int64
+	for _, region := range regions {
+		total += region.Hits
+	}
+	for _, region := range regions {
+		region.Percentage = float64(region.Hits) * 100 / float64(total)
+	}
+	return regions, nil
+}
+
+// GetEUTimeline returns requests from inside and outside the European Union over time
+func (r *statsRepo) GetEUTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*GeoTimelineData, error) {
+	var rows []*struct {
+		Hour   string
+		Region string
+		Hits   int64
+	}
+	bucket := timelineBucket(hours)
+
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(bucket+" as hour, "+euRegionExpression+" as region, COUNT(*) as hits").
+		Where("timestamp > ?", r.clock.Now().Add(-time.Duration(hours)*time.Hour))
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	if err := query.Group("hour, region").Order("hour").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get EU timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	timeline := make([]*GeoTimelineData, 0)
+	for _, row := range rows {
+		if len(timeline) == 0 || timeline[len(timeline)-1].Hour != row.Hour {
+			timeline = append(timeline, &GeoTimelineData{
+				Hour:     row.Hour,
+				Requests: map[string]int64{RegionEU: 0, RegionNonEU: 0, RegionUnknown: 0},
+			})
+		}
+		timeline[len(timeline)-1].Requests[row.Region] += row.Hits
+	}
+	return timeline, nil
+}
+
+// GetContinentTimeline returns requests per continent over time
+// Requests are grouped by country in SQL and folded into continents with the built-in country table.
+func (r *statsRepo) GetContinentTimeline(hours int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*GeoTimelineData, error) {
+	var rows []*struct {
+		Hour    string
+		Country string
+		Hits    int64
+	}
+	bucket := timelineBucket(hours)
+
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(bucket+" as hour, geo_country as country, COUNT(*) as hits").
+		Where("timestamp > ? AND geo_country != ''", r.clock.Now().Add(-time.Duration(hours)*time.Hour))
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	if err := query.Group("hour, geo_country").Order("hour").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get continent timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	timeline := make([]*GeoTimelineData, 0)
+	for _, row := range rows {
+		if len(timeline) == 0 || timeline[len(timeline)-1].Hour != row.Hour {
+			timeline = append(timeline, &GeoTimelineData{Hour: row.Hour, Requests: make(map[string]int64)})
+		}
+		continent := RegionUnknown
+		if country, ok := enrichment.LookupCountry(row.Country); ok {
+			continent = country.Continent
+		}
+		timeline[len(timeline)-1].Requests[continent] += row.Hits
+	}
+	return timeline, nil
+}
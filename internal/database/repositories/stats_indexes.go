@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lowSelectivityShare flags indexes whose first column value matches at least this share of the indexed rows
+	lowSelectivityShare = 0.1
+	// lowSelectivityMinRows avoids flagging indexes of small tables, where any index is cheap
+	lowSelectivityMinRows = 1000
+)
+
+// queryPlanIndex extracts the index used by a step of EXPLAIN QUERY PLAN
+var queryPlanIndex = regexp.MustCompile(`USING (?:COVERING )?INDEX (\w+)`)
+
+// IndexAdvice reports which http_requests indexes the observed queries use
+type IndexAdvice struct {
+	Profile          IndexProfile `json:"profile"`
+	SuggestedProfile IndexProfile `json:"suggested_profile,omitempty"` // Lowest profile creating every used index (empty without analyzed queries)
+
+	AnalyzedQueries int `json:"analyzed_queries"` // Distinct SELECT statements seen since startup
+	FailedQueries   int `json:"failed_queries"`   // Statements EXPLAIN QUERY PLAN couldn't plan
+
+	// sqlite_stat1 is filled by ANALYZE, selectivity is unknown before the first run
+	StatisticsAvailable bool  `json:"statistics_available"`
+	TableRows           int64 `json:"table_rows"`
+
+	Indexes []*IndexUsage `json:"indexes"`
+}
+
+// IndexUsage is the usage and selectivity of one index
+type IndexUsage struct {
+	Name           string       `json:"name"`
+	Profile        IndexProfile `json:"profile,omitempty"` // Lowest profile creating the index, empty when not managed by profiles
+	Rows           int64        `json:"rows"`              // Indexed rows (sqlite_stat1)
+	RowsPerKey     float64      `json:"rows_per_key"`      // Average rows sharing a value of the first column (sqlite_stat1)
+	QueryPatterns  int          `json:"query_patterns"`    // Analyzed statements whose plan uses the index
+	Unused         bool         `json:"unused"`
+	LowSelectivity bool         `json:"low_selectivity"`
+	Recommendation string       `json:"recommendation"` // keep, review or drop
+	Reason         string       `json:"reason,omitempty"`
+}
+
+// GetIndexAdvice plans the given statements with EXPLAIN QUERY PLAN and combines index usage with sqlite_stat1
+// Statements are the SELECTs recorded by the query stats, so usage only reflects the queries run since startup.
+func (r *statsRepo) GetIndexAdvice(profile IndexProfile, statements []string) (*IndexAdvice, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+	db := r.db.WithContext(ctx)
+
+	if profile == "" {
+		profile = DefaultIndexProfile
+	}
+	advice := &IndexAdvice{
+		Profile: profile,
+		Indexes: make([]*IndexUsage, 0),
+	}
+
+	var names []string
+	err := db.Raw(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'index' AND tbl_name = 'http_requests' AND name NOT LIKE 'sqlite_autoindex_%'
+		ORDER BY name
+	`).Scan(&names).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to list indexes", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	usages := make(map[string]*IndexUsage, len(names))
+	for _, name := range names {
+		usage := &IndexUsage{Name: name}
+		if index, ok := performanceIndex(name); ok {
+			usage.Profile = index.Profile
+		}
+		usages[name] = usage
+		advice.Indexes = append(advice.Indexes, usage)
+	}
+
+	// Selectivity from ANALYZE: "rows rows_per_first_column_value ..."
+	var statTable int64
+	db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1'").Scan(&statTable)
+	if statTable > 0 {
+		var stats []struct {
+			Idx  string
+			Stat string
+		}
+		if err := db.Raw("SELECT COALESCE(idx, '') as idx, stat FROM sqlite_stat1 WHERE tbl = 'http_requests'").Scan(&stats).Error; err != nil {
+			r.logger.WithCaller().Warn("Failed to read index statistics", r.logger.Args("error", err))
+		}
+		for _, stat := range stats {
+			fields := strings.Fields(stat.Stat)
+			if len(fields) == 0 {
+				continue
+			}
+			// Full indexes hold every row, partial indexes less
+			rows, _ := strconv.ParseInt(fields[0], 10, 64)
+			advice.TableRows = max(advice.TableRows, rows)
+			usage, ok := usages[stat.Idx]
+			if !ok {
+				continue
+			}
+			advice.StatisticsAvailable = true
+			usage.Rows = rows
+			if len(fields) > 1 {
+				usage.RowsPerKey, _ = strconv.ParseFloat(fields[1], 64)
+			}
+		}
+	}
+
+	// Index usage of the observed statements
+	for _, statement := range statements {
+		var plan []struct {
+			Detail string
+		}
+		if err := db.Raw("EXPLAIN QUERY PLAN " + statement).Scan(&plan).Error; err != nil {
+			r.logger.Debug("Failed to plan query for the index advisor", r.logger.Args("error", err))
+			advice.FailedQueries++
+			continue
+		}
+		advice.AnalyzedQueries++
+
+		used := make(map[string]bool)
+		for _, step := range plan {
+			for _, match := range queryPlanIndex.FindAllStringSubmatch(step.Detail, -1) {
+				used[match[1]] = true
+			}
+		}
+		for name := range used {
+			if usage, ok := usages[name]; ok {
+				usage.QueryPatterns++
+			}
+		}
+	}
+
+	for _, usage := range advice.Indexes {
+		usage.Unused = advice.AnalyzedQueries > 0 && usage.QueryPatterns == 0
+		usage.LowSelectivity = usage.Rows >= lowSelectivityMinRows && usage.RowsPerKey >= float64(usage.Rows)*lowSelectivityShare
+
+		switch {
+		case usage.Profile == "":
+			// Unique deduplication, slow requests and search indexes are needed whatever the queries
+			usage.Recommendation = "keep"
+			usage.Reason = "not managed by index profiles"
+		case usage.Unused:
+			usage.Recommendation = "drop"
+			usage.Reason = "no observed query uses it"
+		case usage.LowSelectivity:
+			usage.Recommendation = "review"
+			usage.Reason = "first column has few distinct values"
+		default:
+			usage.Recommendation = "keep"
+		}
+
+		if usage.QueryPatterns > 0 && usage.Profile != "" &&
+			(advice.SuggestedProfile == "" || usage.Profile.Includes(advice.SuggestedProfile)) {
+			advice.SuggestedProfile = usage.Profile
+		}
+	}
+	if advice.AnalyzedQueries > 0 && advice.SuggestedProfile == "" {
+		advice.SuggestedProfile = IndexProfileMinimal
+	}
+
+	// Drop candidates first
+	sort.SliceStable(advice.Indexes, func(i, j int) bool {
+		return advice.Indexes[i].Recommendation == "drop" && advice.Indexes[j].Recommendation != "drop"
+	})
+
+	return advice, nil
+}
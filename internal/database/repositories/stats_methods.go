@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// StandardMethods are the methods regular browsers and API clients use, everything else is reported as unusual
+var StandardMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"}
+
+// unusualMethodSourcesLimit is the number of top client IPs listed per unusual method
+const unusualMethodSourcesLimit = 10
+
+// UnusualMethodsReport lists unusual methods (TRACE, PROPFIND, CONNECT...) with their sources and timeline
+type UnusualMethodsReport struct {
+	Methods  []*UnusualMethodStats `json:"methods"`
+	Timeline []*MethodTimelineData `json:"timeline"`
+}
+
+// UnusualMethodStats holds the requests using one unusual method
+type UnusualMethodStats struct {
+	Method     string          `json:"method"`
+	Disallowed bool            `json:"disallowed"` // Listed in DISALLOWED_METHODS
+	Hits       int64           `json:"hits"`
+	UniqueIPs  int64           `json:"unique_ips"`
+	FirstSeen  time.Time       `json:"first_seen"`
+	LastSeen   time.Time       `json:"last_seen"`
+	Sources    []*MethodSource `json:"sources"` // Top client IPs
+}
+
+// MethodSource holds the requests of one client IP using an unusual method
+type MethodSource struct {
+	ClientIP string    `json:"client_ip"`
+	Country  string    `json:"country"`
+	Hits     int64     `json:"hits"`
+	Services int64     `json:"services"` // Distinct services targeted
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// MethodTimelineData holds requests per unusual method for one time bucket
+type MethodTimelineData struct {
+	Hour     string           `json:"hour"`
+	Requests map[string]int64 `json:"requests"`
+}
+
+// GetUnusualMethods reports methods outside StandardMethods, plus the disallowed ones even if standard
+func (r *statsRepo) GetUnusualMethods(disallowed []string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*UnusualMethodsReport, error) {
+	since := r.getTimeRange()
+	isDisallowed := make(map[string]bool, len(disallowed))
+	for _, method := range disallowed {
+		isDisallowed[method] = true
+	}
+
+	// Base query shared by the three aggregations
+	unusual := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since)
+		if len(disallowed) > 0 {
+			query = query.Where("(method NOT IN ? OR method IN ?)", StandardMethods, disallowed)
+		} else {
+			query = query.Where("method NOT IN ?", StandardMethods)
+		}
+		query = r.applyServiceFilters(query, filters)
+		query = r.applyExcludeOwnIP(query, excludeIP)
+		return query
+	}
+
+	report := &UnusualMethodsReport{Methods: make([]*UnusualMethodStats, 0), Timeline: make([]*MethodTimelineData, 0)}
+
+	var methods []*struct {
+		Method    string
+		Hits      int64
+		UniqueIPs int64
+		FirstSeen string
+		LastSeen  string
+	}
+	err := unusual().
+		Select("method, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_ips, MIN(timestamp) as first_seen, MAX(timestamp) as last_seen").
+		Group("method").Order("hits DESC").
+		Scan(&methods).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get unusual methods", r.logger.Args("error", err))
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return report, nil
+	}
+
+	byMethod := make(map[string]*UnusualMethodStats, len(methods))
+	for _, method := range methods {
+		stats := &UnusualMethodStats{
+			Method:     method.Method,
+			Disallowed: isDisallowed[method.Method],
+			Hits:       method.Hits,
+			UniqueIPs:  method.UniqueIPs,
+			FirstSeen:  parseSQLiteTime(method.FirstSeen),
+			LastSeen:   parseSQLiteTime(method.LastSeen),
+			Sources:    make([]*MethodSource, 0),
+		}
+		byMethod[method.Method] = stats
+		report.Methods = append(report.Methods, stats)
+	}
+
+	// Top client IPs of every method in one query
+	var sources []*struct {
+		Method   string
+		ClientIP string
+		Country  string
+		Hits     int64
+		Services int64
+		LastSeen string
+	}
+	ranked := unusual().
+		Select("method, client_ip, MAX(geo_country) as country, COUNT(*) as hits, " +
+			"COUNT(DISTINCT COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)) as services, MAX(timestamp) as last_seen, " +
+			"ROW_NUMBER() OVER (PARTITION BY method ORDER BY COUNT(*) DESC) as source_rank").
+		Group("method, client_ip")
+	err = r.db.Table("(?) as ranked", ranked).
+		Select("method, client_ip, country, hits, services, last_seen").
+		Where("source_rank <= ?", unusualMethodSourcesLimit).
+		Order("method, hits DESC").
+		Scan(&sources).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get unusual method sources", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, source := range sources {
+		if stats := byMethod[source.Method]; stats != nil {
+			stats.Sources = append(stats.Sources, &MethodSource{
+				ClientIP: source.ClientIP,
+				Country:  source.Country,
+				Hits:     source.Hits,
+				Services: source.Services,
+				LastSeen: parseSQLiteTime(source.LastSeen),
+			})
+		}
+	}
+
+	var timeline []*struct {
+		Hour   string
+		Method string
+		Hits   int64
+	}
+	bucket := timelineBucket(r.LookbackHours())
+	err = unusual().
+		Select(bucket + " as hour, method, COUNT(*) as hits").
+		Group("hour, method").Order("hour").
+		Scan(&timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get unusual method timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, row := range timeline {
+		if len(report.Timeline) == 0 || report.Timeline[len(report.Timeline)-1].Hour != row.Hour {
+			report.Timeline = append(report.Timeline, &MethodTimelineData{Hour: row.Hour, Requests: make(map[string]int64)})
+		}
+		report.Timeline[len(report.Timeline)-1].Requests[row.Method] = row.Hits
+	}
+
+	return report, nil
+}
+
+// parseSQLiteTime parses a timestamp returned as text by SQLite aggregates (MIN/MAX), zero if unparseable
+func parseSQLiteTime(value string) time.Time {
+	formats := []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// maxNotFoundReferrers caps the referrers listed per missing path
+const maxNotFoundReferrers = 5
+
+// NotFoundReport lists the paths answered with 404 and the pages linking to them
+// Paths with an internal referrer are broken links of the site itself, the others come from outside links or scans.
+type NotFoundReport struct {
+	Total       int64           `json:"total"`        // 404 responses
+	UniquePaths int64           `json:"unique_paths"` // Distinct paths, the unique_404 of the summary
+	Paths       []*NotFoundPath `json:"paths" gorm:"-"`
+}
+
+// NotFoundPath holds the 404 responses of one path
+type NotFoundPath struct {
+	Path           string              `json:"path"`
+	Hits           int64               `json:"hits"`
+	UniqueVisitors int64               `json:"unique_visitors"`
+	FirstSeen      time.Time           `json:"first_seen"`
+	LastSeen       time.Time           `json:"last_seen"`
+	Referrers      []*NotFoundReferrer `json:"referrers"` // Most hits first, at most maxNotFoundReferrers
+}
+
+// NotFoundReferrer is a page linking to a missing path
+type NotFoundReferrer struct {
+	Referrer string `json:"referrer"`
+	Hits     int64  `json:"hits"`
+	Internal bool   `json:"internal"` // The referrer is on the host the path was requested from
+}
+
+// notFoundPathRow holds NotFoundPath columns as scanned, SQLite returns MIN/MAX(timestamp) as text
+type notFoundPathRow struct {
+	Path           string
+	Hits           int64
+	UniqueVisitors int64
+	FirstSeen      string
+	LastSeen       string
+}
+
+// notFoundReferrerRow holds the 404 responses of one path from one referrer on one host
+type notFoundReferrerRow struct {
+	Path     string
+	Referrer string
+	Host     string
+	Hits     int64
+}
+
+// GetNotFoundReport returns the limit paths with the most 404 responses, with their referrers
+func (r *statsRepo) GetNotFoundReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*NotFoundReport, error) {
+	since := r.getTimeRange()
+
+	notFound := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ? AND status_code = 404", since)
+		query = r.applyServiceFilters(query, filters)
+		return r.applyExcludeOwnIP(query, excludeIP)
+	}
+
+	report := &NotFoundReport{Paths: make([]*NotFoundPath, 0)}
+	if err := notFound().Select("COUNT(*) as total, COUNT(DISTINCT path) as unique_paths").Scan(report).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to count 404 responses", r.logger.Args("error", err))
+		return nil, err
+	}
+	if report.Total == 0 {
+		return report, nil
+	}
+
+	var rows []*notFoundPathRow
+	err := notFound().
+		Select("path, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, MIN(timestamp) as first_seen, MAX(timestamp) as last_seen").
+		Group("path").
+		Order("hits DESC, path").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get 404 paths", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	byPath := make(map[string]*NotFoundPath, len(rows))
+	paths := make([]string, 0, len(rows))
+	for _, row := range rows {
+		path := &NotFoundPath{
+			Path:           row.Path,
+			Hits:           row.Hits,
+			UniqueVisitors: row.UniqueVisitors,
+			FirstSeen:      parseSQLiteTime(row.FirstSeen),
+			LastSeen:       parseSQLiteTime(row.LastSeen),
+			Referrers:      make([]*NotFoundReferrer, 0),
+		}
+		byPath[row.Path] = path
+		paths = append(paths, row.Path)
+		report.Paths = append(report.Paths, path)
+	}
+
+	var referrerRows []*notFoundReferrerRow
+	err = notFound().
+		Select("path, referer as referrer, host, COUNT(*) as hits").
+		Where("path IN ? AND referer != '' AND referer != '-'", paths).
+		Group("path, referer, host").
+		Order("hits DESC, referrer").
+		Scan(&referrerRows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get 404 referrers", r.logger.Args("error", err))
+		return nil, err
+	}
+	addNotFoundReferrers(byPath, referrerRows)
+
+	return report, nil
+}
+
+// addNotFoundReferrers adds the referrers of each path, merging the hosts a referrer linked to the path on
+func addNotFoundReferrers(byPath map[string]*NotFoundPath, rows []*notFoundReferrerRow) {
+	seen := make(map[[2]string]*NotFoundReferrer)
+	for _, row := range rows {
+		path := byPath[row.Path]
+		if path == nil {
+			continue
+		}
+		key := [2]string{row.Path, row.Referrer}
+		referrer := seen[key]
+		if referrer == nil {
+			referrer = &NotFoundReferrer{Referrer: row.Referrer}
+			seen[key] = referrer
+			path.Referrers = append(path.Referrers, referrer)
+		}
+		referrer.Hits += row.Hits
+		if parsed, err := url.Parse(row.Referrer); err == nil && strings.EqualFold(parsed.Hostname(), hostWithoutPort(row.Host)) {
+			referrer.Internal = true
+		}
+	}
+
+	for _, path := range byPath {
+		referrers := path.Referrers
+		sort.SliceStable(referrers, func(i, j int) bool { return referrers[i].Hits > referrers[j].Hits })
+		if len(path.Referrers) > maxNotFoundReferrers {
+			path.Referrers = path.Referrers[:maxNotFoundReferrers]
+		}
+	}
+}
+
+// hostWithoutPort returns the host of a Host header value
+func hostWithoutPort(host string) string {
+	if parsed, err := url.Parse("//" + host); err == nil {
+		return parsed.Hostname()
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestNotFoundReport(t *testing.T) {
+	// /old-page is linked from the blog (3 hits) and a forum (1 hit), /wp-login.php is scanned without referrer
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for i, request := range []struct {
+		path, referrer, clientIP string
+		status                   int
+	}{
+		{"/old-page", "https://example.com:443/blog", "10.0.0.1", 404},
+		{"/old-page", "https://example.com/blog", "10.0.0.2", 404},
+		{"/old-page", "https://example.com/blog", "10.0.0.3", 404},
+		{"/old-page", "https://forum.example.org/t/1", "10.0.0.4", 404},
+		{"/wp-login.php", "", "203.0.113.7", 404},
+		{"/wp-login.php", "-", "203.0.113.7", 404},
+		{"/", "https://example.com/blog", "10.0.0.1", 200},
+	} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: request.clientIP, Method: "GET", Host: "example.com", Path: request.path, StatusCode: request.status,
+			Referer: request.referrer,
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	report, err := statsRepo.GetNotFoundReport(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 6 || report.UniquePaths != 2 || len(report.Paths) != 2 {
+		t.Fatalf("Expected 6 404 responses on 2 paths, got %+v", report)
+	}
+
+	oldPage := report.Paths[0]
+	if oldPage.Path != "/old-page" || oldPage.Hits != 4 || oldPage.UniqueVisitors != 4 || !oldPage.FirstSeen.Before(oldPage.LastSeen) {
+		t.Errorf("Unexpected /old-page stats %+v", oldPage)
+	}
+	if len(oldPage.Referrers) != 3 {
+		t.Fatalf("Expected 3 referrers of /old-page, got %d", len(oldPage.Referrers))
+	}
+	if blog := oldPage.Referrers[0]; blog.Referrer != "https://example.com/blog" || blog.Hits != 2 || !blog.Internal {
+		t.Errorf("Expected the internal blog link first, got %+v", blog)
+	}
+	for _, referrer := range oldPage.Referrers[1:] {
+		if referrer.Hits != 1 || referrer.Internal != (referrer.Referrer == "https://example.com:443/blog") {
+			t.Errorf("Unexpected referrer %+v", referrer)
+		}
+	}
+
+	if scanned := report.Paths[1]; scanned.Path != "/wp-login.php" || len(scanned.Referrers) != 0 {
+		t.Errorf("Expected /wp-login.php without referrers, got %+v", scanned)
+	}
+}
@@ -0,0 +1,203 @@
+package repositories
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sort keys of top-N results
+const (
+	SortByHits            = "hits"
+	SortByBandwidth       = "bandwidth"
+	SortByErrorCount      = "error_count"
+	SortByAvgResponseTime = "avg_response_time"
+)
+
+// TopSortKeys lists the accepted sort keys, hits first (the default)
+var TopSortKeys = []string{SortByHits, SortByBandwidth, SortByErrorCount, SortByAvgResponseTime}
+
+// TopPage selects a page of top-N results and their order
+// The zero value is the first page sorted by hits, descending, the order of top-N results without a page.
+type TopPage struct {
+	Offset    int
+	SortBy    string // One of TopSortKeys, "" = hits
+	Ascending bool
+}
+
+// IsDefault reports whether the page is the first one in the default order
+func (p TopPage) IsDefault() bool {
+	return p.Offset == 0 && (p.SortBy == "" || p.SortBy == SortByHits) && !p.Ascending
+}
+
+// TopFilter keeps the top-N rows matching thresholds, e.g. the 5xx paths under /api with at least 100 hits
+// StatusClass and PathPrefix select the requests that are counted, MinHits the rows that are returned.
+type TopFilter struct {
+	MinHits     int64
+	StatusClass int    // First digit of the status codes (1-5), 0 = all
+	PathPrefix  string // Paths starting with the prefix, matched literally
+}
+
+// IsZero reports whether the filter keeps every row
+func (f TopFilter) IsZero() bool {
+	return f.MinHits <= 0 && f.StatusClass == 0 && f.PathPrefix == ""
+}
+
+// WithTopPage returns a repository sharing the same connection whose top-N queries return page
+// Used by the top endpoints for offset, sort_by and sort_dir. Approximate top-K queries run exactly for pages
+// other than the default one: sampled rankings are only reliable for the head of the list by hits.
+func (r *statsRepo) WithTopPage(page TopPage) StatsRepository {
+	return &statsRepo{
+		db:            r.db,
+		logger:        r.logger,
+		lookbackHours: r.lookbackHours,
+		statusPolicy:  r.statusPolicy,
+		slowPolicy:    r.slowPolicy,
+		ctx:           r.ctx,
+		page:          page,
+		topFilter:     r.topFilter,
+		clock:         r.clock,
+	}
+}
+
+// WithTopFilter returns a repository sharing the same connection whose top-N queries only return rows matching filter
+// Like pages, filtered top-K queries are never sampled.
+func (r *statsRepo) WithTopFilter(filter TopFilter) StatsRepository {
+	return &statsRepo{
+		db:            r.db,
+		logger:        r.logger,
+		lookbackHours: r.lookbackHours,
+		statusPolicy:  r.statusPolicy,
+		slowPolicy:    r.slowPolicy,
+		ctx:           r.ctx,
+		page:          r.page,
+		topFilter:     filter,
+		clock:         r.clock,
+	}
+}
+
+// exactTop reports whether top-N queries must count every row: pages and filters other than the default
+func (r *statsRepo) exactTop() bool {
+	return !r.page.IsDefault() || !r.topFilter.IsZero()
+}
+
+// filterTopRequests restricts a top-N query to the requests of the status class and path prefix of the filter
+func (r *statsRepo) filterTopRequests(query *gorm.DB) *gorm.DB {
+	if class := r.topFilter.StatusClass; class > 0 {
+		query = query.Where("status_code >= ? AND status_code < ?", class*100, (class+1)*100)
+	}
+	if r.topFilter.PathPrefix != "" {
+		query = query.Where(`path LIKE ? ESCAPE '\'`, escapeLike(r.topFilter.PathPrefix)+"%")
+	}
+	return query
+}
+
+// sortExpression returns the aggregate of a sort key over the grouped requests
+// Errors are failures of the status policy, average response times include requests without timing (0 ms)
+// like the avg_response_time columns.
+func (r *statsRepo) sortExpression(sortBy string) (string, []interface{}) {
+	switch sortBy {
+	case SortByBandwidth:
+		return "COALESCE(SUM(response_size), 0)", nil
+	case SortByErrorCount:
+		failureCond, failureArgs := r.statusPolicy.FailureCondition()
+		return "COUNT(CASE WHEN " + failureCond + " THEN 1 END)", failureArgs
+	case SortByAvgResponseTime:
+		return "COALESCE(AVG(response_time_ms), 0)", nil
+	default:
+		return "COUNT(*)", nil
+	}
+}
+
+// applyTop filters a grouped query by the top-N filter, orders it by the sort key of the page and selects the page
+// of limit rows (0 = all rows)
+// Rows with the same value are ordered by hits, then by key, so consecutive pages neither repeat nor skip rows.
+func (r *statsRepo) applyTop(query *gorm.DB, key string, limit int) *gorm.DB {
+	query = r.filterTopRequests(query)
+	if r.topFilter.MinHits > 0 {
+		query = query.Having("COUNT(*) >= ?", r.topFilter.MinHits)
+	}
+
+	direction := "DESC"
+	if r.page.Ascending {
+		direction = "ASC"
+	}
+	expression, args := r.sortExpression(r.page.SortBy)
+	query = query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:  expression + " " + direction + ", COUNT(*) DESC, " + key + " ASC",
+		Vars: args,
+	}})
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if r.page.Offset > 0 {
+		query = query.Offset(r.page.Offset)
+	}
+	return query
+}
+
+// topRanking holds the aggregates of a top-N row computed in Go, for filtering, ordering and paging like applyTop
+type topRanking struct {
+	Hits             int64
+	Bandwidth        int64
+	ErrorCount       int64
+	ResponseTimeSum  float64
+	ResponseTimeRows int64
+}
+
+// value returns the aggregate of a sort key
+func (t *topRanking) value(sortBy string) float64 {
+	switch sortBy {
+	case SortByBandwidth:
+		return float64(t.Bandwidth)
+	case SortByErrorCount:
+		return float64(t.ErrorCount)
+	case SortByAvgResponseTime:
+		if t.ResponseTimeRows == 0 {
+			return 0
+		}
+		return t.ResponseTimeSum / float64(t.ResponseTimeRows)
+	default:
+		return float64(t.Hits)
+	}
+}
+
+// pageTop drops rows aggregated in Go below the minimum hits, sorts them by the page's sort key and returns the page of
+// limit rows (0 = all rows)
+// ranking returns the aggregates of a row and key its tie-breaker, as applyTop does in SQL.
+func pageTop[T any](rows []T, page TopPage, minHits int64, limit int, ranking func(T) *topRanking, key func(T) string) []T {
+	if minHits > 0 {
+		kept := rows[:0]
+		for _, row := range rows {
+			if ranking(row).Hits >= minHits {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := ranking(rows[i]), ranking(rows[j])
+		if va, vb := a.value(page.SortBy), b.value(page.SortBy); va != vb {
+			if page.Ascending {
+				return va < vb
+			}
+			return va > vb
+		}
+		if a.Hits != b.Hits {
+			return a.Hits > b.Hits
+		}
+		return key(rows[i]) < key(rows[j])
+	})
+
+	if page.Offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[page.Offset:]
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows
+}
@@ -0,0 +1,145 @@
+package repositories_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestTopPage(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// /a: most hits, /b: most bandwidth and errors, /c: slowest, /d ties with /c on hits
+	requests := []struct {
+		path    string
+		referer string
+		status  int
+		size    int64
+		timeMs  float64
+		count   int
+	}{
+		{"/a", "https://a.example/x", 200, 100, 10, 5},
+		{"/b", "https://b.example/x", 500, 5000, 20, 3},
+		{"/c", "https://c.example/x", 200, 100, 900, 2},
+		{"/d", "https://a.example/y", 404, 100, 30, 2},
+	}
+	now := time.Now()
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(request.path, i),
+				ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: request.path, Referer: request.referer,
+				StatusCode: request.status, ResponseSize: request.size, ResponseTimeMs: request.timeMs,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		page     repositories.TopPage
+		limit    int
+		expected string
+	}{
+		{"default order", repositories.TopPage{}, 10, "/a /b /c /d"},
+		{"second page", repositories.TopPage{Offset: 1}, 2, "/b /c"},
+		{"past the end", repositories.TopPage{Offset: 10}, 2, ""},
+		{"bandwidth", repositories.TopPage{SortBy: repositories.SortByBandwidth}, 10, "/b /a /c /d"},
+		{"errors", repositories.TopPage{SortBy: repositories.SortByErrorCount}, 2, "/b /d"},
+		{"fastest first", repositories.TopPage{SortBy: repositories.SortByAvgResponseTime, Ascending: true}, 10, "/a /b /d /c"},
+		{"fewest hits, ties by path", repositories.TopPage{Ascending: true}, 10, "/c /d /b /a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := statsRepo.WithTopPage(tt.page).GetTopPaths(tt.limit, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := joinPaths(paths); actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// Shares are of all 12 hits, whatever the page
+	paths, err := statsRepo.WithTopPage(repositories.TopPage{Offset: 1}).GetTopPaths(1, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0].Path != "/b" || paths[0].Share != 25 {
+		t.Errorf("Expected /b with a 25%% share on the second page, got %+v", paths)
+	}
+	ips, err := statsRepo.GetTopIPAddresses(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || ips[0].Share != 100 {
+		t.Errorf("Expected one IP with all hits, got %+v", ips)
+	}
+
+	filterTests := []struct {
+		name     string
+		filter   repositories.TopFilter
+		expected string
+	}{
+		{"min hits", repositories.TopFilter{MinHits: 3}, "/a /b"},
+		{"5xx", repositories.TopFilter{StatusClass: 5}, "/b"},
+		{"4xx with enough hits", repositories.TopFilter{StatusClass: 4, MinHits: 3}, ""},
+		{"path prefix", repositories.TopFilter{PathPrefix: "/c"}, "/c"},
+		{"literal prefix", repositories.TopFilter{PathPrefix: "/_"}, ""},
+	}
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := statsRepo.WithTopFilter(tt.filter).GetTopPaths(10, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := joinPaths(paths); actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+
+	// Referrer domains are aggregated in Go: a.example has the most hits (7) but b.example the most errors
+	domains, err := statsRepo.WithTopPage(repositories.TopPage{SortBy: repositories.SortByErrorCount}).GetTopReferrerDomains(2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 2 || domains[0].Domain != "b.example" || domains[1].Domain != "a.example" || domains[1].Hits != 7 {
+		t.Errorf("Unexpected referrer domains by errors: %+v %+v", domains[0], domains[1])
+	}
+	domains, err = statsRepo.WithTopPage(repositories.TopPage{Offset: 2}).GetTopReferrerDomains(2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0].Domain != "c.example" {
+		t.Errorf("Expected the last referrer domain on the second page, got %d domains", len(domains))
+	}
+	domains, err = statsRepo.WithTopFilter(repositories.TopFilter{MinHits: 3, StatusClass: 2}).GetTopReferrerDomains(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0].Domain != "a.example" || domains[0].Hits != 5 {
+		t.Errorf("Expected a.example with its 5 successful hits, got %d domains", len(domains))
+	}
+}
+
+// joinPaths returns the paths of top path rows separated by spaces
+func joinPaths(paths []*repositories.PathStats) string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = path.Path
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// PreflightReport summarizes OPTIONS requests, mostly CORS preflights sent by browsers before cross-origin calls
+// Failed preflights, and preflights never followed by the actual request, point at a CORS misconfiguration;
+// many preflights per request point at responses without Access-Control-Max-Age (a preflight storm).
+type PreflightReport struct {
+	Preflights     int64              `json:"preflights"` // OPTIONS requests
+	Share          float64            `json:"share"`      // Percentage of all requests
+	Failed         int64              `json:"failed"`     // Answered with a 4xx/5xx status
+	Followed       int64              `json:"followed"`   // Followed by a request of the same client to the same host and path
+	OriginCaptured bool               `json:"origin_captured" gorm:"-"`
+	Origins        []*PreflightOrigin `json:"origins" gorm:"-"` // Empty unless CAPTURE_HEADERS includes Origin
+	Paths          []*PreflightPath   `json:"paths" gorm:"-"`   // Most preflights first
+	ByStatus       []*StatusCodeStats `json:"by_status" gorm:"-"`
+}
+
+// PreflightOrigin holds the preflights sent from one origin
+type PreflightOrigin struct {
+	Origin     string `json:"origin"`
+	Preflights int64  `json:"preflights"`
+	Failed     int64  `json:"failed"`
+	Followed   int64  `json:"followed"`
+}
+
+// PreflightPath holds the preflights of one host and path
+type PreflightPath struct {
+	Host                 string  `json:"host"`
+	Path                 string  `json:"path"`
+	Preflights           int64   `json:"preflights"`
+	Failed               int64   `json:"failed"`
+	Followed             int64   `json:"followed"`
+	Requests             int64   `json:"requests"`               // Other requests to the host and path
+	PreflightsPerRequest float64 `json:"preflights_per_request"` // 0 when there was no other request
+}
+
+// preflightFollowed is the condition of a preflight followed by the actual request in the time range
+const preflightFollowed = `EXISTS (SELECT 1 FROM http_requests actual WHERE actual.client_ip = http_requests.client_ip
+	AND actual.host = http_requests.host AND actual.path = http_requests.path AND actual.method != 'OPTIONS'
+	AND actual.timestamp > ?)`
+
+// GetPreflightReport returns the OPTIONS volume with the limit origins and paths with the most preflights
+func (r *statsRepo) GetPreflightReport(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*PreflightReport, error) {
+	since := r.getTimeRange()
+
+	requests := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since)
+		query = r.applyServiceFilters(query, filters)
+		return r.applyExcludeOwnIP(query, excludeIP)
+	}
+	preflights := func() *gorm.DB {
+		return requests().Where("method = 'OPTIONS'")
+	}
+	counts := `COUNT(*) as preflights, COUNT(CASE WHEN status_code >= 400 THEN 1 END) as failed,
+		COUNT(CASE WHEN ` + preflightFollowed + ` THEN 1 END) as followed`
+
+	var total int64
+	if err := requests().Count(&total).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to count requests", r.logger.Args("error", err))
+		return nil, err
+	}
+	report := &PreflightReport{
+		Origins:  make([]*PreflightOrigin, 0),
+		Paths:    make([]*PreflightPath, 0),
+		ByStatus: make([]*StatusCodeStats, 0),
+	}
+	if err := preflights().Select(counts, since).Scan(report).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to count preflights", r.logger.Args("error", err))
+		return nil, err
+	}
+	if report.Preflights == 0 {
+		return report, nil
+	}
+	report.Share = float64(report.Preflights) * 100 / float64(total)
+
+	if err := preflights().Select("status_code, COUNT(*) as count").Group("status_code").Order("count DESC").Scan(&report.ByStatus).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get preflight status codes", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	origin := headerExpression("Origin")
+	err := preflights().
+		Select(origin+" as origin, "+counts, since).
+		Where(origin + " IS NOT NULL").
+		Group("origin").
+		Order("preflights DESC, origin").
+		Limit(limit).
+		Scan(&report.Origins).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get preflight origins", r.logger.Args("error", err))
+		return nil, err
+	}
+	report.OriginCaptured = len(report.Origins) > 0
+
+	err = preflights().
+		Select("host, path, "+counts, since).
+		Group("host, path").
+		Order("preflights DESC, host, path").
+		Limit(limit).
+		Scan(&report.Paths).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get preflight paths", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	// Requests following the preflights, to tell a preflight per request from cached preflights
+	hosts := make([]string, 0, len(report.Paths))
+	paths := make([]string, 0, len(report.Paths))
+	for _, path := range report.Paths {
+		hosts = append(hosts, path.Host)
+		paths = append(paths, path.Path)
+	}
+	var actual []*struct {
+		Host     string
+		Path     string
+		Requests int64
+	}
+	err = requests().
+		Select("host, path, COUNT(*) as requests").
+		Where("method != 'OPTIONS' AND host IN ? AND path IN ?", hosts, paths).
+		Group("host, path").
+		Scan(&actual).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to count requests after preflights", r.logger.Args("error", err))
+		return nil, err
+	}
+	requestCounts := make(map[[2]string]int64, len(actual))
+	for _, row := range actual {
+		requestCounts[[2]string{row.Host, row.Path}] = row.Requests
+	}
+	for _, path := range report.Paths {
+		path.Requests = requestCounts[[2]string{path.Host, path.Path}]
+		if path.Requests > 0 {
+			path.PreflightsPerRequest = float64(path.Preflights) / float64(path.Requests)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,68 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestPreflightReport(t *testing.T) {
+	// /api/orders is preflighted twice from app.example.com and called once, /api/admin rejects its preflight
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for i, request := range []struct {
+		method, path, origin, clientIP string
+		status                         int
+	}{
+		{"OPTIONS", "/api/orders", "https://app.example.com", "10.0.0.1", 204},
+		{"OPTIONS", "/api/orders", "https://app.example.com", "10.0.0.1", 204},
+		{"POST", "/api/orders", "https://app.example.com", "10.0.0.1", 201},
+		{"OPTIONS", "/api/admin", "https://evil.example.net", "203.0.113.7", 403},
+		{"GET", "/", "", "10.0.0.2", 200},
+	} {
+		metadata := ""
+		if request.origin != "" {
+			metadata = `{"headers":{"Origin":"` + request.origin + `"}}`
+		}
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: request.clientIP, Method: request.method, Host: "api.example.com", Path: request.path,
+			StatusCode: request.status, ProxyMetadata: metadata,
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	report, err := statsRepo.GetPreflightReport(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Preflights != 3 || report.Share != 60 || report.Failed != 1 || report.Followed != 2 {
+		t.Errorf("Unexpected totals %+v", report)
+	}
+	if len(report.ByStatus) != 2 || report.ByStatus[0].StatusCode != 204 || report.ByStatus[0].Count != 2 {
+		t.Errorf("Unexpected status codes %+v", report.ByStatus)
+	}
+
+	if !report.OriginCaptured || len(report.Origins) != 2 {
+		t.Fatalf("Expected 2 origins, got %+v", report.Origins)
+	}
+	if app := report.Origins[0]; app.Origin != "https://app.example.com" || app.Preflights != 2 || app.Followed != 2 || app.Failed != 0 {
+		t.Errorf("Unexpected app origin %+v", app)
+	}
+	if evil := report.Origins[1]; evil.Preflights != 1 || evil.Failed != 1 || evil.Followed != 0 {
+		t.Errorf("Unexpected rejected origin %+v", evil)
+	}
+
+	if len(report.Paths) != 2 {
+		t.Fatalf("Expected 2 paths, got %+v", report.Paths)
+	}
+	if orders := report.Paths[0]; orders.Path != "/api/orders" || orders.Requests != 1 || orders.PreflightsPerRequest != 2 {
+		t.Errorf("Unexpected /api/orders stats %+v", orders)
+	}
+	if admin := report.Paths[1]; admin.Path != "/api/admin" || admin.Requests != 0 || admin.PreflightsPerRequest != 0 {
+		t.Errorf("Unexpected /api/admin stats %+v", admin)
+	}
+}
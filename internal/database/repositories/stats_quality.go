@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// dataQualityFields are the columns checked by the data quality report, with the condition of a missing value
+// A field missing from every row of a source usually means its log format (or proxy configuration) drops it:
+// response times aren't in CLF, backends only in Traefik logs, countries need GeoIP and public addresses.
+var dataQualityFields = []struct {
+	Name    string
+	Missing string
+}{
+	{"host", "COALESCE(host, '') = ''"},
+	{"response_time_ms", "COALESCE(response_time_ms, 0) = 0"},
+	{"geo_country", "COALESCE(geo_country, '') = ''"},
+	{"backend_name", "COALESCE(backend_name, '') = ''"},
+}
+
+// DataQualityReport holds the share of requests missing key fields, in total, per source and over time
+type DataQualityReport struct {
+	Total    *DataQualityStats          `json:"total"`
+	Sources  []*DataQualityStats        `json:"sources"`
+	Timeline []*DataQualityTimelineData `json:"timeline"` // Per source and time bucket
+}
+
+// DataQualityStats holds the missing fields of a set of requests
+type DataQualityStats struct {
+	SourceName string          `json:"source_name,omitempty"`
+	Requests   int64           `json:"requests"`
+	Fields     []*FieldQuality `json:"fields"` // In the order of dataQualityFields
+}
+
+// FieldQuality holds how many requests miss one field
+type FieldQuality struct {
+	Field          string  `json:"field"`
+	Missing        int64   `json:"missing"`
+	MissingPercent float64 `json:"missing_percent"`
+}
+
+// DataQualityTimelineData holds the missing fields of one source in one time bucket
+type DataQualityTimelineData struct {
+	Hour string `json:"hour"`
+	DataQualityStats
+}
+
+// dataQualityRow is a row of the data quality aggregates, missing_<field> columns follow dataQualityFields
+type dataQualityRow struct {
+	Hour                  string
+	SourceName            string
+	Requests              int64
+	MissingHost           int64
+	MissingResponseTimeMs int64
+	MissingGeoCountry     int64
+	MissingBackendName    int64
+}
+
+// dataQualityAggregates counts the requests and, per field of dataQualityFields, those missing it
+func dataQualityAggregates() string {
+	columns := []string{"COUNT(*) as requests"}
+	for _, field := range dataQualityFields {
+		columns = append(columns, "SUM(CASE WHEN "+field.Missing+" THEN 1 ELSE 0 END) as missing_"+field.Name)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// stats converts the row to stats in the order of dataQualityFields
+func (row *dataQualityRow) stats() DataQualityStats {
+	missing := []int64{row.MissingHost, row.MissingResponseTimeMs, row.MissingGeoCountry, row.MissingBackendName}
+	stats := DataQualityStats{SourceName: row.SourceName, Requests: row.Requests, Fields: make([]*FieldQuality, len(dataQualityFields))}
+	for i, field := range dataQualityFields {
+		quality := &FieldQuality{Field: field.Name, Missing: missing[i]}
+		if row.Requests > 0 {
+			quality.MissingPercent = float64(missing[i]) / float64(row.Requests) * 100
+		}
+		stats.Fields[i] = quality
+	}
+	return stats
+}
+
+// GetDataQuality returns the share of requests missing key fields (dataQualityFields) in the time range,
+// in total, per source and per source over time, to spot a log format dropping information
+func (r *statsRepo) GetDataQuality(filters []filter.Service) (*DataQualityReport, error) {
+	since := r.getTimeRange()
+	requests := func() *gorm.DB {
+		return r.applyServiceFilters(r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since), filters)
+	}
+
+	var total dataQualityRow
+	if err := requests().Select(dataQualityAggregates()).Scan(&total).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get data quality", r.logger.Args("error", err))
+		return nil, err
+	}
+	totalStats := total.stats()
+	report := &DataQualityReport{
+		Total:    &totalStats,
+		Sources:  make([]*DataQualityStats, 0),
+		Timeline: make([]*DataQualityTimelineData, 0),
+	}
+	if total.Requests == 0 {
+		return report, nil
+	}
+
+	var sources []dataQualityRow
+	err := requests().
+		Select("source_name, " + dataQualityAggregates()).
+		Group("source_name").
+		Order("requests DESC, source_name").
+		Scan(&sources).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get data quality per source", r.logger.Args("error", err))
+		return nil, err
+	}
+	for i := range sources {
+		stats := sources[i].stats()
+		report.Sources = append(report.Sources, &stats)
+	}
+
+	var timeline []dataQualityRow
+	err = requests().
+		Select(timelineBucket(r.LookbackHours()) + " as hour, source_name, " + dataQualityAggregates()).
+		Group("hour, source_name").
+		Order("hour, source_name").
+		Scan(&timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get data quality timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+	for i := range timeline {
+		report.Timeline = append(report.Timeline, &DataQualityTimelineData{Hour: timeline[i].Hour, DataQualityStats: timeline[i].stats()})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,70 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestDataQuality(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// A Traefik JSON source with every field, a CLF source without response times and backends
+	now := time.Now().UTC()
+	requests := []models.HTTPRequest{
+		{SourceName: "traefik", Host: "example.com", ResponseTimeMs: 12, GeoCountry: "DE", BackendName: "web@docker"},
+		{SourceName: "traefik", Host: "example.com", ResponseTimeMs: 8, GeoCountry: "", BackendName: "web@docker"},
+		{SourceName: "clf", Host: "example.com", GeoCountry: "US"},
+		{SourceName: "clf", Host: "", GeoCountry: "US"},
+	}
+	for i, request := range requests {
+		request.Timestamp, request.RequestHash = now, fmt.Sprint(i)
+		request.ClientIP, request.Method, request.Path, request.StatusCode = "203.0.113.7", "GET", "/", 200
+		if err := db.Create(&request).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	report, err := statsRepo.GetDataQuality(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := func(stats *repositories.DataQualityStats) map[string]float64 {
+		percents := make(map[string]float64)
+		for _, field := range stats.Fields {
+			percents[field.Field] = field.MissingPercent
+		}
+		return percents
+	}
+
+	if total := missing(report.Total); report.Total.Requests != 4 || total["host"] != 25 || total["response_time_ms"] != 50 || total["geo_country"] != 25 || total["backend_name"] != 50 {
+		t.Errorf("Unexpected totals %v", total)
+	}
+	if len(report.Sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(report.Sources))
+	}
+	for _, source := range report.Sources {
+		percents := missing(source)
+		switch source.SourceName {
+		case "clf":
+			if percents["response_time_ms"] != 100 || percents["backend_name"] != 100 || percents["host"] != 50 {
+				t.Errorf("Unexpected clf quality %v", percents)
+			}
+		case "traefik":
+			if percents["response_time_ms"] != 0 || percents["geo_country"] != 50 {
+				t.Errorf("Unexpected traefik quality %v", percents)
+			}
+		}
+	}
+	if len(report.Timeline) != 2 || report.Timeline[0].Hour == "" {
+		t.Errorf("Expected one bucket per source, got %+v", report.Timeline)
+	}
+}
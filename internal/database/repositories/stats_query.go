@@ -0,0 +1,281 @@
+package repositories
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultQueryLimit is the number of groups returned by a structured query without limit
+	DefaultQueryLimit = 10
+
+	// MaxQueryLimit is the largest number of groups a structured query returns
+	MaxQueryLimit = 100
+)
+
+// queryMetrics maps the metrics of a structured query to their aggregate, "failed" is replaced by the status policy
+var queryMetrics = map[string]string{
+	"requests":          "COUNT(*)",
+	"unique_visitors":   "COUNT(DISTINCT client_ip)",
+	"bandwidth":         "COALESCE(SUM(response_size), 0)",
+	"avg_response_time": "COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0)",
+	"max_response_time": "COALESCE(MAX(response_time_ms), 0)",
+	"error_rate":        "COALESCE(100.0 * SUM(CASE WHEN failed THEN 1 ELSE 0 END) / COUNT(*), 0)",
+}
+
+// queryGroups maps the group_by values of a structured query to their expression
+var queryGroups = map[string]string{
+	"hour":        "strftime('%Y-%m-%d %H:00', timestamp)",
+	"day":         "strftime('%Y-%m-%d', timestamp)",
+	"path":        "path",
+	"host":        "host",
+	"method":      "method",
+	"status_code": "status_code",
+	"country":     "geo_country",
+	"client_ip":   "client_ip",
+	"browser":     "browser",
+	"os":          "os",
+	"device_type": "device_type",
+	"backend":     "backend_name",
+	"router":      "router_name",
+}
+
+// queryFilters maps the filters of a structured query to their column (service uses the service matching)
+var queryFilters = map[string]string{
+	"service":     "",
+	"host":        "host",
+	"path":        "path",
+	"method":      "method",
+	"status_code": "status_code",
+	"country":     "geo_country",
+	"client_ip":   "client_ip",
+	"browser":     "browser",
+	"os":          "os",
+	"device_type": "device_type",
+	"backend":     "backend_name",
+	"router":      "router_name",
+}
+
+// StructuredQuery is an analytics query built from a fixed vocabulary, for chat bots and assistants
+// It is validated before running: only known metrics, groups and filters reach SQL, values are bound.
+// Captured request headers group and filter as "header:<Name>", e.g. "header:X-Tenant-Id", and labels
+// as "label:<name>", e.g. "label:customer".
+type StructuredQuery struct {
+	Metric  string            `json:"metric"`             // See QueryMetrics
+	GroupBy string            `json:"group_by,omitempty"` // See QueryGroups, empty = one total
+	Filters map[string]string `json:"filters,omitempty"`  // See QueryFilters, combined with AND
+	Hours   int               `json:"hours,omitempty"`    // Time range, 0 = default lookback
+	Limit   int               `json:"limit,omitempty"`    // Groups returned (by value, time groups by time)
+}
+
+// QueryRow is one group of a structured query result
+type QueryRow struct {
+	Group string  `json:"group,omitempty"`
+	Value float64 `json:"value"`
+}
+
+// QueryMetrics returns the metrics accepted by structured queries
+func QueryMetrics() []string {
+	return sortedKeys(queryMetrics)
+}
+
+// QueryGroups returns the group_by values accepted by structured queries
+func QueryGroups() []string {
+	return sortedKeys(queryGroups)
+}
+
+// QueryFilters returns the filters accepted by structured queries
+func QueryFilters() []string {
+	return sortedKeys(queryFilters)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Normalize validates the query and fills in its defaults (lookbackHours when no range is given)
+// Errors name the accepted values, so a caller can correct its query.
+func (q *StructuredQuery) Normalize(lookbackHours int) error {
+	q.Metric = strings.ToLower(strings.TrimSpace(q.Metric))
+	q.GroupBy = strings.ToLower(strings.TrimSpace(q.GroupBy))
+	if q.Metric == "" {
+		q.Metric = "requests"
+	}
+	if _, ok := queryMetrics[q.Metric]; !ok {
+		return fmt.Errorf("unknown metric %q, expected one of %s", q.Metric, strings.Join(QueryMetrics(), ", "))
+	}
+	if header, ok := strings.CutPrefix(q.GroupBy, HeaderPrefix); ok {
+		canonical, err := CanonicalHeaderName(header)
+		if err != nil {
+			return fmt.Errorf("invalid group_by %q: %w", q.GroupBy, err)
+		}
+		q.GroupBy = HeaderPrefix + canonical
+	} else if label, ok := strings.CutPrefix(q.GroupBy, LabelPrefix); ok {
+		if err := ValidateLabelName(label); err != nil {
+			return fmt.Errorf("invalid group_by %q: %w", q.GroupBy, err)
+		}
+	} else if _, ok := queryGroups[q.GroupBy]; q.GroupBy != "" && !ok {
+		return fmt.Errorf("unknown group_by %q, expected one of %s, %s<Name> or %s<name>", q.GroupBy, strings.Join(QueryGroups(), ", "), HeaderPrefix, LabelPrefix)
+	}
+	filters := make(map[string]string, len(q.Filters))
+	for name, value := range q.Filters {
+		if header, ok := strings.CutPrefix(name, HeaderPrefix); ok {
+			canonical, err := CanonicalHeaderName(header)
+			if err != nil {
+				return fmt.Errorf("invalid filter %q: %w", name, err)
+			}
+			filters[HeaderPrefix+canonical] = value
+			continue
+		}
+		if label, ok := strings.CutPrefix(name, LabelPrefix); ok {
+			label = strings.ToLower(label)
+			if err := ValidateLabelName(label); err != nil {
+				return fmt.Errorf("invalid filter %q: %w", name, err)
+			}
+			filters[LabelPrefix+label] = value
+			continue
+		}
+		if _, ok := queryFilters[name]; !ok {
+			return fmt.Errorf("unknown filter %q, expected one of %s, %s<Name> or %s<name>", name, strings.Join(QueryFilters(), ", "), HeaderPrefix, LabelPrefix)
+		}
+		if name == "status_code" {
+			if _, _, err := parseStatusFilter(value); err != nil {
+				return err
+			}
+		}
+		filters[name] = value
+	}
+	if len(filters) > 0 {
+		q.Filters = filters
+	}
+
+	if q.Hours <= 0 {
+		q.Hours = lookbackHours
+	}
+	q.Hours = clampLookbackHours(q.Hours)
+	if q.GroupBy == "" {
+		q.Limit = 0
+	} else if q.Limit <= 0 {
+		q.Limit = DefaultQueryLimit
+	} else if q.Limit > MaxQueryLimit {
+		q.Limit = MaxQueryLimit
+	}
+	return nil
+}
+
+// APICall returns the GET request of the query, e.g. for a chat bot to show or reuse
+func (q *StructuredQuery) APICall() string {
+	params := url.Values{"metric": {q.Metric}, "hours": {strconv.Itoa(q.Hours)}}
+	if q.GroupBy != "" {
+		params.Set("group_by", q.GroupBy)
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	for name, value := range q.Filters {
+		if header, ok := strings.CutPrefix(name, HeaderPrefix); ok {
+			name = "header[" + header + "]"
+		} else if label, ok := strings.CutPrefix(name, LabelPrefix); ok {
+			name = "label[" + label + "]"
+		}
+		params.Set(name, value)
+	}
+	return "/api/v1/query?" + params.Encode()
+}
+
+// parseStatusFilter parses a status code ("404") or class ("5xx") into an inclusive range
+func parseStatusFilter(value string) (int, int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) == 3 && strings.HasSuffix(value, "xx") && value[0] >= '1' && value[0] <= '5' {
+		class := int(value[0]-'0') * 100
+		return class, class + 99, nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 100 || code > 599 {
+		return 0, 0, fmt.Errorf("invalid status_code filter %q, expected a code (404) or a class (5xx)", value)
+	}
+	return code, code, nil
+}
+
+// RunQuery runs a normalized structured query over the repository lookback
+func (r *statsRepo) RunQuery(q *StructuredQuery) ([]*QueryRow, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	aggregate := queryMetrics[q.Metric]
+	var selectArgs []interface{}
+	if strings.Contains(aggregate, "failed") {
+		failureCond, failureArgs := r.statusPolicy.FailureCondition()
+		aggregate = strings.Replace(aggregate, "failed", failureCond, 1)
+		selectArgs = failureArgs
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).Where("timestamp > ?", r.getTimeRange())
+	query = r.applyQueryFilters(query, q.Filters)
+
+	rows := make([]*QueryRow, 0)
+	if q.GroupBy == "" {
+		var value float64
+		if err := query.Select(aggregate+" AS value", selectArgs...).Scan(&value).Error; err != nil {
+			return nil, err
+		}
+		return append(rows, &QueryRow{Value: value}), nil
+	}
+
+	group := queryGroups[q.GroupBy]
+	if header, ok := strings.CutPrefix(q.GroupBy, HeaderPrefix); ok {
+		group = headerExpression(header)
+	} else if label, ok := strings.CutPrefix(q.GroupBy, LabelPrefix); ok {
+		group = labelExpression(label)
+	}
+	query = query.Select("CAST("+group+" AS TEXT) AS \"group\", "+aggregate+" AS value", selectArgs...).Group(group)
+	if q.GroupBy == "hour" || q.GroupBy == "day" {
+		// Latest buckets, returned in chronological order
+		query = r.db.WithContext(ctx).Table("(?) AS buckets", query.Order(group+" DESC").Limit(q.Limit)).Order("\"group\"")
+	} else {
+		query = query.Order("value DESC").Limit(q.Limit)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// applyQueryFilters adds the filters of a normalized structured query
+func (r *statsRepo) applyQueryFilters(query *gorm.DB, filters map[string]string) *gorm.DB {
+	for name, value := range filters {
+		switch name {
+		case "service":
+			query = r.applyServiceFilters(query, []filter.Service{{Name: value, Type: "auto"}})
+		case "status_code":
+			low, high, _ := parseStatusFilter(value)
+			query = query.Where("status_code BETWEEN ? AND ?", low, high)
+		case "method":
+			query = query.Where("method = ?", strings.ToUpper(value))
+		case "country":
+			query = query.Where("geo_country = ?", strings.ToUpper(value))
+		default:
+			if header, ok := strings.CutPrefix(name, HeaderPrefix); ok {
+				query = query.Where(headerExpression(header)+" = ?", value)
+				continue
+			}
+			if label, ok := strings.CutPrefix(name, LabelPrefix); ok {
+				query = query.Where(labelCondition, label, value)
+				continue
+			}
+			query = query.Where(queryFilters[name]+" = ?", value)
+		}
+	}
+	return query
+}
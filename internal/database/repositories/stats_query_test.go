@@ -0,0 +1,113 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestRunQuery(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// 6 requests to /a (2 of them 404), 3 to /b from tenant acme, 1 older than the range
+	now := time.Now()
+	for i, path := range []string{"/a", "/a", "/a", "/a", "/a", "/a", "/b", "/b", "/b", "/old"} {
+		status, timestamp, metadata := 200, now.Add(-time.Duration(i)*time.Minute), ""
+		if i < 2 {
+			status = 404
+		}
+		if path == "/b" {
+			metadata = `{"headers":{"X-Tenant-Id":"acme"}}`
+		}
+		if path == "/old" {
+			timestamp = now.Add(-48 * time.Hour)
+		}
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: timestamp, RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: path, StatusCode: status, ProxyMetadata: metadata,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	run := func(query *repositories.StructuredQuery) []*repositories.QueryRow {
+		t.Helper()
+		if err := query.Normalize(repo.LookbackHours()); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := repo.WithLookback(query.Hours).RunQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	rows := run(&repositories.StructuredQuery{GroupBy: "path"})
+	if len(rows) != 2 || rows[0].Group != "/a" || rows[0].Value != 6 || rows[1].Value != 3 {
+		t.Errorf("Unexpected requests per path %+v", rows)
+	}
+	rows = run(&repositories.StructuredQuery{Metric: "error_rate", Filters: map[string]string{"path": "/a"}})
+	if len(rows) != 1 || rows[0].Value < 33.3 || rows[0].Value > 33.4 {
+		t.Errorf("Expected an error rate of 33.3%%, got %+v", rows)
+	}
+	rows = run(&repositories.StructuredQuery{Filters: map[string]string{"status_code": "4xx"}, Hours: 72})
+	if len(rows) != 1 || rows[0].Value != 2 {
+		t.Errorf("Expected 2 requests with a 4xx status, got %+v", rows)
+	}
+
+	// Captured headers group and filter like columns, requests without the header form the "" group
+	rows = run(&repositories.StructuredQuery{GroupBy: "header:x-tenant-id"})
+	if len(rows) != 2 || rows[0].Group != "" || rows[0].Value != 6 || rows[1].Group != "acme" || rows[1].Value != 3 {
+		t.Errorf("Unexpected requests per tenant %+v", rows)
+	}
+	query := &repositories.StructuredQuery{Metric: "requests", Filters: map[string]string{"header:x-tenant-id": "acme"}}
+	if rows := run(query); len(rows) != 1 || rows[0].Value != 3 {
+		t.Errorf("Expected 3 requests from tenant acme, got %+v", rows)
+	}
+	if query.APICall() != "/api/v1/query?header%5BX-Tenant-Id%5D=acme&hours=24&metric=requests" {
+		t.Errorf("Unexpected API call %s", query.APICall())
+	}
+
+	headers, err := repositories.ParseHeaderFilter(map[string]string{"x-tenant-id": "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err = repositories.NewHTTPRequestRepository(db, log, "").Stream(repositories.RequestFilter{Headers: headers}, func(r *models.HTTPRequest) error {
+		paths = append(paths, r.Path)
+		return nil
+	})
+	if err != nil || fmt.Sprint(paths) != "[/b /b /b]" {
+		t.Errorf("Expected the requests of tenant acme, got %v (%v)", paths, err)
+	}
+
+	query = &repositories.StructuredQuery{GroupBy: "hour", Limit: 500}
+	if rows := run(query); len(rows) == 0 || rows[len(rows)-1].Group != now.UTC().Format("2006-01-02 15:00") {
+		t.Errorf("Expected hourly buckets ending with the current hour, got %+v", rows)
+	}
+	if query.Limit != repositories.MaxQueryLimit || query.APICall() != "/api/v1/query?group_by=hour&hours=24&limit=100&metric=requests" {
+		t.Errorf("Unexpected normalized query %+v (%s)", query, query.APICall())
+	}
+
+	for _, invalid := range []*repositories.StructuredQuery{
+		{Metric: "requests; DROP TABLE http_requests"},
+		{GroupBy: "user_agent"},
+		{Filters: map[string]string{"query_string": "x"}},
+		{Filters: map[string]string{"status_code": "9xx"}},
+		{GroupBy: `header:X-Tenant"Id`},
+		{Filters: map[string]string{"header:": "acme"}},
+	} {
+		if err := invalid.Normalize(24); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+	"loglynx/internal/sketch"
+
+	"gorm.io/gorm"
+)
+
+// ResponseTimeRollupName is the checkpoint name of the hourly response time rollups
+const ResponseTimeRollupName = "response_time"
+
+// responseTimeFromRollups computes response time stats from hourly rollups, merged with the requests of
+// the partial hours at both ends of the time range. It returns nil (without error) when rollups cannot
+// answer the query: filters on a single column, or rollups lagging behind ingestion.
+func (r *statsRepo) responseTimeFromRollups(since time.Time, filters []filter.Service) (*ResponseTimeStats, error) {
+	for _, filter := range filters {
+		if filter.Type != "" && filter.Type != "auto" {
+			return nil, nil
+		}
+	}
+
+	var checkpoint models.RollupCheckpoint
+	err := r.db.Where("name = ?", ResponseTimeRollupName).Limit(1).Find(&checkpoint).Error
+	if err != nil || checkpoint.Name == "" {
+		return nil, err
+	}
+
+	// Rollups cover the complete hours of the range up to the last run, the rest is read from requests
+	firstHour := since.UTC().Truncate(time.Hour).Add(time.Hour)
+	builtUntil := checkpoint.BuiltUntil.UTC()
+	if !firstHour.Before(builtUntil) {
+		return nil, nil
+	}
+
+	// Rollups are stale if their hours received requests the rollup service has not seen yet
+	var pending []uint
+	err = r.db.Model(&models.HTTPRequest{}).
+		Where("id > ? AND timestamp >= ? AND timestamp < ?", checkpoint.LastID, firstHour, builtUntil).
+		Limit(1).
+		Pluck("id", &pending).Error
+	if err != nil || len(pending) > 0 {
+		return nil, err
+	}
+
+	merged := sketch.New(sketch.DefaultRelativeAccuracy)
+
+	query := r.db.Model(&models.ResponseTimeRollup{}).
+		Select("sketch").
+		Where("hour >= ? AND hour < ?", firstHour, builtUntil)
+	if len(filters) > 0 {
+		conds := make([]string, 0, len(filters))
+		args := make([]interface{}, 0, len(filters))
+		for _, service := range filters {
+			cond, arg := service.ColumnCondition("service")
+			conds = append(conds, cond)
+			args = append(args, arg)
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	var encoded [][]byte
+	if err := query.Pluck("sketch", &encoded).Error; err != nil {
+		return nil, err
+	}
+	for _, data := range encoded {
+		hourSketch, err := sketch.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := merged.Merge(hourSketch); err != nil {
+			return nil, err
+		}
+	}
+
+	// Partial hours at both ends of the range come straight from requests
+	edges := func() *gorm.DB {
+		q := r.db.Model(&models.HTTPRequest{}).
+			Select("response_time_ms").
+			Where("response_time_ms > 0").
+			Where("(timestamp > ? AND timestamp < ?) OR timestamp >= ?", since, firstHour, builtUntil)
+		return r.applyServiceFilters(q, filters)
+	}
+	var edgeTimes []float64
+	if err := edges().Pluck("response_time_ms", &edgeTimes).Error; err != nil {
+		return nil, err
+	}
+	for _, ms := range edgeTimes {
+		merged.Add(ms)
+	}
+
+	stats := &ResponseTimeStats{
+		Min: merged.Min(),
+		Max: merged.Max(),
+		P50: merged.Quantile(0.50),
+		P95: merged.Quantile(0.95),
+		P99: merged.Quantile(0.99),
+	}
+	if merged.Count() > 0 {
+		stats.Avg = merged.Sum() / float64(merged.Count())
+	}
+	return stats, nil
+}
+
+// ServiceHourRollup holds the requests, errors and response times of one service over one complete hour
+type ServiceHourRollup struct {
+	Service      string
+	Requests     int64
+	Errors       int64 // Failures of the status policy
+	Bandwidth    int64
+	ResponseTime *ResponseTimeStats `gorm:"-"` // From the response time rollup, nil when no request of the hour was timed
+}
+
+// GetRollupsBuiltUntil returns the end of the last hour rolled up (zero before the first rollup run)
+func (r *statsRepo) GetRollupsBuiltUntil() (time.Time, error) {
+	return r.rollupBuiltUntil(ResponseTimeRollupName)
+}
+
+// rollupBuiltUntil returns the end of the last hour of a rollup, zero before its first run
+func (r *statsRepo) rollupBuiltUntil(name string) (time.Time, error) {
+	var checkpoint models.RollupCheckpoint
+	err := r.db.Where("name = ?", name).Limit(1).Find(&checkpoint).Error
+	if err != nil || checkpoint.Name == "" {
+		return time.Time{}, err
+	}
+	return checkpoint.BuiltUntil.UTC(), nil
+}
+
+// GetServiceHourRollups returns the rollup of every service with requests in the hour starting at hour
+// Services are grouped like the rollups ("auto" service filter), response times come from the rollups and
+// counts from the requests of the hour.
+func (r *statsRepo) GetServiceHourRollups(hour time.Time) ([]*ServiceHourRollup, error) {
+	hour = hour.UTC().Truncate(time.Hour)
+
+	var rollups []*ServiceHourRollup
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	err := r.db.Model(&models.HTTPRequest{}).
+		Select("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) as service, COUNT(*) as requests, "+
+			"COUNT(CASE WHEN "+failureCond+" THEN 1 END) as errors, COALESCE(SUM(response_size), 0) as bandwidth", failureArgs...).
+		Where("timestamp >= ? AND timestamp < ?", hour, hour.Add(time.Hour)).
+		Group("service").
+		Order("service").
+		Scan(&rollups).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get service hour rollups", r.logger.Args("hour", hour, "error", err))
+		return nil, err
+	}
+
+	var responseTimes []*models.ResponseTimeRollup
+	if err := r.db.Where("hour = ?", hour).Find(&responseTimes).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get response time rollups", r.logger.Args("hour", hour, "error", err))
+		return nil, err
+	}
+	byService := make(map[string]*ServiceHourRollup, len(rollups))
+	for _, rollup := range rollups {
+		byService[rollup.Service] = rollup
+	}
+	for _, responseTime := range responseTimes {
+		rollup, ok := byService[responseTime.Service]
+		if !ok || responseTime.Count == 0 {
+			continue
+		}
+		hourSketch, err := sketch.Decode(responseTime.Sketch)
+		if err != nil {
+			return nil, err
+		}
+		rollup.ResponseTime = &ResponseTimeStats{
+			Min: responseTime.MinMs,
+			Max: responseTime.MaxMs,
+			Avg: responseTime.SumMs / float64(responseTime.Count),
+			P50: hourSketch.Quantile(0.50),
+			P95: hourSketch.Quantile(0.95),
+			P99: hourSketch.Quantile(0.99),
+		}
+	}
+	return rollups, nil
+}
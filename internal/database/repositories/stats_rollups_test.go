@@ -0,0 +1,102 @@
+package repositories_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/filter"
+
+	"github.com/pterm/pterm"
+)
+
+func TestResponseTimeStats_Rollups(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	rng := rand.New(rand.NewSource(1))
+	now := time.Now()
+	var apiTimes []float64
+	insert := func(i int, timestamp time.Time, backend string, ms float64) {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: timestamp, RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", BackendName: backend, ResponseTimeMs: ms,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3000; i++ {
+		// Spread over the last 23 hours, including the current (partial) hour
+		timestamp := now.Add(-time.Duration(rng.Int63n(int64(23 * time.Hour))))
+		ms := math.Exp(rng.NormFloat64() + math.Log(40))
+		backend := "api@docker"
+		if i%3 == 0 {
+			backend = "web@docker"
+		} else {
+			apiTimes = append(apiTimes, ms)
+		}
+		insert(i, timestamp, backend, ms)
+	}
+	sort.Float64s(apiTimes)
+
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	filters := []filter.Service{{Name: "api", Type: "auto", MatchType: filter.MatchPrefix}}
+
+	exact, err := repo.GetResponseTimeStats(filters, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollups := database.NewRollupService(db, log, time.Minute)
+	if err := rollups.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&models.ResponseTimeRollup{}).Count(&count)
+	if count == 0 {
+		t.Fatal("Expected rollups to be created")
+	}
+
+	stats, err := repo.GetResponseTimeStats(filters, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Min != exact.Min || stats.Max != exact.Max || math.Abs(stats.Avg-exact.Avg) > 1e-6 {
+		t.Errorf("Expected exact min/max/avg %v/%v/%v, got %v/%v/%v", exact.Min, exact.Max, exact.Avg, stats.Min, stats.Max, stats.Avg)
+	}
+	for q, got := range map[float64]float64{0.5: stats.P50, 0.95: stats.P95, 0.99: stats.P99} {
+		expected := apiTimes[int(q*float64(len(apiTimes)-1))]
+		if math.Abs(got-expected)/expected > 0.011 {
+			t.Errorf("p%v: expected %.3f within 1%%, got %.3f", q*100, expected, got)
+		}
+	}
+
+	// A late request in an already rolled up hour makes rollups stale: stats fall back to requests
+	insert(5000, now.Add(-10*time.Hour), "api@docker", 100000)
+	stale, err := repo.GetResponseTimeStats(filters, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale.Max != 100000 {
+		t.Errorf("Expected stale rollups to be bypassed, got max %v", stale.Max)
+	}
+
+	if err := rollups.Run(); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := repo.GetResponseTimeStats(filters, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Max != 100000 || refreshed.Min != exact.Min {
+		t.Errorf("Expected rebuilt rollups to include the late request, got min %v max %v", refreshed.Min, refreshed.Max)
+	}
+}
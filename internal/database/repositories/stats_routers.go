@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// routerDetailLimit is the number of paths and services listed in a router drill-down
+const routerDetailLimit = 10
+
+// RouterStats holds the traffic of one router (Traefik RouterName)
+type RouterStats struct {
+	RouterName      string  `json:"router_name"`
+	Hits            int64   `json:"hits"`
+	UniqueVisitors  int64   `json:"unique_visitors"`
+	Bandwidth       int64   `json:"bandwidth"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+	ErrorCount      int64   `json:"error_count"`
+	ErrorRate       float64 `json:"error_rate"` // Percentage of failed requests
+	Services        int64   `json:"services"`   // Distinct services behind the router
+}
+
+// RouterDetail drills down into one router
+type RouterDetail struct {
+	Summary      *RouterStats          `json:"summary"`
+	ResponseTime *ResponseTimeStats    `json:"response_time"`
+	Timeline     []*RouterTimelineData `json:"timeline"`
+	StatusCodes  []*StatusCodeStats    `json:"status_codes"`
+	TopPaths     []*RouterTargetStats  `json:"top_paths"`
+	Services     []*RouterTargetStats  `json:"services"`
+}
+
+// RouterTimelineData holds the requests, errors and latency of a router for one time bucket
+type RouterTimelineData struct {
+	Hour            string  `json:"hour"`
+	Requests        int64   `json:"requests"`
+	Errors          int64   `json:"errors"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+}
+
+// RouterTargetStats holds the requests of a router to one path or service
+type RouterTargetStats struct {
+	Name            string  `json:"name"`
+	Hits            int64   `json:"hits"`
+	ErrorCount      int64   `json:"error_count"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+}
+
+// routerAggregates computes RouterStats columns, failures follow the status policy of each row's service
+func (r *statsRepo) routerAggregates() (string, []interface{}) {
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	return `COUNT(*) as hits,
+			COUNT(DISTINCT client_ip) as unique_visitors,
+			COALESCE(SUM(response_size), 0) as bandwidth,
+			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time,
+			COUNT(CASE WHEN ` + failureCond + ` THEN 1 END) as error_count,
+			COUNT(DISTINCT COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)) as services`, failureArgs
+}
+
+// routerQuery returns requests of the time range with service and IP filters, optionally of one router
+func (r *statsRepo) routerQuery(router string, filters []filter.Service, excludeIP *filter.ExcludeIP) *gorm.DB {
+	query := r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", r.getTimeRange())
+	if router != "" {
+		query = query.Where("router_name = ?", router)
+	} else {
+		query = query.Where("router_name != ''")
+	}
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+	return query
+}
+
+// GetTopRouters returns the routers with the most requests
+// Only proxies logging a router name (Traefik) have routers.
+func (r *statsRepo) GetTopRouters(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*RouterStats, error) {
+	var routers []*RouterStats
+
+	aggregates, args := r.routerAggregates()
+	query := r.routerQuery("", filters, excludeIP).
+		Select("router_name, "+aggregates, args...).
+		Group("router_name")
+	err := r.applyTop(query, "router_name", limit).Scan(&routers).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get top routers", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	for _, router := range routers {
+		router.ErrorRate = routerErrorRate(router.ErrorCount, router.Hits)
+	}
+	return routers, nil
+}
+
+// GetRouterDetail returns the summary, latency percentiles, timeline, status codes, top paths and services of a router
+// Returns nil when the router has no requests in the time range.
+func (r *statsRepo) GetRouterDetail(router string, filters []filter.Service, excludeIP *filter.ExcludeIP) (*RouterDetail, error) {
+	detail := &RouterDetail{
+		Summary:      &RouterStats{},
+		ResponseTime: &ResponseTimeStats{},
+		Timeline:     make([]*RouterTimelineData, 0),
+		StatusCodes:  make([]*StatusCodeStats, 0),
+		TopPaths:     make([]*RouterTargetStats, 0),
+		Services:     make([]*RouterTargetStats, 0),
+	}
+
+	aggregates, args := r.routerAggregates()
+	if err := r.routerQuery(router, filters, excludeIP).Select(aggregates, args...).Scan(detail.Summary).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get router stats", r.logger.Args("router", router, "error", err))
+		return nil, err
+	}
+	if detail.Summary.Hits == 0 {
+		return nil, nil
+	}
+	detail.Summary.RouterName = router
+	detail.Summary.ErrorRate = routerErrorRate(detail.Summary.ErrorCount, detail.Summary.Hits)
+
+	err := r.scanResponseTimePercentiles(r.routerQuery(router, filters, excludeIP), detail.ResponseTime)
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get router response times", r.logger.Args("router", router, "error", err))
+		return nil, err
+	}
+
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	err = r.routerQuery(router, filters, excludeIP).
+		Select(timelineBucket(r.LookbackHours())+` as hour,
+			COUNT(*) as requests,
+			COUNT(CASE WHEN `+failureCond+` THEN 1 END) as errors,
+			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time`, failureArgs...).
+		Group("hour").
+		Order("hour").
+		Scan(&detail.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get router timeline", r.logger.Args("router", router, "error", err))
+		return nil, err
+	}
+
+	err = r.routerQuery(router, filters, excludeIP).
+		Select("status_code, COUNT(*) as count").
+		Group("status_code").
+		Order("count DESC").
+		Scan(&detail.StatusCodes).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get router status codes", r.logger.Args("router", router, "error", err))
+		return nil, err
+	}
+
+	targets := []struct {
+		expression string
+		target     *[]*RouterTargetStats
+	}{
+		{"path", &detail.TopPaths},
+		{"COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)", &detail.Services},
+	}
+	for _, target := range targets {
+		err := r.routerQuery(router, filters, excludeIP).
+			Select(target.expression+` as name,
+				COUNT(*) as hits,
+				COUNT(CASE WHEN `+failureCond+` THEN 1 END) as error_count,
+				COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time`, failureArgs...).
+			Group("name").
+			Order("hits DESC").
+			Limit(routerDetailLimit).
+			Scan(target.target).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to get router breakdown", r.logger.Args("router", router, "error", err))
+			return nil, err
+		}
+	}
+
+	return detail, nil
+}
+
+// scanResponseTimePercentiles computes latency percentiles of the requests of a query
+// CUME_DIST rather than NTILE(100): routers and consumers often have fewer than 100 requests.
+func (r *statsRepo) scanResponseTimePercentiles(requests *gorm.DB, stats *ResponseTimeStats) error {
+	percentiles := requests.
+		Select("response_time_ms, CUME_DIST() OVER (ORDER BY response_time_ms) as cume_dist").
+		Where("response_time_ms > 0")
+	return r.db.Table("(?) as stats_data", percentiles).
+		Select(`COALESCE(MIN(response_time_ms), 0) as min,
+			COALESCE(MAX(response_time_ms), 0) as max,
+			COALESCE(AVG(response_time_ms), 0) as avg,
+			COALESCE(MIN(CASE WHEN cume_dist >= 0.50 THEN response_time_ms END), 0) as p50,
+			COALESCE(MIN(CASE WHEN cume_dist >= 0.95 THEN response_time_ms END), 0) as p95,
+			COALESCE(MIN(CASE WHEN cume_dist >= 0.99 THEN response_time_ms END), 0) as p99`).
+		Scan(stats).Error
+}
+
+// routerErrorRate returns the percentage of failed requests
+func routerErrorRate(errors, hits int64) float64 {
+	if hits == 0 {
+		return 0
+	}
+	return float64(errors) / float64(hits) * 100
+}
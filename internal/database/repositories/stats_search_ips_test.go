@@ -0,0 +1,50 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestSearchIPs(t *testing.T) {
+	now := time.Now().UTC()
+	var requests []*models.HTTPRequest
+	for i, request := range []struct {
+		clientIP string
+		age      time.Duration
+	}{
+		{"10.0.0.1", time.Minute},
+		{"10.0.0.1", 2 * time.Minute},
+		{"10.0.0.12", time.Minute},
+		{"192.168.10.0", time.Minute},
+		{"10.0.9.9", 48 * time.Hour}, // Outside the range
+	} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-request.age), RequestHash: fmt.Sprint(i),
+			ClientIP: request.clientIP, Method: "GET", Host: "example.com", Path: "/", StatusCode: 200,
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	results, err := statsRepo.SearchIPs("10.0", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].IPAddress != "10.0.0.1" || results[0].Hits != 2 || results[0].LastSeen.IsZero() {
+		t.Fatalf("Expected 3 IPs in the range with 10.0.0.1 first, got %+v", results)
+	}
+
+	// Short queries don't scan, LIKE wildcards match literally
+	for _, query := range []string{"10", "10_0", "%"} {
+		results, err := statsRepo.SearchIPs(query, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 0 {
+			t.Errorf("%q: expected no IP, got %d", query, len(results))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// SlowRequestThresholdMs is the default slow request threshold
+const SlowRequestThresholdMs = 1000
+
+// SlowRequestSummary aggregates requests slower than a threshold
+type SlowRequestSummary struct {
+	ThresholdMs       float64             `json:"threshold_ms"`                          // Explicit threshold, or default of services without override
+	ServiceThresholds map[string]float64  `json:"service_thresholds,omitempty" gorm:"-"` // Per-service thresholds, when no explicit threshold was given
+	Total             int64               `json:"total"`
+	AvgResponseTimeMs float64             `json:"avg_response_time_ms"`
+	MaxResponseTimeMs float64             `json:"max_response_time_ms"`
+	ByPath            []*SlowRequestGroup `json:"by_path" gorm:"-"`
+	ByBackend         []*SlowRequestGroup `json:"by_backend" gorm:"-"`
+}
+
+// SlowRequestGroup holds the slow requests of one path or backend
+type SlowRequestGroup struct {
+	Name              string  `json:"name"`
+	Count             int64   `json:"count"`
+	AvgResponseTimeMs float64 `json:"avg_response_time_ms"`
+	MaxResponseTimeMs float64 `json:"max_response_time_ms"`
+}
+
+// GetSlowRequestSummary counts slow requests, with the top paths and backends by slow requests
+// Requests are slower than thresholdMs, or than the threshold of their service when thresholdMs is 0.
+func (r *statsRepo) GetSlowRequestSummary(thresholdMs float64, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*SlowRequestSummary, error) {
+	since := r.getTimeRange()
+	slowFilter := &SlowFilter{ThresholdMs: thresholdMs, Policy: r.slowPolicy}
+
+	slow := func() *gorm.DB {
+		query := slowFilter.apply(r.db.Model(&models.HTTPRequest{}).Where("timestamp > ?", since))
+		query = r.applyServiceFilters(query, filters)
+		query = r.applyExcludeOwnIP(query, excludeIP)
+		return query
+	}
+
+	summary := &SlowRequestSummary{}
+	err := slow().
+		Select("COUNT(*) as total, COALESCE(AVG(response_time_ms), 0) as avg_response_time_ms, COALESCE(MAX(response_time_ms), 0) as max_response_time_ms").
+		Scan(summary).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to count slow requests", r.logger.Args("error", err))
+		return nil, err
+	}
+	summary.ThresholdMs, summary.ServiceThresholds = slowFilter.describe()
+	summary.ByPath = make([]*SlowRequestGroup, 0)
+	summary.ByBackend = make([]*SlowRequestGroup, 0)
+	if summary.Total == 0 {
+		return summary, nil
+	}
+
+	groups := []struct {
+		expression string
+		target     *[]*SlowRequestGroup
+	}{
+		{"path", &summary.ByPath},
+		{"COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)", &summary.ByBackend},
+	}
+	for _, group := range groups {
+		err := slow().
+			Select(group.expression + " as name, COUNT(*) as count, AVG(response_time_ms) as avg_response_time_ms, MAX(response_time_ms) as max_response_time_ms").
+			Group("name").
+			Order("count DESC").
+			Limit(limit).
+			Scan(group.target).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to group slow requests", r.logger.Args("error", err))
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+
+	"gorm.io/gorm"
+)
+
+// MaxSnapshotWindow is the widest window of a traffic snapshot on each side of its time
+const MaxSnapshotWindow = time.Hour
+
+// TrafficSnapshot is what was happening around a point in time, assembled for incident retrospectives
+// An IP is new when it sent no request before the window, known otherwise.
+type TrafficSnapshot struct {
+	At              time.Time          `json:"at" gorm:"-"`
+	From            time.Time          `json:"from" gorm:"-"`
+	To              time.Time          `json:"to" gorm:"-"`
+	Requests        int64              `json:"requests"`
+	Bandwidth       int64              `json:"bandwidth"`
+	AvgResponseTime float64            `json:"avg_response_time"`
+	UniqueIPs       int64              `json:"unique_ips"`
+	NewIPs          int64              `json:"new_ips"`
+	KnownIPs        int64              `json:"known_ips" gorm:"-"`
+	Timeline        []*SnapshotMinute  `json:"timeline" gorm:"-"` // One bucket per minute with requests
+	StatusCodes     []*StatusCodeStats `json:"status_codes" gorm:"-"`
+	TopIPs          []*SnapshotIP      `json:"top_ips" gorm:"-"`
+	TopPaths        []*SnapshotPath    `json:"top_paths" gorm:"-"`
+	TopASNs         []*SnapshotASN     `json:"top_asns" gorm:"-"`
+}
+
+// SnapshotMinute holds the requests of one minute of a snapshot
+type SnapshotMinute struct {
+	Minute   string `json:"minute"` // YYYY-MM-DD HH:MM
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"` // 4xx/5xx responses
+}
+
+// SnapshotIP holds the requests of one client IP in a snapshot
+type SnapshotIP struct {
+	IPAddress string `json:"ip_address"`
+	Country   string `json:"country"`
+	ASN       int    `json:"asn"`
+	ASNOrg    string `json:"asn_org"`
+	Hits      int64  `json:"hits"`
+	Errors    int64  `json:"errors"`
+	Bandwidth int64  `json:"bandwidth"`
+	New       bool   `json:"new" gorm:"column:is_new"`
+}
+
+// SnapshotPath holds the requests of one host and path in a snapshot
+type SnapshotPath struct {
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	Hits   int64  `json:"hits"`
+	Errors int64  `json:"errors"`
+}
+
+// SnapshotASN holds the requests of one autonomous system in a snapshot
+type SnapshotASN struct {
+	ASN       int    `json:"asn"`
+	ASNOrg    string `json:"asn_org"`
+	Hits      int64  `json:"hits"`
+	UniqueIPs int64  `json:"unique_ips"`
+	NewIPs    int64  `json:"new_ips"`
+}
+
+// snapshotNewIP is the condition of a request from an IP without request before the window
+const snapshotNewIP = `NOT EXISTS (SELECT 1 FROM http_requests earlier WHERE earlier.client_ip = http_requests.client_ip
+	AND earlier.timestamp < ?)`
+
+// GetTrafficSnapshot returns the traffic from window before at to window after it, with the limit top IPs,
+// paths and ASNs. The snapshot ignores the lookback of the repository.
+func (r *statsRepo) GetTrafficSnapshot(at time.Time, window time.Duration, limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) (*TrafficSnapshot, error) {
+	from, to := at.Add(-window), at.Add(window)
+
+	requests := func() *gorm.DB {
+		query := r.db.Model(&models.HTTPRequest{}).Where("timestamp >= ? AND timestamp <= ?", from, to)
+		query = r.applyServiceFilters(query, filters)
+		return r.applyExcludeOwnIP(query, excludeIP)
+	}
+
+	snapshot := &TrafficSnapshot{
+		At:          at,
+		From:        from,
+		To:          to,
+		Timeline:    make([]*SnapshotMinute, 0),
+		StatusCodes: make([]*StatusCodeStats, 0),
+		TopIPs:      make([]*SnapshotIP, 0),
+		TopPaths:    make([]*SnapshotPath, 0),
+		TopASNs:     make([]*SnapshotASN, 0),
+	}
+	err := requests().
+		Select(`COUNT(*) as requests, COALESCE(SUM(response_size), 0) as bandwidth,
+			COALESCE(AVG(response_time_ms), 0) as avg_response_time, COUNT(DISTINCT client_ip) as unique_ips,
+			COUNT(DISTINCT CASE WHEN `+snapshotNewIP+` THEN client_ip END) as new_ips`, from).
+		Scan(snapshot).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot totals", r.logger.Args("error", err))
+		return nil, err
+	}
+	snapshot.KnownIPs = snapshot.UniqueIPs - snapshot.NewIPs
+	if snapshot.Requests == 0 {
+		return snapshot, nil
+	}
+
+	err = requests().
+		Select("strftime('%Y-%m-%d %H:%M', timestamp) as minute, COUNT(*) as requests, COUNT(CASE WHEN status_code >= 400 THEN 1 END) as errors").
+		Group("minute").
+		Order("minute").
+		Scan(&snapshot.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	if err := requests().Select("status_code, COUNT(*) as count").Group("status_code").Order("count DESC").Scan(&snapshot.StatusCodes).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot status codes", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	err = requests().
+		Select(`client_ip as ip_address, MAX(geo_country) as country, MAX(asn) as asn, MAX(asn_org) as asn_org,
+			COUNT(*) as hits, COUNT(CASE WHEN status_code >= 400 THEN 1 END) as errors,
+			COALESCE(SUM(response_size), 0) as bandwidth, MAX(`+snapshotNewIP+`) as is_new`, from).
+		Group("client_ip").
+		Order("hits DESC, ip_address").
+		Limit(limit).
+		Scan(&snapshot.TopIPs).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot IPs", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	err = requests().
+		Select("host, path, COUNT(*) as hits, COUNT(CASE WHEN status_code >= 400 THEN 1 END) as errors").
+		Group("host, path").
+		Order("hits DESC, host, path").
+		Limit(limit).
+		Scan(&snapshot.TopPaths).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot paths", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	err = requests().
+		Select(`asn, MAX(asn_org) as asn_org, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_ips,
+			COUNT(DISTINCT CASE WHEN `+snapshotNewIP+` THEN client_ip END) as new_ips`, from).
+		Where("asn > 0").
+		Group("asn").
+		Order("hits DESC, asn").
+		Limit(limit).
+		Scan(&snapshot.TopASNs).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get snapshot ASNs", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	return snapshot, nil
+}
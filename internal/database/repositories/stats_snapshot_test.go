@@ -0,0 +1,76 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+)
+
+func TestTrafficSnapshot(t *testing.T) {
+	// 10.0.0.1 was seen the day before, 203.0.113.7 (AS64500) appears during the spike, one request is after the window
+	at := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Minute)
+	var requests []*models.HTTPRequest
+	for i, request := range []struct {
+		offset   time.Duration
+		clientIP string
+		path     string
+		status   int
+		asn      int
+	}{
+		{-24 * time.Hour, "10.0.0.1", "/", 200, 0},
+		{-time.Minute, "10.0.0.1", "/", 200, 0},
+		{0, "203.0.113.7", "/login", 401, 64500},
+		{time.Second, "203.0.113.7", "/login", 401, 64500},
+		{2 * time.Second, "203.0.113.7", "/login", 200, 64500},
+		{time.Hour, "10.0.0.2", "/", 200, 0},
+	} {
+		requests = append(requests, &models.HTTPRequest{
+			SourceName: "test", Timestamp: at.Add(request.offset), RequestHash: fmt.Sprint(i),
+			ClientIP: request.clientIP, Method: "GET", Host: "example.com", Path: request.path, StatusCode: request.status,
+			ResponseSize: 100, ASN: request.asn, ASNOrg: "Example Hosting",
+		})
+	}
+	statsRepo := fake.NewStatsRepository(t, nil, requests...)
+
+	snapshot, err := statsRepo.GetTrafficSnapshot(at, 5*time.Minute, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Requests != 4 || snapshot.Bandwidth != 400 || snapshot.UniqueIPs != 2 || snapshot.NewIPs != 1 || snapshot.KnownIPs != 1 {
+		t.Errorf("Unexpected totals %+v", snapshot)
+	}
+	if len(snapshot.Timeline) != 2 || snapshot.Timeline[1].Requests != 3 || snapshot.Timeline[1].Errors != 2 {
+		t.Errorf("Unexpected timeline %+v", snapshot.Timeline)
+	}
+	if len(snapshot.StatusCodes) != 2 || snapshot.StatusCodes[0].Count != 2 {
+		t.Errorf("Unexpected status codes %+v", snapshot.StatusCodes)
+	}
+
+	if len(snapshot.TopIPs) != 2 {
+		t.Fatalf("Expected 2 IPs, got %+v", snapshot.TopIPs)
+	}
+	if attacker := snapshot.TopIPs[0]; attacker.IPAddress != "203.0.113.7" || attacker.Hits != 3 || attacker.Errors != 2 || !attacker.New || attacker.ASN != 64500 {
+		t.Errorf("Unexpected new IP %+v", attacker)
+	}
+	if known := snapshot.TopIPs[1]; known.IPAddress != "10.0.0.1" || known.New {
+		t.Errorf("Expected 10.0.0.1 to be known, got %+v", known)
+	}
+	if len(snapshot.TopPaths) != 2 || snapshot.TopPaths[0].Path != "/login" {
+		t.Errorf("Unexpected paths %+v", snapshot.TopPaths)
+	}
+	if len(snapshot.TopASNs) != 1 || snapshot.TopASNs[0].ASN != 64500 || snapshot.TopASNs[0].NewIPs != 1 {
+		t.Errorf("Unexpected ASNs %+v", snapshot.TopASNs)
+	}
+
+	// Nothing happened a week earlier
+	snapshot, err = statsRepo.GetTrafficSnapshot(at.Add(-7*24*time.Hour), 5*time.Minute, 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Requests != 0 || len(snapshot.TopIPs) != 0 || len(snapshot.Timeline) != 0 {
+		t.Errorf("Expected an empty snapshot, got %+v", snapshot)
+	}
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// SourceHostStats holds the requests of one host when the databases of other hosts are attached (ATTACH_DATABASES)
+type SourceHostStats struct {
+	SourceHost     string    `json:"source_host"`
+	Requests       int64     `json:"requests"`
+	Share          float64   `json:"share"`
+	Bandwidth      int64     `json:"bandwidth"`
+	UniqueVisitors int64     `json:"unique_visitors"`
+	FailedRequests int64     `json:"failed_requests"`
+	LastSeen       time.Time `json:"last_seen" gorm:"-"`
+}
+
+// sourceHostRow holds SourceHostStats columns as scanned, SQLite returns MAX(timestamp) as text
+type sourceHostRow struct {
+	SourceHostStats
+	LastSeenText string `gorm:"column:last_seen"`
+}
+
+// GetSourceHostStats returns the requests per source host, only available on connections with attached databases
+// (the source_host column comes from the merged http_requests view)
+func (r *statsRepo) GetSourceHostStats(filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*SourceHostStats, error) {
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(`source_host, COUNT(*) as requests, `+shareColumn+`, COALESCE(SUM(response_size), 0) as bandwidth,
+			COUNT(DISTINCT client_ip) as unique_visitors, COUNT(CASE WHEN `+failureCond+` THEN 1 END) as failed_requests,
+			MAX(timestamp) as last_seen`, failureArgs...).
+		Where("timestamp > ?", r.getTimeRange())
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	var rows []*sourceHostRow
+	if err := query.Group("source_host").Order("requests DESC, source_host").Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get source host stats", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	hosts := make([]*SourceHostStats, 0, len(rows))
+	for _, row := range rows {
+		row.LastSeen = parseSQLiteTime(row.LastSeenText)
+		hosts = append(hosts, &row.SourceHostStats)
+	}
+	return hosts, nil
+}
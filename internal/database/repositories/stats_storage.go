@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+)
+
+// growthSampleDays is the window used to measure the daily ingestion rate
+const growthSampleDays = 7
+
+// StorageBreakdown describes what the database is made of and how fast it grows
+type StorageBreakdown struct {
+	PageSize    int64   `json:"page_size"`
+	PageCount   int64   `json:"page_count"`
+	FreePages   int64   `json:"free_pages"` // Pages reusable without growing the file (VACUUM returns them to the disk)
+	UsedBytes   int64   `json:"used_bytes"`
+	TotalRows   int64   `json:"total_rows"`
+	BytesPerRow float64 `json:"bytes_per_row"` // Used bytes (tables and indexes) per stored request
+
+	// Per table and index sizes need SQLite's dbstat virtual table (SQLITE_ENABLE_DBSTAT_VTAB)
+	ObjectSizesAvailable bool             `json:"object_sizes_available"`
+	Objects              []*StorageObject `json:"objects"`
+
+	Partitions []*PartitionStats `json:"partitions"`
+	Growth     *StorageGrowth    `json:"growth"`
+}
+
+// StorageObject is the size of one table or index
+type StorageObject struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`  // table or index
+	Table     string  `json:"table"` // Table an index belongs to (the table itself for tables)
+	SizeBytes int64   `json:"size_bytes"`
+	Share     float64 `json:"share"` // Percentage of the used bytes
+}
+
+// PartitionStats is the number of requests of one month (partition_key)
+type PartitionStats struct {
+	Month          string `json:"month"`
+	Rows           int64  `json:"rows"`
+	EstimatedBytes int64  `json:"estimated_bytes"` // Rows × bytes per row
+}
+
+// StorageGrowth projects database growth from the ingestion rate of the last days
+type StorageGrowth struct {
+	SampleDays  float64 `json:"sample_days"`
+	RowsPerDay  float64 `json:"rows_per_day"`
+	BytesPerDay float64 `json:"bytes_per_day"`
+}
+
+// GetStorageBreakdown returns page usage, table and index sizes, rows per month and the daily growth
+func (r *statsRepo) GetStorageBreakdown() (*StorageBreakdown, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+	db := r.db.WithContext(ctx)
+
+	breakdown := &StorageBreakdown{
+		Objects:    make([]*StorageObject, 0),
+		Partitions: make([]*PartitionStats, 0),
+		Growth:     &StorageGrowth{},
+	}
+
+	pragmas := []struct {
+		name   string
+		target *int64
+	}{
+		{"page_size", &breakdown.PageSize},
+		{"page_count", &breakdown.PageCount},
+		{"freelist_count", &breakdown.FreePages},
+	}
+	for _, pragma := range pragmas {
+		if err := db.Raw("PRAGMA " + pragma.name).Scan(pragma.target).Error; err != nil {
+			r.logger.WithCaller().Error("Failed to read database pragma", r.logger.Args("pragma", pragma.name, "error", err))
+			return nil, err
+		}
+	}
+	breakdown.UsedBytes = (breakdown.PageCount - breakdown.FreePages) * breakdown.PageSize
+
+	if err := db.Model(&models.HTTPRequest{}).Count(&breakdown.TotalRows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to count requests", r.logger.Args("error", err))
+		return nil, err
+	}
+	if breakdown.TotalRows > 0 {
+		breakdown.BytesPerRow = float64(breakdown.UsedBytes) / float64(breakdown.TotalRows)
+	}
+
+	// dbstat is only compiled in with SQLITE_ENABLE_DBSTAT_VTAB, sizes are left out without it
+	var dbstat int64
+	if err := db.Raw("SELECT COUNT(*) FROM pragma_module_list WHERE name = 'dbstat'").Scan(&dbstat).Error; err != nil {
+		r.logger.WithCaller().Warn("Failed to detect the dbstat module", r.logger.Args("error", err))
+	}
+	if dbstat > 0 {
+		err := db.Raw(`
+			SELECT s.name as name, COALESCE(m.type, 'table') as type, COALESCE(m.tbl_name, s.name) as "table", SUM(s.pgsize) as size_bytes
+			FROM dbstat s
+			LEFT JOIN sqlite_master m ON m.name = s.name
+			GROUP BY s.name
+			ORDER BY size_bytes DESC
+		`).Scan(&breakdown.Objects).Error
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to get table and index sizes", r.logger.Args("error", err))
+			return nil, err
+		}
+		breakdown.ObjectSizesAvailable = true
+		for _, object := range breakdown.Objects {
+			if breakdown.UsedBytes > 0 {
+				object.Share = float64(object.SizeBytes) / float64(breakdown.UsedBytes) * 100
+			}
+		}
+	}
+
+	err := db.Model(&models.HTTPRequest{}).
+		Select("partition_key as month, COUNT(*) as rows").
+		Group("partition_key").
+		Order("month").
+		Scan(&breakdown.Partitions).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to count rows per partition", r.logger.Args("error", err))
+		return nil, err
+	}
+	for _, partition := range breakdown.Partitions {
+		partition.EstimatedBytes = int64(float64(partition.Rows) * breakdown.BytesPerRow)
+	}
+
+	// Daily rate over the last days, or since the oldest request when the database is younger
+	sampleStart := r.clock.Now().AddDate(0, 0, -growthSampleDays)
+	oldest, _, err := r.GetRecordTimeRange()
+	if err == nil && !oldest.IsZero() && oldest.After(sampleStart) {
+		sampleStart = oldest
+	}
+	var recentRows int64
+	if err := db.Model(&models.HTTPRequest{}).Where("timestamp >= ?", sampleStart).Count(&recentRows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to count recent requests", r.logger.Args("error", err))
+		return nil, err
+	}
+	// Less than an hour of data would extrapolate noise
+	if sampleDays := r.clock.Now().Sub(sampleStart).Hours() / 24; sampleDays >= 1.0/24 {
+		breakdown.Growth.SampleDays = sampleDays
+		breakdown.Growth.RowsPerDay = float64(recentRows) / sampleDays
+		breakdown.Growth.BytesPerDay = breakdown.Growth.RowsPerDay * breakdown.BytesPerRow
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"math"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+const (
+	// approxTopKMinRows is the number of rows in the time range from which top-K queries are sampled
+	approxTopKMinRows = 1000000
+
+	// approxTopKSampleRows is the target number of sampled rows
+	approxTopKSampleRows = 200000
+
+	// approxTopKMinSample is the smallest usable sample, filtered queries with fewer sampled rows run exactly
+	approxTopKMinSample = 20000
+)
+
+// Sampling describes how an approximate result was computed
+// Counts were read from 1 of Rate rows and scaled back up
+type Sampling struct {
+	Rate          int64 `json:"rate"`
+	SampledRows   int64 `json:"sampled_rows"`
+	EstimatedRows int64 `json:"estimated_rows"` // Rows in the time range, before service filters
+}
+
+// topKSampleRate returns how many rows of the time range are represented by one sampled row (1 = no sampling)
+// The row count is estimated from the ID range, which follows ingestion order, so it needs two index lookups
+// instead of counting. Sampling on id % rate lets SQLite skip table reads while scanning the timestamp index.
+func (r *statsRepo) topKSampleRate() (rate int64, estimatedRows int64) {
+	var firstID, lastID int64
+	err := r.db.Model(&models.HTTPRequest{}).
+		Select("id").
+		Where("timestamp > ?", r.getTimeRange()).
+		Order("timestamp ASC").
+		Limit(1).
+		Scan(&firstID).Error
+	if err != nil || firstID == 0 {
+		return 1, 0
+	}
+	if err := r.db.Model(&models.HTTPRequest{}).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		return 1, 0
+	}
+
+	estimatedRows = lastID - firstID + 1
+	if estimatedRows < approxTopKMinRows {
+		return 1, estimatedRows
+	}
+	return estimatedRows / approxTopKSampleRows, estimatedRows
+}
+
+// estimateUniqueVisitors estimates distinct visitors from the distinct visitors of a sample
+// Multiplying by the rate only works when every visitor makes one request, so it assumes visitors make a similar
+// number of requests (hits / visitors) and solves visitors * P(a visitor appears in the sample) = sampled for visitors.
+func estimateUniqueVisitors(sampled int64, rate int64, hits int64) int64 {
+	if sampled <= 0 || rate <= 1 || hits <= sampled {
+		return min(sampled, hits)
+	}
+
+	miss := 1 - 1/float64(rate) // Probability that one request is not sampled
+	seen := func(visitors float64) float64 {
+		return visitors * (1 - math.Pow(miss, float64(hits)/visitors))
+	}
+
+	// seen() increases with the number of visitors, between sampled (every visitor seen) and hits (one request each)
+	low, high := float64(sampled), float64(hits)
+	for range 50 {
+		mid := (low + high) / 2
+		if seen(mid) < float64(sampled) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return int64(math.Round(high))
+}
+
+// GetTopPathsApprox returns the most accessed paths from a sample of large time ranges
+// Sampling is nil when the result is exact (small range, page or top filter, or too few sampled rows after
+// filtering)
+func (r *statsRepo) GetTopPathsApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*PathStats, *Sampling, error) {
+	rate, estimatedRows := r.topKSampleRate()
+	if rate <= 1 || r.exactTop() {
+		paths, err := r.GetTopPaths(limit, filters, excludeIP)
+		return paths, nil, err
+	}
+
+	var rows []*struct {
+		PathStats
+		SampleTotal int64
+	}
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select("path, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, COALESCE(AVG(response_time_ms), 0) as avg_response_time, COALESCE(SUM(response_size), 0) as total_bandwidth, "+shareColumn+", SUM(COUNT(*)) OVER () as sample_total").
+		Where("timestamp > ? AND id % ? = 0", r.getTimeRange(), rate)
+
+	query = r.applyServiceFilters(query, filters)
+	if err := query.Group("path").Order("hits DESC").Limit(limit).Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get approximate top paths", r.logger.Args("error", err))
+		return nil, nil, err
+	}
+
+	if len(rows) == 0 || rows[0].SampleTotal < approxTopKMinSample {
+		paths, err := r.GetTopPaths(limit, filters, excludeIP)
+		return paths, nil, err
+	}
+
+	paths := make([]*PathStats, len(rows))
+	for i, row := range rows {
+		path := row.PathStats
+		path.Hits *= rate
+		path.UniqueVisitors = estimateUniqueVisitors(path.UniqueVisitors, rate, path.Hits)
+		path.TotalBandwidth *= rate
+		paths[i] = &path
+	}
+	return paths, &Sampling{Rate: rate, SampledRows: rows[0].SampleTotal, EstimatedRows: estimatedRows}, nil
+}
+
+// GetTopUserAgentsApprox returns the most common user agents from a sample of large time ranges
+// Sampling is nil when the result is exact
+func (r *statsRepo) GetTopUserAgentsApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UserAgentStats, *Sampling, error) {
+	rate, estimatedRows := r.topKSampleRate()
+	if rate <= 1 || r.exactTop() {
+		agents, err := r.GetTopUserAgents(limit, filters, excludeIP)
+		return agents, nil, err
+	}
+
+	var rows []*struct {
+		UserAgentStats
+		SampleTotal int64
+	}
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select("user_agent, COUNT(*) as count, SUM(COUNT(*)) OVER () as sample_total").
+		Where("timestamp > ? AND user_agent != '' AND id % ? = 0", r.getTimeRange(), rate)
+
+	query = r.applyServiceFilters(query, filters)
+	if err := query.Group("user_agent").Order("count DESC").Limit(limit).Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get approximate top user agents", r.logger.Args("error", err))
+		return nil, nil, err
+	}
+
+	if len(rows) == 0 || rows[0].SampleTotal < approxTopKMinSample {
+		agents, err := r.GetTopUserAgents(limit, filters, excludeIP)
+		return agents, nil, err
+	}
+
+	agents := make([]*UserAgentStats, len(rows))
+	for i, row := range rows {
+		agent := row.UserAgentStats
+		agent.Count *= rate
+		agents[i] = &agent
+	}
+	return agents, &Sampling{Rate: rate, SampledRows: rows[0].SampleTotal, EstimatedRows: estimatedRows}, nil
+}
+
+// GetTopReferrersApprox returns the most common referrers from a sample of large time ranges
+// Sampling is nil when the result is exact
+func (r *statsRepo) GetTopReferrersApprox(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*ReferrerStats, *Sampling, error) {
+	rate, estimatedRows := r.topKSampleRate()
+	if rate <= 1 || r.exactTop() {
+		referrers, err := r.GetTopReferrers(limit, filters, excludeIP)
+		return referrers, nil, err
+	}
+
+	var rows []*struct {
+		ReferrerStats
+		SampleTotal int64
+	}
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select("referer as referrer, COUNT(*) as hits, COUNT(DISTINCT client_ip) as unique_visitors, SUM(COUNT(*)) OVER () as sample_total").
+		Where("timestamp > ? AND referer != '' AND id % ? = 0", r.getTimeRange(), rate)
+
+	query = r.applyServiceFilters(query, filters)
+	if err := query.Group("referer").Order("hits DESC").Limit(limit).Scan(&rows).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get approximate top referrers", r.logger.Args("error", err))
+		return nil, nil, err
+	}
+
+	if len(rows) == 0 || rows[0].SampleTotal < approxTopKMinSample {
+		referrers, err := r.GetTopReferrers(limit, filters, excludeIP)
+		return referrers, nil, err
+	}
+
+	referrers := make([]*ReferrerStats, len(rows))
+	for i, row := range rows {
+		referrer := row.ReferrerStats
+		referrer.Hits *= rate
+		referrer.UniqueVisitors = estimateUniqueVisitors(referrer.UniqueVisitors, rate, referrer.Hits)
+		referrers[i] = &referrer
+	}
+	return referrers, &Sampling{Rate: rate, SampledRows: rows[0].SampleTotal, EstimatedRows: estimatedRows}, nil
+}
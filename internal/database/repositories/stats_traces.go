@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"loglynx/internal/database/models"
+)
+
+const (
+	// maxTraceHops caps the requests returned for one trace
+	maxTraceHops = 500
+	// maxDependencyHops caps the requests read to build the service dependency graph, most recent first
+	maxDependencyHops = 100000
+)
+
+// traceExpression is the correlation ID of a request: its trace ID, else its request ID
+const traceExpression = "COALESCE(NULLIF(trace_id, ''), NULLIF(request_id, ''))"
+
+// TraceHop is one request of a trace, as logged by the service that served it
+type TraceHop struct {
+	ID             uint      `json:"id"`
+	StartedAt      time.Time `json:"started_at"` // StartUTC when logged, else the request timestamp
+	Service        string    `json:"service"`    // backend_name, else backend_url, else host
+	Method         string    `json:"method"`
+	Host           string    `json:"host"`
+	Path           string    `json:"path"`
+	StatusCode     int       `json:"status_code"`
+	Failed         bool      `json:"failed"` // Failure per the status policy of the service
+	ResponseTimeMs float64   `json:"response_time_ms"`
+	SelfTimeMs     float64   `json:"self_time_ms"` // Response time not spent in child hops
+	UpstreamAddr   string    `json:"upstream_addr,omitempty"`
+	SourceName     string    `json:"source_name"`
+	Parent         int       `json:"parent"` // Index of the enclosing hop in the trace, -1 for roots
+	Depth          int       `json:"depth"`
+}
+
+// TraceDetail holds the requests sharing a trace ID or request ID, ordered by start
+type TraceDetail struct {
+	TraceID    string      `json:"trace_id"`
+	StartedAt  time.Time   `json:"started_at"`
+	DurationMs float64     `json:"duration_ms"` // From the first start to the last end
+	Services   []string    `json:"services"`    // In order of their first hop
+	Hops       []*TraceHop `json:"hops"`
+	Truncated  bool        `json:"truncated"` // More than maxTraceHops requests, the first ones are returned
+}
+
+// ServiceDependency is a call from one service to another seen in traces
+type ServiceDependency struct {
+	Caller          string  `json:"caller"`
+	Callee          string  `json:"callee"`
+	Calls           int64   `json:"calls"`
+	ErrorCount      int64   `json:"error_count"`       // Failed calls
+	AvgResponseTime float64 `json:"avg_response_time"` // Of the callee hops
+	MaxResponseTime float64 `json:"max_response_time"`
+}
+
+// ServiceDependencies is the service dependency graph of the time range
+type ServiceDependencies struct {
+	Edges     []*ServiceDependency `json:"edges"`
+	Traces    int64                `json:"traces"`    // Traces spanning several services
+	Unlinked  int64                `json:"unlinked"`  // Hops no other hop of their trace encloses in time, besides the first
+	Truncated bool                 `json:"truncated"` // Only the most recent maxDependencyHops requests were read
+}
+
+// traceHopRow is a trace hop as scanned, before linking
+type traceHopRow struct {
+	ID             uint
+	Trace          string
+	Timestamp      time.Time
+	StartUTC       string
+	Service        string
+	Method         string
+	Host           string
+	Path           string
+	StatusCode     int
+	Failed         bool
+	ResponseTimeMs float64
+	UpstreamAddr   string
+	SourceName     string
+}
+
+// traceHopColumns selects traceHopRow, the failure condition being the first argument
+const traceHopColumns = `id, ` + traceExpression + ` as trace, timestamp, start_utc,
+	COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) as service,
+	method, host, path, status_code, CASE WHEN %s THEN 1 ELSE 0 END as failed,
+	response_time_ms, upstream_addr, source_name`
+
+// GetTrace returns the requests with a trace ID or request ID, whatever the time range
+// Each hop is linked to the latest hop that started before it and ended after it, which with one service calling
+// another is its caller. Returns nil when no request has the ID.
+func (r *statsRepo) GetTrace(id string) (*TraceDetail, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	var rows []*traceHopRow
+	err := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
+		Select(fmt.Sprintf(traceHopColumns, failureCond), failureArgs...).
+		Where("trace_id = ? OR request_id = ?", id, id).
+		Order("timestamp, id").
+		Limit(maxTraceHops + 1).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get trace", r.logger.Args("trace_id", id, "error", err))
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	detail := &TraceDetail{TraceID: id, Services: make([]string, 0)}
+	if len(rows) > maxTraceHops {
+		rows = rows[:maxTraceHops]
+		detail.Truncated = true
+	}
+	detail.Hops = linkTraceHops(rows)
+
+	var end time.Time
+	seen := make(map[string]bool)
+	for _, hop := range detail.Hops {
+		if !seen[hop.Service] {
+			seen[hop.Service] = true
+			detail.Services = append(detail.Services, hop.Service)
+		}
+		if hopEnd := hopEndTime(hop); hopEnd.After(end) {
+			end = hopEnd
+		}
+	}
+	detail.StartedAt = detail.Hops[0].StartedAt
+	detail.DurationMs = float64(end.Sub(detail.StartedAt)) / float64(time.Millisecond)
+	return detail, nil
+}
+
+// GetServiceDependencies returns the calls between services seen in traces of the time range, most calls first
+// Only traces with requests to at least two services are read. A hop enclosed in time by a hop of another service
+// is a call from that service; hops of the same service calling each other are not edges.
+func (r *statsRepo) GetServiceDependencies(limit int) (*ServiceDependencies, error) {
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	since := r.getTimeRange()
+	args := append(append([]interface{}{}, failureArgs...), since, since, maxDependencyHops+1)
+	var rows []*traceHopRow
+	err := r.db.WithContext(ctx).Raw(`SELECT * FROM (
+			SELECT `+fmt.Sprintf(traceHopColumns, failureCond)+`
+			FROM http_requests
+			WHERE timestamp > ? AND `+traceExpression+` IN (
+				SELECT `+traceExpression+` FROM http_requests
+				WHERE timestamp > ? AND (trace_id != '' OR request_id != '')
+				GROUP BY `+traceExpression+`
+				HAVING COUNT(DISTINCT COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)) > 1)
+			ORDER BY timestamp DESC
+			LIMIT ?)
+		ORDER BY trace, timestamp, id`, args...).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get service dependencies", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	dependencies := &ServiceDependencies{Edges: make([]*ServiceDependency, 0)}
+	if len(rows) > maxDependencyHops {
+		// Keep the most recent maxDependencyHops rows
+		rows = dropOldestRow(rows)
+		dependencies.Truncated = true
+	}
+
+	type edgeKey struct{ caller, callee string }
+	edges := make(map[edgeKey]*ServiceDependency)
+	totalTime := make(map[edgeKey]float64)
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].Trace == rows[start].Trace {
+			end++
+		}
+		hops := linkTraceHops(rows[start:end])
+		start = end
+		if len(hops) < 2 {
+			continue
+		}
+		dependencies.Traces++
+
+		for i, hop := range hops {
+			if hop.Parent < 0 {
+				if i > 0 {
+					dependencies.Unlinked++
+				}
+				continue
+			}
+			caller := hops[hop.Parent].Service
+			if caller == hop.Service {
+				continue
+			}
+			key := edgeKey{caller, hop.Service}
+			edge, ok := edges[key]
+			if !ok {
+				edge = &ServiceDependency{Caller: caller, Callee: hop.Service}
+				edges[key] = edge
+			}
+			edge.Calls++
+			if hop.Failed {
+				edge.ErrorCount++
+			}
+			totalTime[key] += hop.ResponseTimeMs
+			if hop.ResponseTimeMs > edge.MaxResponseTime {
+				edge.MaxResponseTime = hop.ResponseTimeMs
+			}
+		}
+	}
+
+	for key, edge := range edges {
+		edge.AvgResponseTime = totalTime[key] / float64(edge.Calls)
+		dependencies.Edges = append(dependencies.Edges, edge)
+	}
+	sort.Slice(dependencies.Edges, func(i, j int) bool {
+		a, b := dependencies.Edges[i], dependencies.Edges[j]
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		return a.Callee < b.Callee
+	})
+	if limit > 0 && len(dependencies.Edges) > limit {
+		dependencies.Edges = dependencies.Edges[:limit]
+	}
+	return dependencies, nil
+}
+
+// linkTraceHops orders the requests of one trace by start, longest first on ties, and links each to the latest
+// earlier hop enclosing it in time
+func linkTraceHops(rows []*traceHopRow) []*TraceHop {
+	hops := make([]*TraceHop, len(rows))
+	for i, row := range rows {
+		startedAt := row.Timestamp
+		if row.StartUTC != "" {
+			if parsed, err := time.Parse(time.RFC3339Nano, row.StartUTC); err == nil {
+				startedAt = parsed
+			}
+		}
+		hops[i] = &TraceHop{
+			ID:             row.ID,
+			StartedAt:      startedAt,
+			Service:        row.Service,
+			Method:         row.Method,
+			Host:           row.Host,
+			Path:           row.Path,
+			StatusCode:     row.StatusCode,
+			Failed:         row.Failed,
+			ResponseTimeMs: row.ResponseTimeMs,
+			SelfTimeMs:     row.ResponseTimeMs,
+			UpstreamAddr:   row.UpstreamAddr,
+			SourceName:     row.SourceName,
+			Parent:         -1,
+		}
+	}
+	sort.SliceStable(hops, func(i, j int) bool {
+		if !hops[i].StartedAt.Equal(hops[j].StartedAt) {
+			return hops[i].StartedAt.Before(hops[j].StartedAt)
+		}
+		return hops[i].ResponseTimeMs > hops[j].ResponseTimeMs
+	})
+
+	for j, hop := range hops {
+		end := hopEndTime(hop)
+		for i := j - 1; i >= 0; i-- {
+			if !hopEndTime(hops[i]).Before(end) {
+				hop.Parent = i
+				hop.Depth = hops[i].Depth + 1
+				hops[i].SelfTimeMs -= hop.ResponseTimeMs
+				break
+			}
+		}
+	}
+	for _, hop := range hops {
+		if hop.SelfTimeMs < 0 {
+			hop.SelfTimeMs = 0 // Overlapping children, e.g. parallel calls
+		}
+	}
+	return hops
+}
+
+// hopEndTime returns when a hop was answered
+func hopEndTime(hop *TraceHop) time.Time {
+	return hop.StartedAt.Add(time.Duration(hop.ResponseTimeMs * float64(time.Millisecond)))
+}
+
+// dropOldestRow removes the row with the oldest timestamp from rows ordered by trace
+func dropOldestRow(rows []*traceHopRow) []*traceHopRow {
+	oldest := 0
+	for i, row := range rows {
+		if row.Timestamp.Before(rows[oldest].Timestamp) {
+			oldest = i
+		}
+	}
+	return append(rows[:oldest], rows[oldest+1:]...)
+}
@@ -0,0 +1,92 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestTraces(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+	hash := 0
+	create := func(traceID, requestID, backend string, offsetMs, durationMs float64, status int) {
+		hash++
+		at := start.Add(time.Duration(offsetMs * float64(time.Millisecond)))
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: at, StartUTC: at.Format(time.RFC3339Nano), RequestHash: fmt.Sprint(hash),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/" + backend, BackendName: backend,
+			StatusCode: status, ResponseTimeMs: durationMs, TraceID: traceID, RequestID: requestID,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// t1: gateway (100ms) calls api (10-80ms) which calls db twice, api failing
+	create("t1", "", "gateway", 0, 100, 502)
+	create("t1", "", "api", 10, 70, 500)
+	create("t1", "", "db", 20, 10, 200)
+	create("t1", "", "db", 40, 20, 200)
+	// r2: correlated by request ID only, gateway calling api
+	create("", "r2", "gateway", 200, 50, 200)
+	create("", "r2", "api", 210, 30, 200)
+	// single-service and uncorrelated requests
+	create("t3", "", "gateway", 300, 5, 200)
+	create("", "", "api", 400, 5, 200)
+
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	trace, err := statsRepo.GetTrace("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace == nil || len(trace.Hops) != 4 || trace.DurationMs != 100 {
+		t.Fatalf("Expected 4 hops over 100ms, got %+v", trace)
+	}
+	if services := fmt.Sprint(trace.Services); services != "[gateway api db]" {
+		t.Errorf("Expected services in call order, got %s", services)
+	}
+	for i, expected := range []struct {
+		parent, depth int
+		selfTime      float64
+	}{{-1, 0, 30}, {0, 1, 40}, {1, 2, 10}, {1, 2, 20}} {
+		hop := trace.Hops[i]
+		if hop.Parent != expected.parent || hop.Depth != expected.depth || hop.SelfTimeMs != expected.selfTime {
+			t.Errorf("Hop %d (%s): expected parent %d, depth %d, self time %.0f, got %d, %d, %.0f",
+				i, hop.Service, expected.parent, expected.depth, expected.selfTime, hop.Parent, hop.Depth, hop.SelfTimeMs)
+		}
+	}
+	if !trace.Hops[1].Failed || trace.Hops[2].Failed {
+		t.Errorf("Expected only the failed hops marked, got %+v", trace.Hops)
+	}
+
+	if trace, err := statsRepo.GetTrace("r2"); err != nil || trace == nil || len(trace.Hops) != 2 {
+		t.Errorf("Expected the request ID to correlate 2 hops, got %+v (%v)", trace, err)
+	}
+	if trace, err := statsRepo.GetTrace("missing"); err != nil || trace != nil {
+		t.Errorf("Expected no trace, got %+v (%v)", trace, err)
+	}
+
+	dependencies, err := statsRepo.GetServiceDependencies(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dependencies.Traces != 2 || dependencies.Unlinked != 0 || len(dependencies.Edges) != 2 {
+		t.Fatalf("Expected 2 traces with 2 edges, got %+v", dependencies)
+	}
+	apiDB, gatewayAPI := dependencies.Edges[0], dependencies.Edges[1] // Same calls, by caller
+	if gatewayAPI.Caller != "gateway" || gatewayAPI.Callee != "api" || gatewayAPI.Calls != 2 || gatewayAPI.ErrorCount != 1 ||
+		gatewayAPI.AvgResponseTime != 50 || gatewayAPI.MaxResponseTime != 70 {
+		t.Errorf("Unexpected gateway -> api edge %+v", gatewayAPI)
+	}
+	if apiDB.Caller != "api" || apiDB.Callee != "db" || apiDB.Calls != 2 || apiDB.AvgResponseTime != 15 {
+		t.Errorf("Unexpected api -> db edge %+v", apiDB)
+	}
+}
@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/sketch"
+
+	"gorm.io/gorm"
+)
+
+// TrafficRollupName is the checkpoint name of the hourly traffic rollups
+const TrafficRollupName = "traffic"
+
+// TrafficRollupRow is one hourly traffic rollup as exported to BI tools
+// Latency columns cover the timed requests only; the digest is the base64 DDSketch of their response times,
+// rows can be merged into percentiles of any grouping by decoding it (internal/sketch).
+type TrafficRollupRow struct {
+	Hour          time.Time `json:"hour"`
+	Service       string    `json:"service"`
+	StatusClass   string    `json:"status_class"` // e.g. 5xx
+	Country       string    `json:"country"`
+	Requests      int64     `json:"requests"`
+	Bandwidth     int64     `json:"bandwidth"`
+	TimedRequests int64     `json:"timed_requests"`
+	AvgMs         float64   `json:"avg_ms"`
+	MinMs         float64   `json:"min_ms"`
+	MaxMs         float64   `json:"max_ms"`
+	P50Ms         float64   `json:"p50_ms"`
+	P95Ms         float64   `json:"p95_ms"`
+	P99Ms         float64   `json:"p99_ms"`
+	Digest        string    `json:"digest"`
+}
+
+// GetTrafficRollupsBuiltUntil returns the end of the last hour of the traffic rollups (zero before their first run)
+func (r *statsRepo) GetTrafficRollupsBuiltUntil() (time.Time, error) {
+	return r.rollupBuiltUntil(TrafficRollupName)
+}
+
+// StreamTrafficRollups calls write with the traffic rollups of the hours from from (included) to to (excluded),
+// ordered by hour, service, status class and country. Rows are read as they are written, without query timeout:
+// exports of long ranges can take longer than stats queries.
+// Rollups only cover the local requests seen by the last rollup run: hours holding requests of attached databases
+// (ATTACH_DATABASES), or requests stored since, are computed from the requests instead.
+func (r *statsRepo) StreamTrafficRollups(from time.Time, to time.Time, write func(*TrafficRollupRow) error) error {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	db := r.db.WithContext(ctx)
+	from, to = from.UTC(), to.UTC()
+
+	var checkpoint models.RollupCheckpoint
+	if err := db.Where("name = ?", TrafficRollupName).Limit(1).Find(&checkpoint).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to read traffic rollups", r.logger.Args("error", err))
+		return err
+	}
+	// Requests the last rollup run has not seen: attached databases number theirs above the local ones
+	missing := func(from time.Time, to time.Time) (bool, error) {
+		var pending []uint
+		err := db.Model(&models.HTTPRequest{}).
+			Where("id > ? AND timestamp >= ? AND timestamp < ?", checkpoint.LastID, from, to).
+			Limit(1).
+			Pluck("id", &pending).Error
+		return len(pending) > 0, err
+	}
+
+	stale, err := missing(from, to)
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to read traffic rollups", r.logger.Args("error", err))
+		return err
+	}
+	if !stale {
+		return r.streamStoredTrafficRollups(db, from, to, write)
+	}
+
+	// Hour by hour, so only the hours with requests missing from the rollups are computed
+	for hour := from; hour.Before(to); hour = hour.Add(time.Hour) {
+		if stale, err := missing(hour, hour.Add(time.Hour)); err != nil {
+			return err
+		} else if !stale {
+			if err := r.streamStoredTrafficRollups(db, hour, hour.Add(time.Hour), write); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rollups, err := ComputeTrafficRollups(db, hour)
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to compute traffic rollups", r.logger.Args("hour", hour, "error", err))
+			return err
+		}
+		for _, rollup := range rollups {
+			row, err := trafficRollupRow(rollup)
+			if err != nil {
+				return err
+			}
+			if err := write(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// streamStoredTrafficRollups calls write with the stored traffic rollups of the hours from from to to
+func (r *statsRepo) streamStoredTrafficRollups(db *gorm.DB, from time.Time, to time.Time, write func(*TrafficRollupRow) error) error {
+	rows, err := db.Model(&models.TrafficRollup{}).
+		Where("hour >= ? AND hour < ?", from, to).
+		Order("hour, service, status_class, country").
+		Rows()
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to read traffic rollups", r.logger.Args("error", err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rollup models.TrafficRollup
+		if err := db.ScanRows(rows, &rollup); err != nil {
+			return err
+		}
+		row, err := trafficRollupRow(&rollup)
+		if err != nil {
+			return err
+		}
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ComputeTrafficRollups computes the traffic rollups of the hour starting at hour from its requests,
+// ordered by service, status class and country
+func ComputeTrafficRollups(db *gorm.DB, hour time.Time) ([]*models.TrafficRollup, error) {
+	rows, err := db.Model(&models.HTTPRequest{}).
+		Select("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host), status_code / 100, COALESCE(geo_country, ''), response_size, response_time_ms").
+		Where("timestamp >= ? AND timestamp < ?", hour, hour.Add(time.Hour)).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	type trafficKey struct {
+		service     string
+		statusClass int
+		country     string
+	}
+	rollups := make(map[trafficKey]*models.TrafficRollup)
+	sketches := make(map[trafficKey]*sketch.DDSketch)
+	for rows.Next() {
+		var key trafficKey
+		var size int64
+		var responseTimeMs float64
+		if err := rows.Scan(&key.service, &key.statusClass, &key.country, &size, &responseTimeMs); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rollup, ok := rollups[key]
+		if !ok {
+			rollup = &models.TrafficRollup{Hour: hour, Service: key.service, StatusClass: key.statusClass, Country: key.country}
+			rollups[key] = rollup
+		}
+		rollup.Requests++
+		rollup.Bandwidth += size
+		if responseTimeMs > 0 {
+			sk, ok := sketches[key]
+			if !ok {
+				sk = sketch.New(sketch.DefaultRelativeAccuracy)
+				sketches[key] = sk
+			}
+			sk.Add(responseTimeMs)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	computed := make([]*models.TrafficRollup, 0, len(rollups))
+	for key, rollup := range rollups {
+		if sk, ok := sketches[key]; ok {
+			encoded, err := sk.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			rollup.TimedRequests = int64(sk.Count())
+			rollup.SumMs, rollup.MinMs, rollup.MaxMs = sk.Sum(), sk.Min(), sk.Max()
+			rollup.Sketch = encoded
+		}
+		computed = append(computed, rollup)
+	}
+	sort.Slice(computed, func(i, j int) bool {
+		a, b := computed[i], computed[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.StatusClass != b.StatusClass {
+			return a.StatusClass < b.StatusClass
+		}
+		return a.Country < b.Country
+	})
+	return computed, nil
+}
+
+// trafficRollupRow converts a rollup to its exported row
+func trafficRollupRow(rollup *models.TrafficRollup) (*TrafficRollupRow, error) {
+	row := &TrafficRollupRow{
+		Hour:          rollup.Hour.UTC(),
+		Service:       rollup.Service,
+		StatusClass:   fmt.Sprintf("%dxx", rollup.StatusClass),
+		Country:       rollup.Country,
+		Requests:      rollup.Requests,
+		Bandwidth:     rollup.Bandwidth,
+		TimedRequests: rollup.TimedRequests,
+		MinMs:         rollup.MinMs,
+		MaxMs:         rollup.MaxMs,
+	}
+	if rollup.TimedRequests == 0 || len(rollup.Sketch) == 0 {
+		return row, nil
+	}
+	digest, err := sketch.Decode(rollup.Sketch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid traffic rollup sketch (%s, %s): %w", rollup.Hour.UTC().Format(time.RFC3339), rollup.Service, err)
+	}
+	row.AvgMs = rollup.SumMs / float64(rollup.TimedRequests)
+	row.P50Ms, row.P95Ms, row.P99Ms = digest.Quantile(0.50), digest.Quantile(0.95), digest.Quantile(0.99)
+	row.Digest = base64.StdEncoding.EncodeToString(rollup.Sketch)
+	return row, nil
+}
@@ -0,0 +1,126 @@
+package repositories_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestStreamTrafficRollups(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// Requests of two complete hours, and of the current hour which isn't rolled up yet
+	currentHour := time.Now().UTC().Truncate(time.Hour)
+	hour := currentHour.Add(-2 * time.Hour)
+	insert := func(i int, timestamp time.Time, backend string, status int, country string, ms float64) {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: timestamp, RequestHash: fmt.Sprint(i), ClientIP: "10.0.0.1", Method: "GET",
+			Host: "example", Path: "/", BackendName: backend, StatusCode: status, ResponseSize: 100, ResponseTimeMs: ms, GeoCountry: country,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		insert(i, hour.Add(time.Duration(i)*time.Minute), "api", 200+i%2, "DE", float64(10+i))
+	}
+	insert(10, hour.Add(5*time.Minute), "api", 503, "DE", 0)
+	insert(11, hour.Add(time.Hour), "", 404, "", 5)
+	insert(12, currentHour, "api", 200, "DE", 5)
+
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	if builtUntil, err := repo.GetTrafficRollupsBuiltUntil(); err != nil || !builtUntil.IsZero() {
+		t.Fatalf("Expected no rollups before the first run, got %v (%v)", builtUntil, err)
+	}
+	if err := database.NewRollupService(db, log, time.Minute).Run(); err != nil {
+		t.Fatal(err)
+	}
+	builtUntil, err := repo.GetTrafficRollupsBuiltUntil()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !builtUntil.Equal(currentHour) {
+		t.Errorf("Expected rollups built until %v, got %v", currentHour, builtUntil)
+	}
+
+	var rows []*repositories.TrafficRollupRow
+	err = repo.StreamTrafficRollups(hour.Add(-time.Hour), builtUntil.Add(time.Hour), func(row *repositories.TrafficRollupRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rollups, got %d", len(rows))
+	}
+
+	api := rows[0]
+	if !api.Hour.Equal(hour) || api.Service != "api" || api.StatusClass != "2xx" || api.Country != "DE" || api.Requests != 10 || api.Bandwidth != 1000 {
+		t.Errorf("Unexpected 2xx rollup %+v", api)
+	}
+	if api.TimedRequests != 10 || api.MinMs != 10 || api.MaxMs != 19 || api.AvgMs != 14.5 || api.P50Ms < 13 || api.P50Ms > 15 {
+		t.Errorf("Unexpected 2xx latency %+v", api)
+	}
+	if digest, err := base64.StdEncoding.DecodeString(api.Digest); err != nil || len(digest) == 0 {
+		t.Errorf("Expected a base64 digest, got %q (%v)", api.Digest, err)
+	}
+
+	// Requests without response time count in the traffic but not in the latency
+	if failed := rows[1]; failed.StatusClass != "5xx" || failed.Requests != 1 || failed.TimedRequests != 0 || failed.Digest != "" {
+		t.Errorf("Unexpected 5xx rollup %+v", failed)
+	}
+	if missing := rows[2]; !missing.Hour.Equal(hour.Add(time.Hour)) || missing.Service != "example" || missing.StatusClass != "4xx" || missing.Country != "" {
+		t.Errorf("Unexpected 4xx rollup %+v", missing)
+	}
+}
+
+func TestStreamTrafficRollups_RequestsNotRolledUp(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	insert := func(request *models.HTTPRequest) {
+		request.SourceName, request.ClientIP, request.Method, request.Host, request.Path = "test", "10.0.0.1", "GET", "example", "/"
+		request.BackendName, request.StatusCode, request.ResponseSize, request.GeoCountry = "api", 200, 100, "DE"
+		if err := db.Create(request).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert(&models.HTTPRequest{Timestamp: hour, RequestHash: "local-1", ResponseTimeMs: 10})
+	insert(&models.HTTPRequest{Timestamp: hour.Add(time.Hour), RequestHash: "local-2", ResponseTimeMs: 20})
+	if err := database.NewRollupService(db, log, time.Minute).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A request of an attached database (IDs above the local ones) in the first hour only
+	insert(&models.HTTPRequest{ID: 1<<40 + 1, Timestamp: hour.Add(time.Minute), RequestHash: "web1-1", ResponseTimeMs: 30})
+
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+	var rows []*repositories.TrafficRollupRow
+	err := repo.StreamTrafficRollups(hour, hour.Add(2*time.Hour), func(row *repositories.TrafficRollupRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rollups, got %d", len(rows))
+	}
+	if first := rows[0]; !first.Hour.Equal(hour) || first.Requests != 2 || first.TimedRequests != 2 || first.MaxMs != 30 || first.Digest == "" {
+		t.Errorf("Expected the first hour computed with the attached request, got %+v", first)
+	}
+	if second := rows[1]; !second.Hour.Equal(hour.Add(time.Hour)) || second.Requests != 1 || second.MaxMs != 20 {
+		t.Errorf("Expected the stored rollup of the second hour, got %+v", second)
+	}
+}
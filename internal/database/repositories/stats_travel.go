@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+)
+
+// maxLoginLocations bounds the requests read per impossible travel check
+const maxLoginLocations = 50000
+
+// LoginLocation is where an authenticated request to a login path came from
+type LoginLocation struct {
+	ClientUser string    `json:"client_user"`
+	ClientIP   string    `json:"client_ip"`
+	Timestamp  time.Time `json:"timestamp"`
+	Host       string    `json:"host"`
+	Path       string    `json:"path"`
+	GeoCountry string    `json:"geo_country"`
+	GeoCity    string    `json:"geo_city"`
+	GeoLat     float64   `json:"geo_lat"`
+	GeoLon     float64   `json:"geo_lon"`
+}
+
+// GetLoginLocations returns the geolocated requests with a client_user to one of the path prefixes since a time,
+// ordered by user and time so consecutive locations of a user follow each other
+func (r *statsRepo) GetLoginLocations(pathPrefixes []string, since time.Time) ([]*LoginLocation, error) {
+	locations := make([]*LoginLocation, 0)
+	if len(pathPrefixes) == 0 {
+		return locations, nil
+	}
+
+	ctx, cancel := r.withTimeout()
+	defer cancel()
+
+	conditions := make([]string, len(pathPrefixes))
+	args := make([]interface{}, len(pathPrefixes))
+	for i, prefix := range pathPrefixes {
+		conditions[i] = `path LIKE ? ESCAPE '\'`
+		args[i] = escapeLike(prefix) + "%"
+	}
+
+	err := r.db.WithContext(ctx).Model(&models.HTTPRequest{}).
+		Select("client_user, client_ip, timestamp, host, path, geo_country, geo_city, geo_lat, geo_lon").
+		Where("timestamp > ? AND client_user != '' AND geo_country != ''", since).
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Order("client_user, timestamp").
+		Limit(maxLoginLocations).
+		Scan(&locations).Error
+	return locations, err
+}
@@ -0,0 +1,63 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestGetLoginLocations(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	now := time.Now()
+	for i, request := range []struct {
+		user, path, country string
+		age                 time.Duration
+	}{
+		{"bob", "/login", "DE", 10 * time.Minute},
+		{"alice", "/login", "FR", 20 * time.Minute},
+		{"alice", "/api/auth/token", "US", 5 * time.Minute},
+		{"alice", "/login_page", "US", time.Minute}, // Not a login path, "_" must not match as a wildcard
+		{"alice", "/dashboard", "US", time.Minute},  // Not a login path
+		{"", "/login", "US", time.Minute},           // Anonymous
+		{"carol", "/login", "", time.Minute},        // Not geolocated
+		{"dave", "/login", "DE", 3 * time.Hour},     // Outside the window
+	} {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-request.age), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", ClientUser: request.user, Method: "POST", Host: "example", Path: request.path,
+			StatusCode: 200, GeoCountry: request.country,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	locations, err := repo.GetLoginLocations([]string{"/login_"}, now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].Path != "/login_page" {
+		t.Errorf("Expected the LIKE wildcard to be escaped, got %+v", locations)
+	}
+
+	locations, err = repo.GetLoginLocations([]string{"/login", "/api/auth"}, now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, location := range locations {
+		got = append(got, location.ClientUser+":"+location.GeoCountry)
+	}
+	if fmt.Sprint(got) != "[alice:FR alice:US alice:US bob:DE]" {
+		t.Errorf("Expected logins ordered by user and time, got %v", got)
+	}
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+	"loglynx/internal/filter"
+)
+
+// UpstreamStats holds the requests one origin instance (upstream_addr) served for a service
+// Comparing the instances of a service shows uneven balancing, a failing instance or header bloat.
+type UpstreamStats struct {
+	UpstreamAddr            string  `json:"upstream_addr"`
+	BackendName             string  `json:"backend_name"` // Service of the instance (backend_name, else backend_url, else host)
+	Hits                    int64   `json:"hits"`
+	ShareOfService          float64 `json:"share_of_service"` // Percentage of the service's requests served by this instance
+	ErrorCount              int64   `json:"error_count"`
+	ErrorRate               float64 `json:"error_rate"` // Percentage of failed requests
+	AvgResponseTime         float64 `json:"avg_response_time"`
+	AvgUpstreamResponseTime float64 `json:"avg_upstream_response_time"`
+	AvgRequestHeaderBytes   float64 `json:"avg_request_header_bytes"`  // Over requests with a logged size
+	AvgResponseHeaderBytes  float64 `json:"avg_response_header_bytes"` // Over requests with a logged size
+	MaxResponseHeaderBytes  int64   `json:"max_response_header_bytes"`
+}
+
+// GetTopUpstreams returns the origin instances with the most requests, per service
+// Only requests with a logged upstream address (Traefik ServiceAddr, NGINX upstream_addr) are counted.
+func (r *statsRepo) GetTopUpstreams(limit int, filters []filter.Service, excludeIP *filter.ExcludeIP) ([]*UpstreamStats, error) {
+	failureCond, failureArgs := r.statusPolicy.FailureCondition()
+	query := r.db.Model(&models.HTTPRequest{}).
+		Select(`upstream_addr,
+			COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host) as backend_name,
+			COUNT(*) as hits,
+			COUNT(*) * 100.0 / SUM(COUNT(*)) OVER (PARTITION BY COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host)) as share_of_service,
+			COUNT(CASE WHEN `+failureCond+` THEN 1 END) as error_count,
+			COALESCE(AVG(CASE WHEN response_time_ms > 0 THEN response_time_ms END), 0) as avg_response_time,
+			COALESCE(AVG(CASE WHEN upstream_response_time_ms > 0 THEN upstream_response_time_ms END), 0) as avg_upstream_response_time,
+			COALESCE(AVG(NULLIF(request_header_bytes, 0)), 0) as avg_request_header_bytes,
+			COALESCE(AVG(NULLIF(response_header_bytes, 0)), 0) as avg_response_header_bytes,
+			COALESCE(MAX(response_header_bytes), 0) as max_response_header_bytes`, failureArgs...).
+		Where("timestamp > ?", r.getTimeRange()).
+		Where("upstream_addr != ''")
+
+	query = r.applyServiceFilters(query, filters)
+	query = r.applyExcludeOwnIP(query, excludeIP)
+
+	var upstreams []*UpstreamStats
+	query = r.applyTop(query.Group("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host), upstream_addr"), "backend_name, upstream_addr", limit)
+	if err := query.Scan(&upstreams).Error; err != nil {
+		r.logger.WithCaller().Error("Failed to get top upstreams", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	for _, upstream := range upstreams {
+		upstream.ErrorRate = routerErrorRate(upstream.ErrorCount, upstream.Hits)
+	}
+	return upstreams, nil
+}
@@ -0,0 +1,76 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestTopUpstreams(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	// Two instances of shop, one failing with bloated headers; requests without an address aren't counted
+	now := time.Now().UTC()
+	requests := []struct {
+		backend, addr       string
+		status              int
+		responseHeaderBytes int64
+		count               int
+	}{
+		{"shop@docker", "172.18.0.5:80", 200, 400, 3},
+		{"shop@docker", "172.18.0.6:80", 502, 9000, 1},
+		{"api@docker", "172.18.0.7:8080", 200, 0, 2},
+		{"api@docker", "", 200, 0, 5},
+	}
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: now, RequestHash: fmt.Sprint(request, i),
+				ClientIP: "10.0.0.1", Method: "GET", Host: "example.com", Path: "/", StatusCode: request.status, ResponseTimeMs: 10,
+				BackendName: request.backend, UpstreamAddr: request.addr, ResponseHeaderBytes: request.responseHeaderBytes,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	statsRepo := repositories.NewStatsRepository(db, log, 24, nil, nil, nil)
+
+	upstreams, err := statsRepo.GetTopUpstreams(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upstreams) != 3 {
+		t.Fatalf("Expected 3 upstreams, got %d", len(upstreams))
+	}
+	stats := make(map[string]*repositories.UpstreamStats)
+	for _, upstream := range upstreams {
+		stats[upstream.UpstreamAddr] = upstream
+	}
+	if healthy := stats["172.18.0.5:80"]; healthy == nil || healthy.BackendName != "shop@docker" || healthy.Hits != 3 || healthy.ShareOfService != 75 || healthy.ErrorCount != 0 || healthy.AvgResponseHeaderBytes != 400 {
+		t.Errorf("Unexpected stats for the healthy instance %+v", healthy)
+	}
+	if failing := stats["172.18.0.6:80"]; failing == nil || failing.Hits != 1 || failing.ShareOfService != 25 || failing.ErrorRate != 100 || failing.MaxResponseHeaderBytes != 9000 {
+		t.Errorf("Unexpected stats for the failing instance %+v", failing)
+	}
+	if api := stats["172.18.0.7:8080"]; api == nil || api.ShareOfService != 100 || api.AvgResponseHeaderBytes != 0 {
+		t.Errorf("Expected the sole api instance to get its whole traffic, got %+v", api)
+	}
+
+	backends, err := statsRepo.GetTopBackends(10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range backends {
+		if expected := map[string]int64{"shop@docker": 2, "api@docker": 1}[backend.BackendName]; backend.UpstreamAddrs != expected {
+			t.Errorf("Expected %d upstream addresses for %s, got %d", expected, backend.BackendName, backend.UpstreamAddrs)
+		}
+	}
+}
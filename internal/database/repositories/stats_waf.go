@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// WAFReport correlates WAF audit log events with the access log: what the WAF blocked, what it only detected,
+// and how much traffic of the same client IPs and paths it let through
+type WAFReport struct {
+	Summary  *WAFSummary        `json:"summary"`
+	IPs      []*WAFIPStats      `json:"ips"`
+	Paths    []*WAFPathStats    `json:"paths"`
+	Rules    []*WAFRuleStats    `json:"rules"`
+	Timeline []*WAFTimelineData `json:"timeline"`
+}
+
+// WAFSummary holds WAF event totals
+type WAFSummary struct {
+	Blocked   int64 `json:"blocked"`
+	Detected  int64 `json:"detected"` // Rules matched but the request went through
+	ClientIPs int64 `json:"client_ips"`
+}
+
+// WAFTraffic holds blocked and allowed requests of a client IP or path
+// Allowed requests are access log requests not answered with a 403, the status WAFs block with: blocked
+// requests are logged by the proxy too when the WAF runs as a middleware (Traefik plugin, ingress module).
+type WAFTraffic struct {
+	Blocked   int64     `json:"blocked"`
+	Detected  int64     `json:"detected"`
+	Allowed   int64     `json:"allowed"`
+	BlockRate float64   `json:"block_rate"`         // Percentage of blocked requests among blocked and allowed
+	LastSeen  time.Time `json:"last_seen" gorm:"-"` // Last WAF event
+
+	LastSeenText string `json:"-" gorm:"column:last_seen"` // MAX(timestamp) is returned as text by SQLite
+}
+
+// WAFIPStats holds the WAF events of one client IP
+type WAFIPStats struct {
+	ClientIP    string `json:"client_ip"`
+	LastMessage string `json:"last_message"` // Rule message of the last event
+	WAFTraffic
+}
+
+// WAFPathStats holds the WAF events of one path
+type WAFPathStats struct {
+	Path string `json:"path"`
+	WAFTraffic
+}
+
+// WAFRuleStats holds the events of one rule message
+type WAFRuleStats struct {
+	Message   string `json:"message"`
+	RuleIDs   string `json:"rule_ids"` // Rules of the last event with this message
+	Severity  string `json:"severity"`
+	Events    int64  `json:"events"`
+	Blocked   int64  `json:"blocked"`
+	ClientIPs int64  `json:"client_ips"`
+}
+
+// WAFTimelineData holds WAF events for one time bucket
+type WAFTimelineData struct {
+	Hour     string `json:"hour"`
+	Blocked  int64  `json:"blocked"`
+	Detected int64  `json:"detected"`
+}
+
+// wafAggregates counts blocked and detected events over the grouped WAF events
+const wafAggregates = `
+	SUM(CASE WHEN action = 'blocked' THEN 1 ELSE 0 END) as blocked,
+	SUM(CASE WHEN action = 'blocked' THEN 0 ELSE 1 END) as detected,
+	MAX(timestamp) as last_seen`
+
+// GetWAFReport returns WAF event totals, the client IPs, paths and rules with the most events, and a timeline
+// limit applies to client IPs, paths and rules, which are ordered by blocked requests.
+func (r *statsRepo) GetWAFReport(limit int) (*WAFReport, error) {
+	since := r.getTimeRange()
+	events := func() *gorm.DB {
+		return r.db.Model(&models.WAFEvent{}).Where("timestamp > ?", since)
+	}
+
+	report := &WAFReport{
+		Summary:  &WAFSummary{},
+		IPs:      make([]*WAFIPStats, 0),
+		Paths:    make([]*WAFPathStats, 0),
+		Rules:    make([]*WAFRuleStats, 0),
+		Timeline: make([]*WAFTimelineData, 0),
+	}
+
+	err := events().
+		Select(`SUM(CASE WHEN action = 'blocked' THEN 1 ELSE 0 END) as blocked,
+			SUM(CASE WHEN action = 'blocked' THEN 0 ELSE 1 END) as detected,
+			COUNT(DISTINCT client_ip) as client_ips`).
+		Scan(report.Summary).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get WAF summary", r.logger.Args("error", err))
+		return nil, err
+	}
+	if report.Summary.Blocked+report.Summary.Detected == 0 {
+		return report, nil
+	}
+
+	err = events().
+		Select("client_ip, " + wafAggregates + ", (SELECT w.message FROM waf_events w WHERE w.client_ip = waf_events.client_ip ORDER BY w.timestamp DESC LIMIT 1) as last_message").
+		Group("client_ip").
+		Order("blocked DESC, detected DESC").
+		Limit(limit).
+		Scan(&report.IPs).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get WAF client IPs", r.logger.Args("error", err))
+		return nil, err
+	}
+	ips := make([]string, len(report.IPs))
+	for i, ip := range report.IPs {
+		ips[i] = ip.ClientIP
+	}
+	allowed, err := r.allowedRequests("client_ip", ips, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range report.IPs {
+		ip.applyAllowed(allowed[ip.ClientIP])
+	}
+
+	err = events().
+		Select("path, " + wafAggregates).
+		Group("path").
+		Order("blocked DESC, detected DESC").
+		Limit(limit).
+		Scan(&report.Paths).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get WAF paths", r.logger.Args("error", err))
+		return nil, err
+	}
+	paths := make([]string, len(report.Paths))
+	for i, path := range report.Paths {
+		paths[i] = path.Path
+	}
+	allowed, err = r.allowedRequests("path", paths, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range report.Paths {
+		path.applyAllowed(allowed[path.Path])
+	}
+
+	err = events().
+		Select(`message, MAX(rule_ids) as rule_ids, MAX(severity) as severity, COUNT(*) as events,
+			SUM(CASE WHEN action = 'blocked' THEN 1 ELSE 0 END) as blocked, COUNT(DISTINCT client_ip) as client_ips`).
+		Group("message").
+		Order("blocked DESC, events DESC").
+		Limit(limit).
+		Scan(&report.Rules).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get WAF rules", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	err = events().
+		Select(timelineBucket(r.LookbackHours()) + ` as hour,
+			SUM(CASE WHEN action = 'blocked' THEN 1 ELSE 0 END) as blocked,
+			SUM(CASE WHEN action = 'blocked' THEN 0 ELSE 1 END) as detected`).
+		Group("hour").
+		Order("hour").
+		Scan(&report.Timeline).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to get WAF timeline", r.logger.Args("error", err))
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// allowedRequests counts the access log requests not answered with a 403 per value of column (client_ip or path)
+func (r *statsRepo) allowedRequests(column string, values []string, since time.Time) (map[string]int64, error) {
+	counts := make(map[string]int64, len(values))
+	if len(values) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		Value    string
+		Requests int64
+	}
+	err := r.db.Model(&models.HTTPRequest{}).
+		Select(column+" as value, COUNT(*) as requests").
+		Where("timestamp > ? AND status_code != 403", since).
+		Where(column+" IN ?", values).
+		Group(column).
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.WithCaller().Error("Failed to count requests allowed by the WAF", r.logger.Args("column", column, "error", err))
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.Value] = row.Requests
+	}
+	return counts, nil
+}
+
+// applyAllowed sets the allowed requests and the block rate
+func (t *WAFTraffic) applyAllowed(allowed int64) {
+	t.LastSeen = parseSQLiteTime(t.LastSeenText)
+	t.Allowed = allowed
+	if total := t.Blocked + t.Allowed; total > 0 {
+		t.BlockRate = float64(t.Blocked) / float64(total) * 100
+	}
+}
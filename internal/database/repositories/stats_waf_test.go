@@ -0,0 +1,70 @@
+package repositories_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestGetWAFReport(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+	if err := db.Create(&models.LogSource{Name: "waf-audit", Path: "audit.log", ParserType: "waf"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 10.0.0.1 was blocked twice on /login and got 3 requests through, one of the blocks is also in the access log
+	now := time.Now()
+	for i, status := range []int{200, 200, 302, 403} {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "POST", Host: "example", Path: "/login", StatusCode: status,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := []*models.WAFEvent{
+		{SourceName: "waf-audit", Timestamp: now.Add(-3 * time.Minute), EventHash: "a", ClientIP: "10.0.0.1", Path: "/login", StatusCode: 403, Action: models.WAFActionBlocked, RuleIDs: "942100", Message: "SQL Injection"},
+		{SourceName: "waf-audit", Timestamp: now.Add(-2 * time.Minute), EventHash: "b", ClientIP: "10.0.0.1", Path: "/login", StatusCode: 403, Action: models.WAFActionBlocked, RuleIDs: "942100", Message: "SQL Injection"},
+		{SourceName: "waf-audit", Timestamp: now.Add(-time.Minute), EventHash: "c", ClientIP: "10.0.0.2", Path: "/", StatusCode: 200, Action: models.WAFActionDetected, RuleIDs: "913100", Message: "Security scanner"},
+		{SourceName: "waf-audit", Timestamp: now.Add(-time.Minute), EventHash: "a", ClientIP: "10.0.0.1", Path: "/login", StatusCode: 403, Action: models.WAFActionBlocked}, // Duplicate
+	}
+	inserted, err := repositories.NewWAFEventRepository(db).CreateBatchWithPosition(events, &repositories.SourcePosition{SourceName: "waf-audit", Position: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var source models.LogSource
+	if err := db.First(&source, "name = ?", "waf-audit").Error; err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 3 || source.LastPosition != 42 {
+		t.Errorf("Expected 3 events inserted and the position saved, got %d and %d", inserted, source.LastPosition)
+	}
+
+	report, err := repositories.NewStatsRepository(db, log, 24, nil, nil, nil).GetWAFReport(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.Blocked != 2 || report.Summary.Detected != 1 || report.Summary.ClientIPs != 2 {
+		t.Errorf("Unexpected summary %+v", report.Summary)
+	}
+	if len(report.IPs) != 2 || report.IPs[0].ClientIP != "10.0.0.1" || report.IPs[0].Allowed != 3 || report.IPs[0].BlockRate != 40 {
+		t.Errorf("Expected 10.0.0.1 first with 3 allowed requests and a 40%% block rate, got %+v", report.IPs[0])
+	}
+	if report.IPs[0].LastMessage != "SQL Injection" || report.IPs[0].LastSeen.IsZero() {
+		t.Errorf("Expected the last message and time of 10.0.0.1, got %+v", report.IPs[0])
+	}
+	if len(report.Paths) != 2 || report.Paths[0].Path != "/login" || report.Paths[0].Blocked != 2 {
+		t.Errorf("Unexpected paths %+v", report.Paths)
+	}
+	if len(report.Rules) != 2 || report.Rules[0].Message != "SQL Injection" || report.Rules[0].Events != 2 || len(report.Timeline) == 0 {
+		t.Errorf("Unexpected rules %+v or timeline %+v", report.Rules, report.Timeline)
+	}
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/filter"
+)
+
+// DefaultFailureStatusCodes is the status code definition of a failed request when none is configured
+const DefaultFailureStatusCodes = "400-599"
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// StatusPolicy defines which status codes count as failures, globally and per service
+// Used for success rates so that e.g. 401/404 on an API can be treated as normal responses
+type StatusPolicy struct {
+	defaultRanges []StatusRange
+	services      []serviceStatusRanges // Ordered to keep generated SQL deterministic
+}
+
+type serviceStatusRanges struct {
+	name   string
+	ranges []StatusRange
+}
+
+// NewDefaultStatusPolicy returns a policy where every 4xx and 5xx response is a failure
+func NewDefaultStatusPolicy() *StatusPolicy {
+	return &StatusPolicy{
+		defaultRanges: []StatusRange{{Min: 400, Max: 599}},
+	}
+}
+
+// ParseStatusPolicy builds a policy from the global code list and per-service overrides
+// Code lists are comma-separated codes or ranges, e.g. "400-599" or "500-599,429"
+// Overrides are semicolon-separated "service:codes" pairs, e.g. "api:500-599,429;files:400-599"
+func ParseStatusPolicy(defaultCodes string, serviceCodes string) (*StatusPolicy, error) {
+	if strings.TrimSpace(defaultCodes) == "" {
+		defaultCodes = DefaultFailureStatusCodes
+	}
+
+	defaultRanges, err := parseStatusRanges(defaultCodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid failure status codes %q: %w", defaultCodes, err)
+	}
+
+	policy := &StatusPolicy{defaultRanges: defaultRanges}
+
+	for _, entry := range strings.Split(serviceCodes, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid service failure codes %q: expected service:codes", entry)
+		}
+
+		name := strings.TrimSpace(entry[:idx])
+		ranges, err := parseStatusRanges(entry[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid failure status codes for service %q: %w", name, err)
+		}
+
+		policy.services = append(policy.services, serviceStatusRanges{name: name, ranges: ranges})
+	}
+
+	return policy, nil
+}
+
+// parseStatusRanges parses a comma-separated list of codes and ranges
+func parseStatusRanges(codes string) ([]StatusRange, error) {
+	var ranges []StatusRange
+
+	for _, part := range strings.Split(codes, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		minCode, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		maxCode := minCode
+		if len(bounds) == 2 {
+			if maxCode, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("invalid status code %q", part)
+			}
+		}
+
+		if minCode < 100 || maxCode > 599 || minCode > maxCode {
+			return nil, fmt.Errorf("status code range %q out of bounds (100-599)", part)
+		}
+
+		ranges = append(ranges, StatusRange{Min: minCode, Max: maxCode})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no status codes specified")
+	}
+
+	return ranges, nil
+}
+
+// HasOverrides reports whether any per-service failure definition is configured
+func (p *StatusPolicy) HasOverrides() bool {
+	return p != nil && len(p.services) > 0
+}
+
+// IsFailure reports whether a response counts as failed for the service it belongs to
+// Services are matched like the "auto" service filter (backend_name > backend_url > host)
+func (p *StatusPolicy) IsFailure(backendName, backendURL, host string, statusCode int) bool {
+	if p == nil {
+		return statusCode >= 400
+	}
+
+	for _, svc := range p.services {
+		if matchesAutoService(svc.name, backendName, backendURL, host) {
+			return inStatusRanges(svc.ranges, statusCode)
+		}
+	}
+
+	return inStatusRanges(p.defaultRanges, statusCode)
+}
+
+// FailureCondition returns a SQL boolean expression (with args) matching failed requests
+func (p *StatusPolicy) FailureCondition() (string, []interface{}) {
+	if p == nil {
+		return "(status_code >= 400)", nil
+	}
+
+	if len(p.services) == 0 {
+		return rangesCondition(p.defaultRanges), nil
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(p.services)*3)
+
+	sb.WriteString("(CASE")
+	for _, svc := range p.services {
+		cond, condArgs := filter.Service{Name: svc.name}.Condition()
+		sb.WriteString(" WHEN " + cond + " THEN ")
+		sb.WriteString(rangesCondition(svc.ranges))
+		args = append(args, condArgs...)
+	}
+	sb.WriteString(" ELSE ")
+	sb.WriteString(rangesCondition(p.defaultRanges))
+	sb.WriteString(" END)")
+
+	return sb.String(), args
+}
+
+// rangesCondition builds a SQL condition for a set of status ranges
+// Codes are validated integers, so they are inlined rather than bound
+func rangesCondition(ranges []StatusRange) string {
+	conditions := make([]string, len(ranges))
+	for i, rng := range ranges {
+		if rng.Min == rng.Max {
+			conditions[i] = fmt.Sprintf("status_code = %d", rng.Min)
+		} else {
+			conditions[i] = fmt.Sprintf("status_code BETWEEN %d AND %d", rng.Min, rng.Max)
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// inStatusRanges checks whether a status code falls in any of the ranges
+func inStatusRanges(ranges []StatusRange, statusCode int) bool {
+	for _, rng := range ranges {
+		if statusCode >= rng.Min && statusCode <= rng.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAutoService mirrors the "auto" service filter used in SQL queries
+func matchesAutoService(name, backendName, backendURL, host string) bool {
+	if backendName != "" {
+		return backendName == name
+	}
+	if backendURL != "" {
+		return backendURL == name
+	}
+	return host == name
+}
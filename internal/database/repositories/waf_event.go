@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"loglynx/internal/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WAFEventRepository stores events read from WAF audit logs
+type WAFEventRepository interface {
+	// CreateBatchWithPosition inserts events, skipping duplicates, and saves the source position in the same
+	// transaction. Returns the number of events inserted.
+	CreateBatchWithPosition(events []*models.WAFEvent, position *SourcePosition) (int64, error)
+}
+
+type wafEventRepo struct {
+	db *gorm.DB
+}
+
+// NewWAFEventRepository creates a new WAF event repository
+func NewWAFEventRepository(db *gorm.DB) WAFEventRepository {
+	return &wafEventRepo{db: db}
+}
+
+func (r *wafEventRepo) CreateBatchWithPosition(events []*models.WAFEvent, position *SourcePosition) (int64, error) {
+	var inserted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(events) > 0 {
+			result := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "event_hash"}},
+				DoNothing: true,
+			}).Create(&events)
+			if result.Error != nil {
+				return result.Error
+			}
+			inserted = result.RowsAffected
+		}
+		if position != nil {
+			return updateTracking(tx, position)
+		}
+		return nil
+	})
+	return inserted, err
+}
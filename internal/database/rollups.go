@@ -0,0 +1,261 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/sketch"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// RollupService keeps hourly rollups up to date: response times (per service quantile sketches), the requests
+// per value of the filter fields and the traffic per service, status class and country exported to BI tools. Only complete hours are rolled up: stats queries read hours still in progress
+// from requests.
+type RollupService struct {
+	db       *gorm.DB
+	logger   *pterm.Logger
+	interval time.Duration
+	stopChan chan struct{}
+	running  bool
+}
+
+// NewRollupService creates a rollup service building rollups every interval
+func NewRollupService(db *gorm.DB, logger *pterm.Logger, interval time.Duration) *RollupService {
+	return &RollupService{
+		db:       db,
+		logger:   logger,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start builds missing rollups in the background, then keeps them up to date
+func (s *RollupService) Start() {
+	if s.interval <= 0 {
+		s.logger.Info("Response time rollups disabled, percentiles are computed from requests")
+		return
+	}
+	if s.running {
+		return
+	}
+	s.running = true
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Run(); err != nil {
+				s.logger.WithCaller().Error("Failed to update response time rollups", s.logger.Args("error", err))
+			}
+
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("Response time rollups enabled", s.logger.Args("interval", s.interval))
+}
+
+// Stop stops updating rollups
+func (s *RollupService) Stop() {
+	if !s.running {
+		return
+	}
+	close(s.stopChan)
+	s.running = false
+}
+
+// Run rebuilds the rollups of every complete hour that received requests since the last run
+func (s *RollupService) Run() error {
+	if err := s.runRollup(repositories.ResponseTimeRollupName, s.rebuildHour); err != nil {
+		return err
+	}
+	if err := s.runRollup(repositories.FieldValueRollupName, s.rebuildFieldValues); err != nil {
+		return err
+	}
+	return s.runRollup(repositories.TrafficRollupName, s.rebuildTraffic)
+}
+
+// runRollup rebuilds with rebuild the hours of the rollup name that changed since its checkpoint
+// Each rollup has its own checkpoint, a rollup added later is built for every stored hour on its first run.
+func (s *RollupService) runRollup(name string, rebuild func(hour time.Time) error) error {
+	var checkpoint models.RollupCheckpoint
+	err := s.db.Where("name = ?", name).
+		Attrs(models.RollupCheckpoint{Name: name}).
+		FirstOrInit(&checkpoint).Error
+	if err != nil {
+		return err
+	}
+
+	var maxID uint
+	if err := s.db.Model(&models.HTTPRequest{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return err
+	}
+
+	// The current hour is still receiving requests, it is rolled up once complete
+	currentHour := time.Now().UTC().Truncate(time.Hour)
+	if maxID <= checkpoint.LastID && !checkpoint.BuiltUntil.Before(currentHour) {
+		return nil
+	}
+
+	// Rebuild hours that received requests since the last run, and hours completed since then
+	hourExpr := "strftime('%Y-%m-%d %H:00:00', timestamp)"
+	var changed, completed []string
+	err = s.db.Model(&models.HTTPRequest{}).
+		Distinct(hourExpr).
+		Where("id > ? AND id <= ? AND timestamp < ?", checkpoint.LastID, maxID, currentHour).
+		Pluck(hourExpr, &changed).Error
+	if err != nil {
+		return err
+	}
+	err = s.db.Model(&models.HTTPRequest{}).
+		Distinct(hourExpr).
+		Where("timestamp >= ? AND timestamp < ?", checkpoint.BuiltUntil, currentHour).
+		Pluck(hourExpr, &completed).Error
+	if err != nil {
+		return err
+	}
+
+	hours := make(map[string]struct{}, len(changed)+len(completed))
+	for _, value := range append(changed, completed...) {
+		hours[value] = struct{}{}
+	}
+
+	started := time.Now()
+	for value := range hours {
+		hour, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.UTC)
+		if err != nil {
+			return fmt.Errorf("unexpected rollup hour %q: %w", value, err)
+		}
+		if err := rebuild(hour); err != nil {
+			return fmt.Errorf("failed to roll up %s: %w", value, err)
+		}
+	}
+
+	checkpoint.LastID = maxID
+	checkpoint.BuiltUntil = currentHour
+	checkpoint.UpdatedAt = time.Now()
+	if err := s.db.Save(&checkpoint).Error; err != nil {
+		return err
+	}
+
+	if len(hours) > 0 {
+		s.logger.Debug("Updated rollups",
+			s.logger.Args("rollup", name, "hours", len(hours), "last_id", maxID, "duration", time.Since(started).Round(time.Millisecond)))
+	}
+	return nil
+}
+
+// rebuildHour recomputes the rollups of one hour from its requests
+func (s *RollupService) rebuildHour(hour time.Time) error {
+	rows, err := s.db.Model(&models.HTTPRequest{}).
+		Select("COALESCE(NULLIF(backend_name, ''), NULLIF(backend_url, ''), host), response_time_ms").
+		Where("timestamp >= ? AND timestamp < ? AND response_time_ms > 0", hour, hour.Add(time.Hour)).
+		Rows()
+	if err != nil {
+		return err
+	}
+
+	sketches := make(map[string]*sketch.DDSketch)
+	for rows.Next() {
+		var service string
+		var responseTimeMs float64
+		if err := rows.Scan(&service, &responseTimeMs); err != nil {
+			rows.Close()
+			return err
+		}
+		sk, ok := sketches[service]
+		if !ok {
+			sk = sketch.New(sketch.DefaultRelativeAccuracy)
+			sketches[service] = sk
+		}
+		sk.Add(responseTimeMs)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rollups := make([]*models.ResponseTimeRollup, 0, len(sketches))
+	for service, sk := range sketches {
+		encoded, err := sk.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		rollups = append(rollups, &models.ResponseTimeRollup{
+			Hour:    hour,
+			Service: service,
+			Count:   int64(sk.Count()),
+			SumMs:   sk.Sum(),
+			MinMs:   sk.Min(),
+			MaxMs:   sk.Max(),
+			Sketch:  encoded,
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hour = ?", hour).Delete(&models.ResponseTimeRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rollups) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rollups, 100).Error
+	})
+}
+
+// rebuildFieldValues recomputes the requests per value of each filter field in one hour
+func (s *RollupService) rebuildFieldValues(hour time.Time) error {
+	var rollups []*models.FieldValueRollup
+	for _, field := range repositories.FieldValueFields {
+		var values []*models.FieldValueRollup
+		err := s.db.Model(&models.HTTPRequest{}).
+			Select(fmt.Sprintf("'%s' AS field, %s AS value, COUNT(*) AS count", field, field)).
+			Where("timestamp >= ? AND timestamp < ? AND "+field+" != ''", hour, hour.Add(time.Hour)).
+			Group(field).
+			Scan(&values).Error
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			value.Hour = hour
+		}
+		rollups = append(rollups, values...)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hour = ?", hour).Delete(&models.FieldValueRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rollups) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rollups, 100).Error
+	})
+}
+
+// rebuildTraffic recomputes the traffic rollups of one hour from its requests
+func (s *RollupService) rebuildTraffic(hour time.Time) error {
+	rollups, err := repositories.ComputeTrafficRollups(s.db, hour)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("hour = ?", hour).Delete(&models.TrafficRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rollups) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rollups, 100).Error
+	})
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// SearchIndexTable is the FTS5 table indexing path, referer and user agent of http_requests
+const SearchIndexTable = "http_requests_fts"
+
+// searchIndexSchema creates the full-text index and the triggers keeping it in sync with http_requests
+// The trigram tokenizer makes MATCH work for any substring of at least 3 characters, case-insensitive.
+// It is an external content table: the text is only stored once, in http_requests.
+var searchIndexSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS http_requests_fts USING fts5(
+		path, referer, user_agent,
+		content='http_requests', content_rowid='id', tokenize='trigram'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS http_requests_fts_insert AFTER INSERT ON http_requests BEGIN
+		INSERT INTO http_requests_fts(rowid, path, referer, user_agent) VALUES (new.id, new.path, new.referer, new.user_agent);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS http_requests_fts_delete AFTER DELETE ON http_requests BEGIN
+		INSERT INTO http_requests_fts(http_requests_fts, rowid, path, referer, user_agent) VALUES ('delete', old.id, old.path, old.referer, old.user_agent);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS http_requests_fts_update AFTER UPDATE OF path, referer, user_agent ON http_requests BEGIN
+		INSERT INTO http_requests_fts(http_requests_fts, rowid, path, referer, user_agent) VALUES ('delete', old.id, old.path, old.referer, old.user_agent);
+		INSERT INTO http_requests_fts(rowid, path, referer, user_agent) VALUES (new.id, new.path, new.referer, new.user_agent);
+	END`,
+}
+
+// EnsureSearchIndex creates (enabled) or removes (disabled) the full-text search index
+// Requests already in the database are indexed in the background. Without FTS5 support in the SQLite build
+// (build tag sqlite_fts5), search falls back to LIKE scans.
+func EnsureSearchIndex(db *gorm.DB, enabled bool, logger *pterm.Logger) {
+	if !enabled {
+		dropSearchIndex(db, logger)
+		return
+	}
+
+	var existing int64
+	db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", SearchIndexTable).Scan(&existing)
+	if existing > 0 {
+		// Created by a build with FTS5: without the module the triggers would make every insert fail
+		if err := db.Exec("SELECT rowid FROM http_requests_fts LIMIT 0").Error; err != nil {
+			logger.Warn("Full-text search index unusable, removing it", logger.Args("error", err))
+			dropSearchIndex(db, logger)
+			return
+		}
+		logger.Debug("Full-text search index verified")
+		return
+	}
+
+	for _, statement := range searchIndexSchema {
+		if err := db.Exec(statement).Error; err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				logger.Warn("SQLite was built without FTS5, request search will scan the table (build with -tags sqlite_fts5)")
+			} else {
+				logger.Warn("Failed to create full-text search index", logger.Args("error", err))
+			}
+			dropSearchIndex(db, logger)
+			return
+		}
+	}
+
+	var count int64
+	db.Table("http_requests").Count(&count)
+	if count == 0 {
+		logger.Debug("Full-text search index created")
+		return
+	}
+
+	// Index existing requests without delaying startup, new requests are indexed by the triggers
+	go func() {
+		logger.Info("🔎 Building full-text search index in background", logger.Args("requests", count))
+		startTime := time.Now()
+		if err := db.Exec("INSERT INTO http_requests_fts(http_requests_fts) VALUES ('rebuild')").Error; err != nil {
+			logger.Warn("Failed to build full-text search index", logger.Args("error", err))
+			return
+		}
+		logger.Info("✅ Full-text search index built", logger.Args("elapsed_seconds", time.Since(startTime).Seconds()))
+	}()
+}
+
+// dropSearchIndex removes the index and its triggers, so ingestion doesn't pay for an unused index
+func dropSearchIndex(db *gorm.DB, logger *pterm.Logger) {
+	statements := []string{
+		"DROP TRIGGER IF EXISTS http_requests_fts_insert",
+		"DROP TRIGGER IF EXISTS http_requests_fts_delete",
+		"DROP TRIGGER IF EXISTS http_requests_fts_update",
+		"DROP TABLE IF EXISTS http_requests_fts",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			// DROP TABLE of an FTS5 table fails without the module, the triggers are gone anyway
+			logger.Debug("Failed to drop full-text search index", logger.Args("error", err))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// EnsureSlowRequestIndex creates the slow requests partial index for the lowest slow threshold
+// The threshold is stored in the index definition: when the configuration changed, the index is dropped and
+// rebuilt in the background (queries scan the table meanwhile).
+func EnsureSlowRequestIndex(db *gorm.DB, policy *repositories.SlowPolicy, logger *pterm.Logger) {
+	var existing string
+	db.Raw("SELECT COALESCE(sql, '') FROM sqlite_master WHERE type = 'index' AND name = ?", repositories.SlowRequestIndex).Scan(&existing)
+
+	condition := policy.IndexCondition()
+	if existing != "" && strings.HasSuffix(strings.TrimSpace(existing), "WHERE "+condition) {
+		logger.Debug("Slow requests index verified", logger.Args("condition", condition))
+		return
+	}
+
+	if existing != "" {
+		logger.Info("Slow request threshold changed, rebuilding slow requests index", logger.Args("condition", condition))
+		if err := db.Exec("DROP INDEX IF EXISTS " + repositories.SlowRequestIndex).Error; err != nil {
+			logger.Warn("Failed to drop slow requests index", logger.Args("error", err))
+			return
+		}
+	}
+
+	var count int64
+	db.Table("http_requests").Count(&count)
+	if count == 0 {
+		if err := db.Exec(policy.IndexSQL()).Error; err != nil {
+			logger.Warn("Failed to create slow requests index", logger.Args("error", err))
+		}
+		return
+	}
+
+	// Building the index reads the whole table, don't delay startup
+	go func() {
+		startTime := time.Now()
+		if err := db.Exec(policy.IndexSQL()).Error; err != nil {
+			logger.Warn("Failed to create slow requests index", logger.Args("error", err))
+			return
+		}
+		logger.Info("✅ Slow requests index built",
+			logger.Args("condition", condition, "elapsed_seconds", time.Since(startTime).Seconds()))
+	}()
+}
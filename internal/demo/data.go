@@ -0,0 +1,193 @@
+package demo
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// service is a demo backend with its own host, latency and routes
+type service struct {
+	name         string
+	host         string
+	port         int
+	latencyMs    float64 // Median response time
+	routes       []route
+	routeWeights []float64
+}
+
+// route is a request template
+type route struct {
+	method      string
+	path        string
+	contentType string
+	size        int64 // Average response size
+	referer     string
+	errorRate   float64 // Share of 500 responses
+	notFound    float64 // Share of 404 responses
+	redirect    float64 // Share of 301/304 responses
+	authFailure float64 // Share of 401/403 responses
+}
+
+// status picks the status code of a request
+func (r route) status(rng *rand.Rand) int {
+	pick := rng.Float64()
+	switch {
+	case pick < r.errorRate:
+		return 500
+	case pick < r.errorRate+r.notFound:
+		return 404
+	case pick < r.errorRate+r.notFound+r.authFailure:
+		return 401 + 2*rng.IntN(2) // 401 or 403
+	case pick < r.errorRate+r.notFound+r.authFailure+r.redirect:
+		if r.method == "GET" && r.contentType != "application/json" {
+			return 304
+		}
+		return 301
+	case r.method == "POST":
+		return 201
+	case r.method == "DELETE":
+		return 204
+	}
+	return 200
+}
+
+func newService(name, host string, port int, latencyMs float64, routes []route, weights []float64) service {
+	return service{name: name, host: host, port: port, latencyMs: latencyMs, routes: routes, routeWeights: weights}
+}
+
+var services = []service{
+	newService("website", "www.example.com", 80, 25, []route{
+		{method: "GET", path: "/", contentType: "text/html", size: 48000, redirect: 0.1},
+		{method: "GET", path: "/blog", contentType: "text/html", size: 36000, referer: "https://www.google.com/", redirect: 0.1},
+		{method: "GET", path: "/blog/introducing-loglynx", contentType: "text/html", size: 52000, referer: "https://news.ycombinator.com/", redirect: 0.05},
+		{method: "GET", path: "/pricing", contentType: "text/html", size: 31000, referer: "https://www.example.com/"},
+		{method: "GET", path: "/static/app.js", contentType: "application/javascript", size: 240000, referer: "https://www.example.com/", redirect: 0.4},
+		{method: "GET", path: "/static/style.css", contentType: "text/css", size: 42000, referer: "https://www.example.com/", redirect: 0.4},
+		{method: "GET", path: "/images/hero.webp", contentType: "image/webp", size: 380000, referer: "https://www.example.com/", redirect: 0.3},
+		{method: "GET", path: "/old-page", contentType: "text/html", size: 200, notFound: 0.7},
+	}, []float64{20, 8, 5, 4, 15, 15, 10, 1}),
+	newService("api", "api.example.com", 8080, 60, []route{
+		{method: "GET", path: "/v1/products", contentType: "application/json", size: 12000, errorRate: 0.005},
+		{method: "GET", path: "/v1/products/42", contentType: "application/json", size: 2100, errorRate: 0.005, notFound: 0.03},
+		{method: "POST", path: "/v1/orders", contentType: "application/json", size: 900, errorRate: 0.02, authFailure: 0.03},
+		{method: "GET", path: "/v1/orders", contentType: "application/json", size: 6400, errorRate: 0.01, authFailure: 0.05},
+		{method: "DELETE", path: "/v1/cart/items/7", contentType: "application/json", size: 0, errorRate: 0.01},
+		{method: "POST", path: "/v1/auth/login", contentType: "application/json", size: 600, authFailure: 0.12},
+		{method: "GET", path: "/v1/health", contentType: "application/json", size: 40},
+	}, []float64{25, 15, 6, 8, 3, 5, 10}),
+	newService("shop", "shop.example.com", 3000, 140, []route{
+		{method: "GET", path: "/", contentType: "text/html", size: 64000, redirect: 0.05},
+		{method: "GET", path: "/products/lynx-hoodie", contentType: "text/html", size: 58000, referer: "https://shop.example.com/", notFound: 0.01},
+		{method: "GET", path: "/cart", contentType: "text/html", size: 22000, errorRate: 0.01},
+		{method: "POST", path: "/checkout", contentType: "text/html", size: 18000, errorRate: 0.03},
+		{method: "GET", path: "/assets/bundle.js", contentType: "application/javascript", size: 520000, redirect: 0.4},
+	}, []float64{12, 15, 6, 2, 10}),
+	newService("grafana", "grafana.example.com", 3000, 220, []route{
+		{method: "GET", path: "/d/overview", contentType: "text/html", size: 90000, authFailure: 0.05},
+		{method: "POST", path: "/api/ds/query", contentType: "application/json", size: 45000, errorRate: 0.01},
+		{method: "GET", path: "/api/live/ws", contentType: "application/json", size: 300},
+	}, []float64{3, 12, 2}),
+	newService("auth", "auth.example.com", 9000, 45, []route{
+		{method: "GET", path: "/login", contentType: "text/html", size: 14000},
+		{method: "POST", path: "/login", contentType: "text/html", size: 3000, authFailure: 0.15},
+		{method: "GET", path: "/.well-known/openid-configuration", contentType: "application/json", size: 1800},
+		{method: "POST", path: "/oauth/token", contentType: "application/json", size: 1200, authFailure: 0.04},
+	}, []float64{5, 5, 4, 6}),
+}
+
+// serviceWeights is the share of traffic of each service
+var serviceWeights = []float64{35, 30, 18, 7, 10}
+
+// scannerRoutes are probed by scanner clients, whatever the service
+var scannerRoutes = []route{
+	{method: "GET", path: "/wp-login.php", contentType: "text/html", size: 150, notFound: 1},
+	{method: "GET", path: "/.env", contentType: "text/html", size: 150, notFound: 1},
+	{method: "GET", path: "/phpmyadmin/", contentType: "text/html", size: 150, notFound: 1},
+	{method: "POST", path: "/xmlrpc.php", contentType: "text/html", size: 150, notFound: 0.8, authFailure: 0.2},
+	{method: "GET", path: "/.git/config", contentType: "text/html", size: 150, notFound: 0.9, authFailure: 0.1},
+}
+
+// country is a client location, with the network most of its clients come from
+type country struct {
+	code     string
+	city     string
+	lat, lon float64
+	eu       bool
+	asn      int
+	asnOrg   string
+	octet    int // First octet of generated IPv4 addresses
+}
+
+var countries = []country{
+	{"US", "New York", 40.71, -74.01, false, 7922, "Comcast Cable Communications", 73},
+	{"US", "San Francisco", 37.77, -122.42, false, 7018, "AT&T Services", 99},
+	{"DE", "Berlin", 52.52, 13.40, true, 3320, "Deutsche Telekom AG", 91},
+	{"FR", "Paris", 48.86, 2.35, true, 3215, "Orange S.A.", 90},
+	{"GB", "London", 51.51, -0.13, false, 2856, "British Telecommunications PLC", 86},
+	{"NL", "Amsterdam", 52.37, 4.90, true, 1136, "KPN B.V.", 77},
+	{"ES", "Madrid", 40.42, -3.70, true, 3352, "Telefonica de Espana", 83},
+	{"IT", "Milan", 45.46, 9.19, true, 3269, "Telecom Italia", 79},
+	{"BR", "Sao Paulo", -23.55, -46.63, false, 28573, "Claro S.A.", 177},
+	{"IN", "Mumbai", 19.08, 72.88, false, 55836, "Reliance Jio Infocomm", 49},
+	{"JP", "Tokyo", 35.68, 139.69, false, 2516, "KDDI Corporation", 106},
+	{"AU", "Sydney", -33.87, 151.21, false, 1221, "Telstra Corporation", 101},
+	{"CA", "Toronto", 43.65, -79.38, false, 577, "Bell Canada", 70},
+	{"CN", "Beijing", 39.90, 116.41, false, 4134, "Chinanet", 123},
+	{"RU", "Moscow", 55.76, 37.62, false, 12389, "Rostelecom", 95},
+}
+
+var countryWeights = []float64{18, 10, 14, 9, 9, 5, 4, 4, 5, 6, 4, 3, 4, 3, 2}
+
+// scannerCountry hosts the scanners, a cloud provider like most of them
+var scannerCountry = country{"US", "Ashburn", 39.04, -77.49, false, 14061, "DigitalOcean, LLC", 167}
+
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Safari/605.1.15",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (iPad; CPU OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+	"curl/8.5.0",
+}
+
+var userAgentWeights = []float64{30, 14, 18, 14, 7, 6, 3, 3, 3, 1, 1}
+
+var scannerUserAgents = []string{
+	"python-requests/2.31.0",
+	"Go-http-client/1.1",
+	"Mozilla/5.0 zgrab/0.x",
+}
+
+// client is a visitor reused across requests, so IPs, countries and user agents stay consistent
+type client struct {
+	ip        string
+	country   *country
+	lat, lon  float64
+	userAgent string
+	protocol  string
+	scanner   bool // Probes well-known admin and config paths
+}
+
+func (g *generator) newClient(scanner bool) *client {
+	c := &client{scanner: scanner, protocol: "HTTP/2.0"}
+	if scanner {
+		c.country = &scannerCountry
+		c.userAgent = scannerUserAgents[g.rng.IntN(len(scannerUserAgents))]
+		c.protocol = "HTTP/1.1"
+	} else {
+		c.country = &countries[weightedIndex(g.rng, countryWeights)]
+		c.userAgent = userAgents[weightedIndex(g.rng, userAgentWeights)]
+		if g.rng.Float64() < 0.15 {
+			c.protocol = "HTTP/1.1"
+		}
+	}
+	c.ip = fmt.Sprintf("%d.%d.%d.%d", c.country.octet, g.rng.IntN(256), g.rng.IntN(256), 1+g.rng.IntN(254))
+	c.lat = c.country.lat + (g.rng.Float64()-0.5)*0.2
+	c.lon = c.country.lon + (g.rng.Float64()-0.5)*0.2
+	return c
+}
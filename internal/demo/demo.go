@@ -0,0 +1,236 @@
+package demo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/parser/useragent"
+
+	"github.com/pterm/pterm"
+)
+
+// SourceName is the log source of generated requests
+// No log_sources row is created for it, so the ingestion coordinator never tries to read it.
+const SourceName = "demo"
+
+// batchSize is the number of generated requests stored at once
+const batchSize = 1000
+
+// Options controls the generated traffic
+type Options struct {
+	Days           int       // Days of traffic before End
+	RequestsPerDay int       // Average requests per day, spread over the day with a diurnal pattern
+	End            time.Time // Last generated timestamp, zero = now (truncated to the hour)
+	Seed           uint64    // Same seed and End generate the same requests
+}
+
+// DefaultOptions generates a week of traffic ending now
+func DefaultOptions() Options {
+	return Options{Days: 7, RequestsPerDay: 20000, Seed: 1}
+}
+
+// Seed generates the demo traffic into the database and returns the number of stored requests
+// Requests already stored (same options, same hour) are skipped as duplicates.
+func Seed(repo repositories.HTTPRequestRepository, opts Options, logger *pterm.Logger) (int64, error) {
+	var stored int64
+	err := Generate(opts, func(batch []*models.HTTPRequest) error {
+		result, err := repo.CreateBatch(batch)
+		if err != nil {
+			return err
+		}
+		stored += int64(result.Inserted)
+		logger.Debug("Demo requests stored", logger.Args("stored", stored))
+		return nil
+	})
+	return stored, err
+}
+
+// Generate builds the demo traffic in chronological batches
+func Generate(opts Options, fn func([]*models.HTTPRequest) error) error {
+	if opts.Days <= 0 || opts.RequestsPerDay <= 0 {
+		return fmt.Errorf("demo traffic needs positive days and requests per day, got %d and %d", opts.Days, opts.RequestsPerDay)
+	}
+	end := opts.End
+	if end.IsZero() {
+		end = time.Now().Truncate(time.Hour)
+	}
+
+	g := newGenerator(opts.Seed)
+	batch := make([]*models.HTTPRequest, 0, batchSize)
+	start := end.Add(-time.Duration(opts.Days) * 24 * time.Hour)
+
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		// Poisson-like variation around the diurnal average
+		expected := float64(opts.RequestsPerDay) / 24 * diurnalFactor(hour)
+		count := int(expected * (0.85 + 0.3*g.rng.Float64()))
+		burst := g.burstFor(hour)
+
+		offsets := make([]time.Duration, count)
+		for i := range offsets {
+			offsets[i] = time.Duration(g.rng.Int64N(int64(time.Hour)))
+		}
+		slices.Sort(offsets)
+
+		for _, offset := range offsets {
+			batch = append(batch, g.request(hour.Add(offset), burst))
+			if len(batch) == batchSize {
+				if err := fn(batch); err != nil {
+					return err
+				}
+				batch = make([]*models.HTTPRequest, 0, batchSize)
+			}
+		}
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// diurnalFactor scales the hourly average: quiet at night (around 04:00), busiest in the afternoon,
+// with less traffic on weekends. Factors average to about 1 over a week.
+func diurnalFactor(t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	factor := 0.25 + 1.5*(1-math.Cos(2*math.Pi*(hour-4)/24))/2
+	if weekday := t.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		factor *= 0.7
+	}
+	return factor
+}
+
+// burst is an error burst of one service: an outage returning 502/503 for part of an hour
+type burst struct {
+	service  *service
+	from, to time.Duration // Offsets within the hour
+}
+
+type generator struct {
+	rng     *rand.Rand
+	clients []*client
+	seq     int64 // Makes request hashes unique, generated requests can share all logged fields
+}
+
+func newGenerator(seed uint64) *generator {
+	g := &generator{rng: rand.New(rand.NewPCG(seed, seed^0x5eed))}
+	for i := 0; i < 1500; i++ {
+		g.clients = append(g.clients, g.newClient(false))
+	}
+	for i := 0; i < 15; i++ {
+		g.clients = append(g.clients, g.newClient(true))
+	}
+	return g
+}
+
+// burstFor returns the error burst of an hour, if any: about two outages a day
+func (g *generator) burstFor(hour time.Time) *burst {
+	if g.rng.Float64() >= 2.0/24 {
+		return nil
+	}
+	from := time.Duration(g.rng.Int64N(int64(40 * time.Minute)))
+	return &burst{
+		service: &services[g.rng.IntN(len(services))],
+		from:    from,
+		to:      from + 5*time.Minute + time.Duration(g.rng.Int64N(int64(15*time.Minute))),
+	}
+}
+
+// request generates one request at t
+func (g *generator) request(t time.Time, burst *burst) *models.HTTPRequest {
+	c := g.clients[g.rng.IntN(len(g.clients))]
+	svc := &services[weightedIndex(g.rng, serviceWeights)]
+	route := svc.routes[weightedIndex(g.rng, svc.routeWeights)]
+	if c.scanner {
+		route = scannerRoutes[g.rng.IntN(len(scannerRoutes))]
+	}
+
+	status := route.status(g.rng)
+	responseTime := svc.latencyMs * math.Exp(g.rng.NormFloat64()*0.6)
+	if burst != nil && burst.service.name == svc.name {
+		offset := t.Sub(t.Truncate(time.Hour))
+		if offset >= burst.from && offset < burst.to && g.rng.Float64() < 0.6 {
+			status = 502 + g.rng.IntN(2) // 502 or 503
+			responseTime = 5 + g.rng.Float64()*30
+			if g.rng.Float64() < 0.3 {
+				status, responseTime = 504, 30000
+			}
+		}
+	}
+	if g.rng.Float64() < 0.01 {
+		responseTime *= 10 // Occasional slow request
+	}
+
+	size := int64(0)
+	if status != 304 && status != 204 {
+		size = int64(float64(route.size) * (0.5 + g.rng.Float64()))
+	}
+
+	g.seq++
+	duration := int64(responseTime * float64(time.Millisecond))
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%d|%s|%s", SourceName, t.UnixNano(), g.seq, c.ip, route.path))
+
+	ua := useragent.Parse(c.userAgent)
+	return &models.HTTPRequest{
+		SourceName:             SourceName,
+		Timestamp:              t,
+		RequestHash:            fmt.Sprintf("%x", hash),
+		ClientIP:               c.ip,
+		ClientPort:             1024 + g.rng.IntN(64000),
+		Method:                 route.method,
+		Protocol:               c.protocol,
+		Host:                   svc.host,
+		Path:                   route.path,
+		RequestScheme:          "https",
+		StatusCode:             status,
+		ResponseSize:           size,
+		ResponseTimeMs:         responseTime,
+		ResponseContentType:    route.contentType,
+		Duration:               duration,
+		StartUTC:               t.UTC().Format(time.RFC3339Nano),
+		UpstreamResponseTimeMs: responseTime * 0.9,
+		UserAgent:              c.userAgent,
+		Referer:                route.referer,
+		Browser:                ua.Browser,
+		BrowserVersion:         ua.BrowserVersion,
+		OS:                     ua.OS,
+		OSVersion:              ua.OSVersion,
+		DeviceType:             ua.DeviceType,
+		BackendName:            svc.name + "@docker",
+		BackendURL:             fmt.Sprintf("http://%s:%d", svc.name, svc.port),
+		RouterName:             svc.name + "-secure@docker",
+		UpstreamStatus:         status,
+		UpstreamContentType:    route.contentType,
+		TLSVersion:             "1.3",
+		TLSCipher:              "TLS_AES_128_GCM_SHA256",
+		TLSServerName:          svc.host,
+		GeoCountry:             c.country.code,
+		GeoCity:                c.country.city,
+		GeoLat:                 c.lat,
+		GeoLon:                 c.lon,
+		GeoEU:                  c.country.eu,
+		ASN:                    c.country.asn,
+		ASNOrg:                 c.country.asnOrg,
+	}
+}
+
+// weightedIndex picks an index with probability proportional to its weight
+func weightedIndex(rng *rand.Rand, weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	pick := rng.Float64() * total
+	for i, w := range weights {
+		if pick < w {
+			return i
+		}
+		pick -= w
+	}
+	return len(weights) - 1
+}
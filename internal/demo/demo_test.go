@@ -0,0 +1,101 @@
+package demo
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestGenerate_Deterministic(t *testing.T) {
+	opts := Options{Days: 1, RequestsPerDay: 2000, End: time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), Seed: 7}
+
+	collect := func() []*models.HTTPRequest {
+		var requests []*models.HTTPRequest
+		if err := Generate(opts, func(batch []*models.HTTPRequest) error {
+			requests = append(requests, batch...)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return requests
+	}
+	first, second := collect(), collect()
+
+	if len(first) == 0 || len(first) != len(second) {
+		t.Fatalf("Expected the same number of requests, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].RequestHash != second[i].RequestHash {
+			t.Fatalf("Request %d differs between runs", i)
+		}
+	}
+
+	// Afternoon is busier than night, and timestamps stay within the day
+	var night, afternoon int
+	for _, request := range first {
+		if request.Timestamp.Before(opts.End.Add(-24*time.Hour)) || !request.Timestamp.Before(opts.End) {
+			t.Fatalf("Request outside the generated range: %v", request.Timestamp)
+		}
+		switch request.Timestamp.Hour() {
+		case 3, 4, 5:
+			night++
+		case 15, 16, 17:
+			afternoon++
+		}
+	}
+	if afternoon <= 2*night {
+		t.Errorf("Expected a diurnal pattern, got %d night and %d afternoon requests", night, afternoon)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelError)
+	db := fake.NewDB(t)
+
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+	opts := Options{Days: 2, RequestsPerDay: 3000, End: time.Now().Truncate(time.Hour), Seed: 1}
+	stored, err := Seed(repo, opts, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored < 4000 {
+		t.Fatalf("Expected about 6000 requests stored, got %d", stored)
+	}
+
+	// Seeding again with the same options stores nothing new
+	again, err := Seed(repo, opts, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != 0 {
+		t.Errorf("Expected generated requests to be deduplicated, got %d new", again)
+	}
+
+	stats := repositories.NewStatsRepository(db, log, 72, nil, nil, nil)
+	summary, err := stats.GetSummary(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalRequests != stored || summary.FailedRequests == 0 || summary.UniqueVisitors < 100 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	services, err := stats.GetServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) < len(serviceWeights) {
+		t.Errorf("Expected a service per demo backend, got %+v", services)
+	}
+	countries, err := stats.GetTopCountries(20, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(countries) < 10 {
+		t.Errorf("Expected traffic from many countries, got %d", len(countries))
+	}
+}
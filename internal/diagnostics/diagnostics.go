@@ -0,0 +1,330 @@
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"loglynx/internal/alerting"
+	"loglynx/internal/config"
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/enrichment"
+	"loglynx/internal/ingestion"
+	"loglynx/internal/parser/waf"
+	"loglynx/internal/transform"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pterm/pterm"
+)
+
+// geoIPMaxAge is the age after which a GeoIP database is reported as outdated
+// (MaxMind publishes GeoLite2 updates twice a week)
+const geoIPMaxAge = 30 * 24 * time.Hour
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn" // LogLynx runs, with a feature disabled or degraded
+	StatusFail Status = "fail" // LogLynx can't start, or ignores part of the configuration
+)
+
+// Check is one line of the diagnostics report
+type Check struct {
+	Category string // config, database, source, geoip
+	Name     string
+	Status   Status
+	Detail   string
+}
+
+// Report holds the checks of a diagnostics run
+type Report struct {
+	Checks []Check
+}
+
+// Run checks the configuration, the database file, the log sources and the GeoIP databases
+// sources are the registered log sources, the configured Traefik log is checked when there are none yet.
+// Nothing is written: the database check creates and removes a temporary file next to the database.
+func Run(cfg *config.Config, sources []*models.LogSource) *Report {
+	report := &Report{}
+	report.checkConfig(cfg)
+	report.checkDatabase(cfg.Database.Path, cfg.Server.HealthMinFreeDiskMB)
+	report.checkSources(cfg, sources)
+	report.checkGeoIP(cfg)
+	return report
+}
+
+// Count returns the number of checks with a status
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Print writes the checks as a table
+func (r *Report) Print() {
+	data := pterm.TableData{{"Status", "Check", "Name", "Detail"}}
+	for _, check := range r.Checks {
+		data = append(data, []string{statusLabel(check.Status), check.Category, check.Name, check.Detail})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// Log prints the table when a check didn't pass, a single line otherwise
+func (r *Report) Log(logger *pterm.Logger) {
+	failed, warnings := r.Count(StatusFail), r.Count(StatusWarn)
+	if failed == 0 && warnings == 0 {
+		logger.Info("Startup diagnostics passed", logger.Args("checks", len(r.Checks)))
+		return
+	}
+	r.Print()
+	logger.Warn("Startup diagnostics found problems, run \"loglynx check\" after fixing the configuration",
+		logger.Args("failed", failed, "warnings", warnings))
+}
+
+func (r *Report) add(category string, name string, status Status, detail string) {
+	r.Checks = append(r.Checks, Check{Category: category, Name: name, Status: status, Detail: detail})
+}
+
+// addError adds a passing check when err is nil, a check with the given status otherwise
+func (r *Report) addError(category string, name string, err error, status Status, detail string) {
+	if err != nil {
+		r.add(category, name, status, err.Error())
+		return
+	}
+	r.add(category, name, StatusPass, detail)
+}
+
+// checkConfig parses the settings that are only validated when the service using them starts
+func (r *Report) checkConfig(cfg *config.Config) {
+	_, err := repositories.ParseStatusPolicy(cfg.Analytics.FailureStatusCodes, cfg.Analytics.ServiceFailureStatusCodes)
+	r.addError("config", "FAILURE_STATUS_CODES", err, StatusFail, cfg.Analytics.FailureStatusCodes)
+
+	_, err = repositories.ParseSlowPolicy(cfg.Analytics.SlowRequestThresholdMs, cfg.Analytics.ServiceSlowRequestThresholds)
+	r.addError("config", "SLOW_REQUEST_THRESHOLD_MS", err, StatusFail, fmt.Sprintf("%gms", cfg.Analytics.SlowRequestThresholdMs))
+
+	_, err = repositories.ParseCostModel(cfg.Analytics.EgressCostPerGB, cfg.Analytics.EgressCostCurrency, cfg.Analytics.ServiceEgressCosts)
+	r.addError("config", "EGRESS_COST_PER_GB", err, StatusFail, fmt.Sprintf("%g %s", cfg.Analytics.EgressCostPerGB, cfg.Analytics.EgressCostCurrency))
+
+	_, err = repositories.ParseIndexProfile(cfg.Database.IndexProfile)
+	r.addError("config", "DB_INDEX_PROFILE", err, StatusFail, cfg.Database.IndexProfile)
+
+	if cfg.Database.AttachDatabases != "" {
+		attached, err := database.ParseAttachedDatabases(cfg.Database.AttachDatabases)
+		for _, attachedDB := range attached {
+			if _, statErr := os.Stat(attachedDB.Path); statErr != nil && err == nil {
+				err = fmt.Errorf("attached database %s: %w", attachedDB.Name, statErr)
+			}
+		}
+		r.addError("config", "ATTACH_DATABASES", err, StatusFail, fmt.Sprintf("%d databases", len(attached)))
+	}
+
+	_, err = ingestion.ParseDedupOptions(cfg.LogSources.DedupLineOffset, cfg.LogSources.ParserDedupLineOffset)
+	r.addError("config", "DEDUP_LINE_OFFSET", err, StatusFail, cfg.LogSources.DedupLineOffset)
+
+	_, err = time.Parse("15:04", cfg.Database.CleanupTime)
+	if err != nil {
+		err = fmt.Errorf("%q is not a HH:MM time, cleanup runs at 02:00", cfg.Database.CleanupTime)
+	}
+	r.addError("config", "DB_CLEANUP_TIME", err, StatusFail, cfg.Database.CleanupTime)
+
+	if cfg.Alerting.RulesFile != "" {
+		rules, err := alerting.LoadRules(cfg.Alerting.RulesFile)
+		r.addError("config", "ALERT_RULES_FILE", err, StatusFail, fmt.Sprintf("%d rules", len(rules)))
+	}
+
+	labels, err := ingestion.ParseLabelRules(cfg.LogSources.LabelRules)
+	r.addError("config", "LABEL_RULES", err, StatusFail, fmt.Sprintf("%d rules", labels.Len()))
+
+	if cfg.LogSources.TransformRulesFile != "" {
+		rules, err := transform.LoadRules(cfg.LogSources.TransformRulesFile)
+		r.addError("config", "TRANSFORM_RULES_FILE", err, StatusFail, fmt.Sprintf("%d rules", rules.Len()))
+	}
+}
+
+// checkDatabase checks the database directory and file can be written and the disk has room left
+func (r *Report) checkDatabase(path string, minFreeMB int) {
+	dir := filepath.Dir(path)
+	probe, err := os.CreateTemp(dir, ".loglynx-check-*")
+	if err != nil {
+		r.add("database", "directory", StatusFail, fmt.Sprintf("%s is not writable (WAL and backup files are created there): %v", dir, err))
+	} else {
+		probe.Close()
+		os.Remove(probe.Name())
+		r.add("database", "directory", StatusPass, dir)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		r.add("database", "file", StatusPass, path+" will be created")
+	case err != nil:
+		r.add("database", "file", StatusFail, fmt.Sprintf("%s is not writable: %v", path, err))
+	default:
+		file.Close()
+		r.add("database", "file", StatusPass, path)
+	}
+
+	free, _, err := database.DiskSpace(path)
+	if err != nil {
+		return // Not supported on this platform
+	}
+	detail := fmt.Sprintf("%d MB free", free/(1024*1024))
+	if minFreeMB > 0 && free < uint64(minFreeMB)*1024*1024 {
+		r.add("database", "disk space", StatusWarn, fmt.Sprintf("%s, below HEALTH_MIN_FREE_DISK_MB (%d MB)", detail, minFreeMB))
+		return
+	}
+	r.add("database", "disk space", StatusPass, detail)
+}
+
+// checkSources checks the files of the log sources and WAF audit logs can be read
+func (r *Report) checkSources(cfg *config.Config, sources []*models.LogSource) {
+	requestSources := 0
+	for _, source := range sources {
+		if source.ParserType == waf.ParserType {
+			continue // Checked below from WAF_LOG_PATHS, a removed path keeps its source
+		}
+		requestSources++
+		r.checkSourcePath(source.Name, source.Path)
+	}
+	if requestSources == 0 {
+		if cfg.LogSources.TraefikLogPath == "" {
+			r.add("source", "TRAEFIK_LOG_PATH", StatusWarn, "no log source registered or configured")
+		} else {
+			r.checkSourcePath("TRAEFIK_LOG_PATH", cfg.LogSources.TraefikLogPath)
+		}
+	}
+
+	for _, path := range ingestion.ParseWAFLogPaths(cfg.LogSources.WAFLogPaths) {
+		r.checkSourcePath(ingestion.WAFSourceName(path), path)
+	}
+}
+
+// checkSourcePath checks a log file, or the files matched by a glob pattern, can be opened for reading
+func (r *Report) checkSourcePath(name string, path string) {
+	if !strings.ContainsAny(path, "*?[") {
+		if err := checkReadable(path); err != nil {
+			r.add("source", name, StatusFail, err.Error())
+			return
+		}
+		r.add("source", name, StatusPass, path)
+		return
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		r.add("source", name, StatusFail, fmt.Sprintf("invalid pattern %s: %v", path, err))
+		return
+	}
+	if len(matches) == 0 {
+		r.add("source", name, StatusWarn, "no file matches "+path)
+		return
+	}
+	for _, match := range matches {
+		if err := checkReadable(match); err != nil {
+			r.add("source", name, StatusFail, err.Error())
+			return
+		}
+	}
+	r.add("source", name, StatusPass, fmt.Sprintf("%s (%d files)", path, len(matches)))
+}
+
+// checkReadable opens a regular file and reads its first byte
+func checkReadable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not a log file", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Read(make([]byte, 1)); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkGeoIP opens the configured GeoIP databases and reports their type and build date
+// A missing database only disables the enrichment it provides, so it is a warning.
+func (r *Report) checkGeoIP(cfg *config.Config) {
+	if !cfg.GeoIP.Enabled {
+		r.add("geoip", "GEOIP_ENABLED", StatusPass, "disabled")
+		return
+	}
+
+	providers, err := enrichment.ParseGeoIPProviders(cfg.GeoIP.Providers)
+	if err != nil {
+		r.add("geoip", "GEOIP_PROVIDERS", StatusFail, err.Error())
+		return
+	}
+	r.add("geoip", "GEOIP_PROVIDERS", StatusPass, strings.Join(providers, ", "))
+
+	type geoIPDatabase struct{ name, path string }
+	providerDatabases := map[string][]geoIPDatabase{
+		enrichment.ProviderMaxMind: {
+			{"GEOIP_CITY_DB", cfg.GeoIP.CityDBPath},
+			{"GEOIP_COUNTRY_DB", cfg.GeoIP.CountryDBPath},
+			{"GEOIP_ASN_DB", cfg.GeoIP.ASNDBPath},
+		},
+		enrichment.ProviderDBIP: {
+			{"DBIP_CITY_DB", cfg.GeoIP.DBIPCityDBPath},
+			{"DBIP_ASN_DB", cfg.GeoIP.DBIPASNDBPath},
+		},
+		enrichment.ProviderIPinfo:      {{"IPINFO_DB", cfg.GeoIP.IPinfoDBPath}},
+		enrichment.ProviderIP2Location: {{"IP2LOCATION_DB", cfg.GeoIP.IP2LocationDBPath}},
+	}
+
+	var databases []geoIPDatabase
+	for _, provider := range providers {
+		databases = append(databases, providerDatabases[provider]...)
+	}
+	for _, db := range databases {
+		if db.path == "" {
+			continue
+		}
+		reader, err := maxminddb.Open(db.path)
+		if err != nil {
+			r.add("geoip", db.name, StatusWarn, fmt.Sprintf("%s not available: %v", db.path, err))
+			continue
+		}
+		metadata := reader.Metadata
+		reader.Close()
+
+		built := time.Unix(int64(metadata.BuildEpoch), 0)
+		detail := fmt.Sprintf("%s, built %s", metadata.DatabaseType, built.Format("2006-01-02"))
+		if age := time.Since(built); age > geoIPMaxAge {
+			r.add("geoip", db.name, StatusWarn, fmt.Sprintf("%s (%d days old, update the database)", detail, int(age.Hours()/24)))
+			continue
+		}
+		r.add("geoip", db.name, StatusPass, detail)
+	}
+}
+
+// statusLabel colors a status for the table
+func statusLabel(status Status) string {
+	switch status {
+	case StatusPass:
+		return pterm.Green("PASS")
+	case StatusWarn:
+		return pterm.Yellow("WARN")
+	default:
+		return pterm.Red("FAIL")
+	}
+}
@@ -0,0 +1,96 @@
+package diagnostics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"loglynx/internal/config"
+	"loglynx/internal/database/models"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Database.Path = filepath.Join(t.TempDir(), "loglynx.db")
+	cfg.Database.IndexProfile = "analytics"
+	cfg.Database.CleanupTime = "02:00"
+	cfg.Analytics.FailureStatusCodes = "400-599"
+	cfg.Analytics.SlowRequestThresholdMs = 1000
+	cfg.Analytics.EgressCostPerGB = 0.09
+	cfg.Analytics.EgressCostCurrency = "USD"
+	cfg.LogSources.DedupLineOffset = "auto"
+	return cfg
+}
+
+func findCheck(t *testing.T, report *Report, category string, name string) Check {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Category == category && check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("No %s check named %s in %+v", category, name, report.Checks)
+	return Check{}
+}
+
+func TestRun_Sources(t *testing.T) {
+	dir := t.TempDir()
+	accessLog := filepath.Join(dir, "access.log")
+	if err := os.WriteFile(accessLog, []byte("line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t)
+	cfg.LogSources.WAFLogPaths = filepath.Join(dir, "audit.log")
+	report := Run(cfg, []*models.LogSource{
+		{Name: "traefik", Path: accessLog, ParserType: "traefik"},
+		{Name: "pods", Path: filepath.Join(dir, "pods", "*.log"), ParserType: "cri+traefik"},
+		{Name: "dir", Path: dir, ParserType: "traefik"},
+	})
+
+	if check := findCheck(t, report, "source", "traefik"); check.Status != StatusPass {
+		t.Errorf("Expected readable file to pass, got %+v", check)
+	}
+	if check := findCheck(t, report, "source", "pods"); check.Status != StatusWarn {
+		t.Errorf("Expected pattern without matches to warn, got %+v", check)
+	}
+	if check := findCheck(t, report, "source", "dir"); check.Status != StatusFail {
+		t.Errorf("Expected directory to fail, got %+v", check)
+	}
+	if check := findCheck(t, report, "source", "waf-audit"); check.Status != StatusFail {
+		t.Errorf("Expected missing WAF audit log to fail, got %+v", check)
+	}
+}
+
+func TestRun_ConfiguredTraefikLog(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.LogSources.TraefikLogPath = filepath.Join(t.TempDir(), "missing.log")
+
+	report := Run(cfg, nil)
+	if check := findCheck(t, report, "source", "TRAEFIK_LOG_PATH"); check.Status != StatusFail {
+		t.Errorf("Expected missing configured log to fail, got %+v", check)
+	}
+	if check := findCheck(t, report, "database", "file"); check.Status != StatusPass {
+		t.Errorf("Expected new database file to pass, got %+v", check)
+	}
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Analytics.FailureStatusCodes = "abc"
+	cfg.Database.CleanupTime = "25:00"
+
+	report := Run(cfg, nil)
+	for _, name := range []string{"FAILURE_STATUS_CODES", "DB_CLEANUP_TIME"} {
+		if check := findCheck(t, report, "config", name); check.Status != StatusFail {
+			t.Errorf("Expected %s to fail, got %+v", name, check)
+		}
+	}
+	if check := findCheck(t, report, "config", "DB_INDEX_PROFILE"); check.Status != StatusPass {
+		t.Errorf("Expected DB_INDEX_PROFILE to pass, got %+v", check)
+	}
+	if failed := report.Count(StatusFail); failed < 2 {
+		t.Errorf("Expected at least 2 failed checks, got %d", failed)
+	}
+}
@@ -0,0 +1,114 @@
+package discovery
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/parser/apache"
+
+	"github.com/pterm/pterm"
+)
+
+// apacheLogPaths are the access logs of the stock Apache packages (Debian/Ubuntu, then RHEL/Fedora)
+// Debian logs requests to the default site in access.log and the other virtual hosts in other_vhosts_access.log.
+var apacheLogPaths = []string{
+	"/var/log/apache2/access.log",
+	"/var/log/apache2/other_vhosts_access.log",
+	"/var/log/httpd/access_log",
+}
+
+// ApacheDetector registers the Apache httpd access logs, APACHE_LOG_PATH or the stock paths
+type ApacheDetector struct {
+	logger         *pterm.Logger
+	configuredPath string
+	autoDiscover   bool
+	format         string
+}
+
+func NewApacheDetector(logger *pterm.Logger) ServiceDetector {
+	return &ApacheDetector{
+		logger:         logger,
+		configuredPath: os.Getenv("APACHE_LOG_PATH"),
+		autoDiscover:   os.Getenv("LOG_AUTO_DISCOVER") != "false",
+		format:         os.Getenv("APACHE_LOG_FORMAT"),
+	}
+}
+
+func (d *ApacheDetector) Name() string {
+	return apache.ParserType
+}
+
+func (d *ApacheDetector) Detect() ([]*models.LogSource, error) {
+	sources := []*models.LogSource{}
+
+	paths := apacheLogPaths
+	if d.configuredPath != "" {
+		paths = []string{d.configuredPath}
+	} else if !d.autoDiscover {
+		d.logger.Trace("Apache discovery disabled", d.logger.Args("LOG_AUTO_DISCOVER", false))
+		return sources, nil
+	}
+	d.logger.Trace("Detecting Apache log sources...", d.logger.Args("paths", strings.Join(paths, ",")))
+
+	// Lines are checked with the configured format, like the parser reading them
+	var formats []string
+	if d.format != "" {
+		formats = []string{d.format}
+	}
+	parser, err := apache.NewParser(formats, d.logger)
+	if err != nil {
+		d.logger.Warn("Invalid APACHE_LOG_FORMAT, checking logs with the stock formats", d.logger.Args("error", err))
+		parser, _ = apache.NewParser(nil, d.logger)
+	}
+
+	for _, path := range paths {
+		fileInfo, err := os.Stat(path)
+		if err != nil || fileInfo.IsDir() {
+			d.logger.Trace("File not accessible", d.logger.Args("path", path, "error", err))
+			continue
+		}
+
+		// An empty log at a stock path is Apache's own, it is followed until requests come in
+		line, err := firstLine(path)
+		if err != nil {
+			d.logger.WithCaller().Warn("Apache access log not readable", d.logger.Args("path", path, "error", err))
+			continue
+		}
+		if line != "" && !parser.CanParse(line) {
+			d.logger.WithCaller().Warn("Format invalid - not an Apache access log (set APACHE_LOG_FORMAT to its LogFormat)",
+				d.logger.Args("path", path))
+			continue
+		}
+
+		name := "apache-" + strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		d.logger.Info("✓ Apache log source detected", d.logger.Args("name", name, "path", path))
+		sources = append(sources, &models.LogSource{
+			Name:       name,
+			Path:       path,
+			ParserType: apache.ParserType,
+		})
+	}
+
+	if len(sources) == 0 && d.configuredPath != "" {
+		d.logger.Warn("No valid Apache log source found at configured path",
+			d.logger.Args("APACHE_LOG_PATH", d.configuredPath))
+	}
+
+	return sources, nil
+}
+
+// firstLine returns the first line of a file, empty for an empty file
+func firstLine(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan()
+	return scanner.Text(), scanner.Err()
+}
@@ -3,6 +3,7 @@ package discovery
 import (
 	"loglynx/internal/database/models"
 	"loglynx/internal/database/repositories"
+	"loglynx/internal/parser/waf"
 
 	"github.com/pterm/pterm"
 )
@@ -12,6 +13,13 @@ type ServiceDetector interface {
     Detect() ([]*models.LogSource, error)
 }
 
+// ContinuousDetector is a detector whose sources come and go at runtime (e.g. Kubernetes pods)
+// It runs on every discovery pass, and only sources with a new name are registered
+type ContinuousDetector interface {
+    ServiceDetector
+    Continuous() bool
+}
+
 type Engine struct {
     repo      repositories.LogSourceRepository
     detectors []ServiceDetector
@@ -22,6 +30,10 @@ func NewEngine(repo repositories.LogSourceRepository, logger *pterm.Logger) *Eng
         repo: repo,
         detectors: []ServiceDetector{
             NewTraefikDetector(logger),
+            NewIISDetector(logger),
+            NewApacheDetector(logger),
+            NewHAProxyDetector(logger),
+            NewKubernetesDetector(logger),
         },
     }
 }
@@ -33,9 +45,9 @@ func (e *Engine) Run(logger *pterm.Logger) error {
         return err
     }
     
-    if len(existing) > 0 {
+    if requestSourceCount(existing) > 0 {
 	    logger.Trace("Discovery is not needed.")
-        return nil
+        return e.runContinuous(existing, logger)
     }
 
     logger.Debug("Starting discovery...")
@@ -61,4 +73,50 @@ func (e *Engine) Run(logger *pterm.Logger) error {
 
     logger.Debug("Discovery completed")
     return nil
-}
\ No newline at end of file
+}
+
+// requestSourceCount counts the sources of the request pipeline, WAF audit log sources don't replace discovery
+func requestSourceCount(sources []*models.LogSource) int {
+    count := 0
+    for _, source := range sources {
+        if source.ParserType != waf.ParserType {
+            count++
+        }
+    }
+    return count
+}
+
+// runContinuous registers sources of continuous detectors that aren't known yet
+func (e *Engine) runContinuous(existing []*models.LogSource, logger *pterm.Logger) error {
+    known := make(map[string]bool, len(existing))
+    for _, source := range existing {
+        known[source.Name] = true
+    }
+
+    for _, detector := range e.detectors {
+        continuous, ok := detector.(ContinuousDetector)
+        if !ok || !continuous.Continuous() {
+            continue
+        }
+
+        sources, err := detector.Detect()
+        if err != nil {
+            logger.WithCaller().Warn("Detection failed,", logger.Args("detector", detector.Name(), "error", err))
+            continue
+        }
+
+        for _, source := range sources {
+            if known[source.Name] {
+                continue
+            }
+            if err := e.repo.Create(source); err != nil {
+                logger.WithCaller().Error("Detection failed,", logger.Args("detector", source.Name, "error", err))
+            } else {
+                known[source.Name] = true
+                logger.Info("Registered new log source.", logger.Args("Name", source.Name, "Path", source.Path))
+            }
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/parser/haproxy"
+
+	"github.com/pterm/pterm"
+)
+
+// haproxyLogPath is where the rsyslog configuration of the Debian and Ubuntu packages writes the HAProxy log
+const haproxyLogPath = "/var/log/haproxy.log"
+
+// haproxyCheckedLines is how many lines are read to validate the format, the log starts with startup messages
+const haproxyCheckedLines = 100
+
+// HAProxyDetector registers the HAProxy log, HAPROXY_LOG_PATH or the stock rsyslog path
+type HAProxyDetector struct {
+	logger         *pterm.Logger
+	configuredPath string
+	autoDiscover   bool
+}
+
+func NewHAProxyDetector(logger *pterm.Logger) ServiceDetector {
+	return &HAProxyDetector{
+		logger:         logger,
+		configuredPath: os.Getenv("HAPROXY_LOG_PATH"),
+		autoDiscover:   os.Getenv("LOG_AUTO_DISCOVER") != "false",
+	}
+}
+
+func (d *HAProxyDetector) Name() string {
+	return haproxy.ParserType
+}
+
+func (d *HAProxyDetector) Detect() ([]*models.LogSource, error) {
+	sources := []*models.LogSource{}
+
+	path := d.configuredPath
+	if path == "" {
+		if !d.autoDiscover {
+			d.logger.Trace("HAProxy discovery disabled", d.logger.Args("LOG_AUTO_DISCOVER", false))
+			return sources, nil
+		}
+		path = haproxyLogPath
+	}
+	d.logger.Trace("Detecting HAProxy log source...", d.logger.Args("path", path))
+
+	if fileInfo, err := os.Stat(path); err != nil || fileInfo.IsDir() {
+		d.logger.Trace("File not accessible", d.logger.Args("path", path, "error", err))
+		if d.configuredPath != "" {
+			d.logger.Warn("No valid HAProxy log source found at configured path",
+				d.logger.Args("HAPROXY_LOG_PATH", d.configuredPath))
+		}
+		return sources, nil
+	}
+
+	valid, err := isHAProxyHTTPLog(path, haproxy.NewParser(d.logger))
+	if err != nil {
+		d.logger.WithCaller().Warn("HAProxy log not readable", d.logger.Args("path", path, "error", err))
+		return sources, nil
+	}
+	if !valid {
+		d.logger.WithCaller().Warn("Format invalid - not an HAProxy HTTP log (enable option httplog)",
+			d.logger.Args("path", path))
+		return sources, nil
+	}
+
+	name := "haproxy"
+	if base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)); base != "haproxy" {
+		name += "-" + base
+	}
+	d.logger.Info("✓ HAProxy log source detected", d.logger.Args("name", name, "path", path))
+	sources = append(sources, &models.LogSource{
+		Name:       name,
+		Path:       path,
+		ParserType: haproxy.ParserType,
+	})
+	return sources, nil
+}
+
+// isHAProxyHTTPLog checks the first lines of a log: one HTTP log line is enough, a log with only startup
+// messages (no request yet) is accepted, client logs in another format (TCP, custom log-format) aren't
+func isHAProxyHTTPLog(path string, parser *haproxy.Parser) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 0; i < haproxyCheckedLines && scanner.Scan(); i++ {
+		line := scanner.Text()
+		if parser.CanParse(line) {
+			return true, nil
+		}
+		if _, err := parser.Parse(line); !errors.Is(err, haproxy.ErrNotHTTPLog) {
+			return false, nil
+		}
+	}
+	return true, scanner.Err()
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+)
+
+// IISDetector registers one source per IIS site found under the IIS log root
+// Sites write daily files (u_ex251015.log), so each source follows the u_ex*.log pattern
+type IISDetector struct {
+	logger  *pterm.Logger
+	logDir  string
+	enabled bool
+}
+
+func NewIISDetector(logger *pterm.Logger) ServiceDetector {
+	logDir := os.Getenv("IIS_LOG_DIR")
+	if logDir == "" {
+		logDir = `C:\inetpub\logs\LogFiles`
+	}
+
+	return &IISDetector{
+		logger:  logger,
+		logDir:  logDir,
+		enabled: os.Getenv("IIS_LOG_DISCOVERY") == "true",
+	}
+}
+
+func (d *IISDetector) Name() string {
+	return "iis"
+}
+
+func (d *IISDetector) Detect() ([]*models.LogSource, error) {
+	sources := []*models.LogSource{}
+	if !d.enabled {
+		d.logger.Trace("IIS discovery disabled", d.logger.Args("IIS_LOG_DISCOVERY", false))
+		return sources, nil
+	}
+
+	d.logger.Trace("Detecting IIS log sources...", d.logger.Args("dir", d.logDir))
+
+	siteDirs, err := filepath.Glob(filepath.Join(d.logDir, "W3SVC*"))
+	if err != nil {
+		return sources, err
+	}
+
+	for _, siteDir := range siteDirs {
+		if info, err := os.Stat(siteDir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		pattern := filepath.Join(siteDir, "u_ex*.log")
+		if matches, _ := filepath.Glob(pattern); len(matches) == 0 {
+			d.logger.Trace("No W3C log files in site directory", d.logger.Args("dir", siteDir))
+			continue
+		}
+
+		site := filepath.Base(siteDir)
+		d.logger.Info("✓ IIS log source detected", d.logger.Args("site", site, "pattern", pattern))
+		sources = append(sources, &models.LogSource{
+			Name:       "iis-" + strings.ToLower(site),
+			Path:       pattern,
+			ParserType: "iis",
+		})
+	}
+
+	if len(sources) == 0 {
+		d.logger.Warn("No IIS log sources found",
+			d.logger.Args("IIS_LOG_DIR", d.logDir, "hint", "IIS must log in W3C format (u_ex*.log files)"))
+	}
+
+	return sources, nil
+}
@@ -0,0 +1,239 @@
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+)
+
+// In-cluster service account files mounted into every pod
+const (
+	serviceAccountDir   = "/var/run/secrets/kubernetes.io/serviceaccount"
+	serviceAccountToken = serviceAccountDir + "/token"
+	serviceAccountCA    = serviceAccountDir + "/ca.crt"
+)
+
+// defaultIngressSelectors match the official Traefik and ingress-nginx Helm charts
+const defaultIngressSelectors = "app.kubernetes.io/name=traefik;app.kubernetes.io/name=ingress-nginx"
+
+// KubernetesDetector finds ingress controller pods through the Kubernetes API and registers
+// their container log files (kubelet layout under /var/log/pods) as sources.
+// Meant for a DaemonSet: only pods scheduled on this node (K8S_NODE_NAME) are registered.
+type KubernetesDetector struct {
+	logger     *pterm.Logger
+	enabled    bool
+	selectors  []string // Label selectors, each listed separately
+	namespace  string   // Empty for all namespaces
+	nodeName   string
+	containers map[string]bool // Container names to register, empty for all
+	podLogDir  string
+	parserType string
+	apiURL     string
+	client     *http.Client
+}
+
+// podList is the subset of the Kubernetes PodList used for discovery
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+			UID       string `json:"uid"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName   string `json:"nodeName"`
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+func NewKubernetesDetector(logger *pterm.Logger) ServiceDetector {
+	selectors := []string{}
+	for _, selector := range strings.Split(getEnvOrDefault("K8S_LABEL_SELECTORS", defaultIngressSelectors), ";") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+
+	containers := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("K8S_CONTAINER_NAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			containers[name] = true
+		}
+	}
+
+	nodeName := os.Getenv("K8S_NODE_NAME")
+	if nodeName == "" {
+		nodeName = os.Getenv("NODE_NAME")
+	}
+
+	return &KubernetesDetector{
+		logger:     logger,
+		enabled:    os.Getenv("K8S_DISCOVERY") == "true",
+		selectors:  selectors,
+		namespace:  os.Getenv("K8S_NAMESPACE"),
+		nodeName:   nodeName,
+		containers: containers,
+		podLogDir:  getEnvOrDefault("K8S_POD_LOG_DIR", "/var/log/pods"),
+		parserType: getEnvOrDefault("K8S_PARSER", "traefik"),
+	}
+}
+
+func (d *KubernetesDetector) Name() string {
+	return "kubernetes"
+}
+
+// Continuous reports that pods are looked up on every discovery run, not only on an empty database
+func (d *KubernetesDetector) Continuous() bool {
+	return d.enabled
+}
+
+func (d *KubernetesDetector) Detect() ([]*models.LogSource, error) {
+	sources := []*models.LogSource{}
+	if !d.enabled {
+		d.logger.Trace("Kubernetes discovery disabled", d.logger.Args("K8S_DISCOVERY", false))
+		return sources, nil
+	}
+
+	if d.client == nil {
+		if err := d.initClient(); err != nil {
+			return sources, err
+		}
+	}
+
+	if d.nodeName == "" {
+		d.logger.Warn("K8S_NODE_NAME not set, registering ingress pods of all nodes",
+			d.logger.Args("hint", "Set K8S_NODE_NAME from spec.nodeName (downward API) when running as a DaemonSet"))
+	}
+
+	seen := make(map[string]bool)
+	for _, selector := range d.selectors {
+		pods, err := d.listPods(selector)
+		if err != nil {
+			return sources, err
+		}
+
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				if len(d.containers) > 0 && !d.containers[container.Name] {
+					continue
+				}
+
+				name := fmt.Sprintf("k8s-%s-%s-%s", pod.Metadata.Namespace, pod.Metadata.Name, container.Name)
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+
+				// kubelet layout: <namespace>_<pod>_<uid>/<container>/<restart count>.log
+				podDir := fmt.Sprintf("%s_%s_%s", pod.Metadata.Namespace, pod.Metadata.Name, pod.Metadata.UID)
+				pattern := filepath.Join(d.podLogDir, podDir, container.Name, "*.log")
+
+				d.logger.Debug("Kubernetes ingress container found",
+					d.logger.Args("source", name, "selector", selector, "pattern", pattern))
+				sources = append(sources, &models.LogSource{
+					Name:       name,
+					Path:       pattern,
+					ParserType: "cri+" + d.parserType,
+				})
+			}
+		}
+	}
+
+	d.logger.Trace("Kubernetes discovery completed", d.logger.Args("sources", len(sources), "node", d.nodeName))
+	return sources, nil
+}
+
+// initClient builds an API client from the in-cluster service account
+func (d *KubernetesDetector) initClient() error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return fmt.Errorf("not running in a Kubernetes cluster (KUBERNETES_SERVICE_HOST/PORT not set)")
+	}
+
+	caData, err := os.ReadFile(serviceAccountCA)
+	if err != nil {
+		return fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caData) {
+		return fmt.Errorf("invalid service account CA certificate")
+	}
+
+	d.apiURL = "https://" + net.JoinHostPort(host, port)
+	d.client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: caPool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	return nil
+}
+
+// listPods lists running pods matching a label selector (on this node when known)
+func (d *KubernetesDetector) listPods(selector string) (*podList, error) {
+	// Projected service account tokens are rotated, so the token is read on every request
+	token, err := os.ReadFile(serviceAccountToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	path := "/api/v1/pods"
+	if d.namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(d.namespace) + "/pods"
+	}
+
+	fieldSelector := "status.phase=Running"
+	if d.nodeName != "" {
+		fieldSelector += ",spec.nodeName=" + d.nodeName
+	}
+
+	query := url.Values{}
+	query.Set("labelSelector", selector)
+	query.Set("fieldSelector", fieldSelector)
+
+	req, err := http.NewRequest(http.MethodGet, d.apiURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list pods (selector %q): %s", selector, resp.Status)
+	}
+
+	var pods podList
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return nil, fmt.Errorf("failed to decode pod list: %w", err)
+	}
+	return &pods, nil
+}
+
+// getEnvOrDefault returns an environment variable or a default value when unset
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
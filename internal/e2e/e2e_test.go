@@ -0,0 +1,183 @@
+package e2e
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// binary is the server under test, built once for all tests
+var binary string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if testing.Short() {
+		fmt.Println("Skipping end-to-end tests in short mode")
+		os.Exit(0)
+	}
+
+	binary = os.Getenv("LOGLYNX_E2E_BINARY")
+	if binary == "" {
+		dir, err := os.MkdirTemp("", "loglynx-e2e")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		binary, err = BuildServer(dir)
+		if err != nil {
+			os.RemoveAll(dir)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		code := m.Run()
+		os.RemoveAll(dir)
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+// statsResponse is the envelope of the stats endpoints
+type statsResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+type summary struct {
+	TotalRequests  int64 `json:"total_requests"`
+	FailedRequests int64 `json:"failed_requests"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+type timelineBucket struct {
+	Hour     string `json:"hour"`
+	Requests int64  `json:"requests"`
+}
+
+type logProcessing struct {
+	LogSourceName string `json:"log_source_name"`
+	Dedup         struct {
+		RecordsInserted   int64 `json:"records_inserted"`
+		DuplicatesSkipped int64 `json:"duplicates_skipped"`
+	} `json:"dedup"`
+}
+
+type ingestionStatus struct {
+	Recovery struct {
+		UncleanShutdown bool `json:"unclean_shutdown"`
+		Sources         []struct {
+			CompletedAt *time.Time `json:"completed_at"`
+		} `json:"sources"`
+	} `json:"recovery"`
+}
+
+// processed returns the records inserted and duplicates skipped over all sources
+func processed(s *Server) (inserted int64, duplicates int64) {
+	var sources []logProcessing
+	if _, err := s.getJSON("/api/v1/stats/log-processing", &sources); err != nil {
+		return 0, 0
+	}
+	for _, source := range sources {
+		inserted += source.Dedup.RecordsInserted
+		duplicates += source.Dedup.DuplicatesSkipped
+	}
+	return inserted, duplicates
+}
+
+// waitProcessed waits until the server stored or skipped the given number of lines
+func waitProcessed(s *Server, inserted, duplicates int64) {
+	s.t.Helper()
+	s.WaitFor(fmt.Sprintf("%d inserted and %d duplicate lines", inserted, duplicates), 30*time.Second, func() bool {
+		gotInserted, gotDuplicates := processed(s)
+		return gotInserted == inserted && gotDuplicates == duplicates
+	})
+}
+
+func getSummary(s *Server) summary {
+	s.t.Helper()
+	var response statsResponse[summary]
+	s.GetJSON("/api/v1/stats/summary?hours=24", &response)
+	return response.Data
+}
+
+func getTimeline(s *Server) map[string]int64 {
+	s.t.Helper()
+	var response statsResponse[[]timelineBucket]
+	s.GetJSON("/api/v1/stats/timeline?hours=24", &response)
+	buckets := make(map[string]int64)
+	for _, bucket := range response.Data {
+		buckets[bucket.Hour] = bucket.Requests
+	}
+	return buckets
+}
+
+func TestIngestionToAPI(t *testing.T) {
+	server := NewServer(t, binary, nil)
+	now := time.Now().UTC()
+
+	// 10 requests 3 hours ago read at startup, then 6 one hour ago from the same clients, 2 lines repeated and one
+	// that doesn't parse
+	earlier := Traffic(now.Add(-3*time.Hour).Truncate(time.Hour), 10, "api")
+	recent := Traffic(now.Add(-time.Hour).Truncate(time.Hour), 6, "web")
+	server.AppendLines(Lines(earlier)...)
+	server.Start()
+	waitProcessed(server, 10, 0)
+	server.AppendLines(Lines(recent)...)
+	server.AppendLines(recent[0].TraefikJSON(), recent[1].TraefikJSON(), "not an access log line")
+	waitProcessed(server, 16, 2)
+
+	got := getSummary(server)
+	if got.TotalRequests != 16 || got.FailedRequests != 3 || got.UniqueVisitors != 4 {
+		t.Errorf("Expected 16 requests, 3 failed, from 4 visitors, got %+v", got)
+	}
+	timeline := getTimeline(server)
+	for _, expected := range []struct {
+		at       time.Time
+		requests int64
+	}{{earlier[0].At, 10}, {recent[0].At, 6}} {
+		hour := expected.at.Format("2006-01-02 15:00")
+		if timeline[hour] != expected.requests {
+			t.Errorf("Expected %d requests in the %s bucket, got %d (%v)", expected.requests, hour, timeline[hour], timeline)
+		}
+	}
+
+	// Lines appended while the server runs keep being picked up
+	server.AppendLines(Lines(Traffic(now.Add(-10*time.Minute), 4, "api"))...)
+	waitProcessed(server, 20, 2)
+	if got := getSummary(server); got.TotalRequests != 20 {
+		t.Errorf("Expected 20 requests after appending 4, got %d", got.TotalRequests)
+	}
+}
+
+func TestRestartResumesWithoutDuplicates(t *testing.T) {
+	server := NewServer(t, binary, nil)
+	now := time.Now().UTC()
+	server.AppendLines(Lines(Traffic(now.Add(-2*time.Hour), 12, "api"))...)
+	server.Start()
+	waitProcessed(server, 12, 0)
+
+	// A graceful restart resumes at the saved position: nothing is read twice
+	server.Restart()
+	server.AppendLines(Lines(Traffic(now.Add(-time.Hour), 3, "web"))...)
+	waitProcessed(server, 15, 0)
+	var status ingestionStatus
+	server.GetJSON("/api/v1/system/ingestion", &status)
+	if status.Recovery.UncleanShutdown {
+		t.Errorf("Expected no recovery after a graceful restart, got %+v", status.Recovery)
+	}
+
+	// A crash is reported as recovered once the lines after the saved position are read again
+	server.Kill()
+	server.Start()
+	server.WaitFor("the recovery to complete", 30*time.Second, func() bool {
+		var status ingestionStatus
+		if _, err := server.getJSON("/api/v1/system/ingestion", &status); err != nil {
+			return false
+		}
+		sources := status.Recovery.Sources
+		return status.Recovery.UncleanShutdown && len(sources) == 1 && sources[0].CompletedAt != nil
+	})
+	if got := getSummary(server); got.TotalRequests != 15 {
+		t.Errorf("Expected 15 requests after the crash, got %d", got.TotalRequests)
+	}
+}
@@ -0,0 +1,74 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Request is one access log fixture line
+type Request struct {
+	At       time.Time
+	ClientIP string
+	Method   string
+	Host     string
+	Path     string
+	Service  string
+	Status   int
+	Size     int64
+	Duration time.Duration
+}
+
+// TraefikJSON renders the request as a Traefik JSON access log line
+// StartUTC has nanoseconds, so identical lines are duplicates whatever their position in the file.
+func (r Request) TraefikJSON() string {
+	line, _ := json.Marshal(map[string]interface{}{
+		"StartUTC":              r.At.UTC().Format(time.RFC3339Nano),
+		"ClientHost":            r.ClientIP,
+		"ClientAddr":            r.ClientIP + ":51234",
+		"RequestMethod":         r.Method,
+		"request_Host":          r.Host,
+		"RequestPath":           r.Path,
+		"RequestProtocol":       "HTTP/1.1",
+		"request_User-Agent":    "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0",
+		"DownstreamStatus":      r.Status,
+		"DownstreamContentSize": r.Size,
+		"Duration":              r.Duration.Nanoseconds(),
+		"ServiceName":           r.Service,
+		"RouterName":            r.Service + "-router",
+	})
+	return string(line)
+}
+
+// Traffic generates count requests spread over the minutes following start, one client per 3 requests
+// Requests get distinct nanosecond start times, so none of them is a duplicate of another.
+func Traffic(start time.Time, count int, service string) []Request {
+	requests := make([]Request, count)
+	for i := range requests {
+		status := 200
+		if i%5 == 4 {
+			status = 404
+		}
+		requests[i] = Request{
+			At:       start.Add(time.Duration(i)*time.Minute + time.Duration(i+1)*time.Microsecond),
+			ClientIP: fmt.Sprintf("203.0.113.%d", i/3+1),
+			Method:   "GET",
+			Host:     service + ".example.com",
+			Path:     fmt.Sprintf("/items/%d", i),
+			Service:  service,
+			Status:   status,
+			Size:     1024,
+			Duration: 25 * time.Millisecond,
+		}
+	}
+	return requests
+}
+
+// Lines renders requests as Traefik JSON access log lines
+func Lines(requests []Request) []string {
+	lines := make([]string, len(requests))
+	for i, request := range requests {
+		lines[i] = request.TraefikJSON()
+	}
+	return lines
+}
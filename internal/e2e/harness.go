@@ -0,0 +1,275 @@
+// Package e2e runs the LogLynx server binary against generated access logs and checks its API
+// The tests build the server, start it on a temporary data directory and a free port, append fixture lines to the
+// access log it reads and wait for the API to reflect them. Set LOGLYNX_E2E_BINARY to test a prebuilt binary.
+// docker-compose.yml runs them in a container with the toolchain of the Dockerfile.
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// startTimeout is how long a started server has to answer its liveness probe
+	startTimeout = 30 * time.Second
+	// stopTimeout is how long a stopped server has to exit before it is killed
+	stopTimeout = 15 * time.Second
+	// pollInterval is how often WaitFor checks its condition
+	pollInterval = 100 * time.Millisecond
+)
+
+// BuildServer compiles the server of the module containing this package into dir
+func BuildServer(dir string) (string, error) {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the module: %w", err)
+	}
+	root := filepath.Dir(strings.TrimSpace(string(out)))
+
+	binary := filepath.Join(dir, "loglynx")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/server")
+	build.Dir = root
+	if output, err := build.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to build the server: %w\n%s", err, output)
+	}
+	return binary, nil
+}
+
+// Server is a LogLynx server reading one Traefik access log
+type Server struct {
+	URL     string
+	LogPath string // Access log the server reads, AppendLines writes to it
+	DataDir string // Holds the database, kept across restarts
+
+	t      testing.TB
+	binary string
+	env    []string // Environment of the server process
+	cmd    *exec.Cmd
+	exited chan struct{}
+	output *syncBuffer
+}
+
+// NewServer prepares the binary to run on a new data directory with an empty access log, stopped when the test ends
+// Discovery ignores an empty log, so append the first lines before calling Start. env adds or overrides configuration
+// variables.
+func NewServer(t testing.TB, binary string, env map[string]string) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	s := &Server{
+		LogPath: filepath.Join(dir, "access.log"),
+		DataDir: filepath.Join(dir, "data"),
+		t:       t,
+		binary:  binary,
+		output:  &syncBuffer{},
+	}
+	if err := os.MkdirAll(s.DataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(s.LogPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	port := freePort(t)
+	s.URL = fmt.Sprintf("http://127.0.0.1:%d", port)
+	config := map[string]string{
+		"SERVER_HOST":           "127.0.0.1",
+		"SERVER_PORT":           fmt.Sprint(port),
+		"DB_PATH":               filepath.Join(s.DataDir, "loglynx.db"),
+		"TRAEFIK_LOG_PATH":      s.LogPath,
+		"TRAEFIK_LOG_FORMAT":    "json",
+		"LOG_AUTO_DISCOVER":     "true",
+		"INITIAL_IMPORT_ENABLE": "false",
+		"DASHBOARD_ENABLED":     "false",
+		"SPLASH_SCREEN_ENABLED": "false",
+		"GEOIP_ENABLED":         "false",
+		"BATCH_FLUSH_MAX":       "1s",
+		"LOG_LEVEL":             "info",
+	}
+	for name, value := range env {
+		config[name] = value
+	}
+	s.env = os.Environ()
+	for name, value := range config {
+		s.env = append(s.env, name+"="+value)
+	}
+
+	t.Cleanup(func() {
+		s.Stop()
+		if t.Failed() {
+			t.Logf("Server output:\n%s", s.output.String())
+		}
+	})
+	return s
+}
+
+// Start runs the server process and waits until it answers
+func (s *Server) Start() {
+	s.t.Helper()
+	if s.cmd != nil {
+		s.t.Fatal("Server already running")
+	}
+
+	cmd := exec.Command(s.binary)
+	cmd.Dir = s.DataDir // Relative paths (.env, geoip) resolve in the data directory, not the repository
+	cmd.Env = s.env
+	cmd.Stdout = s.output
+	cmd.Stderr = s.output
+	if err := cmd.Start(); err != nil {
+		s.t.Fatalf("Failed to start the server: %v", err)
+	}
+	s.cmd = cmd
+	s.exited = make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(s.exited)
+	}()
+	s.waitLive()
+}
+
+// Stop interrupts the server like a shutdown signal and waits for it to exit
+func (s *Server) Stop() {
+	if s.cmd == nil {
+		return
+	}
+	s.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-s.exited:
+	case <-time.After(stopTimeout):
+		s.t.Errorf("Server did not stop within %s, killing it", stopTimeout)
+		s.cmd.Process.Kill()
+		<-s.exited
+	}
+	s.cmd = nil
+}
+
+// Kill ends the server without a graceful shutdown, like a crash or power loss
+func (s *Server) Kill() {
+	if s.cmd == nil {
+		return
+	}
+	s.cmd.Process.Kill()
+	<-s.exited
+	s.cmd = nil
+}
+
+// Restart stops the server gracefully and starts it again on the same data directory
+func (s *Server) Restart() {
+	s.t.Helper()
+	s.Stop()
+	s.Start()
+}
+
+// waitLive waits until the liveness probe answers
+func (s *Server) waitLive() {
+	s.t.Helper()
+	deadline := time.Now().Add(startTimeout)
+	for {
+		select {
+		case <-s.exited:
+			s.t.Fatalf("Server exited during startup:\n%s", s.output.String())
+		default:
+		}
+		response, err := http.Get(s.URL + "/health/live")
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Server not live within %s: %v", startTimeout, err)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// AppendLines writes lines to the access log
+func (s *Server) AppendLines(lines ...string) {
+	s.t.Helper()
+	file, err := os.OpenFile(s.LogPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer file.Close()
+	for _, line := range lines {
+		if _, err := io.WriteString(file, line+"\n"); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+// GetJSON decodes the response of a GET request, failing the test on errors and status codes other than 200
+func (s *Server) GetJSON(path string, out interface{}) {
+	s.t.Helper()
+	if status, err := s.getJSON(path, out); err != nil || status != http.StatusOK {
+		s.t.Fatalf("GET %s: status %d, %v", path, status, err)
+	}
+}
+
+func (s *Server) getJSON(path string, out interface{}) (int, error) {
+	response, err := http.Get(s.URL + path)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode, fmt.Errorf("%s", body)
+	}
+	return response.StatusCode, json.Unmarshal(body, out)
+}
+
+// WaitFor polls done until it returns true, failing the test after timeout
+func (s *Server) WaitFor(what string, timeout time.Duration, done func() bool) {
+	s.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort(t testing.TB) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// syncBuffer collects the server output written from the process goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,252 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+// DefaultBackfillBatchSize is the number of requests re-enriched per transaction
+const DefaultBackfillBatchSize = 1000
+
+// Backfill states
+const (
+	BackfillIdle      = "idle"
+	BackfillRunning   = "running"
+	BackfillDone      = "done"
+	BackfillFailed    = "failed"
+	BackfillCancelled = "cancelled"
+)
+
+// ErrBackfillRunning is returned when a backfill is started while one is running
+var ErrBackfillRunning = errors.New("an enrichment backfill is already running")
+
+// ErrGeoIPUnavailable is returned when a backfill is started without GeoIP databases
+var ErrGeoIPUnavailable = errors.New("GeoIP enrichment is not available")
+
+// BackfillProgress reports the state of the last backfill
+type BackfillProgress struct {
+	State      string     `json:"state"`
+	Total      int64      `json:"total"`     // Requests missing GeoIP or ASN data when the backfill started
+	Processed  int64      `json:"processed"` // Requests looked up so far
+	Enriched   int64      `json:"enriched"`  // Requests that got data (private and unknown addresses stay blank)
+	Percent    float64    `json:"percent"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Backfill re-enriches stored requests missing GeoIP or ASN data, e.g. after GeoIP databases were added
+// or updated, in batches of increasing ID so ingestion keeps writing between them
+type Backfill struct {
+	db        *gorm.DB
+	geoIP     *GeoIPEnricher
+	logger    *pterm.Logger
+	batchSize int
+
+	mu       sync.Mutex
+	progress BackfillProgress
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewBackfill creates an idle backfill job, geoIP may be nil when GeoIP is disabled
+func NewBackfill(db *gorm.DB, geoIP *GeoIPEnricher, logger *pterm.Logger, batchSize int) *Backfill {
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+	return &Backfill{
+		db:        db,
+		geoIP:     geoIP,
+		logger:    logger,
+		batchSize: batchSize,
+		progress:  BackfillProgress{State: BackfillIdle},
+	}
+}
+
+// Start begins a backfill in the background
+// Returns ErrBackfillRunning when one is running and ErrGeoIPUnavailable without GeoIP databases.
+func (b *Backfill) Start() (BackfillProgress, error) {
+	if b.geoIP == nil || !b.geoIP.IsEnabled() {
+		return b.Progress(), ErrGeoIPUnavailable
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.progress.State == BackfillRunning {
+		return b.snapshot(), ErrBackfillRunning
+	}
+
+	var total int64
+	if err := b.missing(b.db.Model(&models.HTTPRequest{})).Count(&total).Error; err != nil {
+		return b.snapshot(), err
+	}
+
+	now := time.Now()
+	b.progress = BackfillProgress{State: BackfillRunning, Total: total, StartedAt: &now}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	b.logger.Info("Enrichment backfill started", b.logger.Args("requests", total, "providers", b.geoIP.Providers()))
+	go b.run(ctx, b.done)
+	return b.snapshot(), nil
+}
+
+// Cancel stops a running backfill after its current batch, rows already updated keep their data
+func (b *Backfill) Cancel() bool {
+	b.mu.Lock()
+	cancel, done := b.cancel, b.done
+	running := b.progress.State == BackfillRunning
+	b.mu.Unlock()
+
+	if !running {
+		return false
+	}
+	cancel()
+	<-done
+	return true
+}
+
+// Stop cancels a running backfill, used on shutdown
+func (b *Backfill) Stop() {
+	if b.Cancel() {
+		b.logger.Info("Enrichment backfill cancelled by shutdown")
+	}
+}
+
+// Progress returns the state of the running or last backfill
+func (b *Backfill) Progress() BackfillProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshot()
+}
+
+// snapshot copies the progress with its percentage, b.mu must be held
+func (b *Backfill) snapshot() BackfillProgress {
+	progress := b.progress
+	switch {
+	case progress.State == BackfillDone:
+		progress.Percent = 100
+	case progress.Total > 0:
+		progress.Percent = min(float64(progress.Processed)*100/float64(progress.Total), 100)
+	}
+	return progress
+}
+
+// missing restricts a query to requests without country or ASN
+func (b *Backfill) missing(query *gorm.DB) *gorm.DB {
+	return query.Where("client_ip != '' AND (geo_country = '' OR geo_country IS NULL OR asn = 0 OR asn IS NULL)")
+}
+
+// run processes batches until no request is left, the context is cancelled or a batch fails
+func (b *Backfill) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	var lastID uint64
+	state, errorMessage := BackfillDone, ""
+	for {
+		if ctx.Err() != nil {
+			state = BackfillCancelled
+			break
+		}
+		processed, enriched, nextID, err := b.batch(lastID)
+		if err != nil {
+			b.logger.WithCaller().Error("Enrichment backfill failed", b.logger.Args("error", err, "after_id", lastID))
+			state, errorMessage = BackfillFailed, err.Error()
+			break
+		}
+		if processed == 0 {
+			break
+		}
+		lastID = nextID
+
+		b.mu.Lock()
+		b.progress.Processed += processed
+		b.progress.Enriched += enriched
+		b.mu.Unlock()
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	b.progress.State, b.progress.Error, b.progress.FinishedAt = state, errorMessage, &now
+	progress := b.snapshot()
+	b.mu.Unlock()
+
+	b.logger.Info("Enrichment backfill finished",
+		b.logger.Args("state", progress.State, "processed", progress.Processed, "enriched", progress.Enriched))
+}
+
+// batch re-enriches the next requests after lastID missing data, looking up each address once
+// Returns the number of requests looked up and updated, and the last ID of the batch.
+func (b *Backfill) batch(lastID uint64) (processed int64, enriched int64, nextID uint64, err error) {
+	var rows []struct {
+		ID       uint64
+		ClientIP string
+	}
+	err = b.missing(b.db.Model(&models.HTTPRequest{}).Select("id, client_ip")).
+		Where("id > ?", lastID).
+		Order("id").
+		Limit(b.batchSize).
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return 0, 0, lastID, err
+	}
+
+	idsByIP := make(map[string][]uint64)
+	for _, row := range rows {
+		idsByIP[row.ClientIP] = append(idsByIP[row.ClientIP], row.ID)
+	}
+
+	// Lookups come first: Refresh writes ip_reputation, which would wait for the transaction's lock
+	columnsByIP := make(map[string]map[string]any, len(idsByIP))
+	for ip := range idsByIP {
+		location, err := b.geoIP.Refresh(ip)
+		if err != nil {
+			continue // Invalid addresses stay blank
+		}
+		if columns := location.columns(); len(columns) > 0 {
+			columnsByIP[ip] = columns
+		}
+	}
+
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		for ip, columns := range columnsByIP {
+			result := tx.Model(&models.HTTPRequest{}).Where("id IN ?", idsByIP[ip]).Updates(columns)
+			if result.Error != nil {
+				return result.Error
+			}
+			enriched += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, lastID, err
+	}
+	return int64(len(rows)), enriched, rows[len(rows)-1].ID, nil
+}
+
+// columns returns the request columns of the known fields, so data a provider doesn't have isn't blanked
+func (l *GeoIPLocation) columns() map[string]any {
+	columns := make(map[string]any)
+	if l.Country != "" {
+		columns["geo_country"] = l.Country
+		columns["geo_eu"] = l.IsEU
+	}
+	if l.City != "" {
+		columns["geo_city"] = l.City
+		columns["geo_lat"] = l.Latitude
+		columns["geo_lon"] = l.Longitude
+	}
+	if l.ASN != 0 {
+		columns["asn"] = l.ASN
+		columns["asn_org"] = l.ASNOrg
+	}
+	return columns
+}
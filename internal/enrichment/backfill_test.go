@@ -0,0 +1,113 @@
+package enrichment_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/enrichment"
+
+	"github.com/pterm/pterm"
+)
+
+// tableProvider knows the addresses of its table
+type tableProvider map[string]enrichment.GeoIPLocation
+
+func (p tableProvider) Name() string { return "table" }
+
+func (p tableProvider) Lookup(ip net.IP) (*enrichment.GeoIPLocation, error) {
+	location := p[ip.String()]
+	return &location, nil
+}
+
+func (p tableProvider) Close() error { return nil }
+
+func TestBackfill(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	db := fake.NewDB(t)
+
+	// Rows ingested before the databases were added, one already enriched, one private address
+	requests := []struct {
+		ip      string
+		country string
+		count   int
+	}{
+		{"203.0.113.7", "", 5},
+		{"198.51.100.1", "", 2},
+		{"192.0.2.1", "FR", 1},
+		{"10.0.0.1", "", 3},
+	}
+	for _, request := range requests {
+		for i := 0; i < request.count; i++ {
+			err := db.Create(&models.HTTPRequest{
+				SourceName: "test", Timestamp: time.Now(), RequestHash: fmt.Sprint(request, i),
+				ClientIP: request.ip, GeoCountry: request.country, ASN: 64500,
+				Method: "GET", Host: "example.com", Path: "/", StatusCode: 200,
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := db.Create(&models.IPReputation{IPAddress: "203.0.113.7", FirstSeen: time.Now(), LastSeen: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	geoIP, err := enrichment.NewGeoIPEnricher([]enrichment.GeoIPProvider{tableProvider{
+		"203.0.113.7":  {Country: "DE", CountryName: "Germany", IsEU: true, City: "Berlin", Latitude: 52.5, Longitude: 13.4},
+		"198.51.100.1": {Country: "US", CountryName: "United States", ASN: 13335, ASNOrg: "Cloudflare, Inc."},
+		"192.0.2.1":    {Country: "IT"},
+	}}, db, log, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backfill := enrichment.NewBackfill(db, geoIP, log, 2)
+	progress, err := backfill.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Total != 10 {
+		t.Errorf("Expected 10 requests missing data, got %d", progress.Total)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for backfill.Progress().State == enrichment.BackfillRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	progress = backfill.Progress()
+	if progress.State != enrichment.BackfillDone || progress.Processed != 10 || progress.Enriched != 7 || progress.Percent != 100 {
+		t.Errorf("Unexpected progress %+v", progress)
+	}
+
+	request := func(ip string) (request models.HTTPRequest) {
+		db.Where("client_ip = ?", ip).First(&request)
+		return request
+	}
+	if request := request("203.0.113.7"); request.GeoCountry != "DE" || request.GeoCity != "Berlin" || !request.GeoEU || request.ASN != 64500 {
+		t.Errorf("Expected location data added and the ASN kept, got %+v", request)
+	}
+	if request := request("198.51.100.1"); request.GeoCountry != "US" || request.ASN != 13335 || request.ASNOrg != "Cloudflare, Inc." {
+		t.Errorf("Expected country and ASN replaced, got %+v", request)
+	}
+	if request := request("192.0.2.1"); request.GeoCountry != "FR" {
+		t.Errorf("Expected complete rows left alone, got %s", request.GeoCountry)
+	}
+
+	var reputation models.IPReputation
+	db.Where("ip_address = ?", "203.0.113.7").First(&reputation)
+	if reputation.Country != "DE" || reputation.City != "Berlin" {
+		t.Errorf("Expected the reputation refreshed, got %+v", reputation)
+	}
+
+	// Nothing to cancel once finished, unavailable without GeoIP
+	if backfill.Cancel() {
+		t.Error("Expected no running backfill to cancel")
+	}
+	if _, err := enrichment.NewBackfill(db, nil, log, 0).Start(); err != enrichment.ErrGeoIPUnavailable {
+		t.Errorf("Expected ErrGeoIPUnavailable, got %v", err)
+	}
+}
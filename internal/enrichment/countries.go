@@ -0,0 +1,343 @@
+package enrichment
+
+import (
+	"sort"
+	"strings"
+)
+
+// Continent codes, as used by GeoIP databases
+var continentNames = map[string]string{
+	"AF": "Africa",
+	"AN": "Antarctica",
+	"AS": "Asia",
+	"EU": "Europe",
+	"NA": "North America",
+	"OC": "Oceania",
+	"SA": "South America",
+}
+
+// Country describes an ISO 3166-1 alpha-2 country code
+type Country struct {
+	Code          string
+	Name          string
+	Flag          string // Emoji flag, built from the code's regional indicator symbols
+	Continent     string // Continent code (AF, AN, AS, EU, NA, OC, SA)
+	ContinentName string
+}
+
+type countryInfo struct {
+	name      string
+	continent string
+}
+
+// countries maps ISO 3166-1 alpha-2 codes (plus XK, used by GeoIP databases for Kosovo) to name and continent
+// Transcontinental countries are assigned to a single continent, matching web/static/js/core/country-data.js.
+var countries = map[string]countryInfo{
+	// Africa
+	"DZ": {"Algeria", "AF"},
+	"AO": {"Angola", "AF"},
+	"BJ": {"Benin", "AF"},
+	"BW": {"Botswana", "AF"},
+	"BF": {"Burkina Faso", "AF"},
+	"BI": {"Burundi", "AF"},
+	"CM": {"Cameroon", "AF"},
+	"CV": {"Cape Verde", "AF"},
+	"CF": {"Central African Republic", "AF"},
+	"TD": {"Chad", "AF"},
+	"KM": {"Comoros", "AF"},
+	"CG": {"Congo", "AF"},
+	"CD": {"Congo (Democratic Republic)", "AF"},
+	"DJ": {"Djibouti", "AF"},
+	"EG": {"Egypt", "AF"},
+	"GQ": {"Equatorial Guinea", "AF"},
+	"ER": {"Eritrea", "AF"},
+	"SZ": {"Eswatini", "AF"},
+	"ET": {"Ethiopia", "AF"},
+	"GA": {"Gabon", "AF"},
+	"GM": {"Gambia", "AF"},
+	"GH": {"Ghana", "AF"},
+	"GN": {"Guinea", "AF"},
+	"GW": {"Guinea-Bissau", "AF"},
+	"CI": {"Ivory Coast", "AF"},
+	"KE": {"Kenya", "AF"},
+	"LS": {"Lesotho", "AF"},
+	"LR": {"Liberia", "AF"},
+	"LY": {"Libya", "AF"},
+	"MG": {"Madagascar", "AF"},
+	"MW": {"Malawi", "AF"},
+	"ML": {"Mali", "AF"},
+	"MR": {"Mauritania", "AF"},
+	"MU": {"Mauritius", "AF"},
+	"YT": {"Mayotte", "AF"},
+	"MA": {"Morocco", "AF"},
+	"MZ": {"Mozambique", "AF"},
+	"NA": {"Namibia", "AF"},
+	"NE": {"Niger", "AF"},
+	"NG": {"Nigeria", "AF"},
+	"RE": {"Reunion", "AF"},
+	"RW": {"Rwanda", "AF"},
+	"SH": {"Saint Helena", "AF"},
+	"ST": {"Sao Tome and Principe", "AF"},
+	"SN": {"Senegal", "AF"},
+	"SC": {"Seychelles", "AF"},
+	"SL": {"Sierra Leone", "AF"},
+	"SO": {"Somalia", "AF"},
+	"ZA": {"South Africa", "AF"},
+	"SS": {"South Sudan", "AF"},
+	"SD": {"Sudan", "AF"},
+	"TZ": {"Tanzania", "AF"},
+	"TG": {"Togo", "AF"},
+	"TN": {"Tunisia", "AF"},
+	"UG": {"Uganda", "AF"},
+	"EH": {"Western Sahara", "AF"},
+	"ZM": {"Zambia", "AF"},
+	"ZW": {"Zimbabwe", "AF"},
+	// Antarctica
+	"AQ": {"Antarctica", "AN"},
+	"BV": {"Bouvet Island", "AN"},
+	"TF": {"French Southern and Antarctic Lands", "AN"},
+	"HM": {"Heard Island and McDonald Islands", "AN"},
+	"GS": {"South Georgia and the South Sandwich Islands", "AN"},
+	// Asia
+	"AF": {"Afghanistan", "AS"},
+	"AM": {"Armenia", "AS"},
+	"AZ": {"Azerbaijan", "AS"},
+	"BH": {"Bahrain", "AS"},
+	"BD": {"Bangladesh", "AS"},
+	"BT": {"Bhutan", "AS"},
+	"IO": {"British Indian Ocean Territory", "AS"},
+	"BN": {"Brunei", "AS"},
+	"KH": {"Cambodia", "AS"},
+	"CN": {"China", "AS"},
+	"CX": {"Christmas Island", "AS"},
+	"CC": {"Cocos Islands", "AS"},
+	"GE": {"Georgia", "AS"},
+	"HK": {"Hong Kong", "AS"},
+	"IN": {"India", "AS"},
+	"ID": {"Indonesia", "AS"},
+	"IR": {"Iran", "AS"},
+	"IQ": {"Iraq", "AS"},
+	"IL": {"Israel", "AS"},
+	"JP": {"Japan", "AS"},
+	"JO": {"Jordan", "AS"},
+	"KZ": {"Kazakhstan", "AS"},
+	"KW": {"Kuwait", "AS"},
+	"KG": {"Kyrgyzstan", "AS"},
+	"LA": {"Laos", "AS"},
+	"LB": {"Lebanon", "AS"},
+	"MO": {"Macao", "AS"},
+	"MY": {"Malaysia", "AS"},
+	"MV": {"Maldives", "AS"},
+	"MN": {"Mongolia", "AS"},
+	"MM": {"Myanmar", "AS"},
+	"NP": {"Nepal", "AS"},
+	"KP": {"North Korea", "AS"},
+	"OM": {"Oman", "AS"},
+	"PK": {"Pakistan", "AS"},
+	"PS": {"Palestine", "AS"},
+	"PH": {"Philippines", "AS"},
+	"QA": {"Qatar", "AS"},
+	"SA": {"Saudi Arabia", "AS"},
+	"SG": {"Singapore", "AS"},
+	"KR": {"South Korea", "AS"},
+	"LK": {"Sri Lanka", "AS"},
+	"SY": {"Syria", "AS"},
+	"TW": {"Taiwan", "AS"},
+	"TJ": {"Tajikistan", "AS"},
+	"TH": {"Thailand", "AS"},
+	"TL": {"Timor-Leste", "AS"},
+	"TR": {"Turkey", "AS"},
+	"TM": {"Turkmenistan", "AS"},
+	"AE": {"United Arab Emirates", "AS"},
+	"UZ": {"Uzbekistan", "AS"},
+	"VN": {"Vietnam", "AS"},
+	"YE": {"Yemen", "AS"},
+	// Europe
+	"AX": {"Aland Islands", "EU"},
+	"AL": {"Albania", "EU"},
+	"AD": {"Andorra", "EU"},
+	"AT": {"Austria", "EU"},
+	"BY": {"Belarus", "EU"},
+	"BE": {"Belgium", "EU"},
+	"BA": {"Bosnia and Herzegovina", "EU"},
+	"BG": {"Bulgaria", "EU"},
+	"HR": {"Croatia", "EU"},
+	"CY": {"Cyprus", "EU"},
+	"CZ": {"Czech Republic", "EU"},
+	"DK": {"Denmark", "EU"},
+	"EE": {"Estonia", "EU"},
+	"FO": {"Faroe Islands", "EU"},
+	"FI": {"Finland", "EU"},
+	"FR": {"France", "EU"},
+	"DE": {"Germany", "EU"},
+	"GI": {"Gibraltar", "EU"},
+	"GR": {"Greece", "EU"},
+	"GG": {"Guernsey", "EU"},
+	"HU": {"Hungary", "EU"},
+	"IS": {"Iceland", "EU"},
+	"IE": {"Ireland", "EU"},
+	"IM": {"Isle of Man", "EU"},
+	"IT": {"Italy", "EU"},
+	"JE": {"Jersey", "EU"},
+	"XK": {"Kosovo", "EU"},
+	"LV": {"Latvia", "EU"},
+	"LI": {"Liechtenstein", "EU"},
+	"LT": {"Lithuania", "EU"},
+	"LU": {"Luxembourg", "EU"},
+	"MT": {"Malta", "EU"},
+	"MD": {"Moldova", "EU"},
+	"MC": {"Monaco", "EU"},
+	"ME": {"Montenegro", "EU"},
+	"NL": {"Netherlands", "EU"},
+	"MK": {"North Macedonia", "EU"},
+	"NO": {"Norway", "EU"},
+	"PL": {"Poland", "EU"},
+	"PT": {"Portugal", "EU"},
+	"RO": {"Romania", "EU"},
+	"RU": {"Russia", "EU"},
+	"SM": {"San Marino", "EU"},
+	"RS": {"Serbia", "EU"},
+	"SK": {"Slovakia", "EU"},
+	"SI": {"Slovenia", "EU"},
+	"ES": {"Spain", "EU"},
+	"SJ": {"Svalbard and Jan Mayen", "EU"},
+	"SE": {"Sweden", "EU"},
+	"CH": {"Switzerland", "EU"},
+	"UA": {"Ukraine", "EU"},
+	"GB": {"United Kingdom", "EU"},
+	"VA": {"Vatican City", "EU"},
+	// North America
+	"AI": {"Anguilla", "NA"},
+	"AG": {"Antigua and Barbuda", "NA"},
+	"AW": {"Aruba", "NA"},
+	"BS": {"Bahamas", "NA"},
+	"BB": {"Barbados", "NA"},
+	"BZ": {"Belize", "NA"},
+	"BM": {"Bermuda", "NA"},
+	"BQ": {"Bonaire, Sint Eustatius and Saba", "NA"},
+	"VG": {"British Virgin Islands", "NA"},
+	"CA": {"Canada", "NA"},
+	"KY": {"Cayman Islands", "NA"},
+	"CR": {"Costa Rica", "NA"},
+	"CU": {"Cuba", "NA"},
+	"CW": {"Curacao", "NA"},
+	"DM": {"Dominica", "NA"},
+	"DO": {"Dominican Republic", "NA"},
+	"SV": {"El Salvador", "NA"},
+	"GL": {"Greenland", "NA"},
+	"GD": {"Grenada", "NA"},
+	"GP": {"Guadeloupe", "NA"},
+	"GT": {"Guatemala", "NA"},
+	"HT": {"Haiti", "NA"},
+	"HN": {"Honduras", "NA"},
+	"JM": {"Jamaica", "NA"},
+	"MQ": {"Martinique", "NA"},
+	"MX": {"Mexico", "NA"},
+	"MS": {"Montserrat", "NA"},
+	"NI": {"Nicaragua", "NA"},
+	"PA": {"Panama", "NA"},
+	"PR": {"Puerto Rico", "NA"},
+	"BL": {"Saint Barthelemy", "NA"},
+	"KN": {"Saint Kitts and Nevis", "NA"},
+	"LC": {"Saint Lucia", "NA"},
+	"MF": {"Saint Martin", "NA"},
+	"PM": {"Saint Pierre and Miquelon", "NA"},
+	"VC": {"Saint Vincent and the Grenadines", "NA"},
+	"SX": {"Sint Maarten", "NA"},
+	"TT": {"Trinidad and Tobago", "NA"},
+	"TC": {"Turks and Caicos Islands", "NA"},
+	"US": {"United States", "NA"},
+	"VI": {"United States Virgin Islands", "NA"},
+	// Oceania
+	"AS": {"American Samoa", "OC"},
+	"AU": {"Australia", "OC"},
+	"CK": {"Cook Islands", "OC"},
+	"FJ": {"Fiji", "OC"},
+	"PF": {"French Polynesia", "OC"},
+	"GU": {"Guam", "OC"},
+	"KI": {"Kiribati", "OC"},
+	"MH": {"Marshall Islands", "OC"},
+	"FM": {"Micronesia", "OC"},
+	"NR": {"Nauru", "OC"},
+	"NC": {"New Caledonia", "OC"},
+	"NZ": {"New Zealand", "OC"},
+	"NU": {"Niue", "OC"},
+	"NF": {"Norfolk Island", "OC"},
+	"MP": {"Northern Mariana Islands", "OC"},
+	"PW": {"Palau", "OC"},
+	"PG": {"Papua New Guinea", "OC"},
+	"PN": {"Pitcairn Islands", "OC"},
+	"WS": {"Samoa", "OC"},
+	"SB": {"Solomon Islands", "OC"},
+	"TK": {"Tokelau", "OC"},
+	"TO": {"Tonga", "OC"},
+	"TV": {"Tuvalu", "OC"},
+	"UM": {"U.S. Minor Outlying Islands", "OC"},
+	"VU": {"Vanuatu", "OC"},
+	"WF": {"Wallis and Futuna", "OC"},
+	// South America
+	"AR": {"Argentina", "SA"},
+	"BO": {"Bolivia", "SA"},
+	"BR": {"Brazil", "SA"},
+	"CL": {"Chile", "SA"},
+	"CO": {"Colombia", "SA"},
+	"EC": {"Ecuador", "SA"},
+	"FK": {"Falkland Islands", "SA"},
+	"GF": {"French Guiana", "SA"},
+	"GY": {"Guyana", "SA"},
+	"PY": {"Paraguay", "SA"},
+	"PE": {"Peru", "SA"},
+	"SR": {"Suriname", "SA"},
+	"UY": {"Uruguay", "SA"},
+	"VE": {"Venezuela", "SA"},
+}
+
+// euCountries are the codes GeoIP databases flag as is_in_european_union: the member states and the
+// outermost regions with their own ISO code. Only used for requests enriched before the flag was stored.
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true, "DK": true, "EE": true, "ES": true,
+	"FI": true, "FR": true, "GR": true, "HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SE": true, "SI": true, "SK": true,
+	"AX": true, "GF": true, "GP": true, "MF": true, "MQ": true, "RE": true, "YT": true,
+}
+
+// EUCountryCodes returns the country codes in the European Union, see euCountries
+func EUCountryCodes() []string {
+	codes := make([]string, 0, len(euCountries))
+	for code := range euCountries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// LookupCountry returns name, flag and continent of a country code (case-insensitive)
+// ok is false for empty or unknown codes, only Code is set then.
+func LookupCountry(code string) (country Country, ok bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	country.Code = code
+
+	info, ok := countries[code]
+	if !ok {
+		return country, false
+	}
+	country.Name = info.name
+	country.Flag = countryFlag(code)
+	country.Continent = info.continent
+	country.ContinentName = continentNames[info.continent]
+	return country, true
+}
+
+// ContinentName returns the name of a continent code, or an empty string if unknown
+func ContinentName(code string) string {
+	return continentNames[strings.ToUpper(code)]
+}
+
+// countryFlag converts a two-letter code to its flag emoji (a pair of regional indicator symbols)
+func countryFlag(code string) string {
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return ""
+	}
+	const regionalIndicatorA = 0x1F1E6
+	return string([]rune{regionalIndicatorA + rune(code[0]-'A'), regionalIndicatorA + rune(code[1]-'A')})
+}
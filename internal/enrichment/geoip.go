@@ -5,9 +5,9 @@ import (
 	"loglynx/internal/database/models"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/oschwald/geoip2-golang"
 	"github.com/pterm/pterm"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -15,70 +15,32 @@ import (
 
 // GeoIPEnricher provides GeoIP enrichment with caching
 type GeoIPEnricher struct {
-	cityDB    *geoip2.Reader
-	countryDB *geoip2.Reader
-	asnDB     *geoip2.Reader
+	providers []GeoIPProvider // Looked up in order, later ones filling what earlier ones don't know
 	db        *gorm.DB
 	logger    *pterm.Logger
 	cache     map[string]*models.IPReputation
 	cacheMu   sync.RWMutex
 	enabled   bool
-	cacheSize int // Maximum cache size from config (GEOIP_CACHE_SIZE)
+	cacheSize int         // Maximum cache size from config (GEOIP_CACHE_SIZE)
+	ready     atomic.Bool // Set once the initial cache load finished
 }
 
-// NewGeoIPEnricher creates a new GeoIP enricher
-// Handles City, Country, and ASN databases - works with any combination available
-func NewGeoIPEnricher(cityDBPath, countryDBPath, asnDBPath string, db *gorm.DB, logger *pterm.Logger, cacheSize int) (*GeoIPEnricher, error) {
+// NewGeoIPEnricher creates a new GeoIP enricher over the opened providers (see OpenGeoIPProviders)
+// Works with any combination of databases, enrichment is disabled without providers.
+func NewGeoIPEnricher(providers []GeoIPProvider, db *gorm.DB, logger *pterm.Logger, cacheSize int) (*GeoIPEnricher, error) {
 	if cacheSize <= 0 {
 		cacheSize = 10000 // Default fallback
 	}
 
 	enricher := &GeoIPEnricher{
+		providers: providers,
 		db:        db,
 		logger:    logger,
 		cache:     make(map[string]*models.IPReputation, cacheSize), // Pre-allocate with capacity
-		enabled:   false,
+		enabled:   len(providers) > 0,
 		cacheSize: cacheSize,
 	}
 
-	// Try to load City database (provides most detailed location data)
-	if cityDBPath != "" {
-		cityDB, err := geoip2.Open(cityDBPath)
-		if err != nil {
-			logger.Warn("GeoIP City database not available",
-				logger.Args("path", cityDBPath, "error", err))
-		} else {
-			enricher.cityDB = cityDB
-			enricher.enabled = true
-			logger.Info("Loaded GeoIP City database", logger.Args("path", cityDBPath))
-		}
-	}
-
-	// Try to load Country database (fallback if City is not available)
-	if countryDBPath != "" {
-		countryDB, err := geoip2.Open(countryDBPath)
-		if err != nil {
-			logger.Warn("GeoIP Country database not available",
-				logger.Args("path", countryDBPath, "error", err))
-		} else {
-			enricher.countryDB = countryDB
-			enricher.enabled = true
-			logger.Info("Loaded GeoIP Country database", logger.Args("path", countryDBPath))
-		}
-	}
-
-	// Try to load ASN database (provides ISP/organization data)
-	if asnDBPath != "" {
-		asnDB, err := geoip2.Open(asnDBPath)
-		if err != nil {
-			logger.Warn("GeoIP ASN database not available",
-				logger.Args("path", asnDBPath, "error", err))
-		} else {
-			enricher.asnDB = asnDB
-			logger.Info("Loaded GeoIP ASN database", logger.Args("path", asnDBPath))
-		}
-	}
-
 	if !enricher.enabled {
 		logger.Warn("GeoIP enrichment disabled - no databases available")
 	}
@@ -103,6 +65,7 @@ func (g *GeoIPEnricher) Enrich(request *models.HTTPRequest) error {
 		request.GeoCity = cached.City
 		request.GeoLat = cached.Latitude
 		request.GeoLon = cached.Longitude
+		request.GeoEU = cached.IsEU
 		request.ASN = cached.ASN
 		request.ASNOrg = cached.ASNOrg
 
@@ -115,6 +78,24 @@ func (g *GeoIPEnricher) Enrich(request *models.HTTPRequest) error {
 	return g.lookupAndCache(request)
 }
 
+// lookup queries the providers in order until the location is complete
+func (g *GeoIPEnricher) lookup(ip net.IP) *GeoIPLocation {
+	location := &GeoIPLocation{}
+	for _, provider := range g.providers {
+		found, err := provider.Lookup(ip)
+		if err != nil {
+			g.logger.Debug("GeoIP lookup failed", g.logger.Args("provider", provider.Name(), "ip", ip.String(), "error", err))
+		}
+		if found != nil {
+			location.merge(found)
+		}
+		if location.complete() {
+			break
+		}
+	}
+	return location
+}
+
 // lookupAndCache performs GeoIP lookup and caches the result
 func (g *GeoIPEnricher) lookupAndCache(request *models.HTTPRequest) error {
 	ip := net.ParseIP(request.ClientIP)
@@ -123,72 +104,32 @@ func (g *GeoIPEnricher) lookupAndCache(request *models.HTTPRequest) error {
 		return fmt.Errorf("invalid IP: %s", request.ClientIP)
 	}
 
+	location := g.lookup(ip)
 	reputation := &models.IPReputation{
-		IPAddress: request.ClientIP,
-		FirstSeen: time.Now(),
-		LastSeen:  time.Now(),
+		IPAddress:   request.ClientIP,
+		Country:     location.Country,
+		CountryName: location.CountryName,
+		IsEU:        location.IsEU,
+		City:        location.City,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
+		ASN:         location.ASN,
+		ASNOrg:      location.ASNOrg,
+		FirstSeen:   time.Now(),
+		LastSeen:    time.Now(),
 	}
 
-	// Lookup City data (preferred - provides city, country, and coordinates)
-	cityLookupSuccess := false
-	if g.cityDB != nil {
-		record, err := g.cityDB.City(ip)
-		if err == nil {
-			reputation.Country = record.Country.IsoCode
-			reputation.CountryName = record.Country.Names["en"]
-			reputation.City = record.City.Names["en"]
-			reputation.Latitude = record.Location.Latitude
-			reputation.Longitude = record.Location.Longitude
-
-			// Populate request
-			request.GeoCountry = reputation.Country
-			request.GeoCity = reputation.City
-			request.GeoLat = reputation.Latitude
-			request.GeoLon = reputation.Longitude
-
-			cityLookupSuccess = true
-			g.logger.Debug("GeoIP City lookup successful",
-				g.logger.Args("ip", request.ClientIP, "country", reputation.Country, "city", reputation.City))
-		} else {
-			g.logger.Debug("GeoIP City lookup failed", g.logger.Args("ip", request.ClientIP, "error", err))
-		}
-	}
-
-	// Fallback to Country database if City lookup failed or unavailable
-	if !cityLookupSuccess && g.countryDB != nil {
-		record, err := g.countryDB.Country(ip)
-		if err == nil {
-			reputation.Country = record.Country.IsoCode
-			reputation.CountryName = record.Country.Names["en"]
-			// Country DB doesn't provide city or coordinates, but we get country at least
-
-			// Populate request
-			request.GeoCountry = reputation.Country
-
-			g.logger.Debug("GeoIP Country lookup successful",
-				g.logger.Args("ip", request.ClientIP, "country", reputation.Country))
-		} else {
-			g.logger.Debug("GeoIP Country lookup failed", g.logger.Args("ip", request.ClientIP, "error", err))
-		}
-	}
+	// Populate request
+	request.GeoCountry = reputation.Country
+	request.GeoCity = reputation.City
+	request.GeoLat = reputation.Latitude
+	request.GeoLon = reputation.Longitude
+	request.GeoEU = reputation.IsEU
+	request.ASN = reputation.ASN
+	request.ASNOrg = reputation.ASNOrg
 
-	// Lookup ASN data
-	if g.asnDB != nil {
-		record, err := g.asnDB.ASN(ip)
-		if err == nil {
-			reputation.ASN = int(record.AutonomousSystemNumber)
-			reputation.ASNOrg = record.AutonomousSystemOrganization
-
-			// Populate request
-			request.ASN = reputation.ASN
-			request.ASNOrg = reputation.ASNOrg
-
-			g.logger.Debug("GeoIP ASN lookup successful",
-				g.logger.Args("ip", request.ClientIP, "asn", reputation.ASN, "org", reputation.ASNOrg))
-		} else {
-			g.logger.Debug("GeoIP ASN lookup failed", g.logger.Args("ip", request.ClientIP, "error", err))
-		}
-	}
+	g.logger.Debug("GeoIP lookup finished",
+		g.logger.Args("ip", request.ClientIP, "country", reputation.Country, "city", reputation.City, "asn", reputation.ASN))
 
 	// Store in memory cache first (fast, thread-safe)
 	g.cacheMu.Lock()
@@ -257,12 +198,61 @@ func (g *GeoIPEnricher) lookupAndCache(request *models.HTTPRequest) error {
 	return nil
 }
 
+// Refresh looks up an address again, bypassing the caches, and replaces its cached data
+// Used by the backfill after databases were added or updated, which leaves earlier lookups outdated.
+func (g *GeoIPEnricher) Refresh(clientIP string) (*GeoIPLocation, error) {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP: %s", clientIP)
+	}
+
+	location := g.lookup(ip)
+	if len(location.columns()) == 0 {
+		return location, nil
+	}
+
+	// Only the known fields are replaced, like in the requests
+	columns := make(map[string]any)
+	g.cacheMu.Lock()
+	cached, exists := g.cache[clientIP]
+	refreshed := models.IPReputation{}
+	if exists {
+		refreshed = *cached
+	}
+	if location.Country != "" {
+		refreshed.Country, refreshed.CountryName, refreshed.IsEU = location.Country, location.CountryName, location.IsEU
+		columns["country"], columns["country_name"], columns["is_eu"] = location.Country, location.CountryName, location.IsEU
+	}
+	if location.City != "" {
+		refreshed.City, refreshed.Latitude, refreshed.Longitude = location.City, location.Latitude, location.Longitude
+		columns["city"], columns["latitude"], columns["longitude"] = location.City, location.Latitude, location.Longitude
+	}
+	if location.ASN != 0 {
+		refreshed.ASN, refreshed.ASNOrg = location.ASN, location.ASNOrg
+		columns["asn"], columns["asn_org"] = location.ASN, location.ASNOrg
+	}
+	if exists {
+		g.cache[clientIP] = &refreshed
+	}
+	g.cacheMu.Unlock()
+
+	err := g.db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)}).
+		Model(&models.IPReputation{}).
+		Where("ip_address = ?", clientIP).
+		Updates(columns).Error
+	if err != nil {
+		g.logger.Debug("Failed to refresh IP reputation", g.logger.Args("ip", clientIP, "error", err))
+	}
+	return location, nil
+}
+
 // LoadCache preloads the memory cache from database
 // Optimized to load only hot IPs (recent activity) and skip if cache is already large
 func (g *GeoIPEnricher) LoadCache() error {
 	if !g.enabled {
 		return nil
 	}
+	defer g.ready.Store(true)
 
 	// Skip cache loading if already populated (avoids startup delay on restart)
 	g.cacheMu.RLock()
@@ -342,24 +332,32 @@ func (g *GeoIPEnricher) LoadCache() error {
 
 // Close closes the GeoIP databases
 func (g *GeoIPEnricher) Close() error {
-	if g.cityDB != nil {
-		g.cityDB.Close()
-	}
-	if g.countryDB != nil {
-		g.countryDB.Close()
-	}
-	if g.asnDB != nil {
-		g.asnDB.Close()
+	for _, provider := range g.providers {
+		provider.Close()
 	}
 	g.logger.Info("Closed GeoIP databases")
 	return nil
 }
 
+// Providers returns the names of the opened providers, in lookup order
+func (g *GeoIPEnricher) Providers() []string {
+	names := make([]string, len(g.providers))
+	for i, provider := range g.providers {
+		names[i] = provider.Name()
+	}
+	return names
+}
+
 // IsEnabled returns whether GeoIP enrichment is available
 func (g *GeoIPEnricher) IsEnabled() bool {
 	return g.enabled
 }
 
+// IsReady returns whether the databases are loaded and the initial cache load finished
+func (g *GeoIPEnricher) IsReady() bool {
+	return g.enabled && g.ready.Load()
+}
+
 // GetCacheSize returns the number of entries in memory cache
 func (g *GeoIPEnricher) GetCacheSize() int {
 	g.cacheMu.RLock()
@@ -0,0 +1,339 @@
+package enrichment
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pterm/pterm"
+)
+
+// GeoIP providers, as named in GEOIP_PROVIDERS
+const (
+	ProviderMaxMind     = "maxmind"
+	ProviderDBIP        = "dbip"
+	ProviderIPinfo      = "ipinfo"
+	ProviderIP2Location = "ip2location"
+)
+
+// GeoIPProviderNames lists the supported providers
+var GeoIPProviderNames = []string{ProviderMaxMind, ProviderDBIP, ProviderIPinfo, ProviderIP2Location}
+
+// GeoIPLocation is what a provider knows about an address, fields are empty when unknown
+type GeoIPLocation struct {
+	Country     string // ISO 3166-1 alpha-2 code
+	CountryName string
+	IsEU        bool
+	City        string
+	Latitude    float64
+	Longitude   float64
+	ASN         int
+	ASNOrg      string
+}
+
+// complete reports whether nothing is left for a fallback provider to fill
+func (l *GeoIPLocation) complete() bool {
+	return l.Country != "" && l.City != "" && l.ASN != 0
+}
+
+// merge fills the fields l doesn't know from a fallback provider's location
+// City and coordinates are only taken when both providers agree on the country.
+func (l *GeoIPLocation) merge(fallback *GeoIPLocation) {
+	if l.Country == "" && fallback.Country != "" {
+		l.Country, l.CountryName, l.IsEU = fallback.Country, fallback.CountryName, fallback.IsEU
+	}
+	if l.City == "" && fallback.City != "" && fallback.Country == l.Country {
+		l.City = fallback.City
+		if l.Latitude == 0 && l.Longitude == 0 {
+			l.Latitude, l.Longitude = fallback.Latitude, fallback.Longitude
+		}
+	}
+	if l.ASN == 0 && fallback.ASN != 0 {
+		l.ASN, l.ASNOrg = fallback.ASN, fallback.ASNOrg
+	}
+}
+
+// GeoIPProvider looks up addresses in the databases of one GeoIP vendor
+type GeoIPProvider interface {
+	Name() string
+	Lookup(ip net.IP) (*GeoIPLocation, error)
+	Close() error
+}
+
+// GeoIPDatabases holds the database paths of each provider, paths of unused providers are ignored
+type GeoIPDatabases struct {
+	CityDB    string // MaxMind GeoLite2/GeoIP2
+	CountryDB string
+	ASNDB     string
+
+	DBIPCityDB string // DB-IP City or Country (Lite), GeoIP2-compatible mmdb
+	DBIPASNDB  string
+
+	IPinfoDB      string // IPinfo mmdb: Lite, country_asn or location
+	IP2LocationDB string // IP2Location (LITE) mmdb
+}
+
+// ParseGeoIPProviders parses a comma-separated list of providers, in lookup order
+func ParseGeoIPProviders(list string) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		known := false
+		for _, provider := range GeoIPProviderNames {
+			known = known || provider == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown GeoIP provider %q (expected one of %s)", name, strings.Join(GeoIPProviderNames, ", "))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no GeoIP provider configured")
+	}
+	return names, nil
+}
+
+// OpenGeoIPProviders opens the databases of the providers, in order
+// Providers without any available database are skipped with a warning.
+func OpenGeoIPProviders(names []string, databases GeoIPDatabases, logger *pterm.Logger) []GeoIPProvider {
+	var providers []GeoIPProvider
+	for _, name := range names {
+		var provider GeoIPProvider
+		switch name {
+		case ProviderMaxMind:
+			provider = openGeoIP2Provider(name, databases.CityDB, databases.CountryDB, databases.ASNDB, logger)
+		case ProviderDBIP:
+			provider = openGeoIP2Provider(name, databases.DBIPCityDB, "", databases.DBIPASNDB, logger)
+		case ProviderIPinfo:
+			provider = openMMDBProvider(name, databases.IPinfoDB, ipinfoLocation, logger)
+		case ProviderIP2Location:
+			provider = openMMDBProvider(name, databases.IP2LocationDB, geoIP2Location, logger)
+		}
+		if provider == nil {
+			logger.Warn("GeoIP provider has no database available, skipping it", logger.Args("provider", name))
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// geoIP2Provider reads databases in the GeoIP2 layout: MaxMind GeoLite2/GeoIP2 and DB-IP
+type geoIP2Provider struct {
+	name      string
+	cityDB    *geoip2.Reader // Also opens Country databases
+	countryDB *geoip2.Reader // Fallback if City is not available
+	asnDB     *geoip2.Reader
+}
+
+// openGeoIP2Provider opens the available databases, nil when none is
+func openGeoIP2Provider(name, cityDBPath, countryDBPath, asnDBPath string, logger *pterm.Logger) *geoIP2Provider {
+	provider := &geoIP2Provider{name: name}
+	open := func(kind, path string) *geoip2.Reader {
+		if path == "" {
+			return nil
+		}
+		reader, err := geoip2.Open(path)
+		if err != nil {
+			logger.Warn("GeoIP "+kind+" database not available", logger.Args("provider", name, "path", path, "error", err))
+			return nil
+		}
+		logger.Info("Loaded GeoIP "+kind+" database", logger.Args("provider", name, "path", path))
+		return reader
+	}
+	provider.cityDB = open("City", cityDBPath)
+	provider.countryDB = open("Country", countryDBPath)
+	provider.asnDB = open("ASN", asnDBPath)
+
+	if provider.cityDB == nil && provider.countryDB == nil && provider.asnDB == nil {
+		return nil
+	}
+	return provider
+}
+
+func (p *geoIP2Provider) Name() string { return p.name }
+
+// Lookup prefers the City database (city and coordinates), falling back to the Country database
+func (p *geoIP2Provider) Lookup(ip net.IP) (*GeoIPLocation, error) {
+	location := &GeoIPLocation{}
+	var errs []error
+
+	if p.cityDB != nil {
+		record, err := p.cityDB.City(ip)
+		if err == nil {
+			location.Country = record.Country.IsoCode
+			location.CountryName = record.Country.Names["en"]
+			location.IsEU = record.Country.IsInEuropeanUnion
+			location.City = record.City.Names["en"]
+			location.Latitude = record.Location.Latitude
+			location.Longitude = record.Location.Longitude
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if location.Country == "" && p.countryDB != nil {
+		record, err := p.countryDB.Country(ip)
+		if err == nil {
+			// Country databases don't provide city or coordinates
+			location.Country = record.Country.IsoCode
+			location.CountryName = record.Country.Names["en"]
+			location.IsEU = record.Country.IsInEuropeanUnion
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if p.asnDB != nil {
+		record, err := p.asnDB.ASN(ip)
+		if err == nil {
+			location.ASN = int(record.AutonomousSystemNumber)
+			location.ASNOrg = record.AutonomousSystemOrganization
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	return location, errors.Join(errs...)
+}
+
+func (p *geoIP2Provider) Close() error {
+	for _, reader := range []*geoip2.Reader{p.cityDB, p.countryDB, p.asnDB} {
+		if reader != nil {
+			reader.Close()
+		}
+	}
+	return nil
+}
+
+// mmdbProvider reads a single mmdb database whose records are converted by a vendor-specific function
+type mmdbProvider struct {
+	name     string
+	reader   *maxminddb.Reader
+	location func(record map[string]any) *GeoIPLocation
+}
+
+// openMMDBProvider opens the database, nil when it isn't available
+func openMMDBProvider(name, path string, location func(map[string]any) *GeoIPLocation, logger *pterm.Logger) *mmdbProvider {
+	if path == "" {
+		return nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		logger.Warn("GeoIP database not available", logger.Args("provider", name, "path", path, "error", err))
+		return nil
+	}
+	logger.Info("Loaded GeoIP database", logger.Args("provider", name, "path", path, "type", reader.Metadata.DatabaseType))
+	return &mmdbProvider{name: name, reader: reader, location: location}
+}
+
+func (p *mmdbProvider) Name() string { return p.name }
+
+func (p *mmdbProvider) Lookup(ip net.IP) (*GeoIPLocation, error) {
+	var record map[string]any
+	if err := p.reader.Lookup(ip, &record); err != nil {
+		return &GeoIPLocation{}, err
+	}
+	location := p.location(record)
+	if location.Country != "" && !location.IsEU {
+		// Only MaxMind-compatible databases carry the flag
+		location.IsEU = euCountries[location.Country]
+	}
+	return location, nil
+}
+
+func (p *mmdbProvider) Close() error {
+	return p.reader.Close()
+}
+
+// ipinfoLocation converts an IPinfo record, whose fields are flat and differ between products:
+// Lite has country_code and country (name), country_asn has country (code) and country_name,
+// location has city and coordinates as strings; ASNs are "AS13335" with as_name.
+func ipinfoLocation(record map[string]any) *GeoIPLocation {
+	location := &GeoIPLocation{
+		Country:     strings.ToUpper(mmdbString(record, "country_code")),
+		CountryName: mmdbString(record, "country_name"),
+		City:        mmdbString(record, "city"),
+		ASNOrg:      mmdbString(record, "as_name"),
+	}
+	if country := mmdbString(record, "country"); location.Country == "" && len(country) == 2 {
+		location.Country = strings.ToUpper(country)
+	} else if location.CountryName == "" && len(country) > 2 {
+		location.CountryName = country
+	}
+	location.Latitude, _ = mmdbNumber(record["latitude"])
+	location.Longitude, _ = mmdbNumber(record["longitude"])
+	if asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(mmdbString(record, "asn")), "AS")); err == nil {
+		location.ASN = asn
+	}
+	return location
+}
+
+// geoIP2Location converts a record in the GeoIP2 layout (country.iso_code, city.names.en, location.latitude),
+// used by the IP2Location mmdb databases
+func geoIP2Location(record map[string]any) *GeoIPLocation {
+	location := &GeoIPLocation{
+		Country:     strings.ToUpper(mmdbString(record, "country", "iso_code")),
+		CountryName: mmdbString(record, "country", "names", "en"),
+		City:        mmdbString(record, "city", "names", "en"),
+		ASNOrg:      mmdbString(record, "autonomous_system_organization"),
+	}
+	location.IsEU, _ = mmdbValue(record, "country", "is_in_european_union").(bool)
+	location.Latitude, _ = mmdbNumber(mmdbValue(record, "location", "latitude"))
+	location.Longitude, _ = mmdbNumber(mmdbValue(record, "location", "longitude"))
+	if asn, ok := mmdbNumber(record["autonomous_system_number"]); ok {
+		location.ASN = int(asn)
+	}
+	return location
+}
+
+// mmdbValue returns the value at a path of nested maps, nil when missing
+func mmdbValue(record map[string]any, path ...string) any {
+	var value any = record
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// mmdbString returns the string at a path of nested maps, empty when missing or not a string
+func mmdbString(record map[string]any, path ...string) string {
+	value, _ := mmdbValue(record, path...).(string)
+	return strings.TrimSpace(value)
+}
+
+// mmdbNumber converts a decoded number, or a number stored as string, to float64
+func mmdbNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,103 @@
+package enrichment
+
+import (
+	"net"
+	"testing"
+
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+)
+
+// staticProvider answers every lookup with the same location
+type staticProvider struct {
+	name     string
+	location GeoIPLocation
+	lookups  int
+}
+
+func (p *staticProvider) Name() string { return p.name }
+
+func (p *staticProvider) Lookup(net.IP) (*GeoIPLocation, error) {
+	p.lookups++
+	location := p.location
+	return &location, nil
+}
+
+func (p *staticProvider) Close() error { return nil }
+
+func TestParseGeoIPProviders(t *testing.T) {
+	providers, err := ParseGeoIPProviders(" DBIP, ipinfo,dbip ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0] != ProviderDBIP || providers[1] != ProviderIPinfo {
+		t.Errorf("Unexpected providers %v", providers)
+	}
+	for _, invalid := range []string{"", " , ", "maxmind,geolite"} {
+		if _, err := ParseGeoIPProviders(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestGeoIPEnricher_FallbackChain(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+
+	// Country only, then a city of another country and the ASN, then a complete answer never asked
+	country := &staticProvider{name: "maxmind", location: GeoIPLocation{Country: "DE", CountryName: "Germany", IsEU: true}}
+	asn := &staticProvider{name: "ipinfo", location: GeoIPLocation{Country: "AT", City: "Vienna", Latitude: 48.2, Longitude: 16.4, ASN: 3320, ASNOrg: "Deutsche Telekom AG"}}
+	city := &staticProvider{name: "dbip", location: GeoIPLocation{Country: "DE", City: "Berlin", Latitude: 52.5, Longitude: 13.4, ASN: 1}}
+	unused := &staticProvider{name: "ip2location", location: GeoIPLocation{Country: "FR", City: "Paris", ASN: 2}}
+
+	enricher, err := NewGeoIPEnricher([]GeoIPProvider{country, asn, city, unused}, nil, logger, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := enricher.lookup(net.ParseIP("203.0.113.7"))
+	expected := GeoIPLocation{Country: "DE", CountryName: "Germany", IsEU: true, City: "Berlin", Latitude: 52.5, Longitude: 13.4, ASN: 3320, ASNOrg: "Deutsche Telekom AG"}
+	if *location != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *location)
+	}
+	if unused.lookups != 0 {
+		t.Error("Expected the chain to stop once the location is complete")
+	}
+	if names := enricher.Providers(); len(names) != 4 || names[0] != "maxmind" {
+		t.Errorf("Unexpected provider names %v", names)
+	}
+
+	if enricher, _ := NewGeoIPEnricher(nil, nil, logger, 10); enricher.IsEnabled() {
+		t.Error("Expected enrichment disabled without providers")
+	}
+	if err := (&GeoIPEnricher{}).Enrich(&models.HTTPRequest{ClientIP: "203.0.113.7"}); err != nil {
+		t.Errorf("Expected a disabled enricher to skip requests, got %v", err)
+	}
+}
+
+func TestIPinfoLocation(t *testing.T) {
+	// IPinfo Lite
+	location := ipinfoLocation(map[string]any{"country_code": "US", "country": "United States", "asn": "AS13335", "as_name": "Cloudflare, Inc."})
+	if location.Country != "US" || location.CountryName != "United States" || location.ASN != 13335 || location.ASNOrg != "Cloudflare, Inc." {
+		t.Errorf("Unexpected Lite location %+v", location)
+	}
+
+	// Country ASN and location databases
+	location = ipinfoLocation(map[string]any{"country": "DE", "country_name": "Germany", "city": "Berlin", "latitude": "52.52437", "longitude": "13.41053"})
+	if location.Country != "DE" || location.CountryName != "Germany" || location.City != "Berlin" || location.Latitude != 52.52437 || location.Longitude != 13.41053 || location.ASN != 0 {
+		t.Errorf("Unexpected location %+v", location)
+	}
+}
+
+func TestGeoIP2Location(t *testing.T) {
+	location := geoIP2Location(map[string]any{
+		"country":  map[string]any{"iso_code": "fr", "names": map[string]any{"en": "France"}},
+		"city":     map[string]any{"names": map[string]any{"en": "Paris"}},
+		"location": map[string]any{"latitude": 48.85341, "longitude": float32(2.5)},
+	})
+	if location.Country != "FR" || location.CountryName != "France" || location.City != "Paris" || location.Latitude != 48.85341 || location.Longitude != 2.5 {
+		t.Errorf("Unexpected location %+v", location)
+	}
+	if location := geoIP2Location(map[string]any{"autonomous_system_number": uint64(64496)}); location.ASN != 64496 || location.Country != "" {
+		t.Errorf("Unexpected ASN location %+v", location)
+	}
+}
@@ -0,0 +1,218 @@
+// Package filter holds the service and own-IP filters of the API, shared by stats queries and real-time metrics
+// A filter builds its SQL condition for repositories and matches events in memory for the real-time collector, both
+// with the same semantics: "auto" matches backend_name, then backend_url when there is no backend name, then host.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Service types
+const (
+	TypeAuto        = "auto"
+	TypeBackendName = "backend_name"
+	TypeBackendURL  = "backend_url"
+	TypeHost        = "host"
+)
+
+// Service filter match types
+// Wildcards use * (any characters) and ? (one character), matching is case-sensitive for all types.
+const (
+	MatchExact    = "exact"
+	MatchPrefix   = "prefix"
+	MatchWildcard = "wildcard"
+	MatchRegex    = "regex"
+)
+
+// maxPatternLength bounds patterns, regexes are compiled and cached per distinct pattern
+const maxPatternLength = 256
+
+// globEscaper escapes GLOB metacharacters so they match literally
+var globEscaper = strings.NewReplacer("[", "[[]", "*", "[*]", "?", "[?]")
+
+// regexCache holds compiled regexes, shared by SQL REGEXP calls and in-memory matching
+var regexCache sync.Map // pattern -> *regexp.Regexp
+
+// Service selects the requests of a service
+type Service struct {
+	Name      string `json:"name"`                 // Service name, or pattern for non-exact match types
+	Type      string `json:"type"`                 // auto (default), backend_name, backend_url or host
+	MatchType string `json:"match_type,omitempty"` // exact (default), prefix, wildcard or regex
+}
+
+// ExcludeIP hides the requests of a client IP, on all services or only on ExcludeServices
+type ExcludeIP struct {
+	ClientIP        string
+	ExcludeServices []Service
+}
+
+// KnownType reports whether the service type is one of the types above, unknown types are treated as auto
+func (s Service) KnownType() bool {
+	switch s.Type {
+	case TypeAuto, TypeBackendName, TypeBackendURL, TypeHost, "":
+		return true
+	}
+	return false
+}
+
+// Validate checks the match type and pattern of the filter
+func (s Service) Validate() error {
+	switch s.MatchType {
+	case "", MatchExact:
+		return nil
+	case MatchPrefix, MatchWildcard, MatchRegex:
+	default:
+		return fmt.Errorf("invalid match type %q: expected exact, prefix, wildcard or regex", s.MatchType)
+	}
+
+	if s.Name == "" {
+		return fmt.Errorf("empty %s pattern", s.MatchType)
+	}
+	if len(s.Name) > maxPatternLength {
+		return fmt.Errorf("%s pattern longer than %d characters", s.MatchType, maxPatternLength)
+	}
+	if s.MatchType == MatchRegex {
+		if _, err := CompileRegex(s.Name); err != nil {
+			return fmt.Errorf("invalid regex %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// CompileRegex compiles a service regex, reusing earlier compilations
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// MatchValue reports whether a value matches a pattern, as the SQL condition would
+func MatchValue(matchType, pattern, value string) bool {
+	switch matchType {
+	case MatchPrefix:
+		return strings.HasPrefix(value, pattern)
+	case MatchWildcard:
+		re, err := CompileRegex(wildcardToRegex(pattern))
+		return err == nil && re.MatchString(value)
+	case MatchRegex:
+		re, err := CompileRegex(pattern)
+		return err == nil && re.MatchString(value)
+	default:
+		return value == pattern
+	}
+}
+
+// wildcardToRegex converts a * and ? wildcard pattern to an anchored regex
+func wildcardToRegex(pattern string) string {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return "^" + quoted + "$"
+}
+
+// Matches reports whether a request with these fields belongs to the service, as Condition would select it
+func (s Service) Matches(backendName, backendURL, host string) bool {
+	switch s.Type {
+	case TypeBackendName:
+		return MatchValue(s.MatchType, s.Name, backendName)
+	case TypeBackendURL:
+		return MatchValue(s.MatchType, s.Name, backendURL)
+	case TypeHost:
+		return MatchValue(s.MatchType, s.Name, host)
+	default:
+		switch {
+		case backendName != "":
+			return MatchValue(s.MatchType, s.Name, backendName)
+		case backendURL != "":
+			return MatchValue(s.MatchType, s.Name, backendURL)
+		default:
+			return MatchValue(s.MatchType, s.Name, host)
+		}
+	}
+}
+
+// MatchesAny reports whether a request belongs to at least one of the services (OR semantics)
+func MatchesAny(filters []Service, backendName, backendURL, host string) bool {
+	for _, s := range filters {
+		if s.Matches(backendName, backendURL, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Excludes reports whether a request is hidden by the filter, a nil filter hides nothing
+func (e *ExcludeIP) Excludes(clientIP, backendName, backendURL, host string) bool {
+	if e == nil || e.ClientIP == "" || clientIP != e.ClientIP {
+		return false
+	}
+	return len(e.ExcludeServices) == 0 || MatchesAny(e.ExcludeServices, backendName, backendURL, host)
+}
+
+// ColumnCondition returns a condition matching a column against the filter pattern, whatever the service type
+// Prefix and wildcard use GLOB, which is case-sensitive like equality and can use column indexes for prefixes.
+// Regex relies on the REGEXP function registered on database connections.
+func (s Service) ColumnCondition(column string) (string, interface{}) {
+	switch s.MatchType {
+	case MatchPrefix:
+		return column + " GLOB ?", globEscaper.Replace(s.Name) + "*"
+	case MatchWildcard:
+		// Only * and ? are wildcards, brackets match literally
+		return column + " GLOB ?", strings.ReplaceAll(s.Name, "[", "[[]")
+	case MatchRegex:
+		return column + " REGEXP ?", s.Name
+	default:
+		return column + " = ?", s.Name
+	}
+}
+
+// Condition returns the SQL condition selecting the requests of the service, with one arg per placeholder
+func (s Service) Condition() (string, []interface{}) {
+	switch s.Type {
+	case TypeBackendName, TypeBackendURL, TypeHost:
+		cond, arg := s.ColumnCondition(s.Type)
+		return cond, []interface{}{arg}
+	default:
+		nameCond, arg := s.ColumnCondition("backend_name")
+		urlCond, _ := s.ColumnCondition("backend_url")
+		hostCond, _ := s.ColumnCondition("host")
+		return "(" + nameCond + " OR (backend_name = '' AND " + urlCond + ") OR (backend_name = '' AND backend_url = '' AND " + hostCond + "))",
+			[]interface{}{arg, arg, arg}
+	}
+}
+
+// AnyCondition ORs the conditions of several services, returns "" without filters
+func AnyCondition(filters []Service) (string, []interface{}) {
+	conds := make([]string, 0, len(filters))
+	args := make([]interface{}, 0, len(filters)*3)
+	for _, s := range filters {
+		cond, condArgs := s.Condition()
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// Condition returns the SQL condition keeping the requests not hidden by the filter, "" when it hides nothing
+func (e *ExcludeIP) Condition() (string, []interface{}) {
+	if e == nil || e.ClientIP == "" {
+		return "", nil
+	}
+	if len(e.ExcludeServices) == 0 {
+		return "client_ip != ?", []interface{}{e.ClientIP}
+	}
+	serviceCond, serviceArgs := AnyCondition(e.ExcludeServices)
+	return "NOT (client_ip = ? AND " + serviceCond + ")", append([]interface{}{e.ClientIP}, serviceArgs...)
+}
@@ -0,0 +1,118 @@
+package filter_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+	"loglynx/internal/filter"
+)
+
+func TestService_Validate(t *testing.T) {
+	valid := []filter.Service{
+		{Name: "api@docker"},
+		{Name: "api-", MatchType: filter.MatchPrefix},
+		{Name: "api-*@docker", MatchType: filter.MatchWildcard},
+		{Name: `^api-(v1|v2)@`, MatchType: filter.MatchRegex},
+	}
+	for _, service := range valid {
+		if err := service.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid: %v", service, err)
+		}
+	}
+
+	invalid := []filter.Service{
+		{Name: "api", MatchType: "like"},
+		{Name: "", MatchType: filter.MatchPrefix},
+		{Name: "api-(", MatchType: filter.MatchRegex},
+		{Name: strings.Repeat("a", 257), MatchType: filter.MatchWildcard},
+	}
+	for _, service := range invalid {
+		if err := service.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", service)
+		}
+	}
+}
+
+// Real-time metrics match events in memory while stats select them in SQL, both must agree
+func TestConditionsAgreeWithMatching(t *testing.T) {
+	db := fake.NewDB(t)
+
+	// The backend name hides the URL and host from auto filters, an empty one doesn't
+	requests := []*models.HTTPRequest{
+		{ClientIP: "10.0.0.1", BackendName: "api@docker", BackendURL: "http://api:8080", Host: "api.example.com"},
+		{ClientIP: "10.0.0.1", BackendName: "", BackendURL: "http://api:8080", Host: "web.example.com"},
+		{ClientIP: "10.0.0.2", BackendName: "", BackendURL: "", Host: "api.example.com"},
+		{ClientIP: "10.0.0.2", BackendName: "web@docker", BackendURL: "http://web:80", Host: "api.example.com"},
+	}
+	for i, request := range requests {
+		request.SourceName, request.Timestamp, request.RequestHash = "test", time.Now(), fmt.Sprint(i)
+		request.Method, request.Path = "GET", "/"
+		if err := db.Create(request).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	selected := func(cond string, args []interface{}) []bool {
+		t.Helper()
+		if placeholders := strings.Count(cond, "?"); placeholders != len(args) {
+			t.Fatalf("%s: %d placeholders for %d args", cond, placeholders, len(args))
+		}
+		var ids []uint
+		if err := db.Model(&models.HTTPRequest{}).Where(cond, args...).Order("id").Pluck("id", &ids).Error; err != nil {
+			t.Fatalf("%s: %v", cond, err)
+		}
+		found := make([]bool, len(requests))
+		for _, id := range ids {
+			for i, request := range requests {
+				found[i] = found[i] || request.ID == id
+			}
+		}
+		return found
+	}
+
+	services := []filter.Service{
+		{Name: "api@docker"},
+		{Name: "http://api:8080", Type: filter.TypeAuto},
+		{Name: "api.example.com"},
+		{Name: "api.example.com", Type: filter.TypeHost},
+		{Name: "http://api:8080", Type: filter.TypeBackendURL},
+		{Name: "web", Type: filter.TypeBackendName, MatchType: filter.MatchPrefix},
+		{Name: "api.*", MatchType: filter.MatchWildcard},
+		{Name: `^http://(api|web):`, MatchType: filter.MatchRegex},
+	}
+	for _, service := range services {
+		got := selected(service.Condition())
+		for i, request := range requests {
+			if matched := service.Matches(request.BackendName, request.BackendURL, request.Host); matched != got[i] {
+				t.Errorf("%+v on request %d: SQL selected %v, in memory %v", service, i, got[i], matched)
+			}
+		}
+	}
+
+	// The own IP excluded from all services, from one, and from two
+	excludes := []*filter.ExcludeIP{
+		{ClientIP: "10.0.0.1"},
+		{ClientIP: "10.0.0.1", ExcludeServices: []filter.Service{{Name: "api@docker"}}},
+		{ClientIP: "10.0.0.2", ExcludeServices: []filter.Service{{Name: "api.example.com"}, {Name: "web@docker"}}},
+	}
+	for _, exclude := range excludes {
+		got := selected(exclude.Condition())
+		for i, request := range requests {
+			if kept := !exclude.Excludes(request.ClientIP, request.BackendName, request.BackendURL, request.Host); kept != got[i] {
+				t.Errorf("%+v on request %d: SQL kept %v, in memory %v", exclude, i, got[i], kept)
+			}
+		}
+	}
+
+	var nilExclude *filter.ExcludeIP
+	if cond, _ := nilExclude.Condition(); cond != "" || nilExclude.Excludes("10.0.0.1", "", "", "") {
+		t.Error("Expected a nil filter to exclude nothing")
+	}
+	if cond, args := filter.AnyCondition(services[:2]); len(args) != 6 || !selected(cond, args)[1] {
+		t.Errorf("Expected 2 auto services to bind 6 args and select request 1, got %s %v", cond, args)
+	}
+}
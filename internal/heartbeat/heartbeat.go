@@ -0,0 +1,72 @@
+// Package heartbeat pings dead man's switch URLs (healthchecks.io, Uptime Kuma push monitors, Cronitor...)
+// A ping is only sent when the monitored job succeeded: operators are notified when pings stop arriving,
+// which also covers LogLynx being stopped or stuck. A nil *Pinger is valid and never pings.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"loglynx/internal/version"
+
+	"github.com/pterm/pterm"
+)
+
+// Pinger sends GET requests to a ping URL
+type Pinger struct {
+	client *http.Client
+	url    string
+	name   string // Job being monitored, for logs
+	logger *pterm.Logger
+}
+
+// NewPinger creates a pinger of pingURL for the named job
+func NewPinger(pingURL string, name string, timeout time.Duration, logger *pterm.Logger) (*Pinger, error) {
+	parsed, err := url.Parse(pingURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid %s healthcheck URL %q: expected http(s)://host/path", name, pingURL)
+	}
+	return &Pinger{
+		client: &http.Client{Timeout: timeout},
+		url:    pingURL,
+		name:   name,
+		logger: logger,
+	}, nil
+}
+
+// Ping tells the monitor the job is alive, failures are logged
+func (p *Pinger) Ping(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	if err := p.ping(ctx); err != nil {
+		p.logger.Warn("Failed to send healthcheck ping", p.logger.Args("job", p.name, "error", err))
+		return
+	}
+	p.logger.Trace("Healthcheck ping sent", p.logger.Args("job", p.name))
+}
+
+// ping sends one ping, any non-2xx response is an error
+func (p *Pinger) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "loglynx/"+version.Version)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("healthcheck URL returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package heartbeat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+func TestPinger(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+
+	pings := 0
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/ping/uuid" || !strings.HasPrefix(r.UserAgent(), "loglynx/") {
+			t.Errorf("Unexpected ping %s %s (%s)", r.Method, r.URL.Path, r.UserAgent())
+		}
+		pings++
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	pinger, err := NewPinger(server.URL+"/ping/uuid", "ingestion", time.Second, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pinger.ping(context.Background()); err != nil || pings != 1 {
+		t.Errorf("Expected one successful ping, got %d (%v)", pings, err)
+	}
+	status = http.StatusNotFound
+	if err := pinger.ping(context.Background()); err == nil {
+		t.Error("Expected an error for a 404 response")
+	}
+
+	// A nil pinger (healthcheck disabled) doesn't ping
+	var disabled *Pinger
+	disabled.Ping(context.Background())
+
+	for _, invalid := range []string{"hc-ping.com/uuid", "ftp://hc-ping.com/uuid", "https://"} {
+		if _, err := NewPinger(invalid, "cleanup", time.Second, log); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package i18n
+
+// catalogs are the supported locales by code
+// Keys group labels by where they appear: nav (sidebar), page (titles), header, filter, metric and common.
+var catalogs = map[string]*Catalog{
+	"en": {
+		Locale: "en",
+		Name:   "English",
+		Formats: Formats{
+			DecimalSeparator: ".",
+			GroupSeparator:   ",",
+			DateFormat:       "MM/DD/YYYY",
+			TimeFormat:       "h:mm A",
+			FirstDayOfWeek:   0,
+		},
+		Labels: map[string]string{
+			"nav.dashboards":  "Dashboards",
+			"nav.analytics":   "Analytics",
+			"nav.overview":    "Overview",
+			"nav.realtime":    "Real-time Monitor",
+			"nav.traffic":     "Traffic Analysis",
+			"nav.geographic":  "Geographic Analytics",
+			"nav.performance": "Performance",
+			"nav.security":    "Security & Network",
+			"nav.users":       "User Analytics",
+			"nav.content":     "Content Analytics",
+			"nav.backends":    "Backend Health",
+			"nav.system":      "System Stats",
+			"nav.caption":     "Advanced log analytics platform",
+
+			"page.overview":        "Executive Overview",
+			"page.realtime":        "Real-time Monitor",
+			"page.traffic":         "Traffic Analysis",
+			"page.performance":     "Performance Monitoring",
+			"page.security":        "Security & Network",
+			"page.users":           "User Analytics",
+			"page.content":         "Content Analytics",
+			"page.backends":        "Backend Health",
+			"page.geographic":      "Geographic Analytics",
+			"page.system":          "System Statistics",
+			"page.ip-detail":       "IP Analytics",
+			"page.consumer-detail": "Consumer Analytics",
+
+			"header.subtitle":        "Real-time Log Analytics",
+			"header.ip_search":       "IP Search",
+			"header.filters":         "Filters",
+			"header.all_services":    "All Services",
+			"header.all_traffic":     "All Traffic",
+			"header.search_services": "Search services...",
+			"header.hide_my_traffic": "Hide My Traffic",
+			"header.clear":           "Clear",
+
+			"filter.auto":         "Auto Detect",
+			"filter.backend_name": "Backend Name",
+			"filter.backend_url":  "Backend URL",
+			"filter.host":         "Host",
+
+			"metric.requests":        "Requests",
+			"metric.unique_visitors": "Unique Visitors",
+			"metric.bandwidth":       "Bandwidth",
+			"metric.response_time":   "Response Time",
+			"metric.error_rate":      "Error Rate",
+			"metric.status_code":     "Status Code",
+			"metric.country":         "Country",
+			"metric.path":            "Path",
+			"metric.backend":         "Backend",
+
+			"common.loading":  "Loading...",
+			"common.no_data":  "No data for the selected time range",
+			"common.error":    "Failed to load data",
+			"common.last_24h": "Last 24 hours",
+			"common.refresh":  "Refresh",
+		},
+	},
+	"de": {
+		Locale: "de",
+		Name:   "Deutsch",
+		Formats: Formats{
+			DecimalSeparator: ",",
+			GroupSeparator:   ".",
+			DateFormat:       "DD.MM.YYYY",
+			TimeFormat:       "HH:mm",
+			FirstDayOfWeek:   1,
+		},
+		Labels: map[string]string{
+			"nav.dashboards":  "Dashboards",
+			"nav.analytics":   "Analysen",
+			"nav.overview":    "Übersicht",
+			"nav.realtime":    "Echtzeit-Monitor",
+			"nav.traffic":     "Traffic-Analyse",
+			"nav.geographic":  "Geografische Analyse",
+			"nav.performance": "Performance",
+			"nav.security":    "Sicherheit & Netzwerk",
+			"nav.users":       "Nutzeranalyse",
+			"nav.content":     "Inhaltsanalyse",
+			"nav.backends":    "Backend-Zustand",
+			"nav.system":      "Systemstatistik",
+			"nav.caption":     "Plattform für Log-Analysen",
+
+			"page.overview":        "Übersicht",
+			"page.realtime":        "Echtzeit-Monitor",
+			"page.traffic":         "Traffic-Analyse",
+			"page.performance":     "Performance-Überwachung",
+			"page.security":        "Sicherheit & Netzwerk",
+			"page.users":           "Nutzeranalyse",
+			"page.content":         "Inhaltsanalyse",
+			"page.backends":        "Backend-Zustand",
+			"page.geographic":      "Geografische Analyse",
+			"page.system":          "Systemstatistik",
+			"page.ip-detail":       "IP-Analyse",
+			"page.consumer-detail": "Consumer-Analyse",
+
+			"header.subtitle":        "Log-Analyse in Echtzeit",
+			"header.ip_search":       "IP-Suche",
+			"header.filters":         "Filter",
+			"header.all_services":    "Alle Dienste",
+			"header.all_traffic":     "Gesamter Traffic",
+			"header.search_services": "Dienste suchen...",
+			"header.hide_my_traffic": "Eigenen Traffic ausblenden",
+			"header.clear":           "Zurücksetzen",
+
+			"filter.auto":         "Automatisch",
+			"filter.backend_name": "Backend-Name",
+			"filter.backend_url":  "Backend-URL",
+			"filter.host":         "Host",
+
+			"metric.requests":        "Anfragen",
+			"metric.unique_visitors": "Eindeutige Besucher",
+			"metric.bandwidth":       "Bandbreite",
+			"metric.response_time":   "Antwortzeit",
+			"metric.error_rate":      "Fehlerquote",
+			"metric.status_code":     "Statuscode",
+			"metric.country":         "Land",
+			"metric.path":            "Pfad",
+			"metric.backend":         "Backend",
+
+			"common.loading":  "Wird geladen...",
+			"common.no_data":  "Keine Daten im gewählten Zeitraum",
+			"common.error":    "Daten konnten nicht geladen werden",
+			"common.last_24h": "Letzte 24 Stunden",
+			"common.refresh":  "Aktualisieren",
+		},
+	},
+	"fr": {
+		Locale: "fr",
+		Name:   "Français",
+		Formats: Formats{
+			DecimalSeparator: ",",
+			GroupSeparator:   " ",
+			DateFormat:       "DD/MM/YYYY",
+			TimeFormat:       "HH:mm",
+			FirstDayOfWeek:   1,
+		},
+		Labels: map[string]string{
+			"nav.dashboards":  "Tableaux de bord",
+			"nav.analytics":   "Analyses",
+			"nav.overview":    "Vue d'ensemble",
+			"nav.realtime":    "Suivi en temps réel",
+			"nav.traffic":     "Analyse du trafic",
+			"nav.geographic":  "Analyse géographique",
+			"nav.performance": "Performances",
+			"nav.security":    "Sécurité et réseau",
+			"nav.users":       "Analyse des utilisateurs",
+			"nav.content":     "Analyse du contenu",
+			"nav.backends":    "État des backends",
+			"nav.system":      "Statistiques système",
+			"nav.caption":     "Plateforme d'analyse de logs",
+
+			"page.overview":        "Vue d'ensemble",
+			"page.realtime":        "Suivi en temps réel",
+			"page.traffic":         "Analyse du trafic",
+			"page.performance":     "Suivi des performances",
+			"page.security":        "Sécurité et réseau",
+			"page.users":           "Analyse des utilisateurs",
+			"page.content":         "Analyse du contenu",
+			"page.backends":        "État des backends",
+			"page.geographic":      "Analyse géographique",
+			"page.system":          "Statistiques système",
+			"page.ip-detail":       "Analyse d'IP",
+			"page.consumer-detail": "Analyse du consommateur",
+
+			"header.subtitle":        "Analyse de logs en temps réel",
+			"header.ip_search":       "Recherche d'IP",
+			"header.filters":         "Filtres",
+			"header.all_services":    "Tous les services",
+			"header.all_traffic":     "Tout le trafic",
+			"header.search_services": "Rechercher des services...",
+			"header.hide_my_traffic": "Masquer mon trafic",
+			"header.clear":           "Effacer",
+
+			"filter.auto":         "Détection automatique",
+			"filter.backend_name": "Nom du backend",
+			"filter.backend_url":  "URL du backend",
+			"filter.host":         "Hôte",
+
+			"metric.requests":        "Requêtes",
+			"metric.unique_visitors": "Visiteurs uniques",
+			"metric.bandwidth":       "Bande passante",
+			"metric.response_time":   "Temps de réponse",
+			"metric.error_rate":      "Taux d'erreur",
+			"metric.status_code":     "Code de statut",
+			"metric.country":         "Pays",
+			"metric.path":            "Chemin",
+			"metric.backend":         "Backend",
+
+			"common.loading":  "Chargement...",
+			"common.no_data":  "Aucune donnée sur la période sélectionnée",
+			"common.error":    "Échec du chargement des données",
+			"common.last_24h": "Dernières 24 heures",
+			"common.refresh":  "Actualiser",
+		},
+	},
+	"es": {
+		Locale: "es",
+		Name:   "Español",
+		Formats: Formats{
+			DecimalSeparator: ",",
+			GroupSeparator:   ".",
+			DateFormat:       "DD/MM/YYYY",
+			TimeFormat:       "HH:mm",
+			FirstDayOfWeek:   1,
+		},
+		Labels: map[string]string{
+			"nav.dashboards":  "Paneles",
+			"nav.analytics":   "Análisis",
+			"nav.overview":    "Resumen",
+			"nav.realtime":    "Monitor en tiempo real",
+			"nav.traffic":     "Análisis de tráfico",
+			"nav.geographic":  "Análisis geográfico",
+			"nav.performance": "Rendimiento",
+			"nav.security":    "Seguridad y red",
+			"nav.users":       "Análisis de usuarios",
+			"nav.content":     "Análisis de contenido",
+			"nav.backends":    "Estado de los backends",
+			"nav.system":      "Estadísticas del sistema",
+			"nav.caption":     "Plataforma de análisis de logs",
+
+			"page.overview":        "Resumen ejecutivo",
+			"page.realtime":        "Monitor en tiempo real",
+			"page.traffic":         "Análisis de tráfico",
+			"page.performance":     "Monitorización del rendimiento",
+			"page.security":        "Seguridad y red",
+			"page.users":           "Análisis de usuarios",
+			"page.content":         "Análisis de contenido",
+			"page.backends":        "Estado de los backends",
+			"page.geographic":      "Análisis geográfico",
+			"page.system":          "Estadísticas del sistema",
+			"page.ip-detail":       "Análisis de IP",
+			"page.consumer-detail": "Análisis del consumidor",
+
+			"header.subtitle":        "Análisis de logs en tiempo real",
+			"header.ip_search":       "Buscar IP",
+			"header.filters":         "Filtros",
+			"header.all_services":    "Todos los servicios",
+			"header.all_traffic":     "Todo el tráfico",
+			"header.search_services": "Buscar servicios...",
+			"header.hide_my_traffic": "Ocultar mi tráfico",
+			"header.clear":           "Limpiar",
+
+			"filter.auto":         "Detección automática",
+			"filter.backend_name": "Nombre del backend",
+			"filter.backend_url":  "URL del backend",
+			"filter.host":         "Host",
+
+			"metric.requests":        "Peticiones",
+			"metric.unique_visitors": "Visitantes únicos",
+			"metric.bandwidth":       "Ancho de banda",
+			"metric.response_time":   "Tiempo de respuesta",
+			"metric.error_rate":      "Tasa de errores",
+			"metric.status_code":     "Código de estado",
+			"metric.country":         "País",
+			"metric.path":            "Ruta",
+			"metric.backend":         "Backend",
+
+			"common.loading":  "Cargando...",
+			"common.no_data":  "No hay datos en el periodo seleccionado",
+			"common.error":    "No se pudieron cargar los datos",
+			"common.last_24h": "Últimas 24 horas",
+			"common.refresh":  "Actualizar",
+		},
+	},
+	"it": {
+		Locale: "it",
+		Name:   "Italiano",
+		Formats: Formats{
+			DecimalSeparator: ",",
+			GroupSeparator:   ".",
+			DateFormat:       "DD/MM/YYYY",
+			TimeFormat:       "HH:mm",
+			FirstDayOfWeek:   1,
+		},
+		Labels: map[string]string{
+			"nav.dashboards":  "Dashboard",
+			"nav.analytics":   "Analisi",
+			"nav.overview":    "Panoramica",
+			"nav.realtime":    "Monitor in tempo reale",
+			"nav.traffic":     "Analisi del traffico",
+			"nav.geographic":  "Analisi geografica",
+			"nav.performance": "Prestazioni",
+			"nav.security":    "Sicurezza e rete",
+			"nav.users":       "Analisi degli utenti",
+			"nav.content":     "Analisi dei contenuti",
+			"nav.backends":    "Stato dei backend",
+			"nav.system":      "Statistiche di sistema",
+			"nav.caption":     "Piattaforma di analisi dei log",
+
+			"page.overview":        "Panoramica",
+			"page.realtime":        "Monitor in tempo reale",
+			"page.traffic":         "Analisi del traffico",
+			"page.performance":     "Monitoraggio delle prestazioni",
+			"page.security":        "Sicurezza e rete",
+			"page.users":           "Analisi degli utenti",
+			"page.content":         "Analisi dei contenuti",
+			"page.backends":        "Stato dei backend",
+			"page.geographic":      "Analisi geografica",
+			"page.system":          "Statistiche di sistema",
+			"page.ip-detail":       "Analisi IP",
+			"page.consumer-detail": "Analisi del consumer",
+
+			"header.subtitle":        "Analisi dei log in tempo reale",
+			"header.ip_search":       "Cerca IP",
+			"header.filters":         "Filtri",
+			"header.all_services":    "Tutti i servizi",
+			"header.all_traffic":     "Tutto il traffico",
+			"header.search_services": "Cerca servizi...",
+			"header.hide_my_traffic": "Nascondi il mio traffico",
+			"header.clear":           "Azzera",
+
+			"filter.auto":         "Rilevamento automatico",
+			"filter.backend_name": "Nome backend",
+			"filter.backend_url":  "URL backend",
+			"filter.host":         "Host",
+
+			"metric.requests":        "Richieste",
+			"metric.unique_visitors": "Visitatori unici",
+			"metric.bandwidth":       "Banda",
+			"metric.response_time":   "Tempo di risposta",
+			"metric.error_rate":      "Tasso di errore",
+			"metric.status_code":     "Codice di stato",
+			"metric.country":         "Paese",
+			"metric.path":            "Percorso",
+			"metric.backend":         "Backend",
+
+			"common.loading":  "Caricamento...",
+			"common.no_data":  "Nessun dato nel periodo selezionato",
+			"common.error":    "Impossibile caricare i dati",
+			"common.last_24h": "Ultime 24 ore",
+			"common.refresh":  "Aggiorna",
+		},
+	},
+}
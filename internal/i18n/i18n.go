@@ -0,0 +1,122 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when no supported locale is requested, its catalog has every label
+const DefaultLocale = "en"
+
+// Formats are the formatting hints of a locale, for clients formatting numbers and dates themselves
+type Formats struct {
+	DecimalSeparator string `json:"decimal_separator"`
+	GroupSeparator   string `json:"group_separator"`
+	DateFormat       string `json:"date_format"`       // e.g. "DD/MM/YYYY"
+	TimeFormat       string `json:"time_format"`       // "HH:mm" (24-hour) or "h:mm A" (12-hour)
+	FirstDayOfWeek   int    `json:"first_day_of_week"` // 0 = Sunday, 1 = Monday
+}
+
+// Catalog holds the labels of the dashboard in one locale
+type Catalog struct {
+	Locale  string            `json:"locale"`
+	Name    string            `json:"name"` // Native name of the language, e.g. "Deutsch"
+	Formats Formats           `json:"formats"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Locale describes a supported locale, without its labels
+type Locale struct {
+	Locale string `json:"locale"`
+	Name   string `json:"name"`
+}
+
+// Locales returns the supported locales, sorted by code
+func Locales() []Locale {
+	locales := make([]Locale, 0, len(catalogs))
+	for _, catalog := range catalogs {
+		locales = append(locales, Locale{Locale: catalog.Locale, Name: catalog.Name})
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i].Locale < locales[j].Locale })
+	return locales
+}
+
+// Normalize returns the supported locale of a language tag ("de-CH" and "DE" are "de"), empty when unsupported
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// Lookup returns the catalog of a locale, labels it doesn't translate are in English
+func Lookup(tag string) (*Catalog, bool) {
+	locale := Normalize(tag)
+	if locale == "" {
+		return nil, false
+	}
+	catalog := *catalogs[locale]
+	catalog.Labels = make(map[string]string, len(catalogs[DefaultLocale].Labels))
+	for key, label := range catalogs[DefaultLocale].Labels {
+		catalog.Labels[key] = label
+	}
+	for key, label := range catalogs[locale].Labels {
+		catalog.Labels[key] = label
+	}
+	return &catalog, true
+}
+
+// T returns the label of a key in a locale, falling back to English, then to the key itself
+func T(locale, key string) string {
+	if catalog, ok := catalogs[Normalize(locale)]; ok {
+		if label, ok := catalog.Labels[key]; ok {
+			return label
+		}
+	}
+	if label, ok := catalogs[DefaultLocale].Labels[key]; ok {
+		return label
+	}
+	return key
+}
+
+// Negotiate returns the supported locale preferred by an Accept-Language header, fallback when none is
+// Languages are tried by decreasing quality, in header order for equal qualities; q=0 excludes a language.
+func Negotiate(acceptLanguage, fallback string) string {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+	var candidates []candidate
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	for _, candidate := range candidates {
+		if locale := Normalize(candidate.tag); locale != "" {
+			return locale
+		}
+	}
+	return fallback
+}
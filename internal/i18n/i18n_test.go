@@ -0,0 +1,66 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", DefaultLocale},
+		{"de-CH,de;q=0.9,en;q=0.8", "de"},
+		{"ja,fr;q=0.5,it;q=0.7", "it"},
+		{"fr;q=0,es", "es"},
+		{"pt-BR, *;q=0.5", DefaultLocale},
+		{"EN_us", "en"},
+		{"it;q=abc,es;q=0.1", "es"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, DefaultLocale); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogsTranslateOnlyKnownKeys(t *testing.T) {
+	english := catalogs[DefaultLocale].Labels
+	for locale, catalog := range catalogs {
+		if catalog.Locale != locale {
+			t.Errorf("catalog %q has locale %q", locale, catalog.Locale)
+		}
+		for key := range catalog.Labels {
+			if _, ok := english[key]; !ok {
+				t.Errorf("catalog %q has key %q missing from the English catalog", locale, key)
+			}
+		}
+	}
+}
+
+func TestLookupFallsBackToEnglish(t *testing.T) {
+	catalogs["xx"] = &Catalog{Locale: "xx", Name: "Test", Labels: map[string]string{"nav.overview": "Xx"}}
+	defer delete(catalogs, "xx")
+
+	catalog, ok := Lookup("xx-YY")
+	if !ok {
+		t.Fatal("expected xx-YY to resolve to xx")
+	}
+	if catalog.Labels["nav.overview"] != "Xx" || catalog.Labels["nav.system"] != "System Stats" {
+		t.Errorf("unexpected labels: %q, %q", catalog.Labels["nav.overview"], catalog.Labels["nav.system"])
+	}
+	if len(catalogs["xx"].Labels) != 1 {
+		t.Error("Lookup modified the stored catalog")
+	}
+	if _, ok := Lookup("pt"); ok {
+		t.Error("expected pt to be unsupported")
+	}
+
+	if got := T("de", "nav.overview"); got != "Übersicht" {
+		t.Errorf("T(de) = %q", got)
+	}
+	if got := T("pt", "nav.overview"); got != "Overview" {
+		t.Errorf("T(pt) = %q", got)
+	}
+	if got := T("de", "unknown.key"); got != "unknown.key" {
+		t.Errorf("T(unknown) = %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+package ingestion
+
+import (
+	"time"
+)
+
+const (
+	// tuneInterval is how often the arrival rate is sampled to adapt batching
+	tuneInterval = 5 * time.Second
+	// rateSmoothing is the EWMA weight of the latest arrival rate sample
+	rateSmoothing = 0.3
+	// targetFillTime is how long a full batch should take to accumulate at the current rate
+	targetFillTime = 1 * time.Second
+)
+
+// BatchSettings defines the bounds the processor adapts batch size and flush interval within
+// When Adaptive is false, MaxSize and MaxFlush are used as static values
+type BatchSettings struct {
+	Adaptive bool
+	MinSize  int
+	MaxSize  int
+	MinFlush time.Duration
+	MaxFlush time.Duration
+}
+
+// normalized applies defaults and fixes inverted bounds
+func (s BatchSettings) normalized() BatchSettings {
+	if s.MaxSize <= 0 {
+		s.MaxSize = 1000
+	}
+	if s.MinSize <= 0 || s.MinSize > s.MaxSize {
+		s.MinSize = s.MaxSize
+	}
+	if s.MaxFlush <= 0 {
+		s.MaxFlush = 30 * time.Second
+	}
+	if s.MinFlush <= 0 || s.MinFlush > s.MaxFlush {
+		s.MinFlush = s.MaxFlush
+	}
+	return s
+}
+
+// batchTuner picks the batch size and flush interval from the recent arrival rate
+// Low traffic gets small batches flushed quickly (data shows up fast),
+// high traffic gets large batches (fewer, cheaper transactions)
+type batchTuner struct {
+	settings      BatchSettings
+	rate          float64 // Smoothed arrival rate (lines/sec)
+	windowLines   int
+	windowStart   time.Time
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// newBatchTuner starts with the largest batches so an initial import isn't throttled
+func newBatchTuner(settings BatchSettings, now time.Time) *batchTuner {
+	settings = settings.normalized()
+	return &batchTuner{
+		settings:      settings,
+		windowStart:   now,
+		batchSize:     settings.MaxSize,
+		flushInterval: settings.MaxFlush,
+	}
+}
+
+// observe records lines read and returns true when a new rate sample was taken
+func (t *batchTuner) observe(lines int, now time.Time) bool {
+	if !t.settings.Adaptive {
+		return false
+	}
+
+	t.windowLines += lines
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < tuneInterval {
+		return false
+	}
+
+	sample := float64(t.windowLines) / elapsed.Seconds()
+	t.rate = rateSmoothing*sample + (1-rateSmoothing)*t.rate
+	t.windowLines = 0
+	t.windowStart = now
+
+	t.batchSize, t.flushInterval = t.settingsForRate(t.rate)
+	return true
+}
+
+// settingsForRate sizes batches to fill in about targetFillTime, and scales the flush
+// interval with the batch size so quiet sources are flushed after MinFlush
+func (t *batchTuner) settingsForRate(rate float64) (int, time.Duration) {
+	s := t.settings
+
+	batchSize := int(rate * targetFillTime.Seconds())
+	if batchSize < s.MinSize {
+		batchSize = s.MinSize
+	}
+	if batchSize > s.MaxSize {
+		batchSize = s.MaxSize
+	}
+
+	flushInterval := s.MinFlush
+	if s.MaxSize > s.MinSize {
+		fraction := float64(batchSize-s.MinSize) / float64(s.MaxSize-s.MinSize)
+		flushInterval += time.Duration(fraction * float64(s.MaxFlush-s.MinFlush))
+	}
+
+	return batchSize, flushInterval.Round(100 * time.Millisecond)
+}
@@ -2,6 +2,7 @@ package ingestion
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -9,24 +10,51 @@ import (
 	"loglynx/internal/database/repositories"
 	"loglynx/internal/enrichment"
 	parsers "loglynx/internal/parser"
+	"loglynx/internal/parser/waf"
+	"loglynx/internal/statsd"
+	"loglynx/internal/transform"
 
 	"github.com/pterm/pterm"
 )
 
+// EventRecorder receives batches after they have been written to the database
+// Used to feed in-memory consumers such as the real-time metrics collector
+type EventRecorder interface {
+	Record(batch []*models.HTTPRequest)
+}
+
+// Enricher adds data to parsed requests after the built-in enrichment, e.g. an enricher plugin
+type Enricher interface {
+	Name() string
+	Enrich(request *models.HTTPRequest) error
+}
+
 // Coordinator manages multiple source processors
 type Coordinator struct {
 	sourceRepo          repositories.LogSourceRepository
 	httpRepo            repositories.HTTPRequestRepository
 	parserReg           *parsers.Registry
 	geoIP               *enrichment.GeoIPEnricher
+	recorder            EventRecorder               // Optional, may be nil
+	metrics             *statsd.Client              // Optional, nil when StatsD is disabled
+	dedupOptions        *DedupOptions               // Line offset hashing mode per parser type
+	fieldMappings       *FieldMappings              // Field overrides per source, nil keeps the parsed fields
+	forwarded           *ForwardedResolver          // Client resolution behind trusted proxies, nil keeps the parsed client
+	enrichers           []Enricher                  // Applied in order after GeoIP and user agent parsing
+	transforms          *transform.Rules            // User rules dropping or rewriting requests, nil keeps them
+	labels              *LabelRules                 // Labels assigned from request fields, nil assigns none
+	pool                *WorkerPool                 // Shared parsing/enrichment pool (created on Start)
+	writer              *BatchWriter                // Single database writer for all sources (created on Start)
 	processors          map[string]*SourceProcessor // Changed from slice to map for O(1) lookup by source name
+	recoveries          map[string]*recoveryTracker // Sources found not stopped cleanly at startup, kept across restarts
 	logger              *pterm.Logger
 	mu                  sync.RWMutex
 	isRunning           bool
-	initialImportDays   int  // Number of days to import on first run (0 = all)
-	initialImportEnable bool // Enable initial import limiting
-	batchSize           int  // Batch size for log processing
-	workerPoolSize      int  // Worker pool size for parallel parsing
+	initialImportDays   int           // Number of days to import on first run (0 = all)
+	initialImportEnable bool          // Enable initial import limiting
+	batching            BatchSettings // Batch size and flush interval bounds
+	workerPoolSize      int           // Total workers shared by all sources for parsing and enrichment
+	maxLineLength       int           // Lines longer than this (bytes) are skipped
 }
 
 // NewCoordinator creates a new ingestion coordinator
@@ -35,25 +63,55 @@ func NewCoordinator(
 	httpRepo repositories.HTTPRequestRepository,
 	parserReg *parsers.Registry,
 	geoIP *enrichment.GeoIPEnricher,
+	recorder EventRecorder,
+	metrics *statsd.Client,
+	dedupOptions *DedupOptions,
+	fieldMappings *FieldMappings,
+	forwarded *ForwardedResolver,
+	enrichers []Enricher,
+	transforms *transform.Rules,
+	labels *LabelRules,
 	logger *pterm.Logger,
 	initialImportDays int,
 	initialImportEnable bool,
-	batchSize int,
+	batching BatchSettings,
 	workerPoolSize int,
+	maxLineLength int,
 ) *Coordinator {
 	return &Coordinator{
 		sourceRepo:          sourceRepo,
 		httpRepo:            httpRepo,
 		parserReg:           parserReg,
 		geoIP:               geoIP,
+		recorder:            recorder,
+		metrics:             metrics,
+		dedupOptions:        dedupOptions,
+		fieldMappings:       fieldMappings,
+		forwarded:           forwarded,
+		enrichers:           enrichers,
+		transforms:          transforms,
+		labels:              labels,
 		processors:          make(map[string]*SourceProcessor),
+		recoveries:          make(map[string]*recoveryTracker),
 		logger:              logger,
 		isRunning:           false,
 		initialImportDays:   initialImportDays,
 		initialImportEnable: initialImportEnable,
-		batchSize:           batchSize,
+		batching:            batching,
 		workerPoolSize:      workerPoolSize,
+		maxLineLength:       maxLineLength,
+	}
+}
+
+// requestSources drops the WAF audit log sources, which the WAF ingester reads
+func requestSources(sources []*models.LogSource) []*models.LogSource {
+	filtered := make([]*models.LogSource, 0, len(sources))
+	for _, source := range sources {
+		if source.ParserType != waf.ParserType {
+			filtered = append(filtered, source)
+		}
 	}
+	return filtered
 }
 
 // Start initializes and starts all source processors
@@ -68,6 +126,20 @@ func (c *Coordinator) Start() error {
 
 	c.logger.Info("Starting ingestion coordinator...")
 
+	// A parser field renamed without its column would stop being stored without any error
+	if err := ValidateEventMapping(c.parserReg); err != nil {
+		c.logger.WithCaller().Error("Parser events don't match the request columns, these fields are dropped",
+			c.logger.Args("error", err))
+	}
+
+	// One bounded pool for all sources instead of a pool per source
+	c.pool = NewWorkerPool(c.workerPoolSize, c.logger)
+	c.pool.Start()
+
+	// One writer for all sources instead of processors contending for SQLite's write lock
+	c.writer = NewBatchWriter(c.httpRepo, DefaultWriterMaxRecords, c.logger)
+	c.writer.Start()
+
 	// Load all sources from database
 	sources, err := c.sourceRepo.FindAll()
 	if err != nil {
@@ -75,6 +147,7 @@ func (c *Coordinator) Start() error {
 			c.logger.Args("error", err))
 		return fmt.Errorf("failed to load log sources: %w", err)
 	}
+	sources = requestSources(sources)
 
 	if len(sources) == 0 {
 		c.logger.Warn("No log sources found in database. Please run discovery first or configure log sources manually.")
@@ -140,10 +213,21 @@ func (c *Coordinator) startSourceProcessorLocked(source *models.LogSource) error
 		c.httpRepo,
 		c.sourceRepo,
 		c.geoIP,
+		c.recorder,
+		c.metrics,
+		c.pool,
+		c.writer,
+		c.dedupOptions.ModeFor(source.ParserType),
+		c.fieldMappings.For(source.Name),
+		c.forwarded,
+		c.enrichers,
+		c.transforms,
+		c.labels,
 		c.logger,
-		c.batchSize,
-		c.workerPoolSize,
+		c.batching,
+		c.maxLineLength,
 	)
+	processor.recovery = c.recoveryFor(source)
 
 	// Apply initial import limit if enabled and this is a new source
 	if c.initialImportEnable && c.initialImportDays > 0 {
@@ -197,6 +281,18 @@ func (c *Coordinator) Stop() {
 	// Wait for all processors to stop
 	wg.Wait()
 
+	// Processors are stopped, nothing can submit jobs anymore
+	if c.pool != nil {
+		c.pool.Stop()
+		c.pool = nil
+	}
+
+	// Processors flushed their last batches, commit what is still queued
+	if c.writer != nil {
+		c.writer.Stop()
+		c.writer = nil
+	}
+
 	// Clear processors map
 	c.processors = make(map[string]*SourceProcessor)
 	c.isRunning = false
@@ -204,15 +300,54 @@ func (c *Coordinator) Stop() {
 	c.logger.Info("Ingestion coordinator stopped successfully")
 }
 
+// CoordinatorStatus describes the ingestion pipeline state
+type CoordinatorStatus struct {
+	IsRunning        bool              `json:"is_running"`
+	ActiveProcessors int               `json:"active_processors"`
+	WorkerPool       *WorkerPoolStats  `json:"worker_pool,omitempty"`
+	Writer           *BatchWriterStats `json:"writer,omitempty"`
+	Processors       []ProcessorStats  `json:"processors"`
+	Recovery         RecoveryStatus    `json:"recovery"` // Sources read again after an unclean shutdown
+}
+
 // GetStatus returns the current status of the coordinator
-func (c *Coordinator) GetStatus() map[string]interface{} {
+func (c *Coordinator) GetStatus() CoordinatorStatus {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return map[string]interface{}{
-		"is_running":        c.isRunning,
-		"active_processors": len(c.processors),
+	status := CoordinatorStatus{
+		IsRunning:        c.isRunning,
+		ActiveProcessors: len(c.processors),
+		Processors:       make([]ProcessorStats, 0, len(c.processors)),
+		Recovery:         RecoveryStatus{Sources: make([]SourceRecovery, 0, len(c.recoveries))},
 	}
+
+	if c.pool != nil {
+		poolStats := c.pool.Stats()
+		status.WorkerPool = &poolStats
+	}
+
+	if c.writer != nil {
+		writerStats := c.writer.Stats()
+		status.Writer = &writerStats
+	}
+
+	for _, processor := range c.processors {
+		status.Processors = append(status.Processors, processor.Stats())
+	}
+	sort.Slice(status.Processors, func(i, j int) bool {
+		return status.Processors[i].Source < status.Processors[j].Source
+	})
+
+	for _, recovery := range c.recoveries {
+		status.Recovery.Sources = append(status.Recovery.Sources, recovery.snapshot())
+	}
+	sort.Slice(status.Recovery.Sources, func(i, j int) bool {
+		return status.Recovery.Sources[i].Source < status.Recovery.Sources[j].Source
+	})
+	status.Recovery.UncleanShutdown = len(status.Recovery.Sources) > 0
+
+	return status
 }
 
 // IsRunning returns whether the coordinator is currently running
@@ -229,6 +364,32 @@ func (c *Coordinator) GetProcessorCount() int {
 	return len(c.processors)
 }
 
+// recoveryFor returns the recovery of a source whose processor didn't stop cleanly, nil for the others
+// A recovery interrupted by a restart (e.g. the maintenance window) goes on with the new processor.
+// IMPORTANT: Caller must hold c.mu lock
+func (c *Coordinator) recoveryFor(source *models.LogSource) *recoveryTracker {
+	if recovery, exists := c.recoveries[source.Name]; exists && recovery.snapshot().CompletedAt == nil {
+		return recovery
+	}
+	if !source.ProcessorRunning {
+		return nil
+	}
+
+	recovery := newRecoveryTracker(source, time.Now())
+	c.recoveries[source.Name] = recovery
+	report := recovery.snapshot()
+	c.logger.Warn("Unclean shutdown detected, re-processing from the last saved position",
+		c.logger.Args(
+			"source", source.Name,
+			"position", report.Position,
+			"file_size", report.FileSize,
+			"reprocess_bytes", report.ReprocessBytes,
+			"file_replaced", report.FileReplaced,
+			"position_saved_at", report.PositionSavedAt,
+		))
+	return recovery
+}
+
 // Restart stops and restarts the coordinator
 func (c *Coordinator) Restart() error {
 	c.logger.Info("Restarting ingestion coordinator...")
@@ -311,6 +472,7 @@ func (c *Coordinator) SyncWithDatabase() error {
 			c.logger.Args("error", err))
 		return fmt.Errorf("failed to load log sources: %w", err)
 	}
+	sources = requestSources(sources)
 
 	// Build map of database sources for efficient lookup
 	dbSources := make(map[string]*models.LogSource)
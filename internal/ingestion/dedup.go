@@ -0,0 +1,107 @@
+package ingestion
+
+import (
+	"fmt"
+	"strings"
+
+	parsers "loglynx/internal/parser"
+)
+
+// LineOffsetMode controls whether the file offset of a line is part of its dedup hash
+type LineOffsetMode string
+
+const (
+	// LineOffsetAuto adds the offset only for events without nanosecond timing (e.g. CLF logs)
+	LineOffsetAuto LineOffsetMode = "auto"
+	// LineOffsetAlways adds the offset to every hash
+	LineOffsetAlways LineOffsetMode = "always"
+	// LineOffsetNever keeps the hash purely content-based
+	LineOffsetNever LineOffsetMode = "never"
+)
+
+// DedupOptions holds the line offset mode, globally and per parser type
+type DedupOptions struct {
+	defaultMode LineOffsetMode
+	parserModes map[string]LineOffsetMode
+}
+
+// NewDefaultDedupOptions returns options using the offset only where timing is imprecise
+func NewDefaultDedupOptions() *DedupOptions {
+	return &DedupOptions{defaultMode: LineOffsetAuto}
+}
+
+// ParseDedupOptions builds dedup options from the global mode and per-parser overrides
+// Overrides are semicolon-separated "parser:mode" pairs, e.g. "traefik:always;apache:never"
+func ParseDedupOptions(defaultMode string, parserModes string) (*DedupOptions, error) {
+	opts := NewDefaultDedupOptions()
+
+	if strings.TrimSpace(defaultMode) != "" {
+		mode, err := parseLineOffsetMode(defaultMode)
+		if err != nil {
+			return nil, err
+		}
+		opts.defaultMode = mode
+	}
+
+	for _, entry := range strings.Split(parserModes, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid parser dedup mode %q: expected parser:mode", entry)
+		}
+
+		mode, err := parseLineOffsetMode(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.parserModes == nil {
+			opts.parserModes = make(map[string]LineOffsetMode)
+		}
+		opts.parserModes[strings.TrimSpace(parts[0])] = mode
+	}
+
+	return opts, nil
+}
+
+// parseLineOffsetMode validates a mode string
+func parseLineOffsetMode(value string) (LineOffsetMode, error) {
+	mode := LineOffsetMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case LineOffsetAuto, LineOffsetAlways, LineOffsetNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid line offset mode %q (expected auto, always or never)", value)
+	}
+}
+
+// ModeFor returns the line offset mode for a parser type
+func (o *DedupOptions) ModeFor(parserType string) LineOffsetMode {
+	if o == nil {
+		return LineOffsetAuto
+	}
+	if mode, ok := o.parserModes[parserType]; ok {
+		return mode
+	}
+	return o.defaultMode
+}
+
+// useLineOffset reports whether the line offset must be part of an event's hash
+// Events that don't report their precision are treated as imprecise
+func useLineOffset(mode LineOffsetMode, event parsers.Event) bool {
+	switch mode {
+	case LineOffsetAlways:
+		return true
+	case LineOffsetNever:
+		return false
+	}
+
+	if precise, ok := event.(parsers.PrecisionReporter); ok {
+		return !precise.HasPreciseTiming()
+	}
+	return true
+}
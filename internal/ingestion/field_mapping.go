@@ -0,0 +1,210 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	parsers "loglynx/internal/parser"
+)
+
+// mappableFields maps the field names of field mapping overrides to the event fields they replace
+// Text and integer fields can be remapped, events without the field ignore the override.
+var mappableFields = map[string]string{
+	"client_ip":             "ClientIP",
+	"client_user":           "ClientUser",
+	"host":                  "Host",
+	"method":                "Method",
+	"path":                  "Path",
+	"query_string":          "QueryString",
+	"request_scheme":        "RequestScheme",
+	"user_agent":            "UserAgent",
+	"referer":               "Referer",
+	"request_id":            "RequestID",
+	"trace_id":              "TraceID",
+	"backend_name":          "BackendName",
+	"backend_url":           "BackendURL",
+	"router_name":           "RouterName",
+	"upstream_addr":         "UpstreamAddr",
+	"request_header_bytes":  "RequestHeaderBytes",
+	"response_header_bytes": "ResponseHeaderBytes",
+}
+
+// MappableFields returns the field names accepted by field mapping overrides
+func MappableFields() []string {
+	fields := make([]string, 0, len(mappableFields))
+	for field := range mappableFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// FieldMapping replaces parsed event fields with values read from other keys of JSON log lines,
+// e.g. the client IP from request_Cf-Connecting-Ip behind Cloudflare
+type FieldMapping struct {
+	keys map[string][]string // Event field name -> JSON key, or path segments of a nested key
+}
+
+// FieldMappings are the field mapping overrides per source name
+type FieldMappings struct {
+	sources  map[string]*FieldMapping
+	fallback *FieldMapping // Mapping of "*", for sources without their own
+}
+
+// ParseFieldMappings parses semicolon-separated "source:field=key,field=key" overrides, "*" applying to
+// every source without its own, e.g. "edge:client_ip=request_Cf-Connecting-Ip,host=request_X-Forwarded-Host"
+// Keys are keys of the JSON log line; a key missing from the line is read as a dotted path (request.headers.host).
+func ParseFieldMappings(list string) (*FieldMappings, error) {
+	mappings := &FieldMappings{}
+
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		source, fields, ok := strings.Cut(entry, ":")
+		source = strings.TrimSpace(source)
+		if !ok || source == "" {
+			return nil, fmt.Errorf("invalid field mapping %q: expected source:field=key", entry)
+		}
+
+		mapping := &FieldMapping{keys: make(map[string][]string)}
+		for _, pair := range strings.Split(fields, ",") {
+			field, key, ok := strings.Cut(pair, "=")
+			field, key = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(key)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid field mapping %q of source %q: expected field=key", strings.TrimSpace(pair), source)
+			}
+			eventField, known := mappableFields[field]
+			if !known {
+				return nil, fmt.Errorf("unknown field %q in the mapping of source %q (expected one of %s)", field, source, strings.Join(MappableFields(), ", "))
+			}
+			if _, duplicate := mapping.keys[eventField]; duplicate {
+				return nil, fmt.Errorf("field %q mapped twice for source %q", field, source)
+			}
+			mapping.keys[eventField] = strings.Split(key, ".")
+		}
+
+		if source == "*" {
+			if mappings.fallback != nil {
+				return nil, fmt.Errorf("duplicate field mapping for source %q", source)
+			}
+			mappings.fallback = mapping
+			continue
+		}
+		if mappings.sources == nil {
+			mappings.sources = make(map[string]*FieldMapping)
+		}
+		if _, duplicate := mappings.sources[source]; duplicate {
+			return nil, fmt.Errorf("duplicate field mapping for source %q", source)
+		}
+		mappings.sources[source] = mapping
+	}
+
+	return mappings, nil
+}
+
+// For returns the field mapping of a source, nil if it has none
+func (m *FieldMappings) For(sourceName string) *FieldMapping {
+	if m == nil {
+		return nil
+	}
+	if mapping, ok := m.sources[sourceName]; ok {
+		return mapping
+	}
+	return m.fallback
+}
+
+// Apply replaces the mapped event fields with the values of their keys in the JSON line
+// Lines that aren't JSON objects, and keys missing or empty in the line, keep the parsed values.
+func (m *FieldMapping) Apply(line string, event parsers.Event) {
+	if m == nil || !strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return
+	}
+	eventValue := reflect.ValueOf(event)
+	if eventValue.Kind() != reflect.Ptr || eventValue.Elem().Kind() != reflect.Struct {
+		return
+	}
+	eventValue = eventValue.Elem()
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return
+	}
+
+	for name, key := range m.keys {
+		field := eventValue.FieldByName(name)
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		value := lookupKey(raw, key)
+		if name == "ClientIP" {
+			value = forwardedIP(value)
+		}
+		if value == "" {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int64:
+			// Sizes and counts, negative or non-numeric values keep the parsed field
+			if number, err := strconv.ParseInt(value, 10, 64); err == nil && number >= 0 {
+				field.SetInt(number)
+			}
+		}
+	}
+}
+
+// lookupKey returns the text value of a key, or of a nested key when the dotted key isn't a key of raw
+func lookupKey(raw map[string]any, path []string) string {
+	if value, ok := raw[strings.Join(path, ".")]; ok {
+		return jsonText(value)
+	}
+	var value any = raw
+	for _, segment := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		if value, ok = object[segment]; !ok {
+			return ""
+		}
+	}
+	return jsonText(value)
+}
+
+// jsonText formats a JSON string, number or boolean, empty for objects, arrays and null
+func jsonText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// forwardedIP returns the client IP of a header value: the first entry of a list ("203.0.113.7, 10.0.0.1"),
+// without port; empty when it isn't an IP address
+func forwardedIP(value string) string {
+	value, _, _ = strings.Cut(value, ",")
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	if net.ParseIP(value) == nil {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package ingestion
+
+import (
+	"testing"
+
+	"loglynx/internal/database/models"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParseFieldMappings(t *testing.T) {
+	mappings, err := ParseFieldMappings(" edge : client_ip=request_Cf-Connecting-Ip, host=request_X-Forwarded-Host ; *:request_id=trace.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping := mappings.For("edge"); mapping == nil || len(mapping.keys) != 2 {
+		t.Errorf("Unexpected mapping of edge %+v", mapping)
+	}
+	if mapping := mappings.For("internal"); mapping == nil || len(mapping.keys) != 1 {
+		t.Errorf("Expected the * mapping for other sources, got %+v", mapping)
+	}
+	if mapping := (*FieldMappings)(nil).For("edge"); mapping != nil {
+		t.Errorf("Expected no mapping without configuration, got %+v", mapping)
+	}
+
+	for _, invalid := range []string{"edge", "edge:client_ip", "edge:status_code=Status", "edge:host=a,host=b", "a:host=h;a:path=p", ":host=h"} {
+		if _, err := ParseFieldMappings(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestFieldMapping_Apply(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	registry := parsers.NewRegistry(nil, nil, logger)
+	mappings, err := ParseFieldMappings("edge:client_ip=request_Cf-Connecting-Ip,host=request_X-Forwarded-Host,request_id=trace.id,response_header_bytes=resp_header_size")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []LogLine{
+		// Behind Cloudflare: the proxy logs the CDN edge as client
+		{Content: `{"ClientHost":"172.68.1.1","request_Host":"origin.internal","RequestMethod":"GET","RequestPath":"/","DownstreamStatus":200,"time":"2025-10-25T21:11:49Z","request_Cf-Connecting-Ip":"203.0.113.7","request_X-Forwarded-Host":"shop.example.com","trace":{"id":"abc123"},"resp_header_size":512}`},
+		// Missing and invalid values keep the parsed fields
+		{Content: `{"ClientHost":"172.68.1.2","request_Host":"origin.internal","RequestMethod":"GET","RequestPath":"/","DownstreamStatus":200,"time":"2025-10-25T21:11:50Z","request_Cf-Connecting-Ip":"unknown","resp_header_size":-3}`},
+	}
+
+	for _, source := range []*models.LogSource{{Name: "edge", ParserType: "traefik"}, {Name: "pod", ParserType: "cri+traefik"}} {
+		parser, err := registry.Get(source.ParserType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		processor := &SourceProcessor{source: source, parser: parser, fieldMapping: mappings.For("edge"), logger: logger}
+
+		chunk := lines
+		if source.ParserType == "cri+traefik" {
+			chunk = []LogLine{{Content: "2025-10-25T21:11:49.000000000Z stdout F " + lines[0].Content}}
+		}
+		requests := processor.parseChunk(processor.parser, chunk)
+		if len(requests) != len(chunk) {
+			t.Fatalf("%s: expected %d requests, got %d", source.Name, len(chunk), len(requests))
+		}
+		if r := requests[0]; r.ClientIP != "203.0.113.7" || r.Host != "shop.example.com" || r.RequestID != "abc123" || r.ResponseHeaderBytes != 512 {
+			t.Errorf("%s: expected the mapped fields, got client %s, host %s, request id %s, response headers %d", source.Name, r.ClientIP, r.Host, r.RequestID, r.ResponseHeaderBytes)
+		}
+		if len(requests) > 1 {
+			if r := requests[1]; r.ClientIP != "172.68.1.2" || r.Host != "origin.internal" || r.ResponseHeaderBytes != 0 {
+				t.Errorf("%s: expected the parsed fields, got client %s, host %s", source.Name, r.ClientIP, r.Host)
+			}
+		}
+	}
+}
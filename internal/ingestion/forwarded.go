@@ -0,0 +1,114 @@
+package ingestion
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	parsers "loglynx/internal/parser"
+)
+
+// ForwardedResolver picks the client of a request from its X-Forwarded-For chain, skipping the proxies
+// in front of LogLynx's proxy: a fixed number of hops (depth), addresses of trusted networks, or both
+// Entries left of the first untrusted hop can be forged by the client and are ignored.
+type ForwardedResolver struct {
+	trusted []*net.IPNet
+	depth   int // Hops always trusted, counting from the connection peer
+}
+
+// ParseForwardedResolver builds a resolver from comma-separated trusted proxy networks or addresses
+// (e.g. "173.245.48.0/20,10.0.0.1") and the number of proxies in front (depth)
+// Returns nil when neither is configured, keeping the client chosen by the parsers.
+func ParseForwardedResolver(trustedProxies string, depth int) (*ForwardedResolver, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("invalid forwarded proxy depth %d (expected 0 or more)", depth)
+	}
+
+	resolver := &ForwardedResolver{depth: depth}
+	for _, entry := range strings.Split(trustedProxies, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q (expected an IP address or CIDR network)", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			resolver.trusted = append(resolver.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q (expected an IP address or CIDR network)", entry)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+
+	if len(resolver.trusted) == 0 && resolver.depth == 0 {
+		return nil, nil
+	}
+	return resolver, nil
+}
+
+// ClientIP returns the client of a request received from peer with the X-Forwarded-For chain
+// The chain is walked from the peer leftwards while hops are trusted; when every hop is trusted the leftmost
+// one is the client. An entry that isn't an IP address stops the walk at the last valid hop.
+func (r *ForwardedResolver) ClientIP(peer string, chain string) string {
+	hops := make([]string, 0, strings.Count(chain, ",")+2)
+	for _, hop := range strings.Split(chain, ",") {
+		if hop = strings.TrimSpace(hop); hop != "" {
+			hops = append(hops, hop)
+		}
+	}
+	hops = append(hops, peer)
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHop(hops[i])
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if len(hops)-1-i >= r.depth && !r.trustedIP(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// Apply replaces the client of events reporting a forwarded chain
+// Events without a logged X-Forwarded-For header keep the client chosen by the parser (e.g. X-Real-Ip).
+func (r *ForwardedResolver) Apply(event parsers.Event) {
+	reporter, ok := event.(parsers.ForwardedReporter)
+	if r == nil || !ok {
+		return
+	}
+	peer, chain := reporter.ForwardedFor()
+	if peer == "" || strings.TrimSpace(chain) == "" {
+		return
+	}
+	reporter.SetClientIP(r.ClientIP(peer, chain))
+}
+
+// trustedIP reports whether an address belongs to a trusted proxy network
+func (r *ForwardedResolver) trustedIP(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHop parses a chain entry, which may carry a port ("203.0.113.7:5123", "[2001:db8::1]:443")
+func parseHop(hop string) net.IP {
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	return net.ParseIP(strings.Trim(hop, "[]"))
+}
@@ -0,0 +1,114 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+
+	"loglynx/internal/parser/iis"
+	"loglynx/internal/parser/traefik"
+
+	"github.com/pterm/pterm"
+)
+
+func TestForwardedResolver_ClientIP(t *testing.T) {
+	cases := []struct {
+		trusted     string
+		depth       int
+		peer, chain string
+		expected    string
+	}{
+		// Cloudflare in front: its edge is the peer and appended the client
+		{"173.245.48.0/20", 0, "173.245.48.10", "203.0.113.7", "203.0.113.7"},
+		// A forged first entry is ignored
+		{"173.245.48.0/20", 0, "173.245.48.10", "1.1.1.1, 203.0.113.7", "203.0.113.7"},
+		// Trusted hops are skipped recursively, with ports and IPv6
+		{"173.245.48.0/20,10.0.0.0/8", 0, "10.0.0.5", "198.51.100.1:5123, 173.245.48.10", "198.51.100.1"},
+		{"2001:db8::/32", 0, "2001:db8::1", "[2001:db8:1::5]:443, 2001:db9::7", "2001:db9::7"},
+		// An untrusted peer is the client
+		{"173.245.48.0/20", 0, "198.51.100.9", "203.0.113.7", "198.51.100.9"},
+		// Depth skips a fixed number of hops, counting the peer
+		{"", 1, "192.0.2.1", "1.1.1.1, 203.0.113.7", "203.0.113.7"},
+		{"", 2, "192.0.2.1", "1.1.1.1, 203.0.113.7", "1.1.1.1"},
+		{"", 5, "192.0.2.1", "1.1.1.1, 203.0.113.7", "1.1.1.1"},
+		// Depth and networks combine
+		{"10.0.0.1", 1, "192.0.2.1", "203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		// Garbage stops the walk at the last valid hop
+		{"", 2, "192.0.2.1", "unknown, 203.0.113.7", "203.0.113.7"},
+	}
+
+	for _, tc := range cases {
+		resolver, err := ParseForwardedResolver(tc.trusted, tc.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client := resolver.ClientIP(tc.peer, tc.chain); client != tc.expected {
+			t.Errorf("trusted=%q depth=%d peer=%s chain=%q: expected %s, got %s", tc.trusted, tc.depth, tc.peer, tc.chain, tc.expected, client)
+		}
+	}
+
+	if resolver, err := ParseForwardedResolver(" ", 0); err != nil || resolver != nil {
+		t.Errorf("Expected no resolver without configuration, got %+v (%v)", resolver, err)
+	}
+	for _, invalid := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := ParseForwardedResolver(invalid, 0); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+	if _, err := ParseForwardedResolver("", -1); err == nil {
+		t.Error("Expected a negative depth to be rejected")
+	}
+}
+
+func TestForwardedResolver_Apply(t *testing.T) {
+	parser := traefik.NewParser(nil, nil, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	resolver, err := ParseForwardedResolver("173.245.48.0/20", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := parser.Parse(`{"ClientHost":"173.245.48.10","ClientPort":"443","RequestMethod":"GET","RequestPath":"/","DownstreamStatus":200,"time":"2025-10-25T21:11:49Z","request_X-Forwarded-For":"1.1.1.1, 203.0.113.7"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.Apply(event)
+	if event.ClientIP != "203.0.113.7" {
+		t.Errorf("Expected the client from X-Forwarded-For, got %s", event.ClientIP)
+	}
+
+	// Without a logged chain, X-Real-Ip chosen by the parser stays
+	event, err = parser.Parse(`{"ClientHost":"173.245.48.10","request_X-Real-Ip":"198.51.100.4","RequestMethod":"GET","RequestPath":"/","DownstreamStatus":200,"time":"2025-10-25T21:11:49Z"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.Apply(event)
+	if event.ClientIP != "198.51.100.4" {
+		t.Errorf("Expected X-Real-Ip to be kept, got %s", event.ClientIP)
+	}
+}
+
+func TestForwardedResolver_ApplyIIS(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	parser := iis.NewParser(logger)
+	if _, err := parser.Parse("#Fields: date time cs-method cs-uri-stem c-ip sc-status cs(X-Forwarded-For)"); !errors.Is(err, iis.ErrDirective) {
+		t.Fatal(err)
+	}
+
+	// IIS writes the space after the comma as '+'
+	line := "2025-10-15 08:12:45 GET / 10.0.0.1 200 198.51.100.1,+203.0.113.7,+10.0.0.1"
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ClientIP != "198.51.100.1" {
+		t.Errorf("Expected the first hop without configuration, got %s", event.ClientIP)
+	}
+
+	resolver, err := ParseForwardedResolver("10.0.0.0/8", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.Apply(event)
+	if event.ClientIP != "203.0.113.7" {
+		t.Errorf("Expected the client behind the trusted proxies, got %s", event.ClientIP)
+	}
+}
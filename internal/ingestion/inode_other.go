@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package ingestion
+
+import (
+	"os"
+)
+
+// getFileInode is not supported on this platform: rotation is only detected by size and fingerprint changes
+func getFileInode(file *os.File) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package ingestion
+
+import (
+	"os"
+	"syscall"
+)
+
+// getFileInode returns the inode of the file, used to detect rename-based rotation
+func getFileInode(file *os.File) (int64, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return int64(sys.Ino), nil
+	}
+
+	// No inode available: rotation is only detected by size and fingerprint changes
+	return 0, nil
+}
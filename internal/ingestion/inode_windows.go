@@ -0,0 +1,20 @@
+//go:build windows
+
+package ingestion
+
+import (
+	"os"
+	"syscall"
+)
+
+// getFileInode returns the NTFS file index of the file, the Windows counterpart of an inode
+// os.FileInfo doesn't expose it on Windows, so it's read from the open handle
+func getFileInode(file *os.File) (int64, error) {
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(file.Fd()), &info); err != nil {
+		return 0, err
+	}
+
+	// The index is unique per volume; rotated files stay in the same directory (same volume)
+	return int64(uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)), nil
+}
@@ -0,0 +1,122 @@
+package ingestion
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+)
+
+// labelFields maps the request fields label rules can match to their value
+var labelFields = map[string]func(*models.HTTPRequest) string{
+	"host":            func(r *models.HTTPRequest) string { return r.Host },
+	"path":            func(r *models.HTTPRequest) string { return r.Path },
+	"query_string":    func(r *models.HTTPRequest) string { return r.QueryString },
+	"method":          func(r *models.HTTPRequest) string { return r.Method },
+	"client_ip":       func(r *models.HTTPRequest) string { return r.ClientIP },
+	"client_user":     func(r *models.HTTPRequest) string { return r.ClientUser },
+	"user_agent":      func(r *models.HTTPRequest) string { return r.UserAgent },
+	"referer":         func(r *models.HTTPRequest) string { return r.Referer },
+	"backend_name":    func(r *models.HTTPRequest) string { return r.BackendName },
+	"router_name":     func(r *models.HTTPRequest) string { return r.RouterName },
+	"upstream_addr":   func(r *models.HTTPRequest) string { return r.UpstreamAddr },
+	"tls_server_name": func(r *models.HTTPRequest) string { return r.TLSServerName },
+	"geo_country":     func(r *models.HTTPRequest) string { return r.GeoCountry },
+	"source_name":     func(r *models.HTTPRequest) string { return r.SourceName },
+}
+
+// LabelFields returns the field names label rules can match
+func LabelFields() []string {
+	fields := make([]string, 0, len(labelFields))
+	for field := range labelFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// labelRule assigns a label from the match of a pattern on a request field
+type labelRule struct {
+	name    string
+	field   func(*models.HTTPRequest) string
+	pattern *regexp.Regexp
+}
+
+// LabelRules assign labels to requests at ingestion, e.g. api_version=v2 from the path
+type LabelRules struct {
+	rules []labelRule // Ordered as configured
+}
+
+// ParseLabelRules parses semicolon-separated "name=field:pattern" rules, e.g.
+// "api_version=path:^/api/(v\d+)/;customer=host:^([a-z0-9-]+)\.example\.com$"
+// The label is the first capture group of the pattern, or its whole match without group.
+func ParseLabelRules(list string) (*LabelRules, error) {
+	rules := &LabelRules{}
+
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rest, ok := strings.Cut(entry, "=")
+		field, pattern, hasField := strings.Cut(rest, ":")
+		name, field = strings.ToLower(strings.TrimSpace(name)), strings.ToLower(strings.TrimSpace(field))
+		if !ok || !hasField || strings.TrimSpace(pattern) == "" {
+			return nil, fmt.Errorf("invalid label rule %q: expected name=field:pattern", entry)
+		}
+		if err := repositories.ValidateLabelName(name); err != nil {
+			return nil, fmt.Errorf("invalid label rule %q: %w", entry, err)
+		}
+		value, known := labelFields[field]
+		if !known {
+			return nil, fmt.Errorf("unknown field %q in label rule %q (expected one of %s)", field, name, strings.Join(LabelFields(), ", "))
+		}
+		compiled, err := regexp.Compile(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for label %q: %w", name, err)
+		}
+		rules.rules = append(rules.rules, labelRule{name: name, field: value, pattern: compiled})
+	}
+
+	return rules, nil
+}
+
+// Len returns the number of rules
+func (r *LabelRules) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Apply sets the labels whose rule matches the request
+// Several rules may set the same label: the first match wins, and labels set by transform rules are kept.
+func (r *LabelRules) Apply(request *models.HTTPRequest) {
+	if r == nil {
+		return
+	}
+	for _, rule := range r.rules {
+		if _, set := request.Labels[rule.name]; set {
+			continue
+		}
+		match := rule.pattern.FindStringSubmatch(rule.field(request))
+		if match == nil {
+			continue
+		}
+		value := match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+		if value == "" {
+			continue
+		}
+		if request.Labels == nil {
+			request.Labels = make(map[string]string)
+		}
+		request.Labels[rule.name] = value
+	}
+}
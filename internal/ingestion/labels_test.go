@@ -0,0 +1,50 @@
+package ingestion
+
+import (
+	"strings"
+	"testing"
+
+	"loglynx/internal/database/models"
+)
+
+func TestLabelRules(t *testing.T) {
+	rules, err := ParseLabelRules(`api_version=path:^/api/(v\d+)/; Customer = host:^([a-z0-9-]+)\.example\.com$;customer=source_name:.+;bot=user_agent:(?i)bot`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		request  models.HTTPRequest
+		expected map[string]string
+	}{
+		{models.HTTPRequest{Path: "/api/v2/users", Host: "acme.example.com"}, map[string]string{"api_version": "v2", "customer": "acme"}},
+		// The first matching rule of a label wins, patterns without group label with the whole match
+		{models.HTTPRequest{Path: "/", Host: "example.org", SourceName: "edge", UserAgent: "Googlebot/2.1"}, map[string]string{"customer": "edge", "bot": "bot"}},
+		// Labels set by transform rules are kept
+		{models.HTTPRequest{Host: "acme.example.com", Labels: map[string]string{"customer": "override"}}, map[string]string{"customer": "override"}},
+		{models.HTTPRequest{Path: "/"}, nil},
+	}
+	for i, tc := range cases {
+		rules.Apply(&tc.request)
+		if len(tc.request.Labels) != len(tc.expected) {
+			t.Errorf("case %d: labels = %v, want %v", i, tc.request.Labels, tc.expected)
+			continue
+		}
+		for name, value := range tc.expected {
+			if tc.request.Labels[name] != value {
+				t.Errorf("case %d: label %s = %q, want %q", i, name, tc.request.Labels[name], value)
+			}
+		}
+	}
+
+	for list, want := range map[string]string{
+		"api_version=path":        "expected name=field:pattern",
+		"api version=path:^/api/": "invalid label name",
+		"customer=hostname:^acme": "unknown field",
+		"customer=host:^(acme":    "invalid pattern",
+	} {
+		if _, err := ParseLabelRules(list); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseLabelRules(%q) error = %v, want %q", list, err, want)
+		}
+	}
+}
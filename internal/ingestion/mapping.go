@@ -0,0 +1,77 @@
+package ingestion
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	parsers "loglynx/internal/parser"
+)
+
+// modelType is the struct parser events are converted to
+var modelType = reflect.TypeOf(models.HTTPRequest{})
+
+// mappedField reports whether an event field is copied to the HTTPRequest field of the same name and type
+// Shared by convertToDBModel and the mapping check, so both apply the same rule
+func mappedField(field reflect.StructField) bool {
+	if !field.IsExported() || field.Name == "SourceName" {
+		return false
+	}
+	modelField, ok := modelType.FieldByName(field.Name)
+	return ok && modelField.Type == field.Type
+}
+
+// UnmappedFields returns the exported event fields that convertToDBModel silently drops:
+// no HTTPRequest field with the same name, or one of a different type
+// SourceName is set from the log source and unexported fields are internal to the parser.
+func UnmappedFields(event parsers.Event) []string {
+	eventType := reflect.TypeOf(event)
+	if eventType.Kind() == reflect.Ptr {
+		eventType = eventType.Elem()
+	}
+	if eventType.Kind() != reflect.Struct {
+		return []string{fmt.Sprintf("%s is not a struct", eventType)}
+	}
+
+	var unmapped []string
+	for i := 0; i < eventType.NumField(); i++ {
+		field := eventType.Field(i)
+		if !field.IsExported() || field.Name == "SourceName" || mappedField(field) {
+			continue
+		}
+		if modelField, ok := modelType.FieldByName(field.Name); ok {
+			unmapped = append(unmapped, fmt.Sprintf("%s (%s, column is %s)", field.Name, field.Type, modelField.Type))
+		} else {
+			unmapped = append(unmapped, fmt.Sprintf("%s (no column)", field.Name))
+		}
+	}
+	return unmapped
+}
+
+// ValidateEventMapping checks the event of every registered parser against the HTTPRequest columns
+// Returns an error listing the fields that would not be stored, and the parsers whose event can't be checked.
+func ValidateEventMapping(registry *parsers.Registry) error {
+	names := make([]string, 0, len(registry.GetAll()))
+	for name := range registry.GetAll() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		prototype, ok := registry.GetAll()[name].(parsers.EventPrototype)
+		if !ok || prototype.NewEvent() == nil {
+			problems = append(problems, fmt.Sprintf("%s: event type unknown (parser doesn't implement NewEvent)", name))
+			continue
+		}
+		if unmapped := UnmappedFields(prototype.NewEvent()); len(unmapped) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, strings.Join(unmapped, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("parser event fields not stored: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package ingestion
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+// Every field of the built-in parser events must land in a request column
+func TestValidateEventMapping_BuiltinParsers(t *testing.T) {
+	registry := parsers.NewRegistry(nil, nil, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	if err := ValidateEventMapping(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, parser := range registry.GetAll() {
+		cri, err := registry.Get(parsers.CRIPrefix + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.TypeOf(cri.(parsers.EventPrototype).NewEvent()) != reflect.TypeOf(parser.(parsers.EventPrototype).NewEvent()) {
+			t.Errorf("Container wrapper of %s returns a different event type", name)
+		}
+	}
+}
+
+type renamedEvent struct {
+	Timestamp  time.Time
+	SourceName string
+	ClientAddr string // Renamed from ClientIP
+	StatusCode string // Wrong type
+	Method     string
+	internal   bool
+}
+
+func (e *renamedEvent) GetTimestamp() time.Time { return e.Timestamp }
+func (e *renamedEvent) GetSourceName() string   { return e.SourceName }
+
+type renamedParser struct{}
+
+func (renamedParser) Name() string                             { return "renamed" }
+func (renamedParser) Parse(line string) (parsers.Event, error) { return &renamedEvent{}, nil }
+func (renamedParser) CanParse(line string) bool                { return true }
+func (renamedParser) NewEvent() parsers.Event                  { return &renamedEvent{} }
+
+type opaqueParser struct{ renamedParser }
+
+func (opaqueParser) NewEvent() parsers.Event { return nil }
+
+func TestValidateEventMapping_ReportsDroppedFields(t *testing.T) {
+	unmapped := UnmappedFields(&renamedEvent{})
+	expected := []string{"ClientAddr (no column)", "StatusCode (string, column is int)"}
+	if !reflect.DeepEqual(unmapped, expected) {
+		t.Errorf("Expected %v, got %v", expected, unmapped)
+	}
+
+	registry := parsers.NewRegistry(nil, nil, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	registry.Register("renamed", renamedParser{})
+	registry.Register("opaque", opaqueParser{})
+	err := ValidateEventMapping(registry)
+	if err == nil {
+		t.Fatal("Expected the renamed fields to be reported")
+	}
+	expectedErr := "parser event fields not stored: opaque: event type unknown (parser doesn't implement NewEvent); " +
+		"renamed: ClientAddr (no column), StatusCode (string, column is int)"
+	if err.Error() != expectedErr {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Mapped fields are still copied
+	processor := &SourceProcessor{source: &models.LogSource{Name: "test"}, logger: pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)}
+	request := processor.convertToDBModel(&renamedEvent{Method: "GET", ClientAddr: "10.0.0.1", StatusCode: "200"}, 0)
+	if request.Method != "GET" || request.ClientIP != "" || request.StatusCode != 0 || request.SourceName != "test" {
+		t.Errorf("Unexpected conversion %+v", request)
+	}
+}
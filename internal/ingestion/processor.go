@@ -3,8 +3,11 @@ package ingestion
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +16,8 @@ import (
 	"loglynx/internal/enrichment"
 	parsers "loglynx/internal/parser"
 	"loglynx/internal/parser/useragent"
+	"loglynx/internal/statsd"
+	"loglynx/internal/transform"
 
 	"github.com/pterm/pterm"
 )
@@ -21,15 +26,34 @@ import (
 type SourceProcessor struct {
 	source         *models.LogSource
 	parser         parsers.LogParser
+	layout         parsers.LayoutParser // Directive state of the file being read (IIS #Fields), nil for other parsers
 	reader         *IncrementalReader
 	httpRepo       repositories.HTTPRequestRepository
 	sourceRepo     repositories.LogSourceRepository
 	geoIP          *enrichment.GeoIPEnricher
+	recorder       EventRecorder
+	metrics        *statsd.Client // Optional, nil when StatsD is disabled
+	metricTags     []string       // Source and parser tags of emitted metrics
+	pool           *WorkerPool    // Shared parsing/enrichment pool owned by the coordinator
+	writer         *BatchWriter   // Shared database writer owned by the coordinator, nil writes directly
+	lineOffsetMode LineOffsetMode // Whether line offsets are part of dedup hashes
+	fieldMapping   *FieldMapping  // Field overrides of this source, nil keeps the parsed fields
+	forwarded      *ForwardedResolver
+	enrichers      []Enricher
+	transforms     *transform.Rules
+	labels         *LabelRules
+	recovery       *recoveryTracker // Set after an unclean shutdown until reading caught up with the file, else nil
 	logger         *pterm.Logger
-	batchSize      int
-	workerPoolSize int
-	batchTimeout   time.Duration
+	batchSize      int           // Current batch size (adapted by tuner)
+	batchTimeout   time.Duration // Current flush interval (adapted by tuner)
+	tuner          *batchTuner
+	arrivalRate    float64 // Last smoothed arrival rate (lines/sec), guarded by statsMu
+	lastPollAt     time.Time // Last successful read attempt (loop heartbeat), guarded by statsMu
+	lastReadAt     time.Time // Last read that returned lines, guarded by statsMu
+	readPath       string    // File being read and position reached, guarded by statsMu
+	readPosition   int64
 	pollInterval   time.Duration
+	coveredUntil   time.Time // End of the reading time recorded as source coverage, used by the loop only
 	ctx            context.Context
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
@@ -51,26 +75,42 @@ func NewSourceProcessor(
 	httpRepo repositories.HTTPRequestRepository,
 	sourceRepo repositories.LogSourceRepository,
 	geoIP *enrichment.GeoIPEnricher,
+	recorder EventRecorder,
+	metrics *statsd.Client,
+	pool *WorkerPool,
+	writer *BatchWriter,
+	lineOffsetMode LineOffsetMode,
+	fieldMapping *FieldMapping,
+	forwarded *ForwardedResolver,
+	enrichers []Enricher,
+	transforms *transform.Rules,
+	labels *LabelRules,
 	logger *pterm.Logger,
-	batchSize int,
-	workerPoolSize int,
+	batching BatchSettings,
+	maxLineLength int,
 ) *SourceProcessor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	reader := NewIncrementalReader(
 		source.Path,
-		source.LastPosition,
-		source.LastInode,
-		source.LastLineContent,
+		ReadState{
+			Position:    source.LastPosition,
+			Inode:       source.LastInode,
+			LastLine:    source.LastLineContent,
+			Fingerprint: source.FirstLineFingerprint,
+		},
+		maxLineLength,
 		logger,
 	)
 
-	// Apply defaults if not configured
-	if batchSize <= 0 {
-		batchSize = 1000
-	}
-	if workerPoolSize <= 0 {
-		workerPoolSize = 4
+	// Batch size and flush interval adapt to the arrival rate within the configured bounds
+	tuner := newBatchTuner(batching, time.Now())
+
+	// Layouts declared in the file belong to this source only, the registry's parser is shared
+	layout, _ := parser.(parsers.LayoutParser)
+	if layout != nil {
+		layout = layout.WithDefaultLayout()
+		parser = layout
 	}
 
 	// Check if this is an initial load (first time reading this file)
@@ -79,20 +119,36 @@ func NewSourceProcessor(
 	return &SourceProcessor{
 		source:              source,
 		parser:              parser,
+		layout:              layout,
 		reader:              reader,
 		httpRepo:            httpRepo,
 		sourceRepo:          sourceRepo,
 		geoIP:               geoIP,
+		recorder:            recorder,
+		metrics:             metrics,
+		metricTags:          []string{"source:" + source.Name, "parser:" + parser.Name()},
+		pool:                pool,
+		writer:              writer,
+		lineOffsetMode:      lineOffsetMode,
+		fieldMapping:        fieldMapping,
+		forwarded:           forwarded,
+		enrichers:           enrichers,
+		transforms:          transforms,
+		labels:              labels,
 		logger:              logger,
-		batchSize:           batchSize,       // Configurable via BATCH_SIZE env var
-		workerPoolSize:      workerPoolSize,  // Configurable via WORKER_POOL_SIZE env var
-		batchTimeout:        30 * time.Second, // OPTIMIZED: Longer timeout for larger batches (was 2s)
+		batchSize:           tuner.batchSize,     // Bounded by BATCH_SIZE_MIN / BATCH_SIZE
+		batchTimeout:        tuner.flushInterval, // Bounded by BATCH_FLUSH_MIN / BATCH_FLUSH_MAX
+		tuner:               tuner,
 		pollInterval:        100 * time.Millisecond, // OPTIMIZED: Poll more frequently during initial load (was 1s)
 		ctx:                 ctx,
 		cancel:              cancel,
 		totalProcessed:      0,
 		totalErrors:         0,
 		startTime:           time.Now(),
+		lastPollAt:          time.Now(),
+		lastReadAt:          time.Now(),
+		readPath:            reader.Path(),
+		readPosition:        source.LastPosition,
 		isInitialLoad:       isInitialLoad,
 		initialLoadComplete: false,
 	}
@@ -132,7 +188,7 @@ func (sp *SourceProcessor) ApplyInitialImportLimit(importDays int) error {
 	sp.reader.UpdatePosition(startPos, 0, "")
 
 	// Update source tracking in database
-	if err := sp.sourceRepo.UpdateTracking(sp.source.Name, startPos, 0, ""); err != nil {
+	if err := sp.sourceRepo.UpdateTracking(sp.source.Name, startPos, 0, "", ""); err != nil {
 		sp.logger.WithCaller().Error("Failed to update source position",
 			sp.logger.Args("source", sp.source.Name, "error", err))
 		return err
@@ -144,8 +200,70 @@ func (sp *SourceProcessor) ApplyInitialImportLimit(importDays int) error {
 	return nil
 }
 
+// ProcessorStats holds runtime statistics of a source processor
+type ProcessorStats struct {
+	Source         string  `json:"source"`
+	Parser         string  `json:"parser"`
+	TotalProcessed int64   `json:"total_processed"`
+	TotalErrors    int64   `json:"total_errors"`
+	RatePerSec     float64 `json:"rate_per_sec"` // Average since the processor started
+	Uptime         string  `json:"uptime"`
+	// Batching currently chosen by the adaptive tuner
+	AdaptiveBatching bool    `json:"adaptive_batching"`
+	ArrivalRate      float64 `json:"arrival_rate"` // Smoothed lines/sec read from the file
+	BatchSize        int     `json:"batch_size"`
+	FlushIntervalMs  int64   `json:"flush_interval_ms"`
+	// Lines skipped for exceeding MAX_LINE_LENGTH (since the processor started)
+	OversizedLinesSkipped int64 `json:"oversized_lines_skipped"`
+	// Read progress (stall detection)
+	LastPollAt   time.Time `json:"last_poll_at"`  // Last successful read attempt
+	LastReadAt   time.Time `json:"last_read_at"`  // Last read that returned new lines
+	PendingBytes int64     `json:"pending_bytes"` // Bytes written to the file but not read yet
+}
+
+// Stats returns a snapshot of the processor statistics
+func (sp *SourceProcessor) Stats() ProcessorStats {
+	sp.statsMu.Lock()
+	defer sp.statsMu.Unlock()
+
+	elapsed := time.Since(sp.startTime)
+	stats := ProcessorStats{
+		Source:         sp.source.Name,
+		Parser:         sp.source.ParserType,
+		TotalProcessed: sp.totalProcessed,
+		TotalErrors:    sp.totalErrors,
+		Uptime:         elapsed.Round(time.Second).String(),
+
+		AdaptiveBatching: sp.tuner.settings.Adaptive,
+		ArrivalRate:      sp.arrivalRate,
+		BatchSize:        sp.batchSize,
+		FlushIntervalMs:  sp.batchTimeout.Milliseconds(),
+
+		OversizedLinesSkipped: sp.reader.OversizedLines(),
+
+		LastPollAt: sp.lastPollAt,
+		LastReadAt: sp.lastReadAt,
+	}
+	if info, err := os.Stat(sp.readPath); err == nil && info.Size() > sp.readPosition {
+		stats.PendingBytes = info.Size() - sp.readPosition
+	}
+	if elapsed > 0 {
+		stats.RatePerSec = float64(sp.totalProcessed) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// Stalled reports whether the read loop is blocked, or data is waiting but nothing was read, for longer than threshold
+func (s ProcessorStats) Stalled(now time.Time, threshold time.Duration) bool {
+	loopBlocked := now.Sub(s.LastPollAt) > threshold
+	noProgress := s.PendingBytes > 0 && now.Sub(s.LastReadAt) > threshold
+	return loopBlocked || noProgress
+}
+
 // Start begins processing logs from the source
 func (sp *SourceProcessor) Start() {
+	sp.restoreLayout()
+	sp.setRunning(true)
 	sp.wg.Add(1)
 	go sp.processLoop()
 	sp.logger.Info("Started source processor",
@@ -171,15 +289,41 @@ func (sp *SourceProcessor) processLoop() {
 	flushTimer := time.NewTimer(sp.batchTimeout)
 	defer flushTimer.Stop()
 
-	// Periodic position update for progress tracking (every 500ms)
+	// Reading time is recorded as source coverage, to tell hours without traffic from hours nobody read the source
+	coverageTicker := time.NewTicker(coverageInterval)
+	defer coverageTicker.Stop()
+	sp.coveredUntil = time.Now()
+
+	// Periodic position update for state changes without new lines (every 500ms)
 	positionUpdateTicker := time.NewTicker(500 * time.Millisecond)
 	defer positionUpdateTicker.Stop()
 
-	// Track the position of the last read batch
-	var lastReadPos int64
-	var lastReadInode int64
-	var lastReadLine string
-	var lastUpdatedPos int64 // Track last position that was saved to DB
+	// Track the reader state after the last read and the state last saved to DB
+	lastRead := sp.reader.State()
+	lastSaved := lastRead
+	savePosition := func() {
+		if lastRead != lastSaved {
+			sp.updatePosition(lastRead)
+			lastSaved = lastRead
+		}
+	}
+
+	// flush stores the batch together with the reader state it ends at (all read lines are in the batch)
+	flush := func() {
+		err := sp.flushBatch(batch, lastRead)
+		switch {
+		case err == nil:
+			lastSaved = lastRead
+		case errors.Is(err, repositories.ErrWriteAborted):
+			// Stopping before the batch was stored: the saved position stays before its lines, which are read
+			// again on the next start; the periodic save must not move past them either
+			lastSaved = lastRead
+		default:
+			// The batch is dropped, move past it like a successful flush
+			savePosition()
+		}
+		batch = []*models.HTTPRequest{}
+	}
 
 	for {
 		select {
@@ -188,18 +332,20 @@ func (sp *SourceProcessor) processLoop() {
 			if len(batch) > 0 {
 				sp.logger.Debug("Flushing remaining batch on shutdown",
 					sp.logger.Args("source", sp.source.Name, "count", len(batch)))
-				sp.flushBatch(batch)
-				// Update position after final flush
-				sp.updatePosition(lastReadPos, lastReadInode, lastReadLine)
+				flush()
 			}
+			sp.recordCoverage(time.Now())
+			sp.setRunning(false)
 			return
 
+		case now := <-coverageTicker.C:
+			sp.recordCoverage(now)
+
 		case <-positionUpdateTicker.C:
-			// Periodically update position even if batch is not flushed yet
-			// This ensures the progress bar updates smoothly
-			if lastReadPos > 0 && lastReadPos != lastUpdatedPos {
-				sp.updatePosition(lastReadPos, lastReadInode, lastReadLine)
-				lastUpdatedPos = lastReadPos
+			// Only save positions without pending lines, a saved position must never skip unflushed lines
+			// Positions of flushed lines are saved with their batch
+			if len(batch) == 0 {
+				savePosition()
 			}
 
 		case <-flushTimer.C:
@@ -207,25 +353,38 @@ func (sp *SourceProcessor) processLoop() {
 			if len(batch) > 0 {
 				sp.logger.Trace("Batch timeout reached, flushing",
 					sp.logger.Args("source", sp.source.Name, "count", len(batch)))
-				sp.flushBatch(batch)
-				batch = []*models.HTTPRequest{}
-				// Update position after timeout flush
-				if lastReadPos > 0 {
-					sp.updatePosition(lastReadPos, lastReadInode, lastReadLine)
-					lastUpdatedPos = lastReadPos
-				}
+				flush()
 			}
 			flushTimer.Reset(sp.batchTimeout)
 
 		case <-ticker.C:
 			// Poll for new log lines
-			lines, newPos, newInode, newLastLine, err := sp.reader.ReadBatch(sp.batchSize - len(batch))
+			// Read at least one line: the batch may exceed a batch size that was just lowered
+			readLimit := sp.batchSize - len(batch)
+			if readLimit < 1 {
+				readLimit = 1
+			}
+
+			lines, state, err := sp.reader.ReadBatch(readLimit)
 			if err != nil {
 				sp.logger.WithCaller().Error("Failed to read from log file",
 					sp.logger.Args("source", sp.source.Name, "error", err))
+				sp.metrics.Count("ingestion.read_errors", 1, sp.metricTags...)
 				continue
 			}
 
+			// The state may change without new lines (e.g. switching to a new file after rotation)
+			lastRead = state
+			sp.recordActivity(len(lines), state.Position)
+			if len(lines) > 0 {
+				sp.metrics.Count("ingestion.lines", int64(len(lines)), sp.metricTags...)
+			}
+
+			// Adapt batch size and flush interval to the recent arrival rate
+			if sp.tuneBatching(len(lines)) {
+				flushTimer.Reset(sp.batchTimeout)
+			}
+
 			if len(lines) == 0 {
 				// No new lines - reached EOF
 				// BUGFIX: Check if we need to mark initial load as complete AND flush pending batch
@@ -238,12 +397,7 @@ func (sp *SourceProcessor) processLoop() {
 					if len(batch) > 0 {
 						sp.logger.Debug("Flushing final batch before marking initial load complete",
 							sp.logger.Args("source", sp.source.Name, "count", len(batch)))
-						sp.flushBatch(batch)
-						batch = []*models.HTTPRequest{}
-						if lastReadPos > 0 {
-							sp.updatePosition(lastReadPos, lastReadInode, lastReadLine)
-							lastUpdatedPos = lastReadPos
-						}
+						flush()
 					}
 
 					// NOW we can safely disable first-load mode
@@ -256,6 +410,15 @@ func (sp *SourceProcessor) processLoop() {
 						sp.logger.Args("source", sp.source.Name))
 				}
 
+				// Recovering from an unclean shutdown ends once everything read up to the end of the file is stored
+				if len(batch) == 0 && sp.recovery.complete(time.Now()) {
+					report := sp.recovery.snapshot()
+					sp.logger.Info("Recovery from unclean shutdown completed",
+						sp.logger.Args("source", sp.source.Name, "reprocessed_bytes", report.ReprocessBytes,
+							"duplicates_skipped", report.DuplicatesSkipped, "inserted", report.Inserted,
+							"duration", report.CompletedAt.Sub(report.DetectedAt).Round(time.Second).String()))
+				}
+
 				continue // No new lines
 			}
 
@@ -266,133 +429,284 @@ func (sp *SourceProcessor) processLoop() {
 			parsedRequests := sp.parseAndEnrichParallel(lines)
 			batch = append(batch, parsedRequests...)
 
-			lastReadPos = newPos
-			lastReadInode = newInode
-			lastReadLine = newLastLine
-
 			// Flush if batch is full AND update position only after successful flush
 			if len(batch) >= sp.batchSize {
 				sp.logger.Trace("Batch full, flushing",
 					sp.logger.Args("source", sp.source.Name, "count", len(batch)))
-				sp.flushBatch(batch)
-				batch = []*models.HTTPRequest{}
+				flush()
 				flushTimer.Reset(sp.batchTimeout)
-
-				// Update source tracking AFTER successful flush
-				sp.updatePosition(lastReadPos, lastReadInode, lastReadLine)
-				lastUpdatedPos = lastReadPos
 			}
-			// Note: Position is updated periodically by positionUpdateTicker
-			// even if batch is not full yet (for progress tracking)
 		}
 	}
 }
 
-// updatePosition updates the file position in the database after a successful flush
-func (sp *SourceProcessor) updatePosition(position int64, inode int64, lastLine string) {
-	if err := sp.sourceRepo.UpdateTracking(sp.source.Name, position, inode, lastLine); err != nil {
+// recordActivity tracks read progress for stall detection
+func (sp *SourceProcessor) recordActivity(lines int, position int64) {
+	now := time.Now()
+	path := sp.reader.Path()
+
+	sp.statsMu.Lock()
+	defer sp.statsMu.Unlock()
+
+	sp.lastPollAt = now
+	if lines > 0 {
+		sp.lastReadAt = now
+	}
+	sp.readPath = path
+	sp.readPosition = position
+}
+
+// tuneBatching feeds the tuner and applies new batch settings, returning true if they changed
+func (sp *SourceProcessor) tuneBatching(lines int) bool {
+	if !sp.tuner.observe(lines, time.Now()) {
+		return false
+	}
+
+	changed := sp.tuner.batchSize != sp.batchSize || sp.tuner.flushInterval != sp.batchTimeout
+
+	sp.statsMu.Lock()
+	sp.batchSize = sp.tuner.batchSize
+	sp.batchTimeout = sp.tuner.flushInterval
+	sp.arrivalRate = sp.tuner.rate
+	sp.statsMu.Unlock()
+
+	if changed {
+		sp.logger.Debug("Adapted batching to arrival rate",
+			sp.logger.Args(
+				"source", sp.source.Name,
+				"rate_per_sec", int(sp.arrivalRate),
+				"batch_size", sp.batchSize,
+				"flush_interval", sp.batchTimeout.String(),
+			))
+	}
+	return changed
+}
+
+// updatePosition saves the reader state in the database after a successful flush
+// The reader already advanced in memory, so only the persisted state is updated here
+func (sp *SourceProcessor) updatePosition(state ReadState) {
+	if err := sp.sourceRepo.UpdateTracking(sp.source.Name, state.Position, state.Inode, state.LastLine, state.Fingerprint); err != nil {
 		sp.logger.WithCaller().Error("Failed to update source tracking",
 			sp.logger.Args("source", sp.source.Name, "error", err))
 	} else {
 		sp.logger.Trace("Updated source tracking",
-			sp.logger.Args("source", sp.source.Name, "position", position, "inode", inode))
-		sp.reader.UpdatePosition(position, inode, lastLine)
+			sp.logger.Args("source", sp.source.Name, "position", state.Position, "inode", state.Inode))
 	}
 }
 
-// parseAndEnrichParallel processes lines in parallel using worker pool
-func (sp *SourceProcessor) parseAndEnrichParallel(lines []string) []*models.HTTPRequest {
+// parseChunkSize is the number of lines handed to the shared worker pool as a single job
+// Small enough that busy sources interleave fairly with quiet ones
+const parseChunkSize = 100
+
+// parseAndEnrichParallel processes lines in parallel using the shared worker pool
+// Results keep the order of the input lines
+func (sp *SourceProcessor) parseAndEnrichParallel(lines []LogLine) []*models.HTTPRequest {
 	if len(lines) == 0 {
 		return nil
 	}
 
-	// Use configured worker pool size (from WORKER_POOL_SIZE env var)
-	numWorkers := sp.workerPoolSize
-	if numWorkers > len(lines) {
-		numWorkers = len(lines)
+	var chunks []parseSegment
+	for _, segment := range sp.layoutSegments(lines) {
+		for start := 0; start < len(segment.lines); start += parseChunkSize {
+			end := min(start+parseChunkSize, len(segment.lines))
+			chunks = append(chunks, parseSegment{parser: segment.parser, lines: segment.lines[start:end]})
+		}
 	}
+	chunkResults := make([][]*models.HTTPRequest, len(chunks))
 
-	// Channels for work distribution
-	jobs := make(chan string, len(lines))
-	results := make(chan *models.HTTPRequest, len(lines))
-
-	// Start workers
 	var wg sync.WaitGroup
-	for w := 0; w < numWorkers; w++ {
-		wg.Add(1)
-		go func() {
+	for i, chunk := range chunks {
+		chunkIdx := i
+		job := func() {
 			defer wg.Done()
-			for line := range jobs {
-				// Skip lines that this parser cannot handle
-				if !sp.parser.CanParse(line) {
-					sp.logger.Trace("Skipping line not supported by parser",
-						sp.logger.Args("source", sp.source.Name, "parser", sp.parser.Name()))
-					continue
-				}
+			chunkResults[chunkIdx] = sp.parseChunk(chunk.parser, chunk.lines)
+		}
 
-				event, err := sp.parser.Parse(line)
-				if err != nil {
-					sp.logger.Warn("Failed to parse log line",
-						sp.logger.Args("source", sp.source.Name, "error", err, "line_preview", truncate(line, 100)))
-					continue
-				}
+		wg.Add(1)
+		// Run inline when no shared pool is available (or it is shutting down)
+		if sp.pool == nil || !sp.pool.Submit(sp.source.Name, job) {
+			job()
+		}
+	}
+	wg.Wait()
 
-				// Convert to database model
-				dbRequest := sp.convertToDBModel(event)
+	// Collect results
+	parsedRequests := make([]*models.HTTPRequest, 0, len(lines))
+	for _, chunk := range chunkResults {
+		parsedRequests = append(parsedRequests, chunk...)
+	}
 
-				// Enrich with GeoIP data
-				if sp.geoIP != nil {
-					if err := sp.geoIP.Enrich(dbRequest); err != nil {
-						sp.logger.Debug("GeoIP enrichment failed",
-							sp.logger.Args("ip", dbRequest.ClientIP, "error", err))
-					}
-				}
+	return parsedRequests
+}
 
-				// Parse User-Agent string
-				if dbRequest.UserAgent != "" {
-					uaInfo := useragent.Parse(dbRequest.UserAgent)
-					dbRequest.Browser = uaInfo.Browser
-					dbRequest.BrowserVersion = uaInfo.BrowserVersion
-					dbRequest.OS = uaInfo.OS
-					dbRequest.OSVersion = uaInfo.OSVersion
-					dbRequest.DeviceType = uaInfo.DeviceType
-				}
+// parseSegment is a run of lines parsed with the same parser
+type parseSegment struct {
+	parser parsers.LogParser
+	lines  []LogLine
+}
 
-				results <- dbRequest
-			}
-		}()
+// layoutSegments splits lines at the directive lines of layout parsers, applied in file order
+// Each segment is parsed with a snapshot of the layout in effect; a line at offset 0 starts a new file,
+// which starts over from the default layout.
+func (sp *SourceProcessor) layoutSegments(lines []LogLine) []parseSegment {
+	if sp.layout == nil {
+		return []parseSegment{{parser: sp.parser, lines: lines}}
 	}
 
-	// Send jobs
-	for _, line := range lines {
-		jobs <- line
+	var segments []parseSegment
+	parser := sp.layout.Snapshot()
+	start := 0
+	for i, line := range lines {
+		newFile := line.Offset == 0
+		if newFile {
+			sp.layout = sp.layout.WithDefaultLayout()
+		}
+		directive := sp.layout.ApplyDirective(line.Content)
+		if !newFile && !directive {
+			continue
+		}
+
+		if i > start {
+			segments = append(segments, parseSegment{parser: parser, lines: lines[start:i]})
+		}
+		parser = sp.layout.Snapshot()
+		start = i
+		if directive {
+			start = i + 1
+		}
 	}
-	close(jobs)
+	if start < len(lines) {
+		segments = append(segments, parseSegment{parser: parser, lines: lines[start:]})
+	}
+	return segments
+}
 
-	// Wait for workers to finish
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+// restoreLayout applies the directive lines before the saved position when resuming mid-file
+// Otherwise a source with a custom #Fields layout would fail to parse until the next file.
+func (sp *SourceProcessor) restoreLayout() {
+	state := sp.reader.State()
+	if sp.layout == nil || state.Position == 0 {
+		return
+	}
 
-	// Collect results
-	parsedRequests := make([]*models.HTTPRequest, 0, len(lines))
-	for req := range results {
-		parsedRequests = append(parsedRequests, req)
+	err := sp.reader.ScanBefore(state.Position, func(line string) {
+		sp.layout.ApplyDirective(line)
+	})
+	if err != nil {
+		sp.logger.WithCaller().Warn("Failed to read the log layout before the saved position",
+			sp.logger.Args("source", sp.source.Name, "path", sp.reader.Path(), "error", err))
 	}
+}
 
-	return parsedRequests
+// parseChunk parses, converts and enriches a chunk of lines
+func (sp *SourceProcessor) parseChunk(parser parsers.LogParser, lines []LogLine) []*models.HTTPRequest {
+	results := make([]*models.HTTPRequest, 0, len(lines))
+	parseErrors := 0
+	dropped := 0
+
+	for _, logLine := range lines {
+		line := logLine.Content
+
+		// Skip lines that this parser cannot handle
+		if !parser.CanParse(line) {
+			sp.logger.Trace("Skipping line not supported by parser",
+				sp.logger.Args("source", sp.source.Name, "parser", parser.Name()))
+			continue
+		}
+
+		event, err := parser.Parse(line)
+		if errors.Is(err, parsers.ErrSkipLine) {
+			continue
+		}
+		if err != nil {
+			sp.logger.Warn("Failed to parse log line",
+				sp.logger.Args("source", sp.source.Name, "error", err, "line_preview", truncate(line, 100)))
+			parseErrors++
+			continue
+		}
+
+		// Client resolution and field overrides come before conversion, so dedup hashes and GeoIP use them
+		if sp.forwarded != nil {
+			sp.forwarded.Apply(event)
+		}
+		if sp.fieldMapping != nil {
+			sp.fieldMapping.Apply(sp.jsonMessage(line), event)
+		}
+
+		// Convert to database model
+		dbRequest := sp.convertToDBModel(event, logLine.Offset)
+
+		// Enrich with GeoIP data
+		if sp.geoIP != nil {
+			if err := sp.geoIP.Enrich(dbRequest); err != nil {
+				sp.logger.Debug("GeoIP enrichment failed",
+					sp.logger.Args("ip", dbRequest.ClientIP, "error", err))
+			}
+		}
+
+		// Parse User-Agent string
+		if dbRequest.UserAgent != "" {
+			uaInfo := useragent.Parse(dbRequest.UserAgent)
+			dbRequest.Browser = uaInfo.Browser
+			dbRequest.BrowserVersion = uaInfo.BrowserVersion
+			dbRequest.OS = uaInfo.OS
+			dbRequest.OSVersion = uaInfo.OSVersion
+			dbRequest.DeviceType = uaInfo.DeviceType
+		}
+
+		// Enricher plugins come last, so they can rely on and override the built-in enrichment
+		for _, enricher := range sp.enrichers {
+			if err := enricher.Enrich(dbRequest); err != nil {
+				sp.logger.Debug("Enrichment failed",
+					sp.logger.Args("enricher", enricher.Name(), "ip", dbRequest.ClientIP, "error", err))
+			}
+		}
+
+		// Transform rules see the enriched request, e.g. to drop bots or tag requests by country
+		if !sp.transforms.Apply(dbRequest) {
+			dropped++
+			continue
+		}
+		sp.labels.Apply(dbRequest)
+
+		results = append(results, dbRequest)
+	}
+
+	if parseErrors > 0 {
+		sp.metrics.Count("ingestion.parse_errors", int64(parseErrors), sp.metricTags...)
+	}
+	if dropped > 0 {
+		sp.metrics.Count("ingestion.requests.dropped", int64(dropped), sp.metricTags...)
+	}
+	return results
 }
 
-// flushBatch inserts the batch into the database
-func (sp *SourceProcessor) flushBatch(batch []*models.HTTPRequest) {
+// flushBatch inserts the batch into the database and saves the reader state in the same transaction
+// Returns the error if the batch could not be stored
+func (sp *SourceProcessor) flushBatch(batch []*models.HTTPRequest, state ReadState) error {
 	if len(batch) == 0 {
-		return
+		return nil
 	}
 
 	startTime := time.Now()
 
-	if err := sp.httpRepo.CreateBatch(batch); err != nil {
+	position := &repositories.SourcePosition{
+		SourceName:  sp.source.Name,
+		Position:    state.Position,
+		Inode:       state.Inode,
+		LastLine:    state.LastLine,
+		Fingerprint: state.Fingerprint,
+	}
+
+	// The shared writer merges batches of all sources into larger transactions
+	var result *repositories.BatchResult
+	var err error
+	if sp.writer != nil {
+		result, err = sp.writer.Write(batch, position)
+	} else {
+		result, err = sp.httpRepo.CreateBatchWithPosition(batch, position)
+	}
+	if err != nil {
 		sp.logger.WithCaller().Error("Failed to insert batch into database",
 			sp.logger.Args(
 				"source", sp.source.Name,
@@ -403,7 +717,18 @@ func (sp *SourceProcessor) flushBatch(batch []*models.HTTPRequest) {
 		sp.statsMu.Lock()
 		sp.totalErrors += int64(len(batch))
 		sp.statsMu.Unlock()
-		return
+		sp.metrics.Count("ingestion.batch.failed", 1, sp.metricTags...)
+		sp.metrics.Count("ingestion.requests.failed", int64(len(batch)), sp.metricTags...)
+		return err
+	}
+
+	// Persist dedup counters so silently dropped duplicates are visible per source
+	sp.recordDedupStats(result)
+	sp.recovery.record(result)
+
+	// Feed real-time consumers only after a successful insert
+	if sp.recorder != nil {
+		sp.recorder.Record(batch)
 	}
 
 	// Update stats
@@ -414,6 +739,12 @@ func (sp *SourceProcessor) flushBatch(batch []*models.HTTPRequest) {
 
 	duration := time.Since(startTime)
 	elapsed := time.Since(sp.startTime)
+
+	sp.metrics.Timing("ingestion.batch.duration", duration, sp.metricTags...)
+	if result != nil {
+		sp.metrics.Count("ingestion.requests.inserted", int64(result.Inserted), sp.metricTags...)
+		sp.metrics.Count("ingestion.requests.duplicates", int64(result.Duplicates), sp.metricTags...)
+	}
 	rate := float64(totalProcessed) / elapsed.Seconds()
 
 	sp.logger.Debug("Batch processed successfully",
@@ -425,11 +756,80 @@ func (sp *SourceProcessor) flushBatch(batch []*models.HTTPRequest) {
 			"rate_per_sec", int(rate),
 			"elapsed", elapsed.Round(time.Second).String(),
 		))
+	return nil
+}
+
+// coverageInterval is how often reading time is saved as source coverage
+const coverageInterval = time.Minute
+
+// recordCoverage adds the time since the last call to the coverage of the source, split by hour
+func (sp *SourceProcessor) recordCoverage(now time.Time) {
+	from := sp.coveredUntil
+	sp.coveredUntil = now
+	for from.Before(now) {
+		end := from.Truncate(time.Hour).Add(time.Hour)
+		if end.After(now) {
+			end = now
+		}
+		if seconds := int64(end.Sub(from).Round(time.Second) / time.Second); seconds > 0 {
+			if err := sp.sourceRepo.AddCoverage(sp.source.Name, from, seconds); err != nil {
+				sp.logger.Warn("Failed to save the coverage of the source",
+					sp.logger.Args("source", sp.source.Name, "error", err))
+				return
+			}
+		}
+		from = end
+	}
+}
+
+// setRunning marks whether the source is being read, a mark left at startup means the processor didn't stop
+func (sp *SourceProcessor) setRunning(running bool) {
+	if err := sp.sourceRepo.SetProcessorRunning(sp.source.Name, running); err != nil {
+		sp.logger.Warn("Failed to save the processor state of the source",
+			sp.logger.Args("source", sp.source.Name, "running", running, "error", err))
+	}
+}
+
+// recordDedupStats adds a batch outcome to the source's persisted dedup counters
+func (sp *SourceProcessor) recordDedupStats(result *repositories.BatchResult) {
+	if result == nil {
+		return
+	}
+
+	counts := repositories.DedupCounts{
+		Inserted:          int64(result.Inserted),
+		Duplicates:        int64(result.Duplicates),
+		InBatchDuplicates: int64(result.InBatchDuplicates),
+		HashCollisions:    int64(len(result.Collisions)),
+	}
+	if result.FirstLoad {
+		counts.FirstLoadInserts = int64(result.Inserted)
+	}
+
+	// Equal hashes of different requests mean legitimate traffic is dropped, the hash misses a differing field
+	if len(result.Collisions) > 0 {
+		collision := result.Collisions[len(result.Collisions)-1]
+		counts.LastCollision = fmt.Sprintf("%s: %s / %s", collision.Hash[:min(16, len(collision.Hash))], collision.Stored, collision.Incoming)
+		sp.logger.Warn("Hash collisions: duplicates differ from the stored requests",
+			sp.logger.Args("source", sp.source.Name, "collisions", len(result.Collisions), "last", counts.LastCollision))
+	}
+
+	if err := sp.sourceRepo.IncrementDedupCounters(sp.source.Name, counts); err != nil {
+		sp.logger.Warn("Failed to update dedup counters",
+			sp.logger.Args("source", sp.source.Name, "error", err))
+	}
+
+	if result.Duplicates > 0 {
+		sp.logger.Debug("Duplicates skipped in batch",
+			sp.logger.Args("source", sp.source.Name, "inserted", result.Inserted, "duplicates", result.Duplicates,
+				"in_batch", result.InBatchDuplicates, "first_load", result.FirstLoad))
+	}
 }
 
 // convertToDBModel converts a parser event to a database model using reflection
 // This avoids import cycles by not importing specific parser packages
-func (sp *SourceProcessor) convertToDBModel(event interface{}) *models.HTTPRequest {
+// lineOffset is the byte offset of the source line, used in the hash for events without precise timing
+func (sp *SourceProcessor) convertToDBModel(event parsers.Event, lineOffset int64) *models.HTTPRequest {
 	dbModel := &models.HTTPRequest{
 		SourceName: sp.source.Name,
 		Timestamp:  time.Now(),
@@ -452,20 +852,11 @@ func (sp *SourceProcessor) convertToDBModel(event interface{}) *models.HTTPReque
 	// Map fields by name from event to dbModel
 	for i := 0; i < eventValue.NumField(); i++ {
 		eventField := eventValue.Type().Field(i)
-		eventFieldValue := eventValue.Field(i)
 
-		// Skip SourceName as we set it explicitly
-		if eventField.Name == "SourceName" {
-			continue
-		}
-
-		// Find corresponding field in dbModel
-		dbField := dbModelValue.FieldByName(eventField.Name)
-		if dbField.IsValid() && dbField.CanSet() {
-			// Set the value if types match
-			if dbField.Type() == eventFieldValue.Type() {
-				dbField.Set(eventFieldValue)
-			}
+		// Copy fields with the same name and type (SourceName is set explicitly)
+		// Renamed fields are reported by ValidateEventMapping when ingestion starts
+		if mappedField(eventField) {
+			dbModelValue.FieldByName(eventField.Name).Set(eventValue.Field(i))
 		}
 	}
 
@@ -488,6 +879,14 @@ func (sp *SourceProcessor) convertToDBModel(event interface{}) *models.HTTPReque
 		dbModel.StartUTC,      // Nanosecond precision start time
 		dbModel.RequestsTotal, // Total requests at router level
 	)
+
+	// Formats without nanosecond precision (CLF) can't tell apart identical requests in the same second,
+	// so the line's file offset is added to keep bursts from collapsing into one row.
+	// The offset is stable across re-reads of the same file, so crash recovery still deduplicates.
+	if useLineOffset(sp.lineOffsetMode, event) {
+		hashInput = fmt.Sprintf("%s|@%d", hashInput, lineOffset)
+	}
+
 	hash := sha256.Sum256([]byte(hashInput))
 	dbModel.RequestHash = fmt.Sprintf("%x", hash)
 
@@ -497,6 +896,15 @@ func (sp *SourceProcessor) convertToDBModel(event interface{}) *models.HTTPReque
 	return dbModel
 }
 
+// jsonMessage returns the log message of a line, unwrapping container log lines of CRI sources
+func (sp *SourceProcessor) jsonMessage(line string) string {
+	if strings.HasPrefix(sp.source.ParserType, parsers.CRIPrefix) {
+		message, _ := parsers.UnwrapContainerLine(line)
+		return message
+	}
+	return line
+}
+
 // truncate truncates a string to maxLen characters for logging
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,89 @@
+package ingestion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+func TestSourceProcessor_RecordCoverageSplitsHours(t *testing.T) {
+	sourceRepo := fake.NewLogSources(&models.LogSource{Name: "traefik", Path: "/var/log/traefik/access.log", ParserType: "traefik"})
+	start := time.Date(2026, 3, 10, 11, 59, 30, 0, time.UTC)
+	sp := &SourceProcessor{
+		source:       &models.LogSource{Name: "traefik"},
+		sourceRepo:   sourceRepo,
+		logger:       pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled),
+		coveredUntil: start,
+	}
+
+	sp.recordCoverage(start.Add(time.Minute))
+	sp.recordCoverage(start.Add(2 * time.Minute))
+
+	coverage := sourceRepo.Coverage("traefik")
+	if len(coverage) != 2 || coverage["2026-03-10 11:00"] != 30 || coverage["2026-03-10 12:00"] != 90 {
+		t.Fatalf("Expected 30s before 12:00 and 90s after, got %v", coverage)
+	}
+}
+
+func TestSourceProcessor_IISLayoutPerSourceAndFile(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	registry := parsers.NewRegistry(nil, nil, logger)
+	shared, err := registry.Get("iis")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const fields = "#Fields: date time s-sitename cs-method cs-uri-stem c-ip sc-status time-taken"
+	const custom = "2025-10-15 08:12:45 W3SVC2 GET /custom 203.0.113.7 200 40"
+	const standard = "2025-10-15 08:12:46 10.0.0.5 GET /default.aspx - 443 - 203.0.113.8 Mozilla/5.0 - 200 0 0 125"
+
+	// The path of the saved position holds a custom layout declared before it
+	dir := t.TempDir()
+	path := filepath.Join(dir, "u_ex251015.log")
+	header := "#Software: Microsoft Internet Information Services 10.0\r\n" + fields + "\r\n" + custom + "\r\n"
+	if err := os.WriteFile(path, []byte(header), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newProcessor := func(name string, position int64) *SourceProcessor {
+		source := &models.LogSource{Name: name, Path: path, ParserType: "iis", LastPosition: position}
+		return NewSourceProcessor(source, shared, nil, nil, nil, nil, nil, nil, nil, LineOffsetAuto,
+			nil, nil, nil, nil, nil, logger, BatchSettings{}, 1024*1024)
+	}
+
+	// Resuming mid-file reads the layout back from the file
+	resumed := newProcessor("iis-w3svc2", int64(len(header)))
+	resumed.restoreLayout()
+	requests := resumed.parseAndEnrichParallel([]LogLine{{Content: custom, Offset: int64(len(header))}})
+	if len(requests) != 1 || requests[0].Path != "/custom" || requests[0].BackendName != "W3SVC2" {
+		t.Fatalf("Expected the custom layout after resuming, got %+v", requests)
+	}
+
+	// Another source keeps the default layout
+	other := newProcessor("iis-w3svc1", 0)
+	if requests := other.parseAndEnrichParallel([]LogLine{{Content: standard, Offset: 10}}); len(requests) != 1 {
+		t.Fatalf("Expected the default layout in another source, got %d requests", len(requests))
+	}
+
+	// Directives apply in order within a batch, and a new file starts from the default layout
+	requests = resumed.parseAndEnrichParallel([]LogLine{
+		{Content: custom, Offset: 200},
+		{Content: "#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) cs(Referer) sc-status sc-substatus sc-win32-status time-taken", Offset: 300},
+		{Content: standard, Offset: 500},
+		{Content: "#Software: Microsoft Internet Information Services 10.0", Offset: 0},
+		{Content: standard, Offset: 60},
+		{Content: fields, Offset: 200},
+		{Content: custom, Offset: 300},
+	})
+	if len(requests) != 4 || requests[0].Path != "/custom" || requests[1].Path != "/default.aspx" ||
+		requests[2].Path != "/default.aspx" || requests[3].Path != "/custom" {
+		t.Errorf("Expected each line parsed with the layout in effect, got %d requests", len(requests))
+	}
+}
@@ -2,10 +2,11 @@ package ingestion
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 	"os"
-	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	parsers "loglynx/internal/parser"
@@ -13,35 +14,127 @@ import (
 	"github.com/pterm/pterm"
 )
 
+// LogLine is a raw log line with the byte offset it starts at in the file
+type LogLine struct {
+	Content string
+	Offset  int64
+}
+
+// ReadState is the reader position to persist once the lines read so far are stored
+type ReadState struct {
+	Position    int64  // Offset right after the last consumed line
+	Inode       int64  // Identity of the file Position refers to
+	LastLine    string // Tail of the last consumed line (continuity check)
+	Fingerprint string // Fingerprint of the file's first line (detects copytruncate / in-place replacement)
+}
+
 // IncrementalReader reads log files incrementally, tracking position
 // and detecting log rotation
 type IncrementalReader struct {
-	filePath        string
+	filePath        string // File being read (newest match when following a pattern)
+	pattern         string // Glob pattern of a series of files (e.g. IIS daily logs), empty for a single file
 	lastPosition    int64
 	lastInode       int64 // File identifier (inode on Unix, file index on Windows)
 	lastLineContent string
+	fingerprint     string // First-line fingerprint of the file being read
+	rotatedPath     string // Rotated file still being drained before switching to the new file
+	maxLineLength   int    // Lines longer than this (bytes) are skipped
+	oversizedLines  int64  // Number of skipped oversized lines (atomic)
 	logger          *pterm.Logger
 }
 
-// NewIncrementalReader creates a new incremental reader
-func NewIncrementalReader(filePath string, lastPos int64, lastInode int64, lastLine string, logger *pterm.Logger) *IncrementalReader {
-	return &IncrementalReader{
+// DefaultMaxLineLength is the default maximum log line length (1 MB)
+const DefaultMaxLineLength = 1024 * 1024
+
+// NewIncrementalReader creates a new incremental reader resuming from a persisted state
+func NewIncrementalReader(filePath string, state ReadState, maxLineLength int, logger *pterm.Logger) *IncrementalReader {
+	if maxLineLength <= 0 {
+		maxLineLength = DefaultMaxLineLength
+	}
+
+	r := &IncrementalReader{
 		filePath:        filePath,
-		lastPosition:    lastPos,
-		lastInode:       lastInode,
-		lastLineContent: lastLine,
+		lastPosition:    state.Position,
+		lastInode:       state.Inode,
+		lastLineContent: state.LastLine,
+		fingerprint:     state.Fingerprint,
+		maxLineLength:   maxLineLength,
 		logger:          logger,
 	}
+
+	if isGlobPattern(filePath) {
+		r.pattern = filePath
+		r.filePath = newestMatch(filePath)
+	}
+
+	return r
+}
+
+// OversizedLines returns how many lines were skipped for exceeding the maximum line length
+func (r *IncrementalReader) OversizedLines() int64 {
+	return atomic.LoadInt64(&r.oversizedLines)
+}
+
+// Path returns the file currently being read (the rotated file while it is drained)
+func (r *IncrementalReader) Path() string {
+	if r.rotatedPath != "" {
+		return r.rotatedPath
+	}
+	return r.filePath
 }
 
-// ReadBatch reads up to maxLines new lines from the file
-// Returns: lines read, new position, new inode, last line content (for continuity check), error
-func (r *IncrementalReader) ReadBatch(maxLines int) ([]string, int64, int64, string, error) {
+// State returns the current reader position
+func (r *IncrementalReader) State() ReadState {
+	return ReadState{
+		Position:    r.lastPosition,
+		Inode:       r.lastInode,
+		LastLine:    r.lastLineContent,
+		Fingerprint: r.fingerprint,
+	}
+}
+
+// ReadBatch reads up to maxLines new complete lines and advances the reader
+// Returns the lines read and the state to persist once they are stored
+//
+// Rotation handling:
+//   - rename (logrotate create): the inode at the path changes; the rotated file is found by its
+//     inode and drained from the last position before reading the new file from the start
+//   - copytruncate: the file shrinks, or its first-line fingerprint changes; the copy is found by
+//     its fingerprint and drained the same way
+//   - continuity: the tail of the line before the stored position must match the last line read,
+//     otherwise the file changed underneath us and is read again from the start
+func (r *IncrementalReader) ReadBatch(maxLines int) ([]LogLine, ReadState, error) {
+	// Finish the rotated file first so lines written right before rotation aren't lost
+	if r.rotatedPath != "" {
+		return r.drainRotated(maxLines)
+	}
+
+	// Following a series of files: switch to a newer file once it appears
+	if r.pattern != "" {
+		if newest := newestMatch(r.pattern); newest != "" && newest != r.filePath {
+			previous := r.filePath
+			r.filePath = newest
+			if previous != "" {
+				r.logger.Info("Newer log file found, switching to it",
+					r.logger.Args("pattern", r.pattern, "previous", previous, "path", newest))
+				if r.startDrain(previous) {
+					return r.drainRotated(maxLines)
+				}
+				r.resetPosition(0)
+			}
+		}
+		if r.filePath == "" {
+			r.logger.Debug("No log file matches pattern yet, waiting for creation",
+				r.logger.Args("pattern", r.pattern))
+			return []LogLine{}, r.State(), nil
+		}
+	}
+
 	// Check if file exists first
 	if _, err := os.Stat(r.filePath); os.IsNotExist(err) {
 		r.logger.Warn("Log file does not exist yet, waiting for creation",
 			r.logger.Args("path", r.filePath))
-		return []string{}, r.lastPosition, r.lastInode, r.lastLineContent, nil // Return empty, don't error
+		return []LogLine{}, r.State(), nil // Return empty, don't error
 	}
 
 	file, err := os.Open(r.filePath)
@@ -50,11 +143,11 @@ func (r *IncrementalReader) ReadBatch(maxLines int) ([]string, int64, int64, str
 		if os.IsPermission(err) {
 			r.logger.Error("Permission denied accessing log file",
 				r.logger.Args("path", r.filePath, "error", err))
-			return []string{}, r.lastPosition, r.lastInode, r.lastLineContent, nil // Don't crash, just skip this read
+			return []LogLine{}, r.State(), nil // Don't crash, just skip this read
 		}
 		r.logger.Warn("Failed to open log file, will retry",
 			r.logger.Args("path", r.filePath, "error", err))
-		return []string{}, r.lastPosition, r.lastInode, r.lastLineContent, nil // Return empty, don't error
+		return []LogLine{}, r.State(), nil // Return empty, don't error
 	}
 	defer file.Close()
 
@@ -62,7 +155,7 @@ func (r *IncrementalReader) ReadBatch(maxLines int) ([]string, int64, int64, str
 	stat, err := file.Stat()
 	if err != nil {
 		r.logger.WithCaller().Error("Failed to stat log file", r.logger.Args("path", r.filePath, "error", err))
-		return nil, 0, 0, "", err
+		return nil, r.State(), err
 	}
 
 	fileSize := stat.Size()
@@ -74,161 +167,293 @@ func (r *IncrementalReader) ReadBatch(maxLines int) ([]string, int64, int64, str
 		currentInode = 0 // Continue without inode check
 	}
 
-	// ROTATION DETECTION CASE 1: File identity changed (deleted and recreated)
-	// This happens when inode changes, indicating the file was deleted and a new file created
+	currentFingerprint := fileFingerprint(file)
+
+	// ROTATION DETECTION CASE 1: File identity changed (renamed away and recreated)
 	if r.lastInode != 0 && currentInode != 0 && currentInode != r.lastInode {
-		r.logger.Info("Log rotation detected: file deleted and recreated (inode changed)",
+		r.logger.Info("Log rotation detected: file renamed and recreated (inode changed)",
 			r.logger.Args(
 				"path", r.filePath,
 				"old_inode", r.lastInode,
 				"new_inode", currentInode,
 			))
-		r.lastPosition = 0
-		r.lastLineContent = ""
-		r.lastInode = currentInode
+		if r.startDrain(findRotatedFile(r.rotationCandidates(), r.lastInode, "", 0)) {
+			return r.drainRotated(maxLines)
+		}
+		r.resetPosition(currentInode)
 	} else if currentInode != 0 {
 		// Update inode for next check
 		r.lastInode = currentInode
 	}
 
-	// ROTATION DETECTION CASE 2: File truncated (size < last position)
-	if fileSize < r.lastPosition {
-		r.logger.Info("Log rotation detected: file truncated",
+	// ROTATION DETECTION CASE 2: File truncated (copytruncate) or replaced in place
+	truncated := fileSize < r.lastPosition
+	replaced := r.lastPosition > 0 && r.fingerprint != "" && currentFingerprint != "" && currentFingerprint != r.fingerprint
+	if truncated || replaced {
+		r.logger.Info("Log rotation detected: file truncated or replaced in place",
 			r.logger.Args(
 				"path", r.filePath,
-				"old_size", r.lastPosition,
+				"old_position", r.lastPosition,
 				"new_size", fileSize,
+				"fingerprint_changed", replaced,
 			))
-		r.lastPosition = 0
-		r.lastLineContent = ""
+		if r.startDrain(findRotatedFile(r.rotationCandidates(), 0, r.fingerprint, r.lastPosition)) {
+			return r.drainRotated(maxLines)
+		}
+		r.resetPosition(currentInode)
 	}
 
-	// Seek to last known position
-	_, err = file.Seek(r.lastPosition, 0)
+	if currentFingerprint != "" {
+		r.fingerprint = currentFingerprint
+	}
+
+	lines, err := r.readLines(file, maxLines)
 	if err != nil {
-		r.logger.WithCaller().Error("Failed to seek in log file",
-			r.logger.Args("path", r.filePath, "position", r.lastPosition, "error", err))
-		return nil, 0, 0, "", err
+		return nil, r.State(), err
 	}
 
-	// If we're not at the beginning, we might be in the middle of a line.
-	// Seek forward to the next newline to ensure we start at a line boundary.
-	if r.lastPosition > 0 {
-		buf := make([]byte, 1)
-		for {
-			_, err := file.Read(buf)
+	return lines, r.State(), nil
+}
+
+// startDrain switches the reader to a rotated file, returning false if there is nothing to drain
+func (r *IncrementalReader) startDrain(rotatedPath string) bool {
+	if rotatedPath == "" || r.lastPosition == 0 {
+		return false
+	}
+
+	r.logger.Info("Draining rotated log file before switching to the new file",
+		r.logger.Args("path", r.filePath, "rotated_path", rotatedPath, "position", r.lastPosition))
+	r.rotatedPath = rotatedPath
+	return true
+}
+
+// drainRotated reads the remaining lines of the rotated file, then switches to the new file
+func (r *IncrementalReader) drainRotated(maxLines int) ([]LogLine, ReadState, error) {
+	file, err := os.Open(r.rotatedPath)
+	if err != nil {
+		r.logger.Warn("Rotated log file disappeared before it was fully read",
+			r.logger.Args("rotated_path", r.rotatedPath, "error", err))
+		r.rotatedPath = ""
+		r.resetPosition(0)
+		return []LogLine{}, r.State(), nil
+	}
+	defer file.Close()
+
+	lines, err := r.readLines(file, maxLines)
+	if err != nil {
+		return nil, r.State(), err
+	}
+
+	if len(lines) == 0 {
+		r.logger.Info("Rotated log file fully read, continuing with the new file",
+			r.logger.Args("rotated_path", r.rotatedPath))
+		r.rotatedPath = ""
+		r.resetPosition(0)
+	}
+
+	return lines, r.State(), nil
+}
+
+// rotationCandidates lists the files the previous file may have been rotated to
+// When following a pattern, the other files of the series are candidates too
+func (r *IncrementalReader) rotationCandidates() []string {
+	candidates := rotationCandidates(r.filePath)
+	if r.pattern == "" {
+		return candidates
+	}
+
+	for _, match := range globFiles(r.pattern) {
+		if match != r.filePath {
+			candidates = append(candidates, match)
+		}
+	}
+	return candidates
+}
+
+// resetPosition starts reading the file at path from the beginning
+func (r *IncrementalReader) resetPosition(inode int64) {
+	r.lastPosition = 0
+	r.lastInode = inode
+	r.lastLineContent = ""
+	r.fingerprint = ""
+}
+
+// readLines reads up to maxLines complete lines from the stored position and advances it
+func (r *IncrementalReader) readLines(file *os.File, maxLines int) ([]LogLine, error) {
+	position := r.lastPosition
+
+	// Continuity check: the stored position must be right after the last line we read
+	if position > 0 {
+		tail, atBoundary, err := lineTailBefore(file, position)
+		if err != nil {
+			r.logger.WithCaller().Error("Failed to read log file for continuity check",
+				r.logger.Args("path", file.Name(), "position", position, "error", err))
+			return nil, err
+		}
+
+		switch {
+		case !atBoundary:
+			// Position is inside a line (import limit by date, or a position saved by an older version):
+			// move forward to the start of the next line
+			next, found, err := nextLineStart(file, position)
 			if err != nil {
-				if err == io.EOF {
-					// Reached end of file, no more lines
-					return []string{}, r.lastPosition, r.lastInode, r.lastLineContent, nil
-				}
 				r.logger.WithCaller().Error("Failed to read while seeking to newline",
-					r.logger.Args("path", r.filePath, "error", err))
-				return nil, 0, 0, "", err
+					r.logger.Args("path", file.Name(), "error", err))
+				return nil, err
 			}
-			if buf[0] == '\n' {
-				// Found newline, current position is at start of next line
-				break
+			if !found {
+				return []LogLine{}, nil
 			}
+			r.logger.Debug("Realigned position to the next line start",
+				r.logger.Args("path", file.Name(), "old_position", position, "new_position", next))
+			position = next
+		case r.lastLineContent != "" && tail != r.lastLineContent:
+			// The line before our position is not the one we read: the file changed underneath us
+			r.logger.Info("Log rotation detected: line continuity broken, reading file from the start",
+				r.logger.Args("path", file.Name(), "position", position))
+			r.lastPosition = 0
+			r.lastLineContent = ""
+			position = 0
 		}
 	}
 
-	lines := []string{}
-	scanner := bufio.NewScanner(file)
-	firstLine := true
-	rotationDetected := false
+	if _, err := file.Seek(position, io.SeekStart); err != nil {
+		r.logger.WithCaller().Error("Failed to seek in log file",
+			r.logger.Args("path", file.Name(), "position", position, "error", err))
+		return nil, err
+	}
 
-	for scanner.Scan() && len(lines) < maxLines {
-		line := scanner.Text()
+	// Byte offset of the next line start (used to tell apart identical lines in dedup hashes)
+	lineOffset := position
 
-		// ROTATION DETECTION CASE 2: Continuity check for rename-based rotation
-		// This logic runs only on the first line of a new read batch.
-		if firstLine {
-			firstLine = false
-
-			// Temporarily disable continuity check to avoid false warnings
-			/*
-				if r.lastLineContent != "" {
-					// We have a "last line" from a previous run. The first line we read now
-					// should be that same line, because our position is at the start of it.
-					currentTail := getTail(line, 500)
-					if r.lastLineContent == currentTail {
-						// Continuity is valid. We are reading the same line we finished on.
-						// Skip it to avoid processing it twice.
-						r.logger.Trace("Continuity validated, skipping already-processed line")
-						continue
-					}
+	lines := []LogLine{}
+	scanner := bufio.NewScanner(file)
 
-					// If the tails do not match, it means the file has changed underneath us,
-					// which strongly suggests a log rotation via renaming.
-					r.logger.Debug("Line continuity broken: log rotation with rename detected. Resetting to start of file.",
-						r.logger.Args("path", r.filePath, "expected_tail", r.lastLineContent, "actual_tail", currentTail))
+	// Allow lines up to maxLineLength; the extra byte lets the split function see that a line is too long
+	// instead of the scanner failing with ErrTooLong (which would stop ingestion for good)
+	scanner.Buffer(make([]byte, 0, 64*1024), r.maxLineLength+1)
 
-					// Reset position to read the new file from the beginning.
-					r.lastPosition = 0
-					r.lastLineContent = ""
-					// Return immediately to restart the ReadBatch operation with the corrected position.
-					return r.ReadBatch(maxLines)
+	// Track the exact byte offset of each token (ScanLines strips "\n" and "\r\n")
+	var tokenOffset int64
+	skipping := false
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		// Oversized lines are consumed within this call: returning no token at EOF would end the scan
+		consumed := 0
+		for {
+			rest := data[consumed:]
+
+			// Discard the rest of an oversized line up to its newline
+			if skipping {
+				i := bytes.IndexByte(rest, '\n')
+				if i < 0 {
+					lineOffset += int64(len(rest))
+					return len(data), nil, nil
 				}
-			*/
+				skipping = false
+				consumed += i + 1
+				lineOffset += int64(i + 1)
+				continue
+			}
+
+			// A trailing line without newline is still being written: leave it for the next read
+			if atEOF && bytes.IndexByte(rest, '\n') < 0 {
+				return consumed, nil, nil
+			}
+
+			advance, token, err := bufio.ScanLines(rest, atEOF)
+			if token == nil && advance == 0 && err == nil && len(rest) > r.maxLineLength {
+				// No newline within the limit: skip until the end of this line
+				r.skipOversizedLine(lineOffset)
+				skipping = true
+				continue
+			}
+			if token != nil && len(token) > r.maxLineLength {
+				r.skipOversizedLine(lineOffset)
+				consumed += advance
+				lineOffset += int64(advance)
+				continue
+			}
+
+			if token != nil {
+				tokenOffset = lineOffset
+			}
+			lineOffset += int64(advance)
+			return consumed + advance, token, err
 		}
+	})
+
+	// Stop before scanning a line we can't keep, so the position matches the lines returned
+	for len(lines) < maxLines && scanner.Scan() {
+		line := scanner.Text()
 
 		// Add line to batch
 		if line != "" {
-			lines = append(lines, line)
+			lines = append(lines, LogLine{Content: line, Offset: tokenOffset})
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		r.logger.WithCaller().Error("Scanner error while reading log file",
-			r.logger.Args("path", r.filePath, "error", err))
-		return nil, 0, 0, "", err
+			r.logger.Args("path", file.Name(), "error", err))
+		return nil, err
 	}
 
-	// After reading a batch, the file pointer is at the start of the *next* line.
-	// To maintain continuity, we need to know the content of the *last* line we just read.
-	// However, the current position is past it. We can't reliably go backward.
-	// A simple and effective strategy is to not update the position if no lines were read.
-	// If lines were read, we update the position and the last line content.
+	// The split function only consumes what it returned, so lineOffset is exactly
+	// the end of the last consumed line (no read-ahead from the scanner buffer)
+	if lineOffset == r.lastPosition {
+		return lines, nil
+	}
 
-	// Get the current position *before* we potentially modify it.
-	newPos, err := file.Seek(0, io.SeekCurrent)
+	tail, _, err := lineTailBefore(file, lineOffset)
 	if err != nil {
-		r.logger.WithCaller().Warn("Failed to get current position",
-			r.logger.Args("path", r.filePath, "error", err))
-		// If we can't get the position, it's safer to stick with the old one to force a re-read.
-		newPos = r.lastPosition
+		r.logger.WithCaller().Warn("Failed to read last line for continuity check",
+			r.logger.Args("path", file.Name(), "error", err))
 	}
 
-	// If we read any lines, we update our tracking info.
-	if len(lines) > 0 {
-		lastLineRead := lines[len(lines)-1]
+	r.logger.Trace("Read batch from log file",
+		r.logger.Args(
+			"path", file.Name(),
+			"lines_read", len(lines),
+			"old_position", r.lastPosition,
+			"new_position", lineOffset,
+		))
 
-		newLastPosition := newPos
+	r.lastPosition = lineOffset
+	r.lastLineContent = tail
 
-		// Get last line for next continuity check
-		lastLineForCheck := getTail(lastLineRead, 500)
+	return lines, nil
+}
 
-		r.logger.Trace("Read batch from log file",
-			r.logger.Args(
-				"path", r.filePath,
-				"lines_read", len(lines),
-				"old_position", r.lastPosition,
-				"new_position", newLastPosition,
-				"rotation_detected", rotationDetected,
-			))
+// skipOversizedLine counts and reports a line exceeding the maximum line length
+func (r *IncrementalReader) skipOversizedLine(offset int64) {
+	atomic.AddInt64(&r.oversizedLines, 1)
+	r.logger.Warn("Skipping log line exceeding maximum line length",
+		r.logger.Args("path", r.filePath, "offset", offset, "max_line_length", r.maxLineLength))
+}
 
-		return lines, newLastPosition, r.lastInode, lastLineForCheck, nil
+// ScanBefore calls fn with each complete line of the current file before the given position, in order
+func (r *IncrementalReader) ScanBefore(position int64, fn func(line string)) error {
+	file, err := os.Open(r.Path())
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	// No new lines were read, so we don't update the position or last line content.
-	return []string{}, r.lastPosition, r.lastInode, r.lastLineContent, nil
+	reader := bufio.NewReader(io.LimitReader(file, position))
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(strings.TrimRight(line, "\r\n"))
+	}
 }
 
-// UpdatePosition is called by the processor to confirm the position after a successful batch write.
+// UpdatePosition moves the reader to an explicit position (e.g. initial import limit)
 func (r *IncrementalReader) UpdatePosition(position int64, inode int64, lastLine string) {
-	// This function is now less critical as ReadBatch returns the correct state,
-	// but we keep it for explicit state management by the caller if needed.
 	r.lastPosition = position
 	r.lastInode = inode
 	r.lastLineContent = lastLine
@@ -243,9 +468,8 @@ func (r *IncrementalReader) UpdatePosition(position int64, inode int64, lastLine
 // Reset resets the reader to the beginning of the file
 func (r *IncrementalReader) Reset() {
 	r.logger.Info("Resetting reader to beginning", r.logger.Args("path", r.filePath))
-	r.lastPosition = 0
-	r.lastInode = 0
-	r.lastLineContent = ""
+	r.rotatedPath = ""
+	r.resetPosition(0)
 }
 
 // getTail returns the last maxLen characters of a string
@@ -300,6 +524,7 @@ func (r *IncrementalReader) FindStartPositionByDate(cutoffDate time.Time, parser
 
 		// Find next line boundary
 		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), r.maxLineLength+1)
 		if mid > 0 {
 			// Skip partial line
 			scanner.Scan()
@@ -372,6 +597,7 @@ func (r *IncrementalReader) FindStartPositionByDate(cutoffDate time.Time, parser
 
 		file.Seek(lookbackStart, io.SeekStart)
 		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), r.maxLineLength+1)
 
 		var refinedPosition int64 = lookbackStart
 		var lastLineStart int64 = lookbackStart
@@ -451,45 +677,3 @@ func extractTimestamp(event interface{}) time.Time {
 	// This is handled by the parser, so we'll just return zero time if not available
 	return time.Time{}
 }
-
-// getFileInode returns a stable identifier for the file using reflection to access system-specific inode
-// This works across platforms (Linux, macOS, Windows) without build tags
-func getFileInode(file *os.File) (int64, error) {
-	stat, err := file.Stat()
-	if err != nil {
-		return 0, err
-	}
-
-	// Try to get the real inode using reflection on stat.Sys()
-	// This works on Unix/Linux/macOS where Sys() returns *syscall.Stat_t with Ino field
-	sys := stat.Sys()
-	if sys != nil {
-		// Use reflection to safely access Ino field if it exists
-		v := reflect.ValueOf(sys)
-		if v.Kind() == reflect.Ptr {
-			v = v.Elem()
-		}
-		if v.Kind() == reflect.Struct {
-			// Try to get Ino field (Unix/Linux/macOS)
-			inoField := v.FieldByName("Ino")
-			if inoField.IsValid() && inoField.CanUint() {
-				return int64(inoField.Uint()), nil
-			}
-
-			// Try FileIndex for Windows (similar to inode)
-			fileIndexField := v.FieldByName("FileIndexHigh")
-			if fileIndexField.IsValid() && fileIndexField.CanUint() {
-				fileIndexHigh := fileIndexField.Uint()
-				fileIndexLow := uint64(0)
-				if lowField := v.FieldByName("FileIndexLow"); lowField.IsValid() && lowField.CanUint() {
-					fileIndexLow = lowField.Uint()
-				}
-				return int64((fileIndexHigh << 32) | fileIndexLow), nil
-			}
-		}
-	}
-
-	// Fallback: Since we can't get a real inode, we return 0 and rely only on file size changes
-	// This means we won't detect rotation by inode, but we'll still detect truncation
-	return 0, nil
-}
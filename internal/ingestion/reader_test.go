@@ -0,0 +1,330 @@
+package ingestion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+func newTestReader(t *testing.T, path string, state ReadState) *IncrementalReader {
+	t.Helper()
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	return NewIncrementalReader(path, state, 0, logger)
+}
+
+// appendLines writes lines "<prefix>-<i>" for i in [from, to)
+func appendLines(t *testing.T, path string, prefix string, from, to int) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	for i := from; i < to; i++ {
+		if _, err := fmt.Fprintf(file, "%s-%d\n", prefix, i); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+// readAll reads batches until the reader settles (two empty reads in a row,
+// since finishing a rotated file returns an empty batch before switching files)
+func readAll(t *testing.T, reader *IncrementalReader, batchSize int) []string {
+	t.Helper()
+	var lines []string
+	empty := 0
+	for i := 0; i < 1000; i++ {
+		batch, _, err := reader.ReadBatch(batchSize)
+		if err != nil {
+			t.Fatalf("ReadBatch: %v", err)
+		}
+		if len(batch) == 0 {
+			empty++
+			if empty == 2 {
+				return lines
+			}
+			continue
+		}
+		empty = 0
+		for _, line := range batch {
+			lines = append(lines, line.Content)
+		}
+	}
+	t.Fatal("reader did not settle")
+	return nil
+}
+
+// expectExactlyOnce checks every expected line was read once and nothing else was read
+func expectExactlyOnce(t *testing.T, got []string, expected []string) {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, line := range got {
+		counts[line]++
+	}
+	for _, line := range expected {
+		switch counts[line] {
+		case 1:
+		case 0:
+			t.Errorf("line %q was lost", line)
+		default:
+			t.Errorf("line %q was read %d times", line, counts[line])
+		}
+		delete(counts, line)
+	}
+	for line := range counts {
+		t.Errorf("unexpected line %q", line)
+	}
+}
+
+func lineNames(prefix string, from, to int) []string {
+	names := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", prefix, i))
+	}
+	return names
+}
+
+func TestReader_AppendOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendLines(t, path, "a", 0, 10)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 3)
+
+	appendLines(t, path, "a", 10, 15)
+	got = append(got, readAll(t, reader, 3)...)
+
+	expectExactlyOnce(t, got, lineNames("a", 0, 15))
+}
+
+func TestReader_LineOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("first\r\nsecond\nthird\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := newTestReader(t, path, ReadState{})
+	lines, state, err := reader.ReadBatch(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []LogLine{{"first", 0}, {"second", 7}, {"third", 14}}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+	if state.Position != 20 {
+		t.Errorf("expected position 20, got %d", state.Position)
+	}
+}
+
+func TestReader_PartialTrailingLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendLines(t, path, "a", 0, 2)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("a-2 still being wr")
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 10)
+	expectExactlyOnce(t, got, lineNames("a", 0, 2))
+
+	file.WriteString("itten\n")
+	file.Close()
+
+	got = readAll(t, reader, 10)
+	expectExactlyOnce(t, got, []string{"a-2 still being written"})
+}
+
+func TestReader_RenameRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLines(t, path, "old", 0, 10)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 4)
+
+	// Lines written right before rotation, not read yet
+	appendLines(t, path, "old", 10, 15)
+
+	// logrotate "create": rename the file and create a new one
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path, "new", 0, 5)
+
+	got = append(got, readAll(t, reader, 4)...)
+
+	expected := append(lineNames("old", 0, 15), lineNames("new", 0, 5)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+func TestReader_RenameRotationResumeFromState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLines(t, path, "old", 0, 10)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 4)
+	saved := reader.State()
+
+	// Rotation happens while LogLynx is stopped
+	appendLines(t, path, "old", 10, 12)
+	if err := os.Rename(path, path+"-20250101"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, path, "new", 0, 3)
+
+	restarted := newTestReader(t, path, saved)
+	got = append(got, readAll(t, restarted, 4)...)
+
+	expected := append(lineNames("old", 0, 12), lineNames("new", 0, 3)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+// copyTruncate simulates logrotate copytruncate: copy the file, then truncate it in place
+func copyTruncate(t *testing.T, path string, rotated string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rotated, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReader_CopyTruncate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLines(t, path, "old", 0, 10)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 4)
+
+	appendLines(t, path, "old", 10, 13)
+	copyTruncate(t, path, path+".1")
+	appendLines(t, path, "new", 0, 2)
+
+	got = append(got, readAll(t, reader, 4)...)
+
+	expected := append(lineNames("old", 0, 13), lineNames("new", 0, 2)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+func TestReader_CopyTruncateNewFileLarger(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLines(t, path, "old", 0, 5)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 4)
+
+	appendLines(t, path, "old", 5, 7)
+	copyTruncate(t, path, path+".1")
+
+	// The new file grows past the old position before the next read (size check alone misses it)
+	appendLines(t, path, "new", 0, 50)
+
+	got = append(got, readAll(t, reader, 8)...)
+
+	expected := append(lineNames("old", 0, 7), lineNames("new", 0, 50)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+func TestReader_CopyTruncateWithoutCopy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	appendLines(t, path, "old", 0, 10)
+
+	reader := newTestReader(t, path, ReadState{})
+	got := readAll(t, reader, 4)
+
+	// Rotated copy was compressed right away: nothing to drain, the new file is read from the start
+	copyTruncate(t, path, path+".1.gz")
+	appendLines(t, path, "new", 0, 3)
+
+	got = append(got, readAll(t, reader, 4)...)
+
+	expected := append(lineNames("old", 0, 10), lineNames("new", 0, 3)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+func TestReader_RealignsMidLinePosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendLines(t, path, "a", 0, 5)
+
+	// Positions from the initial import limit may point inside a line
+	reader := newTestReader(t, path, ReadState{Position: 2})
+	got := readAll(t, reader, 10)
+
+	expectExactlyOnce(t, got, lineNames("a", 1, 5))
+}
+
+func TestReader_FollowsNewestFileOfPattern(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "u_ex*.log")
+	appendLines(t, filepath.Join(dir, "u_ex251014.log"), "day1", 0, 5)
+
+	reader := newTestReader(t, pattern, ReadState{})
+	got := readAll(t, reader, 2)
+
+	// Last requests of the day are written right before the next daily file is created
+	appendLines(t, filepath.Join(dir, "u_ex251014.log"), "day1", 5, 8)
+	appendLines(t, filepath.Join(dir, "u_ex251015.log"), "day2", 0, 4)
+	got = append(got, readAll(t, reader, 2)...)
+
+	// Restart on the next day with the persisted state
+	saved := reader.State()
+	appendLines(t, filepath.Join(dir, "u_ex251015.log"), "day2", 4, 6)
+	appendLines(t, filepath.Join(dir, "u_ex251016.log"), "day3", 0, 3)
+
+	restarted := newTestReader(t, pattern, saved)
+	got = append(got, readAll(t, restarted, 2)...)
+
+	expected := append(lineNames("day1", 0, 8), lineNames("day2", 0, 6)...)
+	expected = append(expected, lineNames("day3", 0, 3)...)
+	expectExactlyOnce(t, got, expected)
+}
+
+func TestReader_KubeletPodLogs(t *testing.T) {
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "*.log")
+	current := filepath.Join(dir, "0.log")
+	appendLines(t, current, "first", 0, 6)
+
+	reader := newTestReader(t, pattern, ReadState{})
+	got := readAll(t, reader, 4)
+
+	// kubelet rotation: 0.log is renamed with a timestamp suffix and recreated
+	appendLines(t, current, "first", 6, 9)
+	if err := os.Rename(current, current+".20261015-120000"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, current, "second", 0, 3)
+	got = append(got, readAll(t, reader, 4)...)
+
+	// Container restart: the new container writes to <restart count>.log
+	appendLines(t, current, "second", 3, 5)
+	appendLines(t, filepath.Join(dir, "1.log"), "restarted", 0, 4)
+	got = append(got, readAll(t, reader, 4)...)
+
+	expected := append(lineNames("first", 0, 9), lineNames("second", 0, 5)...)
+	expected = append(expected, lineNames("restarted", 0, 4)...)
+	expectExactlyOnce(t, got, expected)
+}
@@ -0,0 +1,95 @@
+package ingestion
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+)
+
+// SourceRecovery reports how a source resumes after an unclean shutdown (crash, kill, power loss)
+// Reading restarts at the last saved position: lines after it that were already stored are skipped as duplicates,
+// the others were lost by the shutdown and are inserted now.
+type SourceRecovery struct {
+	Source            string     `json:"source"`
+	Path              string     `json:"path"`
+	Position          int64      `json:"position"`               // Saved position reading resumes at
+	PositionSavedAt   *time.Time `json:"position_saved_at"`      // When the position was saved, the last known progress
+	FileSize          int64      `json:"file_size"`              // Size of the file at startup
+	ReprocessBytes    int64      `json:"reprocess_bytes"`        // Bytes read again up to the size at startup
+	FileReplaced      bool       `json:"file_replaced"`          // Smaller than the position (rotated or truncated), read from the start
+	DuplicatesSkipped int64      `json:"duplicates_skipped"`     // Already stored lines skipped during the recovery
+	Inserted          int64      `json:"inserted"`               // Lines stored during the recovery
+	DetectedAt        time.Time  `json:"detected_at"`            // When the processor started again
+	CompletedAt       *time.Time `json:"completed_at,omitempty"` // When reading caught up with the file, nil while recovering
+}
+
+// RecoveryStatus lists the sources recovering, or recovered, from an unclean shutdown since the coordinator started
+type RecoveryStatus struct {
+	UncleanShutdown bool             `json:"unclean_shutdown"`
+	Sources         []SourceRecovery `json:"sources"`
+}
+
+// recoveryTracker counts the outcome of the batches read until a recovering processor reaches the end of the file
+type recoveryTracker struct {
+	mu     sync.Mutex
+	report SourceRecovery
+}
+
+// newRecoveryTracker reports where reading a source resumes, compared with the size of its file at startup
+func newRecoveryTracker(source *models.LogSource, now time.Time) *recoveryTracker {
+	report := SourceRecovery{
+		Source:          source.Name,
+		Path:            source.Path,
+		Position:        source.LastPosition,
+		PositionSavedAt: source.LastReadAt,
+		DetectedAt:      now,
+	}
+	if info, err := os.Stat(source.Path); err == nil {
+		report.FileSize = info.Size()
+		if report.FileSize >= source.LastPosition {
+			report.ReprocessBytes = report.FileSize - source.LastPosition
+		} else {
+			report.FileReplaced = true
+			report.ReprocessBytes = report.FileSize
+		}
+	}
+	return &recoveryTracker{report: report}
+}
+
+// record adds a stored batch to the recovery, ignored once it completed
+func (t *recoveryTracker) record(result *repositories.BatchResult) {
+	if t == nil || result == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report.CompletedAt != nil {
+		return
+	}
+	t.report.DuplicatesSkipped += int64(result.Duplicates)
+	t.report.Inserted += int64(result.Inserted)
+}
+
+// complete ends the recovery, false when it already ended
+func (t *recoveryTracker) complete(now time.Time) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.report.CompletedAt != nil {
+		return false
+	}
+	t.report.CompletedAt = &now
+	return true
+}
+
+// snapshot returns a copy of the report
+func (t *recoveryTracker) snapshot() SourceRecovery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report
+}
@@ -0,0 +1,119 @@
+package ingestion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories/fake"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+func TestCoordinator_RecoversFromUncleanShutdown(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	logPath := filepath.Join(t.TempDir(), "access.log")
+	appendLines := func(from, to int) int64 {
+		file, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		for i := from; i < to; i++ {
+			fmt.Fprintf(file, `192.168.1.100 - - [15/May/2025:12:06:%02d +0000] "GET /page/%d HTTP/1.1" 200 1024 "-" "Mozilla/5.0" %d "router" "http://backend:8080" 15ms`+"\n", i, i, i)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+	midPosition := appendLines(0, 2)
+	appendLines(2, 4)
+	sourceRepo := fake.NewLogSources(&models.LogSource{Name: "traefik", Path: logPath, ParserType: "traefik"})
+	httpRepo := fake.NewHTTPRequests(sourceRepo)
+	dedup, err := ParseDedupOptions("always", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCoordinator := func() *Coordinator {
+		return NewCoordinator(sourceRepo, httpRepo, parsers.NewRegistry(nil, nil, log), nil, nil, nil, dedup, nil, nil, nil, nil, nil, log,
+			0, false, BatchSettings{MaxSize: 10, MaxFlush: 50 * time.Millisecond}, 1, 0)
+	}
+	source := func() *models.LogSource {
+		source, err := sourceRepo.FindByName("traefik")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return source
+	}
+	waitFor := func(what string, done func() bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// A clean run stores the 4 lines and clears the running mark when it stops
+	coordinator := newCoordinator()
+	if err := coordinator.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if !source().ProcessorRunning {
+		t.Error("Expected the source marked running")
+	}
+	waitFor("the first lines", func() bool { return source().RecordsInserted == 4 })
+	coordinator.Stop()
+	if source().ProcessorRunning {
+		t.Error("Expected a clean stop to clear the running mark")
+	}
+
+	coordinator = newCoordinator()
+	if err := coordinator.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if status := coordinator.GetStatus(); status.Recovery.UncleanShutdown || len(status.Recovery.Sources) != 0 {
+		t.Errorf("Expected no recovery after a clean stop, got %+v", status.Recovery)
+	}
+	coordinator.Stop()
+
+	// A crash leaves the mark with a position saved before the last 2 stored lines, 2 lines arrive meanwhile
+	if err := sourceRepo.UpdateTracking("traefik", midPosition, source().LastInode, "", source().FirstLineFingerprint); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.SetProcessorRunning("traefik", true); err != nil {
+		t.Fatal(err)
+	}
+	size := appendLines(4, 6)
+
+	coordinator = newCoordinator()
+	if err := coordinator.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer coordinator.Stop()
+	var recovery SourceRecovery
+	waitFor("the recovery", func() bool {
+		status := coordinator.GetStatus()
+		if len(status.Recovery.Sources) != 1 {
+			return false
+		}
+		recovery = status.Recovery.Sources[0]
+		return recovery.CompletedAt != nil
+	})
+	if recovery.Position != midPosition || recovery.FileSize != size || recovery.ReprocessBytes != size-midPosition || recovery.FileReplaced {
+		t.Errorf("Expected re-processing from %d to %d, got %+v", midPosition, size, recovery)
+	}
+	if recovery.DuplicatesSkipped != 2 || recovery.Inserted != 2 {
+		t.Errorf("Expected 2 duplicates skipped and 2 lines inserted, got %+v", recovery)
+	}
+	if !coordinator.GetStatus().Recovery.UncleanShutdown {
+		t.Error("Expected the unclean shutdown reported")
+	}
+}
@@ -0,0 +1,239 @@
+package ingestion
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// fingerprintSize is how much of the first line is hashed to identify a file's content
+	fingerprintSize = 1024
+	// tailWindow is how many bytes before a position are read for the continuity check
+	tailWindow = 514
+)
+
+// compressedSuffixes are rotated files that can't be drained (logrotate compress)
+var compressedSuffixes = []string{".gz", ".bz2", ".xz", ".zst", ".zip"}
+
+// fileFingerprint hashes the first line of a file (up to fingerprintSize bytes)
+// Returns "" while the first line is still incomplete
+func fileFingerprint(file *os.File) string {
+	buf := make([]byte, fingerprintSize)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+	buf = buf[:n]
+
+	if i := strings.IndexByte(string(buf), '\n'); i >= 0 {
+		buf = buf[:i]
+	} else if n < fingerprintSize {
+		return ""
+	}
+
+	sum := sha256.Sum256(buf)
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// lineTailBefore returns the tail of the last line ending before position,
+// and whether position is at a line boundary (preceded by a newline)
+func lineTailBefore(file *os.File, position int64) (string, bool, error) {
+	if position <= 0 {
+		return "", true, nil
+	}
+
+	n := int64(tailWindow)
+	if n > position {
+		n = position
+	}
+
+	buf := make([]byte, n)
+	read, err := file.ReadAt(buf, position-n)
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	if int64(read) < n {
+		// Position is past the end of the file
+		return "", false, nil
+	}
+
+	atBoundary := buf[n-1] == '\n'
+
+	s := strings.TrimRight(string(buf), " \t\n\r")
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+
+	return getTail(s, 500), atBoundary, nil
+}
+
+// nextLineStart returns the offset right after the next newline at or after position
+func nextLineStart(file *os.File, position int64) (int64, bool, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := file.ReadAt(buf, position)
+		if i := strings.IndexByte(string(buf[:n]), '\n'); i >= 0 {
+			return position + int64(i) + 1, true, nil
+		}
+		position += int64(n)
+
+		if err == io.EOF {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+}
+
+// rotationCandidates lists the files next to path that may be its rotated copy
+// (e.g. access.log.1 or access.log-20250101). Compressed files are ignored.
+func rotationCandidates(path string) []string {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	candidates := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == base || !strings.HasPrefix(name, base) || !entry.Type().IsRegular() || isCompressed(name) {
+			continue
+		}
+		candidates = append(candidates, filepath.Join(dir, name))
+	}
+	return candidates
+}
+
+// findRotatedFile returns the candidate matching either the inode the file had before it was renamed,
+// or the first-line fingerprint of a copytruncate copy that is at least minSize bytes long
+func findRotatedFile(candidates []string, inode int64, fingerprint string, minSize int64) string {
+	if inode == 0 && fingerprint == "" {
+		return ""
+	}
+
+	for _, candidate := range candidates {
+		if matchesRotatedFile(candidate, inode, fingerprint, minSize) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// matchesRotatedFile checks a candidate against the rotated file's inode or fingerprint
+func matchesRotatedFile(candidate string, inode int64, fingerprint string, minSize int64) bool {
+	file, err := os.Open(candidate)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if inode != 0 {
+		if candidateInode, err := getFileInode(file); err == nil && candidateInode == inode {
+			return true
+		}
+	}
+
+	if fingerprint != "" {
+		stat, err := file.Stat()
+		if err != nil || stat.Size() < minSize {
+			return false
+		}
+		return fileFingerprint(file) == fingerprint
+	}
+
+	return false
+}
+
+// isGlobPattern reports whether a source path is a pattern matching a series of files
+// (e.g. IIS daily logs: C:\inetpub\logs\LogFiles\W3SVC1\u_ex*.log)
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// newestMatch returns the file matching pattern with the greatest name
+// Names are compared rather than modification times: date-stamped names (u_ex251015.log) and
+// container restart counters (9.log, 10.log) sort chronologically, while the previous file
+// may still be written to right after the switch
+func newestMatch(pattern string) string {
+	matches := globFiles(pattern)
+	if len(matches) == 0 {
+		return ""
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return naturalLess(matches[i], matches[j])
+	})
+	return matches[len(matches)-1]
+}
+
+// naturalLess compares names with digit runs compared by numeric value ("9.log" < "10.log")
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+		if aDigits != "" && bDigits != "" {
+			aNum := strings.TrimLeft(aDigits, "0")
+			bNum := strings.TrimLeft(bDigits, "0")
+			if len(aNum) != len(bNum) {
+				return len(aNum) < len(bNum)
+			}
+			if aNum != bNum {
+				return aNum < bNum
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// leadingDigits returns the run of ASCII digits at the start of s
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// globFiles returns the regular, uncompressed files matching pattern
+func globFiles(pattern string) []string {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil
+	}
+
+	files := []string{}
+	for _, match := range matches {
+		if isCompressed(match) {
+			continue
+		}
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+			files = append(files, match)
+		}
+	}
+	return files
+}
+
+// isCompressed reports whether a rotated file name has a compression suffix
+func isCompressed(name string) bool {
+	for _, suffix := range compressedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
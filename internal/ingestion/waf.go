@@ -0,0 +1,253 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/parser/waf"
+
+	"github.com/pterm/pterm"
+	"gorm.io/gorm"
+)
+
+const (
+	// wafPollInterval is how often WAF audit logs are checked for new lines
+	wafPollInterval = time.Second
+	// wafBatchSize is the number of audit log lines read and stored at once
+	wafBatchSize = 500
+	// maxWAFRuleIDs bounds the rule IDs stored per event
+	maxWAFRuleIDs = 10
+)
+
+// WAFIngester reads WAF audit logs (ModSecurity, Coraza) into waf_events
+// Each file is a log source with the "waf" parser type, so positions survive restarts like request
+// sources, but the coordinator leaves these sources alone.
+type WAFIngester struct {
+	paths         []string
+	sourceRepo    repositories.LogSourceRepository
+	wafRepo       repositories.WAFEventRepository
+	parser        *waf.Parser
+	maxLineLength int
+	logger        *pterm.Logger
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+// NewWAFIngester creates an ingester for the WAF audit log files
+func NewWAFIngester(paths []string, sourceRepo repositories.LogSourceRepository, wafRepo repositories.WAFEventRepository, maxLineLength int, logger *pterm.Logger) *WAFIngester {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WAFIngester{
+		paths:         paths,
+		sourceRepo:    sourceRepo,
+		wafRepo:       wafRepo,
+		parser:        waf.NewParser(logger),
+		maxLineLength: maxLineLength,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// ParseWAFLogPaths parses a comma-separated list of WAF audit log files
+func ParseWAFLogPaths(list string) []string {
+	var paths []string
+	for _, path := range strings.Split(list, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// WAFSourceName returns the log source name of a WAF audit log file, e.g. "waf-audit" for /var/log/coraza/audit.log
+func WAFSourceName(path string) string {
+	base := filepath.Base(path)
+	return "waf-" + strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// Start registers a log source per file and starts reading them, nothing is read if a file can't be registered
+func (w *WAFIngester) Start() error {
+	names := make(map[string]bool, len(w.paths))
+	for _, path := range w.paths {
+		name := WAFSourceName(path)
+		if names[name] {
+			return fmt.Errorf("WAF audit logs %s share the source name %s, use different file names", path, name)
+		}
+		names[name] = true
+
+		if err := w.registerSource(name, path); err != nil {
+			return fmt.Errorf("failed to register WAF source %s: %w", name, err)
+		}
+	}
+
+	for name := range names {
+		w.wg.Add(1)
+		go w.readLoop(name)
+	}
+
+	w.logger.Info("Started WAF audit log ingestion", w.logger.Args("sources", len(w.paths)))
+	return nil
+}
+
+// Stop stops reading, positions of stored events are already saved
+func (w *WAFIngester) Stop() {
+	w.cancel()
+	w.wg.Wait()
+	w.logger.Info("Stopped WAF audit log ingestion")
+}
+
+// registerSource creates the log source of a file, or moves an existing one to a new path
+func (w *WAFIngester) registerSource(name string, path string) error {
+	source, err := w.sourceRepo.FindByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.logger.Info("Registered new log source.", w.logger.Args("Name", name, "Path", path))
+		return w.sourceRepo.Create(&models.LogSource{Name: name, Path: path, ParserType: waf.ParserType})
+	}
+	if err != nil {
+		return err
+	}
+	if source.Path == path && source.ParserType == waf.ParserType {
+		return nil
+	}
+
+	w.logger.Info("WAF audit log path changed, reading from the start", w.logger.Args("source", name, "path", path))
+	source.Path = path
+	source.ParserType = waf.ParserType
+	source.LastPosition = 0
+	source.LastInode = 0
+	source.LastLineContent = ""
+	source.FirstLineFingerprint = ""
+	return w.sourceRepo.Update(source)
+}
+
+// readLoop polls one audit log
+// The reader resumes from the saved position of the source.
+func (w *WAFIngester) readLoop(name string) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(wafPollInterval)
+	defer ticker.Stop()
+
+	var reader *IncrementalReader
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if reader == nil {
+			source, err := w.sourceRepo.FindByName(name)
+			if err != nil {
+				w.logger.WithCaller().Error("Failed to load WAF source", w.logger.Args("source", name, "error", err))
+				continue
+			}
+			reader = NewIncrementalReader(source.Path, ReadState{
+				Position:    source.LastPosition,
+				Inode:       source.LastInode,
+				LastLine:    source.LastLineContent,
+				Fingerprint: source.FirstLineFingerprint,
+			}, w.maxLineLength, w.logger)
+		}
+
+		// Drain the backlog before waiting for the next tick
+		for w.ctx.Err() == nil {
+			lines, state, err := reader.ReadBatch(wafBatchSize)
+			if err != nil {
+				w.logger.WithCaller().Error("Failed to read WAF audit log", w.logger.Args("source", name, "error", err))
+				break
+			}
+			if len(lines) == 0 {
+				break
+			}
+
+			events := w.parseLines(name, lines)
+			inserted, err := w.wafRepo.CreateBatchWithPosition(events, &repositories.SourcePosition{
+				SourceName:  name,
+				Position:    state.Position,
+				Inode:       state.Inode,
+				LastLine:    state.LastLine,
+				Fingerprint: state.Fingerprint,
+			})
+			if err != nil {
+				// Re-read the lines from the saved position on the next tick
+				w.logger.WithCaller().Error("Failed to store WAF events", w.logger.Args("source", name, "count", len(events), "error", err))
+				reader = nil
+				break
+			}
+			w.logger.Debug("WAF events stored", w.logger.Args("source", name, "lines", len(lines), "inserted", inserted))
+		}
+	}
+}
+
+// parseLines converts audit log lines to events, skipping transactions without matched rules
+func (w *WAFIngester) parseLines(name string, lines []LogLine) []*models.WAFEvent {
+	events := make([]*models.WAFEvent, 0, len(lines))
+	for _, line := range lines {
+		if !w.parser.CanParse(line.Content) {
+			continue
+		}
+		event, err := w.parser.Parse(line.Content)
+		if errors.Is(err, waf.ErrNoRules) {
+			continue
+		}
+		if err != nil {
+			w.logger.Warn("Failed to parse WAF audit log line",
+				w.logger.Args("source", name, "error", err, "line_preview", truncate(line.Content, 100)))
+			continue
+		}
+		events = append(events, newWAFEvent(name, event, line.Offset))
+	}
+	return events
+}
+
+// newWAFEvent converts a parsed transaction to its database model
+// The hash uses the transaction ID when logged, the line offset otherwise (same second, same client)
+func newWAFEvent(name string, event *waf.Event, offset int64) *models.WAFEvent {
+	action := models.WAFActionDetected
+	if event.Blocked {
+		action = models.WAFActionBlocked
+	}
+	ruleIDs := event.RuleIDs
+	if len(ruleIDs) > maxWAFRuleIDs {
+		ruleIDs = ruleIDs[:maxWAFRuleIDs]
+	}
+
+	identity := event.TransactionID
+	if identity == "" {
+		identity = fmt.Sprintf("@%d", offset)
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%s", name, event.Timestamp.UnixNano(), event.ClientIP, identity)))
+
+	return &models.WAFEvent{
+		SourceName: name,
+		Timestamp:  event.Timestamp,
+		EventHash:  fmt.Sprintf("%x", hash),
+		ClientIP:   event.ClientIP,
+		Method:     truncateField(event.Method, 10),
+		Host:       truncateField(event.Host, 255),
+		Path:       truncateField(event.Path, 2048),
+		StatusCode: event.StatusCode,
+		Action:     action,
+		RuleIDs:    truncateField(strings.Join(ruleIDs, ","), 255),
+		Message:    truncateField(event.Message, 512),
+		Severity:   event.Severity,
+	}
+}
+
+// truncateField cuts a value to the size of its column
+func truncateField(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	return value[:size]
+}
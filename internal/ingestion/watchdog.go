@@ -0,0 +1,92 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"loglynx/internal/heartbeat"
+
+	"github.com/pterm/pterm"
+)
+
+// Watchdog pings a healthcheck URL while ingestion makes progress
+// No ping is sent while ingestion is stopped (including VACUUM pauses) or a source is stalled,
+// so the monitor alerts when LogLynx stops processing logs for longer than its grace period.
+type Watchdog struct {
+	coordinator    *Coordinator
+	pinger         *heartbeat.Pinger
+	interval       time.Duration
+	stallThreshold time.Duration
+	logger         *pterm.Logger
+	stopChan       chan struct{}
+	running        bool
+}
+
+// NewWatchdog creates a watchdog checking ingestion every interval
+func NewWatchdog(coordinator *Coordinator, pinger *heartbeat.Pinger, interval time.Duration, stallThreshold time.Duration, logger *pterm.Logger) *Watchdog {
+	return &Watchdog{
+		coordinator:    coordinator,
+		pinger:         pinger,
+		interval:       interval,
+		stallThreshold: stallThreshold,
+		logger:         logger,
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start checks ingestion and pings in the background
+func (w *Watchdog) Start() {
+	if w.running || w.interval <= 0 {
+		return
+	}
+	w.running = true
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case <-ticker.C:
+			}
+
+			if err := w.Check(); err != nil {
+				w.logger.Warn("Ingestion watchdog: not pinging the healthcheck URL", w.logger.Args("reason", err))
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+			w.pinger.Ping(ctx)
+			cancel()
+		}
+	}()
+
+	w.logger.Info("Ingestion watchdog enabled", w.logger.Args("interval", w.interval))
+}
+
+// Stop stops the watchdog
+func (w *Watchdog) Stop() {
+	if !w.running {
+		return
+	}
+	close(w.stopChan)
+	w.running = false
+}
+
+// Check returns why ingestion isn't healthy, nil when it is
+func (w *Watchdog) Check() error {
+	status := w.coordinator.GetStatus()
+	if !status.IsRunning {
+		return fmt.Errorf("ingestion is not running")
+	}
+
+	now := time.Now()
+	for _, processor := range status.Processors {
+		if processor.Stalled(now, w.stallThreshold) {
+			return fmt.Errorf("source %s stalled for more than %s", processor.Source, w.stallThreshold)
+		}
+	}
+	return nil
+}
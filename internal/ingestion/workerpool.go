@@ -0,0 +1,217 @@
+package ingestion
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// DefaultSourceQueueLimit is the number of pending jobs a single source may queue before Submit blocks
+const DefaultSourceQueueLimit = 64
+
+// WorkerPool is a bounded parsing/enrichment pool shared by all source processors
+// Jobs are queued per source and picked round-robin, so a busy source can't starve quiet ones.
+// Submit blocks once a source has too many pending jobs, pushing back on its read loop.
+type WorkerPool struct {
+	size       int
+	queueLimit int
+	logger     *pterm.Logger
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queues   map[string]*sourceQueue
+	order    []string // Round-robin order of sources
+	next     int      // Index in order where the next pick starts
+	pending  int      // Jobs queued across all sources
+	busy     int      // Workers currently running a job
+	stopped  bool
+	wg       sync.WaitGroup
+}
+
+// sourceQueue holds pending jobs and wait statistics of one source
+type sourceQueue struct {
+	jobs      []poolJob
+	completed int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+type poolJob struct {
+	fn         func()
+	enqueuedAt time.Time
+}
+
+// WorkerPoolStats is a snapshot of the shared worker pool
+type WorkerPoolStats struct {
+	Workers     int                `json:"workers"`
+	BusyWorkers int                `json:"busy_workers"`
+	QueuedJobs  int                `json:"queued_jobs"`
+	Sources     []SourceQueueStats `json:"sources"`
+}
+
+// SourceQueueStats holds queue wait metrics for one source
+type SourceQueueStats struct {
+	Source        string  `json:"source"`
+	QueuedJobs    int     `json:"queued_jobs"`
+	CompletedJobs int64   `json:"completed_jobs"`
+	AvgWaitMs     float64 `json:"avg_wait_ms"` // Average time a job waited for a worker
+	MaxWaitMs     float64 `json:"max_wait_ms"`
+}
+
+// NewWorkerPool creates a shared worker pool with the given number of workers
+func NewWorkerPool(size int, logger *pterm.Logger) *WorkerPool {
+	if size <= 0 {
+		size = 4
+	}
+
+	p := &WorkerPool{
+		size:       size,
+		queueLimit: DefaultSourceQueueLimit,
+		logger:     logger,
+		queues:     make(map[string]*sourceQueue),
+	}
+	p.notEmpty = sync.NewCond(&p.mu)
+	p.notFull = sync.NewCond(&p.mu)
+	return p
+}
+
+// Start launches the workers
+func (p *WorkerPool) Start() {
+	for i := 0; i < p.size; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	p.logger.Debug("Started shared worker pool", p.logger.Args("workers", p.size))
+}
+
+// Stop waits for queued jobs to finish and stops the workers
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	p.logger.Debug("Stopped shared worker pool")
+}
+
+// Submit queues a job for a source, blocking while the source's queue is full
+// Returns false if the pool is stopped (the job is not run)
+func (p *WorkerPool) Submit(source string, fn func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue := p.queueFor(source)
+	for len(queue.jobs) >= p.queueLimit && !p.stopped {
+		p.notFull.Wait()
+	}
+	if p.stopped {
+		return false
+	}
+
+	queue.jobs = append(queue.jobs, poolJob{fn: fn, enqueuedAt: time.Now()})
+	p.pending++
+	p.notEmpty.Signal()
+	return true
+}
+
+// queueFor returns the queue of a source, creating it if needed (caller holds mu)
+func (p *WorkerPool) queueFor(source string) *sourceQueue {
+	queue, exists := p.queues[source]
+	if !exists {
+		queue = &sourceQueue{}
+		p.queues[source] = queue
+		p.order = append(p.order, source)
+	}
+	return queue
+}
+
+// worker runs jobs until the pool is stopped and drained
+func (p *WorkerPool) worker() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		for p.pending == 0 && !p.stopped {
+			p.notEmpty.Wait()
+		}
+		if p.pending == 0 && p.stopped {
+			p.mu.Unlock()
+			return
+		}
+
+		job, queue := p.nextJobLocked()
+		wait := time.Since(job.enqueuedAt)
+		queue.completed++
+		queue.totalWait += wait
+		if wait > queue.maxWait {
+			queue.maxWait = wait
+		}
+		p.busy++
+		p.notFull.Broadcast()
+		p.mu.Unlock()
+
+		job.fn()
+
+		p.mu.Lock()
+		p.busy--
+		p.mu.Unlock()
+	}
+}
+
+// nextJobLocked pops the next job, rotating over sources with pending work (caller holds mu)
+func (p *WorkerPool) nextJobLocked() (poolJob, *sourceQueue) {
+	for i := 0; i < len(p.order); i++ {
+		idx := (p.next + i) % len(p.order)
+		queue := p.queues[p.order[idx]]
+		if len(queue.jobs) == 0 {
+			continue
+		}
+
+		job := queue.jobs[0]
+		queue.jobs[0] = poolJob{}
+		queue.jobs = queue.jobs[1:]
+		p.pending--
+		p.next = idx + 1
+		return job, queue
+	}
+
+	// Unreachable while pending > 0
+	return poolJob{fn: func() {}}, &sourceQueue{}
+}
+
+// Stats returns a snapshot of worker usage and per-source queue wait times
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := WorkerPoolStats{
+		Workers:     p.size,
+		BusyWorkers: p.busy,
+		QueuedJobs:  p.pending,
+		Sources:     make([]SourceQueueStats, 0, len(p.queues)),
+	}
+
+	for name, queue := range p.queues {
+		sourceStats := SourceQueueStats{
+			Source:        name,
+			QueuedJobs:    len(queue.jobs),
+			CompletedJobs: queue.completed,
+			MaxWaitMs:     float64(queue.maxWait.Microseconds()) / 1000,
+		}
+		if queue.completed > 0 {
+			sourceStats.AvgWaitMs = float64(queue.totalWait.Microseconds()) / 1000 / float64(queue.completed)
+		}
+		stats.Sources = append(stats.Sources, sourceStats)
+	}
+
+	sort.Slice(stats.Sources, func(i, j int) bool {
+		return stats.Sources[i].Source < stats.Sources[j].Source
+	})
+
+	return stats
+}
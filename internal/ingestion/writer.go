@@ -0,0 +1,240 @@
+package ingestion
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"github.com/pterm/pterm"
+)
+
+// DefaultWriterMaxRecords is the number of requests above which the writer stops merging batches into a commit
+const DefaultWriterMaxRecords = 5000
+
+// errWriterStopped is returned to batches submitted after the writer stopped
+var errWriterStopped = fmt.Errorf("batch writer stopped: %w", repositories.ErrWriteAborted)
+
+// BatchWriter commits the batches of all source processors from a single goroutine
+// SQLite has a single writer: instead of processors contending for the write lock (and waiting on busy timeouts),
+// batches queued while a commit runs are merged into the next transaction, together with their source positions.
+type BatchWriter struct {
+	repo       repositories.HTTPRequestRepository
+	maxRecords int
+	logger     *pterm.Logger
+
+	queue    chan *writeRequest
+	stopChan chan struct{}
+	exited   chan struct{} // Closed once the queue is drained after Stop
+	wg       sync.WaitGroup
+
+	statsMu        sync.Mutex
+	commits        int64
+	batches        int64
+	records        int64
+	fallbacks      int64 // Merged commits that failed and were retried batch by batch
+	totalCommit    time.Duration
+	maxCommit      time.Duration
+	maxMergedBatch int
+}
+
+// writeRequest is a batch waiting for the writer, done receives its outcome
+type writeRequest struct {
+	batch *repositories.SourceBatch
+	done  chan writeResult
+}
+
+type writeResult struct {
+	result *repositories.BatchResult
+	err    error
+}
+
+// BatchWriterStats is a snapshot of the central writer
+type BatchWriterStats struct {
+	Commits          int64   `json:"commits"`
+	Batches          int64   `json:"batches"`
+	Records          int64   `json:"records"`
+	BatchesPerCommit float64 `json:"batches_per_commit"` // Average number of source batches merged per transaction
+	MaxMergedBatches int     `json:"max_merged_batches"`
+	QueuedBatches    int     `json:"queued_batches"`
+	AvgCommitMs      float64 `json:"avg_commit_ms"`
+	MaxCommitMs      float64 `json:"max_commit_ms"`
+	Fallbacks        int64   `json:"fallbacks"`
+}
+
+// NewBatchWriter creates a writer merging batches up to maxRecords requests per transaction
+func NewBatchWriter(repo repositories.HTTPRequestRepository, maxRecords int, logger *pterm.Logger) *BatchWriter {
+	if maxRecords <= 0 {
+		maxRecords = DefaultWriterMaxRecords
+	}
+
+	return &BatchWriter{
+		repo:       repo,
+		maxRecords: maxRecords,
+		logger:     logger,
+		queue:      make(chan *writeRequest, 256),
+		stopChan:   make(chan struct{}),
+		exited:     make(chan struct{}),
+	}
+}
+
+// Start launches the writer goroutine
+func (w *BatchWriter) Start() {
+	w.wg.Add(1)
+	go w.run()
+	w.logger.Debug("Started batch writer", w.logger.Args("max_records_per_commit", w.maxRecords))
+}
+
+// Stop commits the batches already queued and stops the writer
+// Processors must be stopped first, batches submitted afterwards fail.
+func (w *BatchWriter) Stop() {
+	close(w.stopChan)
+	w.wg.Wait()
+	w.logger.Debug("Stopped batch writer")
+}
+
+// Write queues a batch and waits until it is committed (or failed)
+func (w *BatchWriter) Write(requests []*models.HTTPRequest, position *repositories.SourcePosition) (*repositories.BatchResult, error) {
+	req := &writeRequest{
+		batch: &repositories.SourceBatch{Requests: requests, Position: position},
+		done:  make(chan writeResult, 1),
+	}
+
+	select {
+	case w.queue <- req:
+	case <-w.stopChan:
+		return nil, errWriterStopped
+	}
+
+	select {
+	case res := <-req.done:
+		return res.result, res.err
+	case <-w.exited:
+		// Queued after the writer drained its queue
+		select {
+		case res := <-req.done:
+			return res.result, res.err
+		default:
+			return nil, errWriterStopped
+		}
+	}
+}
+
+// run commits queued batches until stopped, then drains the queue
+func (w *BatchWriter) run() {
+	defer w.wg.Done()
+	defer close(w.exited)
+
+	for {
+		select {
+		case req := <-w.queue:
+			w.commit(w.collect(req))
+		case <-w.stopChan:
+			for {
+				select {
+				case req := <-w.queue:
+					w.commit(w.collect(req))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect merges the batches already queued behind the first one, up to maxRecords requests
+func (w *BatchWriter) collect(first *writeRequest) []*writeRequest {
+	pending := []*writeRequest{first}
+	records := len(first.batch.Requests)
+
+	for records < w.maxRecords {
+		select {
+		case req := <-w.queue:
+			pending = append(pending, req)
+			records += len(req.batch.Requests)
+		default:
+			return pending
+		}
+	}
+	return pending
+}
+
+// commit writes merged batches in one transaction
+// If it fails, batches are retried one by one so a single bad batch doesn't fail the others.
+func (w *BatchWriter) commit(pending []*writeRequest) {
+	batches := make([]*repositories.SourceBatch, len(pending))
+	records := 0
+	for i, req := range pending {
+		batches[i] = req.batch
+		records += len(req.batch.Requests)
+	}
+
+	started := time.Now()
+	results, err := w.repo.CreateSourceBatches(batches)
+	w.recordCommit(len(pending), records, time.Since(started), err != nil && len(pending) > 1)
+
+	if err == nil {
+		for i, req := range pending {
+			req.done <- writeResult{result: results[i]}
+		}
+		return
+	}
+
+	if len(pending) == 1 {
+		pending[0].done <- writeResult{err: err}
+		return
+	}
+
+	w.logger.Warn("Merged commit failed, retrying batches separately",
+		w.logger.Args("batches", len(pending), "records", records, "error", err))
+	for _, req := range pending {
+		results, err := w.repo.CreateSourceBatches([]*repositories.SourceBatch{req.batch})
+		if err != nil {
+			req.done <- writeResult{err: err}
+			continue
+		}
+		req.done <- writeResult{result: results[0]}
+	}
+}
+
+func (w *BatchWriter) recordCommit(batches int, records int, duration time.Duration, fallback bool) {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	w.commits++
+	w.batches += int64(batches)
+	w.records += int64(records)
+	w.totalCommit += duration
+	if duration > w.maxCommit {
+		w.maxCommit = duration
+	}
+	if batches > w.maxMergedBatch {
+		w.maxMergedBatch = batches
+	}
+	if fallback {
+		w.fallbacks++
+	}
+}
+
+// Stats returns a snapshot of the writer activity since start
+func (w *BatchWriter) Stats() BatchWriterStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	stats := BatchWriterStats{
+		Commits:          w.commits,
+		Batches:          w.batches,
+		Records:          w.records,
+		MaxMergedBatches: w.maxMergedBatch,
+		QueuedBatches:    len(w.queue),
+		MaxCommitMs:      float64(w.maxCommit.Microseconds()) / 1000,
+		Fallbacks:        w.fallbacks,
+	}
+	if w.commits > 0 {
+		stats.BatchesPerCommit = float64(w.batches) / float64(w.commits)
+		stats.AvgCommitMs = float64(w.totalCommit.Microseconds()) / 1000 / float64(w.commits)
+	}
+	return stats
+}
@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestBatchWriter_MergesSources(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	db := fake.NewDB(t)
+
+	const sources, batches, batchSize = 8, 20, 30
+	for s := 0; s < sources; s++ {
+		if err := db.Create(&models.LogSource{Name: fmt.Sprint("source-", s), Path: "/dev/null", ParserType: "traefik"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := NewBatchWriter(repositories.NewHTTPRequestRepository(db, log, ""), 0, log)
+	writer.Start()
+
+	var wg sync.WaitGroup
+	for s := 0; s < sources; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			name := fmt.Sprint("source-", s)
+			for b := 0; b < batches; b++ {
+				requests := make([]*models.HTTPRequest, 0, batchSize)
+				for i := 0; i < batchSize; i++ {
+					requests = append(requests, &models.HTTPRequest{
+						SourceName: name, Timestamp: time.Now(), RequestHash: fmt.Sprint(name, "-", b, "-", i),
+						ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/",
+					})
+				}
+				result, err := writer.Write(requests, &repositories.SourcePosition{SourceName: name, Position: int64(b + 1)})
+				if err != nil || result.Inserted != batchSize {
+					t.Errorf("%s batch %d: expected %d inserted, got %+v, %v", name, b, batchSize, result, err)
+					return
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	writer.Stop()
+
+	var count int64
+	db.Model(&models.HTTPRequest{}).Count(&count)
+	if count != sources*batches*batchSize {
+		t.Errorf("Expected %d requests, got %d", sources*batches*batchSize, count)
+	}
+
+	var positions []int64
+	db.Model(&models.LogSource{}).Pluck("last_position", &positions)
+	for _, position := range positions {
+		if position != batches {
+			t.Errorf("Expected every source at position %d, got %v", batches, positions)
+			break
+		}
+	}
+
+	stats := writer.Stats()
+	if stats.Batches != sources*batches || stats.Commits > stats.Batches {
+		t.Errorf("Unexpected writer stats %+v", stats)
+	}
+
+	if _, err := writer.Write(nil, nil); err == nil {
+		t.Error("Expected an error writing to a stopped writer")
+	}
+}
@@ -0,0 +1,62 @@
+package apache
+
+import (
+	"time"
+)
+
+// HTTPRequestEvent represents a request from an Apache httpd access log
+// Field names match models.HTTPRequest so the processor maps them directly
+type HTTPRequestEvent struct {
+	Timestamp  time.Time
+	SourceName string
+
+	// Client info
+	ClientIP   string
+	ClientUser string
+
+	// Request info
+	Method        string
+	Protocol      string
+	Host          string // %v or %V, the virtual host
+	Path          string
+	QueryString   string
+	RequestLength int64
+	RequestScheme string
+	RequestID     string
+
+	// Response info
+	StatusCode     int
+	ResponseSize   int64
+	ResponseTimeMs float64
+	Duration       int64 // Nanoseconds, from %D or %T
+
+	// Headers
+	UserAgent string
+	Referer   string
+
+	peerIP       string // %a or %h, the proxy when requests are forwarded
+	forwardedFor string // %{X-Forwarded-For}i, when logged
+}
+
+func (e *HTTPRequestEvent) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
+func (e *HTTPRequestEvent) GetSourceName() string {
+	return e.SourceName
+}
+
+// ForwardedFor returns the connection peer and the logged X-Forwarded-For chain
+func (e *HTTPRequestEvent) ForwardedFor() (string, string) {
+	return e.peerIP, e.forwardedFor
+}
+
+// SetClientIP replaces the client address with the one resolved from the forwarded chain
+func (e *HTTPRequestEvent) SetClientIP(ip string) {
+	e.ClientIP = ip
+}
+
+// HasPreciseTiming is always false: %t only has second resolution
+func (e *HTTPRequestEvent) HasPreciseTiming() bool {
+	return false
+}
@@ -0,0 +1,269 @@
+package apache
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// ParserType is the parser type of Apache httpd log sources
+const ParserType = "apache"
+
+// Nicknames are the LogFormat directives of the stock Apache configurations
+// Debian writes access.log in combined and other_vhosts_access.log in vhost_combined.
+var Nicknames = map[string]string{
+	"common":         `%h %l %u %t "%r" %>s %b`,
+	"combined":       `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`,
+	"vhost_combined": `%v:%p %h %l %u %t "%r" %>s %O "%{Referer}i" "%{User-Agent}i"`,
+}
+
+// DefaultFormats are tried in order when no LogFormat is configured, the most specific first
+var DefaultFormats = []string{"vhost_combined", "combined", "common"}
+
+// timestampLayout is the layout of %t
+const timestampLayout = "02/Jan/2006:15:04:05 -0700"
+
+// directiveRe matches one LogFormat directive: %% or %[<>][{param}]letter, with optional status code conditions
+var directiveRe = regexp.MustCompile(`^%(?:%|!?[0-9,]*[<>]?(?:\{([^}]*)\})?([a-zA-Z]))`)
+
+// format is a compiled LogFormat directive
+type format struct {
+	regex  *regexp.Regexp
+	fields []string // Directive of each capture group, e.g. "h", "s", "{referer}i"
+}
+
+// Parser implements the LogParser interface for Apache httpd access logs
+// Lines are matched against LogFormat directives (the stock nicknames by default, see DefaultFormats); fields
+// logged after the known ones are ignored, so a format with %D appended to combined still parses without %D.
+type Parser struct {
+	logger  *pterm.Logger
+	formats []*format
+}
+
+// NewParser creates an Apache parser for LogFormat strings or nicknames, DefaultFormats when none are given
+func NewParser(formats []string, logger *pterm.Logger) (*Parser, error) {
+	if len(formats) == 0 {
+		formats = DefaultFormats
+	}
+
+	p := &Parser{logger: logger}
+	for _, source := range formats {
+		if nickname, ok := Nicknames[source]; ok {
+			source = nickname
+		}
+		compiled, err := compileFormat(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LogFormat %q: %w", source, err)
+		}
+		p.formats = append(p.formats, compiled)
+	}
+	return p, nil
+}
+
+// Name returns the parser identifier
+func (p *Parser) Name() string {
+	return ParserType
+}
+
+// CanParse checks if the line matches one of the formats
+func (p *Parser) CanParse(line string) bool {
+	compiled, _ := p.match(line)
+	return compiled != nil
+}
+
+// Parse parses an access log line with the first matching format
+func (p *Parser) Parse(line string) (*HTTPRequestEvent, error) {
+	compiled, values := p.match(line)
+	if compiled == nil {
+		return nil, fmt.Errorf("line doesn't match the Apache LogFormat")
+	}
+
+	event := &HTTPRequestEvent{}
+	var remoteHost, remoteAddr, port string
+	for i, field := range compiled.fields {
+		value := values[i+1]
+		if value == "-" {
+			continue
+		}
+
+		switch field {
+		case "h":
+			remoteHost = value
+		case "a":
+			remoteAddr = value
+		case "u":
+			event.ClientUser = value
+		case "t":
+			timestamp, err := time.Parse(timestampLayout, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp: %w", err)
+			}
+			event.Timestamp = timestamp
+		case "r":
+			setRequestLine(event, value)
+		case "m":
+			event.Method = value
+		case "U":
+			event.Path = value
+		case "q":
+			event.QueryString = strings.TrimPrefix(value, "?")
+		case "H":
+			event.Protocol = value
+		case "s":
+			statusCode, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status code: %w", err)
+			}
+			event.StatusCode = statusCode
+		case "b", "B", "O":
+			event.ResponseSize, _ = strconv.ParseInt(value, 10, 64)
+		case "I":
+			event.RequestLength, _ = strconv.ParseInt(value, 10, 64)
+		case "D", "{us}T":
+			if micros, err := strconv.ParseInt(value, 10, 64); err == nil {
+				event.Duration = micros * int64(time.Microsecond)
+			}
+		case "T", "{s}T", "{ms}T":
+			// Only when %D isn't logged, it's more precise
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && event.Duration == 0 {
+				unit := time.Second
+				if field == "{ms}T" {
+					unit = time.Millisecond
+				}
+				event.Duration = n * int64(unit)
+			}
+		case "v", "V":
+			event.Host = value
+		case "p":
+			port = value
+		case "{referer}i":
+			event.Referer = value
+		case "{user-agent}i":
+			event.UserAgent = value
+		case "{x-forwarded-for}i":
+			event.forwardedFor = value
+		case "{x-request-id}i":
+			event.RequestID = value
+		}
+	}
+
+	// %a is the client address, %h its hostname with HostnameLookups on
+	event.ClientIP = remoteAddr
+	if event.ClientIP == "" {
+		event.ClientIP = remoteHost
+	}
+	event.peerIP = event.ClientIP
+
+	// Behind a proxy or load balancer, the peer is the proxy; prefer the forwarded client address
+	// With trusted proxies configured, ingestion resolves the client from the whole chain instead.
+	if event.forwardedFor != "" {
+		event.ClientIP = strings.TrimSpace(strings.Split(event.forwardedFor, ",")[0])
+	}
+
+	event.ResponseTimeMs = float64(event.Duration) / float64(time.Millisecond)
+	switch port {
+	case "":
+	case "443":
+		event.RequestScheme = "https"
+	default:
+		event.RequestScheme = "http"
+	}
+
+	p.logger.Trace("Parsed Apache log line",
+		p.logger.Args("method", event.Method, "path", event.Path, "status", event.StatusCode))
+
+	return event, nil
+}
+
+// match returns the first matching format and the submatches of its fields, nil without match
+func (p *Parser) match(line string) (*format, []string) {
+	for _, compiled := range p.formats {
+		if values := compiled.regex.FindStringSubmatch(line); values != nil {
+			return compiled, values
+		}
+	}
+	return nil, nil
+}
+
+// compileFormat turns a LogFormat string into a regular expression with one group per directive
+// Quoted directives may contain spaces and escaped quotes, the others are single tokens.
+func compileFormat(source string) (*format, error) {
+	compiled := &format{}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	for i := 0; i < len(source); {
+		if source[i] != '%' {
+			pattern.WriteString(regexp.QuoteMeta(source[i : i+1]))
+			i++
+			continue
+		}
+
+		directive := directiveRe.FindStringSubmatch(source[i:])
+		if directive == nil {
+			return nil, fmt.Errorf("unsupported directive at %q", source[i:])
+		}
+		quoted := i > 0 && source[i-1] == '"'
+		i += len(directive[0])
+		if directive[0] == "%%" {
+			pattern.WriteString("%")
+			continue
+		}
+
+		field := directive[2]
+		if directive[1] != "" {
+			if field == "t" {
+				return nil, fmt.Errorf("only the default %%t time format is supported, not %s", directive[0])
+			}
+			field = "{" + strings.ToLower(directive[1]) + "}" + field
+		}
+
+		switch {
+		case field == "t":
+			pattern.WriteString(`\[([^\]]+)\]`)
+		case quoted:
+			pattern.WriteString(`((?:[^"\\]|\\.)*)`)
+		case field == "s":
+			pattern.WriteString(`(\d{3}|-)`)
+		default:
+			pattern.WriteString(`(\S*)`)
+		}
+		compiled.fields = append(compiled.fields, field)
+	}
+
+	for _, required := range []string{"t", "s"} {
+		if !slices.Contains(compiled.fields, required) {
+			return nil, fmt.Errorf("%%%s is required", required)
+		}
+	}
+	if !slices.Contains(compiled.fields, "r") && !slices.Contains(compiled.fields, "U") {
+		return nil, fmt.Errorf("%%r or %%U is required")
+	}
+
+	// Fields appended after the known ones are ignored
+	pattern.WriteString(`(?:\s.*)?$`)
+	regex, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	compiled.regex = regex
+	return compiled, nil
+}
+
+// setRequestLine sets the method, path, query string and protocol from %r (e.g. "GET /a?b=1 HTTP/1.1")
+func setRequestLine(event *HTTPRequestEvent, request string) {
+	request = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(request)
+	parts := strings.SplitN(request, " ", 3)
+	event.Method = parts[0]
+	if len(parts) > 1 {
+		event.Path, event.QueryString, _ = strings.Cut(parts[1], "?")
+	}
+	if len(parts) > 2 {
+		event.Protocol = parts[2]
+	}
+}
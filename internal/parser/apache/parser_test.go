@@ -0,0 +1,112 @@
+package apache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParser_CustomFormat(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser, err := NewParser([]string{`%v %a %l %u %t "%r" %>s %b %I "%{Referer}i" "%{User-agent}i" "%{X-Forwarded-For}i" %D`}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := `shop.example.com 10.0.0.2 - - [15/Oct/2025:08:12:45 +0000] "GET /cart?id=7 HTTP/1.1" 200 2048 512 "-" "Mozilla/5.0" "203.0.113.7, 10.0.0.1" 12345`
+	if !parser.CanParse(line) {
+		t.Fatal("Expected parser to accept the custom format")
+	}
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+
+	if !event.Timestamp.Equal(time.Date(2025, 10, 15, 8, 12, 45, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp %v", event.Timestamp)
+	}
+	if event.Host != "shop.example.com" {
+		t.Errorf("Expected %%v as host, got %s", event.Host)
+	}
+	if event.ResponseTimeMs != 12.345 || event.Duration != int64(12345*time.Microsecond) {
+		t.Errorf("Expected %%D of 12.345ms, got %fms (%dns)", event.ResponseTimeMs, event.Duration)
+	}
+	if event.Path != "/cart" || event.QueryString != "id=7" || event.ResponseSize != 2048 || event.RequestLength != 512 {
+		t.Errorf("Unexpected request fields %+v", event)
+	}
+	if event.UserAgent != "Mozilla/5.0" || event.Referer != "" {
+		t.Errorf("Unexpected headers: user agent %q, referer %q", event.UserAgent, event.Referer)
+	}
+
+	// The forwarded client is preferred, the peer is kept for trusted proxy resolution
+	peer, chain := event.ForwardedFor()
+	if event.ClientIP != "203.0.113.7" || peer != "10.0.0.2" || chain != "203.0.113.7, 10.0.0.1" {
+		t.Errorf("Unexpected client %s, peer %s, chain %s", event.ClientIP, peer, chain)
+	}
+}
+
+func TestParser_Nicknames(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	parser, err := NewParser(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := parser.Parse(`www.example.com:443 203.0.113.7 - - [15/Oct/2025:08:12:45 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.5.0"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Host != "www.example.com" || event.RequestScheme != "https" || event.ClientIP != "203.0.113.7" {
+		t.Errorf("Unexpected vhost_combined event %+v", event)
+	}
+
+	// Fields appended to combined are ignored
+	event, err = parser.Parse(`203.0.113.7 - - [15/Oct/2025:08:12:45 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.5.0" 1532`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Host != "" || event.UserAgent != "curl/8.5.0" || event.ResponseTimeMs != 0 {
+		t.Errorf("Unexpected combined event %+v", event)
+	}
+
+	// %T is used without %D
+	parser, err = NewParser([]string{"common", `%h %l %u %t "%r" %>s %b %{ms}T`}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err = parser.Parse(`203.0.113.7 - - [15/Oct/2025:08:12:45 +0000] "GET / HTTP/1.1" 200 512 87`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ResponseTimeMs != 0 {
+		t.Errorf("Expected common to match first and ignore the time, got %fms", event.ResponseTimeMs)
+	}
+
+	parser, err = NewParser([]string{`%h %l %u %t "%r" %>s %b %{ms}T`}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err = parser.Parse(`203.0.113.7 - - [15/Oct/2025:08:12:45 +0000] "GET / HTTP/1.1" 200 512 87`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ResponseTimeMs != 87 {
+		t.Errorf("Expected %%{ms}T of 87ms, got %fms", event.ResponseTimeMs)
+	}
+}
+
+func TestParser_InvalidFormats(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	for _, format := range []string{
+		`%h %l %u "%r" %>s %b`,              // no time
+		`%h %l %u %t "%r" %b`,               // no status
+		`%h %l %u %t %>s %b`,                // no request
+		`%h %l %u %{%d/%b/%Y}t "%r" %>s %b`, // custom time format
+		`%h %l %u %t "%r" %>s %b %`,         // dangling %
+	} {
+		if _, err := NewParser([]string{format}, logger); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package parsers
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CRIPrefix marks parser types reading container log files written by the kubelet,
+// e.g. "cri+traefik" parses Traefik lines wrapped in the CRI (containerd, CRI-O) or docker json-file format
+const CRIPrefix = "cri+"
+
+// criParserWrapper unwraps container runtime log lines before handing them to the inner parser
+type criParserWrapper struct {
+	inner LogParser
+}
+
+// Name returns the parser identifier
+func (w *criParserWrapper) Name() string {
+	return CRIPrefix + w.inner.Name()
+}
+
+// CanParse checks if the unwrapped message can be parsed by the inner parser
+func (w *criParserWrapper) CanParse(line string) bool {
+	message, ok := unwrapContainerLine(line)
+	return ok && w.inner.CanParse(message)
+}
+
+// Parse unwraps the container log line and parses the message
+func (w *criParserWrapper) Parse(line string) (Event, error) {
+	message, ok := unwrapContainerLine(line)
+	if !ok {
+		return nil, ErrSkipLine
+	}
+	return w.inner.Parse(message)
+}
+
+// NewEvent returns an empty event of the inner parser, or nil if it doesn't expose one
+func (w *criParserWrapper) NewEvent() Event {
+	if prototype, ok := w.inner.(EventPrototype); ok {
+		return prototype.NewEvent()
+	}
+	return nil
+}
+
+// dockerLogLine is a line of the docker json-file log driver
+type dockerLogLine struct {
+	Log string `json:"log"`
+}
+
+// unwrapContainerLine extracts the application message from a container log line
+// CRI format: "<RFC3339Nano timestamp> <stdout|stderr> <F|P> <message>"
+// Partial (P) lines are fragments of a line longer than the runtime buffer (16 KB) and are skipped.
+func unwrapContainerLine(line string) (string, bool) {
+	if strings.HasPrefix(line, `{"log":`) {
+		var entry dockerLogLine
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return "", false
+		}
+		return strings.TrimRight(entry.Log, "\r\n"), true
+	}
+
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 4 || parts[2] != "F" {
+		return "", false
+	}
+	return parts[3], true
+}
+
+// UnwrapContainerLine extracts the application message from a container log line (CRI or docker json-file)
+// Returns false for partial lines and lines in neither format.
+func UnwrapContainerLine(line string) (string, bool) {
+	return unwrapContainerLine(line)
+}
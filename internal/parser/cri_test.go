@@ -0,0 +1,41 @@
+package parsers
+
+import (
+	"errors"
+	"testing"
+
+	"loglynx/internal/parser/traefik"
+
+	"github.com/pterm/pterm"
+)
+
+func TestUnwrapContainerLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+		ok       bool
+	}{
+		{"cri full line", `2026-10-15T08:12:45.123456789Z stdout F 10.0.0.1 - - [15/Oct/2026:08:12:45 +0000] "GET / HTTP/1.1" 200 5`, `10.0.0.1 - - [15/Oct/2026:08:12:45 +0000] "GET / HTTP/1.1" 200 5`, true},
+		{"cri partial line", `2026-10-15T08:12:45.123456789Z stdout P {"ClientHost":`, "", false},
+		{"docker json-file", `{"log":"{\"ClientHost\":\"10.0.0.1\"}\n","stream":"stdout","time":"2026-10-15T08:12:45.123Z"}`, `{"ClientHost":"10.0.0.1"}`, true},
+		{"not a container line", `plain text`, "", false},
+	}
+
+	for _, tt := range tests {
+		message, ok := unwrapContainerLine(tt.line)
+		if ok != tt.ok || message != tt.expected {
+			t.Errorf("%s: expected (%q, %v), got (%q, %v)", tt.name, tt.expected, tt.ok, message, ok)
+		}
+	}
+}
+
+func TestCRIParserWrapper_SkipsPartialLines(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	wrapper := &criParserWrapper{inner: &traefikParserWrapper{traefik.NewParser(nil, nil, logger)}}
+
+	_, err := wrapper.Parse(`2026-10-15T08:12:45.123456789Z stdout P {"ClientHost":`)
+	if !errors.Is(err, ErrSkipLine) {
+		t.Errorf("Expected ErrSkipLine for a partial line, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"loglynx/internal/parser/waf"
+
+	"github.com/pterm/pterm"
+)
+
+// go test ./internal/parser -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the parser corpus")
+
+// goldenDir holds one directory per parser type, each with sample logs (*.log) and their expected results
+// (*.golden.json). A file is parsed line by line with one parser, like a log source.
+const goldenDir = "testdata/golden"
+
+// goldenResult is the expected outcome of a sample line
+type goldenResult struct {
+	Line          int             `json:"line"`
+	CanParse      bool            `json:"can_parse"`
+	Skipped       bool            `json:"skipped,omitempty"` // ErrSkipLine (directives, partial container lines)
+	Error         string          `json:"error,omitempty"`
+	PreciseTiming *bool           `json:"precise_timing,omitempty"`
+	Event         json.RawMessage `json:"event,omitempty"`
+}
+
+// wafParserWrapper runs the WAF audit log parser, read by the WAF ingester rather than the registry
+type wafParserWrapper struct {
+	*waf.Parser
+}
+
+func (w *wafParserWrapper) Parse(line string) (Event, error) {
+	event, err := w.Parser.Parse(line)
+	if errors.Is(err, waf.ErrNoRules) {
+		return nil, ErrSkipLine
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// goldenParser returns a new parser of a type, so stateful parsers (IIS #Fields) start fresh for each file
+func goldenParser(t *testing.T, parserType string) LogParser {
+	t.Helper()
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	if parserType == waf.ParserType {
+		return &wafParserWrapper{waf.NewParser(logger)}
+	}
+	parser, err := NewRegistry(nil, nil, logger).Get(parserType)
+	if err != nil {
+		t.Fatalf("No parser for corpus directory %s: %v", parserType, err)
+	}
+	return parser
+}
+
+func TestGolden_EveryParserHasCorpus(t *testing.T) {
+	for name := range NewRegistry(nil, nil, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)).GetAll() {
+		matches, _ := filepath.Glob(filepath.Join(goldenDir, name, "*.log"))
+		if len(matches) == 0 {
+			t.Errorf("Parser %s has no sample logs in %s/%s", name, goldenDir, name)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	// Audit logs without a time zone are parsed in local time
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	dirs, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		samples, err := filepath.Glob(filepath.Join(goldenDir, dir.Name(), "*.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sample := range samples {
+			t.Run(dir.Name()+"/"+filepath.Base(sample), func(t *testing.T) {
+				checkGolden(t, goldenParser(t, dir.Name()), sample)
+			})
+		}
+	}
+}
+
+// checkGolden parses a sample log and compares the results with its golden file
+func checkGolden(t *testing.T, parser LogParser, sample string) {
+	file, err := os.Open(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var results []goldenResult
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		results = append(results, parseGolden(t, parser, number, scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+
+	golden := strings.TrimSuffix(sample, ".log") + ".golden.json"
+	if *updateGolden {
+		if err := os.WriteFile(golden, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Missing golden file, run go test ./internal/parser -run TestGolden -update: %v", err)
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+
+	// Report the first differing line for a readable failure
+	var expectedResults []goldenResult
+	if err := json.Unmarshal(expected, &expectedResults); err != nil {
+		t.Fatalf("Invalid golden file %s: %v", golden, err)
+	}
+	for i := 0; i < max(len(expectedResults), len(results)); i++ {
+		var want, got []byte
+		if i < len(expectedResults) {
+			want, _ = json.Marshal(expectedResults[i])
+		}
+		if i < len(results) {
+			got, _ = json.Marshal(results[i])
+		}
+		if !bytes.Equal(want, got) {
+			t.Fatalf("Line %d of %s differs from %s (rerun with -update if the change is intended)\nexpected: %s\nactual:   %s",
+				i+1, sample, golden, want, got)
+		}
+	}
+	t.Fatalf("%s differs from %s in formatting, rerun with -update", sample, golden)
+}
+
+// parseGolden parses one line and records the outcome
+func parseGolden(t *testing.T, parser LogParser, number int, line string) goldenResult {
+	result := goldenResult{Line: number, CanParse: parser.CanParse(line)}
+
+	event, err := parser.Parse(line)
+	switch {
+	case errors.Is(err, ErrSkipLine):
+		result.Skipped = true
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		if reporter, ok := event.(PrecisionReporter); ok {
+			precise := reporter.HasPreciseTiming()
+			result.PreciseTiming = &precise
+		}
+		if result.Event, err = json.Marshal(event); err != nil {
+			t.Fatalf("Line %d: failed to encode event: %v", number, err)
+		}
+	}
+	return result
+}
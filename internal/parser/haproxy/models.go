@@ -0,0 +1,54 @@
+package haproxy
+
+import (
+	"time"
+)
+
+// HTTPRequestEvent represents a request from an HAProxy HTTP log (option httplog)
+// Field names match models.HTTPRequest so the processor maps them directly
+type HTTPRequestEvent struct {
+	Timestamp  time.Time
+	SourceName string
+
+	// Client info
+	ClientIP   string
+	ClientPort int
+
+	// Request info
+	Method        string
+	Protocol      string
+	Host          string // Only known for absolute request URIs (HTTP/2, proxies)
+	Path          string
+	QueryString   string
+	RequestScheme string
+
+	// Response info
+	StatusCode             int
+	ResponseSize           int64
+	ResponseTimeMs         float64 // Ta (Tt before HAProxy 1.8), the total time
+	UpstreamResponseTimeMs float64 // Tr, waiting for the server's response headers
+	UpstreamStatus         int     // Status of the server, when it answered
+	Duration               int64   // Nanoseconds, from the total time
+	RetryAttempts          int
+
+	// Routing: the frontend, the backend and the server that handled the request
+	RouterName  string
+	BackendName string
+	BackendURL  string
+
+	// Termination state, timers and captured headers as JSON
+	ProxyMetadata string
+}
+
+func (e *HTTPRequestEvent) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
+func (e *HTTPRequestEvent) GetSourceName() string {
+	return e.SourceName
+}
+
+// HasPreciseTiming is always false: the accept date has millisecond resolution
+func (e *HTTPRequestEvent) HasPreciseTiming() bool {
+	return false
+}
@@ -0,0 +1,177 @@
+package haproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// ParserType is the parser type of HAProxy log sources
+const ParserType = "haproxy"
+
+// ErrNotHTTPLog is returned for lines of the HAProxy log without a client connection (startup, alerts)
+var ErrNotHTTPLog = errors.New("HAProxy message without request")
+
+// httpLogRe matches the HTTP log format (option httplog), after the optional syslog header:
+// client:port [accept date] frontend backend/server TR/Tw/Tc/Tr/Ta status bytes request_cookie response_cookie
+// termination_state actconn/feconn/beconn/srv_conn/retries srv_queue/backend_queue {request headers} {response headers} "request"
+var httpLogRe = regexp.MustCompile(`(?:^|\s)(\S+):(\d+) \[(\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2}\.\d{3})\] (\S+) ([^/\s]+)/(\S+) ` +
+	`(-?\d+)/(-?\d+)/(-?\d+)/(-?\d+)/\+?(-?\d+) (-?\d+) \+?(\d+) \S+ \S+ (\S{4}) \d+/\d+/\d+/\d+/\+?(\d+) \d+/\d+` +
+	`(?: \{([^}]*)\})?(?: \{([^}]*)\})? "(.*)$`)
+
+// acceptDateRe tells apart client logs from the other messages HAProxy writes to the same log
+var acceptDateRe = regexp.MustCompile(`\[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2}\.\d{3}\]`)
+
+// acceptDateLayout is the layout of the accept date, in the local time of the HAProxy host
+const acceptDateLayout = "02/Jan/2006:15:04:05.000"
+
+// timerNames are the names of the five timers, Tq and Tt before HAProxy 1.8
+var timerNames = []string{"TR", "Tw", "Tc", "Tr", "Ta"}
+
+// Parser implements the LogParser interface for HAProxy HTTP logs
+// Lines may keep their syslog header (rsyslog files) or not (log stdout format raw). TCP logs and custom
+// log-format layouts aren't supported.
+type Parser struct {
+	logger *pterm.Logger
+}
+
+// NewParser creates a new HAProxy parser
+func NewParser(logger *pterm.Logger) *Parser {
+	return &Parser{logger: logger}
+}
+
+// Name returns the parser identifier
+func (p *Parser) Name() string {
+	return ParserType
+}
+
+// CanParse checks if the line is an HTTP log line
+func (p *Parser) CanParse(line string) bool {
+	return httpLogRe.MatchString(line)
+}
+
+// Parse parses an HTTP log line; other HAProxy messages return ErrNotHTTPLog
+func (p *Parser) Parse(line string) (*HTTPRequestEvent, error) {
+	values := httpLogRe.FindStringSubmatch(line)
+	if values == nil {
+		if !acceptDateRe.MatchString(line) {
+			return nil, ErrNotHTTPLog
+		}
+		return nil, fmt.Errorf("not an HAProxy HTTP log line (option httplog)")
+	}
+
+	timestamp, err := time.ParseInLocation(acceptDateLayout, values[3], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accept date: %w", err)
+	}
+
+	var timers [5]int
+	for i := range timers {
+		timers[i], _ = strconv.Atoi(values[7+i])
+	}
+	statusCode, _ := strconv.Atoi(values[12])
+	terminationState := values[14]
+
+	event := &HTTPRequestEvent{
+		Timestamp:   timestamp,
+		ClientIP:    values[1],
+		RouterName:  strings.TrimSuffix(values[4], "~"),
+		BackendName: values[5],
+		BackendURL:  values[6],
+		StatusCode:  statusCode,
+	}
+	event.ClientPort, _ = strconv.Atoi(values[2])
+	event.ResponseSize, _ = strconv.ParseInt(values[13], 10, 64)
+	event.RetryAttempts, _ = strconv.Atoi(values[15])
+
+	// No server was selected (e.g. denied or redirected by the frontend)
+	if event.BackendURL == "<NOSRV>" {
+		event.BackendURL = ""
+	}
+
+	// Status -1: no response was sent; when the client aborted, report it as 499 like other proxies
+	if event.StatusCode < 0 {
+		event.StatusCode = 0
+		if terminationState[0] == 'C' {
+			event.StatusCode = 499
+		}
+	}
+
+	// Timers are -1 for the steps that weren't reached
+	if total := timers[4]; total >= 0 {
+		event.Duration = int64(total) * int64(time.Millisecond)
+		event.ResponseTimeMs = float64(total)
+	}
+	if responseTime := timers[3]; responseTime >= 0 {
+		event.UpstreamResponseTimeMs = float64(responseTime)
+		event.UpstreamStatus = event.StatusCode
+	}
+
+	event.RequestScheme = "http"
+	if strings.HasSuffix(values[4], "~") {
+		event.RequestScheme = "https"
+	}
+	setRequestLine(event, strings.TrimSuffix(values[18], `"`))
+
+	metadata := map[string]any{"termination_state": terminationState}
+	timerValues := make(map[string]int, len(timers))
+	for i, name := range timerNames {
+		timerValues[name] = timers[i]
+	}
+	metadata["timers"] = timerValues
+	if values[16] != "" {
+		metadata["request_headers"] = values[16]
+	}
+	if values[17] != "" {
+		metadata["response_headers"] = values[17]
+	}
+	if encoded, err := json.Marshal(metadata); err == nil {
+		event.ProxyMetadata = string(encoded)
+	}
+
+	p.logger.Trace("Parsed HAProxy log line",
+		p.logger.Args("method", event.Method, "path", event.Path, "status", event.StatusCode, "backend", event.BackendName))
+
+	return event, nil
+}
+
+// setRequestLine sets the method, path, query string and protocol from the logged request line
+// Absolute URIs (HTTP/2 requests, forward proxies) also give the host and scheme; <BADREQ> leaves them empty.
+func setRequestLine(event *HTTPRequestEvent, request string) {
+	if request == "<BADREQ>" {
+		return
+	}
+
+	parts := strings.SplitN(request, " ", 3)
+	event.Method = parts[0]
+	if len(parts) > 2 {
+		event.Protocol = parts[2]
+	}
+	if len(parts) < 2 {
+		return
+	}
+
+	uri := parts[1]
+	if strings.Contains(uri, "://") {
+		if parsed, err := url.Parse(uri); err == nil {
+			event.Host = parsed.Host
+			if parsed.Scheme == "http" || parsed.Scheme == "https" {
+				event.RequestScheme = parsed.Scheme
+			}
+			event.Path = parsed.EscapedPath()
+			if event.Path == "" {
+				event.Path = "/"
+			}
+			event.QueryString = parsed.RawQuery
+			return
+		}
+	}
+	event.Path, event.QueryString, _ = strings.Cut(uri, "?")
+}
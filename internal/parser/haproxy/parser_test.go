@@ -0,0 +1,80 @@
+package haproxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParser_HTTPLog(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(logger)
+
+	line := `Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html?lang=en HTTP/1.1"`
+	if !parser.CanParse(line) {
+		t.Fatal("Expected parser to accept the HTTP log line")
+	}
+
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+
+	expectedTime := time.Date(2009, 2, 6, 12, 14, 14, 655*int(time.Millisecond), time.Local)
+	if !event.Timestamp.Equal(expectedTime) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTime, event.Timestamp)
+	}
+	if event.ClientIP != "10.0.1.2" || event.ClientPort != 33317 {
+		t.Errorf("Unexpected client %s:%d", event.ClientIP, event.ClientPort)
+	}
+	if event.RouterName != "http-in" || event.BackendName != "static" || event.BackendURL != "srv1" {
+		t.Errorf("Expected frontend, backend and server, got %s %s/%s", event.RouterName, event.BackendName, event.BackendURL)
+	}
+	if event.ResponseTimeMs != 109 || event.UpstreamResponseTimeMs != 69 || event.Duration != int64(109*time.Millisecond) {
+		t.Errorf("Unexpected timers: total %fms, server %fms", event.ResponseTimeMs, event.UpstreamResponseTimeMs)
+	}
+	if event.StatusCode != 200 || event.UpstreamStatus != 200 || event.ResponseSize != 2750 {
+		t.Errorf("Unexpected response: status %d, upstream %d, %d bytes", event.StatusCode, event.UpstreamStatus, event.ResponseSize)
+	}
+	if event.Method != "GET" || event.Path != "/index.html" || event.QueryString != "lang=en" || event.Protocol != "HTTP/1.1" {
+		t.Errorf("Unexpected request: %s %s ?%s %s", event.Method, event.Path, event.QueryString, event.Protocol)
+	}
+	if event.ProxyMetadata != `{"request_headers":"1wt.eu","termination_state":"----","timers":{"TR":10,"Ta":109,"Tc":30,"Tr":69,"Tw":0}}` {
+		t.Errorf("Unexpected metadata %s", event.ProxyMetadata)
+	}
+}
+
+func TestParser_ClientAbort(t *testing.T) {
+	parser := NewParser(pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+
+	// The client left while the server was processing: no response, no server status
+	event, err := parser.Parse(`10.0.1.2:33318 [06/Feb/2009:12:14:15.000] http-in~ api/srv2 0/0/1/-1/+4012 -1 0 - - CH-- 2/2/1/1/+2 0/0 "POST /orders HTTP/1.1"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.StatusCode != 499 || event.UpstreamStatus != 0 || event.UpstreamResponseTimeMs != 0 {
+		t.Errorf("Expected a 499 without server status, got %d (upstream %d)", event.StatusCode, event.UpstreamStatus)
+	}
+	if event.ResponseTimeMs != 4012 || event.RetryAttempts != 2 || event.RequestScheme != "https" {
+		t.Errorf("Unexpected abort event %+v", event)
+	}
+}
+
+func TestParser_OtherMessages(t *testing.T) {
+	parser := NewParser(pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+
+	if _, err := parser.Parse(`Feb  6 12:14:10 localhost haproxy[14389]: Proxy http-in started.`); !errors.Is(err, ErrNotHTTPLog) {
+		t.Errorf("Expected ErrNotHTTPLog for a startup message, got %v", err)
+	}
+
+	// TCP logs have a client but not the HTTP fields
+	tcp := `10.0.1.2:33319 [06/Feb/2009:12:14:16.000] tcp-in db/pg1 0/0/5001 0 -- 1/1/0/0/0 0/0`
+	if parser.CanParse(tcp) {
+		t.Error("Expected TCP log lines to be rejected")
+	}
+	if _, err := parser.Parse(tcp); err == nil || errors.Is(err, ErrNotHTTPLog) {
+		t.Errorf("Expected a parse error for a TCP log line, got %v", err)
+	}
+}
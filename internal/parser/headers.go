@@ -0,0 +1,40 @@
+package parsers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// headerNamePattern matches the header names that can be captured
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// sensitiveHeaders carry credentials and are never captured
+// X-Api-Key is only kept as a fingerprint (api_key_hash).
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// ParseCapturedHeaders parses a comma-separated list of request or response headers to capture, e.g. "X-Tenant-Id,X-Region"
+// Names are returned in canonical form (http.CanonicalHeaderKey), duplicates once.
+func ParseCapturedHeaders(list string) ([]string, error) {
+	var headers []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !headerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+
+		name = http.CanonicalHeaderKey(name)
+		if slices.Contains(sensitiveHeaders, name) {
+			return nil, fmt.Errorf("header %q carries credentials and can't be captured", name)
+		}
+		if !slices.Contains(headers, name) {
+			headers = append(headers, name)
+		}
+	}
+	return headers, nil
+}
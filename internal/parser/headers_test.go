@@ -0,0 +1,22 @@
+package parsers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseCapturedHeaders(t *testing.T) {
+	headers, err := ParseCapturedHeaders(" x-tenant-id, X-Region ,,X-TENANT-ID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(headers) != "[X-Tenant-Id X-Region]" {
+		t.Errorf("Expected canonical headers without duplicates, got %v", headers)
+	}
+
+	for _, invalid := range []string{"X Tenant", `X-Tenant"Id`, "authorization", "X-Region,Cookie", "x-api-key"} {
+		if _, err := ParseCapturedHeaders(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package iis
+
+import (
+	"time"
+)
+
+// HTTPRequestEvent represents a request from an IIS W3C extended log
+// Field names match models.HTTPRequest so the processor maps them directly
+type HTTPRequestEvent struct {
+	Timestamp  time.Time
+	SourceName string
+
+	// Client info
+	ClientIP   string
+	ClientUser string
+
+	// Request info
+	Method        string
+	Protocol      string
+	Host          string
+	Path          string
+	QueryString   string
+	RequestLength int64
+	RequestScheme string
+
+	// Response info
+	StatusCode     int
+	ResponseSize   int64
+	ResponseTimeMs float64
+
+	// Headers
+	UserAgent string
+	Referer   string
+
+	// Site the request was served by (s-sitename, e.g. W3SVC1)
+	BackendName string
+
+	peerIP       string // c-ip, the proxy when requests are forwarded
+	forwardedFor string // cs(X-Forwarded-For), when logged
+}
+
+func (e *HTTPRequestEvent) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
+func (e *HTTPRequestEvent) GetSourceName() string {
+	return e.SourceName
+}
+
+// ForwardedFor returns the connection peer and the logged X-Forwarded-For chain
+func (e *HTTPRequestEvent) ForwardedFor() (string, string) {
+	return e.peerIP, e.forwardedFor
+}
+
+// SetClientIP replaces the client address with the one resolved from the forwarded chain
+func (e *HTTPRequestEvent) SetClientIP(ip string) {
+	e.ClientIP = ip
+}
+
+// HasPreciseTiming is always false: W3C logs only have second resolution
+func (e *HTTPRequestEvent) HasPreciseTiming() bool {
+	return false
+}
@@ -0,0 +1,171 @@
+package iis
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// ErrDirective is returned for W3C directive lines (#Software, #Fields, ...), which carry no request
+var ErrDirective = errors.New("W3C directive line")
+
+// defaultFields is the field layout IIS writes when logging is left at its default settings
+var defaultFields = []string{
+	"date", "time", "s-ip", "cs-method", "cs-uri-stem", "cs-uri-query", "s-port", "cs-username",
+	"c-ip", "cs(User-Agent)", "cs(Referer)", "sc-status", "sc-substatus", "sc-win32-status", "time-taken",
+}
+
+// Parser implements the LogParser interface for IIS W3C extended logs
+// The column layout comes from the last "#Fields:" directive seen, which makes it state of the file being
+// read: each source parses with its own instance (WithDefaultLayout) and applies directives in file order.
+type Parser struct {
+	logger *pterm.Logger
+
+	mu     sync.RWMutex
+	fields map[string]int // Field name -> column index
+	count  int
+}
+
+// NewParser creates a new IIS parser using the default field layout until a #Fields directive is read
+func NewParser(logger *pterm.Logger) *Parser {
+	p := &Parser{logger: logger}
+	p.setFields(defaultFields)
+	return p
+}
+
+// Name returns the parser identifier
+func (p *Parser) Name() string {
+	return "iis"
+}
+
+// CanParse checks if the line is a W3C directive or has as many columns as the current layout
+func (p *Parser) CanParse(line string) bool {
+	if line == "" {
+		return false
+	}
+	if line[0] == '#' {
+		return true
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(strings.Fields(line)) == p.count
+}
+
+// Parse parses a W3C log line; directive lines update the field layout and return ErrDirective
+func (p *Parser) Parse(line string) (*HTTPRequestEvent, error) {
+	if strings.HasPrefix(line, "#") {
+		if fields, ok := strings.CutPrefix(line, "#Fields:"); ok {
+			p.setFields(strings.Fields(fields))
+			p.logger.Debug("IIS field layout updated", p.logger.Args("fields", strings.TrimSpace(fields)))
+		}
+		return nil, ErrDirective
+	}
+
+	p.mu.RLock()
+	fields, count := p.fields, p.count
+	p.mu.RUnlock()
+
+	values := strings.Fields(line)
+	if len(values) != count {
+		return nil, fmt.Errorf("expected %d fields, got %d", count, len(values))
+	}
+
+	get := func(name string) string {
+		if i, ok := fields[name]; ok && values[i] != "-" {
+			return values[i]
+		}
+		return ""
+	}
+
+	// Timestamps are always UTC in W3C logs
+	timestamp, err := time.Parse("2006-01-02 15:04:05", get("date")+" "+get("time"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	statusCode, err := strconv.Atoi(get("sc-status"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid status code: %w", err)
+	}
+
+	event := &HTTPRequestEvent{
+		Timestamp:   timestamp,
+		ClientIP:    get("c-ip"),
+		ClientUser:  get("cs-username"),
+		Method:      get("cs-method"),
+		Protocol:    get("cs-version"),
+		Host:        get("cs-host"),
+		Path:        get("cs-uri-stem"),
+		QueryString: get("cs-uri-query"),
+		StatusCode:  statusCode,
+		// IIS replaces spaces with '+' in header values
+		UserAgent:   strings.ReplaceAll(get("cs(User-Agent)"), "+", " "),
+		Referer:     get("cs(Referer)"),
+		BackendName: get("s-sitename"),
+
+		peerIP:       get("c-ip"),
+		forwardedFor: strings.ReplaceAll(get("cs(X-Forwarded-For)"), "+", " "),
+	}
+
+	// Behind a proxy or load balancer, c-ip is the proxy; prefer the forwarded client address
+	// With trusted proxies configured, ingestion resolves the client from the whole chain instead.
+	if event.forwardedFor != "" {
+		event.ClientIP = strings.TrimSpace(strings.Split(event.forwardedFor, ",")[0])
+	}
+
+	event.RequestLength, _ = strconv.ParseInt(get("cs-bytes"), 10, 64)
+	event.ResponseSize, _ = strconv.ParseInt(get("sc-bytes"), 10, 64)
+	if timeTaken, err := strconv.ParseFloat(get("time-taken"), 64); err == nil {
+		event.ResponseTimeMs = timeTaken
+	}
+
+	event.RequestScheme = "http"
+	if get("s-port") == "443" {
+		event.RequestScheme = "https"
+	}
+
+	p.logger.Trace("Parsed IIS log line",
+		p.logger.Args("method", event.Method, "path", event.Path, "status", event.StatusCode))
+
+	return event, nil
+}
+
+// WithDefaultLayout returns a new parser with the default field layout, for one file of one source
+func (p *Parser) WithDefaultLayout() *Parser {
+	return NewParser(p.logger)
+}
+
+// ApplyDirective updates the field layout from a #Fields line, returning false for lines that aren't directives
+func (p *Parser) ApplyDirective(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	_, _ = p.Parse(line)
+	return true
+}
+
+// Snapshot returns a parser with the current field layout, unaffected by later directives
+func (p *Parser) Snapshot() *Parser {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return &Parser{logger: p.logger, fields: p.fields, count: p.count}
+}
+
+// setFields replaces the column layout
+func (p *Parser) setFields(names []string) {
+	fields := make(map[string]int, len(names))
+	for i, name := range names {
+		fields[name] = i
+	}
+
+	p.mu.Lock()
+	p.fields = fields
+	p.count = len(names)
+	p.mu.Unlock()
+}
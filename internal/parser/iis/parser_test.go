@@ -0,0 +1,89 @@
+package iis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParser_DefaultFields(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(logger)
+
+	line := `2025-10-15 08:12:45 10.0.0.5 GET /default.aspx id=42 443 - 203.0.113.7 Mozilla/5.0+(Windows+NT+10.0) https://example.com/ 200 0 0 125`
+
+	if !parser.CanParse(line) {
+		t.Fatal("Expected parser to accept default W3C line")
+	}
+
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+
+	expectedTime := time.Date(2025, 10, 15, 8, 12, 45, 0, time.UTC)
+	if !event.Timestamp.Equal(expectedTime) {
+		t.Errorf("Expected timestamp %v, got %v", expectedTime, event.Timestamp)
+	}
+	if event.ClientIP != "203.0.113.7" {
+		t.Errorf("Expected client IP 203.0.113.7, got %s", event.ClientIP)
+	}
+	if event.Method != "GET" || event.Path != "/default.aspx" || event.QueryString != "id=42" {
+		t.Errorf("Unexpected request fields: %s %s ?%s", event.Method, event.Path, event.QueryString)
+	}
+	if event.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", event.StatusCode)
+	}
+	if event.ResponseTimeMs != 125 {
+		t.Errorf("Expected response time 125ms, got %f", event.ResponseTimeMs)
+	}
+	if event.UserAgent != "Mozilla/5.0 (Windows NT 10.0)" {
+		t.Errorf("Expected decoded user agent, got %s", event.UserAgent)
+	}
+	if event.RequestScheme != "https" {
+		t.Errorf("Expected https scheme, got %s", event.RequestScheme)
+	}
+	if event.ClientUser != "" {
+		t.Errorf("Expected empty user for '-', got %s", event.ClientUser)
+	}
+}
+
+func TestParser_FieldsDirective(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(logger)
+
+	if _, err := parser.Parse("#Fields: date time s-sitename cs-method cs-uri-stem cs-host c-ip sc-status sc-bytes time-taken cs(X-Forwarded-For)"); !errors.Is(err, ErrDirective) {
+		t.Fatalf("Expected ErrDirective for #Fields line, got %v", err)
+	}
+
+	line := `2025-10-15 08:12:45 W3SVC2 POST /api/orders shop.example.com 10.0.0.1 201 512 40 198.51.100.4`
+	if !parser.CanParse(line) {
+		t.Fatal("Expected parser to accept line matching the new field layout")
+	}
+
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+	if event.BackendName != "W3SVC2" || event.Host != "shop.example.com" {
+		t.Errorf("Unexpected site/host: %s %s", event.BackendName, event.Host)
+	}
+	if event.ClientIP != "198.51.100.4" {
+		t.Errorf("Expected forwarded client IP, got %s", event.ClientIP)
+	}
+	if event.ResponseSize != 512 {
+		t.Errorf("Expected response size 512, got %d", event.ResponseSize)
+	}
+}
+
+func TestParser_RejectsOtherFormats(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(logger)
+
+	clf := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/endpoint HTTP/1.1" 200 1024 "https://example.com" "Mozilla/5.0"`
+	if parser.CanParse(clf) {
+		t.Error("Expected parser to reject CLF line")
+	}
+}
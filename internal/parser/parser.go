@@ -1,14 +1,49 @@
 package parsers
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrSkipLine is returned by parsers for lines that carry no request (e.g. W3C directives)
+// They are skipped without being reported as parse failures
+var ErrSkipLine = errors.New("line carries no request")
+
 type Event interface {
     GetTimestamp() time.Time
     GetSourceName() string
 }
 
+// PrecisionReporter is optionally implemented by events to tell whether their timing
+// fields are precise enough to distinguish identical requests for deduplication
+type PrecisionReporter interface {
+    HasPreciseTiming() bool
+}
+
+// ForwardedReporter is optionally implemented by events of proxies that log the X-Forwarded-For header,
+// so the client can be resolved from the chain with the trusted proxy configuration
+type ForwardedReporter interface {
+    ForwardedFor() (peer string, chain string) // Connection peer and X-Forwarded-For value, empty when not logged
+    SetClientIP(ip string)
+}
+
+// EventPrototype is optionally implemented by parsers to return an empty event,
+// so the fields of their event struct can be checked against the stored columns at startup
+type EventPrototype interface {
+    NewEvent() Event
+}
+
+// LayoutParser is optionally implemented by parsers of formats whose directive lines (W3C #Fields) set the
+// layout of the lines that follow in the same file. The layout is state of the file being read: each source
+// parses with its own instance, applies directive lines in file order and parses the lines in between
+// with a snapshot of the layout in effect.
+type LayoutParser interface {
+    LogParser
+    WithDefaultLayout() LayoutParser      // New instance with the default layout, for a source or a new file
+    ApplyDirective(line string) bool      // Applies a directive line, false for other lines
+    Snapshot() LogParser                  // Parser of the current layout, unaffected by later directives
+}
+
 type LogParser interface {
     Name() string
     Parse(line string) (Event, error)
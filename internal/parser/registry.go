@@ -1,8 +1,13 @@
 package parsers
 
 import (
+	"errors"
 	"fmt"
+	"loglynx/internal/parser/apache"
+	"loglynx/internal/parser/haproxy"
+	"loglynx/internal/parser/iis"
 	"loglynx/internal/parser/traefik"
+	"strings"
 
 	"github.com/pterm/pterm"
 )
@@ -23,18 +28,112 @@ func (w *traefikParserWrapper) Parse(line string) (Event, error) {
 	return w.Parser.Parse(line)
 }
 
+// NewEvent returns an empty Traefik event for field mapping checks
+func (w *traefikParserWrapper) NewEvent() Event {
+	return &traefik.HTTPRequestEvent{}
+}
+
+// iisParserWrapper wraps iis.Parser to implement LogParser interface
+type iisParserWrapper struct {
+	*iis.Parser
+}
+
+// Parse adapts iis.Parser.Parse to return Event interface
+// W3C directive lines are reported as ErrSkipLine
+func (w *iisParserWrapper) Parse(line string) (Event, error) {
+	event, err := w.Parser.Parse(line)
+	if errors.Is(err, iis.ErrDirective) {
+		return nil, ErrSkipLine
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// NewEvent returns an empty IIS event for field mapping checks
+func (w *iisParserWrapper) NewEvent() Event {
+	return &iis.HTTPRequestEvent{}
+}
+
+// WithDefaultLayout returns a new IIS parser with the default field layout
+func (w *iisParserWrapper) WithDefaultLayout() LayoutParser {
+	return &iisParserWrapper{w.Parser.WithDefaultLayout()}
+}
+
+// Snapshot returns an IIS parser with the current field layout
+func (w *iisParserWrapper) Snapshot() LogParser {
+	return &iisParserWrapper{w.Parser.Snapshot()}
+}
+
+// apacheParserWrapper wraps apache.Parser to implement LogParser interface
+type apacheParserWrapper struct {
+	*apache.Parser
+}
+
+// Parse adapts apache.Parser.Parse to return Event interface
+func (w *apacheParserWrapper) Parse(line string) (Event, error) {
+	event, err := w.Parser.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// NewEvent returns an empty Apache event for field mapping checks
+func (w *apacheParserWrapper) NewEvent() Event {
+	return &apache.HTTPRequestEvent{}
+}
+
+// haproxyParserWrapper wraps haproxy.Parser to implement LogParser interface
+type haproxyParserWrapper struct {
+	*haproxy.Parser
+}
+
+// Parse adapts haproxy.Parser.Parse to return Event interface
+// HAProxy messages without a request (startup, alerts) are reported as ErrSkipLine
+func (w *haproxyParserWrapper) Parse(line string) (Event, error) {
+	event, err := w.Parser.Parse(line)
+	if errors.Is(err, haproxy.ErrNotHTTPLog) {
+		return nil, ErrSkipLine
+	}
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// NewEvent returns an empty HAProxy event for field mapping checks
+func (w *haproxyParserWrapper) NewEvent() Event {
+	return &haproxy.HTTPRequestEvent{}
+}
+
 // NewRegistry creates a new parser registry with all built-in parsers
-func NewRegistry(logger *pterm.Logger) *Registry {
+// capturedHeaders and responseHeaders are the request and response headers Traefik JSON logs keep in proxy_metadata
+// (ParseCapturedHeaders).
+func NewRegistry(capturedHeaders, responseHeaders []string, logger *pterm.Logger) *Registry {
 	registry := &Registry{
 		parsers: make(map[string]LogParser),
 		logger:  logger,
 	}
 
 	// Register built-in parsers with wrappers
-	traefikParser := traefik.NewParser(logger)
+	traefikParser := traefik.NewParser(capturedHeaders, responseHeaders, logger)
 	registry.Register("traefik", &traefikParserWrapper{traefikParser})
 	logger.Debug("Registered parser", logger.Args("type", "traefik"))
 
+	iisParser := iis.NewParser(logger)
+	registry.Register("iis", &iisParserWrapper{iisParser})
+	logger.Debug("Registered parser", logger.Args("type", "iis"))
+
+	// The stock formats never fail to compile
+	apacheParser, _ := apache.NewParser(nil, logger)
+	registry.Register(apache.ParserType, &apacheParserWrapper{apacheParser})
+	logger.Debug("Registered parser", logger.Args("type", apache.ParserType))
+
+	registry.Register(haproxy.ParserType, &haproxyParserWrapper{haproxy.NewParser(logger)})
+	logger.Debug("Registered parser", logger.Args("type", haproxy.ParserType))
+
 	return registry
 }
 
@@ -43,8 +142,31 @@ func (r *Registry) Register(name string, parser LogParser) {
 	r.parsers[name] = parser
 }
 
+// SetApacheLogFormat replaces the formats of the Apache parser with a LogFormat string or nickname (e.g. combined)
+// An empty format keeps the stock formats.
+func (r *Registry) SetApacheLogFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	parser, err := apache.NewParser([]string{format}, r.logger)
+	if err != nil {
+		return err
+	}
+	r.Register(apache.ParserType, &apacheParserWrapper{parser})
+	return nil
+}
+
 // Get retrieves a parser by type
+// Types prefixed with CRIPrefix (e.g. "cri+traefik") return the parser reading container log files
 func (r *Registry) Get(parserType string) (LogParser, error) {
+	if inner, ok := strings.CutPrefix(parserType, CRIPrefix); ok {
+		parser, err := r.Get(inner)
+		if err != nil {
+			return nil, err
+		}
+		return &criParserWrapper{inner: parser}, nil
+	}
+
 	parser, exists := r.parsers[parserType]
 	if !exists {
 		r.logger.WithCaller().Warn("Parser not found", r.logger.Args("type", parserType))
@@ -13,6 +13,8 @@ type HTTPRequestEvent struct {
 	ClientIP       string
 	ClientPort     int
 	ClientUser     string
+	peerIP         string // Address of the connection (ClientHost), X-Real-Ip may have replaced it in ClientIP
+	forwardedFor   string // X-Forwarded-For request header, when logged
 
 	// Request info
 	Method         string
@@ -35,10 +37,16 @@ type HTTPRequestEvent struct {
 	UpstreamResponseTimeMs float64
 	RetryAttempts  int     // Number of retry attempts
 	RequestsTotal  int     // Total number of requests at router level (Traefik CLF field)
+	preciseTiming  bool    // True when Duration/StartUTC come from the log with nanosecond precision (JSON)
 
 	// Headers
 	UserAgent      string
 	Referer        string
+	APIKeyHash     string // Fingerprint of the X-Api-Key request header, the key itself isn't kept
+
+	// Header sizes (not logged by Traefik, FIELD_MAPPINGS can fill them)
+	RequestHeaderBytes  int64
+	ResponseHeaderBytes int64
 
 	// Proxy/Upstream info
 	BackendName         string
@@ -46,6 +54,7 @@ type HTTPRequestEvent struct {
 	RouterName          string
 	UpstreamStatus      int
 	UpstreamContentType string // origin_Content-Type
+	UpstreamAddr        string // ServiceAddr, the server instance that answered (host:port)
 	ClientHostname      string // ClientHost field (may contain hostname)
 
 	// TLS info
@@ -75,4 +84,20 @@ func (e *HTTPRequestEvent) GetTimestamp() time.Time {
 
 func (e *HTTPRequestEvent) GetSourceName() string {
 	return e.SourceName
+}
+
+// ForwardedFor returns the connection peer and the logged X-Forwarded-For chain
+func (e *HTTPRequestEvent) ForwardedFor() (string, string) {
+	return e.peerIP, e.forwardedFor
+}
+
+// SetClientIP replaces the client address with the one resolved from the forwarded chain
+func (e *HTTPRequestEvent) SetClientIP(ip string) {
+	e.ClientIP = ip
+}
+
+// HasPreciseTiming reports whether the event can be told apart from identical requests in the same second
+// CLF logs only have second precision, so their Duration/StartUTC are derived and not unique enough
+func (e *HTTPRequestEvent) HasPreciseTiming() bool {
+	return e.preciseTiming
 }
\ No newline at end of file
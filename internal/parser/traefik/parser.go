@@ -1,6 +1,8 @@
 package traefik
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -30,6 +32,8 @@ type Parser struct {
 	logger         *pterm.Logger
 	clfRegex       *regexp.Regexp
 	genericCLFRegex *regexp.Regexp  // Pre-compiled generic CLF regex for performance
+	capturedHeaders []string        // Request headers stored in ProxyMetadata (canonical names)
+	responseHeaders []string        // Response headers stored in ProxyMetadata (canonical names)
 }
 
 // CLF regex pattern for Traefik Common Log Format
@@ -41,7 +45,9 @@ const traefikCLFPattern = `^(\S+) \S+ (\S+) \[([^\]]+)\] "([A-Z]+) ([^ "]+)? HTT
 const genericCLFPattern = `^(\S+) \S+ (\S+) \[([^\]]+)\] "([A-Z]+) ([^ "]+)? HTTP/[0-9.]+" (\d{3}) (\d+|-) "([^"]*)" "([^"]*)"`
 
 // NewParser creates a new Traefik parser instance
-func NewParser(logger *pterm.Logger) *Parser {
+// capturedHeaders are the request headers kept in ProxyMetadata, e.g. X-Tenant-Id, responseHeaders the response
+// headers, e.g. Server.
+func NewParser(capturedHeaders, responseHeaders []string, logger *pterm.Logger) *Parser {
 	// Pre-compile regex patterns (try Traefik CLF first, fall back to generic CLF)
 	// OPTIMIZATION: Compile once at initialization instead of on every line
 	clfRegex := regexp.MustCompile(traefikCLFPattern)
@@ -51,6 +57,8 @@ func NewParser(logger *pterm.Logger) *Parser {
 		logger:          logger,
 		clfRegex:        clfRegex,
 		genericCLFRegex: genericCLFRegex,
+		capturedHeaders: capturedHeaders,
+		responseHeaders: responseHeaders,
 	}
 }
 
@@ -206,6 +214,13 @@ func (p *Parser) parseJSON(line string) (*HTTPRequestEvent, error) {
 	// Extract client hostname (may be same as IP or actual hostname)
 	clientHostname := getString(raw, "ClientHost")
 
+	// Connection peer, for resolving the client from X-Forwarded-For behind trusted proxies
+	peer := clientHostname
+	if peer == "" {
+		peer = getString(raw, "ClientAddr")
+	}
+	peerIP, _ := parseClientHost(peer)
+
 	// Extract query string from path if present
 	path := getString(raw, "RequestPath")
 	if path == "" {
@@ -227,7 +242,10 @@ func (p *Parser) parseJSON(line string) (*HTTPRequestEvent, error) {
 		// Client info
 		ClientIP:       ip,
 		ClientPort:     port,
+		ClientUser:     clientUser(getString(raw, "ClientUsername")),
 		ClientHostname: clientHostname, // May be hostname or same as IP
+		peerIP:         peerIP,
+		forwardedFor:   getHeader(raw, "X-Forwarded-For"),
 
 		// Request info
 		Method:        strings.ToUpper(method),
@@ -247,17 +265,23 @@ func (p *Parser) parseJSON(line string) (*HTTPRequestEvent, error) {
 		Duration:      int64(getDuration(raw, "Duration")), // Nanoseconds
 		StartUTC:      getString(raw, "StartUTC"),          // Timestamp with nanosecond precision
 		RetryAttempts: getInt(raw, "RetryAttempts"),
+
+		// Backend timing: OriginDuration stops when the client aborts, OriginStatus stays 0 if the backend never answered
+		UpstreamResponseTimeMs: getDuration(raw, "OriginDuration") / 1000000,
 		RequestsTotal: getInt(raw, "RequestsTotal"), // Total requests at router level (defaults to 0 if not present)
 
 		// Headers
-		UserAgent: getString(raw, "request_User-Agent"),
-		Referer:   getString(raw, "request_Referer"),
+		UserAgent:  getString(raw, "request_User-Agent"),
+		Referer:    getString(raw, "request_Referer"),
+		APIKeyHash: apiKeyHash(getHeader(raw, "X-Api-Key")),
 
 		// Traefik-specific (may not be present)
 		BackendName:         getString(raw, "ServiceName"),
 		BackendURL:          getString(raw, "backend_URL"),
-		RouterName:          getString(raw, "router_Name"),
+		RouterName:          getRouterName(raw),
 		UpstreamContentType: getString(raw, "origin_Content-Type"),
+		UpstreamStatus:      getInt(raw, "OriginStatus"),
+		UpstreamAddr:        getString(raw, "ServiceAddr"),
 
 		// TLS info
 		TLSVersion: getString(raw, "TLSVersion"),
@@ -265,6 +289,13 @@ func (p *Parser) parseJSON(line string) (*HTTPRequestEvent, error) {
 
 		// Tracing
 		RequestID: getString(raw, "request_X-Request-Id"),
+		TraceID:   getTraceID(raw),
+
+		// Configured extra headers (CAPTURE_HEADERS)
+		ProxyMetadata: p.proxyMetadata(raw),
+
+		// JSON logs carry Traefik's nanosecond Duration/StartUTC
+		preciseTiming: getString(raw, "StartUTC") != "" || getDuration(raw, "Duration") > 0,
 	}
 
 	if event.Referer == "" && redirectTarget != "" {
@@ -279,6 +310,10 @@ func (p *Parser) parseJSON(line string) (*HTTPRequestEvent, error) {
 		event.StatusCode = 0
 	}
 
+	if event.UpstreamStatus < 100 || event.UpstreamStatus >= 600 {
+		event.UpstreamStatus = 0
+	}
+
 	// Log trace for successful parse
 	p.logger.Trace("Successfully parsed Traefik log",
 		p.logger.Args(
@@ -322,8 +357,8 @@ func (p *Parser) parseTraefikCLF(matches []string) (*HTTPRequestEvent, error) {
 	}
 
 	// Extract fields from regex capture groups
-	clientHost := matches[1] // Client IP (possibly with port)
-	// matches[2] is userid (usually "-")
+	clientHost := matches[1]        // Client IP (possibly with port)
+	userID := matches[2]            // Authenticated user ("-" without authentication)
 	timestampStr := matches[3]      // Timestamp
 	method := matches[4]            // HTTP method
 	requestPath := matches[5]       // Request path
@@ -409,6 +444,7 @@ func (p *Parser) parseTraefikCLF(matches []string) (*HTTPRequestEvent, error) {
 		// Client info
 		ClientIP:       ip,
 		ClientPort:     port,
+		ClientUser:     clientUser(userID),
 		ClientHostname: "", // Not available in CLF
 
 		// Request info
@@ -438,7 +474,7 @@ func (p *Parser) parseTraefikCLF(matches []string) (*HTTPRequestEvent, error) {
 		// Traefik-specific
 		BackendName:         backendName, // ServiceName not in CLF
 		BackendURL:          backendURL,
-		RouterName:          "",
+		RouterName:          backendName, // CLF only logs the router name
 		UpstreamContentType: "", // Not available in CLF
 
 		// TLS info
@@ -477,6 +513,7 @@ func (p *Parser) parseGenericCLF(matches []string) (*HTTPRequestEvent, error) {
 
 	// Extract fields from regex capture groups
 	clientHost := matches[1]   // Client IP
+	userID := matches[2]       // Authenticated user ("-" without authentication)
 	timestampStr := matches[3] // Timestamp
 	method := matches[4]       // HTTP method
 	requestPath := matches[5]  // Request path
@@ -538,6 +575,7 @@ func (p *Parser) parseGenericCLF(matches []string) (*HTTPRequestEvent, error) {
 		// Client info
 		ClientIP:       ip,
 		ClientPort:     port,
+		ClientUser:     clientUser(userID),
 		ClientHostname: "",
 
 		// Request info
@@ -623,6 +661,118 @@ func getString(m map[string]any, key string) string {
 	return ""
 }
 
+// getHeader returns a request header logged by Traefik (request_<Name>), matching the name case-insensitively
+// Traefik logs header names as configured in accessLog.fields.headers.names.
+func getHeader(m map[string]any, name string) string {
+	return getPrefixedHeader(m, "request_", name)
+}
+
+// getResponseHeader returns a response header logged by Traefik, as sent by the backend (origin_<Name>) or else as
+// sent to the client (downstream_<Name>), so headers a middleware strips still fingerprint the backend.
+func getResponseHeader(m map[string]any, name string) string {
+	if value := getPrefixedHeader(m, "origin_", name); value != "" {
+		return value
+	}
+	return getPrefixedHeader(m, "downstream_", name)
+}
+
+// getPrefixedHeader returns the header logged under prefix+name, matching the name case-insensitively
+func getPrefixedHeader(m map[string]any, prefix, name string) string {
+	if value := getString(m, prefix+name); value != "" {
+		return value
+	}
+	for key := range m {
+		if len(key) == len(prefix)+len(name) && strings.EqualFold(key, prefix+name) {
+			return getString(m, key)
+		}
+	}
+	return ""
+}
+
+// maxCapturedHeaderLength caps captured header values so a client can't bloat proxy_metadata
+const maxCapturedHeaderLength = 256
+
+// proxyMetadata returns the captured request and response headers as JSON, e.g.
+// {"headers":{"X-Tenant-Id":"acme"},"response_headers":{"Server":"nginx"}}
+// Empty when no configured header was logged.
+func (p *Parser) proxyMetadata(raw map[string]any) string {
+	if len(p.capturedHeaders) == 0 && len(p.responseHeaders) == 0 {
+		return ""
+	}
+
+	metadata := make(map[string]any)
+	if headers := capturedValues(raw, p.capturedHeaders, getHeader); len(headers) > 0 {
+		metadata["headers"] = headers
+	}
+	if headers := capturedValues(raw, p.responseHeaders, getResponseHeader); len(headers) > 0 {
+		metadata["response_headers"] = headers
+	}
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// capturedValues returns the logged values of the headers by name, skipping absent and redacted ones
+func capturedValues(raw map[string]any, names []string, get func(map[string]any, string) string) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range names {
+		value := get(raw, name)
+		if value == "" || value == "REDACTED" {
+			continue
+		}
+		if len(value) > maxCapturedHeaderLength {
+			value = strings.ToValidUTF8(value[:maxCapturedHeaderLength], "")
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// clientUser returns the authenticated user, empty for "-" (no authentication)
+func clientUser(user string) string {
+	if user == "-" {
+		return ""
+	}
+	return user
+}
+
+// apiKeyHash returns a fingerprint identifying an API key (first 16 hex digits of its SHA-256)
+// Keys redacted by Traefik (accessLog.fields.headers.names: redact) identify no one.
+func apiKeyHash(key string) string {
+	if key == "" || key == "REDACTED" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// getTraceID extracts the distributed tracing ID: "TraceId" logged by Traefik with tracing enabled, else the trace ID of
+// a logged W3C traceparent (00-<trace>-<span>-<flags>) or B3 header
+func getTraceID(m map[string]any) string {
+	if id := getString(m, "TraceId"); id != "" {
+		return id
+	}
+	if parts := strings.Split(getHeader(m, "Traceparent"), "-"); len(parts) == 4 && len(parts[1]) == 32 {
+		return parts[1]
+	}
+	return getHeader(m, "X-B3-Traceid")
+}
+
+// getRouterName extracts the router name, logged as "RouterName" by Traefik
+// "router_Name" is kept for logs written by older configurations.
+func getRouterName(m map[string]any) string {
+	if name := getString(m, "RouterName"); name != "" {
+		return name
+	}
+	return getString(m, "router_Name")
+}
+
 // getInt safely extracts an integer value from the map
 func getInt(m map[string]any, key string) int {
 	if val, ok := m[key]; ok {
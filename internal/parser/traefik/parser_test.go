@@ -9,7 +9,7 @@ import (
 
 func TestParser_CanParse_JSON(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamStatus":200,"Duration":299425702,"RequestMethod":"GET","RequestPath":"/","RequestProtocol":"HTTP/1.1","ServiceName":"next-service@file","TLSCipher":"TLS_AES_128_GCM_SHA256","TLSVersion":"1.3","request_User-Agent":"Mozilla/5.0","request_X-Real-Ip":"103.4.250.66","time":"2025-10-25T21:11:49Z"}`
 
@@ -20,7 +20,7 @@ func TestParser_CanParse_JSON(t *testing.T) {
 
 func TestParser_CanParse_TraefikCLF(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	clfLog := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/endpoint HTTP/1.1" 200 1024 "https://example.com" "Mozilla/5.0" 42 "my-router" "http://backend:8080" 150ms`
 
@@ -31,7 +31,7 @@ func TestParser_CanParse_TraefikCLF(t *testing.T) {
 
 func TestParser_CanParse_GenericCLF(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	genericCLF := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/endpoint HTTP/1.1" 200 1024 "https://example.com" "Mozilla/5.0"`
 
@@ -42,7 +42,7 @@ func TestParser_CanParse_GenericCLF(t *testing.T) {
 
 func TestParser_CanParse_Invalid(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	tests := []string{
 		"",
@@ -59,7 +59,7 @@ func TestParser_CanParse_Invalid(t *testing.T) {
 
 func TestParser_ParseJSON(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamContentSize":31869,"DownstreamStatus":200,"Duration":299425702,"RequestMethod":"GET","RequestPath":"/test?redirect=https://example.com","RequestProtocol":"HTTP/1.1","ServiceName":"next-service@file","TLSCipher":"TLS_AES_128_GCM_SHA256","TLSVersion":"1.3","request_User-Agent":"Mozilla/5.0 (Test)","request_Referer":"https://referrer.com","request_X-Real-Ip":"103.4.250.66","time":"2025-10-25T21:11:49Z"}`
 
@@ -95,9 +95,129 @@ func TestParser_ParseJSON(t *testing.T) {
 	}
 }
 
+func TestParser_ParseRouterName(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(nil, nil, logger)
+
+	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamStatus":200,"RequestMethod":"GET","RequestPath":"/","RouterName":"next-router@file","ServiceName":"next-service@file","time":"2025-10-25T21:11:49Z"}`
+
+	event, err := parser.Parse(jsonLog)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON log: %v", err)
+	}
+	if event.RouterName != "next-router@file" {
+		t.Errorf("Expected RouterName 'next-router@file', got '%s'", event.RouterName)
+	}
+	if event.BackendName != "next-service@file" {
+		t.Errorf("Expected BackendName 'next-service@file', got '%s'", event.BackendName)
+	}
+
+	// The Traefik CLF format only logs the router, which also names the backend
+	clfLog := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/endpoint HTTP/1.1" 200 1024 "https://example.com" "Mozilla/5.0" 42 "my-router" "http://backend:8080" 150ms`
+	event, err = parser.Parse(clfLog)
+	if err != nil {
+		t.Fatalf("Failed to parse Traefik CLF log: %v", err)
+	}
+	if event.RouterName != "my-router" || event.BackendName != "my-router" {
+		t.Errorf("Expected RouterName and BackendName 'my-router', got '%s' and '%s'", event.RouterName, event.BackendName)
+	}
+}
+
+func TestParser_ParseJSONClientAbort(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(nil, nil, logger)
+
+	// Client gave up after 3s while the backend was still working (context canceled)
+	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamStatus":499,"Duration":3000000000,"OriginDuration":2950000000,"OriginStatus":0,"RequestMethod":"GET","RequestPath":"/slow","ServiceName":"api@docker","time":"2025-10-25T21:11:49Z"}`
+
+	event, err := parser.Parse(jsonLog)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON log: %v", err)
+	}
+
+	if event.StatusCode != 499 {
+		t.Errorf("Expected StatusCode 499, got %d", event.StatusCode)
+	}
+	if event.UpstreamStatus != 0 {
+		t.Errorf("Expected UpstreamStatus 0, got %d", event.UpstreamStatus)
+	}
+	if event.UpstreamResponseTimeMs != 2950 {
+		t.Errorf("Expected UpstreamResponseTimeMs 2950, got %f", event.UpstreamResponseTimeMs)
+	}
+}
+
+func TestParser_ParseJSONCapturedHeaders(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser([]string{"X-Tenant-Id", "X-Region", "X-Plan"}, nil, logger)
+
+	// Traefik keeps the configured header case, redacted and absent headers aren't captured
+	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamStatus":200,"RequestMethod":"GET","RequestPath":"/","request_x-tenant-id":"acme","request_X-Region":"eu-west","request_X-Plan":"REDACTED","time":"2025-10-25T21:11:49Z"}`
+
+	event, err := parser.Parse(jsonLog)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON log: %v", err)
+	}
+	if expected := `{"headers":{"X-Region":"eu-west","X-Tenant-Id":"acme"}}`; event.ProxyMetadata != expected {
+		t.Errorf("Expected ProxyMetadata %s, got %s", expected, event.ProxyMetadata)
+	}
+
+	event, err = NewParser(nil, nil, logger).Parse(jsonLog)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON log: %v", err)
+	}
+	if event.ProxyMetadata != "" {
+		t.Errorf("Expected no ProxyMetadata without captured headers, got %s", event.ProxyMetadata)
+	}
+}
+
+func TestParser_ParseJSONCapturedResponseHeaders(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser([]string{"X-Tenant-Id"}, []string{"Cache-Control", "Server", "X-Powered-By"}, logger)
+
+	// The origin value wins over the downstream one, X-Powered-By was stripped by a middleware
+	jsonLog := `{"ClientHost":"103.4.250.66","DownstreamStatus":200,"RequestMethod":"GET","RequestPath":"/","request_X-Tenant-Id":"acme","origin_Server":"nginx/1.25.3","origin_x-powered-by":"PHP/8.2","downstream_Server":"traefik","downstream_Cache-Control":"no-store","time":"2025-10-25T21:11:49Z"}`
+
+	event, err := parser.Parse(jsonLog)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON log: %v", err)
+	}
+	expected := `{"headers":{"X-Tenant-Id":"acme"},"response_headers":{"Cache-Control":"no-store","Server":"nginx/1.25.3","X-Powered-By":"PHP/8.2"}}`
+	if event.ProxyMetadata != expected {
+		t.Errorf("Expected ProxyMetadata %s, got %s", expected, event.ProxyMetadata)
+	}
+}
+
+func TestParser_ParseJSONTraceID(t *testing.T) {
+	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
+	parser := NewParser(nil, nil, logger)
+
+	tests := []struct {
+		name     string
+		fields   string
+		expected string
+	}{
+		{"traefik tracing", `"TraceId":"4bf92f3577b34da6a3ce929d0e0e4736","request_Traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"`, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"traceparent", `"request_traceparent":"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"`, "0af7651916cd43dd8448eb211c80319c"},
+		{"invalid traceparent", `"request_Traceparent":"garbage"`, ""},
+		{"b3", `"request_X-B3-Traceid":"80f198ee56343ba864fe8b2a57d3eff7"`, "80f198ee56343ba864fe8b2a57d3eff7"},
+		{"none", `"request_X-Request-Id":"abc"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parser.Parse(`{"ClientHost":"103.4.250.66","DownstreamStatus":200,"RequestMethod":"GET","RequestPath":"/",` + tt.fields + `,"time":"2025-10-25T21:11:49Z"}`)
+			if err != nil {
+				t.Fatalf("Failed to parse JSON log: %v", err)
+			}
+			if event.TraceID != tt.expected {
+				t.Errorf("Expected TraceID %q, got %q", tt.expected, event.TraceID)
+			}
+		})
+	}
+}
+
 func TestParser_ParseTraefikCLF(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	clfLog := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/endpoint HTTP/1.1" 200 1024 "https://example.com" "Mozilla/5.0" 42 "my-router" "http://backend:8080" 150ms`
 
@@ -144,7 +264,7 @@ func TestParser_ParseTraefikCLF(t *testing.T) {
 
 func TestParser_ParseGenericCLF(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	genericCLF := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "POST /api/users HTTP/1.1" 201 512 "https://app.example.com" "curl/7.68.0"`
 
@@ -180,7 +300,7 @@ func TestParser_ParseGenericCLF(t *testing.T) {
 
 func TestParser_DetectFormat(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	tests := []struct {
 		name     string
@@ -226,7 +346,7 @@ func TestParser_DetectFormat(t *testing.T) {
 
 func TestParser_ParseCLFWithDashValues(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	// Test with "-" values for referer and user agent
 	clfLog := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api HTTP/1.1" 200 - "-" "-" 42 "-" "-" 150ms`
@@ -256,7 +376,7 @@ func TestParser_ParseCLFWithDashValues(t *testing.T) {
 
 func TestParser_ParseCLFWithQueryString(t *testing.T) {
 	logger := pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace)
-	parser := NewParser(logger)
+	parser := NewParser(nil, nil, logger)
 
 	clfLog := `192.168.1.100 - - [15/May/2025:12:06:30 +0000] "GET /api/search?q=test&limit=10 HTTP/1.1" 200 1024 "-" "Mozilla" 42 "router" "http://backend" 150ms`
 
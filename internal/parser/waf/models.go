@@ -0,0 +1,35 @@
+package waf
+
+import (
+	"time"
+)
+
+// ParserType is the parser type of WAF audit log sources
+// These sources are read by the WAF ingester into waf_events, not by the request pipeline.
+const ParserType = "waf"
+
+// Event is a transaction of a WAF audit log with the rules it matched
+type Event struct {
+	Timestamp  time.Time
+	SourceName string
+
+	TransactionID string // unique_id (ModSecurity) or id (Coraza), empty when not logged
+	ClientIP      string
+	Method        string
+	Host          string
+	Path          string
+	StatusCode    int
+
+	Blocked  bool     // The WAF interrupted the request
+	RuleIDs  []string // Matched rules, in log order
+	Message  string   // Most specific rule message (anomaly score rules only when nothing else matched)
+	Severity string   // Highest severity of the matched rules
+}
+
+func (e *Event) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
+func (e *Event) GetSourceName() string {
+	return e.SourceName
+}
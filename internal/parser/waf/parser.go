@@ -0,0 +1,250 @@
+package waf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// ErrNoRules is returned for audited transactions that matched no rule and were not blocked
+// (e.g. logged for their status code with SecAuditLogRelevantStatus)
+var ErrNoRules = errors.New("transaction matched no WAF rule")
+
+// severityNames are the syslog severities rules use, numbered as in ModSecurity and Coraza
+var severityNames = []string{"EMERGENCY", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+// flexString decodes JSON strings and numbers, ModSecurity logs rule IDs and severities as strings
+// where Coraza logs numbers
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*f = ""
+		return nil
+	}
+	*f = flexString(data)
+	return nil
+}
+
+// auditLog covers the JSON audit logs of libmodsecurity 3 (SecAuditLogFormat JSON) and Coraza
+// (SecAuditLogFormat JSON), which share the transaction/request/response layout with different field names
+type auditLog struct {
+	Transaction struct {
+		ClientIP string `json:"client_ip"`
+
+		// ModSecurity 3
+		TimeStamp string         `json:"time_stamp"` // "Wed Oct 15 08:12:45 2025", local time
+		UniqueID  string         `json:"unique_id"`
+		Messages  []auditMessage `json:"messages"`
+
+		// Coraza
+		Timestamp     string `json:"timestamp"`      // "2025/10/15 08:12:45", local time
+		UnixTimestamp int64  `json:"unix_timestamp"` // Nanoseconds
+		ID            string `json:"id"`
+		IsInterrupted bool   `json:"is_interrupted"`
+
+		Request struct {
+			Method  string                     `json:"method"`
+			URI     string                     `json:"uri"`
+			Headers map[string]json.RawMessage `json:"headers"` // string values (ModSecurity) or lists (Coraza)
+		} `json:"request"`
+		Response struct {
+			HTTPCode int `json:"http_code"` // ModSecurity 3
+			Status   int `json:"status"`    // Coraza
+		} `json:"response"`
+	} `json:"transaction"`
+
+	Messages []auditMessage `json:"messages"` // Coraza
+}
+
+// auditMessage is a matched rule
+type auditMessage struct {
+	Message string       `json:"message"`
+	Details *ruleDetails `json:"details"` // ModSecurity 3
+	Data    *ruleDetails `json:"data"`    // Coraza
+}
+
+type ruleDetails struct {
+	RuleID   flexString `json:"ruleId"` // ModSecurity 3
+	ID       flexString `json:"id"`     // Coraza
+	Msg      string     `json:"msg"`
+	Severity flexString `json:"severity"`
+}
+
+// Parser reads WAF JSON audit logs, one transaction per line
+// ModSecurity 3 logs no interruption flag: its transactions count as blocked when answered with a 403.
+type Parser struct {
+	logger *pterm.Logger
+}
+
+// NewParser creates a new WAF audit log parser
+func NewParser(logger *pterm.Logger) *Parser {
+	return &Parser{logger: logger}
+}
+
+// Name returns the parser identifier
+func (p *Parser) Name() string {
+	return ParserType
+}
+
+// CanParse checks if the line looks like a JSON audit log transaction
+func (p *Parser) CanParse(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "{") && strings.Contains(line, `"transaction"`)
+}
+
+// Parse parses an audit log line, returning ErrNoRules for transactions without matched rules
+func (p *Parser) Parse(line string) (*Event, error) {
+	var entry auditLog
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return nil, fmt.Errorf("invalid JSON audit log: %w", err)
+	}
+	tx := &entry.Transaction
+	if tx.ClientIP == "" {
+		return nil, errors.New("audit log without client_ip")
+	}
+
+	event := &Event{
+		ClientIP:   tx.ClientIP,
+		Method:     tx.Request.Method,
+		Host:       headerValue(tx.Request.Headers, "host"),
+		Path:       tx.Request.URI,
+		StatusCode: tx.Response.Status,
+		Blocked:    tx.IsInterrupted,
+	}
+	if path, _, found := strings.Cut(event.Path, "?"); found {
+		event.Path = path
+	}
+
+	messages := entry.Messages
+	switch {
+	case tx.UnixTimestamp > 0:
+		event.Timestamp = time.Unix(0, tx.UnixTimestamp)
+		event.TransactionID = tx.ID
+	case tx.Timestamp != "":
+		timestamp, err := time.ParseInLocation("2006/01/02 15:04:05", tx.Timestamp, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q: %w", tx.Timestamp, err)
+		}
+		event.Timestamp = timestamp
+		event.TransactionID = tx.ID
+	case tx.TimeStamp != "":
+		timestamp, err := time.ParseInLocation(time.ANSIC, tx.TimeStamp, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time_stamp %q: %w", tx.TimeStamp, err)
+		}
+		event.Timestamp = timestamp
+		event.TransactionID = tx.UniqueID
+		event.StatusCode = tx.Response.HTTPCode
+		event.Blocked = tx.Response.HTTPCode == 403 && len(tx.Messages) > 0
+		messages = tx.Messages
+	default:
+		return nil, errors.New("audit log without timestamp")
+	}
+
+	applyMessages(event, messages)
+	if len(event.RuleIDs) == 0 && !event.Blocked {
+		return nil, ErrNoRules
+	}
+	return event, nil
+}
+
+// applyMessages collects the rule IDs, message and highest severity of the matched rules
+func applyMessages(event *Event, messages []auditMessage) {
+	severity := len(severityNames)
+	var anomalyMessage string
+
+	for _, message := range messages {
+		details := message.Details
+		if details == nil {
+			details = message.Data
+		}
+		if details == nil {
+			continue
+		}
+
+		id := string(details.RuleID)
+		if id == "" {
+			id = string(details.ID)
+		}
+		if id != "" && id != "0" && !slices.Contains(event.RuleIDs, id) {
+			event.RuleIDs = append(event.RuleIDs, id)
+		}
+
+		text := message.Message
+		if text == "" {
+			text = details.Msg
+		}
+		// OWASP CRS anomaly score rules (949xxx, 959xxx, 980xxx) only say the score was exceeded
+		if isAnomalyScoreRule(id) {
+			if anomalyMessage == "" {
+				anomalyMessage = text
+			}
+		} else if event.Message == "" {
+			event.Message = text
+		}
+
+		if level := severityLevel(string(details.Severity)); level < severity {
+			severity = level
+		}
+	}
+
+	if event.Message == "" {
+		event.Message = anomalyMessage
+	}
+	if severity < len(severityNames) {
+		event.Severity = severityNames[severity]
+	}
+}
+
+// severityLevel returns the number of a severity given as number or name, len(severityNames) if unknown
+func severityLevel(severity string) int {
+	if level, err := strconv.Atoi(severity); err == nil {
+		if level >= 0 && level < len(severityNames) {
+			return level
+		}
+		return len(severityNames)
+	}
+	for level, name := range severityNames {
+		if strings.EqualFold(severity, name) {
+			return level
+		}
+	}
+	return len(severityNames)
+}
+
+func isAnomalyScoreRule(id string) bool {
+	return strings.HasPrefix(id, "949") || strings.HasPrefix(id, "959") || strings.HasPrefix(id, "980")
+}
+
+// headerValue returns a request header logged as a string or a list of strings, matched case-insensitively
+func headerValue(headers map[string]json.RawMessage, name string) string {
+	for key, raw := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			return value
+		}
+		var values []string
+		if err := json.Unmarshal(raw, &values); err == nil && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package waf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+func TestParser_Coraza(t *testing.T) {
+	parser := NewParser(pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace))
+
+	line := `{"transaction":{"timestamp":"2025/10/15 08:12:45","unix_timestamp":1760515965123456789,"id":"tx1","client_ip":"203.0.113.7","client_port":51234,"request":{"method":"GET","protocol":"HTTP/1.1","uri":"/search?q=1' OR '1'='1","headers":{"host":["shop.example.com"]}},"response":{"status":403},"is_interrupted":true},"messages":[{"actionset":"","message":"","data":{"id":942100,"msg":"SQL Injection Attack Detected via libinjection","severity":2}},{"message":"","data":{"id":949110,"msg":"Inbound Anomaly Score Exceeded (Total Score: 5)","severity":0}}]}`
+	if !parser.CanParse(line) {
+		t.Fatal("Expected parser to accept Coraza audit log line")
+	}
+
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+	if !event.Timestamp.Equal(time.Unix(0, 1760515965123456789)) || event.TransactionID != "tx1" {
+		t.Errorf("Unexpected transaction %s at %v", event.TransactionID, event.Timestamp)
+	}
+	if event.ClientIP != "203.0.113.7" || event.Method != "GET" || event.Host != "shop.example.com" || event.Path != "/search" {
+		t.Errorf("Unexpected request fields: %s %s %s%s", event.ClientIP, event.Method, event.Host, event.Path)
+	}
+	if !event.Blocked || event.StatusCode != 403 {
+		t.Errorf("Expected a blocked request with status 403, got blocked=%v status=%d", event.Blocked, event.StatusCode)
+	}
+	if len(event.RuleIDs) != 2 || event.RuleIDs[0] != "942100" {
+		t.Errorf("Unexpected rule IDs %v", event.RuleIDs)
+	}
+	if event.Message != "SQL Injection Attack Detected via libinjection" || event.Severity != "EMERGENCY" {
+		t.Errorf("Expected the SQL injection message, got %q (%s)", event.Message, event.Severity)
+	}
+}
+
+func TestParser_ModSecurity(t *testing.T) {
+	parser := NewParser(pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace))
+
+	line := `{"transaction":{"client_ip":"198.51.100.4","time_stamp":"Wed Oct 15 08:12:45 2025","server_id":"x","unique_id":"u1","request":{"method":"POST","http_version":1.1,"uri":"/wp-login.php","headers":{"Host":"blog.example.com"}},"response":{"http_code":200},"messages":[{"message":"Found User-Agent associated with security scanner","details":{"ruleId":"913100","severity":"2"}}]}}`
+	event, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Failed to parse line: %v", err)
+	}
+
+	expectedTime := time.Date(2025, 10, 15, 8, 12, 45, 0, time.Local)
+	if !event.Timestamp.Equal(expectedTime) || event.TransactionID != "u1" {
+		t.Errorf("Unexpected transaction %s at %v", event.TransactionID, event.Timestamp)
+	}
+	if event.Blocked || event.StatusCode != 200 {
+		t.Errorf("Expected a detected request with status 200, got blocked=%v status=%d", event.Blocked, event.StatusCode)
+	}
+	if event.Host != "blog.example.com" || len(event.RuleIDs) != 1 || event.RuleIDs[0] != "913100" || event.Severity != "CRITICAL" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
+
+func TestParser_NoRules(t *testing.T) {
+	parser := NewParser(pterm.DefaultLogger.WithLevel(pterm.LogLevelTrace))
+
+	line := `{"transaction":{"client_ip":"198.51.100.4","time_stamp":"Wed Oct 15 08:12:45 2025","request":{"method":"GET","uri":"/missing"},"response":{"http_code":404},"messages":[]}}`
+	if _, err := parser.Parse(line); !errors.Is(err, ErrNoRules) {
+		t.Errorf("Expected ErrNoRules, got %v", err)
+	}
+	if parser.CanParse(`10.0.0.1 - - [15/Oct/2025:08:12:45 +0000] "GET / HTTP/1.1" 200 5`) {
+		t.Error("Expected parser to reject access log lines")
+	}
+}
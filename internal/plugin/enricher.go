@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+)
+
+// enrichableFields maps the fields enricher plugins may set to the request fields they replace
+// Fields of the dedup hash (timestamp, client, method, host, path, status) are computed before enrichment
+// and can't change.
+var enrichableFields = map[string]string{
+	"client_user":     "ClientUser",
+	"client_hostname": "ClientHostname",
+	"backend_name":    "BackendName",
+	"router_name":     "RouterName",
+	"browser":         "Browser",
+	"browser_version": "BrowserVersion",
+	"os":              "OS",
+	"os_version":      "OSVersion",
+	"device_type":     "DeviceType",
+	"trace_id":        "TraceID",
+	"geo_country":     "GeoCountry",
+	"geo_city":        "GeoCity",
+	"geo_lat":         "GeoLat",
+	"geo_lon":         "GeoLon",
+	"geo_eu":          "GeoEU",
+	"asn":             "ASN",
+	"asn_org":         "ASNOrg",
+	"proxy_metadata":  "ProxyMetadata",
+}
+
+// Enricher is an enricher plugin, called for every parsed request after the built-in enrichment
+type Enricher struct {
+	*process
+	info Info
+}
+
+// Name returns the name of the plugin
+func (e *Enricher) Name() string {
+	return e.info.Name
+}
+
+// Info returns what the plugin told about itself in the handshake
+func (e *Enricher) Info() Info {
+	return e.info
+}
+
+// Enrich sends the request to the plugin and sets the fields it answers, {"fields": {"asn_org": "..."}}
+// Fields that can't be enriched are ignored and reported in the error, after the others were set.
+func (e *Enricher) Enrich(request *models.HTTPRequest) error {
+	var result struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := e.call(methodEnrich, map[string]any{"request": eventFromRequest(request)}, &result); err != nil {
+		return err
+	}
+
+	requestValue := reflect.ValueOf(request).Elem()
+	var rejected []string
+	for key, raw := range result.Fields {
+		name, ok := enrichableFields[key]
+		if !ok {
+			rejected = append(rejected, key)
+			continue
+		}
+		field := requestValue.FieldByName(name)
+		value := reflect.New(field.Type())
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			rejected = append(rejected, key)
+			continue
+		}
+		field.Set(value.Elem())
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return fmt.Errorf("plugin returned fields that can't be enriched: %s", strings.Join(rejected, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"reflect"
+	"time"
+
+	"loglynx/internal/database/models"
+)
+
+// HTTPRequestEvent is a request as exchanged with plugins: returned by parser plugins, sent to enricher plugins
+// Field names match the HTTPRequest columns, so events are stored like those of the built-in parsers.
+type HTTPRequestEvent struct {
+	Timestamp  time.Time `json:"timestamp"` // RFC 3339, required
+	SourceName string    `json:"source_name,omitempty"`
+
+	ClientIP   string `json:"client_ip"`
+	ClientPort int    `json:"client_port,omitempty"`
+	ClientUser string `json:"client_user,omitempty"`
+
+	Method        string `json:"method"`
+	Protocol      string `json:"protocol,omitempty"`
+	Host          string `json:"host"`
+	Path          string `json:"path"`
+	QueryString   string `json:"query_string,omitempty"`
+	RequestLength int64  `json:"request_length,omitempty"`
+	RequestScheme string `json:"request_scheme,omitempty"`
+
+	StatusCode          int     `json:"status_code"`
+	ResponseSize        int64   `json:"response_size,omitempty"`
+	ResponseTimeMs      float64 `json:"response_time_ms,omitempty"`
+	ResponseContentType string  `json:"response_content_type,omitempty"`
+
+	Duration               int64   `json:"duration,omitempty"`  // Nanoseconds, makes dedup hashes precise
+	StartUTC               string  `json:"start_utc,omitempty"` // RFC 3339 with nanoseconds, makes dedup hashes precise
+	UpstreamResponseTimeMs float64 `json:"upstream_response_time_ms,omitempty"`
+	RetryAttempts          int     `json:"retry_attempts,omitempty"`
+
+	UserAgent           string `json:"user_agent,omitempty"`
+	Referer             string `json:"referer,omitempty"`
+	RequestHeaderBytes  int64  `json:"request_header_bytes,omitempty"`
+	ResponseHeaderBytes int64  `json:"response_header_bytes,omitempty"`
+
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceType     string `json:"device_type,omitempty"`
+
+	BackendName         string `json:"backend_name,omitempty"`
+	BackendURL          string `json:"backend_url,omitempty"`
+	RouterName          string `json:"router_name,omitempty"`
+	UpstreamStatus      int    `json:"upstream_status,omitempty"`
+	UpstreamContentType string `json:"upstream_content_type,omitempty"`
+	UpstreamAddr        string `json:"upstream_addr,omitempty"`
+	ClientHostname      string `json:"client_hostname,omitempty"`
+
+	TLSVersion    string `json:"tls_version,omitempty"`
+	TLSCipher     string `json:"tls_cipher,omitempty"`
+	TLSServerName string `json:"tls_server_name,omitempty"`
+
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+
+	GeoCountry string  `json:"geo_country,omitempty"`
+	GeoCity    string  `json:"geo_city,omitempty"`
+	GeoLat     float64 `json:"geo_lat,omitempty"`
+	GeoLon     float64 `json:"geo_lon,omitempty"`
+	GeoEU      bool    `json:"geo_eu,omitempty"`
+	ASN        int     `json:"asn,omitempty"`
+	ASNOrg     string  `json:"asn_org,omitempty"`
+
+	ProxyMetadata string `json:"proxy_metadata,omitempty"` // JSON object as string
+}
+
+func (e *HTTPRequestEvent) GetTimestamp() time.Time { return e.Timestamp }
+
+func (e *HTTPRequestEvent) GetSourceName() string { return e.SourceName }
+
+// HasPreciseTiming reports whether the event carries nanosecond timing, else dedup hashes use line offsets
+func (e *HTTPRequestEvent) HasPreciseTiming() bool {
+	return e.Duration > 0 || e.StartUTC != ""
+}
+
+// sanitize clears values the database constraints reject, so one bad event doesn't fail its batch
+func (e *HTTPRequestEvent) sanitize() {
+	if e.StatusCode < 0 || e.StatusCode >= 600 {
+		e.StatusCode = 0
+	}
+	if e.UpstreamStatus < 0 || e.UpstreamStatus >= 600 {
+		e.UpstreamStatus = 0
+	}
+	if e.ClientPort < 0 || e.ClientPort > 65535 {
+		e.ClientPort = 0
+	}
+	switch e.RequestScheme {
+	case "", "http", "https", "ws", "wss":
+	default:
+		e.RequestScheme = ""
+	}
+	for _, value := range []*int64{&e.RequestLength, &e.ResponseSize, &e.Duration, &e.RequestHeaderBytes, &e.ResponseHeaderBytes} {
+		*value = max(*value, 0)
+	}
+	for _, value := range []*float64{&e.ResponseTimeMs, &e.UpstreamResponseTimeMs} {
+		*value = max(*value, 0)
+	}
+	e.RetryAttempts = max(e.RetryAttempts, 0)
+}
+
+// eventFromRequest copies the fields of a stored request to an event, for enricher plugins
+func eventFromRequest(request *models.HTTPRequest) *HTTPRequestEvent {
+	event := &HTTPRequestEvent{}
+	eventValue := reflect.ValueOf(event).Elem()
+	requestValue := reflect.ValueOf(request).Elem()
+	for i := 0; i < eventValue.NumField(); i++ {
+		field := eventValue.Type().Field(i)
+		if requestField := requestValue.FieldByName(field.Name); requestField.IsValid() && requestField.Type() == field.Type {
+			eventValue.Field(i).Set(requestField)
+		}
+	}
+	return event
+}
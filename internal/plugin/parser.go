@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"errors"
+	"strings"
+
+	parsers "loglynx/internal/parser"
+)
+
+// Parser is a parser plugin, log sources use it with the parser type of its handshake
+type Parser struct {
+	*process
+	info Info
+}
+
+// Name returns the parser type of the plugin
+func (p *Parser) Name() string {
+	return p.info.ParserType
+}
+
+// Info returns what the plugin told about itself in the handshake
+func (p *Parser) Info() Info {
+	return p.info
+}
+
+// CanParse accepts every non-empty line, the plugin skips the lines it doesn't understand
+func (p *Parser) CanParse(line string) bool {
+	return strings.TrimSpace(line) != ""
+}
+
+// Parse asks the plugin for the request of a line
+// The plugin answers {"event": {...}}, or {"skip": true} for lines carrying no request (ErrSkipLine).
+func (p *Parser) Parse(line string) (parsers.Event, error) {
+	var result struct {
+		Event *HTTPRequestEvent `json:"event"`
+		Skip  bool              `json:"skip"`
+	}
+	if err := p.call(methodParse, map[string]string{"line": line}, &result); err != nil {
+		return nil, err
+	}
+	if result.Skip || result.Event == nil {
+		return nil, parsers.ErrSkipLine
+	}
+	if result.Event.Timestamp.IsZero() {
+		return nil, errors.New("plugin returned an event without timestamp")
+	}
+	result.Event.sanitize()
+	return result.Event, nil
+}
+
+// NewEvent returns an empty plugin event for field mapping checks
+func (p *Parser) NewEvent() parsers.Event {
+	return &HTTPRequestEvent{}
+}
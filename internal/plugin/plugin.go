@@ -0,0 +1,103 @@
+// Package plugin runs third-party parsers and enrichers as subprocesses, so LogLynx can be extended
+// without forking. Plugins talk JSON lines over stdin/stdout: a handshake checking the protocol and
+// LogLynx versions, then one parse or enrich call at a time. Any language works and plugins don't
+// depend on the Go toolchain LogLynx was built with, unlike Go plugin .so files.
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+// DefaultTimeout is how long a plugin may take to answer a call
+const DefaultTimeout = 5 * time.Second
+
+// ParseCommands parses a semicolon-separated list of plugin commands, arguments separated by spaces
+// e.g. "/opt/loglynx/plugins/haproxy;/opt/loglynx/plugins/threat-intel --feed /data/feed.csv"
+func ParseCommands(list string) [][]string {
+	var commands [][]string
+	for _, entry := range strings.Split(list, ";") {
+		if command := strings.Fields(entry); len(command) > 0 {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// Set holds the running plugins
+type Set struct {
+	Parsers   []*Parser
+	Enrichers []*Enricher
+	logger    *pterm.Logger
+}
+
+// Load starts the plugins and checks their handshake against the protocol and LogLynx version
+// Plugins failing to start or incompatible are stopped and skipped with a warning.
+func Load(commands [][]string, timeout time.Duration, loglynxVersion string, logger *pterm.Logger) *Set {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	set := &Set{logger: logger}
+	for _, command := range commands {
+		p, info, err := start(command, timeout, loglynxVersion, logger)
+		if err != nil {
+			logger.Warn("Plugin not loaded", logger.Args("command", strings.Join(command, " "), "error", err))
+			continue
+		}
+		logger.Info("Loaded plugin", logger.Args("name", info.Name, "version", info.Version, "kind", info.Kind, "parser_type", info.ParserType))
+
+		switch info.Kind {
+		case KindParser:
+			set.Parsers = append(set.Parsers, &Parser{process: p, info: *info})
+		case KindEnricher:
+			set.Enrichers = append(set.Enrichers, &Enricher{process: p, info: *info})
+		}
+	}
+	return set
+}
+
+// start starts a plugin and performs the handshake, the process is stopped when it fails
+func start(command []string, timeout time.Duration, loglynxVersion string, logger *pterm.Logger) (*process, *Info, error) {
+	p, err := startProcess(command, timeout, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	var info Info
+	err = p.call(methodHandshake, handshakeParams{Protocol: ProtocolVersion, LogLynxVersion: loglynxVersion}, &info)
+	if err == nil {
+		err = info.check(loglynxVersion)
+	}
+	if err != nil {
+		p.Close()
+		return nil, nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	return p, &info, nil
+}
+
+// Register adds the parser plugins to the registry, plugins can't replace parsers already registered
+func (s *Set) Register(registry *parsers.Registry) {
+	for _, parser := range s.Parsers {
+		if _, exists := registry.GetAll()[parser.Name()]; exists {
+			s.logger.Warn("Parser plugin type already registered, skipping it",
+				s.logger.Args("plugin", parser.info.Name, "parser_type", parser.Name()))
+			continue
+		}
+		registry.Register(parser.Name(), parser)
+		s.logger.Debug("Registered parser", s.logger.Args("type", parser.Name(), "plugin", parser.info.Name))
+	}
+}
+
+// Close stops the plugins, used on shutdown
+func (s *Set) Close() {
+	for _, parser := range s.Parsers {
+		parser.Close()
+	}
+	for _, enricher := range s.Enrichers {
+		enricher.Close()
+	}
+}
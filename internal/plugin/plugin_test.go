@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	parsers "loglynx/internal/parser"
+
+	"github.com/pterm/pterm"
+)
+
+// fakePluginEnv makes the test binary act as a plugin, its value selects the behavior
+const fakePluginEnv = "LOGLYNX_FAKE_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if kind := os.Getenv(fakePluginEnv); kind != "" {
+		runFakePlugin(kind)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runFakePlugin answers the protocol on stdin/stdout like a real plugin
+func runFakePlugin(kind string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var call struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid call:", err)
+			continue
+		}
+		var result any
+		switch call.Method {
+		case methodHandshake:
+			info := Info{Protocol: ProtocolVersion, Name: "fake-" + kind, Version: "0.1.0", Kind: KindEnricher}
+			switch kind {
+			case "parser", "slow":
+				info.Kind, info.ParserType = KindParser, "fake"
+			case "future":
+				info.MinLogLynxVersion = "99.0.0"
+			}
+			result = info
+		case methodParse:
+			var params struct {
+				Line string `json:"line"`
+			}
+			json.Unmarshal(call.Params, &params)
+			if kind == "slow" {
+				time.Sleep(time.Second)
+			}
+			if params.Line == "# comment" {
+				result = map[string]bool{"skip": true}
+			} else {
+				result = map[string]any{"event": map[string]any{
+					"timestamp": "2026-03-01T10:00:00Z", "client_ip": "203.0.113.7", "method": "GET",
+					"host": "example.com", "path": params.Line, "status_code": 200, "client_port": 70000,
+				}}
+			}
+		case methodEnrich:
+			result = map[string]any{"fields": map[string]any{"asn_org": "Example Net", "asn": 64500, "status_code": 500}}
+		}
+		encoder.Encode(map[string]any{"id": call.ID, "result": result})
+	}
+}
+
+func loadFake(t *testing.T, kind string, timeout time.Duration) *Set {
+	t.Helper()
+	t.Setenv(fakePluginEnv, kind)
+	set := Load([][]string{{os.Args[0]}}, timeout, "1.0.1", pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	t.Cleanup(set.Close)
+	return set
+}
+
+func TestParserPlugin(t *testing.T) {
+	set := loadFake(t, "parser", 0)
+	if len(set.Parsers) != 1 || len(set.Enrichers) != 0 {
+		t.Fatalf("loaded %d parsers and %d enrichers, want 1 parser", len(set.Parsers), len(set.Enrichers))
+	}
+
+	registry := parsers.NewRegistry(nil, nil, pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	set.Register(registry)
+	parser, err := registry.Get("fake")
+	if err != nil {
+		t.Fatalf("parser plugin not registered: %v", err)
+	}
+
+	event, err := parser.Parse("/index.html")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	request := event.(*HTTPRequestEvent)
+	if request.Path != "/index.html" || request.StatusCode != 200 || !request.Timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Parse() = %+v", request)
+	}
+	if request.ClientPort != 0 {
+		t.Errorf("invalid client port %d not cleared", request.ClientPort)
+	}
+
+	if _, err := parser.Parse("# comment"); !errors.Is(err, parsers.ErrSkipLine) {
+		t.Errorf("Parse() of skipped line error = %v, want ErrSkipLine", err)
+	}
+}
+
+func TestEnricherPlugin(t *testing.T) {
+	set := loadFake(t, "enricher", 0)
+	if len(set.Enrichers) != 1 {
+		t.Fatalf("loaded %d enrichers, want 1", len(set.Enrichers))
+	}
+
+	request := &models.HTTPRequest{ClientIP: "203.0.113.7", StatusCode: 200}
+	err := set.Enrichers[0].Enrich(request)
+	if err == nil {
+		t.Error("Enrich() accepted a field that can't be enriched")
+	}
+	if request.ASNOrg != "Example Net" || request.ASN != 64500 {
+		t.Errorf("enriched ASN = %d %q, want 64500 \"Example Net\"", request.ASN, request.ASNOrg)
+	}
+	if request.StatusCode != 200 {
+		t.Errorf("status code changed to %d", request.StatusCode)
+	}
+}
+
+func TestIncompatiblePluginNotLoaded(t *testing.T) {
+	set := loadFake(t, "future", 0)
+	if len(set.Parsers)+len(set.Enrichers) != 0 {
+		t.Error("plugin requiring a newer LogLynx was loaded")
+	}
+
+	set = Load([][]string{{"/nonexistent/loglynx-plugin"}}, 0, "1.0.1", pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled))
+	if len(set.Parsers)+len(set.Enrichers) != 0 {
+		t.Error("missing plugin command was loaded")
+	}
+}
+
+func TestPluginTimeout(t *testing.T) {
+	set := loadFake(t, "slow", 200*time.Millisecond)
+	if len(set.Parsers) != 1 {
+		t.Fatal("slow plugin not loaded")
+	}
+	if _, err := set.Parsers[0].Parse("/slow"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Parse() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	commands := ParseCommands(" /opt/a ; ;/opt/b --feed /data/feed.csv;")
+	if len(commands) != 2 || len(commands[0]) != 1 || len(commands[1]) != 3 || commands[1][2] != "/data/feed.csv" {
+		t.Errorf("ParseCommands() = %q", commands)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.1", "1.0.1", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"v1.0", "1.0.0", 0},
+		{"1.0.1-rc1", "1.1", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// ErrExited is returned by calls to a plugin whose process exited
+var ErrExited = errors.New("plugin process exited")
+
+// ErrTimeout is returned when a plugin doesn't answer a call in time
+var ErrTimeout = errors.New("plugin did not answer in time")
+
+// maxMessageSize bounds a message of a plugin, a request with its headers fits easily
+const maxMessageSize = 1024 * 1024
+
+// closeTimeout is how long a plugin has to exit after its stdin was closed, before it is killed
+const closeTimeout = 2 * time.Second
+
+// process is a running plugin, answering one call at a time over stdin/stdout
+type process struct {
+	name    string // Command, for logs
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	timeout time.Duration
+	logger  *pterm.Logger
+
+	responses  chan response // Closed when stdout reaches EOF
+	readerDone chan struct{} // Closed when the stdout reader returned
+	done       chan struct{} // Closed by Close, stops the stdout reader
+	closeOnce  sync.Once
+
+	mu     sync.Mutex // Calls are serialized, the protocol has one request in flight
+	nextID uint64
+}
+
+// startProcess starts a plugin command, its stderr is logged
+func startProcess(command []string, timeout time.Duration, logger *pterm.Logger) (*process, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.WaitDelay = closeTimeout // Children of the plugin keeping its pipes open don't block shutdown
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		name:       command[0],
+		cmd:        cmd,
+		stdin:      stdin,
+		timeout:    timeout,
+		logger:     logger,
+		responses:  make(chan response),
+		readerDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go p.readResponses(stdout)
+	go p.logStderr(stderr)
+	return p, nil
+}
+
+// readResponses forwards the messages of the plugin to the pending call
+func (p *process) readResponses(stdout io.Reader) {
+	defer close(p.readerDone)
+	defer close(p.responses)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var message response
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			p.logger.Warn("Invalid plugin message", p.logger.Args("plugin", p.name, "error", err))
+			continue
+		}
+		select {
+		case p.responses <- message:
+		case <-p.done:
+			return
+		}
+	}
+	select {
+	case <-p.done:
+		return // Pipes closed by Close
+	default:
+	}
+	if err := scanner.Err(); err != nil {
+		p.logger.Warn("Failed to read plugin output", p.logger.Args("plugin", p.name, "error", err))
+	}
+}
+
+// logStderr logs what the plugin writes to stderr
+func (p *process) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.logger.Info("Plugin: "+scanner.Text(), p.logger.Args("plugin", p.name))
+	}
+}
+
+// call sends a request and decodes the result of its response into result (ignored when nil)
+// A response arriving after its call timed out is dropped by the next call.
+func (p *process) call(method string, params any, result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+	message, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if _, err := p.stdin.Write(append(message, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrExited, err)
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case answer, ok := <-p.responses:
+			if !ok {
+				return ErrExited
+			}
+			if answer.ID != id {
+				continue
+			}
+			if answer.Error != "" {
+				return errors.New(answer.Error)
+			}
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(answer.Result, result)
+		case <-timer.C:
+			return fmt.Errorf("%w (%s after %s)", ErrTimeout, method, p.timeout)
+		}
+	}
+}
+
+// Close closes the plugin's stdin and waits for it to exit, killing it when it doesn't
+func (p *process) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		// Plugins exit when their stdin is closed, which closes their stdout
+		p.stdin.Close()
+		close(p.done)
+		select {
+		case <-p.readerDone:
+		case <-time.After(closeTimeout):
+			p.logger.Warn("Plugin did not exit, killing it", p.logger.Args("plugin", p.name))
+			p.cmd.Process.Kill()
+		}
+		err = p.cmd.Wait()
+	})
+	return err
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the version of the plugin protocol, plugins must answer the handshake with the same one
+// It changes when messages change incompatibly; new optional fields keep it.
+const ProtocolVersion = 1
+
+// Plugin kinds
+const (
+	KindParser   = "parser"   // Turns log lines of a new format into requests
+	KindEnricher = "enricher" // Adds data to parsed requests
+)
+
+// Protocol methods
+const (
+	methodHandshake = "handshake"
+	methodParse     = "parse"
+	methodEnrich    = "enrich"
+)
+
+// request is a call sent to a plugin on stdin, one JSON object per line
+type request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+// response is the answer of a plugin on stdout, one JSON object per line with the ID of the request
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// handshakeParams tells a starting plugin which protocol and LogLynx version it talks to
+type handshakeParams struct {
+	Protocol       int    `json:"protocol"`
+	LogLynxVersion string `json:"loglynx_version"`
+}
+
+// Info is what a plugin tells about itself in the handshake
+type Info struct {
+	Protocol          int    `json:"protocol"`
+	Name              string `json:"name"`
+	Version           string `json:"version"`
+	Kind              string `json:"kind"`                          // KindParser or KindEnricher
+	ParserType        string `json:"parser_type,omitempty"`         // Parser type of log sources using a parser plugin
+	MinLogLynxVersion string `json:"min_loglynx_version,omitempty"` // Oldest LogLynx version the plugin works with
+}
+
+// check verifies the plugin is compatible with this LogLynx version
+func (i *Info) check(loglynxVersion string) error {
+	if i.Protocol != ProtocolVersion {
+		return fmt.Errorf("plugin speaks protocol %d, LogLynx speaks protocol %d", i.Protocol, ProtocolVersion)
+	}
+	if i.Name == "" {
+		return fmt.Errorf("plugin has no name")
+	}
+	switch i.Kind {
+	case KindParser:
+		if i.ParserType == "" {
+			return fmt.Errorf("parser plugin %s has no parser_type", i.Name)
+		}
+	case KindEnricher:
+	default:
+		return fmt.Errorf("plugin %s has unknown kind %q (expected %s or %s)", i.Name, i.Kind, KindParser, KindEnricher)
+	}
+	if i.MinLogLynxVersion != "" && compareVersions(loglynxVersion, i.MinLogLynxVersion) < 0 {
+		return fmt.Errorf("plugin %s requires LogLynx %s or later, this is %s", i.Name, i.MinLogLynxVersion, loglynxVersion)
+	}
+	return nil
+}
+
+// compareVersions compares dotted numeric versions ("1.2.10" > "1.2.9"), ignoring a "v" prefix and suffixes
+// such as "-rc1"; missing components count as 0
+func compareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "-")
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, _ := strconv.Atoi(part)
+		parts = append(parts, number)
+	}
+	return parts
+}
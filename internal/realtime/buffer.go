@@ -0,0 +1,139 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"loglynx/internal/database/models"
+)
+
+// DefaultBufferCapacity is the number of recent events kept in memory when no size is configured
+const DefaultBufferCapacity = 50000
+
+// BufferRetention is how far back events are kept in the ring buffer
+// Events older than this (e.g. historical lines during initial import) are not recorded
+const BufferRetention = 5 * time.Minute
+
+// Event is a lightweight copy of an inserted request used for real-time aggregation
+type Event struct {
+	Timestamp      time.Time
+	ClientIP       string
+	Method         string
+	Host           string
+	Path           string
+	BackendName    string
+	BackendURL     string
+	StatusCode     int
+	ResponseTimeMs float64
+	Protocol       string
+	UserAgent      string
+	Browser        string
+	OS             string
+}
+
+// EventBuffer is a fixed-size ring buffer of recently ingested events
+type EventBuffer struct {
+	mu     sync.RWMutex
+	events []Event
+	next   int  // index of the next slot to write
+	full   bool // true once the buffer has wrapped around
+
+	// evictedNewest is the newest timestamp among overwritten events: ranges after it are complete
+	evictedNewest time.Time
+}
+
+// NewEventBuffer creates a new ring buffer with the given capacity
+func NewEventBuffer(capacity int) *EventBuffer {
+	if capacity <= 0 {
+		capacity = DefaultBufferCapacity
+	}
+	return &EventBuffer{
+		events: make([]Event, capacity),
+	}
+}
+
+// Add appends events to the buffer, overwriting the oldest entries when full
+func (b *EventBuffer) Add(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		if b.full && b.events[b.next].Timestamp.After(b.evictedNewest) {
+			b.evictedNewest = b.events[b.next].Timestamp
+		}
+		b.events[b.next] = event
+		b.next++
+		if b.next == len(b.events) {
+			b.next = 0
+			b.full = true
+		}
+	}
+}
+
+// Since returns a copy of the buffered events with a timestamp after the given time, in arrival order
+// Events don't arrive in timestamp order (sources flush independently, long requests are logged under their
+// start time), so the whole buffer is scanned.
+func (b *EventBuffer) Since(since time.Time) []Event {
+	events, _ := b.SinceComplete(since)
+	return events
+}
+
+// SinceComplete is Since, also telling whether the range is complete
+// It is not when an overwritten event belonged to the range.
+func (b *EventBuffer) SinceComplete(since time.Time) ([]Event, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	size := b.next
+	if b.full {
+		size = len(b.events)
+	}
+
+	// Walk the occupied slots oldest first
+	start := 0
+	if b.full {
+		start = b.next
+	}
+	var result []Event
+	for i := 0; i < size; i++ {
+		event := b.events[(start+i)%len(b.events)]
+		if event.Timestamp.After(since) {
+			result = append(result, event)
+		}
+	}
+	return result, !b.evictedNewest.After(since)
+}
+
+// Len returns the number of events currently held in the buffer
+func (b *EventBuffer) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.full {
+		return len(b.events)
+	}
+	return b.next
+}
+
+// newEvent converts a database model into a buffered event
+func newEvent(req *models.HTTPRequest) Event {
+	return Event{
+		Timestamp:      req.Timestamp,
+		ClientIP:       req.ClientIP,
+		Method:         req.Method,
+		Host:           req.Host,
+		Path:           req.Path,
+		BackendName:    req.BackendName,
+		BackendURL:     req.BackendURL,
+		StatusCode:     req.StatusCode,
+		ResponseTimeMs: req.ResponseTimeMs,
+		Protocol:       req.Protocol,
+		UserAgent:      req.UserAgent,
+		Browser:        req.Browser,
+		OS:             req.OS,
+	}
+}
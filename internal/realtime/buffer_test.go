@@ -0,0 +1,65 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBuffer_Since(t *testing.T) {
+	start := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	buffer := NewEventBuffer(4)
+
+	if events := buffer.Since(start); len(events) != 0 {
+		t.Fatalf("Expected no events in an empty buffer, got %d", len(events))
+	}
+
+	// Six events one second apart wrap the buffer: only the last four are kept
+	for i := 0; i < 6; i++ {
+		buffer.Add([]Event{{Timestamp: start.Add(time.Duration(i) * time.Second), StatusCode: 200 + i}})
+	}
+	if buffer.Len() != 4 {
+		t.Fatalf("Expected 4 buffered events, got %d", buffer.Len())
+	}
+
+	events := buffer.Since(start.Add(3500 * time.Millisecond))
+	if len(events) != 2 || events[0].StatusCode != 204 || events[1].StatusCode != 205 {
+		t.Errorf("Expected the events of seconds 4 and 5 oldest first, got %+v", events)
+	}
+	if events := buffer.Since(start); len(events) != 4 || events[0].StatusCode != 202 {
+		t.Errorf("Expected the 4 kept events oldest first, got %+v", events)
+	}
+	if events := buffer.Since(start.Add(5 * time.Second)); len(events) != 0 {
+		t.Errorf("Expected no event after the newest one, got %+v", events)
+	}
+}
+
+func TestEventBuffer_SinceOutOfOrder(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	buffer := NewEventBuffer(4)
+
+	// A long request logged under its start time arrives after newer events, then a later flush
+	buffer.Add([]Event{
+		{Timestamp: now.Add(-10 * time.Second), StatusCode: 200},
+		{Timestamp: now.Add(-5 * time.Second), StatusCode: 201},
+	})
+	buffer.Add([]Event{{Timestamp: now.Add(-2 * time.Minute), StatusCode: 202}})
+	buffer.Add([]Event{{Timestamp: now.Add(-3 * time.Second), StatusCode: 203}})
+
+	events, complete := buffer.SinceComplete(now.Add(-time.Minute))
+	if len(events) != 3 || events[0].StatusCode != 200 || events[2].StatusCode != 203 {
+		t.Errorf("Expected the 3 events of the last minute past the late one, got %+v", events)
+	}
+	if !complete {
+		t.Error("Expected a complete range before the buffer wraps")
+	}
+
+	// Overwriting the event of -10s makes the last minute incomplete, not the last 8 seconds
+	buffer.Add([]Event{{Timestamp: now.Add(-time.Second), StatusCode: 204}})
+	if _, complete := buffer.SinceComplete(now.Add(-time.Minute)); complete {
+		t.Error("Expected an incomplete range after overwriting one of its events")
+	}
+	events, complete = buffer.SinceComplete(now.Add(-8 * time.Second))
+	if len(events) != 3 || !complete {
+		t.Errorf("Expected the 3 events of the last 8 seconds, complete, got %+v (complete %v)", events, complete)
+	}
+}
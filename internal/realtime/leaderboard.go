@@ -0,0 +1,129 @@
+package realtime
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"loglynx/internal/filter"
+)
+
+// Supported leaderboard windows
+const (
+	LeaderboardWindow1m = time.Minute
+	LeaderboardWindow5m = 5 * time.Minute
+)
+
+// DefaultLeaderboardLimit is the number of entries returned per leaderboard
+const DefaultLeaderboardLimit = 10
+
+// LeaderboardEntry represents a single ranked item in a leaderboard
+type LeaderboardEntry struct {
+	Key   string  `json:"key"`
+	Count int64   `json:"count"`
+	Rate  float64 `json:"rate"` // req/sec over the window
+}
+
+// Leaderboards contains the rolling "what's hot right now" rankings
+type Leaderboards struct {
+	Window         string             `json:"window"`
+	WindowSeconds  float64            `json:"window_seconds"` // Seconds actually covered, less than the window when the buffer wrapped inside it
+	Truncated      bool               `json:"truncated"`      // The buffer (REALTIME_BUFFER_SIZE) doesn't hold the whole window
+	TotalRequests  int64              `json:"total_requests"`
+	TopIPs         []LeaderboardEntry `json:"top_ips"`
+	TopPaths       []LeaderboardEntry `json:"top_paths"`
+	TopStatusCodes []LeaderboardEntry `json:"top_status_codes"`
+	Timestamp      time.Time          `json:"timestamp"`
+}
+
+// ParseLeaderboardWindow converts a window name ("1m", "5m") into a duration
+// Unknown values fall back to the 1-minute window
+func ParseLeaderboardWindow(window string) time.Duration {
+	if window == "5m" {
+		return LeaderboardWindow5m
+	}
+	return LeaderboardWindow1m
+}
+
+// GetLeaderboards returns the top IPs, paths and status codes over the given window
+// Computed from the in-memory event buffer, window is capped to BufferRetention. When traffic outgrows the buffer,
+// counts and rates cover the part of the window it still holds.
+func (m *MetricsCollector) GetLeaderboards(window time.Duration, limit int, serviceFilters []filter.Service, excludeIPFilter *filter.ExcludeIP) *Leaderboards {
+	if window <= 0 || window > BufferRetention {
+		window = LeaderboardWindow1m
+	}
+	if limit <= 0 {
+		limit = DefaultLeaderboardLimit
+	}
+
+	now := m.clock.Now()
+	events, complete := m.buffer.SinceComplete(now.Add(-window))
+	covered := window
+	if !complete && len(events) > 0 {
+		oldest := events[0].Timestamp
+		for _, event := range events[1:] {
+			if event.Timestamp.Before(oldest) {
+				oldest = event.Timestamp
+			}
+		}
+		covered = max(now.Sub(oldest), time.Second)
+	}
+
+	ipCounts := make(map[string]int64)
+	pathCounts := make(map[string]int64)
+	statusCounts := make(map[string]int64)
+	var total int64
+
+	for _, event := range events {
+		if len(serviceFilters) > 0 && !filter.MatchesAny(serviceFilters, event.BackendName, event.BackendURL, event.Host) {
+			continue
+		}
+		if excludeIPFilter.Excludes(event.ClientIP, event.BackendName, event.BackendURL, event.Host) {
+			continue
+		}
+
+		total++
+		ipCounts[event.ClientIP]++
+		pathCounts[event.Path]++
+		statusCounts[strconv.Itoa(event.StatusCode)]++
+	}
+
+	seconds := covered.Seconds()
+	return &Leaderboards{
+		Window:         window.String(),
+		WindowSeconds:  seconds,
+		Truncated:      !complete,
+		TotalRequests:  total,
+		TopIPs:         rankCounts(ipCounts, limit, seconds),
+		TopPaths:       rankCounts(pathCounts, limit, seconds),
+		TopStatusCodes: rankCounts(statusCounts, limit, seconds),
+		Timestamp:      now,
+	}
+}
+
+// rankCounts sorts counters by descending count (ties by key) and keeps the top entries
+func rankCounts(counts map[string]int64, limit int, seconds float64) []LeaderboardEntry {
+	entries := make([]LeaderboardEntry, 0, len(counts))
+	for key, count := range counts {
+		if key == "" {
+			continue
+		}
+		entries = append(entries, LeaderboardEntry{
+			Key:   key,
+			Count: count,
+			Rate:  float64(count) / seconds,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"loglynx/internal/clock"
+	"loglynx/internal/database/models"
+
+	"github.com/pterm/pterm"
+)
+
+func TestGetLeaderboards(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	collector := NewMetricsCollector(pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled), 100, nil, clock.NewFake(now))
+
+	// Three minutes ago one request, in the last minute 6 from 10.0.0.1 and 3 from 10.0.0.2
+	batch := []*models.HTTPRequest{{Timestamp: now.Add(-3 * time.Minute), ClientIP: "10.0.0.9", Path: "/old", StatusCode: 200}}
+	for i := 0; i < 9; i++ {
+		request := &models.HTTPRequest{Timestamp: now.Add(time.Duration(i-30) * time.Second), ClientIP: "10.0.0.1", Path: "/", StatusCode: 200}
+		if i%3 == 2 {
+			request.ClientIP, request.Path, request.StatusCode = "10.0.0.2", "/login", 401
+		}
+		batch = append(batch, request)
+	}
+	collector.Record(batch)
+
+	top := collector.GetLeaderboards(LeaderboardWindow1m, 10, nil, nil)
+	if top.TotalRequests != 9 || top.Truncated || top.WindowSeconds != 60 {
+		t.Fatalf("Expected 9 requests over a complete minute, got %d over %.0fs (truncated %v)", top.TotalRequests, top.WindowSeconds, top.Truncated)
+	}
+	if len(top.TopIPs) != 2 || top.TopIPs[0].Key != "10.0.0.1" || top.TopIPs[0].Count != 6 || top.TopIPs[0].Rate != 0.1 {
+		t.Errorf("Unexpected top IPs %+v", top.TopIPs)
+	}
+	if len(top.TopStatusCodes) != 2 || top.TopStatusCodes[1].Key != "401" || top.TopStatusCodes[1].Count != 3 {
+		t.Errorf("Unexpected top status codes %+v", top.TopStatusCodes)
+	}
+
+	if top := collector.GetLeaderboards(LeaderboardWindow5m, 1, nil, nil); top.TotalRequests != 10 || len(top.TopPaths) != 1 || top.TopPaths[0].Key != "/" {
+		t.Errorf("Expected 10 requests over 5 minutes with / on top, got %d %+v", top.TotalRequests, top.TopPaths)
+	}
+}
+
+func TestGetLeaderboards_BufferWrappedInsideWindow(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	collector := NewMetricsCollector(pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled), 10, nil, clock.NewFake(now))
+
+	// 40 requests over the last 4 minutes, the buffer only holds the 10 of the last minute
+	var batch []*models.HTTPRequest
+	for i := 40; i > 0; i-- {
+		batch = append(batch, &models.HTTPRequest{Timestamp: now.Add(-time.Duration(i) * 6 * time.Second), ClientIP: "10.0.0.1", StatusCode: 200})
+	}
+	collector.Record(batch)
+
+	top := collector.GetLeaderboards(LeaderboardWindow5m, 10, nil, nil)
+	if !top.Truncated || top.TotalRequests != 10 || top.WindowSeconds != 60 {
+		t.Fatalf("Expected 10 requests over the 60s still buffered, got %d over %.0fs (truncated %v)", top.TotalRequests, top.WindowSeconds, top.Truncated)
+	}
+	if top.TopIPs[0].Rate != 10.0/60 {
+		t.Errorf("Expected the rate over the covered 60s, got %f", top.TopIPs[0].Rate)
+	}
+}
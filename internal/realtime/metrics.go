@@ -5,16 +5,23 @@ import (
 	"sync"
 	"time"
 
+	"loglynx/internal/clock"
+	"loglynx/internal/database/models"
 	"loglynx/internal/database/repositories"
+	"loglynx/internal/filter"
 
 	"github.com/pterm/pterm"
-	"gorm.io/gorm"
 )
 
 // MetricsCollector collects real-time metrics
+// Metrics are computed from an in-memory ring buffer fed by the ingestion pipeline,
+// so no database queries are needed to serve the realtime endpoints
 type MetricsCollector struct {
-	db     *gorm.DB
-	logger *pterm.Logger
+	buffer       *EventBuffer
+	window       *SlidingWindow             // Per-second counters for burst detection
+	statusPolicy *repositories.StatusPolicy // Which status codes count as errors
+	clock        clock.Clock
+	logger       *pterm.Logger
 
 	// Current metrics
 	mu                sync.RWMutex
@@ -30,7 +37,9 @@ type MetricsCollector struct {
 
 // RealtimeMetrics represents current real-time statistics
 type RealtimeMetrics struct {
-	RequestRate       float64   `json:"request_rate"`      // req/sec
+	RequestRate       float64   `json:"request_rate"`      // req/sec (60s average)
+	InstantRate       float64   `json:"instant_rate"`      // req/sec of the newest complete second with requests
+	BurstMax          float64   `json:"burst_max"`         // busiest single second in the last 60s (req/sec)
 	ErrorRate         float64   `json:"error_rate"`        // errors/sec
 	AvgResponseTime   float64   `json:"avg_response_time"` // ms
 	ActiveConnections int       `json:"active_connections"`
@@ -41,14 +50,39 @@ type RealtimeMetrics struct {
 }
 
 // NewMetricsCollector creates a new real-time metrics collector
-func NewMetricsCollector(db *gorm.DB, logger *pterm.Logger) *MetricsCollector {
+// bufferSize is the number of recent events kept in memory (0 = DefaultBufferCapacity)
+// statusPolicy defines error responses per service (nil = all 4xx/5xx), clk is the current time (nil = system clock)
+func NewMetricsCollector(logger *pterm.Logger, bufferSize int, statusPolicy *repositories.StatusPolicy, clk clock.Clock) *MetricsCollector {
+	clk = clock.OrSystem(clk)
 	return &MetricsCollector{
-		db:         db,
-		logger:     logger,
-		lastUpdate: time.Now(),
+		buffer:       NewEventBuffer(bufferSize),
+		window:       NewSlidingWindow(),
+		statusPolicy: statusPolicy,
+		clock:        clk,
+		logger:       logger,
+		lastUpdate:   clk.Now(),
 	}
 }
 
+// Record adds a batch of successfully inserted requests to the ring buffer
+// Called by the ingestion pipeline after each flush
+func (m *MetricsCollector) Record(batch []*models.HTTPRequest) {
+	now := m.clock.Now()
+	cutoff := now.Add(-BufferRetention)
+
+	events := make([]Event, 0, len(batch))
+	for _, req := range batch {
+		// Skip historical lines (initial import, catch-up after restart)
+		if req.Timestamp.Before(cutoff) {
+			continue
+		}
+		events = append(events, newEvent(req))
+		m.window.Add(req.Timestamp, now)
+	}
+
+	m.buffer.Add(events)
+}
+
 // Start begins collecting metrics at regular intervals
 func (m *MetricsCollector) Start(interval time.Duration) {
 	ticker := time.NewTicker(interval)
@@ -58,52 +92,19 @@ func (m *MetricsCollector) Start(interval time.Duration) {
 		}
 	}()
 	m.logger.Info("Real-time metrics collector started",
-		m.logger.Args("interval", interval.String()))
+		m.logger.Args("interval", interval.String(), "buffer_capacity", len(m.buffer.events)))
 }
 
-// collectMetrics gathers current statistics from the database
-// Optimized to use a single query instead of 5 separate queries
+// collectMetrics aggregates the last minute of buffered events into the global snapshot
 func (m *MetricsCollector) collectMetrics() {
-	now := time.Now()
-	oneMinuteAgo := now.Add(-1 * time.Minute)
-
-	// Single aggregated query to get all metrics at once
-	type MetricsResult struct {
-		TotalCount  int64   `gorm:"column:total_count"`
-		ErrorCount  int64   `gorm:"column:error_count"`
-		AvgRespTime float64 `gorm:"column:avg_response_time"`
-		Status2xx   int64   `gorm:"column:status_2xx"`
-		Status4xx   int64   `gorm:"column:status_4xx"`
-		Status5xx   int64   `gorm:"column:status_5xx"`
-	}
-
-	var result MetricsResult
-	err := m.db.Table("http_requests").
-		Select(`
-			COUNT(*) as total_count,
-			SUM(CASE WHEN status_code >= 400 THEN 1 ELSE 0 END) as error_count,
-			COALESCE(AVG(response_time_ms), 0) as avg_response_time,
-			SUM(CASE WHEN status_code >= 200 AND status_code < 300 THEN 1 ELSE 0 END) as status_2xx,
-			SUM(CASE WHEN status_code >= 400 AND status_code < 500 THEN 1 ELSE 0 END) as status_4xx,
-			SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as status_5xx
-		`).
-		Where("timestamp > ?", oneMinuteAgo).
-		Scan(&result).Error
-
-	if err != nil {
-		m.logger.Warn("Failed to collect real-time metrics", m.logger.Args("error", err))
-		return
-	}
-
-	// Calculate rates (per second)
-	requestRate := float64(result.TotalCount) / 60.0
-	errorRate := float64(result.ErrorCount) / 60.0
+	now := m.clock.Now()
+	result := aggregateEvents(m.buffer.Since(now.Add(-1*time.Minute)), now, m.statusPolicy)
 
 	// Update metrics with lock
 	m.mu.Lock()
-	m.requestRate = requestRate
-	m.errorRate = errorRate
-	m.avgResponseTime = result.AvgRespTime
+	m.requestRate = result.RequestRate
+	m.errorRate = result.ErrorRate
+	m.avgResponseTime = result.AvgResponseTime
 	m.last2xxCount = result.Status2xx
 	m.last4xxCount = result.Status4xx
 	m.last5xxCount = result.Status5xx
@@ -112,19 +113,61 @@ func (m *MetricsCollector) collectMetrics() {
 
 	m.logger.Trace("Collected real-time metrics",
 		m.logger.Args(
-			"request_rate", requestRate,
-			"error_rate", errorRate,
-			"avg_response_time", result.AvgRespTime,
+			"request_rate", result.RequestRate,
+			"error_rate", result.ErrorRate,
+			"avg_response_time", result.AvgResponseTime,
+			"buffered_events", m.buffer.Len(),
 		))
 }
 
+// aggregateEvents computes rates and status counters over a one-minute window of events
+func aggregateEvents(events []Event, now time.Time, statusPolicy *repositories.StatusPolicy) *RealtimeMetrics {
+	var totalCount, errorCount int64
+	var totalResponseTime float64
+	metrics := &RealtimeMetrics{Timestamp: now}
+	window := NewSlidingWindow()
+
+	for _, event := range events {
+		totalCount++
+		window.Add(event.Timestamp, now)
+		totalResponseTime += event.ResponseTimeMs
+
+		switch {
+		case event.StatusCode >= 200 && event.StatusCode < 300:
+			metrics.Status2xx++
+		case event.StatusCode >= 400 && event.StatusCode < 500:
+			metrics.Status4xx++
+		case event.StatusCode >= 500:
+			metrics.Status5xx++
+		}
+		if statusPolicy.IsFailure(event.BackendName, event.BackendURL, event.Host, event.StatusCode) {
+			errorCount++
+		}
+	}
+
+	// Calculate rates (per second)
+	metrics.RequestRate = float64(totalCount) / 60.0
+	metrics.ErrorRate = float64(errorCount) / 60.0
+	if totalCount > 0 {
+		metrics.AvgResponseTime = totalResponseTime / float64(totalCount)
+	}
+	metrics.InstantRate, metrics.BurstMax = window.Rates(now)
+
+	return metrics
+}
+
 // GetMetrics returns the current metrics snapshot
+// Instant and burst rates are read live from the sliding window, not from the last interval
 func (m *MetricsCollector) GetMetrics() *RealtimeMetrics {
+	instantRate, burstMax := m.window.Rates(m.clock.Now())
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	return &RealtimeMetrics{
 		RequestRate:       m.requestRate,
+		InstantRate:       instantRate,
+		BurstMax:          burstMax,
 		ErrorRate:         m.errorRate,
 		AvgResponseTime:   m.avgResponseTime,
 		ActiveConnections: m.activeConnections,
@@ -135,27 +178,14 @@ func (m *MetricsCollector) GetMetrics() *RealtimeMetrics {
 	}
 }
 
-// ServiceFilter represents a service filter
-type ServiceFilter struct {
-	Name string
-	Type string
-}
-
-// ExcludeIPFilter represents IP exclusion filter
-type ExcludeIPFilter struct {
-	ClientIP        string
-	ExcludeServices []ServiceFilter
-}
-
 // GetMetricsWithHost returns real-time metrics filtered by host
-// This queries the database on-demand to provide accurate per-host metrics
 func (m *MetricsCollector) GetMetricsWithHost(host string) *RealtimeMetrics {
 	return m.GetMetricsWithFilters(host, nil, nil)
 }
 
 // GetMetricsWithFilters returns real-time metrics with service and IP exclusion filters
-func (m *MetricsCollector) GetMetricsWithFilters(host string, serviceFilters []ServiceFilter, excludeIPFilter *ExcludeIPFilter) *RealtimeMetrics {
-	now := time.Now()
+func (m *MetricsCollector) GetMetricsWithFilters(host string, serviceFilters []filter.Service, excludeIPFilter *filter.ExcludeIP) *RealtimeMetrics {
+	now := m.clock.Now()
 	oneMinuteAgo := now.Add(-1 * time.Minute)
 
 	// If no filters specified, return global metrics
@@ -163,120 +193,29 @@ func (m *MetricsCollector) GetMetricsWithFilters(host string, serviceFilters []S
 		return m.GetMetrics()
 	}
 
-	// Query database with filters
-	type MetricsResult struct {
-		TotalCount  int64   `gorm:"column:total_count"`
-		ErrorCount  int64   `gorm:"column:error_count"`
-		AvgRespTime float64 `gorm:"column:avg_response_time"`
-		Status2xx   int64   `gorm:"column:status_2xx"`
-		Status4xx   int64   `gorm:"column:status_4xx"`
-		Status5xx   int64   `gorm:"column:status_5xx"`
-	}
-
-	var result MetricsResult
-	query := m.db.Table("http_requests").
-		Select(`
-			COUNT(*) as total_count,
-			SUM(CASE WHEN status_code >= 400 THEN 1 ELSE 0 END) as error_count,
-			COALESCE(AVG(response_time_ms), 0) as avg_response_time,
-			SUM(CASE WHEN status_code >= 200 AND status_code < 300 THEN 1 ELSE 0 END) as status_2xx,
-			SUM(CASE WHEN status_code >= 400 AND status_code < 500 THEN 1 ELSE 0 END) as status_4xx,
-			SUM(CASE WHEN status_code >= 500 THEN 1 ELSE 0 END) as status_5xx
-		`).
-		Where("timestamp > ?", oneMinuteAgo)
-
-	// Apply host filter (legacy single host filter)
+	// Legacy single host filter matches the host segment of backend_name
+	hostPattern := ""
 	if host != "" {
-		query = query.Where("backend_name LIKE ?", "%-"+strings.ReplaceAll(host, " ", "-")+"-%")
+		hostPattern = "-" + strings.ReplaceAll(host, " ", "-") + "-"
 	}
 
-	// Apply service filters (new multi-service filter)
-	if len(serviceFilters) > 0 {
-		conditions := make([]string, len(serviceFilters))
-		args := make([]interface{}, len(serviceFilters))
-
-		for i, filter := range serviceFilters {
-			switch filter.Type {
-			case "backend_name":
-				conditions[i] = "backend_name = ?"
-				args[i] = filter.Name
-			case "backend_url":
-				conditions[i] = "backend_url = ?"
-				args[i] = filter.Name
-			case "host":
-				conditions[i] = "host = ?"
-				args[i] = filter.Name
-			default:
-				// Auto-detect: try all fields
-				conditions[i] = "(backend_name = ? OR backend_url = ? OR host = ?)"
-				args[i] = filter.Name
-			}
+	events := m.buffer.Since(oneMinuteAgo)
+	filtered := events[:0]
+	for _, event := range events {
+		if hostPattern != "" && !strings.Contains(event.BackendName, hostPattern) {
+			continue
 		}
-
-		// Combine conditions with OR
-		whereClause := strings.Join(conditions, " OR ")
-		query = query.Where("("+whereClause+")", args...)
-	}
-
-	// Apply IP exclusion filter
-	if excludeIPFilter != nil && excludeIPFilter.ClientIP != "" {
-		if len(excludeIPFilter.ExcludeServices) == 0 {
-			// Exclude IP from all services
-			query = query.Where("client_ip != ?", excludeIPFilter.ClientIP)
-		} else {
-			// Exclude IP only from specific services
-			// Build condition: (client_ip != ? AND (service matches))
-			serviceConditions := make([]string, len(excludeIPFilter.ExcludeServices))
-			serviceArgs := make([]interface{}, 0, len(excludeIPFilter.ExcludeServices)*3)
-
-			for i, filter := range excludeIPFilter.ExcludeServices {
-				switch filter.Type {
-				case "backend_name":
-					serviceConditions[i] = "backend_name = ?"
-					serviceArgs = append(serviceArgs, filter.Name)
-				case "backend_url":
-					serviceConditions[i] = "backend_url = ?"
-					serviceArgs = append(serviceArgs, filter.Name)
-				case "host":
-					serviceConditions[i] = "host = ?"
-					serviceArgs = append(serviceArgs, filter.Name)
-				default:
-					serviceConditions[i] = "(backend_name = ? OR backend_url = ? OR host = ?)"
-					serviceArgs = append(serviceArgs, filter.Name, filter.Name, filter.Name)
-				}
-			}
-
-			serviceWhere := strings.Join(serviceConditions, " OR ")
-			allArgs := append([]interface{}{excludeIPFilter.ClientIP}, serviceArgs...)
-			query = query.Where("NOT (client_ip = ? AND ("+serviceWhere+"))", allArgs...)
+		if len(serviceFilters) > 0 && !filter.MatchesAny(serviceFilters, event.BackendName, event.BackendURL, event.Host) {
+			continue
 		}
-	}
-
-	err := query.Scan(&result).Error
-
-	if err != nil {
-		m.logger.Warn("Failed to collect filtered real-time metrics",
-			m.logger.Args("error", err, "host", host))
-		// Return empty metrics on error
-		return &RealtimeMetrics{
-			Timestamp: now,
+		if excludeIPFilter.Excludes(event.ClientIP, event.BackendName, event.BackendURL, event.Host) {
+			continue
 		}
+		filtered = append(filtered, event)
 	}
 
-	// Calculate rates (per second)
-	requestRate := float64(result.TotalCount) / 60.0
-	errorRate := float64(result.ErrorCount) / 60.0
-
-	return &RealtimeMetrics{
-		RequestRate:       requestRate,
-		ErrorRate:         errorRate,
-		AvgResponseTime:   result.AvgRespTime,
-		ActiveConnections: 0, // Not applicable for filtered metrics
-		Status2xx:         result.Status2xx,
-		Status4xx:         result.Status4xx,
-		Status5xx:         result.Status5xx,
-		Timestamp:         now,
-	}
+	// ActiveConnections is not applicable for filtered metrics
+	return aggregateEvents(filtered, now, m.statusPolicy)
 }
 
 // ServiceMetrics represents metrics for a single service
@@ -286,97 +225,46 @@ type ServiceMetrics struct {
 }
 
 // GetPerServiceMetrics returns real-time metrics for each service
-func (m *MetricsCollector) GetPerServiceMetrics(filters []repositories.ServiceFilter, excludeIP *repositories.ExcludeIPFilter) []ServiceMetrics {
-	now := time.Now()
+func (m *MetricsCollector) GetPerServiceMetrics(serviceFilters []filter.Service, excludeIPFilter *filter.ExcludeIP) []ServiceMetrics {
+	now := m.clock.Now()
 	oneMinuteAgo := now.Add(-1 * time.Minute)
 
-	// Query database for per-service metrics
-	type ServiceResult struct {
-		BackendName string `gorm:"column:backend_name"`
-		BackendURL  string `gorm:"column:backend_url"`
-		Host        string `gorm:"column:host"`
-		TotalCount  int64  `gorm:"column:total_count"`
-	}
-
-	query := m.db.Table("http_requests").
-		Select("backend_name, backend_url, host, COUNT(*) as total_count").
-		Where("timestamp > ?", oneMinuteAgo)
-
-	// Apply service filters (if any)
-	if len(filters) > 0 {
-		serviceQuery := m.db.Where("1 = 0") // Start with false condition
-		for _, filter := range filters {
-			switch filter.Type {
-			case "backend_name":
-				serviceQuery = serviceQuery.Or("backend_name = ?", filter.Name)
-			case "backend_url":
-				serviceQuery = serviceQuery.Or("backend_url = ?", filter.Name)
-			case "host":
-				serviceQuery = serviceQuery.Or("host = ?", filter.Name)
-			}
+	// Count events per service, keeping first-seen order for stable output
+	counts := make(map[string]int64)
+	order := []string{}
+	for _, event := range m.buffer.Since(oneMinuteAgo) {
+		if len(serviceFilters) > 0 && !filter.MatchesAny(serviceFilters, event.BackendName, event.BackendURL, event.Host) {
+			continue
 		}
-		query = query.Where(serviceQuery)
-	}
-
-	// Apply IP exclusion filter (if any)
-	if excludeIP != nil && excludeIP.ClientIP != "" {
-		if len(excludeIP.ExcludeServices) == 0 {
-			// Exclude IP from all services
-			query = query.Where("client_ip != ?", excludeIP.ClientIP)
-		} else {
-			// Exclude IP only on specific services
-			serviceConds := []string{}
-			args := []interface{}{excludeIP.ClientIP}
-
-			for _, filter := range excludeIP.ExcludeServices {
-				switch filter.Type {
-				case "backend_name":
-					serviceConds = append(serviceConds, "backend_name = ?")
-					args = append(args, filter.Name)
-				case "backend_url":
-					serviceConds = append(serviceConds, "backend_url = ?")
-					args = append(args, filter.Name)
-				case "host":
-					serviceConds = append(serviceConds, "host = ?")
-					args = append(args, filter.Name)
-				}
-			}
-
-			whereClause := "NOT (client_ip = ? AND (" + strings.Join(serviceConds, " OR ") + "))"
-			query = query.Where(whereClause, args...)
+		if excludeIPFilter.Excludes(event.ClientIP, event.BackendName, event.BackendURL, event.Host) {
+			continue
 		}
-	}
-
-	var results []ServiceResult
-	err := query.Group("backend_name, backend_url, host").
-		Scan(&results).Error
 
-	if err != nil {
-		m.logger.Warn("Failed to collect per-service metrics", m.logger.Args("error", err))
-		return []ServiceMetrics{}
-	}
-
-	// Extract service names and calculate rates
-	serviceMetrics := make([]ServiceMetrics, 0, len(results))
-	for _, result := range results {
 		// Determine service name based on priority: backend_name > backend_url > host
 		serviceName := ""
-		if result.BackendName != "" {
-			serviceName = extractServiceName(result.BackendName)
-		} else if result.BackendURL != "" {
-			serviceName = result.BackendURL
-		} else if result.Host != "" {
-			serviceName = result.Host
+		if event.BackendName != "" {
+			serviceName = extractServiceName(event.BackendName)
+		} else if event.BackendURL != "" {
+			serviceName = event.BackendURL
+		} else if event.Host != "" {
+			serviceName = event.Host
 		}
 
 		if serviceName == "" {
 			continue
 		}
 
-		requestRate := float64(result.TotalCount) / 60.0
+		if _, exists := counts[serviceName]; !exists {
+			order = append(order, serviceName)
+		}
+		counts[serviceName]++
+	}
+
+	serviceMetrics := make([]ServiceMetrics, 0, len(order))
+	for _, serviceName := range order {
 		serviceMetrics = append(serviceMetrics, ServiceMetrics{
 			ServiceName: serviceName,
-			RequestRate: requestRate,
+			RequestRate: float64(counts[serviceName]) / 60.0,
 		})
 	}
 
@@ -407,3 +295,9 @@ func extractServiceName(backendName string) string {
 
 	return backendName
 }
+
+// RecentEvents returns buffered events with a timestamp after the given time
+// Used by consumers such as the alerting engine that need raw events instead of aggregates
+func (m *MetricsCollector) RecentEvents(since time.Time) []Event {
+	return m.buffer.Since(since)
+}
@@ -0,0 +1,72 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// windowSeconds is the length of the sliding window (one bucket per second)
+const windowSeconds = 60
+
+// SlidingWindow counts requests per second over the last minute
+// Buckets are keyed by unix second so stale buckets are detected and reset lazily
+type SlidingWindow struct {
+	mu      sync.Mutex
+	counts  [windowSeconds]int64
+	seconds [windowSeconds]int64 // unix second each bucket currently holds
+}
+
+// NewSlidingWindow creates an empty per-second sliding window
+func NewSlidingWindow() *SlidingWindow {
+	return &SlidingWindow{}
+}
+
+// Add increments the bucket for the given timestamp
+// Timestamps outside the window (older than 60s or in the future) are ignored
+func (w *SlidingWindow) Add(ts time.Time, now time.Time) {
+	sec := ts.Unix()
+	nowSec := now.Unix()
+	if sec > nowSec || sec <= nowSec-windowSeconds {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	idx := sec % windowSeconds
+	if w.seconds[idx] != sec {
+		w.seconds[idx] = sec
+		w.counts[idx] = 0
+	}
+	w.counts[idx]++
+}
+
+// Rates returns the instantaneous rate and the burst max (busiest single second) over the window,
+// both in requests per second
+// Requests reach the window when their batch is stored, 1 to 30 seconds after they were logged, so the
+// instant rate is the count of the newest complete second that has requests, not of the second before now.
+func (w *SlidingWindow) Rates(now time.Time) (instantRate float64, burstMax float64) {
+	nowSec := now.Unix()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var peak int64
+	newestSec := int64(0)
+	for i := 0; i < windowSeconds; i++ {
+		// Ignore buckets that fell out of the window
+		if w.seconds[i] <= nowSec-windowSeconds || w.seconds[i] > nowSec || w.counts[i] == 0 {
+			continue
+		}
+		if w.counts[i] > peak {
+			peak = w.counts[i]
+		}
+		// The current second is still filling up
+		if w.seconds[i] < nowSec && w.seconds[i] > newestSec {
+			newestSec = w.seconds[i]
+			instantRate = float64(w.counts[i])
+		}
+	}
+
+	return instantRate, float64(peak)
+}
@@ -0,0 +1,78 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindow_Rates(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 30, 0, time.UTC)
+	window := NewSlidingWindow()
+
+	// A burst of 5 requests 10 seconds ago, 2 in the last complete second, 1 in the current one
+	for i := 0; i < 5; i++ {
+		window.Add(now.Add(-10*time.Second), now)
+	}
+	window.Add(now.Add(-time.Second), now)
+	window.Add(now.Add(-500*time.Millisecond), now)
+	window.Add(now, now)
+
+	instant, burst := window.Rates(now)
+	if instant != 2 || burst != 5 {
+		t.Errorf("Expected instant rate 2 and burst max 5, got %.0f and %.0f", instant, burst)
+	}
+
+	// Out-of-window and future timestamps are ignored
+	window.Add(now.Add(-60*time.Second), now)
+	window.Add(now.Add(time.Second), now)
+	if instant, burst := window.Rates(now); instant != 2 || burst != 5 {
+		t.Errorf("Expected ignored timestamps not to count, got %.0f and %.0f", instant, burst)
+	}
+
+	// 50 seconds later the burst fell out of the window, the newest second with requests is the one of now
+	later := now.Add(50 * time.Second)
+	if instant, burst := window.Rates(later); instant != 1 || burst != 2 {
+		t.Errorf("Expected instant rate 1 and burst max 2 after the burst expired, got %.0f and %.0f", instant, burst)
+	}
+}
+
+func TestSlidingWindow_ReusesBuckets(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 30, 0, time.UTC)
+	window := NewSlidingWindow()
+
+	for i := 0; i < 3; i++ {
+		window.Add(now.Add(-time.Second), now)
+	}
+
+	// One minute later the same bucket holds a new second, the old counts are reset
+	later := now.Add(windowSeconds * time.Second)
+	window.Add(later.Add(-time.Second), later)
+	if instant, burst := window.Rates(later); instant != 1 || burst != 1 {
+		t.Errorf("Expected the reused bucket to restart at 1, got %.0f and %.0f", instant, burst)
+	}
+}
+
+func TestSlidingWindow_InstantRateFlushLag(t *testing.T) {
+	now := time.Date(2026, 10, 15, 8, 0, 30, 0, time.UTC)
+	window := NewSlidingWindow()
+
+	// A batch stored now holds requests logged up to 5 seconds ago, none in the last second
+	for i := 0; i < 4; i++ {
+		window.Add(now.Add(-6*time.Second), now)
+	}
+	for i := 0; i < 3; i++ {
+		window.Add(now.Add(-5*time.Second), now)
+	}
+	if instant, _ := window.Rates(now); instant != 3 {
+		t.Errorf("Expected the rate of the newest second with requests, got %.0f", instant)
+	}
+
+	// Requests of the current second don't count until it is complete
+	window.Add(now, now)
+	if instant, _ := window.Rates(now); instant != 3 {
+		t.Errorf("Expected the current second to be ignored, got %.0f", instant)
+	}
+	if instant, _ := window.Rates(now.Add(time.Second)); instant != 1 {
+		t.Errorf("Expected the rate of the completed second, got %.0f", instant)
+	}
+}
@@ -0,0 +1,154 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"loglynx/internal/database/repositories"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	serviceLabel  = "service"
+	quantileLabel = "quantile"
+
+	// maxCatchUpHours bounds the hours pushed after a restart or an outage
+	// Prometheus rejects samples older than its head block (2 to 3 hours).
+	maxCatchUpHours = 3
+)
+
+// Exporter pushes the rollups of every completed hour, once, after the rollup service built them
+// Samples are timestamped at the end of their hour. Hours rebuilt later (late log lines) aren't pushed again.
+type Exporter struct {
+	statsRepo   repositories.StatsRepository
+	client      *Client
+	labels      []Label
+	interval    time.Duration
+	logger      *pterm.Logger
+	pushedUntil time.Time // End of the last hour pushed
+	stopChan    chan struct{}
+	running     bool
+}
+
+// NewExporter creates an exporter checking for new rollups every interval (the rollup interval)
+func NewExporter(statsRepo repositories.StatsRepository, client *Client, labels []Label, interval time.Duration, logger *pterm.Logger) *Exporter {
+	return &Exporter{
+		statsRepo: statsRepo,
+		client:    client,
+		labels:    labels,
+		interval:  interval,
+		logger:    logger,
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start pushes new rollups in the background
+func (e *Exporter) Start() {
+	if e.interval <= 0 {
+		e.logger.Warn("Remote-write needs response time rollups, set RESPONSE_TIME_ROLLUP_INTERVAL to enable it")
+		return
+	}
+	if e.running {
+		return
+	}
+	e.running = true
+
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.stopChan:
+				return
+			case <-ticker.C:
+			}
+
+			if err := e.Run(); err != nil {
+				e.logger.Warn("Failed to push rollups to remote-write, retrying next interval", e.logger.Args("error", err))
+			}
+		}
+	}()
+
+	e.logger.Info("Remote-write of service rollups enabled", e.logger.Args("interval", e.interval))
+}
+
+// Stop stops pushing rollups
+func (e *Exporter) Stop() {
+	if !e.running {
+		return
+	}
+	close(e.stopChan)
+	e.running = false
+}
+
+// Run pushes the hours rolled up since the last push
+// The first run only pushes the last complete hour.
+func (e *Exporter) Run() error {
+	builtUntil, err := e.statsRepo.GetRollupsBuiltUntil()
+	if err != nil || builtUntil.IsZero() {
+		return err
+	}
+
+	hour := builtUntil.Add(-time.Hour)
+	if !e.pushedUntil.IsZero() {
+		hour = e.pushedUntil
+		if oldest := builtUntil.Add(-maxCatchUpHours * time.Hour); hour.Before(oldest) {
+			e.logger.Warn("Skipping rollups too old for remote-write", e.logger.Args("from", hour, "until", oldest))
+			hour = oldest
+		}
+	}
+
+	for ; hour.Before(builtUntil); hour = hour.Add(time.Hour) {
+		rollups, err := e.statsRepo.GetServiceHourRollups(hour)
+		if err != nil {
+			return err
+		}
+
+		samples := e.samples(hour, rollups)
+		if len(samples) > 0 {
+			err = e.client.Push(context.Background(), samples)
+			if errors.Is(err, ErrRejected) {
+				e.logger.Warn("Remote-write endpoint rejected the rollups of an hour", e.logger.Args("hour", hour, "error", err))
+			} else if err != nil {
+				return err
+			} else {
+				e.logger.Debug("Pushed rollups to remote-write", e.logger.Args("hour", hour, "services", len(rollups), "samples", len(samples)))
+			}
+		}
+		e.pushedUntil = hour.Add(time.Hour)
+	}
+	return nil
+}
+
+// samples returns the series of the services of an hour
+func (e *Exporter) samples(hour time.Time, rollups []*repositories.ServiceHourRollup) []Sample {
+	timestamp := hour.Add(time.Hour)
+	samples := make([]Sample, 0, len(rollups)*8)
+	for _, rollup := range rollups {
+		service := Label{Name: serviceLabel, Value: rollup.Service}
+		add := func(name string, value float64, extra ...Label) {
+			labels := append(append(append(make([]Label, 0, len(e.labels)+2), e.labels...), service), extra...)
+			samples = append(samples, Sample{Name: name, Labels: labels, Value: value, Timestamp: timestamp})
+		}
+
+		add("loglynx_service_requests_per_hour", float64(rollup.Requests))
+		add("loglynx_service_errors_per_hour", float64(rollup.Errors))
+		add("loglynx_service_response_bytes_per_hour", float64(rollup.Bandwidth))
+		if rollup.ResponseTime == nil {
+			continue
+		}
+		add("loglynx_service_response_time_avg_ms", rollup.ResponseTime.Avg)
+		add("loglynx_service_response_time_max_ms", rollup.ResponseTime.Max)
+		for _, quantile := range []struct {
+			q     float64
+			value float64
+		}{{0.5, rollup.ResponseTime.P50}, {0.95, rollup.ResponseTime.P95}, {0.99, rollup.ResponseTime.P99}} {
+			add("loglynx_service_response_time_ms", quantile.value, Label{Name: quantileLabel, Value: strconv.FormatFloat(quantile.q, 'f', -1, 64)})
+		}
+	}
+	return samples
+}
@@ -0,0 +1,189 @@
+// Package remotewrite pushes hourly per-service rollups to a Prometheus remote-write endpoint
+// Prometheus (--web.enable-remote-write-receiver), VictoriaMetrics, Mimir or Grafana Cloud store them next to
+// infrastructure metrics, for longer than the request retention of LogLynx.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"loglynx/internal/version"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxErrorBody bounds the error response read from the remote-write endpoint
+const maxErrorBody = 4096
+
+// ErrRejected marks samples the endpoint refused (4xx): resending them can't succeed
+var ErrRejected = errors.New("rejected by the remote-write endpoint")
+
+// labelName is the syntax of Prometheus label names
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Label is a Prometheus label
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value of a time series
+type Sample struct {
+	Name      string  // Metric name
+	Labels    []Label // Without __name__
+	Value     float64
+	Timestamp time.Time
+}
+
+// ParseLabels parses static labels added to every series, e.g. "job=loglynx,env=prod"
+// Labels set by the exporter (service, quantile) can't be overridden.
+func ParseLabels(list string) ([]Label, error) {
+	var labels []Label
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid label %q: expected name=value", pair)
+		}
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		if name == serviceLabel || name == quantileLabel {
+			return nil, fmt.Errorf("label %q is set by the exporter", name)
+		}
+		labels = append(labels, Label{Name: name, Value: value})
+	}
+	return labels, nil
+}
+
+// Client sends samples to a remote-write endpoint (protocol 1.0: snappy-compressed protobuf WriteRequest)
+type Client struct {
+	client      *http.Client
+	endpoint    string
+	username    string
+	password    string
+	bearerToken string
+}
+
+// NewClient creates a client of the remote-write endpoint, with basic auth (username) or a bearer token
+func NewClient(endpoint string, username string, password string, bearerToken string, timeout time.Duration) (*Client, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid remote-write URL %q: expected http(s)://host[:port]/path", endpoint)
+	}
+	if username != "" && bearerToken != "" {
+		return nil, errors.New("set either a username or a bearer token for remote-write, not both")
+	}
+	return &Client{
+		client:      &http.Client{Timeout: timeout},
+		endpoint:    endpoint,
+		username:    username,
+		password:    password,
+		bearerToken: bearerToken,
+	}, nil
+}
+
+// Push sends samples in one request
+// Errors wrapping ErrRejected are final, others (network, 5xx, 429) may succeed later.
+func (c *Client) Push(ctx context.Context, samples []Sample) error {
+	body := snappyBlock(encodeWriteRequest(samples))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "loglynx/"+version.Version)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	} else if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err = fmt.Errorf("remote-write endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
+
+// encodeWriteRequest encodes samples as a prometheus.WriteRequest, one time series per sample
+// Fields: WriteRequest.timeseries = 1, TimeSeries.labels = 1 and samples = 2, Label.name = 1 and value = 2,
+// Sample.value = 1 (double) and timestamp = 2 (milliseconds). Labels must be sorted by name.
+func encodeWriteRequest(samples []Sample) []byte {
+	var request []byte
+	for _, sample := range samples {
+		labels := append([]Label{{Name: "__name__", Value: sample.Name}}, sample.Labels...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		var series []byte
+		for _, label := range labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Name)
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Value)
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, encoded)
+		}
+
+		var value []byte
+		value = protowire.AppendTag(value, 1, protowire.Fixed64Type)
+		value = protowire.AppendFixed64(value, math.Float64bits(sample.Value))
+		value = protowire.AppendTag(value, 2, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(sample.Timestamp.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, value)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return request
+}
+
+// snappyBlock encodes data in the snappy block format with literals only
+// Remote-write requires snappy, and the rollups of an hour are a few KB: compressing isn't worth a dependency.
+func snappyBlock(data []byte) []byte {
+	block := binary.AppendUvarint(make([]byte, 0, len(data)+16), uint64(len(data)))
+	for len(data) > 0 {
+		n := min(len(data), 65536)
+		switch {
+		case n <= 60:
+			block = append(block, byte(n-1)<<2)
+		case n <= 256:
+			block = append(block, 60<<2, byte(n-1))
+		default:
+			block = append(block, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		block = append(block, data[:n]...)
+		data = data[n:]
+	}
+	return block
+}
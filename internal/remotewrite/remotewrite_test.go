@@ -0,0 +1,222 @@
+package remotewrite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"loglynx/internal/database"
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeSnappyLiterals decodes a snappy block made of literals, as written by snappyBlock
+func decodeSnappyLiterals(t *testing.T, block []byte) []byte {
+	t.Helper()
+	length, n := binary.Uvarint(block)
+	block = block[n:]
+	var data []byte
+	for len(block) > 0 {
+		tag := block[0]
+		if tag&3 != 0 {
+			t.Fatalf("Unexpected snappy copy element %x", tag)
+		}
+		size := int(tag>>2) + 1
+		switch tag >> 2 {
+		case 60:
+			size, block = int(block[1])+1, block[1:]
+		case 61:
+			size, block = int(binary.LittleEndian.Uint16(block[1:3]))+1, block[2:]
+		}
+		data = append(data, block[1:1+size]...)
+		block = block[1+size:]
+	}
+	if uint64(len(data)) != length {
+		t.Fatalf("Snappy block announces %d bytes, holds %d", length, len(data))
+	}
+	return data
+}
+
+// decodeWriteRequest returns the series of a WriteRequest as "name{label="value",...} value@timestamp"
+func decodeWriteRequest(t *testing.T, request []byte) []string {
+	t.Helper()
+	fields := func(message []byte, field func(num protowire.Number, typ protowire.Type, value []byte, fixed uint64, varint uint64)) {
+		for len(message) > 0 {
+			num, typ, n := protowire.ConsumeTag(message)
+			message = message[n:]
+			switch typ {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeBytes(message)
+				field(num, typ, value, 0, 0)
+				message = message[n:]
+			case protowire.Fixed64Type:
+				value, n := protowire.ConsumeFixed64(message)
+				field(num, typ, nil, value, 0)
+				message = message[n:]
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(message)
+				field(num, typ, nil, 0, value)
+				message = message[n:]
+			default:
+				t.Fatalf("Unexpected wire type %d", typ)
+			}
+		}
+	}
+
+	var series []string
+	fields(request, func(_ protowire.Number, _ protowire.Type, timeSeries []byte, _ uint64, _ uint64) {
+		var name, sample string
+		var labels []string
+		fields(timeSeries, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64, _ uint64) {
+			switch num {
+			case 1:
+				var label [2]string
+				fields(value, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64, _ uint64) {
+					label[num-1] = string(value)
+				})
+				if label[0] == "__name__" {
+					name = label[1]
+				} else {
+					labels = append(labels, fmt.Sprintf("%s=%q", label[0], label[1]))
+				}
+			case 2:
+				var v float64
+				var ts uint64
+				fields(value, func(num protowire.Number, _ protowire.Type, _ []byte, fixed uint64, varint uint64) {
+					if num == 1 {
+						v = math.Float64frombits(fixed)
+					} else {
+						ts = varint
+					}
+				})
+				sample = fmt.Sprintf("%g@%d", v, ts)
+			}
+		})
+		if !sort.StringsAreSorted(labels) {
+			t.Errorf("Labels of %s are not sorted: %v", name, labels)
+		}
+		series = append(series, fmt.Sprintf("%s{%s} %s", name, strings.Join(labels, ","), sample))
+	})
+	return series
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels(" job=loglynx, env = prod ,")
+	if err != nil || len(labels) != 2 || labels[1] != (Label{Name: "env", Value: "prod"}) {
+		t.Errorf("Unexpected labels %v (%v)", labels, err)
+	}
+	for _, invalid := range []string{"job", "job=", "1job=x", "__name__=x", "service=x", "quantile=x"} {
+		if _, err := ParseLabels(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSnappyBlock_LongLiterals(t *testing.T) {
+	data := []byte(strings.Repeat("loglynx", 20000))
+	for _, size := range []int{1, 60, 61, 256, 257, 65536, len(data)} {
+		if decoded := decodeSnappyLiterals(t, snappyBlock(data[:size])); string(decoded) != string(data[:size]) {
+			t.Errorf("Block of %d bytes decoded to %d bytes", size, len(decoded))
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	db := fake.NewDB(t)
+
+	// Last hour: 3 api requests (one 502) and 1 untimed web request; the current hour isn't complete
+	lastHour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	for i, request := range []struct {
+		backend string
+		status  int
+		ms      float64
+		offset  time.Duration
+	}{
+		{"api", 200, 10, 0}, {"api", 200, 20, time.Minute}, {"api", 502, 30, 2 * time.Minute},
+		{"web", 200, 0, 3 * time.Minute}, {"api", 200, 99, time.Hour},
+	} {
+		err := db.Create(&models.HTTPRequest{
+			SourceName: "test", Timestamp: lastHour.Add(request.offset), RequestHash: fmt.Sprint(i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: "/", BackendName: request.backend,
+			StatusCode: request.status, ResponseSize: 1000, ResponseTimeMs: request.ms,
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.NewRollupService(db, log, time.Minute).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var series []string
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" || user != "tenant" || password != "secret" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		series = append(series, decodeWriteRequest(t, decodeSnappyLiterals(t, body))...)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/api/v1/write", "tenant", "secret", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels, _ := ParseLabels("job=loglynx")
+	exporter := NewExporter(repositories.NewStatsRepository(db, log, 24, nil, nil, nil), client, labels, time.Minute, log)
+
+	// Unavailable endpoint: the hour is retried on the next run
+	if err := exporter.Run(); err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("Expected a retryable error, got %v", err)
+	}
+	series = nil
+	status = http.StatusNoContent
+	if err := exporter.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := lastHour.Add(time.Hour).UnixMilli()
+	expected := []string{
+		fmt.Sprintf(`loglynx_service_requests_per_hour{job="loglynx",service="api"} 3@%d`, ts),
+		fmt.Sprintf(`loglynx_service_errors_per_hour{job="loglynx",service="api"} 1@%d`, ts),
+		fmt.Sprintf(`loglynx_service_response_bytes_per_hour{job="loglynx",service="api"} 3000@%d`, ts),
+		fmt.Sprintf(`loglynx_service_response_time_avg_ms{job="loglynx",service="api"} 20@%d`, ts),
+		fmt.Sprintf(`loglynx_service_response_time_max_ms{job="loglynx",service="api"} 30@%d`, ts),
+	}
+	for i, line := range expected {
+		if i >= len(series) || series[i] != line {
+			t.Fatalf("Expected series %d to be %s, got %v", i, line, series)
+		}
+	}
+	if len(series) != 8+3 || !strings.HasPrefix(series[5], `loglynx_service_response_time_ms{job="loglynx",quantile="0.5",service="api"}`) ||
+		series[8] != fmt.Sprintf(`loglynx_service_requests_per_hour{job="loglynx",service="web"} 1@%d`, ts) {
+		t.Errorf("Unexpected series %v", series)
+	}
+
+	// Pushed hours aren't pushed again, rejected ones are skipped
+	series = nil
+	if err := exporter.Run(); err != nil || len(series) != 0 {
+		t.Errorf("Expected nothing to push, got %d series (%v)", len(series), err)
+	}
+	status = http.StatusBadRequest
+	exporter.pushedUntil = lastHour
+	if err := exporter.Run(); err != nil || !exporter.pushedUntil.Equal(lastHour.Add(time.Hour)) {
+		t.Errorf("Expected the rejected hour to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package requestid carries the ID of an API call, so its log lines, slow queries and error response can be matched
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header is the HTTP header carrying the request ID, from the client or a reverse proxy, and in every response
+const Header = "X-Request-ID"
+
+// maxLength bounds client-supplied IDs (UUIDs, Traefik/nginx request IDs are well below)
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a random request ID (16 hex characters)
+func New() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Valid reports whether a client-supplied ID can be reused: letters, digits and -_.:, up to 128 characters
+// Other IDs are replaced, so log lines can't be forged with newlines or escape sequences.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a context carrying the request ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of ctx, or "" outside an API call (ingestion, cleanup)
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
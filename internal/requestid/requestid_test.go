@@ -0,0 +1,36 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for id, expected := range map[string]bool{
+		"3f9c2a7be41d08c5":                     true,
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8": true,
+		"traefik:req_1.2":                      true,
+		"":                                     false,
+		"abc\nfake=log":                        false,
+		"with space":                           false,
+		strings.Repeat("a", 129):               false,
+	} {
+		if Valid(id) != expected {
+			t.Errorf("Valid(%q) = %v, expected %v", id, !expected, expected)
+		}
+	}
+
+	if id := New(); !Valid(id) || len(id) != 16 || id == New() {
+		t.Errorf("Unexpected generated ID %q", id)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("Expected no ID outside an API call, got %q", id)
+	}
+	if id := FromContext(WithID(context.Background(), "abc")); id != "abc" {
+		t.Errorf("Expected abc, got %q", id)
+	}
+}
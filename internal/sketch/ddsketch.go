@@ -0,0 +1,231 @@
+// Package sketch provides mergeable quantile sketches for pre-aggregated percentiles
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultRelativeAccuracy bounds the relative error of quantiles (1%: a p95 of 200ms is reported between 198 and 202ms)
+const DefaultRelativeAccuracy = 0.01
+
+// encodingVersion is the first byte of encoded sketches
+const encodingVersion = 1
+
+// DDSketch estimates quantiles of positive values with a bounded relative error (DDSketch algorithm)
+// Values are counted in logarithmic buckets, so sketches built separately (e.g. one per hour) merge exactly,
+// and their size depends on the range of values rather than on their number.
+type DDSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	bins  map[int32]uint64
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// New returns an empty sketch with the given relative accuracy (0 < relativeAccuracy < 1)
+func New(relativeAccuracy float64) *DDSketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		bins:             make(map[int32]uint64),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+// Add records a value, values <= 0 are ignored
+func (s *DDSketch) Add(value float64) {
+	if value <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	s.bins[s.key(value)]++
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+}
+
+// Merge adds the values of another sketch, both must have the same relative accuracy
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other.relativeAccuracy != s.relativeAccuracy {
+		return fmt.Errorf("cannot merge sketches with relative accuracy %v and %v", s.relativeAccuracy, other.relativeAccuracy)
+	}
+	for key, count := range other.bins {
+		s.bins[key] += count
+	}
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	return nil
+}
+
+// Count returns the number of values
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+// Sum returns the exact sum of the values
+func (s *DDSketch) Sum() float64 {
+	return s.sum
+}
+
+// Min returns the exact smallest value, 0 when empty
+func (s *DDSketch) Min() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.min
+}
+
+// Max returns the exact largest value, 0 when empty
+func (s *DDSketch) Max() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.max
+}
+
+// Quantile returns the estimated value at quantile q (0-1), 0 when empty
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := q * float64(s.count-1)
+	var seen uint64
+	for _, key := range s.sortedKeys() {
+		seen += s.bins[key]
+		if float64(seen) > rank {
+			return math.Max(s.min, math.Min(s.max, s.value(key)))
+		}
+	}
+	return s.max
+}
+
+// key returns the bucket of a value: bucket i holds values in (gamma^(i-1), gamma^i]
+func (s *DDSketch) key(value float64) int32 {
+	return int32(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// value returns the representative value of a bucket, within the relative accuracy of all its values
+func (s *DDSketch) value(key int32) float64 {
+	return 2 * math.Pow(s.gamma, float64(key)) / (s.gamma + 1)
+}
+
+func (s *DDSketch) sortedKeys() []int32 {
+	keys := make([]int32, 0, len(s.bins))
+	for key := range s.bins {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// MarshalBinary encodes the sketch compactly (delta-encoded bucket keys as varints)
+func (s *DDSketch) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+8*4+binary.MaxVarintLen64*(2+2*len(s.bins)))
+	buf = append(buf, encodingVersion)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.relativeAccuracy))
+	buf = binary.AppendUvarint(buf, s.count)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.sum))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.Min()))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.Max()))
+
+	keys := s.sortedKeys()
+	buf = binary.AppendUvarint(buf, uint64(len(keys)))
+	previous := int64(0)
+	for _, key := range keys {
+		buf = binary.AppendVarint(buf, int64(key)-previous)
+		buf = binary.AppendUvarint(buf, s.bins[key])
+		previous = int64(key)
+	}
+	return buf, nil
+}
+
+// Decode returns the sketch encoded by MarshalBinary
+func Decode(data []byte) (*DDSketch, error) {
+	if len(data) < 1+8 || data[0] != encodingVersion {
+		return nil, errors.New("invalid sketch encoding")
+	}
+	r := &reader{data: data[1:]}
+
+	s := New(math.Float64frombits(r.uint64()))
+	s.count = r.uvarint()
+	s.sum = math.Float64frombits(r.uint64())
+	s.min = math.Float64frombits(r.uint64())
+	s.max = math.Float64frombits(r.uint64())
+
+	bins := r.uvarint()
+	key := int64(0)
+	for i := uint64(0); i < bins && r.err == nil; i++ {
+		key += r.varint()
+		s.bins[int32(key)] = r.uvarint()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if s.count == 0 {
+		s.min, s.max = math.Inf(1), math.Inf(-1)
+	}
+	return s, nil
+}
+
+// reader decodes fields in order, remembering the first error
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uint64() uint64 {
+	if r.err != nil || len(r.data) < 8 {
+		r.err = errors.New("truncated sketch")
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errors.New("truncated sketch")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errors.New("truncated sketch")
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
@@ -0,0 +1,86 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// exactQuantile returns the value at rank q*(n-1) of sorted values, as DDSketch.Quantile estimates it
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestDDSketch_RelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := New(DefaultRelativeAccuracy)
+	values := make([]float64, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		// Log-normal response times around 50ms with a long tail
+		v := math.Exp(rng.NormFloat64()*1.2 + math.Log(50))
+		values = append(values, v)
+		s.Add(v)
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 0.999} {
+		expected := exactQuantile(values, q)
+		got := s.Quantile(q)
+		if math.Abs(got-expected)/expected > DefaultRelativeAccuracy+1e-9 {
+			t.Errorf("p%v: expected %.3f within 1%%, got %.3f", q*100, expected, got)
+		}
+	}
+	if s.Min() != values[0] || s.Max() != values[len(values)-1] {
+		t.Errorf("Expected exact min/max %v/%v, got %v/%v", values[0], values[len(values)-1], s.Min(), s.Max())
+	}
+}
+
+func TestDDSketch_MergeAndEncoding(t *testing.T) {
+	whole := New(DefaultRelativeAccuracy)
+	merged := New(DefaultRelativeAccuracy)
+
+	// One sketch per "hour", encoded and decoded as stored in rollups
+	for hour := 0; hour < 24; hour++ {
+		part := New(DefaultRelativeAccuracy)
+		for i := 1; i <= 100; i++ {
+			v := float64(i*(hour+1)) / 3
+			part.Add(v)
+			whole.Add(v)
+		}
+		encoded, err := part.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := Decode(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := merged.Merge(decoded); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if merged.Count() != whole.Count() || math.Abs(merged.Sum()-whole.Sum()) > 1e-6 {
+		t.Errorf("Expected count %d and sum %v, got %d and %v", whole.Count(), whole.Sum(), merged.Count(), merged.Sum())
+	}
+	for _, q := range []float64{0, 0.5, 0.95, 0.99, 1} {
+		if merged.Quantile(q) != whole.Quantile(q) {
+			t.Errorf("q=%v: merged sketch gives %v, single sketch %v", q, merged.Quantile(q), whole.Quantile(q))
+		}
+	}
+
+	if err := merged.Merge(New(0.05)); err == nil {
+		t.Error("Expected an error merging sketches of different accuracy")
+	}
+	if _, err := Decode([]byte{encodingVersion, 1, 2}); err == nil {
+		t.Error("Expected an error decoding a truncated sketch")
+	}
+
+	empty := New(DefaultRelativeAccuracy)
+	encoded, _ := empty.MarshalBinary()
+	decoded, err := Decode(encoded)
+	if err != nil || decoded.Count() != 0 || decoded.Quantile(0.5) != 0 {
+		t.Errorf("Expected an empty sketch to round-trip, got %v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package statsd emits counts and timings of the ingestion pipeline and the API to a StatsD or DogStatsD agent
+// Metrics are sent over UDP from a background goroutine: emitting never blocks ingestion or API calls, metrics
+// are dropped when the queue is full. A nil *Client is valid and discards everything, so callers don't check.
+package statsd
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+const (
+	// maxPacketSize keeps packets within the Ethernet MTU, as recommended by the Datadog agent
+	maxPacketSize = 1432
+
+	// queueSize is the number of metrics waiting to be sent before new ones are dropped
+	queueSize = 8192
+
+	// flushInterval is the longest time a metric waits for a packet to fill up
+	flushInterval = time.Second
+)
+
+// metricReplacer replaces characters with a meaning in the StatsD line format
+var metricReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_", " ", "_")
+
+// tagReplacer replaces characters with a meaning in DogStatsD tags
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// Client sends metrics to a StatsD agent
+type Client struct {
+	conn      net.Conn
+	prefix    string
+	tags      []string // Added to every metric (DogStatsD only)
+	dogStatsD bool     // Plain StatsD has no tags, they are left out
+	queue     chan string
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	dropped   atomic.Int64
+	logger    *pterm.Logger
+}
+
+// New creates a client sending to address (host:port) over UDP
+// prefix is prepended to metric names (e.g. "loglynx."), tags ("env:prod") are added to every metric with DogStatsD.
+func New(address string, prefix string, tags []string, dogStatsD bool, logger *pterm.Logger) (*Client, error) {
+	if address == "" {
+		return nil, errors.New("the StatsD address is required")
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		conn:      conn,
+		prefix:    prefix,
+		dogStatsD: dogStatsD,
+		queue:     make(chan string, queueSize),
+		stop:      make(chan struct{}),
+		logger:    logger,
+	}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			client.tags = append(client.tags, tagReplacer.Replace(tag))
+		}
+	}
+
+	client.wg.Add(1)
+	go client.sendLoop()
+	return client, nil
+}
+
+// ParseTags splits a comma-separated tag list ("env:prod,team:edge")
+func ParseTags(list string) []string {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Count adds value to a counter
+func (c *Client) Count(name string, value int64, tags ...string) {
+	if c == nil {
+		return
+	}
+	c.emit(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing records a duration in milliseconds
+func (c *Client) Timing(name string, duration time.Duration, tags ...string) {
+	if c == nil {
+		return
+	}
+	c.emit(name, strconv.FormatFloat(float64(duration.Microseconds())/1000, 'f', -1, 64), "ms", tags)
+}
+
+// Gauge sets a gauge
+func (c *Client) Gauge(name string, value float64, tags ...string) {
+	if c == nil {
+		return
+	}
+	c.emit(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+// Close sends the queued metrics and closes the connection
+func (c *Client) Close() {
+	if c == nil {
+		return
+	}
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.wg.Wait()
+		c.conn.Close()
+		if dropped := c.dropped.Load(); dropped > 0 {
+			c.logger.Warn("StatsD metrics dropped, the queue was full", c.logger.Args("dropped", dropped))
+		}
+	})
+}
+
+// emit formats a metric line and queues it, dropping it if the queue is full
+func (c *Client) emit(name string, value string, metricType string, tags []string) {
+	var line strings.Builder
+	line.WriteString(metricReplacer.Replace(c.prefix + name))
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(metricType)
+	if c.dogStatsD && len(c.tags)+len(tags) > 0 {
+		line.WriteString("|#")
+		line.WriteString(strings.Join(c.tags, ","))
+		for i, tag := range tags {
+			if i > 0 || len(c.tags) > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(tagReplacer.Replace(tag))
+		}
+	}
+
+	select {
+	case c.queue <- line.String():
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// sendLoop packs queued lines into packets, sent when full or every flushInterval
+func (c *Client) sendLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	packet := make([]byte, 0, maxPacketSize)
+	send := func() {
+		if len(packet) == 0 {
+			return
+		}
+		// UDP: a missing agent only shows up as write errors, which metrics can do without
+		_, _ = c.conn.Write(packet)
+		packet = packet[:0]
+	}
+	add := func(line string) {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+			send()
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+
+	for {
+		select {
+		case line := <-c.queue:
+			add(line)
+		case <-ticker.C:
+			send()
+		case <-c.stop:
+			for {
+				select {
+				case line := <-c.queue:
+					add(line)
+				default:
+					send()
+					return
+				}
+			}
+		}
+	}
+}
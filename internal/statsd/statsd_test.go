@@ -0,0 +1,86 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// listen returns a UDP listener and a function reading the metric lines of the next packet
+func listen(t *testing.T) (string, func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() []string {
+		buf := make([]byte, 65536)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n > maxPacketSize {
+			t.Errorf("Packet of %d bytes exceeds %d", n, maxPacketSize)
+		}
+		return strings.Split(string(buf[:n]), "\n")
+	}
+}
+
+func TestClient(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	address, read := listen(t)
+
+	client, err := New(address, "loglynx.", []string{"env:prod", " "}, true, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Count("ingestion.lines", 42, "source:traefik", "parser:traefik")
+	client.Timing("api.response_time", 1500*time.Microsecond, "route:/api/v1/stats/summary")
+	client.Gauge("queue size", 3)
+	client.Close()
+
+	expected := []string{
+		"loglynx.ingestion.lines:42|c|#env:prod,source:traefik,parser:traefik",
+		"loglynx.api.response_time:1.5|ms|#env:prod,route:/api/v1/stats/summary",
+		"loglynx.queue_size:3|g|#env:prod",
+	}
+	if lines := read(); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+}
+
+func TestClient_PlainStatsD(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	address, read := listen(t)
+
+	client, err := New(address, "", []string{"env:prod"}, false, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lines are split across packets within the MTU
+	for i := 0; i < 100; i++ {
+		client.Count("api.requests", 1, "route:/api/v1/stats/summary")
+	}
+	client.Close()
+
+	var lines []string
+	for len(lines) < 100 {
+		lines = append(lines, read()...)
+	}
+	if len(lines) != 100 || lines[0] != "api.requests:1|c" {
+		t.Errorf("Expected 100 untagged counts, got %d (%q)", len(lines), lines[0])
+	}
+}
+
+func TestClient_Nil(t *testing.T) {
+	var client *Client
+	client.Count("ingestion.lines", 1)
+	client.Timing("api.response_time", time.Second)
+	client.Close()
+}
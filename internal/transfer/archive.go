@@ -0,0 +1,179 @@
+// Package transfer moves requests between LogLynx instances
+// An archive is a gzip-compressed stream of JSON lines: a header, one line per request and a trailer
+// holding the request count, so an import can tell a complete archive from a truncated one.
+package transfer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"loglynx/internal/database/models"
+)
+
+// FormatVersion is the archive format written by this version
+const FormatVersion = 1
+
+// maxLineBytes bounds one archive line (a request with its user agent, referrer and query string)
+const maxLineBytes = 1 << 20
+
+// ErrTruncated is returned when an archive ends without its trailer
+var ErrTruncated = errors.New("archive truncated: no trailer")
+
+// Header describes an archive
+type Header struct {
+	Version    int       `json:"version"`
+	Instance   string    `json:"instance,omitempty"` // Hostname of the exporting instance
+	AppVersion string    `json:"app_version,omitempty"`
+	Agent      bool      `json:"agent,omitempty"` // Batch forwarded by an agent rather than an export
+	ExportedAt time.Time `json:"exported_at"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+// line is one JSON line of an archive, Type tells which field is set
+type line struct {
+	Type     string              `json:"type"` // header, request or end
+	Header   *Header             `json:"header,omitempty"`
+	Request  *models.HTTPRequest `json:"request,omitempty"`
+	Requests int64               `json:"requests,omitempty"` // Trailer: number of requests written
+}
+
+// Writer writes an archive
+type Writer struct {
+	gz       *gzip.Writer
+	enc      *json.Encoder
+	requests int64
+}
+
+// NewWriter starts an archive on w with its header
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Version = FormatVersion
+	gz := gzip.NewWriter(w)
+	writer := &Writer{gz: gz, enc: json.NewEncoder(gz)}
+	if err := writer.enc.Encode(line{Type: "header", Header: &header}); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// WriteRequest appends a request
+func (w *Writer) WriteRequest(request *models.HTTPRequest) error {
+	if err := w.enc.Encode(line{Type: "request", Request: request}); err != nil {
+		return err
+	}
+	w.requests++
+	return nil
+}
+
+// Requests returns the number of requests written
+func (w *Writer) Requests() int64 {
+	return w.requests
+}
+
+// Flush sends buffered requests to the underlying writer
+func (w *Writer) Flush() error {
+	return w.gz.Flush()
+}
+
+// Close writes the trailer and completes the gzip stream
+func (w *Writer) Close() error {
+	if err := w.enc.Encode(line{Type: "end", Requests: w.requests}); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// Reader reads an archive
+type Reader struct {
+	gz       *gzip.Reader
+	lines    *bufio.Reader
+	header   Header
+	requests int64
+}
+
+// NewReader reads the header of the archive on r
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	reader := &Reader{gz: gz, lines: bufio.NewReaderSize(gz, 64*1024)}
+	first, err := reader.next()
+	if err == io.EOF {
+		return nil, ErrTruncated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first.Type != "header" || first.Header == nil {
+		return nil, errors.New("invalid archive: missing header")
+	}
+	if first.Header.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive version %d (expected %d)", first.Header.Version, FormatVersion)
+	}
+	reader.header = *first.Header
+	return reader, nil
+}
+
+// Header returns the archive header
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next request, io.EOF after the last one
+// An archive ending before its trailer, or with a trailer not matching the requests read, returns ErrTruncated.
+func (r *Reader) Next() (*models.HTTPRequest, error) {
+	next, err := r.next()
+	if err == io.EOF {
+		return nil, ErrTruncated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch next.Type {
+	case "request":
+		if next.Request == nil {
+			return nil, errors.New("invalid archive: empty request line")
+		}
+		r.requests++
+		return next.Request, nil
+	case "end":
+		if next.Requests != r.requests {
+			return nil, fmt.Errorf("%w: %d requests read, trailer announces %d", ErrTruncated, r.requests, next.Requests)
+		}
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("invalid archive: unexpected %q line", next.Type)
+	}
+}
+
+// Close releases the gzip reader
+func (r *Reader) Close() error {
+	return r.gz.Close()
+}
+
+func (r *Reader) next() (*line, error) {
+	data, err := r.lines.ReadBytes('\n')
+	if err != nil {
+		// Lines always end with a newline: a partial line or a cut gzip stream means the archive ends here
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if len(data) > maxLineBytes {
+		return nil, fmt.Errorf("invalid archive: line longer than %d bytes", maxLineBytes)
+	}
+
+	var next line
+	if err := json.Unmarshal(data, &next); err != nil {
+		return nil, fmt.Errorf("invalid archive line: %w", err)
+	}
+	return &next, nil
+}
@@ -0,0 +1,76 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/database/repositories/fake"
+
+	"github.com/pterm/pterm"
+)
+
+func TestExportImport(t *testing.T) {
+	log := pterm.DefaultLogger.WithLevel(pterm.LogLevelDisabled)
+	db := fake.NewDB(t)
+	repo := repositories.NewHTTPRequestRepository(db, log, "")
+
+	const count = 2500
+	var archive bytes.Buffer
+	writer, err := NewWriter(&archive, Header{Instance: "test", ExportedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < count; i++ {
+		err := writer.WriteRequest(&models.HTTPRequest{
+			ID: uint(i + 1), SourceName: "traefik", Timestamp: time.Now().Add(-time.Duration(i) * time.Second), RequestHash: fmt.Sprint("hash-", i),
+			ClientIP: "10.0.0.1", Method: "GET", Host: "example", Path: fmt.Sprint("/page/", i), StatusCode: 200, ResponseTimeMs: 12.5,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	importArchive := func(data []byte) (*ImportResult, error) {
+		reader, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return Import(reader, repo, "edge-1/", nil)
+	}
+
+	result, err := importArchive(archive.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Complete || result.Requests != count || result.Inserted != count || result.Header.Instance != "test" {
+		t.Fatalf("Unexpected import result %+v", result)
+	}
+	var sources []string
+	db.Model(&models.HTTPRequest{}).Distinct("source_name").Pluck("source_name", &sources)
+	if len(sources) != 1 || sources[0] != "edge-1/traefik" {
+		t.Errorf("Expected the source prefix to be applied, got %v", sources)
+	}
+
+	// Importing the same archive again stores nothing
+	result, err = importArchive(archive.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 0 || result.Duplicates != count {
+		t.Errorf("Expected every request to be a duplicate, got %+v", result)
+	}
+
+	// A cut archive is reported as truncated
+	if _, err := importArchive(archive.Bytes()[:archive.Len()/2]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+}
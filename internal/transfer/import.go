@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+	"loglynx/internal/ingestion"
+)
+
+// importBatchSize is the number of requests committed per transaction
+const importBatchSize = 1000
+
+// ErrStore wraps database errors of an import, other errors come from the archive
+var ErrStore = errors.New("failed to store requests")
+
+// ImportResult counts the requests of an import
+type ImportResult struct {
+	Requests   int64  `json:"requests"`   // Requests read from the archive
+	Inserted   int64  `json:"inserted"`   // Requests stored
+	Duplicates int64  `json:"duplicates"` // Requests already stored (same request_hash)
+	Complete   bool   `json:"complete"`   // The whole archive was read, including its trailer
+	Header     Header `json:"header"`
+}
+
+// Import stores the requests of an archive, skipping the ones already stored
+// Requests are committed in batches: when the archive is truncated or a batch fails, the batches already
+// committed are kept and importing the same archive again only adds the missing requests.
+// sourcePrefix is prepended to source names to keep the sources of several instances apart (optional).
+// recorder is fed the stored batches like ingested ones, so forwarded requests reach real-time metrics (optional).
+func Import(reader *Reader, repo repositories.HTTPRequestRepository, sourcePrefix string, recorder ingestion.EventRecorder) (*ImportResult, error) {
+	result := &ImportResult{Header: reader.Header()}
+	batch := make([]*models.HTTPRequest, 0, importBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		stored, err := repo.CreateBatch(batch)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStore, err)
+		}
+		result.Inserted += int64(stored.Inserted)
+		result.Duplicates += int64(stored.Duplicates)
+		if recorder != nil {
+			recorder.Record(batch)
+		}
+		batch = make([]*models.HTTPRequest, 0, importBatchSize)
+		return nil
+	}
+
+	for {
+		request, err := reader.Next()
+		if err == io.EOF {
+			result.Complete = true
+			break
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return result, flushErr
+			}
+			return result, err
+		}
+
+		// IDs belong to the exporting instance, the request hash identifies the request
+		request.ID = 0
+		if sourcePrefix != "" {
+			request.SourceName = sourcePrefix + request.SourceName
+		}
+		result.Requests++
+		batch = append(batch, request)
+
+		if len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, flush()
+}
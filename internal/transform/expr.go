@@ -0,0 +1,520 @@
+package transform
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"loglynx/internal/database/models"
+)
+
+// valueType is the static type of an expression, checked when the rules are loaded
+type valueType int
+
+const (
+	typeString valueType = iota
+	typeNumber
+	typeBool
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeBool:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// node is a compiled expression, values are string, float64 or bool according to typ
+type node struct {
+	typ      valueType
+	eval     func(request *models.HTTPRequest) any
+	constant bool // Literal, its value is known when compiling (regular expressions, CIDRs)
+}
+
+func literal(value any, typ valueType) *node {
+	return &node{typ: typ, constant: true, eval: func(*models.HTTPRequest) any { return value }}
+}
+
+// compile parses and type-checks an expression
+//
+//	expr  := and ("||" and)*
+//	and   := not ("&&" not)*
+//	not   := "!" not | cmp
+//	cmp   := sum (("==" | "!=" | "<" | "<=" | ">" | ">=") sum)?
+//	sum   := unary (("+" | "-") unary)*
+//	unary := "-" unary | "(" expr ")" | string | number | true | false | field | function "(" args ")"
+func compile(source string) (*node, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are matched longest first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "(", ")", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end + 1
+		case c >= '0' && c <= '9':
+			end := i
+			for end < len(source) && (source[end] >= '0' && source[end] <= '9' || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:end], pos: i})
+			i = end
+		case isIdentByte(source[i]):
+			end := i
+			for end < len(source) && (isIdentByte(source[end]) || source[end] >= '0' && source[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(source)}), nil
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.pos] }
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the operator when it is next
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if !p.accept(op) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d, found %q", op, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (*node, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.accept("||") {
+		var right *node
+		if right, err = p.parseAnd(); err == nil {
+			left, err = logical("||", left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (*node, error) {
+	left, err := p.parseNot()
+	for err == nil && p.accept("&&") {
+		var right *node
+		if right, err = p.parseNot(); err == nil {
+			left, err = logical("&&", left, right)
+		}
+	}
+	return left, err
+}
+
+func logical(op string, left, right *node) (*node, error) {
+	if left.typ != typeBool || right.typ != typeBool {
+		return nil, fmt.Errorf("%s needs bool operands, got %s and %s", op, left.typ, right.typ)
+	}
+	if op == "&&" {
+		return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any {
+			return left.eval(r).(bool) && right.eval(r).(bool)
+		}}, nil
+	}
+	return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any {
+		return left.eval(r).(bool) || right.eval(r).(bool)
+	}}, nil
+}
+
+func (p *exprParser) parseNot() (*node, error) {
+	if !p.accept("!") {
+		return p.parseComparison()
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if operand.typ != typeBool {
+		return nil, fmt.Errorf("! needs a bool operand, got %s", operand.typ)
+	}
+	return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any { return !operand.eval(r).(bool) }}, nil
+}
+
+func (p *exprParser) parseComparison() (*node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return left, nil
+	}
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ != right.typ {
+		return nil, fmt.Errorf("can't compare %s with %s at position %d", left.typ, right.typ, tok.pos)
+	}
+	if left.typ == typeBool && tok.text != "==" && tok.text != "!=" {
+		return nil, fmt.Errorf("bools can't be ordered with %s", tok.text)
+	}
+
+	op := tok.text
+	return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any {
+		a, b := left.eval(r), right.eval(r)
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		}
+		var cmp int
+		if x, ok := a.(float64); ok {
+			y := b.(float64)
+			switch {
+			case x < y:
+				cmp = -1
+			case x > y:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(a.(string), b.(string))
+		}
+		switch op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	}}, nil
+}
+
+func (p *exprParser) parseSum() (*node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator || (tok.text != "+" && tok.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		switch {
+		case tok.text == "+" && a.typ == typeString && b.typ == typeString:
+			left = &node{typ: typeString, eval: func(r *models.HTTPRequest) any { return a.eval(r).(string) + b.eval(r).(string) }}
+		case a.typ == typeNumber && b.typ == typeNumber && tok.text == "+":
+			left = &node{typ: typeNumber, eval: func(r *models.HTTPRequest) any { return a.eval(r).(float64) + b.eval(r).(float64) }}
+		case a.typ == typeNumber && b.typ == typeNumber:
+			left = &node{typ: typeNumber, eval: func(r *models.HTTPRequest) any { return a.eval(r).(float64) - b.eval(r).(float64) }}
+		default:
+			return nil, fmt.Errorf("can't apply %s to %s and %s at position %d (use string() to convert numbers)", tok.text, a.typ, b.typ, tok.pos)
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (*node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return literal(tok.text, typeString), nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literal(value, typeNumber), nil
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "-":
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			if operand.typ != typeNumber {
+				return nil, fmt.Errorf("- needs a number operand, got %s", operand.typ)
+			}
+			return &node{typ: typeNumber, eval: func(r *models.HTTPRequest) any { return -operand.eval(r).(float64) }}, nil
+		}
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return literal(tok.text == "true", typeBool), nil
+		}
+		if p.accept("(") {
+			return p.parseCall(tok)
+		}
+		field, ok := fields[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at position %d", tok.text, tok.pos)
+		}
+		return &node{typ: field.typ, eval: func(r *models.HTTPRequest) any {
+			return field.get(reflect.ValueOf(r).Elem().Field(field.index))
+		}}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *exprParser) parseCall(name token) (*node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	var args []*node
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if fn.args != nil {
+		if len(args) != len(fn.args) {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", name.text, len(fn.args), len(args))
+		}
+		for i, arg := range args {
+			if arg.typ != fn.args[i] {
+				return nil, fmt.Errorf("argument %d of %s must be a %s, got %s", i+1, name.text, fn.args[i], arg.typ)
+			}
+		}
+	}
+	n, err := fn.build(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name.text, err)
+	}
+	return n, nil
+}
+
+// function is a built-in function, args nil when build checks the arguments itself
+type function struct {
+	args  []valueType
+	build func(args []*node) (*node, error)
+}
+
+var functions = map[string]function{
+	"contains":   stringPredicate(strings.Contains),
+	"startsWith": stringPredicate(strings.HasPrefix),
+	"endsWith":   stringPredicate(strings.HasSuffix),
+	"lower":      stringMapper(strings.ToLower),
+	"upper":      stringMapper(strings.ToUpper),
+	"trimPrefix": stringBinary(strings.TrimPrefix),
+	"trimSuffix": stringBinary(strings.TrimSuffix),
+	"matches": {args: []valueType{typeString, typeString}, build: func(args []*node) (*node, error) {
+		pattern, err := constantRegexp(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any { return pattern.MatchString(args[0].eval(r).(string)) }}, nil
+	}},
+	"replace": {args: []valueType{typeString, typeString, typeString}, build: func(args []*node) (*node, error) {
+		pattern, err := constantRegexp(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: typeString, eval: func(r *models.HTTPRequest) any {
+			return pattern.ReplaceAllString(args[0].eval(r).(string), args[2].eval(r).(string))
+		}}, nil
+	}},
+	"segment": {args: []valueType{typeString, typeNumber}, build: func(args []*node) (*node, error) {
+		return &node{typ: typeString, eval: func(r *models.HTTPRequest) any {
+			segments := strings.Split(strings.Trim(args[0].eval(r).(string), "/"), "/")
+			if i := int(args[1].eval(r).(float64)); i >= 1 && i <= len(segments) {
+				return segments[i-1]
+			}
+			return ""
+		}}, nil
+	}},
+	"inCIDR": {args: []valueType{typeString, typeString}, build: func(args []*node) (*node, error) {
+		if !args[1].constant {
+			return nil, fmt.Errorf("the network must be a string literal")
+		}
+		_, network, err := net.ParseCIDR(args[1].eval(nil).(string))
+		if err != nil {
+			return nil, err
+		}
+		return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any {
+			ip := net.ParseIP(args[0].eval(r).(string))
+			return ip != nil && network.Contains(ip)
+		}}, nil
+	}},
+	"string": {build: func(args []*node) (*node, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes 1 argument, got %d", len(args))
+		}
+		arg := args[0]
+		return &node{typ: typeString, eval: func(r *models.HTTPRequest) any { return formatValue(arg.eval(r)) }}, nil
+	}},
+	"if": {build: func(args []*node) (*node, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("takes 3 arguments, got %d", len(args))
+		}
+		if args[0].typ != typeBool || args[1].typ != args[2].typ {
+			return nil, fmt.Errorf("needs a bool condition and two values of the same type")
+		}
+		return &node{typ: args[1].typ, eval: func(r *models.HTTPRequest) any {
+			if args[0].eval(r).(bool) {
+				return args[1].eval(r)
+			}
+			return args[2].eval(r)
+		}}, nil
+	}},
+}
+
+func stringPredicate(predicate func(s, arg string) bool) function {
+	return function{args: []valueType{typeString, typeString}, build: func(args []*node) (*node, error) {
+		return &node{typ: typeBool, eval: func(r *models.HTTPRequest) any {
+			return predicate(args[0].eval(r).(string), args[1].eval(r).(string))
+		}}, nil
+	}}
+}
+
+func stringBinary(fn func(s, arg string) string) function {
+	return function{args: []valueType{typeString, typeString}, build: func(args []*node) (*node, error) {
+		return &node{typ: typeString, eval: func(r *models.HTTPRequest) any {
+			return fn(args[0].eval(r).(string), args[1].eval(r).(string))
+		}}, nil
+	}}
+}
+
+func stringMapper(fn func(s string) string) function {
+	return function{args: []valueType{typeString}, build: func(args []*node) (*node, error) {
+		return &node{typ: typeString, eval: func(r *models.HTTPRequest) any { return fn(args[0].eval(r).(string)) }}, nil
+	}}
+}
+
+// constantRegexp compiles a pattern given as string literal, once when the rules are loaded
+func constantRegexp(pattern *node) (*regexp.Regexp, error) {
+	if !pattern.constant {
+		return nil, fmt.Errorf("the pattern must be a string literal")
+	}
+	return regexp.Compile(pattern.eval(nil).(string))
+}
+
+// formatValue converts an expression value to a string, numbers without trailing zeros
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return v.(string)
+	}
+}
@@ -0,0 +1,256 @@
+// Package transform applies user-defined rules to parsed requests before they are stored: dropping
+// requests, rewriting fields (hosts, paths) and setting labels, with small expressions such as
+// `startsWith(path, "/api/") && status_code >= 500`, so site-specific logic needs no code change.
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"loglynx/internal/database/models"
+	"loglynx/internal/database/repositories"
+
+	"gorm.io/gorm/schema"
+)
+
+// field is a request column usable in expressions
+type field struct {
+	index    int
+	typ      valueType
+	writable bool
+	get      func(value reflect.Value) any
+	set      func(value reflect.Value, v any)
+}
+
+// fields maps column names (host, status_code, geo_country...) to the request fields
+var fields = requestFields()
+
+func requestFields() map[string]field {
+	// Identity, dedup and storage columns can't be read or rewritten
+	hidden := map[string]bool{"ID": true, "RequestHash": true, "PartitionKey": true, "CreatedAt": true, "Timestamp": true, "LogSource": true}
+	readOnly := map[string]bool{"SourceName": true, "ProxyMetadata": true}
+
+	naming := schema.NamingStrategy{}
+	requestType := reflect.TypeOf(models.HTTPRequest{})
+	result := make(map[string]field)
+	for i := 0; i < requestType.NumField(); i++ {
+		structField := requestType.Field(i)
+		if hidden[structField.Name] {
+			continue
+		}
+		f := field{index: i, writable: !readOnly[structField.Name]}
+		switch structField.Type.Kind() {
+		case reflect.String:
+			f.typ = typeString
+			f.get = func(v reflect.Value) any { return v.String() }
+			f.set = func(v reflect.Value, value any) { v.SetString(value.(string)) }
+		case reflect.Int, reflect.Int64:
+			f.typ = typeNumber
+			f.get = func(v reflect.Value) any { return float64(v.Int()) }
+			f.set = func(v reflect.Value, value any) { v.SetInt(int64(value.(float64))) }
+		case reflect.Float64:
+			f.typ = typeNumber
+			f.get = func(v reflect.Value) any { return v.Float() }
+			f.set = func(v reflect.Value, value any) { v.SetFloat(value.(float64)) }
+		case reflect.Bool:
+			f.typ = typeBool
+			f.get = func(v reflect.Value) any { return v.Bool() }
+			f.set = func(v reflect.Value, value any) { v.SetBool(value.(bool)) }
+		default:
+			continue
+		}
+		result[naming.ColumnName("", structField.Name)] = f
+	}
+	return result
+}
+
+// Rule is a transform rule: when If matches, the request is dropped or its fields and tags are set
+// Set and Tags values are expressions evaluated on the request before the rule changes it.
+type Rule struct {
+	Name string            `json:"name"`
+	If   string            `json:"if,omitempty"`   // Condition, empty matches every request
+	Drop bool              `json:"drop,omitempty"` // Don't store matching requests
+	Set  map[string]string `json:"set,omitempty"`  // Field name to expression, e.g. "host": "lower(host)"
+	Tags map[string]string `json:"tags,omitempty"` // Label name to expression, stored as request labels
+
+	condition *node
+	sets      []assignment
+	tags      []assignment
+}
+
+type assignment struct {
+	name  string
+	field field // Unset for tags
+	value *node
+}
+
+// Rules are the transform rules, applied in order
+type Rules struct {
+	rules []Rule
+}
+
+// LoadRules reads transform rules from a JSON file (array of rules)
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transform rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse transform rules: %w", err)
+	}
+	return NewRules(rules)
+}
+
+// NewRules compiles the rules, reporting the first invalid expression
+func NewRules(rules []Rule) (*Rules, error) {
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid transform rule %q: %w", rules[i].Name, err)
+		}
+	}
+	return &Rules{rules: rules}, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !r.Drop && len(r.Set) == 0 && len(r.Tags) == 0 {
+		return fmt.Errorf("rule does nothing, set drop, set or tags")
+	}
+	if r.Drop && (len(r.Set) > 0 || len(r.Tags) > 0) {
+		return fmt.Errorf("a dropping rule can't set fields or tags")
+	}
+
+	if r.If != "" {
+		condition, err := compile(r.If)
+		if err != nil {
+			return fmt.Errorf("if: %w", err)
+		}
+		if condition.typ != typeBool {
+			return fmt.Errorf("if: condition is a %s, not a bool", condition.typ)
+		}
+		r.condition = condition
+	}
+
+	r.sets, r.tags = nil, nil
+	for _, name := range sortedKeys(r.Set) {
+		f, ok := fields[name]
+		if !ok || !f.writable {
+			return fmt.Errorf("set: %q is not a field that can be set", name)
+		}
+		value, err := compile(r.Set[name])
+		if err != nil {
+			return fmt.Errorf("set %s: %w", name, err)
+		}
+		if value.typ != f.typ {
+			return fmt.Errorf("set %s: expression is a %s, the field is a %s", name, value.typ, f.typ)
+		}
+		r.sets = append(r.sets, assignment{name: name, field: f, value: value})
+	}
+	for _, name := range sortedKeys(r.Tags) {
+		label := strings.ToLower(name)
+		if err := repositories.ValidateLabelName(label); err != nil {
+			return fmt.Errorf("tags: %w", err)
+		}
+		value, err := compile(r.Tags[name])
+		if err != nil {
+			return fmt.Errorf("tag %s: %w", name, err)
+		}
+		r.tags = append(r.tags, assignment{name: label, value: value})
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of rules
+func (r *Rules) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Apply runs the rules on a request, returning false when a rule drops it
+// Rules see the changes of the rules before them. A nil Rules keeps every request unchanged.
+func (r *Rules) Apply(request *models.HTTPRequest) bool {
+	if r == nil {
+		return true
+	}
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if rule.condition != nil && !rule.condition.eval(request).(bool) {
+			continue
+		}
+		if rule.Drop {
+			return false
+		}
+
+		// Values are computed first, so assignments of a rule don't see each other
+		values := make([]any, len(rule.sets))
+		for j, set := range rule.sets {
+			values[j] = set.value.eval(request)
+		}
+		tags := make(map[string]string, len(rule.tags))
+		for _, tag := range rule.tags {
+			tags[tag.name] = formatValue(tag.value.eval(request))
+		}
+
+		requestValue := reflect.ValueOf(request).Elem()
+		for j, set := range rule.sets {
+			set.field.set(requestValue.Field(set.field.index), values[j])
+		}
+		if len(rule.sets) > 0 {
+			sanitize(request)
+		}
+		for name, value := range tags {
+			if value == "" {
+				continue
+			}
+			if request.Labels == nil {
+				request.Labels = make(map[string]string)
+			}
+			request.Labels[name] = value
+		}
+	}
+	return true
+}
+
+// sanitize clears values set by rules that the database constraints reject, so one rule doesn't fail batches
+func sanitize(request *models.HTTPRequest) {
+	if request.StatusCode < 0 || request.StatusCode >= 600 {
+		request.StatusCode = 0
+	}
+	if request.UpstreamStatus < 0 || request.UpstreamStatus >= 600 {
+		request.UpstreamStatus = 0
+	}
+	if request.ClientPort < 0 || request.ClientPort > 65535 {
+		request.ClientPort = 0
+	}
+	switch request.RequestScheme {
+	case "", "http", "https", "ws", "wss":
+	default:
+		request.RequestScheme = ""
+	}
+	for _, value := range []*int64{&request.RequestLength, &request.ResponseSize, &request.Duration, &request.RequestHeaderBytes, &request.ResponseHeaderBytes} {
+		*value = max(*value, 0)
+	}
+	for _, value := range []*float64{&request.ResponseTimeMs, &request.UpstreamResponseTimeMs} {
+		*value = max(*value, 0)
+	}
+	request.RetryAttempts = max(request.RetryAttempts, 0)
+	request.RequestsTotal = max(request.RequestsTotal, 0)
+}
@@ -0,0 +1,87 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"loglynx/internal/database/models"
+)
+
+func TestApply(t *testing.T) {
+	rules, err := NewRules([]Rule{
+		{Name: "health checks", If: `path == "/healthz" || contains(user_agent, "kube-probe")`, Drop: true},
+		{Name: "canonical host", If: `startsWith(host, "www.")`, Set: map[string]string{"host": `trimPrefix(host, "www.")`}},
+		{Name: "api version", If: `matches(path, "^/api/v[0-9]+/")`, Tags: map[string]string{
+			"api_version": `segment(path, 2)`,
+			"class":       `if(status_code >= 500, "failed", "ok")`,
+		}},
+		{Name: "internal", If: `inCIDR(client_ip, "10.0.0.0/8") && !(geo_country == "DE")`, Set: map[string]string{
+			"client_user":     `"internal-" + lower(method)`,
+			"status_code":     `status_code + 1000`,
+			"response_size":   `response_size - 1`,
+			"backend_name":    `replace(backend_name, "@[a-z]+$", "")`,
+			"client_hostname": `string(response_time_ms)`,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+
+	if rules.Apply(&models.HTTPRequest{Path: "/healthz"}) {
+		t.Error("health check not dropped")
+	}
+	if rules.Apply(&models.HTTPRequest{Path: "/", UserAgent: "kube-probe/1.29"}) {
+		t.Error("kube-probe request not dropped")
+	}
+
+	request := &models.HTTPRequest{
+		Host: "www.example.com", Path: "/api/v2/users", StatusCode: 503,
+		ClientIP: "10.1.2.3", Method: "POST", BackendName: "users@docker", ResponseTimeMs: 12.5,
+	}
+	if !rules.Apply(request) {
+		t.Fatal("request dropped")
+	}
+	if request.Host != "example.com" {
+		t.Errorf("host = %q, want example.com", request.Host)
+	}
+	if request.Labels["api_version"] != "v2" || request.Labels["class"] != "failed" || len(request.Labels) != 2 {
+		t.Errorf("labels = %v", request.Labels)
+	}
+	if request.ClientUser != "internal-post" || request.BackendName != "users" || request.ClientHostname != "12.5" {
+		t.Errorf("set fields = %q %q %q", request.ClientUser, request.BackendName, request.ClientHostname)
+	}
+	if request.StatusCode != 0 || request.ResponseSize != 0 {
+		t.Errorf("out of range values kept: status %d, size %d", request.StatusCode, request.ResponseSize)
+	}
+
+	var none *Rules
+	if !none.Apply(&models.HTTPRequest{Path: "/healthz"}) {
+		t.Error("nil rules dropped a request")
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{Name: "noop", If: `true`}, "does nothing"},
+		{Rule{Name: "drop and set", Drop: true, Set: map[string]string{"host": `"x"`}}, "can't set"},
+		{Rule{Name: "not bool", If: `host`, Drop: true}, "not a bool"},
+		{Rule{Name: "unknown field", If: `hostname == "x"`, Drop: true}, `unknown field "hostname"`},
+		{Rule{Name: "type mismatch", If: `status_code == "404"`, Drop: true}, "can't compare number with string"},
+		{Rule{Name: "dynamic pattern", If: `matches(path, host)`, Drop: true}, "string literal"},
+		{Rule{Name: "bad pattern", If: `matches(path, "(")`, Drop: true}, "missing closing )"},
+		{Rule{Name: "read-only", Set: map[string]string{"source_name": `"x"`}}, "not a field that can be set"},
+		{Rule{Name: "wrong type", Set: map[string]string{"status_code": `"200"`}}, "the field is a number"},
+		{Rule{Name: "syntax", If: `(path == "/"`, Drop: true}, `expected ")"`},
+		{Rule{Name: "tag name", Tags: map[string]string{"a b": `"x"`}}, "invalid label name"},
+		{Rule{Name: "arity", If: `contains(path)`, Drop: true}, "takes 2 arguments"},
+	}
+	for _, tt := range tests {
+		_, err := NewRules([]Rule{tt.rule})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewRules(%s) error = %v, want %q", tt.rule.Name, err, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ const LogLynxAPI = {
     baseURL: '/api/v1',
     cache: new Map(),
     cacheTimeout: 30000, // 30 seconds default cache
+    slowResponseMs: 2000, // Server-side durations logged as slow
     currentServices: [], // Array of selected services [{name: 'X', type: 'backend_name'}, ...]
     currentServiceType: 'auto', // Currently selected service type (auto, backend_name, backend_url, host)
     hideMyTraffic: false, // Whether to hide own IP traffic
@@ -29,23 +30,51 @@ const LogLynxAPI = {
             const response = await fetch(url);
 
             if (!response.ok) {
-                throw new Error(`HTTP ${response.status}: ${response.statusText}`);
+                // The request ID matches the call with the server logs (slow queries, errors)
+                const requestId = response.headers.get('X-Request-ID');
+                throw new Error(`HTTP ${response.status}: ${response.statusText}` + (requestId ? ` (request ${requestId})` : ''));
             }
 
-            const data = await response.json();
+            const json = await response.json();
+
+            // Stats endpoints wrap their payload with metadata (effective time range)
+            const { data, meta } = this.unwrap(json);
+
+            // Slow panels show up in the console with the server-side duration and filters
+            if (meta && meta.query_ms > this.slowResponseMs) {
+                console.warn(`Slow API response [${endpoint}]: ${meta.query_ms.toFixed(0)} ms on the server`, meta);
+            }
+
+            // Queries timing out are answered with the last result or over a reduced range
+            if (meta && meta.stale) {
+                console.warn(`Stale API response [${endpoint}]: the query timed out, showing data computed at ${meta.computed_at}`, meta);
+            } else if (meta && meta.requested_hours) {
+                console.warn(`Reduced time range [${endpoint}]: ${meta.range.hours}h instead of ${meta.requested_hours}h after query timeouts`, meta);
+            }
 
             // Store in cache if enabled
             if (useCache) {
                 this.setCache(url, data);
             }
 
-            return { success: true, data };
+            return { success: true, data, meta };
         } catch (error) {
             console.error(`API Error [${endpoint}]:`, error);
             return { success: false, error: error.message };
         }
     },
 
+    /**
+     * Split a stats response envelope into payload and metadata
+     * Responses without an envelope are returned unchanged
+     */
+    unwrap(json) {
+        if (json && typeof json === 'object' && !Array.isArray(json) && 'data' in json && 'meta' in json) {
+            return { data: json.data, meta: json.meta };
+        }
+        return { data: json, meta: null };
+    },
+
     /**
      * Build URL with query parameters and service filter
      */
@@ -53,10 +82,15 @@ const LogLynxAPI = {
         const url = new URL(this.baseURL + endpoint, window.location.origin);
 
         // Add service filters if set (multiple services)
+        // A service may carry a matchType (prefix, wildcard, regex) to select a family of services
         if (this.currentServices && this.currentServices.length > 0) {
+            const withMatchTypes = this.currentServices.some(service => service.matchType);
             this.currentServices.forEach(service => {
                 url.searchParams.append('services[]', service.name);
                 url.searchParams.append('service_types[]', service.type);
+                if (withMatchTypes) {
+                    url.searchParams.append('service_match_types[]', service.matchType || 'exact');
+                }
             });
         }
 
@@ -66,9 +100,13 @@ const LogLynxAPI = {
 
             // Add exclude services if specified
             if (this.hideTrafficServices && this.hideTrafficServices.length > 0) {
+                const withMatchTypes = this.hideTrafficServices.some(service => service.matchType);
                 this.hideTrafficServices.forEach(service => {
                     url.searchParams.append('exclude_services[]', service.name);
                     url.searchParams.append('exclude_service_types[]', service.type);
+                    if (withMatchTypes) {
+                        url.searchParams.append('exclude_service_match_types[]', service.matchType || 'exact');
+                    }
                 });
             }
         }
@@ -207,12 +245,27 @@ const LogLynxAPI = {
         return this.get('/stats/summary');
     },
 
+    /**
+     * Get everything the overview page shows in one request
+     * (summary, timelines, status code distribution, top paths and countries)
+     * @param {number} hours - Time range in hours (1-8760)
+     * @param {number} limit - Number of top paths and countries
+     */
+    async getOverview(hours = 168, limit = 10) {
+        return this.get('/dashboard/overview', { hours, limit });
+    },
+
     /**
      * Get timeline data
      * @param {number} hours - Number of hours to fetch (1-8760)
+     * @param {number} baselineWeeks - Previous weeks averaged into each bucket's baseline (0 = none, ranges up to 720 hours)
      */
-    async getTimeline(hours = 168) {
-        return this.get('/stats/timeline', { hours });
+    async getTimeline(hours = 168, baselineWeeks = 0) {
+        const params = { hours };
+        if (baselineWeeks > 0) {
+            params.baseline_weeks = baselineWeeks;
+        }
+        return this.get('/stats/timeline', params);
     },
 
     /**
@@ -223,6 +276,30 @@ const LogLynxAPI = {
         return this.get('/stats/timeline/status-codes', { hours });
     },
 
+    /**
+     * Get requests per continent over time
+     * @param {number} hours - Number of hours to fetch
+     */
+    async getContinentTimeline(hours = 168) {
+        return this.get('/stats/timeline/continents', { hours });
+    },
+
+    /**
+     * Get EU / non-EU requests over time
+     * @param {number} hours - Number of hours to fetch
+     */
+    async getEUTimeline(hours = 168) {
+        return this.get('/stats/timeline/eu', { hours });
+    },
+
+    /**
+     * Get version adoption of a client over time
+     * @param {Object} target - { client } (a CLIENT_VERSION_RULES name) or { browser }
+     */
+    async getClientVersionTimeline({ client, browser } = {}) {
+        return this.get('/stats/timeline/client-versions', { client, browser });
+    },
+
     /**
      * Get traffic heatmap data
      * @param {number} days - Number of days (1-365)
@@ -231,84 +308,155 @@ const LogLynxAPI = {
         return this.get('/stats/heatmap/traffic', { days });
     },
 
+    /**
+     * Build the query parameters of a top-N endpoint
+     * @param {number} limit - Number of results
+     * @param {Object} options - {offset, sortBy, sortDir, minHits, statusClass, pathPrefix}
+     *   sortBy: hits, bandwidth, error_count or avg_response_time; statusClass: 1xx to 5xx
+     */
+    topParams(limit, { offset, sortBy, sortDir, minHits, statusClass, pathPrefix } = {}) {
+        return {
+            limit,
+            offset,
+            sort_by: sortBy,
+            sort_dir: sortDir,
+            min_hits: minHits,
+            status_class: statusClass,
+            path_prefix: pathPrefix
+        };
+    },
+
     /**
      * Get top paths
      * @param {number} limit - Number of results (1-100)
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopPaths(limit = 10) {
-        return this.get('/stats/top/paths', { limit });
+    async getTopPaths(limit = 10, options = {}) {
+        return this.get('/stats/top/paths', this.topParams(limit, options));
     },
 
     /**
      * Get top countries
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopCountries(limit = 10) {
-        return this.get('/stats/top/countries', { limit });
+    async getTopCountries(limit = 10, options = {}) {
+        return this.get('/stats/top/countries', this.topParams(limit, options));
     },
 
     /**
      * Get top IP addresses
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopIPs(limit = 10) {
-        return this.get('/stats/top/ips', { limit });
+    async getTopIPs(limit = 10, options = {}) {
+        return this.get('/stats/top/ips', this.topParams(limit, options));
     },
 
     /**
      * Get top user agents
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopUserAgents(limit = 10) {
-        return this.get('/stats/top/user-agents', { limit });
+    async getTopUserAgents(limit = 10, options = {}) {
+        return this.get('/stats/top/user-agents', this.topParams(limit, options));
     },
 
     /**
      * Get top browsers
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopBrowsers(limit = 10) {
-        return this.get('/stats/top/browsers', { limit });
+    async getTopBrowsers(limit = 10, options = {}) {
+        return this.get('/stats/top/browsers', this.topParams(limit, options));
     },
 
     /**
      * Get top operating systems
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopOperatingSystems(limit = 10) {
-        return this.get('/stats/top/operating-systems', { limit });
+    async getTopOperatingSystems(limit = 10, options = {}) {
+        return this.get('/stats/top/operating-systems', this.topParams(limit, options));
     },
 
     /**
      * Get top ASNs
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopASNs(limit = 10) {
-        return this.get('/stats/top/asns', { limit });
+    async getTopASNs(limit = 10, options = {}) {
+        return this.get('/stats/top/asns', this.topParams(limit, options));
     },
 
     /**
      * Get top backends
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopBackends(limit = 10) {
-        return this.get('/stats/top/backends', { limit });
+    async getTopBackends(limit = 10, options = {}) {
+        return this.get('/stats/top/backends', this.topParams(limit, options));
+    },
+
+    /**
+     * Get the origin instances (upstream addresses) of each backend
+     * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
+     */
+    async getTopUpstreams(limit = 20, options = {}) {
+        return this.get('/stats/top/upstreams', this.topParams(limit, options));
+    },
+
+    /**
+     * Get top routers (Traefik RouterName)
+     * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
+     */
+    async getTopRouters(limit = 10, options = {}) {
+        return this.get('/stats/top/routers', this.topParams(limit, options));
+    },
+
+    /**
+     * Get router drill-down (percentiles, timeline, status codes, top paths and services)
+     * @param {string} router - Router name, e.g. "api@docker"
+     */
+    async getRouterDetail(router) {
+        return this.get(`/stats/routers/${encodeURIComponent(router)}`);
+    },
+
+    /**
+     * Get top API consumers (authenticated users and API keys) with their quota usage
+     * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
+     */
+    async getTopConsumers(limit = 10, options = {}) {
+        return this.get('/stats/top/consumers', this.topParams(limit, options));
+    },
+
+    /**
+     * Get API consumer drill-down (quota usage, percentiles, timeline, status codes, top paths and client IPs)
+     * @param {string} consumer - User name, or "key:" and the API key fingerprint
+     */
+    async getConsumerDetail(consumer) {
+        return this.get(`/stats/consumers/${encodeURIComponent(consumer)}`);
     },
 
     /**
      * Get top referrers
      * @param {number} limit - Number of results
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopReferrers(limit = 10) {
-        return this.get('/stats/top/referrers', { limit });
+    async getTopReferrers(limit = 10, options = {}) {
+        return this.get('/stats/top/referrers', this.topParams(limit, options));
     },
 
     /**
      * Get top referrer domains
      * @param {number} limit - Number of results (0 = unlimited)
+     * @param {Object} options - Optional page and thresholds, see topParams
      */
-    async getTopReferrerDomains(limit = 10) {
-        return this.get('/stats/top/referrer-domains', { limit });
+    async getTopReferrerDomains(limit = 10, options = {}) {
+        return this.get('/stats/top/referrer-domains', this.topParams(limit, options));
     },
 
     /**
@@ -325,6 +473,13 @@ const LogLynxAPI = {
         return this.get('/stats/distribution/methods');
     },
 
+    /**
+     * Get unusual and disallowed HTTP methods with their top sources and timeline
+     */
+    async getUnusualMethods() {
+        return this.get('/stats/methods/unusual');
+    },
+
     /**
      * Get protocol distribution
      */
@@ -346,6 +501,20 @@ const LogLynxAPI = {
         return this.get('/stats/distribution/device-types');
     },
 
+    /**
+     * Get requests per continent
+     */
+    async getContinentDistribution() {
+        return this.get('/stats/distribution/continents');
+    },
+
+    /**
+     * Get EU / non-EU request distribution
+     */
+    async getEUDistribution() {
+        return this.get('/stats/distribution/eu');
+    },
+
     /**
      * Get response time statistics
      */
@@ -353,6 +522,29 @@ const LogLynxAPI = {
         return this.get('/stats/performance/response-time');
     },
 
+    /**
+     * Get client abort (499/408) analytics with the most affected paths and services
+     */
+    async getClientAbortReport(limit = 20) {
+        return this.get('/stats/performance/client-aborts', { limit });
+    },
+
+    /**
+     * Get the share of requests missing key fields (host, response time, country, backend) per source
+     * @param {number} hours - Time range in hours
+     */
+    async getDataQuality(hours = 24) {
+        return this.get('/stats/data-quality', { hours });
+    },
+
+    /**
+     * Get estimated egress cost over time, per path and per service
+     * @param {number} limit - Number of paths and services
+     */
+    async getCostReport(limit = 20) {
+        return this.get('/stats/cost', { limit });
+    },
+
     /**
      * Get log processing statistics
      */
@@ -375,6 +567,20 @@ const LogLynxAPI = {
         return this.get('/system/timeline', { days });
     },
 
+    /**
+     * Get database size breakdown (tables, indexes, rows per month, growth)
+     */
+    async getSystemStorage() {
+        return this.get('/system/storage');
+    },
+
+    /**
+     * Get index usage of the queries seen since startup and index profile advice
+     */
+    async getSystemIndexes() {
+        return this.get('/system/indexes');
+    },
+
     /**
      * Get recent requests
      * @param {number} limit - Number of results (1-1000)
@@ -384,6 +590,15 @@ const LogLynxAPI = {
         return this.get('/requests/recent', { limit, offset });
     },
 
+    /**
+     * Get requests slower than a threshold, with counts by path and backend
+     * @param {number|null} threshold - Minimum response time in milliseconds, null for the configured per-service thresholds
+     * @param {number} limit - Number of rows (1-1000)
+     */
+    async getSlowRequests(threshold = null, limit = 100) {
+        return this.get('/requests/slow', { threshold, limit });
+    },
+
     /**
      * Get available domains/services
      * DEPRECATED: Use getServices() instead
@@ -399,6 +614,20 @@ const LogLynxAPI = {
         return this.get('/services', {}, true); // Cache this
     },
 
+    /**
+     * Get the distinct values of a filter field with their request counts, for dropdowns and autocomplete
+     * @param {string} field - host, backend_name, browser, os or geo_country
+     * @param {string} query - Keep the values containing it (optional)
+     * @param {number} limit - Number of values (1-500, default: 50)
+     */
+    async getFieldValues(field, query = '', limit = 50) {
+        const params = { field, limit };
+        if (query) {
+            params.q = query;
+        }
+        return this.get('/meta/values', params);
+    },
+
     // ======================
     // Real-time API Methods
     // ======================
@@ -417,15 +646,39 @@ const LogLynxAPI = {
         return this.get('/realtime/services');
     },
 
+    /**
+     * Get live leaderboards (top IPs, paths, status codes)
+     * @param {string} window - Rolling window ("1m" or "5m")
+     * @param {number} limit - Entries per leaderboard
+     */
+    async getRealtimeTop(window = '1m', limit = 10) {
+        return this.get('/realtime/top', { window, limit });
+    },
+
     /**
      * Connect to real-time SSE stream
      * @param {Function} onMessage - Callback for each message
      * @param {Function} onError - Error callback
+     * @param {Function} onTop - Optional callback for leaderboard updates
      * @returns {EventSource} The event source connection
      */
-    connectRealtimeStream(onMessage, onError) {
-        const url = this.buildURL('/realtime/stream');
-        const eventSource = new EventSource(url);
+    connectRealtimeStream(onMessage, onError, onTop) {
+        const url = new URL(this.buildURL('/realtime/stream'));
+        const token = this.streamToken();
+        if (token) {
+            url.searchParams.set('token', token);
+        }
+        const eventSource = new EventSource(url.toString());
+
+        if (onTop) {
+            eventSource.addEventListener('top', (event) => {
+                try {
+                    onTop(JSON.parse(event.data));
+                } catch (error) {
+                    console.error('Failed to parse leaderboard data:', error);
+                }
+            });
+        }
 
         eventSource.onmessage = (event) => {
             try {
@@ -444,6 +697,23 @@ const LogLynxAPI = {
         return eventSource;
     },
 
+    /**
+     * Token of the real-time stream (STREAM_TOKEN), given once as ?stream_token= in the page URL
+     * @returns {string|null} The token kept by the browser, if any
+     */
+    streamToken() {
+        const key = 'loglynx_stream_token';
+        const fromURL = new URLSearchParams(window.location.search).get('stream_token');
+        try {
+            if (fromURL) {
+                localStorage.setItem(key, fromURL);
+            }
+            return localStorage.getItem(key) || fromURL;
+        } catch (e) {
+            return fromURL;
+        }
+    },
+
     // ======================
     // Batch Loading Methods
     // ======================
@@ -452,23 +722,21 @@ const LogLynxAPI = {
      * Load all data for overview dashboard
      */
     async loadOverviewData(timeRange = 168) {
-        const promises = {
-            summary: this.getSummary(),
-            timeline: this.getTimeline(timeRange),
-            statusTimeline: this.getStatusCodeTimeline(timeRange),
-            statusDist: this.getStatusCodeDistribution(),
-            topCountries: this.getTopCountries(5),
-            topPaths: this.getTopPaths(5),
-            recentRequests: this.getRecentRequests(10)
+        const [overviewResult, recentResult] = await Promise.all([
+            this.getOverview(timeRange, 5),
+            this.getRecentRequests(10)
+        ]);
+        const overview = overviewResult.success ? overviewResult.data : null;
+
+        return {
+            summary: overview ? overview.summary : null,
+            timeline: overview ? overview.timeline : null,
+            statusTimeline: overview ? overview.status_code_timeline : null,
+            statusDist: overview ? overview.status_codes : null,
+            topCountries: overview ? overview.top_countries : null,
+            topPaths: overview ? overview.top_paths : null,
+            recentRequests: recentResult.success ? recentResult.data : null
         };
-
-        const results = {};
-        for (const [key, promise] of Object.entries(promises)) {
-            const result = await promise;
-            results[key] = result.success ? result.data : null;
-        }
-
-        return results;
     },
 
     /**
@@ -701,6 +969,28 @@ const LogLynxAPI = {
      */
     async searchIPs(query, limit = 20) {
         return this.get('/ip/search', { q: query, limit });
+    },
+
+    /**
+     * Get the dashboard settings (theme mode, accent color, logo and brand name)
+     */
+    async getSettings() {
+        return this.get('/settings');
+    },
+
+    /**
+     * Get the supported dashboard locales and the locale of this browser
+     */
+    async getLocales() {
+        return this.get('/i18n', {}, true);
+    },
+
+    /**
+     * Get the labels and number/date formatting hints of a locale
+     * @param {string} locale - Locale code, e.g. 'de' (defaults to the dashboard locale)
+     */
+    async getI18nCatalog(locale = LogLynxUtils.locale()) {
+        return this.get(`/i18n/${encodeURIComponent(locale)}`, {}, true);
     }
 };
 
@@ -84,6 +84,42 @@ const LogLynxCharts = {
         ]
     },
 
+    /**
+     * Adapt the default colors to the dashboard theme (light mode, custom accent color)
+     */
+    applyTheme() {
+        const styles = getComputedStyle(document.documentElement);
+        const css = (name) => styles.getPropertyValue(name).trim();
+
+        const theme = window.LOGLYNX_CONFIG?.theme;
+        const light = theme === 'light' || (theme === 'auto' && window.matchMedia('(prefers-color-scheme: light)').matches);
+        if (light) {
+            const text = css('--loglynx-text');
+            const grid = css('--border-color');
+            this.defaultOptions.plugins.legend.labels.color = text;
+            Object.assign(this.defaultOptions.plugins.tooltip, {
+                backgroundColor: css('--loglynx-card'),
+                titleColor: text,
+                bodyColor: text,
+                borderColor: grid
+            });
+            for (const axis of [this.defaultOptions.scales.x, this.defaultOptions.scales.y]) {
+                axis.ticks.color = text;
+                axis.title.color = text;
+                axis.grid.color = grid;
+            }
+            Chart.defaults.color = text;
+        }
+
+        // A custom accent color replaces the LogLynx orange
+        if (document.documentElement.style.getPropertyValue('--loglynx-primary')) {
+            const accent = css('--loglynx-primary');
+            this.colors.primary = accent;
+            this.colors.primaryLight = accent; // The CSS variant is a color-mix(), which Chart.js can't parse
+            this.colors.chartPalette[0] = accent;
+        }
+    },
+
     /**
      * Create a line chart
      */
@@ -384,7 +420,7 @@ const LogLynxCharts = {
             // Hourly labels (HH:MM format)
             return dataPoints.map(d => {
                 const date = new Date(d.hour);
-                return date.toLocaleTimeString('en-US', {
+                return date.toLocaleTimeString(LogLynxUtils.locale(), {
                     hour: '2-digit',
                     minute: '2-digit',
                     hour12: false
@@ -394,7 +430,7 @@ const LogLynxCharts = {
             // Daily labels with day of week
             return dataPoints.map(d => {
                 const date = new Date(d.hour);
-                return date.toLocaleDateString('en-US', {
+                return date.toLocaleDateString(LogLynxUtils.locale(), {
                     weekday: 'short',
                     month: 'short',
                     day: 'numeric'
@@ -404,7 +440,7 @@ const LogLynxCharts = {
             // Daily labels for 30-day range
             return dataPoints.map(d => {
                 const date = new Date(d.hour);
-                return date.toLocaleDateString('en-US', {
+                return date.toLocaleDateString(LogLynxUtils.locale(), {
                     month: 'short',
                     day: 'numeric'
                 });
@@ -420,7 +456,7 @@ const LogLynxCharts = {
                 // Fallback to date parsing
                 const date = new Date(d.hour);
                 if (!isNaN(date.getTime())) {
-                    return date.toLocaleDateString('en-US', {
+                    return date.toLocaleDateString(LogLynxUtils.locale(), {
                         month: 'short',
                         day: 'numeric'
                     });
@@ -492,7 +528,7 @@ const LogLynxCharts = {
      * Format number with locale
      */
     formatNumber(num) {
-        return num.toLocaleString();
+        return num.toLocaleString(LogLynxUtils.locale());
     },
 
     /**
@@ -599,3 +635,7 @@ const LogLynxCharts = {
 
 // Export for use in other scripts
 window.LogLynxCharts = LogLynxCharts;
+
+if (typeof Chart !== 'undefined') {
+    LogLynxCharts.applyTheme();
+}
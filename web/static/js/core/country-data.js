@@ -55,12 +55,14 @@
         'SO': { name: 'Somalia', continent: 'Africa' },
         'ZA': { name: 'South Africa', continent: 'Africa' },
         'SS': { name: 'South Sudan', continent: 'Africa' },
+        'SH': { name: 'Saint Helena', continent: 'Africa' },
         'SD': { name: 'Sudan', continent: 'Africa' },
         'SZ': { name: 'Eswatini', continent: 'Africa' },
         'TZ': { name: 'Tanzania', continent: 'Africa' },
         'TG': { name: 'Togo', continent: 'Africa' },
         'TN': { name: 'Tunisia', continent: 'Africa' },
         'UG': { name: 'Uganda', continent: 'Africa' },
+        'EH': { name: 'Western Sahara', continent: 'Africa' },
         'ZM': { name: 'Zambia', continent: 'Africa' },
         'ZW': { name: 'Zimbabwe', continent: 'Africa' },
 
@@ -250,7 +252,23 @@
         'TV': { name: 'Tuvalu', continent: 'Oceania' },
         'UM': { name: 'U.S. Minor Outlying Islands', continent: 'Oceania' },
         'VU': { name: 'Vanuatu', continent: 'Oceania' },
-        'WF': { name: 'Wallis and Futuna', continent: 'Oceania' }
+        'WF': { name: 'Wallis and Futuna', continent: 'Oceania' },
+
+        // South America
+        'AR': { name: 'Argentina', continent: 'South America' },
+        'BO': { name: 'Bolivia', continent: 'South America' },
+        'BR': { name: 'Brazil', continent: 'South America' },
+        'CL': { name: 'Chile', continent: 'South America' },
+        'CO': { name: 'Colombia', continent: 'South America' },
+        'EC': { name: 'Ecuador', continent: 'South America' },
+        'FK': { name: 'Falkland Islands', continent: 'South America' },
+        'GF': { name: 'French Guiana', continent: 'South America' },
+        'GY': { name: 'Guyana', continent: 'South America' },
+        'PY': { name: 'Paraguay', continent: 'South America' },
+        'PE': { name: 'Peru', continent: 'South America' },
+        'SR': { name: 'Suriname', continent: 'South America' },
+        'UY': { name: 'Uruguay', continent: 'South America' },
+        'VE': { name: 'Venezuela', continent: 'South America' }
     };
 
     // Convert ISO 3166-1 alpha-2 country code to flag
@@ -81,11 +81,18 @@ const LogLynxUtils = {
         }
     },
 
+    /**
+     * Locale of the dashboard (server-selected from settings or Accept-Language)
+     */
+    locale() {
+        return window.LOGLYNX_CONFIG?.locale || document.documentElement.lang || 'en';
+    },
+
     /**
      * Format number with locale
      */
     formatNumber(num) {
-        return num.toLocaleString();
+        return num.toLocaleString(this.locale());
     },
 
     /**
@@ -131,7 +138,7 @@ const LogLynxUtils = {
      */
     formatDateTime(dateString) {
         const date = new Date(dateString);
-        return date.toLocaleString('en-US', {
+        return date.toLocaleString(this.locale(), {
             year: 'numeric',
             month: 'short',
             day: 'numeric',
@@ -1556,7 +1563,7 @@ function initIPSearch() {
                 clearTimeout(globalIPSearchDebounce);
             }
 
-            if (query.length < 2) {
+            if (query.length < 3) {
                 document.getElementById('globalIPSearchResults').innerHTML = '';
                 return;
             }
@@ -0,0 +1,268 @@
+/**
+ * API Consumer Detail Page
+ * Requests, errors, latency and quota usage of one authenticated user or API key
+ */
+
+let currentConsumer = '';
+let consumerDetail = null;
+
+// Chart instances
+let timelineChart = null;
+let statusCodeChart = null;
+
+// DataTables instances
+let pathsTable = null;
+let ipsTable = null;
+
+/**
+ * Load consumer analytics
+ */
+async function loadConsumerAnalytics(consumer) {
+    if (!consumer) {
+        LogLynxUtils.showNotification('Consumer is required', 'error');
+        return;
+    }
+
+    currentConsumer = consumer;
+    $('#currentConsumer').text(consumer);
+
+    showLoading();
+
+    try {
+        const result = await LogLynxAPI.getConsumerDetail(consumer);
+        if (!result.success) {
+            hideLoading();
+            LogLynxUtils.showNotification(result.error || 'No requests for this consumer in the selected time range', 'warning');
+            return;
+        }
+
+        consumerDetail = result.data;
+        updateConsumerKPIs(consumerDetail.summary, consumerDetail.response_time);
+        updateTimelineChart(consumerDetail.timeline);
+        updateStatusCodeChart(consumerDetail.status_codes);
+        updateResponseTimeStats(consumerDetail.response_time);
+        initPathsTable(consumerDetail.top_paths);
+        initIPsTable(consumerDetail.top_ips);
+
+        hideLoading();
+    } catch (error) {
+        console.error('Error loading consumer analytics:', error);
+        hideLoading();
+        LogLynxUtils.showNotification('Failed to load consumer analytics', 'error');
+    }
+}
+
+/**
+ * Update consumer KPIs
+ */
+function updateConsumerKPIs(summary, responseTime) {
+    $('#consumerKind').text(summary.kind === 'api_key' ? 'API key' : 'User');
+    $('#totalRequests').text(LogLynxUtils.formatNumber(summary.hits));
+    $('#requestsPerMinute').text(summary.requests_per_minute.toFixed(2));
+    $('#errorRate').text(summary.error_rate.toFixed(1) + '%');
+    $('#errorCount').text(LogLynxUtils.formatNumber(summary.error_count));
+    $('#avgResponseTime').text(LogLynxUtils.formatDuration(summary.avg_response_time));
+    $('#p95ResponseTime').text(LogLynxUtils.formatDuration(responseTime.p95));
+    $('#totalBandwidth').text(LogLynxUtils.formatBytes(summary.bandwidth));
+    $('#uniqueIPs').text(LogLynxUtils.formatNumber(summary.unique_ips));
+    $('#lastSeen').text(LogLynxUtils.formatDateTime(summary.last_seen));
+
+    const quota = summary.quota;
+    if (!quota) {
+        $('#quotaUsage').text('-').css('color', '');
+        $('#quotaSubtitle').text('No quota configured');
+        return;
+    }
+
+    let color = '#28a745';
+    if (quota.exceeded) {
+        color = '#dc3545';
+    } else if (quota.used_percent >= 80) {
+        color = '#ffc107';
+    }
+    $('#quotaUsage').text(quota.used_percent.toFixed(1) + '%').css('color', color);
+    $('#quotaSubtitle').text(`${LogLynxUtils.formatNumber(quota.used)} / ${LogLynxUtils.formatNumber(quota.limit)} this ${quota.period}, resets ${LogLynxUtils.formatDateTime(quota.resets_at)}`);
+}
+
+/**
+ * Update timeline chart
+ */
+function updateTimelineChart(data) {
+    const ctx = document.getElementById('timelineChart');
+    if (!ctx) return;
+
+    if (timelineChart) {
+        timelineChart.destroy();
+    }
+
+    timelineChart = new Chart(ctx, {
+        type: 'line',
+        data: {
+            labels: data.map(d => d.hour),
+            datasets: [
+                {
+                    label: 'Requests',
+                    data: data.map(d => d.requests),
+                    borderColor: LogLynxCharts.colors.primary,
+                    backgroundColor: LogLynxCharts.colors.primaryAlpha,
+                    tension: 0.4,
+                    fill: true
+                },
+                {
+                    label: 'Errors',
+                    data: data.map(d => d.errors),
+                    borderColor: LogLynxCharts.colors.danger,
+                    tension: 0.4,
+                    fill: false
+                }
+            ]
+        },
+        options: {
+            ...LogLynxCharts.defaultOptions,
+            interaction: {
+                mode: 'index',
+                intersect: false
+            }
+        }
+    });
+}
+
+/**
+ * Update status code chart
+ */
+function updateStatusCodeChart(data) {
+    const ctx = document.getElementById('statusCodeChart');
+    if (!ctx) return;
+
+    if (statusCodeChart) {
+        statusCodeChart.destroy();
+    }
+
+    const colors = data.map(d => {
+        const code = d.status_code;
+        if (code >= 200 && code < 300) return LogLynxCharts.colors.success;
+        if (code >= 300 && code < 400) return LogLynxCharts.colors.info;
+        if (code >= 400 && code < 500) return LogLynxCharts.colors.warning;
+        return LogLynxCharts.colors.danger;
+    });
+
+    statusCodeChart = new Chart(ctx, {
+        type: 'doughnut',
+        data: {
+            labels: data.map(d => `${d.status_code}`),
+            datasets: [{
+                data: data.map(d => d.count),
+                backgroundColor: colors,
+                borderColor: '#1f1f21',
+                borderWidth: 2
+            }]
+        },
+        options: {
+            ...LogLynxCharts.defaultOptions,
+            plugins: {
+                legend: { display: true, position: 'bottom' }
+            }
+        }
+    });
+}
+
+/**
+ * Update response time statistics
+ */
+function updateResponseTimeStats(stats) {
+    $('#rtMin').text(LogLynxUtils.formatDuration(stats.min));
+    $('#rtMax').text(LogLynxUtils.formatDuration(stats.max));
+    $('#rtAvg').text(LogLynxUtils.formatDuration(stats.avg));
+    $('#rtP50').text(LogLynxUtils.formatDuration(stats.p50));
+    $('#rtP95').text(LogLynxUtils.formatDuration(stats.p95));
+    $('#rtP99').text(LogLynxUtils.formatDuration(stats.p99));
+}
+
+/**
+ * Initialize top paths table
+ */
+function initPathsTable(data) {
+    if (pathsTable) {
+        pathsTable.destroy();
+    }
+
+    const tableData = data.map(item => [
+        `<code>${LogLynxUtils.truncate($('<div>').text(item.name).html(), 60)}</code>`,
+        LogLynxUtils.formatNumber(item.hits),
+        LogLynxUtils.formatNumber(item.error_count),
+        LogLynxUtils.formatDuration(item.avg_response_time)
+    ]);
+
+    pathsTable = $('#pathsTable').DataTable({
+        data: tableData,
+        pageLength: 10,
+        order: [],
+        columnDefs: [
+            { targets: [1, 2, 3], className: 'text-end' }
+        ],
+        ...LogLynxCharts.defaultDataTableOptions
+    });
+}
+
+/**
+ * Initialize client IPs table, IPs link to their drill-down
+ */
+function initIPsTable(data) {
+    if (ipsTable) {
+        ipsTable.destroy();
+    }
+
+    const tableData = data.map(item => [
+        `<a href="/ip/${item.name}" class="ip-link"><code>${item.name}</code></a>`,
+        LogLynxUtils.formatNumber(item.hits),
+        LogLynxUtils.formatNumber(item.error_count),
+        LogLynxUtils.formatDuration(item.avg_response_time)
+    ]);
+
+    ipsTable = $('#ipsTable').DataTable({
+        data: tableData,
+        pageLength: 10,
+        order: [],
+        columnDefs: [
+            { targets: [1, 2, 3], className: 'text-end' }
+        ],
+        ...LogLynxCharts.defaultDataTableOptions
+    });
+}
+
+/**
+ * Export the consumer drill-down as JSON
+ */
+function exportConsumerJSON() {
+    if (!consumerDetail) {
+        LogLynxUtils.showNotification('No data available to export', 'error');
+        return;
+    }
+
+    const exportData = {
+        consumer: currentConsumer,
+        generated_at: new Date().toISOString(),
+        data: consumerDetail
+    };
+
+    const blob = new Blob([JSON.stringify(exportData, null, 2)], { type: 'application/json' });
+    const url = URL.createObjectURL(blob);
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = `consumer-analytics-${currentConsumer.replace(/[^\w.-]/g, '_')}-${Date.now()}.json`;
+    a.click();
+    URL.revokeObjectURL(url);
+
+    LogLynxUtils.showNotification('JSON exported successfully', 'success');
+}
+
+/**
+ * Show/hide loading overlay
+ */
+function showLoading() {
+    $('#loadingOverlay').show();
+}
+
+function hideLoading() {
+    $('#loadingOverlay').hide();
+}
@@ -62,7 +62,7 @@ function updateGeoKPIs(countriesData) {
     // Count continents
     const continents = new Set();
     countriesData.forEach(country => {
-        const continent = countryToContinentMap[country.country]?.name || 'Other';
+        const continent = country.continent_name || 'Other';
         continents.add(continent);
     });
     $('#totalContinents').text(continents.size);
@@ -265,7 +265,7 @@ function updateContinentChart(countriesData) {
 
     const continentData = {};
     countriesData.forEach(country => {
-        const continent = countryToContinentMap[country.country]?.name || 'Other';
+        const continent = country.continent_name || 'Other';
         continentData[continent] = (continentData[continent] || 0) + country.hits;
     });
 
@@ -760,7 +760,7 @@ $('#ipSearchInput').on('input', function() {
         clearTimeout(searchDebounceTimer);
     }
 
-    if (query.length < 2) {
+    if (query.length < 3) {
         $('#ipSearchResults').hide();
         return;
     }
@@ -8,40 +8,17 @@ let currentTimeRange = 168; // Default 7 days
 // Load all dashboard data
 async function loadDashboardData() {
     try {
-        // Load summary stats
-        const summaryResult = await LogLynxAPI.getSummary();
-        if (summaryResult.success) {
-            updateSummaryCards(summaryResult.data);
-        }
-        
+        // Load all widgets with a single request
         const timelineHours = currentTimeRange === 'all' ? 8760 : currentTimeRange;
-        const timelineResult = await LogLynxAPI.getTimeline(timelineHours);
-        if (timelineResult.success) {
-            updateTimelineChart(timelineResult.data);
-        }
-
-        // Load status code timeline
-        const statusTimelineResult = await LogLynxAPI.getStatusCodeTimeline(timelineHours);
-        if (statusTimelineResult.success) {
-            updateStatusTimelineChart(statusTimelineResult.data);
-        }
-
-        // Load status code distribution
-        const statusDistResult = await LogLynxAPI.getStatusCodeDistribution();
-        if (statusDistResult.success) {
-            updateStatusChart(statusDistResult.data);
-        }
-
-        // Load top countries
-        const countriesResult = await LogLynxAPI.getTopCountries(5);
-        if (countriesResult.success) {
-            updateTopCountriesTable(countriesResult.data);
-        }
-
-        // Load top paths
-        const pathsResult = await LogLynxAPI.getTopPaths(5);
-        if (pathsResult.success) {
-            updateTopPathsTable(pathsResult.data);
+        const result = await LogLynxAPI.getOverview(timelineHours, 5);
+        if (result.success) {
+            const overview = result.data;
+            updateSummaryCards(overview.summary);
+            updateTimelineChart(overview.timeline);
+            updateStatusTimelineChart(overview.status_code_timeline);
+            updateStatusChart(overview.status_codes);
+            updateTopCountriesTable(overview.top_countries);
+            updateTopPathsTable(overview.top_paths);
         }
 
         // Reload DataTable
@@ -297,7 +274,11 @@ function initDataTable() {
     $('#recentRequestsTable').DataTable({
         ajax: function(data, callback, settings) {
             // Custom ajax function that rebuilds URL with current filters
-            const url = LogLynxAPI.buildURL('/requests/recent', { limit: 500 });
+            // Only the columns shown in the table (and used for the host display)
+            const url = LogLynxAPI.buildURL('/requests/recent', {
+                limit: 500,
+                fields: 'Timestamp,Method,Host,BackendName,BackendURL,Path,StatusCode,ResponseTimeMs,GeoCountry,ClientIP'
+            });
 
             fetch(url)
                 .then(response => response.json())
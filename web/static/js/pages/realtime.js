@@ -158,7 +158,7 @@ function updateRealtimeMetrics(metrics) {
 
     // Update live chart
     const now = new Date();
-    const timeLabel = now.toLocaleTimeString('en-US', {
+    const timeLabel = now.toLocaleTimeString(LogLynxUtils.locale(), {
         hour: '2-digit',
         minute: '2-digit',
         second: '2-digit',
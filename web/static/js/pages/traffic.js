@@ -18,7 +18,7 @@ async function loadTrafficData() {
         }
 
         // Load timeline data
-        const timelineResult = await LogLynxAPI.getTimeline(currentTimeRange);
+        const timelineResult = await LogLynxAPI.getTimeline(currentTimeRange, timelineBaselineWeeks());
         if (timelineResult.success) {
             allTrafficData.timeline = timelineResult.data;
             updateTrafficTimelineChart(timelineResult.data);
@@ -88,7 +88,7 @@ function calculatePeakTraffic(timelineData) {
     $('#peakTraffic').text(LogLynxUtils.formatNumber(maxRequests));
     if (peakTime) {
         const date = new Date(peakTime);
-        $('#peakTrafficTime').text(date.toLocaleDateString('en-US', {
+        $('#peakTrafficTime').text(date.toLocaleDateString(LogLynxUtils.locale(), {
             month: 'short',
             day: 'numeric',
             hour: '2-digit'
@@ -96,6 +96,14 @@ function calculatePeakTraffic(timelineData) {
     }
 }
 
+// Weeks averaged into the usual traffic line of the timeline
+const BASELINE_WEEKS = 4;
+
+// timelineBaselineWeeks returns the baseline weeks of the selected range, the API has none past 30 days
+function timelineBaselineWeeks() {
+    return currentTimeRange <= 720 ? BASELINE_WEEKS : 0;
+}
+
 // Initialize traffic timeline chart
 function initTrafficTimelineChart() {
     trafficTimelineChart = LogLynxCharts.createDualAxisChart('trafficTimelineChart', {
@@ -118,6 +126,17 @@ function initTrafficTimelineChart() {
                 tension: 0.4,
                 fill: true,
                 yAxisID: 'y1'
+            },
+            {
+                label: `Usual Requests (${BASELINE_WEEKS}-week average)`,
+                data: [],
+                borderColor: LogLynxCharts.colors.primary,
+                borderDash: [6, 4],
+                borderWidth: 1.5,
+                pointRadius: 0,
+                tension: 0.4,
+                fill: false,
+                yAxisID: 'y'
             }
         ]
     }, {
@@ -149,6 +168,7 @@ function updateTrafficTimelineChart(data) {
             trafficTimelineChart.data.labels = [];
             trafficTimelineChart.data.datasets[0].data = [];
             trafficTimelineChart.data.datasets[1].data = [];
+            trafficTimelineChart.data.datasets[2].data = [];
             trafficTimelineChart.update('none');
         }
         return;
@@ -157,11 +177,14 @@ function updateTrafficTimelineChart(data) {
     const labels = LogLynxCharts.formatTimelineLabels(data, currentTimeRange);
     const requests = data.map(d => d.requests);
     const visitors = data.map(d => d.unique_visitors || 0);
+    // Without baseline (long ranges, new databases) the line is hidden
+    const baseline = data.map(d => d.baseline ? Math.round(d.baseline.requests) : null);
 
     if (trafficTimelineChart) {
         trafficTimelineChart.data.labels = labels;
         trafficTimelineChart.data.datasets[0].data = requests;
         trafficTimelineChart.data.datasets[1].data = visitors;
+        trafficTimelineChart.data.datasets[2].data = baseline;
         trafficTimelineChart.update('none');
     }
 }
@@ -414,6 +437,7 @@ function initASNDataTable() {
 
             fetch(url)
                 .then(response => response.json())
+                .then(json => LogLynxAPI.unwrap(json).data)
                 .then(json => {
                     callback({ data: json });
                 })
@@ -558,7 +582,7 @@ function initTimeRangeSelector() {
 
 // Load only timeline data
 async function loadTimelineData() {
-    const result = await LogLynxAPI.getTimeline(currentTimeRange);
+    const result = await LogLynxAPI.getTimeline(currentTimeRange, timelineBaselineWeeks());
 
     if (result.success) {
         allTrafficData.timeline = result.data;
@@ -62,6 +62,13 @@ async function loadUserAnalyticsData() {
             initUserAgentTable(userAgentsResult.data);
         }
 
+        // Load API consumers (authenticated users and API keys)
+        const consumersResult = await LogLynxAPI.getTopConsumers(20);
+        if (consumersResult.success) {
+            allUserData.consumers = consumersResult.data;
+            initConsumersTable(consumersResult.data);
+        }
+
         // Load countries for geographic distribution
         const countriesResult = await LogLynxAPI.getTopCountries(15);
         if (countriesResult.success) {
@@ -399,6 +406,62 @@ function initUserAgentTable(userAgentsData) {
     });
 }
 
+// Initialize API consumers DataTable
+function initConsumersTable(consumersData) {
+    if ($.fn.DataTable.isDataTable('#consumersTable')) {
+        $('#consumersTable').DataTable().destroy();
+    }
+
+    $('#consumersTable').DataTable({
+        data: consumersData,
+        columns: [
+            {
+                data: null,
+                render: (data, type, row, meta) => meta.row + 1
+            },
+            {
+                data: 'consumer',
+                render: (d, type, row) => {
+                    const icon = row.kind === 'api_key' ? 'fa-key' : 'fa-user';
+                    const name = $('<div>').text(d).html();
+                    return `<i class="fas ${icon} text-muted"></i> <a href="/consumer/${encodeURIComponent(d)}"><code>${name}</code></a>`;
+                }
+            },
+            {
+                data: 'hits',
+                render: (d) => LogLynxUtils.formatNumber(d)
+            },
+            {
+                data: 'requests_per_minute',
+                render: (d) => d.toFixed(2)
+            },
+            {
+                data: 'error_rate',
+                render: (d) => d.toFixed(1) + '%'
+            },
+            {
+                data: 'avg_response_time',
+                render: (d) => LogLynxUtils.formatDuration(d)
+            },
+            {
+                data: 'quota',
+                render: (d) => {
+                    if (!d) return '<span class="text-muted">-</span>';
+                    const badge = d.exceeded ? 'badge-danger' : d.used_percent >= 80 ? 'badge-warning' : 'badge-success';
+                    return `<span class="badge ${badge}">${d.used_percent.toFixed(0)}%</span> <small class="text-muted">${LogLynxUtils.formatNumber(d.used)} / ${LogLynxUtils.formatNumber(d.limit)} per ${d.period}</small>`;
+                }
+            }
+        ],
+        order: [[2, 'desc']],
+        pageLength: 10,
+        autoWidth: false,
+        responsive: true,
+        language: {
+            emptyTable: 'No API consumers: requests have neither an authenticated user nor an X-Api-Key header'
+        }
+    });
+}
+
 // Update geographic visitors table
 function updateGeoVisitorsTable(data) {
     let html = '';
@@ -572,6 +635,12 @@ function exportReferrerDomainsData() {
     }
 }
 
+function exportConsumersData() {
+    if (allUserData.consumers) {
+        LogLynxUtils.exportAsCSV(allUserData.consumers, 'api-consumers.csv');
+    }
+}
+
 function exportUserAgentsData() {
     const table = $('#userAgentTable').DataTable();
     const data = table.rows().data().toArray();